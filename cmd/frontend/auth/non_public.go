@@ -96,6 +96,17 @@ func AllowAnonymousRequest(req *http.Request) bool {
 		return true
 	}
 
+	// Code host webhooks are sent anonymously. Their handlers verify the webhook
+	// secret of each request.
+	if strings.HasPrefix(req.URL.Path, "/.api/webhooks/") {
+		return true
+	}
+
+	// SCIM clients authenticate with bearer tokens, which the SCIM handlers check themselves.
+	if strings.HasPrefix(req.URL.Path, "/.api/scim/") {
+		return true
+	}
+
 	apiRouteName := matchedRouteName(req, router.Router())
 	if apiRouteName == router.UI {
 		// Test against UI router. (Some of its handlers inject private data into the title or meta tags.)
@@ -139,5 +139,11 @@ func GetAndSaveUser(ctx context.Context, op GetAndSaveUserOp) (userID int32, saf
 		}
 	}
 
+	// A new user or external account may give access to repositories, so don't wait for the next
+	// periodic sync of the user's permissions.
+	if userSaved || !extAcctSaved {
+		db.UserPermissions.SyncInBackground(userID)
+	}
+
 	return userID, "", nil
 }
@@ -82,6 +82,17 @@ type Provider interface {
 	Validate() (problems []string)
 }
 
+// TransientAccountProvider is implemented by authz providers whose external accounts identify the
+// Sourcegraph user itself (instead of an account on an external service). Such accounts are
+// computed by FetchAccount whenever they are needed and are never stored as external accounts of
+// the user.
+type TransientAccountProvider interface {
+	Provider
+
+	// TransientAccounts is a marker method; it does nothing.
+	TransientAccounts()
+}
+
 type Repo struct {
 	// RepoName is the unique name of the repo on Sourcegraph.
 	RepoName api.RepoName
@@ -31,7 +31,10 @@ type externalServices struct{}
 // external services.
 var ExternalServiceKinds = map[string]ExternalServiceKind{
 	"AWSCODECOMMIT":   {CodeHost: true, Definition: "AWSCodeCommitConnection"},
+	"BITBUCKETCLOUD":  {CodeHost: true, Definition: "BitbucketCloudConnection"},
 	"BITBUCKETSERVER": {CodeHost: true, Definition: "BitbucketServerConnection"},
+	"GERRIT":          {CodeHost: true, Definition: "GerritConnection"},
+	"GITEA":           {CodeHost: true, Definition: "GiteaConnection"},
 	"GITHUB":          {CodeHost: true, Definition: "GitHubConnection"},
 	"GITLAB":          {CodeHost: true, Definition: "GitLabConnection"},
 	"GITOLITE":        {CodeHost: true, Definition: "GitoliteConnection"},
@@ -355,6 +358,17 @@ func (c *externalServices) ListAWSCodeCommitConnections(ctx context.Context) ([]
 	return connections, nil
 }
 
+// ListBitbucketCloudConnections returns a list of BitbucketCloudConnection configs.
+//
+// 🚨 SECURITY: The caller must ensure that the actor is a site admin.
+func (c *externalServices) ListBitbucketCloudConnections(ctx context.Context) ([]*schema.BitbucketCloudConnection, error) {
+	var connections []*schema.BitbucketCloudConnection
+	if err := c.listConfigs(ctx, "BITBUCKETCLOUD", &connections); err != nil {
+		return nil, err
+	}
+	return connections, nil
+}
+
 // ListBitbucketServerConnections returns a list of BitbucketServer configs.
 //
 // 🚨 SECURITY: The caller must ensure that the actor is a site admin.
@@ -366,6 +380,28 @@ func (c *externalServices) ListBitbucketServerConnections(ctx context.Context) (
 	return connections, nil
 }
 
+// ListGerritConnections returns a list of GerritConnection configs.
+//
+// 🚨 SECURITY: The caller must ensure that the actor is a site admin.
+func (c *externalServices) ListGerritConnections(ctx context.Context) ([]*schema.GerritConnection, error) {
+	var connections []*schema.GerritConnection
+	if err := c.listConfigs(ctx, "GERRIT", &connections); err != nil {
+		return nil, err
+	}
+	return connections, nil
+}
+
+// ListGiteaConnections returns a list of GiteaConnection configs.
+//
+// 🚨 SECURITY: The caller must ensure that the actor is a site admin.
+func (c *externalServices) ListGiteaConnections(ctx context.Context) ([]*schema.GiteaConnection, error) {
+	var connections []*schema.GiteaConnection
+	if err := c.listConfigs(ctx, "GITEA", &connections); err != nil {
+		return nil, err
+	}
+	return connections, nil
+}
+
 // ListGitHubConnections returns a list of GitHubConnection configs.
 //
 // 🚨 SECURITY: The caller must ensure that the actor is a site admin.
@@ -20,6 +20,7 @@ type MockStores struct {
 	Phabricator MockPhabricator
 
 	ExternalAccounts MockExternalAccounts
+	RepoPermissions  MockRepoPermissions
 
 	OrgInvitations MockOrgInvitations
 
@@ -10,6 +10,7 @@ import (
 
 type MockOrgMembers struct {
 	GetByOrgIDAndUserID func(ctx context.Context, orgID, userID int32) (*types.OrgMembership, error)
+	GetByOrgID          func(ctx context.Context, orgID int32) ([]*types.OrgMembership, error)
 }
 
 func (s *MockOrgMembers) MockGetByOrgIDAndUserID_Return(t *testing.T, returns *types.OrgMembership, returnsErr error) (called *bool) {
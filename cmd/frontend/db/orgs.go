@@ -101,6 +101,10 @@ type OrgsListOptions struct {
 	// Query specifies a search query for organizations.
 	Query string
 
+	// NameOrDisplayName, if set, only includes organizations with this exact name or display name
+	// (both compared case-insensitively).
+	NameOrDisplayName string
+
 	*LimitOffset
 }
 
@@ -125,6 +129,9 @@ func (*orgs) listSQL(opt OrgsListOptions) (conds []*sqlf.Query) {
 		query := "%" + opt.Query + "%"
 		conds = append(conds, sqlf.Sprintf("name ILIKE %s OR display_name ILIKE %s", query, query))
 	}
+	if opt.NameOrDisplayName != "" {
+		conds = append(conds, sqlf.Sprintf("(name=%s OR lower(display_name)=lower(%s))", opt.NameOrDisplayName, opt.NameOrDisplayName))
+	}
 	return conds
 }
 
@@ -58,6 +58,37 @@ func TestOrgs_Count(t *testing.T) {
 	}
 }
 
+func TestOrgs_List_NameOrDisplayName(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	ctx := dbtesting.TestContext(t)
+
+	displayName := "Engineering Team"
+	eng, err := Orgs.Create(ctx, "eng", &displayName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Orgs.Create(ctx, "other", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, value := range []string{"eng", "ENG", "Engineering Team", "engineering team"} {
+		orgs, err := Orgs.List(ctx, &OrgsListOptions{NameOrDisplayName: value})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(orgs) != 1 || orgs[0].ID != eng.ID {
+			t.Errorf("%q: got orgs %+v, want org %d", value, orgs, eng.ID)
+		}
+	}
+	if orgs, err := Orgs.List(ctx, &OrgsListOptions{NameOrDisplayName: "Engineering"}); err != nil {
+		t.Fatal(err)
+	} else if len(orgs) != 0 {
+		t.Errorf("got orgs %+v, want none", orgs)
+	}
+}
+
 func TestOrgs_Delete(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
+)
+
+// RepoPermissions is the set of users and organizations that have a permission on a repository,
+// as set explicitly by a site admin.
+type RepoPermissions struct {
+	RepoID    api.RepoID
+	Perm      authz.Perm
+	UserIDs   []int32
+	OrgIDs    []int32 // the members of these organizations have the permission
+	UpdatedAt time.Time
+}
+
+// repoPermissions provides access to the `repo_permissions` table.
+//
+// The table stores explicit repository permissions, for repositories whose code host is not a
+// source of permissions (such as Gitolite repositories). They are enforced by an authz provider.
+type repoPermissions struct{}
+
+// Get returns the explicit permissions on the repository, or nil if there are none.
+func (s *repoPermissions) Get(ctx context.Context, repoID api.RepoID, p authz.Perm) (*RepoPermissions, error) {
+	rp := RepoPermissions{RepoID: repoID, Perm: p}
+	var userIDs, orgIDs pq.Int64Array
+	err := dbconn.Global.QueryRowContext(ctx, `
+SELECT user_ids, org_ids, updated_at FROM repo_permissions
+WHERE repo_id=$1 AND permission=$2
+`, repoID, p).Scan(&userIDs, &orgIDs, &rp.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rp.UserIDs, rp.OrgIDs = toInt32s(userIDs), toInt32s(orgIDs)
+	return &rp, nil
+}
+
+// Set replaces the explicit permissions on the repository with rp.
+func (s *repoPermissions) Set(ctx context.Context, rp *RepoPermissions) error {
+	_, err := dbconn.Global.ExecContext(ctx, `
+INSERT INTO repo_permissions(repo_id, permission, user_ids, org_ids, updated_at)
+VALUES($1, $2, $3, $4, now())
+ON CONFLICT (repo_id, permission) DO UPDATE SET user_ids=excluded.user_ids, org_ids=excluded.org_ids, updated_at=excluded.updated_at
+`, rp.RepoID, rp.Perm, toInt64Array(rp.UserIDs), toInt64Array(rp.OrgIDs))
+	return err
+}
+
+// Delete removes the explicit permissions on the repository.
+func (s *repoPermissions) Delete(ctx context.Context, repoID api.RepoID, p authz.Perm) error {
+	_, err := dbconn.Global.ExecContext(ctx, "DELETE FROM repo_permissions WHERE repo_id=$1 AND permission=$2", repoID, p)
+	return err
+}
+
+// Exists reports whether there are explicit permissions on any repository.
+func (s *repoPermissions) Exists(ctx context.Context) (exists bool, err error) {
+	if Mocks.RepoPermissions.Exists != nil {
+		return Mocks.RepoPermissions.Exists()
+	}
+	err = dbconn.Global.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM repo_permissions)").Scan(&exists)
+	return exists, err
+}
+
+// ListRepoNames returns the names of the repositories in names that have explicit permissions.
+func (s *repoPermissions) ListRepoNames(ctx context.Context, names []api.RepoName) (map[api.RepoName]struct{}, error) {
+	if Mocks.RepoPermissions.ListRepoNames != nil {
+		return Mocks.RepoPermissions.ListRepoNames(names)
+	}
+	return s.queryRepoNames(ctx, `
+SELECT DISTINCT repo.name FROM repo_permissions p JOIN repo ON repo.id=p.repo_id
+WHERE repo.name=ANY($1)
+`, toStringArray(names))
+}
+
+// ListUserRepoNames returns the names of the repositories in names on which the user has the
+// permission, either directly or through an organization they are a member of.
+func (s *repoPermissions) ListUserRepoNames(ctx context.Context, userID int32, p authz.Perm, names []api.RepoName) (map[api.RepoName]struct{}, error) {
+	if Mocks.RepoPermissions.ListUserRepoNames != nil {
+		return Mocks.RepoPermissions.ListUserRepoNames(userID, p, names)
+	}
+	return s.queryRepoNames(ctx, `
+SELECT repo.name FROM repo_permissions p JOIN repo ON repo.id=p.repo_id
+WHERE repo.name=ANY($1) AND p.permission=$2
+AND ($3=ANY(p.user_ids) OR p.org_ids && ARRAY(SELECT org_id FROM org_members WHERE user_id=$3))
+`, toStringArray(names), p, userID)
+}
+
+func (s *repoPermissions) queryRepoNames(ctx context.Context, query string, args ...interface{}) (map[api.RepoName]struct{}, error) {
+	rows, err := dbconn.Global.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make(map[api.RepoName]struct{})
+	for rows.Next() {
+		var name api.RepoName
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names[name] = struct{}{}
+	}
+	return names, rows.Err()
+}
+
+func toInt32s(a pq.Int64Array) []int32 {
+	ids := make([]int32, len(a))
+	for i, id := range a {
+		ids[i] = int32(id)
+	}
+	return ids
+}
+
+func toInt64Array(ids []int32) pq.Int64Array {
+	a := make(pq.Int64Array, len(ids))
+	for i, id := range ids {
+		a[i] = int64(id)
+	}
+	return a
+}
+
+func toStringArray(names []api.RepoName) pq.StringArray {
+	a := make(pq.StringArray, len(names))
+	for i, name := range names {
+		a[i] = string(name)
+	}
+	return a
+}
+
+type MockRepoPermissions struct {
+	Exists            func() (bool, error)
+	ListRepoNames     func(names []api.RepoName) (map[api.RepoName]struct{}, error)
+	ListUserRepoNames func(userID int32, p authz.Perm, names []api.RepoName) (map[api.RepoName]struct{}, error)
+}
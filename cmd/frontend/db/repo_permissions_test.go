@@ -0,0 +1,117 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbtesting"
+)
+
+func TestRepoPermissions(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	ctx := dbtesting.TestContext(t)
+
+	var userIDs []int32
+	for _, username := range []string{"u1", "u2", "u3"} {
+		user, err := Users.Create(ctx, NewUser{Username: username})
+		if err != nil {
+			t.Fatal(err)
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+	org, err := Orgs.Create(ctx, "o1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OrgMembers.Create(ctx, org.ID, userIDs[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	var repoIDs []api.RepoID
+	names := []api.RepoName{"gitolite.example.com/r1", "gitolite.example.com/r2"}
+	for _, name := range names {
+		if err := Repos.Upsert(ctx, api.InsertRepoOp{Name: name, Enabled: true}); err != nil {
+			t.Fatal(err)
+		}
+		repo, err := Repos.GetByName(ctx, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repoIDs = append(repoIDs, repo.ID)
+	}
+
+	if exists, err := RepoPermissions.Exists(ctx); err != nil {
+		t.Fatal(err)
+	} else if exists {
+		t.Error("expected no explicit permissions")
+	}
+
+	// r1 can be read by u1 and the members of o1 (u2).
+	if err := RepoPermissions.Set(ctx, &RepoPermissions{RepoID: repoIDs[0], Perm: authz.Read, UserIDs: []int32{userIDs[0]}, OrgIDs: []int32{org.ID}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rp, err := RepoPermissions.Get(ctx, repoIDs[0], authz.Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rp == nil || !reflect.DeepEqual(rp.UserIDs, []int32{userIDs[0]}) || !reflect.DeepEqual(rp.OrgIDs, []int32{org.ID}) {
+		t.Errorf("got permissions %s, want u1 and o1", asJSON(t, rp))
+	}
+	if rp, err := RepoPermissions.Get(ctx, repoIDs[1], authz.Read); err != nil {
+		t.Fatal(err)
+	} else if rp != nil {
+		t.Errorf("got permissions %s on r2, want none", asJSON(t, rp))
+	}
+
+	if exists, err := RepoPermissions.Exists(ctx); err != nil {
+		t.Fatal(err)
+	} else if !exists {
+		t.Error("expected explicit permissions")
+	}
+
+	got, err := RepoPermissions.ListRepoNames(ctx, names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[api.RepoName]struct{}{names[0]: {}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got repos with explicit permissions %v, want %v", got, want)
+	}
+
+	for i, want := range []map[api.RepoName]struct{}{
+		{names[0]: {}}, // u1 is allowed directly
+		{names[0]: {}}, // u2 is allowed through o1
+		{},             // u3 is not allowed
+	} {
+		got, err := RepoPermissions.ListUserRepoNames(ctx, userIDs[i], authz.Read, names)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("u%d: got readable repos %v, want %v", i+1, got, want)
+		}
+	}
+
+	// Setting permissions replaces the previous ones.
+	if err := RepoPermissions.Set(ctx, &RepoPermissions{RepoID: repoIDs[0], Perm: authz.Read, UserIDs: []int32{userIDs[2]}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := RepoPermissions.ListUserRepoNames(ctx, userIDs[0], authz.Read, names); err != nil {
+		t.Fatal(err)
+	} else if len(got) != 0 {
+		t.Errorf("got readable repos %v for u1 after replacing permissions, want none", got)
+	}
+
+	if err := RepoPermissions.Delete(ctx, repoIDs[0], authz.Read); err != nil {
+		t.Fatal(err)
+	}
+	if rp, err := RepoPermissions.Get(ctx, repoIDs[0], authz.Read); err != nil {
+		t.Fatal(err)
+	} else if rp != nil {
+		t.Errorf("got permissions %s after deleting them, want none", asJSON(t, rp))
+	}
+}
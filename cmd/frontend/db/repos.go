@@ -100,7 +100,15 @@ func (s *repos) Count(ctx context.Context, opt ReposListOptions) (int, error) {
 }
 
 func (s *repos) getBySQL(ctx context.Context, querySuffix *sqlf.Query) ([]*types.Repo, error) {
-	q := sqlf.Sprintf("SELECT id, name, description, language, enabled, created_at, updated_at, external_id, external_service_type, external_service_id FROM repo %s", querySuffix)
+	// 🚨 SECURITY: This enforces the synced repository permissions of the user. The query suffix
+	// selects from the repositories that the user may access, so that it can't circumvent the
+	// permissions check and any LIMIT applies after it.
+	authzConds, err := authzQueryConds(ctx, authz.Read)
+	if err != nil {
+		return nil, err
+	}
+
+	q := sqlf.Sprintf("SELECT id, name, description, language, enabled, created_at, updated_at, external_id, external_service_type, external_service_id FROM (SELECT * FROM repo WHERE %s) AS repo %s", authzConds, querySuffix)
 	rows, err := dbconn.Global.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
 	if err != nil {
 		return nil, err
@@ -280,6 +288,35 @@ func (s *repos) ListEnabledNames(ctx context.Context) ([]string, error) {
 	return names, nil
 }
 
+// listByExternalService returns the repositories from the code host with the given service type
+// and ID whose IDs are larger than afterID (all of them if afterID is 0).
+//
+// 🚨 SECURITY: It does not check repository permissions, so it must only be used for computing
+// them.
+func (s *repos) listByExternalService(ctx context.Context, serviceType, serviceID string, afterID api.RepoID) ([]*types.Repo, error) {
+	q := sqlf.Sprintf("SELECT id, name, external_id, external_service_type, external_service_id FROM repo WHERE external_service_type=%s AND external_service_id=%s AND id>%s", serviceType, serviceID, afterID)
+	rows, err := dbconn.Global.QueryContext(ctx, q.Query(sqlf.PostgresBindVar), q.Args()...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var repos []*types.Repo
+	for rows.Next() {
+		var repo types.Repo
+		var spec dbExternalRepoSpec
+		if err := rows.Scan(&repo.ID, &repo.Name, &spec.id, &spec.serviceType, &spec.serviceID); err != nil {
+			return nil, err
+		}
+		repo.ExternalRepo = spec.toAPISpec()
+		repos = append(repos, &repo)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
 func parsePattern(p string) ([]*sqlf.Query, error) {
 	exact, like, pattern, err := parseIncludePattern(p)
 	if err != nil {
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 
+	"github.com/keegancsmith/sqlf"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/pkg/actor"
@@ -63,22 +64,68 @@ func isInternalActor(ctx context.Context) bool {
 	return actor.FromContext(ctx).Internal
 }
 
+// authzQueryConds returns a SQL condition on the columns of the repo table that enforces the
+// permissions of the currently authenticated user on repositories from the code hosts of the authz
+// providers: the user must have been granted the permission, according to the permissions that
+// were last synced from the authz providers (see UserPermissions.Sync).
+//
+// Repositories that are not from the code host of an authz provider, and all repositories for
+// anonymous users (whose permissions can't be synced), are left to authzFilter.
+//
+// 🚨 SECURITY: Queries for repositories must include this condition (see repos.getBySQL), because
+// authzFilter trusts it for the repositories it covers.
+func authzQueryConds(ctx context.Context, p authz.Perm) (*sqlf.Query, error) {
+	if isInternalActor(ctx) || !actor.FromContext(ctx).IsAuthenticated() {
+		return sqlf.Sprintf("TRUE"), nil
+	}
+	_, authzProviders := authz.GetProviders()
+	if len(authzProviders) == 0 {
+		return sqlf.Sprintf("TRUE"), nil
+	}
+	currentUser, err := Users.GetByCurrentAuthUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if currentUser.SiteAdmin {
+		return sqlf.Sprintf("TRUE"), nil
+	}
+
+	codeHosts := make([]*sqlf.Query, 0, len(authzProviders))
+	for _, authzProvider := range authzProviders {
+		codeHosts = append(codeHosts, sqlf.Sprintf("(external_service_type=%s AND external_service_id=%s)", authzProvider.ServiceType(), authzProvider.ServiceID()))
+	}
+	return sqlf.Sprintf(
+		"((%s) IS NOT TRUE OR EXISTS (SELECT 1 FROM user_repo_permissions WHERE user_id=%s AND permission=%s AND repo_id=repo.id))",
+		sqlf.Join(codeHosts, "OR"), currentUser.ID, p,
+	), nil
+}
+
+// getFilteredRepoNames returns the names of the repositories on which the user has the permission.
+//
+// 🚨 SECURITY: For an authenticated user, the repositories from the code hosts of the authz
+// providers must already have been filtered by authzQueryConds. They are accepted without asking
+// the authz providers.
 func getFilteredRepoNames(ctx context.Context, currentUser *types.User, repos map[authz.Repo]struct{}, p authz.Perm) (accepted map[api.RepoName]struct{}, err error) {
 	var accts []*extsvc.ExternalAccount
 	authzAllowByDefault, authzProviders := authz.GetProviders()
-	if len(authzProviders) > 0 && currentUser != nil {
-		accts, err = ExternalAccounts.List(ctx, ExternalAccountsListOptions{UserID: currentUser.ID})
-		if err != nil {
-			return nil, err
-		}
-	}
 
 	accepted = make(map[api.RepoName]struct{})  // repositories that have been claimed and have read permissions
 	unverified := make(map[authz.Repo]struct{}) // repositories that have not been claimed by any authz provider
 	for repo := range repos {
+		if currentUser != nil && isFromAuthzProviderCodeHost(repo, authzProviders) {
+			accepted[repo.RepoName] = struct{}{}
+			continue
+		}
 		unverified[repo] = struct{}{}
 	}
 
+	if len(unverified) > 0 && len(authzProviders) > 0 && currentUser != nil {
+		accts, err = ExternalAccounts.List(ctx, ExternalAccountsListOptions{UserID: currentUser.ID})
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// Walk through all authz providers, checking repo permissions against each. If any own a given
 	// repo, we use its permissions for that repo.
 	for _, authzProvider := range authzProviders {
@@ -88,23 +135,10 @@ func getFilteredRepoNames(ctx context.Context, currentUser *types.User, repos ma
 
 		// determine external account to use
 		var providerAcct *extsvc.ExternalAccount
-		for _, acct := range accts {
-			if acct.ServiceID == authzProvider.ServiceID() && acct.ServiceType == authzProvider.ServiceType() {
-				providerAcct = acct
-				break
-			}
-		}
-		if providerAcct == nil && currentUser != nil { // no existing external account for authz provider
-			if pr, err := authzProvider.FetchAccount(ctx, currentUser, accts); err == nil {
-				providerAcct = pr
-				if providerAcct != nil {
-					err := ExternalAccounts.AssociateUserAndSave(ctx, currentUser.ID, providerAcct.ExternalAccountSpec, providerAcct.ExternalAccountData)
-					if err != nil {
-						return nil, err
-					}
-				}
-			} else {
-				log15.Warn("Could not fetch authz provider account for user", "username", currentUser.Username, "authzProvider", authzProvider.ServiceID(), "error", err)
+		if currentUser != nil {
+			providerAcct, err = providerAccount(ctx, authzProvider, currentUser, accts)
+			if err != nil {
+				return nil, err
 			}
 		}
 
@@ -112,13 +146,15 @@ func getFilteredRepoNames(ctx context.Context, currentUser *types.User, repos ma
 		myUnverified, nextUnverified := authzProvider.Repos(ctx, unverified)
 
 		// check the perms on those repos
-		perms, err := authzProvider.RepoPerms(ctx, providerAcct, myUnverified)
-		if err != nil {
-			return nil, err
-		}
-		for unverifiedRepo := range myUnverified {
-			if repoPerms, ok := perms[unverifiedRepo.RepoName]; ok && repoPerms[p] {
-				accepted[unverifiedRepo.RepoName] = struct{}{}
+		if len(myUnverified) > 0 {
+			perms, err := authzProvider.RepoPerms(ctx, providerAcct, myUnverified)
+			if err != nil {
+				return nil, err
+			}
+			for unverifiedRepo := range myUnverified {
+				if repoPerms, ok := perms[unverifiedRepo.RepoName]; ok && repoPerms[p] {
+					accepted[unverifiedRepo.RepoName] = struct{}{}
+				}
 			}
 		}
 		// continue checking repos that didn't belong to this authz provider
@@ -133,3 +169,49 @@ func getFilteredRepoNames(ctx context.Context, currentUser *types.User, repos ma
 
 	return accepted, nil
 }
+
+// isFromAuthzProviderCodeHost reports whether the repository is from the code host of one of the
+// authz providers, i.e. whether authzQueryConds covers it.
+func isFromAuthzProviderCodeHost(repo authz.Repo, authzProviders []authz.Provider) bool {
+	for _, authzProvider := range authzProviders {
+		if repo.ExternalRepoSpec.ServiceType == authzProvider.ServiceType() && repo.ExternalRepoSpec.ServiceID == authzProvider.ServiceID() {
+			return true
+		}
+	}
+	return false
+}
+
+// providerAccount returns the user's external account for the authz provider. If the user has no
+// such account, it asks the authz provider for one and associates it with the user (unless the
+// provider is an authz.TransientAccountProvider). It returns nil if the authz provider has no
+// account for the user.
+func providerAccount(ctx context.Context, authzProvider authz.Provider, user *types.User, accts []*extsvc.ExternalAccount) (*extsvc.ExternalAccount, error) {
+	if _, ok := authzProvider.(authz.TransientAccountProvider); ok {
+		providerAcct, err := authzProvider.FetchAccount(ctx, user, accts)
+		if err != nil {
+			log15.Warn("Could not fetch authz provider account for user", "username", user.Username, "authzProvider", authzProvider.ServiceID(), "error", err)
+			return nil, nil
+		}
+		return providerAcct, nil
+	}
+
+	for _, acct := range accts {
+		if acct.ServiceID == authzProvider.ServiceID() && acct.ServiceType == authzProvider.ServiceType() {
+			return acct, nil
+		}
+	}
+
+	// no existing external account for authz provider
+	providerAcct, err := authzProvider.FetchAccount(ctx, user, accts)
+	if err != nil {
+		log15.Warn("Could not fetch authz provider account for user", "username", user.Username, "authzProvider", authzProvider.ServiceID(), "error", err)
+		return nil, nil
+	}
+	if providerAcct != nil {
+		err := ExternalAccounts.AssociateUserAndSave(ctx, user.ID, providerAcct.ExternalAccountSpec, providerAcct.ExternalAccountData)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return providerAcct, nil
+}
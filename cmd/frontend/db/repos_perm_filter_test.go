@@ -525,6 +525,61 @@ func Test_authzFilter(t *testing.T) {
 	}
 }
 
+func Test_authzFilter_userPermissions(t *testing.T) {
+	gitlabRepo := func(id api.RepoID, name api.RepoName) *types.Repo {
+		return &types.Repo{
+			ID:           id,
+			Name:         name,
+			ExternalRepo: &api.ExternalRepoSpec{ID: string(name), ServiceType: "gitlab", ServiceID: "https://gitlab.mine/"},
+		}
+	}
+	repos := []*types.Repo{
+		gitlabRepo(1, "gitlab.mine/u1/r0"),
+		gitlabRepo(2, "gitlab.mine/u1/r1"),
+		{ID: 3, Name: "gitolite.mine/u1/r2"}, // not from the provider's code host
+	}
+
+	authzFilter_Test{
+		description:         "repositories from the provider's code host are checked by authzQueryConds",
+		authzAllowByDefault: true,
+		authzProviders: []authz.Provider{
+			&MockAuthzProvider{
+				serviceID:   "https://gitlab.mine/",
+				serviceType: "gitlab",
+				repos: map[api.RepoName]struct{}{
+					"gitlab.mine/u1/r0":   {},
+					"gitlab.mine/u1/r1":   {},
+					"gitolite.mine/u1/r2": {},
+				},
+				perms: map[extsvc.ExternalAccount]map[api.RepoName]map[authz.Perm]bool{
+					*acct(1, "gitlab", "https://gitlab.mine/", "u1"): {
+						"gitlab.mine/u1/r1": {authz.Read: true},
+					},
+					{}: {
+						"gitlab.mine/u1/r0": {authz.Read: true},
+					},
+				},
+			},
+		},
+		calls: []authzFilter_call{
+			{
+				description:      "authenticated user, only repos from other code hosts are checked with the authz provider",
+				user:             &types.User{ID: 1},
+				userAccounts:     []*extsvc.ExternalAccount{acct(1, "gitlab", "https://gitlab.mine/", "u1")},
+				repos:            repos,
+				perm:             authz.Read,
+				expFilteredRepos: []*types.Repo{repos[0], repos[1]},
+			},
+			{
+				description:      "anonymous user, all repos are checked with the authz provider",
+				repos:            repos,
+				perm:             authz.Read,
+				expFilteredRepos: []*types.Repo{repos[0]},
+			},
+		},
+	}.run(t)
+}
+
 func Test_authzFilter_createsNewUsers(t *testing.T) {
 	associateUserAndSaveCount := make(map[int32]map[extsvc.ExternalAccountSpec]int)
 	Mocks.ExternalAccounts.AssociateUserAndSave = func(userID int32, spec extsvc.ExternalAccountSpec, data extsvc.ExternalAccountData) error {
@@ -662,6 +717,52 @@ func acct(userID int32, serviceType, serviceID, accountID string) *extsvc.Extern
 	}
 }
 
+func Test_authzFilter_transientAccounts(t *testing.T) {
+	Mocks.ExternalAccounts.AssociateUserAndSave = func(userID int32, spec extsvc.ExternalAccountSpec, data extsvc.ExternalAccountData) error {
+		t.Errorf("unexpected stored account %+v for user %d", spec, userID)
+		return nil
+	}
+	Mocks.ExternalAccounts.List = func(ExternalAccountsListOptions) ([]*extsvc.ExternalAccount, error) { return nil, nil }
+	Mocks.Users.GetByCurrentAuthUser = func(ctx context.Context) (*types.User, error) {
+		return &types.User{ID: actor.FromContext(ctx).UID}, nil
+	}
+	defer func() { Mocks = MockStores{} }()
+
+	explicitAcct := acct(23, "sourcegraph", "explicit", "23")
+	authz.SetProviders(false, []authz.Provider{
+		&mockTransientAccountProvider{MockAuthzProvider{
+			serviceID:   "explicit",
+			serviceType: "sourcegraph",
+			repos:       map[api.RepoName]struct{}{"r": {}},
+			perms: map[extsvc.ExternalAccount]map[api.RepoName]map[authz.Perm]bool{
+				*explicitAcct: {"r": {authz.Read: true}},
+			},
+		}, explicitAcct},
+	})
+	defer authz.SetProviders(true, nil)
+
+	repos := []*types.Repo{{ID: 1, Name: "r"}}
+	filteredRepos, err := authzFilter(actor.WithActor(context.Background(), &actor.Actor{UID: 23}), repos, authz.Read)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(filteredRepos, repos) {
+		t.Errorf("got repos %s, want %s", asJSON(t, filteredRepos), asJSON(t, repos))
+	}
+}
+
+// mockTransientAccountProvider is a MockAuthzProvider whose accounts are not stored.
+type mockTransientAccountProvider struct {
+	MockAuthzProvider
+	acct *extsvc.ExternalAccount // the account returned by FetchAccount
+}
+
+func (m *mockTransientAccountProvider) FetchAccount(ctx context.Context, user *types.User, current []*extsvc.ExternalAccount) (*extsvc.ExternalAccount, error) {
+	return m.acct, nil
+}
+
+func (m *mockTransientAccountProvider) TransientAccounts() {}
+
 type MockAuthzProvider struct {
 	serviceID   string
 	serviceType string
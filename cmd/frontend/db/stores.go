@@ -18,6 +18,8 @@ var (
 	SurveyResponses = &surveyResponses{}
 
 	ExternalAccounts = &userExternalAccounts{}
+	UserPermissions  = &userPermissions{}
+	RepoPermissions  = &repoPermissions{}
 
 	OrgInvitations = &orgInvitations{}
 )
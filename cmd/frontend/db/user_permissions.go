@@ -0,0 +1,378 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/lib/pq"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbconn"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// UserPermissions is the set of repositories on which a user has a permission according to an authz
+// provider, as of the last time the user's permissions were synced.
+type UserPermissions struct {
+	UserID      int32
+	Perm        authz.Perm
+	ServiceType string // the authz provider's ServiceType
+	ServiceID   string // the authz provider's ServiceID
+	RepoIDs     map[api.RepoID]struct{}
+	MaxRepoID   api.RepoID // the largest repository ID when the permissions were synced
+	UpdatedAt   time.Time
+}
+
+// userPermissions provides access to the `user_permissions` and `user_repo_permissions` tables.
+//
+// The `user_repo_permissions` table stores the repositories on which each user has a permission,
+// one row per user and repository, and the `user_permissions` table records when each user's
+// permissions were last synced from each authz provider. They are populated in the background by
+// the permissions syncer in repo-updater (see Sync), so that repository queries can check
+// permissions with an indexed join (see authzQueryConds) instead of asking the authz providers on
+// every request.
+//
+// A user's permissions are also synced when the user is created or signs in with a new external
+// account (see SyncInBackground), and the permissions on repositories that were added since a
+// user's last sync are synced when repo-updater adds repositories (see SyncNewRepos). Revoked
+// permissions are only removed by the next periodic sync.
+type userPermissions struct{}
+
+// Replace replaces the stored permissions of the user with perms. Stored permissions from authz
+// providers that are not in perms are removed.
+func (s *userPermissions) Replace(ctx context.Context, userID int32, p authz.Perm, perms []*UserPermissions) (err error) {
+	tx, err := dbconn.Global.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			rollErr := tx.Rollback()
+			if rollErr != nil {
+				err = multierror.Append(err, rollErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	// Lock the user, so that concurrent syncs of the same user don't conflict.
+	if _, err := tx.ExecContext(ctx, "SELECT id FROM users WHERE id=$1 FOR NO KEY UPDATE", userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_permissions WHERE user_id=$1 AND permission=$2", userID, p); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_repo_permissions WHERE user_id=$1 AND permission=$2", userID, p); err != nil {
+		return err
+	}
+
+	var ids pq.Int64Array
+	for _, up := range perms {
+		if _, err := tx.ExecContext(ctx, `
+INSERT INTO user_permissions(user_id, permission, service_type, service_id, max_repo_id, updated_at)
+VALUES($1, $2, $3, $4, $5, $6)
+`, userID, p, up.ServiceType, up.ServiceID, up.MaxRepoID, up.UpdatedAt); err != nil {
+			return err
+		}
+		for id := range up.RepoIDs {
+			ids = append(ids, int64(id))
+		}
+	}
+
+	// Repositories that were deleted since the permissions were computed are skipped.
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO user_repo_permissions(user_id, permission, repo_id)
+SELECT $1, $2, id FROM repo WHERE id = ANY($3)
+`, userID, p, ids)
+	return err
+}
+
+// ListStale returns the users whose permissions were last synced before syncedBefore or have never
+// been synced, least recently synced first, and the total number of such users. Site admins are
+// omitted because their permissions are never checked.
+func (s *userPermissions) ListStale(ctx context.Context, syncedBefore time.Time, limit int) (stale []api.StaleUserPermissions, total int, err error) {
+	rows, err := dbconn.Global.QueryContext(ctx, `
+SELECT users.id, MIN(p.updated_at) AS synced_at, COUNT(*) OVER ()
+FROM users LEFT JOIN user_permissions p ON p.user_id=users.id
+WHERE users.deleted_at IS NULL AND NOT users.site_admin
+GROUP BY users.id
+HAVING MIN(p.updated_at) IS NULL OR MIN(p.updated_at) < $1
+ORDER BY synced_at ASC NULLS FIRST, users.id ASC
+LIMIT $2
+`, syncedBefore, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u api.StaleUserPermissions
+		if err := rows.Scan(&u.UserID, &u.SyncedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		stale = append(stale, u)
+	}
+	return stale, total, rows.Err()
+}
+
+// Sync computes the repository permissions of the user from the configured authz providers and
+// stores them. It asks each authz provider for the user's permissions on all repositories of the
+// provider's code host.
+func (s *userPermissions) Sync(ctx context.Context, userID int32) error {
+	user, err := Users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.SiteAdmin {
+		return nil // site admins can access all repositories
+	}
+
+	_, authzProviders := authz.GetProviders()
+	accts, err := ExternalAccounts.List(ctx, ExternalAccountsListOptions{UserID: userID})
+	if err != nil {
+		return err
+	}
+
+	// Repositories created after the sync starts are not accessible to the user until they are
+	// synced by SyncNewRepos.
+	syncStarted := time.Now()
+	maxID, err := maxRepoID(ctx)
+	if err != nil {
+		return err
+	}
+
+	perms := make([]*UserPermissions, 0, len(authzProviders))
+	for _, authzProvider := range authzProviders {
+		// Only the repositories of the provider's code host are synced. Providers that are not
+		// backed by a code host (such as explicit permissions) are checked by authzFilter.
+		repos, err := Repos.listByExternalService(ctx, authzProvider.ServiceType(), authzProvider.ServiceID(), 0)
+		if err != nil {
+			return err
+		}
+		repoIDs, err := readableRepos(ctx, authzProvider, user, accts, repos)
+		if err != nil {
+			return err
+		}
+		perms = append(perms, &UserPermissions{
+			UserID:      userID,
+			Perm:        authz.Read,
+			ServiceType: authzProvider.ServiceType(),
+			ServiceID:   authzProvider.ServiceID(),
+			RepoIDs:     repoIDs,
+			MaxRepoID:   maxID,
+			UpdatedAt:   syncStarted,
+		})
+	}
+
+	return s.Replace(ctx, userID, authz.Read, perms)
+}
+
+// SyncInBackground syncs the permissions of the user (see Sync) in a new goroutine and logs any
+// error. It is called when a user is created or signs in with a new external account, so that the
+// user can access repositories right away instead of after the next sync by repo-updater.
+func (s *userPermissions) SyncInBackground(userID int32) {
+	if _, authzProviders := authz.GetProviders(); len(authzProviders) == 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		if err := s.Sync(ctx, userID); err != nil {
+			log15.Error("Failed to sync repository permissions of user.", "user", userID, "error", err)
+		}
+	}()
+}
+
+// SyncNewRepos computes the permissions of users on the repositories that were added since the
+// users' permissions were last synced and adds them to the stored permissions. It only asks the
+// authz providers about the new repositories, so it is much cheaper than syncing all users.
+// Permissions that were synced before max_repo_id was recorded are skipped until their next Sync.
+func (s *userPermissions) SyncNewRepos(ctx context.Context) error {
+	_, authzProviders := authz.GetProviders()
+	if len(authzProviders) == 0 {
+		return nil
+	}
+	maxID, err := maxRepoID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var errs *multierror.Error
+	for _, authzProvider := range authzProviders {
+		if err := s.syncNewRepos(ctx, authzProvider, maxID); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// syncNewRepos syncs the permissions from the authz provider on the repositories with IDs up to
+// maxRepoID for the users whose permissions were synced up to a smaller repository ID.
+func (s *userPermissions) syncNewRepos(ctx context.Context, authzProvider authz.Provider, maxRepoID api.RepoID) error {
+	rows, err := dbconn.Global.QueryContext(ctx, `
+SELECT p.user_id, p.max_repo_id
+FROM user_permissions p JOIN users ON users.id=p.user_id
+WHERE p.permission=$1 AND p.service_type=$2 AND p.service_id=$3 AND p.max_repo_id < $4
+AND users.deleted_at IS NULL AND NOT users.site_admin
+`, authz.Read, authzProvider.ServiceType(), authzProvider.ServiceID(), maxRepoID)
+	if err != nil {
+		return err
+	}
+	type syncedUser struct {
+		userID    int32
+		maxRepoID api.RepoID
+	}
+	var users []syncedUser
+	minRepoID := maxRepoID
+	for rows.Next() {
+		var u syncedUser
+		if err := rows.Scan(&u.userID, &u.maxRepoID); err != nil {
+			rows.Close()
+			return err
+		}
+		if u.maxRepoID < minRepoID {
+			minRepoID = u.maxRepoID
+		}
+		users = append(users, u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+
+	repos, err := Repos.listByExternalService(ctx, authzProvider.ServiceType(), authzProvider.ServiceID(), minRepoID)
+	if err != nil {
+		return err
+	}
+
+	var errs *multierror.Error
+	for _, u := range users {
+		// Repositories added after maxRepoID was read are left to the next call.
+		var newRepos []*types.Repo
+		for _, r := range repos {
+			if r.ID > u.maxRepoID && r.ID <= maxRepoID {
+				newRepos = append(newRepos, r)
+			}
+		}
+
+		var repoIDs map[api.RepoID]struct{}
+		if len(newRepos) > 0 {
+			user, err := Users.GetByID(ctx, u.userID)
+			if err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			accts, err := ExternalAccounts.List(ctx, ExternalAccountsListOptions{UserID: u.userID})
+			if err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			if repoIDs, err = readableRepos(ctx, authzProvider, user, accts, newRepos); err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+		}
+
+		// The stored permissions are advanced to maxRepoID even if the user can't read any of
+		// the new repositories, so that the user is skipped next time.
+		up := &UserPermissions{
+			UserID:      u.userID,
+			Perm:        authz.Read,
+			ServiceType: authzProvider.ServiceType(),
+			ServiceID:   authzProvider.ServiceID(),
+			RepoIDs:     repoIDs,
+			MaxRepoID:   maxRepoID,
+		}
+		if err := s.add(ctx, up, u.maxRepoID); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// add adds the repositories in up.RepoIDs to the user's stored permissions from the authz provider
+// and records that they are synced up to up.MaxRepoID. It does nothing if the stored permissions
+// are no longer synced up to syncedRepoID, because they were synced concurrently.
+func (s *userPermissions) add(ctx context.Context, up *UserPermissions, syncedRepoID api.RepoID) (err error) {
+	tx, err := dbconn.Global.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			rollErr := tx.Rollback()
+			if rollErr != nil {
+				err = multierror.Append(err, rollErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	res, err := tx.ExecContext(ctx, `
+UPDATE user_permissions SET max_repo_id=$5
+WHERE user_id=$1 AND permission=$2 AND service_type=$3 AND service_id=$4 AND max_repo_id=$6
+`, up.UserID, up.Perm, up.ServiceType, up.ServiceID, up.MaxRepoID, syncedRepoID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	ids := make(pq.Int64Array, 0, len(up.RepoIDs))
+	for id := range up.RepoIDs {
+		ids = append(ids, int64(id))
+	}
+	_, err = tx.ExecContext(ctx, `
+INSERT INTO user_repo_permissions(user_id, permission, repo_id)
+SELECT $1, $2, id FROM repo WHERE id = ANY($3)
+ON CONFLICT DO NOTHING
+`, up.UserID, up.Perm, ids)
+	return err
+}
+
+// readableRepos returns the IDs of the repositories among repos that the user can read according
+// to the authz provider.
+func readableRepos(ctx context.Context, authzProvider authz.Provider, user *types.User, accts []*extsvc.ExternalAccount, repos []*types.Repo) (map[api.RepoID]struct{}, error) {
+	repoIDs := make(map[api.RepoID]struct{})
+	if len(repos) == 0 {
+		return repoIDs, nil
+	}
+
+	providerAcct, err := providerAccount(ctx, authzProvider, user, accts)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[api.RepoName]api.RepoID, len(repos))
+	for _, r := range repos {
+		ids[r.Name] = r.ID
+	}
+
+	myRepos, _ := authzProvider.Repos(ctx, authz.ToRepos(repos))
+	repoPerms, err := authzProvider.RepoPerms(ctx, providerAcct, myRepos)
+	if err != nil {
+		return nil, err
+	}
+	for name, p := range repoPerms {
+		if id, ok := ids[name]; ok && p[authz.Read] {
+			repoIDs[id] = struct{}{}
+		}
+	}
+	return repoIDs, nil
+}
+
+// maxRepoID returns the largest repository ID, or 0 if there are no repositories.
+func maxRepoID(ctx context.Context) (api.RepoID, error) {
+	var id api.RepoID
+	err := dbconn.Global.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM repo").Scan(&id)
+	return id, err
+}
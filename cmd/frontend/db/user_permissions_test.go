@@ -0,0 +1,186 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/db/dbtesting"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc"
+)
+
+func TestUserPermissions(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	ctx := dbtesting.TestContext(t)
+
+	var userIDs []int32
+	for _, username := range []string{"u1", "u2", "u3"} {
+		user, err := Users.Create(ctx, NewUser{Username: username})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The first user is the site admin, whose permissions are never checked.
+		if err := Users.SetIsSiteAdmin(ctx, user.ID, false); err != nil {
+			t.Fatal(err)
+		}
+		userIDs = append(userIDs, user.ID)
+	}
+
+	gitlab := &api.ExternalRepoSpec{ServiceType: "gitlab", ServiceID: "https://gitlab.example.com/"}
+	var repoIDs []api.RepoID
+	for _, op := range []api.InsertRepoOp{
+		{Name: "gitlab.example.com/r0", Enabled: true, ExternalRepo: &api.ExternalRepoSpec{ID: "r0", ServiceType: gitlab.ServiceType, ServiceID: gitlab.ServiceID}},
+		{Name: "gitlab.example.com/r1", Enabled: true, ExternalRepo: &api.ExternalRepoSpec{ID: "r1", ServiceType: gitlab.ServiceType, ServiceID: gitlab.ServiceID}},
+		{Name: "gitolite.example.com/r2", Enabled: true},
+	} {
+		if err := Repos.Upsert(ctx, op); err != nil {
+			t.Fatal(err)
+		}
+		repo, err := Repos.GetByName(ctx, op.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repoIDs = append(repoIDs, repo.ID)
+	}
+
+	// Postgres stores timestamps with microsecond precision.
+	synced := time.Now().Truncate(time.Microsecond)
+	if err := UserPermissions.Replace(ctx, userIDs[0], authz.Read, []*UserPermissions{{
+		UserID:      userIDs[0],
+		Perm:        authz.Read,
+		ServiceType: gitlab.ServiceType,
+		ServiceID:   gitlab.ServiceID,
+		RepoIDs:     map[api.RepoID]struct{}{repoIDs[0]: {}, 12345: {}}, // 12345 doesn't exist
+		UpdatedAt:   synced,
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := UserPermissions.Replace(ctx, userIDs[1], authz.Read, []*UserPermissions{{
+		UserID:      userIDs[1],
+		Perm:        authz.Read,
+		ServiceType: gitlab.ServiceType,
+		ServiceID:   gitlab.ServiceID,
+		UpdatedAt:   synced.Add(-2 * time.Hour),
+	}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The repositories from the authz provider's code host are listed according to the stored
+	// permissions. The other repository isn't claimed by any authz provider.
+	authz.SetProviders(true, []authz.Provider{&MockAuthzProvider{serviceType: gitlab.ServiceType, serviceID: gitlab.ServiceID}})
+	defer authz.SetProviders(true, nil)
+	listRepos := func(userID int32) []api.RepoName {
+		t.Helper()
+		repos, err := Repos.List(actor.WithActor(ctx, &actor.Actor{UID: userID}), ReposListOptions{Enabled: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sortedRepoNames(repos)
+	}
+	if got, want := listRepos(userIDs[0]), []api.RepoName{"gitlab.example.com/r0", "gitolite.example.com/r2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got repos %v for u1, want %v", got, want)
+	}
+	if got, want := listRepos(userIDs[2]), []api.RepoName{"gitolite.example.com/r2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got repos %v for u3 (never synced), want %v", got, want)
+	}
+
+	// u3 was never synced, u2 was synced before the cutoff and u1 after it.
+	stale, total, err := UserPermissions.ListStale(ctx, synced.Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 || len(stale) != 2 || stale[0].UserID != userIDs[2] || stale[0].SyncedAt != nil || stale[1].UserID != userIDs[1] || stale[1].SyncedAt == nil {
+		t.Errorf("got stale users %s (total %d), want u3 and u2", asJSON(t, stale), total)
+	}
+	if stale, total, err = UserPermissions.ListStale(ctx, synced.Add(-time.Hour), 1); err != nil {
+		t.Fatal(err)
+	} else if total != 2 || len(stale) != 1 {
+		t.Errorf("got %d stale users (total %d) with limit 1, want 1 (total 2)", len(stale), total)
+	}
+
+	// Replacing with no permissions removes the stored permissions.
+	if err := UserPermissions.Replace(ctx, userIDs[0], authz.Read, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := listRepos(userIDs[0]), []api.RepoName{"gitolite.example.com/r2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got repos %v for u1 after removing its permissions, want %v", got, want)
+	}
+}
+
+func TestUserPermissions_SyncNewRepos(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	ctx := dbtesting.TestContext(t)
+
+	var userID int32
+	for _, username := range []string{"admin", "u"} {
+		user, err := Users.Create(ctx, NewUser{Username: username})
+		if err != nil {
+			t.Fatal(err)
+		}
+		userID = user.ID
+	}
+
+	gitlab := &api.ExternalRepoSpec{ServiceType: "gitlab", ServiceID: "https://gitlab.example.com/"}
+	createRepo := func(name api.RepoName) {
+		t.Helper()
+		if err := Repos.Upsert(ctx, api.InsertRepoOp{Name: name, Enabled: true, ExternalRepo: &api.ExternalRepoSpec{ID: string(name), ServiceType: gitlab.ServiceType, ServiceID: gitlab.ServiceID}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	listRepos := func() []api.RepoName {
+		t.Helper()
+		repos, err := Repos.List(actor.WithActor(ctx, &actor.Actor{UID: userID}), ReposListOptions{Enabled: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sortedRepoNames(repos)
+	}
+
+	// The user has no account on the code host, so the permissions for anonymous users apply.
+	authz.SetProviders(true, []authz.Provider{&MockAuthzProvider{
+		serviceType: gitlab.ServiceType,
+		serviceID:   gitlab.ServiceID,
+		perms: map[extsvc.ExternalAccount]map[api.RepoName]map[authz.Perm]bool{
+			{}: {
+				"gitlab.example.com/r0": {authz.Read: true},
+				"gitlab.example.com/r1": {authz.Read: true},
+			},
+		},
+		repos: map[api.RepoName]struct{}{
+			"gitlab.example.com/r0": {},
+			"gitlab.example.com/r1": {},
+			"gitlab.example.com/r2": {},
+		},
+	}})
+	defer authz.SetProviders(true, nil)
+
+	createRepo("gitlab.example.com/r0")
+	if err := UserPermissions.Sync(ctx, userID); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := listRepos(), []api.RepoName{"gitlab.example.com/r0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got repos %v after sync, want %v", got, want)
+	}
+
+	// Repositories added after the sync are only accessible after the new repositories are synced.
+	createRepo("gitlab.example.com/r1")
+	createRepo("gitlab.example.com/r2")
+	if got, want := listRepos(), []api.RepoName{"gitlab.example.com/r0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got repos %v before syncing new repos, want %v", got, want)
+	}
+	for i := 0; i < 2; i++ {
+		if err := UserPermissions.SyncNewRepos(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got, want := listRepos(), []api.RepoName{"gitlab.example.com/r0", "gitlab.example.com/r1"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got repos %v after syncing new repos (%d times), want %v", got, i+1, want)
+		}
+	}
+}
@@ -416,6 +416,47 @@ func (u *users) Delete(ctx context.Context, id int32) error {
 	return nil
 }
 
+// Undelete restores a soft-deleted user and reserves its username again. The data that Delete
+// removed or soft-deleted along with the user (such as its email addresses, access tokens and
+// external accounts) is not restored.
+func (u *users) Undelete(ctx context.Context, id int32) (err error) {
+	if Mocks.Users.Undelete != nil {
+		return Mocks.Users.Undelete(id)
+	}
+
+	tx, err := dbconn.Global.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			rollErr := tx.Rollback()
+			if rollErr != nil {
+				err = multierror.Append(err, rollErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	var username string
+	if err := tx.QueryRowContext(ctx, "UPDATE users SET deleted_at=NULL, updated_at=now() WHERE id=$1 AND deleted_at IS NOT NULL RETURNING username", id).Scan(&username); err != nil {
+		if err == sql.ErrNoRows {
+			return userNotFoundErr{args: []interface{}{id}}
+		}
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == "users_username" {
+			return errCannotCreateUser{errorCodeUsernameExists}
+		}
+		return err
+	}
+
+	// Reserve username in shared users+orgs namespace (Delete released it).
+	if _, err := tx.ExecContext(ctx, "INSERT INTO names(name, user_id) VALUES($1, $2)", username, id); err != nil {
+		return errCannotCreateUser{errorCodeUsernameExists}
+	}
+	return nil
+}
+
 func (u *users) HardDelete(ctx context.Context, id int32) error {
 	// Wrap in transaction because we delete from multiple tables.
 	tx, err := dbconn.Global.BeginTx(ctx, nil)
@@ -599,6 +640,12 @@ type UsersListOptions struct {
 
 	Tag string // only include users with this tag
 
+	Username string // only include users with this exact username
+
+	// IncludeDeleted includes soft-deleted users (with DeletedAt set). A username is unique only
+	// among users that are not deleted.
+	IncludeDeleted bool
+
 	*LimitOffset
 }
 
@@ -624,7 +671,9 @@ func (u *users) List(ctx context.Context, opt *UsersListOptions) (_ []*types.Use
 
 func (*users) listSQL(opt UsersListOptions) (conds []*sqlf.Query) {
 	conds = []*sqlf.Query{sqlf.Sprintf("TRUE")}
-	conds = append(conds, sqlf.Sprintf("deleted_at IS NULL"))
+	if !opt.IncludeDeleted {
+		conds = append(conds, sqlf.Sprintf("deleted_at IS NULL"))
+	}
 	if opt.Query != "" {
 		query := "%" + opt.Query + "%"
 		conds = append(conds, sqlf.Sprintf("(username ILIKE %s OR display_name ILIKE %s)", query, query))
@@ -644,6 +693,9 @@ func (*users) listSQL(opt UsersListOptions) (conds []*sqlf.Query) {
 	if opt.Tag != "" {
 		conds = append(conds, sqlf.Sprintf("%s::text = ANY(u.tags)", opt.Tag))
 	}
+	if opt.Username != "" {
+		conds = append(conds, sqlf.Sprintf("u.username=%s", opt.Username))
+	}
 	return conds
 }
 
@@ -660,7 +712,7 @@ func (u *users) getOneBySQL(ctx context.Context, query string, args ...interface
 
 // getBySQL returns users matching the SQL query, if any exist.
 func (*users) getBySQL(ctx context.Context, query string, args ...interface{}) ([]*types.User, error) {
-	rows, err := dbconn.Global.QueryContext(ctx, "SELECT u.id, u.username, u.display_name, u.avatar_url, u.created_at, u.updated_at, u.site_admin, u.tags FROM users u "+query, args...)
+	rows, err := dbconn.Global.QueryContext(ctx, "SELECT u.id, u.username, u.display_name, u.avatar_url, u.created_at, u.updated_at, u.site_admin, u.tags, u.deleted_at FROM users u "+query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -670,7 +722,7 @@ func (*users) getBySQL(ctx context.Context, query string, args ...interface{}) (
 	for rows.Next() {
 		var u types.User
 		var displayName, avatarURL sql.NullString
-		err := rows.Scan(&u.ID, &u.Username, &displayName, &avatarURL, &u.CreatedAt, &u.UpdatedAt, &u.SiteAdmin, pq.Array(&u.Tags))
+		err := rows.Scan(&u.ID, &u.Username, &displayName, &avatarURL, &u.CreatedAt, &u.UpdatedAt, &u.SiteAdmin, pq.Array(&u.Tags), &u.DeletedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -499,6 +499,58 @@ func TestUsers_Delete(t *testing.T) {
 	}
 }
 
+func TestUsers_Undelete(t *testing.T) {
+	if testing.Short() {
+		t.Skip()
+	}
+	ctx := dbtesting.TestContext(t)
+
+	user, err := Users.Create(ctx, NewUser{Username: "u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Users.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Deleted users are only listed with IncludeDeleted.
+	if users, err := Users.List(ctx, &UsersListOptions{Username: "u"}); err != nil {
+		t.Fatal(err)
+	} else if len(users) != 0 {
+		t.Errorf("got %d users, want 0", len(users))
+	}
+	if users, err := Users.List(ctx, &UsersListOptions{Username: "u", IncludeDeleted: true}); err != nil {
+		t.Fatal(err)
+	} else if len(users) != 1 || users[0].ID != user.ID || users[0].DeletedAt == nil {
+		t.Errorf("got users %+v, want deleted user %d", users, user.ID)
+	}
+
+	if err := Users.Undelete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Users.GetByID(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	} else if got.DeletedAt != nil {
+		t.Errorf("got DeletedAt %v, want nil", got.DeletedAt)
+	}
+
+	// Can't undelete a user that isn't deleted.
+	if err := Users.Undelete(ctx, user.ID); !errcode.IsNotFound(err) {
+		t.Errorf("got error %v, want ErrUserNotFound", err)
+	}
+
+	// Can't undelete a user whose username was taken in the meantime.
+	if err := Users.Delete(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Users.Create(ctx, NewUser{Username: "u"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Users.Undelete(ctx, user.ID); !IsUsernameExists(err) {
+		t.Errorf("got error %v, want username exists error", err)
+	}
+}
+
 func normalizeUsers(users []*types.User) []*types.User {
 	for _, u := range users {
 		u.CreatedAt = u.CreatedAt.Local().Round(time.Second)
@@ -53,8 +53,9 @@ func (r *schemaResolver) CreateAccessToken(ctx context.Context, args *createAcce
 		switch scope {
 		case authz.ScopeUserAll:
 			hasUserAllScope = true
-		case authz.ScopeSiteAdminSudo:
-			// 🚨 SECURITY: Only site admins may create a token with the "site-admin:sudo" scope.
+		case authz.ScopeSiteAdminSudo, authz.ScopeSiteAdminSCIM:
+			// 🚨 SECURITY: Only site admins may create a token with the "site-admin:sudo" or
+			// "site-admin:scim" scope.
 			if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
 				return nil, err
 			}
@@ -0,0 +1,195 @@
+package graphqlbackend
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gituri"
+	"github.com/sourcegraph/sourcegraph/pkg/symbols/protocol"
+)
+
+// The code navigation below is heuristic and language-agnostic: definitions are
+// looked up by name in the ctags index of the symbols service, and references are
+// word matches found by searcher. Neither understands scoping, so results may
+// include false positives.
+
+const (
+	// maxDefinitions is the maximum number of candidate definitions returned by
+	// DefinitionAt.
+	maxDefinitions = 50
+
+	// maxReferenceFiles is the maximum number of files searched for references
+	// by ReferencesTo.
+	maxReferenceFiles = 500
+)
+
+var mockListTags func(ctx context.Context, args protocol.SearchArgs) ([]protocol.Symbol, error)
+
+func listTags(ctx context.Context, args protocol.SearchArgs) ([]protocol.Symbol, error) {
+	if mockListTags != nil {
+		return mockListTags(ctx, args)
+	}
+	return backend.Symbols.ListTags(ctx, args)
+}
+
+func (r *gitTreeEntryResolver) DefinitionAt(ctx context.Context, args *struct {
+	Line      int32
+	Character int32
+}) ([]*locationResolver, error) {
+	content, err := r.Content(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return definitionsAt(ctx, r.commit, r.path, content, int(args.Line), int(args.Character))
+}
+
+// definitionsAt returns the locations of the symbols named by the identifier at
+// the given zero-based line and character in a file's content. Definitions in the
+// same file are listed first, followed by definitions in files with the same
+// extension, so that the most likely candidates come first.
+func definitionsAt(ctx context.Context, commit *gitCommitResolver, filePath, content string, line, character int) (res []*locationResolver, err error) {
+	name := identifierAt(content, line, character)
+	if name == "" {
+		return []*locationResolver{}, nil
+	}
+
+	ctx, done := context.WithTimeout(ctx, 5*time.Second)
+	defer done()
+	defer func() {
+		if ctx.Err() != nil && len(res) == 0 {
+			err = errors.New("processing symbols is taking longer than expected. Try again in a while")
+		}
+	}()
+
+	symbols, err := listTags(ctx, protocol.SearchArgs{
+		Repo:            commit.repo.repo.Name,
+		CommitID:        api.CommitID(commit.oid),
+		Query:           name,
+		Match:           protocol.MatchExact,
+		IsCaseSensitive: true,
+		First:           maxDefinitions,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rank := func(s protocol.Symbol) int {
+		switch {
+		case s.Path == filePath:
+			return 0
+		case path.Ext(s.Path) == path.Ext(filePath):
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(symbols, func(i, j int) bool { return rank(symbols[i]) < rank(symbols[j]) })
+
+	baseURI, err := gituri.Parse("git://" + string(commit.repo.repo.Name) + "?" + string(commit.oid))
+	if err != nil {
+		return nil, err
+	}
+	locations := make([]*locationResolver, 0, len(symbols))
+	for _, symbol := range symbols {
+		resolver := toSymbolResolver(symbolToLSPSymbolInformation(symbol, baseURI), strings.ToLower(symbol.Language), commit)
+		if resolver == nil {
+			continue
+		}
+		locations = append(locations, resolver.location)
+	}
+	return locations, nil
+}
+
+// identifierAt returns the identifier (a run of letters, digits and underscores)
+// at the zero-based line and character in content. A position just past the end
+// of an identifier refers to that identifier. If there is no identifier at the
+// position, the empty string is returned.
+func identifierAt(content string, line, character int) string {
+	lines := strings.Split(content, "\n")
+	if line < 0 || line >= len(lines) || character < 0 {
+		return ""
+	}
+	runes := []rune(strings.TrimSuffix(lines[line], "\r"))
+	if character >= len(runes) || !isIdentifierRune(runes[character]) {
+		character--
+	}
+	if character < 0 || character >= len(runes) || !isIdentifierRune(runes[character]) {
+		return ""
+	}
+	start, end := character, character+1
+	for start > 0 && isIdentifierRune(runes[start-1]) {
+		start--
+	}
+	for end < len(runes) && isIdentifierRune(runes[end]) {
+		end++
+	}
+	if unicode.IsDigit(runes[start]) {
+		// A number, not an identifier.
+		return ""
+	}
+	return string(runes[start:end])
+}
+
+func isIdentifierRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (r *gitTreeEntryResolver) ReferencesTo(ctx context.Context, args *struct {
+	Symbol string
+}) ([]*locationResolver, error) {
+	return referencesTo(ctx, r.commit, args.Symbol)
+}
+
+// referencesTo returns the locations of whole-word, case-sensitive matches of
+// name in the repository at commit.
+func referencesTo(ctx context.Context, commit *gitCommitResolver, name string) ([]*locationResolver, error) {
+	if strings.TrimSpace(name) == "" {
+		return []*locationResolver{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	gitserverRepo, err := backend.CachedGitRepo(ctx, commit.repo.repo)
+	if err != nil {
+		return nil, err
+	}
+	p := &search.PatternInfo{
+		Pattern:               name,
+		IsWordMatch:           true,
+		IsCaseSensitive:       true,
+		FileMatchLimit:        maxReferenceFiles,
+		PatternMatchesContent: true,
+	}
+	matches, _, err := searchFilesInRepo(ctx, commit.repo.repo, *gitserverRepo, string(commit.oid), p, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	var locations []*locationResolver
+	for _, fm := range matches {
+		resource := &gitTreeEntryResolver{
+			commit: commit,
+			path:   fm.JPath,
+			stat:   createFileInfo(fm.JPath, false),
+		}
+		for _, lm := range fm.JLineMatches {
+			for _, lspRange := range previewRanges(lm.JPreview, lm.JLineNumber, lm.JOffsetAndLengths) {
+				lspRange := lspRange // copy
+				locations = append(locations, &locationResolver{resource: resource, lspRange: &lspRange})
+			}
+		}
+	}
+	if locations == nil {
+		locations = []*locationResolver{}
+	}
+	return locations, nil
+}
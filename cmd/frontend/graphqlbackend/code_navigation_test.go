@@ -0,0 +1,117 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/symbols/protocol"
+)
+
+func TestIdentifierAt(t *testing.T) {
+	content := "func main() {\r\n\tx := foo_bar(42)\n}"
+	tests := []struct {
+		line, character int
+		want            string
+	}{
+		{line: 0, character: 0, want: "func"},
+		{line: 0, character: 6, want: "main"},
+		{line: 0, character: 9, want: "main"}, // just past the end
+		{line: 0, character: 10, want: ""},
+		{line: 1, character: 6, want: "foo_bar"},
+		{line: 1, character: 12, want: "foo_bar"},
+		{line: 1, character: 15, want: ""}, // number
+		{line: 2, character: 0, want: ""},
+		{line: 3, character: 0, want: ""},
+		{line: 0, character: 100, want: ""},
+		{line: -1, character: 0, want: ""},
+	}
+	for _, test := range tests {
+		if got := identifierAt(content, test.line, test.character); got != test.want {
+			t.Errorf("%d:%d: got %q, want %q", test.line, test.character, got, test.want)
+		}
+	}
+}
+
+func TestDefinitionsAt(t *testing.T) {
+	mockListTags = func(_ context.Context, args protocol.SearchArgs) ([]protocol.Symbol, error) {
+		want := protocol.SearchArgs{Repo: "repo", CommitID: "c", Query: "Serve", Match: protocol.MatchExact, IsCaseSensitive: true, First: maxDefinitions}
+		if !reflect.DeepEqual(args, want) {
+			t.Errorf("got args %+v, want %+v", args, want)
+		}
+		return []protocol.Symbol{
+			{Name: "Serve", Path: "b.ts", Line: 1, Language: "TypeScript"},
+			{Name: "Serve", Path: "b.go", Line: 2, Language: "Go", Pattern: "/^func Serve() {$/"},
+			{Name: "Serve", Path: "a.go", Line: 3, Language: "Go"},
+		}, nil
+	}
+	defer func() { mockListTags = nil }()
+
+	commit := &gitCommitResolver{repo: &repositoryResolver{repo: &types.Repo{Name: "repo"}}, oid: "c"}
+	locations, err := definitionsAt(context.Background(), commit, "a.go", "s.Serve()", 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, location := range locations {
+		got = append(got, location.CanonicalURL())
+	}
+	want := []string{
+		"/repo@c/-/blob/a.go#L3:1-3:6",
+		"/repo@c/-/blob/b.go#L2:6-2:11",
+		"/repo@c/-/blob/b.ts#L1:1-1:6",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	locations, err = definitionsAt(context.Background(), commit, "a.go", "s.Serve()", 0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locations) != 0 {
+		t.Errorf("got %d locations for a position without an identifier, want none", len(locations))
+	}
+}
+
+func TestReferencesTo(t *testing.T) {
+	mockSearchFilesInRepo = func(_ context.Context, repo *types.Repo, _ gitserver.Repo, rev string, info *search.PatternInfo, _ time.Duration) ([]*fileMatchResolver, bool, error) {
+		if rev != "c" {
+			t.Errorf("got rev %q, want %q", rev, "c")
+		}
+		if info.Pattern != "Serve" || !info.IsWordMatch || !info.IsCaseSensitive || info.IsRegExp {
+			t.Errorf("got pattern %+v, want case-sensitive word match of Serve", info)
+		}
+		return []*fileMatchResolver{
+			{JPath: "a.go", JLineMatches: []*lineMatch{
+				{JLineNumber: 4, JPreview: "s.Serve(); s.Serve()", JOffsetAndLengths: [][2]int32{{2, 5}, {13, 5}}},
+			}},
+			{JPath: "b.go", JLineMatches: []*lineMatch{
+				{JLineNumber: 0, JPreview: "Serve", JOffsetAndLengths: [][2]int32{{0, 5}}},
+			}},
+		}, false, nil
+	}
+	defer func() { mockSearchFilesInRepo = nil }()
+
+	commit := &gitCommitResolver{repo: &repositoryResolver{repo: &types.Repo{Name: "repo"}}, oid: "c"}
+	locations, err := referencesTo(context.Background(), commit, "Serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, location := range locations {
+		got = append(got, location.CanonicalURL())
+	}
+	want := []string{
+		"/repo@c/-/blob/a.go#L5:3-5:8",
+		"/repo@c/-/blob/a.go#L5:14-5:19",
+		"/repo@c/-/blob/b.go#L1:1-1:6",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
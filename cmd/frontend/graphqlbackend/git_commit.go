@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend/externallink"
@@ -39,6 +40,13 @@ type gitCommitResolver struct {
 	committer *signatureResolver
 	message   string
 	parents   []api.CommitID
+
+	// signature is the commit's signature. Verifying signatures is slow, so unless the commit was
+	// loaded with its signature (signatureLoaded), it is loaded when it is first requested.
+	signatureLoaded bool
+	signatureOnce   sync.Once
+	signature       *git.CommitSignature
+	signatureErr    error
 }
 
 func toGitCommitResolver(repo *repositoryResolver, commit *git.Commit) *gitCommitResolver {
@@ -50,6 +58,7 @@ func toGitCommitResolver(repo *repositoryResolver, commit *git.Commit) *gitCommi
 		committer: toSignatureResolver(commit.Committer),
 		message:   commit.Message,
 		parents:   commit.Parents,
+		signature: commit.Signature,
 	}
 }
 
@@ -88,6 +97,36 @@ func (r *gitCommitResolver) Body() *string {
 	return &body
 }
 
+func (r *gitCommitResolver) Signature(ctx context.Context) (*gitCommitSignatureResolver, error) {
+	r.signatureOnce.Do(func() {
+		if !r.signatureLoaded {
+			r.signature, r.signatureErr = r.loadSignature(ctx)
+		}
+	})
+	if r.signatureErr != nil {
+		return nil, r.signatureErr
+	}
+	if r.signature == nil {
+		return nil, nil
+	}
+	return &gitCommitSignatureResolver{signature: *r.signature}, nil
+}
+
+func (r *gitCommitResolver) loadSignature(ctx context.Context) (*git.CommitSignature, error) {
+	cachedRepo, err := backend.CachedGitRepo(ctx, r.repo.repo)
+	if err != nil {
+		return nil, err
+	}
+	commits, err := git.Commits(ctx, *cachedRepo, git.CommitsOptions{Range: string(r.oid), N: 1, Signatures: true})
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, nil
+	}
+	return commits[0].Signature, nil
+}
+
 func (r *gitCommitResolver) Parents(ctx context.Context) ([]*gitCommitResolver, error) {
 	resolvers := make([]*gitCommitResolver, len(r.parents))
 	for i, parent := range r.parents {
@@ -0,0 +1,20 @@
+package graphqlbackend
+
+import "github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+
+type gitCommitSignatureResolver struct {
+	signature git.CommitSignature
+}
+
+func (r *gitCommitSignatureResolver) Status() string { return string(r.signature.Status) }
+
+func (r *gitCommitSignatureResolver) Verified() bool {
+	return r.signature.Status == git.SignatureVerified
+}
+
+func (r *gitCommitSignatureResolver) KeyID() *string {
+	if r.signature.KeyID == "" {
+		return nil
+	}
+	return &r.signature.KeyID
+}
@@ -1,6 +1,12 @@
 package graphqlbackend
 
-import "github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+import (
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+)
 
 type gitSubmoduleResolver struct {
 	submodule git.Submodule
@@ -17,3 +23,32 @@ func (r *gitSubmoduleResolver) Commit() string {
 func (r *gitSubmoduleResolver) Path() string {
 	return r.submodule.Path
 }
+
+func (r *gitSubmoduleResolver) Repository(ctx context.Context) (*repositoryResolver, error) {
+	repoName, err := reposourceCloneURLToRepoName(ctx, r.submodule.URL)
+	if err != nil {
+		return nil, err
+	}
+	if repoName == "" {
+		// No code host matches the submodule's clone URL.
+		return nil, nil
+	}
+	repo, err := backend.Repos.GetByName(ctx, repoName)
+	if err != nil {
+		if errcode.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &repositoryResolver{repo: repo}, nil
+}
+
+func (r *gitSubmoduleResolver) GitCommit(ctx context.Context) (*gitCommitResolver, error) {
+	repo, err := r.Repository(ctx)
+	if repo == nil || err != nil {
+		return nil, err
+	}
+	// Resolving the commit makes gitserver fetch it if the mirror does not
+	// have it yet.
+	return repo.Commit(ctx, &repositoryCommitArgs{Rev: string(r.submodule.CommitID)})
+}
@@ -289,7 +289,9 @@ func (r *fileDiffResolver) OldFile() *gitTreeEntryResolver {
 }
 
 func (r *fileDiffResolver) NewFile() *gitTreeEntryResolver {
-	if diffPathOrNull(r.fileDiff.NewName) == nil {
+	if diffPathOrNull(r.fileDiff.NewName) == nil || r.cmp.head == nil {
+		// The head is nil when the new file is not in any commit (such as
+		// in a search replace preview).
 		return nil
 	}
 	return &gitTreeEntryResolver{
@@ -0,0 +1,94 @@
+package graphqlbackend
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+)
+
+func (r *repositoryMirrorInfoResolver) DiskUsage(ctx context.Context) (*repositoryDiskUsageResolver, error) {
+	info, err := r.gitserverRepoInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.Size == nil {
+		return nil, nil
+	}
+	return &repositoryDiskUsageResolver{size: *info.Size}, nil
+}
+
+func (r *siteResolver) LargestRepositories(ctx context.Context, args *struct {
+	First *int32
+}) ([]*repositoryDiskUsageEntryResolver, error) {
+	// 🚨 SECURITY: Only site admins can list the disk usage of all repositories, which includes
+	// repositories that the viewer may not have access to.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	limit := 20
+	if args.First != nil {
+		if *args.First < 0 {
+			return nil, errors.New("first must not be negative")
+		}
+		limit = int(*args.First)
+	}
+	stats, err := gitserver.DefaultClient.ReposStats(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*repositoryDiskUsageEntryResolver, 0, len(stats))
+	for _, s := range stats {
+		entries = append(entries, &repositoryDiskUsageEntryResolver{name: s.Repo, size: s.Size})
+	}
+	return entries, nil
+}
+
+type repositoryDiskUsageEntryResolver struct {
+	name api.RepoName
+	size protocol.RepoSize
+}
+
+func (r *repositoryDiskUsageEntryResolver) Name() string { return string(r.name) }
+
+func (r *repositoryDiskUsageEntryResolver) Repository(ctx context.Context) (*repositoryResolver, error) {
+	// The repository may have been deleted from the database, but not yet
+	// from gitserver.
+	repo, err := backend.Repos.GetByName(ctx, r.name)
+	if err != nil {
+		if errcode.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &repositoryResolver{repo: repo}, nil
+}
+
+func (r *repositoryDiskUsageEntryResolver) DiskUsage() *repositoryDiskUsageResolver {
+	return &repositoryDiskUsageResolver{size: r.size}
+}
+
+type repositoryDiskUsageResolver struct {
+	size protocol.RepoSize
+}
+
+func (r *repositoryDiskUsageResolver) TotalBytes() float64 { return float64(r.size.TotalBytes()) }
+
+func (r *repositoryDiskUsageResolver) PackBytes() float64 { return float64(r.size.PackBytes) }
+
+func (r *repositoryDiskUsageResolver) LooseObjectBytes() float64 { return float64(r.size.LooseBytes) }
+
+func (r *repositoryDiskUsageResolver) WorktreeBytes() float64 { return float64(r.size.WorktreeBytes) }
+
+func (r *repositoryDiskUsageResolver) OtherBytes() float64 { return float64(r.size.OtherBytes) }
+
+func (r *repositoryDiskUsageResolver) ComputedAt() string {
+	return r.size.ComputedAt.Format(time.RFC3339)
+}
@@ -0,0 +1,118 @@
+package graphqlbackend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/graphql-go/gqltesting"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+)
+
+func TestSiteLargestRepositories(t *testing.T) {
+	resetMocks()
+	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{SiteAdmin: true}, nil
+	}
+	backend.Mocks.Repos.GetByName = func(ctx context.Context, name api.RepoName) (*types.Repo, error) {
+		if name == "deleted/repo" {
+			return nil, &errcode.Mock{IsNotFound: true}
+		}
+		return &types.Repo{Name: name}, nil
+	}
+
+	computedAt := time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)
+	gitserver.MockReposStats = func(limit int) ([]*protocol.RepoStats, error) {
+		if limit != 2 {
+			t.Errorf("got limit %d, want 2", limit)
+		}
+		return []*protocol.RepoStats{
+			{Repo: "big/repo", Size: protocol.RepoSize{PackBytes: 3 << 30, LooseBytes: 2, WorktreeBytes: 1, ComputedAt: computedAt}},
+			{Repo: "deleted/repo", Size: protocol.RepoSize{PackBytes: 10, OtherBytes: 5, ComputedAt: computedAt}},
+		}, nil
+	}
+	defer func() { gitserver.MockReposStats = nil }()
+
+	gqltesting.RunTests(t, []*gqltesting.Test{
+		{
+			Schema: GraphQLSchema,
+			Query: `
+				{
+					site {
+						largestRepositories(first: 2) {
+							name
+							repository {
+								name
+							}
+							diskUsage {
+								totalBytes
+								packBytes
+								looseObjectBytes
+								worktreeBytes
+								otherBytes
+								computedAt
+							}
+						}
+					}
+				}
+			`,
+			ExpectedResult: `
+				{
+					"site": {
+						"largestRepositories": [
+							{
+								"name": "big/repo",
+								"repository": {
+									"name": "big/repo"
+								},
+								"diskUsage": {
+									"totalBytes": 3221225475,
+									"packBytes": 3221225472,
+									"looseObjectBytes": 2,
+									"worktreeBytes": 1,
+									"otherBytes": 0,
+									"computedAt": "2019-01-02T03:04:05Z"
+								}
+							},
+							{
+								"name": "deleted/repo",
+								"repository": null,
+								"diskUsage": {
+									"totalBytes": 15,
+									"packBytes": 10,
+									"looseObjectBytes": 0,
+									"worktreeBytes": 0,
+									"otherBytes": 5,
+									"computedAt": "2019-01-02T03:04:05Z"
+								}
+							}
+						]
+					}
+				}
+			`,
+		},
+	})
+}
+
+func TestSiteLargestRepositories_negativeFirst(t *testing.T) {
+	resetMocks()
+	db.Mocks.Users.GetByCurrentAuthUser = func(context.Context) (*types.User, error) {
+		return &types.User{SiteAdmin: true}, nil
+	}
+	gitserver.MockReposStats = func(limit int) ([]*protocol.RepoStats, error) {
+		t.Errorf("unexpected call with limit %d", limit)
+		return nil, nil
+	}
+	defer func() { gitserver.MockReposStats = nil }()
+
+	first := int32(-1)
+	if _, err := (&siteResolver{}).LargestRepositories(context.Background(), &struct{ First *int32 }{First: &first}); err == nil {
+		t.Error("got nil error for a negative first")
+	}
+}
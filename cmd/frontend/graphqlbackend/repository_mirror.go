@@ -3,6 +3,7 @@ package graphqlbackend
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"time"
 
@@ -174,6 +175,30 @@ func (r *updateQueueResolver) Total() int32 {
 	return int32(r.queue.Total)
 }
 
+func (r *updateQueueResolver) Priority() string {
+	return strings.ToUpper(r.queue.Priority)
+}
+
+func (r *updateQueueResolver) ExternalService(ctx context.Context) (*externalServiceResolver, error) {
+	if r.queue.ExternalServiceID == 0 {
+		return nil, nil
+	}
+	return externalServiceByID(ctx, marshalExternalServiceID(r.queue.ExternalServiceID))
+}
+
+func (r *updateQueueResolver) WaitingFor() *string {
+	var reason string
+	switch r.queue.WaitingFor {
+	case repoupdaterprotocol.RepoQueueWaitingForExternalServiceConcurrency:
+		reason = "EXTERNAL_SERVICE_CONCURRENCY"
+	case repoupdaterprotocol.RepoQueueWaitingForExternalServiceRateLimit:
+		reason = "EXTERNAL_SERVICE_RATE_LIMIT"
+	default:
+		return nil
+	}
+	return &reason
+}
+
 func (r *schemaResolver) CheckMirrorRepositoryConnection(ctx context.Context, args *struct {
 	Repository *graphql.ID
 	Name       *string
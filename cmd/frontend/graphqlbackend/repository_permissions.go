@@ -0,0 +1,119 @@
+package graphqlbackend
+
+import (
+	"context"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+)
+
+func (r *repositoryResolver) ExplicitPermissions(ctx context.Context) (*repositoryPermissionsResolver, error) {
+	// 🚨 SECURITY: Only site admins can view repository permissions.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	rp, err := db.RepoPermissions.Get(ctx, r.repo.ID, authz.Read)
+	if rp == nil || err != nil {
+		return nil, err
+	}
+	return &repositoryPermissionsResolver{perms: rp}, nil
+}
+
+type repositoryPermissionsResolver struct {
+	perms *db.RepoPermissions
+}
+
+func (r *repositoryPermissionsResolver) Users(ctx context.Context) ([]*UserResolver, error) {
+	users := make([]*UserResolver, 0, len(r.perms.UserIDs))
+	for _, id := range r.perms.UserIDs {
+		user, err := UserByIDInt32(ctx, id)
+		if errcode.IsNotFound(err) {
+			continue // the user was deleted
+		}
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (r *repositoryPermissionsResolver) Organizations(ctx context.Context) ([]*OrgResolver, error) {
+	orgs := make([]*OrgResolver, 0, len(r.perms.OrgIDs))
+	for _, id := range r.perms.OrgIDs {
+		org, err := OrgByIDInt32(ctx, id)
+		if _, ok := err.(*db.OrgNotFoundError); ok {
+			continue // the organization was deleted
+		}
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, nil
+}
+
+func (*schemaResolver) SetRepositoryPermissions(ctx context.Context, args *struct {
+	Repository    graphql.ID
+	Users         []graphql.ID
+	Organizations []graphql.ID
+}) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins can set repository permissions.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	repo, err := repositoryByID(ctx, args.Repository)
+	if err != nil {
+		return nil, err
+	}
+
+	rp := &db.RepoPermissions{RepoID: repo.repo.ID, Perm: authz.Read}
+	for _, id := range args.Users {
+		userID, err := UnmarshalUserID(id)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := db.Users.GetByID(ctx, userID); err != nil {
+			return nil, err
+		}
+		rp.UserIDs = append(rp.UserIDs, userID)
+	}
+	for _, id := range args.Organizations {
+		orgID, err := UnmarshalOrgID(id)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := db.Orgs.GetByID(ctx, orgID); err != nil {
+			return nil, err
+		}
+		rp.OrgIDs = append(rp.OrgIDs, orgID)
+	}
+
+	if err := db.RepoPermissions.Set(ctx, rp); err != nil {
+		return nil, err
+	}
+	return &EmptyResponse{}, nil
+}
+
+func (*schemaResolver) DeleteRepositoryPermissions(ctx context.Context, args *struct {
+	Repository graphql.ID
+}) (*EmptyResponse, error) {
+	// 🚨 SECURITY: Only site admins can delete repository permissions.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	repo, err := repositoryByID(ctx, args.Repository)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.RepoPermissions.Delete(ctx, repo.repo.ID, authz.Read); err != nil {
+		return nil, err
+	}
+	return &EmptyResponse{}, nil
+}
@@ -0,0 +1,55 @@
+package graphqlbackend
+
+import (
+	"context"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+)
+
+func (r *repositoryMirrorInfoResolver) PushMirror(ctx context.Context) (*pushMirrorStatusResolver, error) {
+	// 🚨 SECURITY: Only site admins can see where repositories are pushed to and the errors of
+	// pushes, which may contain sensitive information about the push mirror.
+	if err := backend.CheckCurrentUserIsSiteAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	info, err := r.gitserverRepoInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.PushMirror == nil {
+		return nil, nil
+	}
+	return &pushMirrorStatusResolver{status: *info.PushMirror}, nil
+}
+
+type pushMirrorStatusResolver struct {
+	status protocol.PushMirrorStatus
+}
+
+func (r *pushMirrorStatusResolver) URL() string { return r.status.URL }
+
+func (r *pushMirrorStatusResolver) LastAttemptedAt() *string {
+	if r.status.LastAttempted == nil {
+		return nil
+	}
+	t := r.status.LastAttempted.Format(time.RFC3339)
+	return &t
+}
+
+func (r *pushMirrorStatusResolver) LastPushedAt() *string {
+	if r.status.LastPushed == nil {
+		return nil
+	}
+	t := r.status.LastPushed.Format(time.RFC3339)
+	return &t
+}
+
+func (r *pushMirrorStatusResolver) LastError() *string {
+	if r.status.LastError == "" {
+		return nil
+	}
+	return &r.status.LastError
+}
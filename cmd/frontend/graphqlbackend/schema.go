@@ -52,6 +52,16 @@ type Mutation {
     #
     # Only site admins may perform this mutation.
     setRepositoryEnabled(repository: ID!, enabled: Boolean!): EmptyResponse
+    # Sets the users and organizations that can read the repository. Other users (except site admins)
+    # can't read it. This is intended for repositories whose code host is not a source of repository
+    # permissions, such as Gitolite repositories; permissions enforced by a code host take precedence.
+    #
+    # Only site admins may perform this mutation.
+    setRepositoryPermissions(repository: ID!, users: [ID!]!, organizations: [ID!]!): EmptyResponse!
+    # Removes the permissions set with setRepositoryPermissions from the repository.
+    #
+    # Only site admins may perform this mutation.
+    deleteRepositoryPermissions(repository: ID!): EmptyResponse!
     # Enables or disables all site repositories.
     #
     # Only site admins may perform this mutation.
@@ -102,6 +112,11 @@ type Mutation {
     #
     # Only site admins may perform this mutation.
     randomizeUserPassword(user: ID!): RandomizeUserPasswordResult!
+    # Syncs the user's repository permissions from the configured authorization providers now,
+    # instead of waiting for the periodic background sync.
+    #
+    # Only site admins may perform this mutation.
+    syncUserPermissions(user: ID!): EmptyResponse!
     # Adds an email address to the user's account. The email address will be marked as unverified until the user
     # has followed the email verification process.
     #
@@ -143,6 +158,8 @@ type Mutation {
     # - "user:all": Full control of all resources accessible to the user account.
     # - "site-admin:sudo": Ability to perform any action as any other user. (Only site admins may create tokens
     #   with this scope.)
+    # - "site-admin:scim": Ability to provision users and organizations with the SCIM API. (Only site admins may
+    #   create tokens with this scope.)
     #
     # Only the user or site admins may perform this mutation.
     createAccessToken(user: ID!, scopes: [String!]!, note: String!): CreateAccessTokenResult!
@@ -812,6 +829,39 @@ type Search {
     # cached and thus quicker to query. Useful for e.g. querying sparkline
     # data.
     stats: SearchResultsStats!
+    # A preview of replacing the matches of the query's pattern in file contents with the replacement, which may
+    # refer to the pattern's capture groups as $1 or ${name}. Nothing is changed. The query must have a single
+    # pattern and may not use boolean operators or patterntype:structural.
+    replacePreview(replacement: String!): SearchReplacePreview!
+}
+
+# A preview of replacing the matches of a search query.
+type SearchReplacePreview {
+    # The changes in each repository that has matches.
+    results: [RepositoryReplacePreview!]!
+    # Whether not all matches were replaced because a limit or the deadline was hit.
+    limitHit: Boolean!
+    # Repositories that are busy cloning onto gitserver.
+    cloning: [Repository!]!
+    # Repositories or commits that do not exist.
+    missing: [Repository!]!
+    # Repositories or commits which we did not manage to search in time. Trying
+    # again usually will work.
+    timedout: [Repository!]!
+}
+
+# The changes that replacing the matches of a search query makes to a repository.
+type RepositoryReplacePreview {
+    # The repository.
+    repository: Repository!
+    # The commit whose files the changes apply to.
+    commit: GitCommit!
+    # The changes to each file. The new file of each file diff is null because the changes are not committed.
+    fileDiffs: [FileDiff!]!
+    # The diff stat for all of the file diffs.
+    diffStat: DiffStat!
+    # The raw diff for all of the file diffs.
+    rawDiff: String!
 }
 
 # A search result.
@@ -1048,7 +1098,10 @@ type ExternalServiceConnection {
 # A specific kind of external service.
 enum ExternalServiceKind {
     AWSCODECOMMIT
+    BITBUCKETCLOUD
     BITBUCKETSERVER
+    GERRIT
+    GITEA
     GITHUB
     GITLAB
     GITOLITE
@@ -1195,6 +1248,11 @@ type Repository implements Node & GenericSearchResultInterface {
     redirectURL: String
     # Whether the viewer has admin privileges on this repository.
     viewerCanAdminister: Boolean!
+    # The users and organizations that can read this repository, as set with
+    # Mutation.setRepositoryPermissions, or null if none were set.
+    #
+    # Only site admins can access this field.
+    explicitPermissions: RepositoryPermissions
     # Base64 data uri to an icon.
     icon: String!
     # A markdown string that is rendered prominently.
@@ -1205,6 +1263,14 @@ type Repository implements Node & GenericSearchResultInterface {
     matches: [SearchResultMatch!]!
 }
 
+# The explicit permissions on a repository.
+type RepositoryPermissions {
+    # The users that can read the repository.
+    users: [User!]!
+    # The organizations whose members can read the repository.
+    organizations: [Org!]!
+}
+
 # A URL to a resource on an external service, such as the URL to a repository on its external (origin) code host.
 type ExternalLink {
     # The URL to the resource.
@@ -1237,6 +1303,51 @@ type MirrorRepositoryInfo {
     updateSchedule: UpdateSchedule
     # The state of this repository in the update queue.
     updateQueue: UpdateQueue
+    # The disk usage of the repository's clone, or null if the repository has not been cloned.
+    diskUsage: RepositoryDiskUsage
+    # The status of pushing the repository to its push mirror, or null if the repository has no push mirror. Only
+    # site admins may access this field.
+    pushMirror: PushMirrorStatus
+}
+
+# The status of pushing a repository to its push mirror, a secondary remote that the repository is pushed to after
+# each update.
+type PushMirrorStatus {
+    # The URL of the push mirror, without credentials.
+    url: String!
+    # When the last push was attempted, or null if the repository has not been pushed yet.
+    lastAttemptedAt: String
+    # When the last successful push finished, or null if no push has succeeded yet.
+    lastPushedAt: String
+    # The error of the last push, or null if it succeeded. Failed pushes are retried on the next update.
+    lastError: String
+}
+
+# The disk usage of a repository's clone on gitserver. Sizes are in bytes (as a Float, because they may exceed the
+# range of Int).
+type RepositoryDiskUsage {
+    # The total size of the clone.
+    totalBytes: Float!
+    # The size of the clone's packfiles and their indexes.
+    packBytes: Float!
+    # The size of the clone's loose (unpacked) objects.
+    looseObjectBytes: Float!
+    # The size of the clone's worktrees and checked out files.
+    worktreeBytes: Float!
+    # The size of everything else in the clone, such as refs and configuration.
+    otherBytes: Float!
+    # When the sizes were computed. They are recomputed daily, so they may be out of date.
+    computedAt: String!
+}
+
+# The disk usage of a repository's clone on gitserver, for ranking repositories by size.
+type RepositoryDiskUsageEntry {
+    # The name of the repository.
+    name: String!
+    # The repository, or null if it no longer exists on Sourcegraph (but its clone was not yet removed).
+    repository: Repository
+    # The disk usage of the repository's clone.
+    diskUsage: RepositoryDiskUsage!
 }
 
 # The state of a repository in the update schedule.
@@ -1261,6 +1372,33 @@ type UpdateQueue {
     updating: Boolean!
     # The total number of repos in the update queue (including updating repos).
     total: Int!
+    # The priority of the update.
+    priority: UpdateQueuePriority!
+    # The external service of the repository, if it is known. The update counts against its
+    # update budget (see the updateLimits property of its configuration). Only site admins
+    # may access this field.
+    externalService: ExternalService
+    # Why the update is held back, if it is held back by the update budget of the
+    # repository's external service.
+    waitingFor: UpdateQueueWaitReason
+}
+
+# The priority of an update in the update queue.
+enum UpdateQueuePriority {
+    # A scheduled update.
+    LOW
+    # An update requested by a code host webhook.
+    MEDIUM
+    # An update requested by a user.
+    HIGH
+}
+
+# The reason why an update in the update queue is held back.
+enum UpdateQueueWaitReason {
+    # The external service already has the maximum number of concurrent updates in progress.
+    EXTERNAL_SERVICE_CONCURRENCY
+    # The maximum number of updates per minute has been reached for the external service.
+    EXTERNAL_SERVICE_RATE_LIMIT
 }
 
 # A repository on an external service (such as GitHub, GitLab, Phabricator, etc.).
@@ -1676,6 +1814,8 @@ type GitCommit implements Node {
     author: Signature!
     # This commit's committer, if any.
     committer: Signature
+    # This commit's GPG or SSH signature, if it is signed.
+    signature: GitCommitSignature
     # The full commit message.
     message: String!
     # The first line of the commit message.
@@ -1744,6 +1884,35 @@ type Signature {
     date: String!
 }
 
+# The GPG or SSH signature of a Git commit.
+type GitCommitSignature {
+    # The result of verifying the signature against the trusted signing keys (the "gitTrustedSigningKeys"
+    # site configuration property).
+    status: GitCommitSignatureStatus!
+    # Whether the signature is a good signature made by a trusted key.
+    verified: Boolean!
+    # The ID of the GPG key, or the fingerprint of the SSH key, that made the signature.
+    keyID: String
+}
+
+# The result of verifying the signature of a Git commit.
+enum GitCommitSignatureStatus {
+    # A good signature made by a trusted key.
+    VERIFIED
+    # A good signature made by an SSH key that is not trusted.
+    UNTRUSTED_KEY
+    # A signature that can't be checked, usually because it was made by a GPG key that is not trusted.
+    UNKNOWN_KEY
+    # A signature that does not match the commit.
+    BAD
+    # A good signature that has expired.
+    EXPIRED
+    # A good signature made by a key that has expired.
+    EXPIRED_KEY
+    # A good signature made by a key that has been revoked.
+    REVOKED_KEY
+}
+
 # A person.
 type Person {
     # The name.
@@ -1766,6 +1935,11 @@ type Submodule {
     commit: String!
     # The path to which the submodule is checked out.
     path: String!
+    # The repository of the submodule, or null if it is not mirrored on Sourcegraph.
+    repository: Repository
+    # The commit of the submodule in its repository, or null if the repository is not mirrored on
+    # Sourcegraph.
+    gitCommit: GitCommit
 }
 
 # A file, directory, or other tree entry.
@@ -1960,6 +2134,15 @@ type GitBlob implements TreeEntry & File2 {
         # Return symbols matching the query.
         query: String
     ): SymbolConnection!
+    # Heuristically finds the definitions of the identifier at the given zero-based position in this blob.
+    # Definitions are found by name in the repository's symbols (from ctags), so results are not scope-aware
+    # and may include false positives. Definitions in this blob are listed first, followed by definitions in
+    # files with the same extension.
+    definitionAt(line: Int!, character: Int!): [Location!]!
+    # Heuristically finds references to the given symbol name in this blob's repository at its commit.
+    # References are whole-word, case-sensitive text matches, so results are not scope-aware and may include
+    # false positives.
+    referencesTo(symbol: String!): [Location!]!
     # Always false, since a blob is a file, not directory.
     isSingleChild(
         # Returns the first n files in the tree.
@@ -2005,8 +2188,13 @@ type LineMatch {
     preview: String!
     # The line number.
     lineNumber: Int!
+    # The line number of the last line in the preview. It is only different from lineNumber if a
+    # match spans multiple lines (see the multiline: search keyword).
+    endLineNumber: Int!
     # Tuples of [offset, length] measured in characters (not bytes).
     offsetAndLengths: [[Int!]!]!
+    # The range of each match in offsetAndLengths. Unlike an offset, a range may span multiple lines.
+    ranges: [Range!]!
     # Whether or not the limit was hit.
     limitHit: Boolean!
 }
@@ -2704,6 +2892,12 @@ type Site implements SettingsSubject {
         # Months of history.
         months: Int
     ): SiteUsageStatistics!
+    # The repositories whose clones use the most disk space on gitserver, largest first. Only site admins can
+    # access this field.
+    largestRepositories(
+        # Returns the first n repositories from the list (default 20).
+        first: Int
+    ): [RepositoryDiskUsageEntry!]!
     # Information about this site's management console.
     #
     # Only site admins may retrieve this information.
@@ -61,6 +61,8 @@ func (r *schemaResolver) Search(args *struct {
 	Suggestions(context.Context, *searchSuggestionsArgs) ([]*searchSuggestionResolver, error)
 	//lint:ignore U1000 is used by graphql via reflection
 	Stats(context.Context) (*searchResultsStats, error)
+	//lint:ignore U1000 is used by graphql via reflection
+	ReplacePreview(context.Context, *struct{ Replacement string }) (*searchReplacePreviewResolver, error)
 }, error) {
 	if strings.HasPrefix(args.Query, "!hier!") {
 		return newSearcherResolver(strings.TrimPrefix(args.Query, "!hier!"))
@@ -91,6 +93,10 @@ func asString(v *searchquerytypes.Value) string {
 type searchResolver struct {
 	query *query.Query // the parsed search query
 
+	// stream, if non-nil, receives the results of the search as they are
+	// found (see StreamSearch).
+	stream searchStream
+
 	// Cached resolveRepositories results.
 	reposMu                   sync.Mutex
 	repoRevs, missingRepoRevs []*search.RepositoryRevisions
@@ -513,6 +519,9 @@ func (r *searchResolver) suggestFilePaths(ctx context.Context, limit int) ([]*se
 	// Treat all default terms as though they had `file:` before them (to make it easy for users to
 	// jump to files by just typing their name).
 	for _, v := range r.query.Values(query.FieldDefault) {
+		if v.Not() {
+			continue
+		}
 		includePatterns = append(includePatterns, asString(v))
 	}
 
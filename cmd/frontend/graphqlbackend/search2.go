@@ -119,6 +119,10 @@ func (r *searcherResolver) Stats(ctx context.Context) (stats *searchResultsStats
 	return nil, errors.New("search stats not implemented")
 }
 
+func (r *searcherResolver) ReplacePreview(ctx context.Context, args *struct{ Replacement string }) (*searchReplacePreviewResolver, error) {
+	return nil, errors.New("search replace preview not implemented")
+}
+
 func toSearchResultResolvers(ctx context.Context, sCtx *searchContext, r *search.Result) ([]*searchResultResolver, error) {
 	results := make([]*searchResultResolver, 0, len(r.Files))
 
@@ -135,6 +139,7 @@ func toSearchResultResolvers(ctx context.Context, sCtx *searchContext, r *search
 			lines = append(lines, &lineMatch{
 				JPreview:          string(l.Line),
 				JLineNumber:       int32(l.LineNumber - 1),
+				JEndLineNumber:    int32(l.EndLineNumber - 1),
 				JOffsetAndLengths: offsets,
 			})
 		}
@@ -0,0 +1,256 @@
+package graphqlbackend
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+)
+
+// This file contains the evaluation of queries with boolean operators (and,
+// or, not and parenthesized groups). The query package rewrites such queries
+// into a union of flat queries (disjuncts), combining alternatives into a
+// single regexp where possible. Each disjunct is searched like any other
+// query by doResults, and the results are merged here.
+
+// doDisjunctResults searches each of the disjuncts of a query with boolean
+// operators concurrently and returns the union of their results.
+func (r *searchResolver) doDisjunctResults(ctx context.Context, forceOnlyResultType string) (*searchResultsResolver, error) {
+	if len(r.query.Disjuncts) == 1 {
+		return (&searchResolver{query: r.query.Disjuncts[0], stream: r.stream}).doResults(ctx, forceOnlyResultType)
+	}
+
+	start := time.Now()
+
+	var (
+		wg       sync.WaitGroup
+		resolved = make([]*searchResultsResolver, len(r.query.Disjuncts))
+		errs     = make([]error, len(r.query.Disjuncts))
+	)
+	for i, disjunct := range r.query.Disjuncts {
+		i, disjunct := i, disjunct // shadow so they don't change in the goroutine
+		wg.Add(1)
+		goroutine.Go(func() {
+			defer wg.Done()
+			resolved[i], errs[i] = (&searchResolver{query: disjunct, stream: r.stream}).doResults(ctx, forceOnlyResultType)
+		})
+	}
+	wg.Wait()
+
+	var (
+		resultSets [][]*searchResultResolver
+		common     = searchResultsCommon{maxResultsCount: r.maxResults()}
+		alert      *searchAlert
+		multiErr   *multierror.Error
+	)
+	for i, rr := range resolved {
+		if errs[i] != nil {
+			multiErr = multierror.Append(multiErr, errors.Wrapf(errs[i], "search for %q failed", r.query.Disjuncts[i].Syntax.Input))
+		}
+		if rr == nil {
+			continue
+		}
+		resultSets = append(resultSets, rr.results)
+		common.update(rr.searchResultsCommon)
+		if alert == nil && rr.alert != nil {
+			// Proposed queries only rewrite a single alternative of the query,
+			// so they would change its meaning.
+			alert = &searchAlert{title: rr.alert.title, description: rr.alert.description}
+		}
+	}
+
+	results := unionSearchResults(resultSets)
+
+	// If we have some results, only log the error instead of returning it,
+	// because otherwise the client would not receive the partial results
+	if len(results) > 0 && multiErr != nil {
+		log15.Error("Errors during search", "error", multiErr)
+		multiErr = nil
+	}
+
+	sortResults(results)
+
+	return &searchResultsResolver{
+		start:               start,
+		searchResultsCommon: common,
+		results:             results,
+		alert:               alert,
+	}, multiErr.ErrorOrNil()
+}
+
+// unionSearchResults returns the union of the result sets. File matches for
+// the same file are merged into a single result containing the line matches
+// and symbols from all of them.
+func unionSearchResults(resultSets [][]*searchResultResolver) []*searchResultResolver {
+	var (
+		results     []*searchResultResolver
+		repos       = map[api.RepoName]struct{}{}
+		fileMatches = map[string]*fileMatchResolver{}
+		commits     = map[commitSearchResultKey]struct{}{}
+	)
+	for _, resultSet := range resultSets {
+		for _, result := range resultSet {
+			switch {
+			case result.repo != nil:
+				if _, seen := repos[result.repo.repo.Name]; seen {
+					continue
+				}
+				repos[result.repo.repo.Name] = struct{}{}
+			case result.fileMatch != nil:
+				if m, seen := fileMatches[result.fileMatch.uri]; seen {
+					m.JLimitHit = m.JLimitHit || result.fileMatch.JLimitHit
+					m.JLineMatches = unionLineMatches(m.JLineMatches, result.fileMatch.JLineMatches)
+					if len(m.symbols) == 0 {
+						m.symbols = result.fileMatch.symbols
+					}
+					continue
+				}
+				fileMatches[result.fileMatch.uri] = result.fileMatch
+			case result.diff != nil:
+				key := commitSearchResultKey{
+					repo:   result.diff.commit.repo.repo.Name,
+					commit: result.diff.commit.oid,
+					diff:   result.diff.diffPreview != nil,
+				}
+				if _, seen := commits[key]; seen {
+					continue
+				}
+				commits[key] = struct{}{}
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+type commitSearchResultKey struct {
+	repo   api.RepoName
+	commit gitObjectID
+	diff   bool // diff (not commit message) match
+}
+
+// unionLineMatches returns the union of 2 sets of line matches for the same
+// file, ordered by line number. Matches on the same line are combined.
+func unionLineMatches(a, b []*lineMatch) []*lineMatch {
+	byLine := make(map[int32]*lineMatch, len(a)+len(b))
+	var merged []*lineMatch
+	add := func(lm *lineMatch) {
+		m, ok := byLine[lm.JLineNumber]
+		if !ok {
+			byLine[lm.JLineNumber] = lm
+			merged = append(merged, lm)
+			return
+		}
+		m.JLimitHit = m.JLimitHit || lm.JLimitHit
+		// Multiline matches starting on the same line can have different
+		// previews. Keep the longest so that all offsets are within it.
+		if lm.EndLineNumber() > m.EndLineNumber() {
+			m.JPreview = lm.JPreview
+			m.JEndLineNumber = lm.JEndLineNumber
+		}
+		// The ranges are recomputed from the merged offsets.
+		m.JRanges = nil
+	offsets:
+		for _, ol := range lm.JOffsetAndLengths {
+			for _, existing := range m.JOffsetAndLengths {
+				if existing == ol {
+					continue offsets
+				}
+			}
+			m.JOffsetAndLengths = append(m.JOffsetAndLengths, ol)
+		}
+		sort.Slice(m.JOffsetAndLengths, func(i, j int) bool { return m.JOffsetAndLengths[i][0] < m.JOffsetAndLengths[j][0] })
+	}
+	for _, lm := range a {
+		add(lm)
+	}
+	for _, lm := range b {
+		add(lm)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].JLineNumber < merged[j].JLineNumber })
+	return merged
+}
+
+// maxNegatedFileMatches is the maximum number of files matching a negated
+// pattern that are looked up to exclude them from the results.
+const maxNegatedFileMatches = 10000
+
+// searchNegatedPatterns returns the URIs of the files whose contents match
+// any of the negated patterns (e.g., "b" in the query "a -b"). These files
+// are excluded from the results of the query. If not all such files could be
+// found, limitHit is true.
+func searchNegatedPatterns(ctx context.Context, args *search.Args, negatedPatterns []string) (excluded map[string]struct{}, limitHit bool, err error) {
+	negatedPattern := *args.Pattern
+	negatedPattern.Pattern = unionRegExps(negatedPatterns)
+	negatedPattern.PatternMatchesContent = true
+	negatedPattern.PatternMatchesPath = false
+	negatedPattern.FileMatchLimit = maxNegatedFileMatches
+	negatedArgs := *args
+	negatedArgs.Pattern = &negatedPattern
+
+	fileMatches, common, err := searchFilesInRepos(ctx, &negatedArgs)
+	if err != nil {
+		return nil, false, err
+	}
+	excluded = make(map[string]struct{}, len(fileMatches))
+	for _, fm := range fileMatches {
+		excluded[fm.uri] = struct{}{}
+	}
+	limitHit = common != nil && (common.limitHit || len(common.timedout) > 0 || len(common.cloning) > 0)
+	return excluded, limitHit, nil
+}
+
+// checkNegatedResultTypes returns an error if negated patterns can't be
+// applied to one of the result types. Negated patterns exclude files whose
+// contents match them and repositories whose names match them, so they can't
+// be honored for symbol, diff and commit results.
+func checkNegatedResultTypes(resultTypes []string) error {
+	for _, resultType := range resultTypes {
+		switch resultType {
+		case "symbol", "diff", "commit":
+			return &badRequestError{fmt.Errorf("negated terms (such as -foo or NOT foo) are not supported for %s results", resultType)}
+		}
+	}
+	return nil
+}
+
+// negatedRepoNamePattern returns a regexp matching the names of repositories
+// that are excluded from the results because they match a negated pattern.
+func negatedRepoNamePattern(negatedPatterns []string, caseSensitive bool) (*regexp.Regexp, error) {
+	pattern := unionRegExps(negatedPatterns)
+	if !caseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// excludeNegatedResults removes the results that match a negated pattern from
+// results: the file matches for the files in excludedFiles and the
+// repositories whose names match excludedRepos (if non-nil).
+func excludeNegatedResults(results []*searchResultResolver, excludedFiles map[string]struct{}, excludedRepos *regexp.Regexp) []*searchResultResolver {
+	filtered := results[:0]
+	for _, result := range results {
+		switch {
+		case result.fileMatch != nil:
+			if _, ok := excludedFiles[result.fileMatch.uri]; ok {
+				continue
+			}
+		case result.repo != nil:
+			if excludedRepos != nil && excludedRepos.MatchString(string(result.repo.repo.Name)) {
+				continue
+			}
+		}
+		filtered = append(filtered, result)
+	}
+	return filtered
+}
@@ -0,0 +1,94 @@
+package graphqlbackend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestUnionSearchResults(t *testing.T) {
+	repo := &types.Repo{Name: "r"}
+	fileMatch := func(uri string, lineMatches ...*lineMatch) *searchResultResolver {
+		return &searchResultResolver{fileMatch: &fileMatchResolver{uri: uri, JPath: uri, repo: repo, JLineMatches: lineMatches}}
+	}
+
+	results := unionSearchResults([][]*searchResultResolver{
+		{
+			{repo: &repositoryResolver{repo: repo}},
+			fileMatch("a", &lineMatch{JLineNumber: 1, JOffsetAndLengths: [][2]int32{{0, 1}}}),
+			fileMatch("b", &lineMatch{JLineNumber: 3, JOffsetAndLengths: [][2]int32{{0, 1}}}),
+		},
+		{
+			{repo: &repositoryResolver{repo: repo}},
+			fileMatch("b",
+				&lineMatch{JLineNumber: 3, JOffsetAndLengths: [][2]int32{{0, 1}, {4, 2}}},
+				&lineMatch{JLineNumber: 2, JOffsetAndLengths: [][2]int32{{1, 1}}},
+			),
+			fileMatch("c", &lineMatch{JLineNumber: 1}),
+		},
+	})
+
+	var got []string
+	for _, r := range results {
+		switch {
+		case r.repo != nil:
+			got = append(got, "repo:"+string(r.repo.repo.Name))
+		case r.fileMatch != nil:
+			got = append(got, r.fileMatch.uri)
+		}
+	}
+	if want := []string{"repo:r", "a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got results %v, want %v", got, want)
+	}
+
+	b := results[2].fileMatch
+	wantLineMatches := []*lineMatch{
+		{JLineNumber: 2, JOffsetAndLengths: [][2]int32{{1, 1}}},
+		{JLineNumber: 3, JOffsetAndLengths: [][2]int32{{0, 1}, {4, 2}}},
+	}
+	if !reflect.DeepEqual(b.JLineMatches, wantLineMatches) {
+		t.Errorf("got line matches %+v, want %+v", b.JLineMatches, wantLineMatches)
+	}
+}
+
+func TestExcludeNegatedResults(t *testing.T) {
+	repo := &types.Repo{Name: "r"}
+	excludedRepos, err := negatedRepoNamePattern([]string{"B"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []*searchResultResolver{
+		{repo: &repositoryResolver{repo: repo}},
+		{repo: &repositoryResolver{repo: &types.Repo{Name: "b"}}},
+		{fileMatch: &fileMatchResolver{uri: "a", repo: repo}},
+		{fileMatch: &fileMatchResolver{uri: "b", repo: repo}},
+	}
+	results = excludeNegatedResults(results, map[string]struct{}{"a": {}}, excludedRepos)
+	if len(results) != 2 || results[0].repo == nil || results[0].repo.repo.Name != "r" || results[1].fileMatch.uri != "b" {
+		t.Errorf("got %+v, want repo r and file match b", results)
+	}
+}
+
+func TestCheckNegatedResultTypes(t *testing.T) {
+	tests := map[string]struct {
+		resultTypes []string
+		wantErr     bool
+	}{
+		"default": {resultTypes: []string{"file", "path", "repo", "ref"}},
+		"symbol":  {resultTypes: []string{"symbol"}, wantErr: true},
+		"diff":    {resultTypes: []string{"file", "diff"}, wantErr: true},
+		"commit":  {resultTypes: []string{"commit"}, wantErr: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := checkNegatedResultTypes(test.resultTypes)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if _, ok := err.(*badRequestError); err != nil && !ok {
+				t.Errorf("got error %T, want *badRequestError", err)
+			}
+		})
+	}
+}
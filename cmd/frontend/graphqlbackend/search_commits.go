@@ -111,6 +111,21 @@ type commitSearchOp struct {
 	extraMessageValues []string
 }
 
+// checkSignedResultTypes returns an error if the signed: filter can't be applied to one of the
+// result types. Only diff and commit results have signatures.
+func checkSignedResultTypes(resultTypes []string) error {
+	for _, resultType := range resultTypes {
+		if resultType != "diff" && resultType != "commit" {
+			return &badRequestError{fmt.Errorf("signed: is not supported for %s results (use type:diff or type:commit)", resultType)}
+		}
+	}
+	return nil
+}
+
+// signedMaxCount is the minimum number of commits that git log returns for a query with signed:,
+// whose results are filtered after git log returns them.
+const signedMaxCount = 500
+
 func searchCommitsInRepo(ctx context.Context, op commitSearchOp) (results []*commitSearchResultResolver, limitHit, timedOut bool, err error) {
 	tr, ctx := trace.New(ctx, "searchCommitsInRepo", fmt.Sprintf("repoRevs: %v, pattern %+v", op.repoRevs, op.info))
 	defer func() {
@@ -122,9 +137,22 @@ func searchCommitsInRepo(ctx context.Context, op commitSearchOp) (results []*com
 	repo := op.repoRevs.Repo
 	maxResults := int(op.info.FileMatchLimit)
 
+	signedStr, _ := op.query.StringValue(query.FieldSigned)
+	signed := parseYesNoOnly(signedStr)
+	if signedStr != "" && (signed == Invalid || signed == Only) {
+		return nil, false, false, fmt.Errorf("invalid value for signed: %q (must be yes or no)", signedStr)
+	}
+
+	// git log can't filter by signature, so signed: is applied to the results below. More commits
+	// are fetched in that case so that enough of them are left after filtering.
+	maxCount := maxResults + 1
+	if signedStr != "" && maxCount < signedMaxCount {
+		maxCount = signedMaxCount
+	}
+
 	args := []string{
 		"--no-prefix",
-		"--max-count=" + strconv.Itoa(maxResults+1),
+		"--max-count=" + strconv.Itoa(maxCount),
 	}
 	if op.diff {
 		args = append(args,
@@ -234,6 +262,7 @@ func searchCommitsInRepo(ctx context.Context, op commitSearchOp) (results []*com
 		Diff:              op.diff,
 		OnlyMatchingHunks: true,
 		Args:              args,
+		Signatures:        signedStr != "",
 	})
 	if err != nil {
 		return nil, false, false, err
@@ -241,6 +270,21 @@ func searchCommitsInRepo(ctx context.Context, op commitSearchOp) (results []*com
 
 	// if the result is incomplete, git log timed out and the client should be notified of that
 	timedOut = !complete
+
+	// Only verified signatures (signed:yes) or everything else (signed:no) are kept. If git log
+	// stopped at maxCount, there may be more matching commits than were fetched.
+	if signedStr != "" {
+		limitHit = len(rawResults) >= maxCount
+		wantVerified := signed == Yes || signed == True
+		filtered := rawResults[:0]
+		for _, rawResult := range rawResults {
+			verified := rawResult.Commit.Signature != nil && rawResult.Commit.Signature.Status == git.SignatureVerified
+			if verified == wantVerified {
+				filtered = append(filtered, rawResult)
+			}
+		}
+		rawResults = filtered
+	}
 	if len(rawResults) > maxResults {
 		limitHit = true
 		rawResults = rawResults[:maxResults]
@@ -251,6 +295,7 @@ func searchCommitsInRepo(ctx context.Context, op commitSearchOp) (results []*com
 	for i, rawResult := range rawResults {
 		commit := rawResult.Commit
 		commitResolver := toGitCommitResolver(repoResolver, &commit)
+		commitResolver.signatureLoaded = signedStr != ""
 		results[i] = &commitSearchResultResolver{commit: commitResolver}
 
 		addRefs := func(dst *[]*gitRefResolver, src []string) {
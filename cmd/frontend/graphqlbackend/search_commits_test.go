@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -94,6 +95,98 @@ func (r *commitSearchResultResolver) String() string {
 	return fmt.Sprintf("{commit: %+v diffPreview: %+v messagePreview: %+v}", r.commit, r.diffPreview, r.messagePreview)
 }
 
+func TestSearchCommitsInRepo_signed(t *testing.T) {
+	gitSignatureWithDate := git.Signature{Date: time.Now().AddDate(0, 0, -1)}
+	var gotSignatures bool
+	git.Mocks.RawLogDiffSearch = func(opt git.RawLogDiffSearchOptions) ([]*git.LogCommitSearchResult, bool, error) {
+		gotSignatures = opt.Signatures
+		return []*git.LogCommitSearchResult{
+			{Commit: git.Commit{ID: "c1", Author: gitSignatureWithDate, Signature: &git.CommitSignature{Status: git.SignatureVerified, KeyID: "k1"}}},
+			{Commit: git.Commit{ID: "c2", Author: gitSignatureWithDate, Signature: &git.CommitSignature{Status: git.SignatureUnknownKey, KeyID: "k2"}}},
+			{Commit: git.Commit{ID: "c3", Author: gitSignatureWithDate}},
+		}, true, nil
+	}
+	defer git.ResetMocks()
+
+	for _, test := range []struct {
+		query        string
+		limit        int
+		want         []string
+		wantLimitHit bool
+		wantErr      bool
+	}{
+		{query: "type:commit p", want: []string{"c1", "c2", "c3"}},
+		{query: "type:commit p signed:yes", want: []string{"c1"}},
+		{query: "type:commit p signed:no", want: []string{"c2", "c3"}},
+		{query: "type:commit p signed:no", limit: 1, want: []string{"c2"}, wantLimitHit: true},
+		{query: "type:commit p signed:yes", limit: 1, want: []string{"c1"}},
+		{query: "type:commit p signed:maybe", wantErr: true},
+	} {
+		limit := test.limit
+		if limit == 0 {
+			limit = defaultMaxSearchResults
+		}
+		q, err := query.ParseAndCheck(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		results, limitHit, _, err := searchCommitsInRepo(context.Background(), commitSearchOp{
+			repoRevs: search.RepositoryRevisions{
+				Repo: &types.Repo{ID: 1, Name: "repo"},
+				Revs: []search.RevisionSpecifier{{RevSpec: "rev"}},
+			},
+			info:               &search.PatternInfo{Pattern: "p", FileMatchLimit: int32(limit)},
+			query:              q,
+			extraMessageValues: []string{"p"},
+		})
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.query)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %s", test.query, err)
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, string(r.commit.oid))
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got commits %v, want %v", test.query, got, test.want)
+		}
+		if limitHit != test.wantLimitHit {
+			t.Errorf("%s: got limitHit %v, want %v", test.query, limitHit, test.wantLimitHit)
+		}
+		if wantSignatures := strings.Contains(test.query, "signed:"); gotSignatures != wantSignatures {
+			t.Errorf("%s: got Signatures %v, want %v", test.query, gotSignatures, wantSignatures)
+		}
+	}
+}
+
+func TestCheckSignedResultTypes(t *testing.T) {
+	tests := map[string]struct {
+		resultTypes []string
+		wantErr     bool
+	}{
+		"commit":  {resultTypes: []string{"commit"}},
+		"diff":    {resultTypes: []string{"diff", "commit"}},
+		"default": {resultTypes: []string{"file", "path", "repo", "ref"}, wantErr: true},
+		"symbol":  {resultTypes: []string{"symbol"}, wantErr: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := checkSignedResultTypes(test.resultTypes)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("got error %v, want error %v", err, test.wantErr)
+			}
+			if _, ok := err.(*badRequestError); err != nil && !ok {
+				t.Errorf("got error %T, want *badRequestError", err)
+			}
+		})
+	}
+}
+
 func TestExpandUsernamesToEmails(t *testing.T) {
 	resetMocks()
 	db.Mocks.Users.GetByUsername = func(ctx context.Context, username string) (*types.User, error) {
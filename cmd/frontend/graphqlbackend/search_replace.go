@@ -0,0 +1,312 @@
+package graphqlbackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"sourcegraph.com/sourcegraph/go-diff/diff"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/trace"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+)
+
+// This file contains the preview of replacing the matches of a search query.
+// Searcher computes a unified diff for each file whose contents match, which
+// is resolved with the same FileDiff type as repository comparisons. Nothing
+// is written to any repository.
+
+// ReplacePreview returns the changes that replacing the matches of the
+// query's pattern with args.Replacement would make, in each repository that
+// has matches.
+func (r *searchResolver) ReplacePreview(ctx context.Context, args *struct {
+	Replacement string
+}) (res *searchReplacePreviewResolver, err error) {
+	tr, ctx := trace.New(ctx, "graphql.SearchReplacePreview", r.rawQuery())
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	if !r.query.IsFlat() {
+		return nil, &badRequestError{errors.New("replacing matches of queries with boolean operators is not supported")}
+	}
+	if r.query.IsStructural() {
+		return nil, &badRequestError{errors.New("replacing matches of structural search queries is not supported")}
+	}
+	var patterns int
+	for _, v := range r.query.Values(query.FieldDefault) {
+		if v.Not() {
+			return nil, &badRequestError{errors.New("replacing matches of queries with negated patterns is not supported")}
+		}
+		patterns++
+	}
+	if patterns != 1 {
+		// Multiple patterns are combined into a single regexp with extra
+		// capture groups, which would change the meaning of $1 etc.
+		return nil, &badRequestError{errors.New("replacing matches requires a single search pattern")}
+	}
+
+	ctx, cancel, err := r.withTimeout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	repos, missingRepoRevs, _, overLimit, err := r.resolveRepositories(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if overLimit {
+		return nil, &badRequestError{errors.New("too many matching repositories to preview replacing matches (use repo: to narrow the query)")}
+	}
+	p, err := r.getPatternInfo()
+	if err != nil {
+		return nil, err
+	}
+	// Only file contents are replaced.
+	p.PatternMatchesContent = true
+	if err := p.Validate(); err != nil {
+		return nil, &badRequestError{err}
+	}
+
+	// Like searchFilesInRepos, give a single repository the whole deadline
+	// to fetch its archive.
+	fetchTimeout := 500 * time.Millisecond
+	if len(repos) == 1 || r.searchTimeoutFieldSet() {
+		if deadline, ok := ctx.Deadline(); ok {
+			fetchTimeout = time.Until(deadline)
+		}
+	}
+
+	// Check all repositories before starting any previews, so that no
+	// goroutines are left running when an error is returned.
+	for _, repoRev := range repos {
+		if len(repoRev.Revs) >= 2 {
+			return nil, errMultipleRevsNotSupported
+		}
+	}
+
+	res = &searchReplacePreviewResolver{}
+	for _, repoRev := range missingRepoRevs {
+		res.missing = append(res.missing, repoRev.Repo)
+	}
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, repoRev := range repos {
+		if len(repoRev.Revs) == 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(repoRev search.RepositoryRevisions) {
+			defer wg.Done()
+			rev := repoRev.RevSpecs()[0]
+			preview, limitHit, replaceErr := replacePreviewInRepo(ctx, repoRev.Repo, repoRev.GitserverRepo(), rev, p, args.Replacement, fetchTimeout)
+			mu.Lock()
+			defer mu.Unlock()
+			if fatalErr := handleRepoSearchResult(&res.searchResultsCommon, repoRev, limitHit, false, replaceErr); fatalErr != nil {
+				if err == nil {
+					err = errors.Wrapf(replaceErr, "failed to preview replacing matches in %s", repoRev.String())
+					cancel()
+				}
+				return
+			}
+			if ctx.Err() == nil {
+				res.searched = append(res.searched, repoRev.Repo)
+			}
+			if preview != nil && len(preview.fileDiffs) > 0 {
+				res.previews = append(res.previews, preview)
+			}
+		}(*repoRev)
+	}
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(res.previews, func(i, j int) bool {
+		return res.previews[i].repo.repo.Name < res.previews[j].repo.repo.Name
+	})
+	return res, nil
+}
+
+var mockReplacePreviewInRepo func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, p *search.PatternInfo, replacement string) (preview *repositoryReplacePreviewResolver, limitHit bool, err error)
+
+// replacePreviewInRepo asks searcher for the changes that replacing the
+// matches of p with replacement would make in repo@rev.
+func replacePreviewInRepo(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, p *search.PatternInfo, replacement string, fetchTimeout time.Duration) (preview *repositoryReplacePreviewResolver, limitHit bool, err error) {
+	if mockReplacePreviewInRepo != nil {
+		return mockReplacePreviewInRepo(ctx, repo, gitserverRepo, rev, p, replacement)
+	}
+
+	// Like searchFilesInRepo, do not trigger a repo-updater lookup.
+	commit, err := git.ResolveRevision(ctx, gitserverRepo, nil, rev, &git.ResolveRevisionOptions{NoEnsureRevision: true})
+	if err != nil {
+		return nil, false, err
+	}
+
+	tr, ctx := trace.New(ctx, "searcher.replace", fmt.Sprintf("%s@%s", gitserverRepo.Name, commit))
+	defer func() {
+		tr.SetError(err)
+		tr.Finish()
+	}()
+
+	q, err := searcherParams(ctx, gitserverRepo, commit, p, fetchTimeout)
+	if err != nil {
+		return nil, false, err
+	}
+	q.Set("Replacement", replacement)
+
+	// Use the same searcher as textSearch, which likely has the archive of
+	// repo@commit cached.
+	searcherURL, err := Search().SearcherURLs.Get(string(gitserverRepo.Name)+"@"+string(commit), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	fileDiffs, limitHit, err := replacePreviewURL(ctx, searcherURL+"/replace?"+q.Encode())
+	if err != nil {
+		return nil, false, err
+	}
+
+	repoResolver := &repositoryResolver{repo: repo}
+	return newRepositoryReplacePreviewResolver(repoResolver, &gitCommitResolver{repo: repoResolver, oid: gitObjectID(commit), inputRev: &rev}, fileDiffs), limitHit, nil
+}
+
+func replacePreviewURL(ctx context.Context, url string) (fileDiffs []*diff.FileDiff, limitHit bool, err error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req = req.WithContext(ctx)
+
+	req, ht := nethttp.TraceRequest(opentracing.GlobalTracer(), req,
+		nethttp.OperationName("Searcher Client"),
+		nethttp.ClientTrace(false))
+	defer ht.Finish()
+
+	// Limit number of outstanding searcher requests
+	if err := textSearchLimiter.Acquire(ctx); err != nil {
+		return nil, false, err
+	}
+	defer textSearchLimiter.Release()
+
+	resp, err := searchHTTPClient.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, false, errors.Wrap(err, "searcher request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, false, err
+		}
+		return nil, false, errors.WithStack(&searcherError{StatusCode: resp.StatusCode, Message: string(body)})
+	}
+
+	r := struct {
+		FileDiffs []struct {
+			Path string
+			Diff string
+		}
+		LimitHit    bool
+		DeadlineHit bool
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, false, errors.Wrap(err, "searcher response invalid")
+	}
+	fileDiffs = make([]*diff.FileDiff, len(r.FileDiffs))
+	for i, fd := range r.FileDiffs {
+		fileDiffs[i], err = diff.ParseFileDiff([]byte(fd.Diff))
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "searcher returned invalid diff for %s", fd.Path)
+		}
+	}
+	// A deadline means that not all matches were replaced, which is reported
+	// like a limit because the partial preview is still useful.
+	return fileDiffs, r.LimitHit || r.DeadlineHit, nil
+}
+
+type searchReplacePreviewResolver struct {
+	previews []*repositoryReplacePreviewResolver
+	searchResultsCommon
+}
+
+func (r *searchReplacePreviewResolver) Results() []*repositoryReplacePreviewResolver {
+	if r.previews == nil {
+		return []*repositoryReplacePreviewResolver{}
+	}
+	return r.previews
+}
+
+// repositoryReplacePreviewResolver resolves the changes to the files of a
+// repository at a commit. The changed files are described with
+// fileDiffResolver, as in a comparison whose head is not a commit.
+type repositoryReplacePreviewResolver struct {
+	repo      *repositoryResolver
+	commit    *gitCommitResolver
+	fileDiffs []*diff.FileDiff
+	cmp       *repositoryComparisonResolver
+}
+
+func newRepositoryReplacePreviewResolver(repo *repositoryResolver, commit *gitCommitResolver, fileDiffs []*diff.FileDiff) *repositoryReplacePreviewResolver {
+	return &repositoryReplacePreviewResolver{
+		repo:      repo,
+		commit:    commit,
+		fileDiffs: fileDiffs,
+		cmp: &repositoryComparisonResolver{
+			baseRevspec: string(commit.oid),
+			headRevspec: string(commit.oid),
+			base:        commit,
+			repo:        repo,
+		},
+	}
+}
+
+func (r *repositoryReplacePreviewResolver) Repository() *repositoryResolver { return r.repo }
+
+func (r *repositoryReplacePreviewResolver) Commit() *gitCommitResolver { return r.commit }
+
+func (r *repositoryReplacePreviewResolver) FileDiffs() []*fileDiffResolver {
+	resolvers := make([]*fileDiffResolver, len(r.fileDiffs))
+	for i, fileDiff := range r.fileDiffs {
+		resolvers[i] = &fileDiffResolver{
+			fileDiff: fileDiff,
+			cmp:      r.cmp,
+		}
+	}
+	return resolvers
+}
+
+func (r *repositoryReplacePreviewResolver) DiffStat() *diffStat {
+	var stat diffStat
+	for _, fileDiff := range r.fileDiffs {
+		s := fileDiff.Stat()
+		stat.added += s.Added
+		stat.changed += s.Changed
+		stat.deleted += s.Deleted
+	}
+	return &stat
+}
+
+func (r *repositoryReplacePreviewResolver) RawDiff() (string, error) {
+	b, err := diff.PrintMultiFileDiff(r.fileDiffs)
+	return string(b), err
+}
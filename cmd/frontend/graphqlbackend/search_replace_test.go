@@ -0,0 +1,116 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"sourcegraph.com/sourcegraph/go-diff/diff"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+)
+
+func TestSearchReplacePreview(t *testing.T) {
+	db.Mocks.Repos.List = func(_ context.Context, op db.ReposListOptions) ([]*types.Repo, error) {
+		return []*types.Repo{{Name: "b"}, {Name: "a"}, {Name: "c"}}, nil
+	}
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	const rawDiff = `--- f.go
++++ f.go
+@@ -1,1 +1,1 @@
+-return errors.New("x")
++return fmt.Errorf("x")
+`
+	mockReplacePreviewInRepo = func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, p *search.PatternInfo, replacement string) (*repositoryReplacePreviewResolver, bool, error) {
+		if want := `errors\.New\((.*)\)`; p.Pattern != want {
+			t.Errorf("got pattern %q, want %q", p.Pattern, want)
+		}
+		if want := "fmt.Errorf($1)"; replacement != want {
+			t.Errorf("got replacement %q, want %q", replacement, want)
+		}
+		if repo.Name == "c" {
+			// No matches.
+			return nil, false, nil
+		}
+		fileDiff, err := diff.ParseFileDiff([]byte(rawDiff))
+		if err != nil {
+			t.Fatal(err)
+		}
+		repoResolver := &repositoryResolver{repo: repo}
+		commit := &gitCommitResolver{repo: repoResolver, oid: "c0ffee", inputRev: &rev}
+		return newRepositoryReplacePreviewResolver(repoResolver, commit, []*diff.FileDiff{fileDiff}), repo.Name == "b", nil
+	}
+	defer func() { mockReplacePreviewInRepo = nil }()
+
+	r, err := (&schemaResolver{}).Search(&struct{ Query string }{Query: `errors\.New\((.*)\)`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	preview, err := r.ReplacePreview(context.Background(), &struct{ Replacement string }{Replacement: "fmt.Errorf($1)"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var repos []string
+	for _, result := range preview.Results() {
+		repos = append(repos, string(result.Repository().repo.Name))
+		if got, err := result.RawDiff(); err != nil || got != rawDiff {
+			t.Errorf("%s: got raw diff %q (error %v), want %q", result.Repository().repo.Name, got, err, rawDiff)
+		}
+		if stat := result.DiffStat(); stat.changed != 1 {
+			t.Errorf("%s: got diff stat %+v, want 1 changed", result.Repository().repo.Name, stat)
+		}
+		if newFile := result.FileDiffs()[0].NewFile(); newFile != nil {
+			t.Errorf("%s: got new file %+v, want nil", result.Repository().repo.Name, newFile)
+		}
+	}
+	if want := []string{"a", "b"}; !reflect.DeepEqual(repos, want) {
+		t.Errorf("got repositories %v, want %v", repos, want)
+	}
+	if !preview.LimitHit() {
+		t.Error("expected limit to be hit")
+	}
+}
+
+func TestSearchReplacePreview_unsupported(t *testing.T) {
+	for _, q := range []string{
+		"",
+		"a b",
+		"a -b",
+		"a or b",
+		"f(:[x]) patterntype:structural",
+	} {
+		r, err := (&schemaResolver{}).Search(&struct{ Query string }{Query: q})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.ReplacePreview(context.Background(), &struct{ Replacement string }{}); err == nil {
+			t.Errorf("%q: got nil error", q)
+		}
+	}
+}
+
+func TestSearchReplacePreview_multipleRevs(t *testing.T) {
+	db.Mocks.Repos.List = func(_ context.Context, op db.ReposListOptions) ([]*types.Repo, error) {
+		return []*types.Repo{{Name: "a"}, {Name: "b"}}, nil
+	}
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	mockReplacePreviewInRepo = func(ctx context.Context, repo *types.Repo, gitserverRepo gitserver.Repo, rev string, p *search.PatternInfo, replacement string) (*repositoryReplacePreviewResolver, bool, error) {
+		t.Errorf("unexpected preview in %s", repo.Name)
+		return nil, false, nil
+	}
+	defer func() { mockReplacePreviewInRepo = nil }()
+
+	r, err := (&schemaResolver{}).Search(&struct{ Query string }{Query: "foo repo:a@*refs/heads/*:*refs/tags/*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReplacePreview(context.Background(), &struct{ Replacement string }{Replacement: "bar"}); err != errMultipleRevsNotSupported {
+		t.Errorf("got error %v, want %v", err, errMultipleRevsNotSupported)
+	}
+}
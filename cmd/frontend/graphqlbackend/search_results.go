@@ -24,6 +24,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/goroutine"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
+	searchquerytypes "github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query/types"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
@@ -554,14 +555,11 @@ func (r *searchResolver) Stats(ctx context.Context) (stats *searchResultsStats,
 func (r *searchResolver) getPatternInfo() (*search.PatternInfo, error) {
 	var patternsToCombine []string
 	for _, v := range r.query.Values(query.FieldDefault) {
-		// Treat quoted strings as literal strings to match, not regexps.
-		var pattern string
-		switch {
-		case v.String != nil:
-			pattern = regexp.QuoteMeta(*v.String)
-		case v.Regexp != nil:
-			pattern = v.Regexp.String()
+		// Negated patterns are handled by searchNegatedPatterns.
+		if v.Not() {
+			continue
 		}
+		pattern := patternString(v)
 		if pattern == "" {
 			continue
 		}
@@ -582,6 +580,7 @@ func (r *searchResolver) getPatternInfo() (*search.PatternInfo, error) {
 	patternInfo := &search.PatternInfo{
 		IsRegExp:                     true,
 		IsCaseSensitive:              r.query.IsCaseSensitive(),
+		IsMultiline:                  r.query.IsMultiline(),
 		FileMatchLimit:               r.maxResults(),
 		Pattern:                      regexpPatternMatchingExprsInOrder(patternsToCombine),
 		IncludePatterns:              includePatterns,
@@ -591,9 +590,45 @@ func (r *searchResolver) getPatternInfo() (*search.PatternInfo, error) {
 	if len(excludePatterns) > 0 {
 		patternInfo.ExcludePattern = unionRegExps(excludePatterns)
 	}
+	if r.query.IsStructural() {
+		// Structural patterns are not regexps, so search for the terms
+		// as written, separated by spaces (which match any whitespace).
+		patterns, _ := r.query.StringValues(query.FieldDefault)
+		patternInfo.IsRegExp = false
+		patternInfo.IsStructural = true
+		patternInfo.Pattern = strings.Join(patterns, " ")
+	}
 	return patternInfo, nil
 }
 
+// patternString returns the regexp pattern source string for the default
+// field value v.
+func patternString(v *searchquerytypes.Value) string {
+	// Treat quoted strings as literal strings to match, not regexps.
+	switch {
+	case v.String != nil:
+		return regexp.QuoteMeta(*v.String)
+	case v.Regexp != nil:
+		return v.Regexp.String()
+	}
+	return ""
+}
+
+// negatedPatterns returns the regexp pattern source strings of the query's
+// negated default field values (e.g., "b" in "a -b").
+func (r *searchResolver) negatedPatterns() []string {
+	var patterns []string
+	for _, v := range r.query.Values(query.FieldDefault) {
+		if !v.Not() {
+			continue
+		}
+		if pattern := patternString(v); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
 var (
 	// The default timeout to use for queries.
 	defaultTimeout = 10 * time.Second
@@ -634,6 +669,10 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		tr.Finish()
 	}()
 
+	if !r.query.IsFlat() {
+		return r.doDisjunctResults(ctx, forceOnlyResultType)
+	}
+
 	start := time.Now()
 
 	ctx, cancel, err := r.withTimeout(ctx)
@@ -685,6 +724,26 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		if len(resultTypes) == 0 {
 			resultTypes = []string{"file", "path", "repo", "ref"}
 		}
+		if r.query.SelectsSymbols() {
+			// select:symbol and the symbol filters (symbolkind: and parent:)
+			// select symbol results.
+			resultTypes = []string{"symbol"}
+		}
+		if r.query.IsStructural() {
+			// Structural patterns only match file contents.
+			resultTypes = []string{"file"}
+		}
+	}
+	negatedPatterns := r.negatedPatterns()
+	if len(negatedPatterns) > 0 {
+		if err := checkNegatedResultTypes(resultTypes); err != nil {
+			return nil, err
+		}
+	}
+	if signed, _ := r.query.StringValue(query.FieldSigned); signed != "" {
+		if err := checkSignedResultTypes(resultTypes); err != nil {
+			return nil, err
+		}
 	}
 	seenResultTypes := make(map[string]struct{}, len(resultTypes))
 	for _, resultType := range resultTypes {
@@ -722,6 +781,67 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		return &optionalWg
 	}
 
+	// Files whose contents match a negated pattern (e.g., "-b") and
+	// repositories whose names match one are excluded from the results. Find
+	// the files concurrently with the other searches.
+	var (
+		excludedFiles map[string]struct{}
+		excludedRepos *regexp.Regexp
+		negatedWg     sync.WaitGroup
+	)
+	if len(negatedPatterns) > 0 {
+		excludedRepos, err = negatedRepoNamePattern(negatedPatterns, args.Pattern.IsCaseSensitive)
+		if err != nil {
+			return nil, &badRequestError{err}
+		}
+
+		requiredWg.Add(1)
+		negatedWg.Add(1)
+		goroutine.Go(func() {
+			defer requiredWg.Done()
+			defer negatedWg.Done()
+
+			excluded, limitHit, err := searchNegatedPatterns(ctx, &args, negatedPatterns)
+			// Timeouts are reported through searchResultsCommon so don't report an error for them
+			if err != nil && !isContextError(ctx, err) {
+				multiErrMu.Lock()
+				multiErr = multierror.Append(multiErr, errors.Wrap(err, "negated pattern search failed"))
+				multiErrMu.Unlock()
+			}
+			excludedFiles = excluded
+			if limitHit {
+				// Some files that match a negated pattern may not have been
+				// found, so they may remain in the results.
+				commonMu.Lock()
+				common.limitHit = true
+				commonMu.Unlock()
+			}
+		})
+	}
+
+	// streamResults sends the results of a single backend to r.stream (if
+	// any) before they are merged with the results of other backends.
+	streamResults := func(results []*searchResultResolver) {
+		if r.stream == nil || len(results) == 0 {
+			return
+		}
+		// Wait for the files that match negated patterns so that they are
+		// never sent.
+		negatedWg.Wait()
+		if len(negatedPatterns) > 0 {
+			results = excludeNegatedResults(append([]*searchResultResolver(nil), results...), excludedFiles, excludedRepos)
+		}
+		r.stream.sendResults(results)
+	}
+	updateCommon := func(other *searchResultsCommon) {
+		commonMu.Lock()
+		defer commonMu.Unlock()
+		common.update(*other)
+		if r.stream != nil {
+			r.stream.sendProgress(&common)
+		}
+	}
+
 	searchedFileContentsOrPaths := false
 	for _, resultType := range resultTypes {
 		resultType := resultType // shadow so it doesn't change in the goroutine
@@ -744,15 +864,14 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					multiErr = multierror.Append(multiErr, errors.Wrap(err, "repository search failed"))
 					multiErrMu.Unlock()
 				}
+				streamResults(repoResults)
 				if repoResults != nil {
 					resultsMu.Lock()
 					results = append(results, repoResults...)
 					resultsMu.Unlock()
 				}
 				if repoCommon != nil {
-					commonMu.Lock()
-					common.update(*repoCommon)
-					commonMu.Unlock()
+					updateCommon(repoCommon)
 				}
 			})
 		case "symbol":
@@ -768,6 +887,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					multiErr = multierror.Append(multiErr, errors.Wrap(err, "symbol search failed"))
 					multiErrMu.Unlock()
 				}
+				streamResults(fileMatchResults(symbolFileMatches))
 				for _, symbolFileMatch := range symbolFileMatches {
 					key := symbolFileMatch.uri
 					fileMatchesMu.Lock()
@@ -782,9 +902,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					fileMatchesMu.Unlock()
 				}
 				if symbolsCommon != nil {
-					commonMu.Lock()
-					common.update(*symbolsCommon)
-					commonMu.Unlock()
+					updateCommon(symbolsCommon)
 				}
 			})
 		case "file", "path":
@@ -805,6 +923,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					multiErr = multierror.Append(multiErr, errors.Wrap(err, "text search failed"))
 					multiErrMu.Unlock()
 				}
+				streamResults(fileMatchResults(fileResults))
 				for _, r := range fileResults {
 					key := r.uri
 					fileMatchesMu.Lock()
@@ -822,9 +941,7 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					fileMatchesMu.Unlock()
 				}
 				if fileCommon != nil {
-					commonMu.Lock()
-					common.update(*fileCommon)
-					commonMu.Unlock()
+					updateCommon(fileCommon)
 				}
 			})
 		case "diff":
@@ -839,15 +956,14 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					multiErr = multierror.Append(multiErr, errors.Wrap(err, "diff search failed"))
 					multiErrMu.Unlock()
 				}
+				streamResults(diffResults)
 				if diffResults != nil {
 					resultsMu.Lock()
 					results = append(results, diffResults...)
 					resultsMu.Unlock()
 				}
 				if diffCommon != nil {
-					commonMu.Lock()
-					common.update(*diffCommon)
-					commonMu.Unlock()
+					updateCommon(diffCommon)
 				}
 			})
 		case "commit":
@@ -863,15 +979,14 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 					multiErr = multierror.Append(multiErr, errors.Wrap(err, "commit search failed"))
 					multiErrMu.Unlock()
 				}
+				streamResults(commitResults)
 				if commitResults != nil {
 					resultsMu.Lock()
 					results = append(results, commitResults...)
 					resultsMu.Unlock()
 				}
 				if commitCommon != nil {
-					commonMu.Lock()
-					common.update(*commitCommon)
-					commonMu.Unlock()
+					updateCommon(commitCommon)
 				}
 			})
 		}
@@ -900,6 +1015,10 @@ func (r *searchResolver) doResults(ctx context.Context, forceOnlyResultType stri
 		alert = r.alertForMissingRepoRevs(missingRepoRevs)
 	}
 
+	if len(negatedPatterns) > 0 {
+		results = excludeNegatedResults(results, excludedFiles, excludedRepos)
+	}
+
 	// If we have some results, only log the error instead of returning it,
 	// because otherwise the client would not receive the partial results
 	if len(results) > 0 && multiErr != nil {
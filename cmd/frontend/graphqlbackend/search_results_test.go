@@ -164,6 +164,11 @@ func TestSearchResolver_getPatternInfo(t *testing.T) {
 			IsRegExp:               true,
 			PathPatternsAreRegExps: true,
 		},
+		"p1 -p2": {
+			Pattern:                "p1",
+			IsRegExp:               true,
+			PathPatternsAreRegExps: true,
+		},
 		"p case:yes": {
 			Pattern:                      "p",
 			IsRegExp:                     true,
@@ -171,6 +176,18 @@ func TestSearchResolver_getPatternInfo(t *testing.T) {
 			PathPatternsAreRegExps:       true,
 			PathPatternsAreCaseSensitive: true,
 		},
+		"p multiline:yes": {
+			Pattern:                "p",
+			IsRegExp:               true,
+			IsMultiline:            true,
+			PathPatternsAreRegExps: true,
+		},
+		"patterntype:structural f(:[a], :[b]) file:f": {
+			Pattern:                "f(:[a], :[b])",
+			IsStructural:           true,
+			PathPatternsAreRegExps: true,
+			IncludePatterns:        []string{"f"},
+		},
 		"p file:f": {
 			Pattern:                "p",
 			IsRegExp:               true,
@@ -0,0 +1,275 @@
+package graphqlbackend
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+// This file contains the streaming of search results. Instead of waiting for
+// all backends (repository, text, symbol, diff and commit search) to finish,
+// each backend's results are sent as soon as it returns them, followed by the
+// progress of the search so far.
+
+// searchStream receives the results of a search while it is running.
+type searchStream interface {
+	// sendResults is called with the results of a single backend.
+	sendResults(results []*searchResultResolver)
+
+	// sendProgress is called with the combined searchResultsCommon of the
+	// backends that have finished.
+	sendProgress(common *searchResultsCommon)
+}
+
+// SearchMatch is a search result sent by StreamSearch.
+type SearchMatch struct {
+	// Type is "file", "symbol", "commit", "diff" or "repo".
+	Type       string `json:"type"`
+	Repository string `json:"repository"`
+	URL        string `json:"url"`
+
+	// Commit is the commit ID of a file, symbol, commit or diff match (or
+	// empty for a file or symbol match on the default branch).
+	Commit string `json:"commit,omitempty"`
+
+	// Path, LineMatches and Symbols are set for file and symbol matches.
+	Path        string            `json:"path,omitempty"`
+	LineMatches []SearchLineMatch `json:"lineMatches,omitempty"`
+	Symbols     []SearchSymbol    `json:"symbols,omitempty"`
+
+	// Message, Author, Date and Preview are set for commit and diff
+	// matches. Preview is the matching part of the commit message or diff.
+	Message string `json:"message,omitempty"`
+	Author  string `json:"author,omitempty"`
+	Date    string `json:"date,omitempty"`
+	Preview string `json:"preview,omitempty"`
+
+	LimitHit bool `json:"limitHit,omitempty"`
+}
+
+// SearchLineMatch is a line match in a SearchMatch.
+type SearchLineMatch struct {
+	LineNumber       int32      `json:"lineNumber"`
+	EndLineNumber    int32      `json:"endLineNumber"`
+	Preview          string     `json:"preview"`
+	OffsetAndLengths [][2]int32 `json:"offsetAndLengths"`
+}
+
+// SearchSymbol is a symbol in a SearchMatch.
+type SearchSymbol struct {
+	Name          string `json:"name"`
+	ContainerName string `json:"containerName,omitempty"`
+	Kind          string `json:"kind"`
+	URL           string `json:"url"`
+}
+
+// SearchProgress is the progress of a search sent by StreamSearch. The
+// repositories are those reported by the backends that have finished.
+type SearchProgress struct {
+	RepositoriesSearched        int      `json:"repositoriesSearched"`
+	IndexedRepositoriesSearched int      `json:"indexedRepositoriesSearched"`
+	Cloning                     []string `json:"cloning"`
+	Missing                     []string `json:"missing"`
+	Timedout                    []string `json:"timedout"`
+	LimitHit                    bool     `json:"limitHit"`
+}
+
+// SearchSummary is the summary of a search returned by StreamSearch after
+// all of its results have been sent.
+type SearchSummary struct {
+	SearchProgress
+	ResultCount         int32              `json:"resultCount"`
+	ElapsedMilliseconds int32              `json:"elapsedMilliseconds"`
+	Alert               *SearchStreamAlert `json:"alert,omitempty"`
+}
+
+// SearchStreamAlert is an alert message that should be displayed with the
+// results of a search.
+type SearchStreamAlert struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// StreamSearch runs the search query and calls send with each batch of
+// matches ("matches" event with a []SearchMatch) and with the progress of
+// the search ("progress" event with a *SearchProgress) while it is running.
+// The calls to send are not concurrent. Canceling ctx stops the search.
+//
+// Unlike the GraphQL search results, matches are not sorted or merged: a
+// file can be sent once with its line matches and once with its symbols,
+// and for queries with "or" the same result can be sent more than once.
+func StreamSearch(ctx context.Context, q string, send func(event string, data interface{})) (*SearchSummary, error) {
+	parsed, err := query.ParseAndCheck(q)
+	if err != nil {
+		return nil, err
+	}
+	stream := &sendSearchStream{
+		ctx:      ctx,
+		send:     send,
+		searched: map[string]struct{}{},
+		indexed:  map[string]struct{}{},
+		cloning:  map[string]struct{}{},
+		missing:  map[string]struct{}{},
+		timedout: map[string]struct{}{},
+	}
+	rr, err := (&searchResolver{query: parsed, stream: stream}).doResults(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	stream.addCommon(&rr.searchResultsCommon)
+	summary := &SearchSummary{
+		SearchProgress:      stream.progress(),
+		ResultCount:         rr.ResultCount(),
+		ElapsedMilliseconds: rr.ElapsedMilliseconds(),
+	}
+	summary.LimitHit = rr.LimitHit()
+	if rr.alert != nil {
+		summary.Alert = &SearchStreamAlert{Title: rr.alert.title, Description: rr.alert.description}
+	}
+	return summary, nil
+}
+
+// sendSearchStream is the searchStream of StreamSearch. The progress of a
+// query with boolean operators is the union of the progress of each of its
+// disjuncts, which are searched separately.
+type sendSearchStream struct {
+	ctx  context.Context
+	send func(event string, data interface{})
+
+	mu                                            sync.Mutex
+	searched, indexed, cloning, missing, timedout map[string]struct{} // repository names
+	limitHit                                      bool
+}
+
+func (s *sendSearchStream) sendResults(results []*searchResultResolver) {
+	matches := make([]SearchMatch, 0, len(results))
+	for _, result := range results {
+		matches = append(matches, toSearchMatch(s.ctx, result))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.send("matches", matches)
+}
+
+func (s *sendSearchStream) sendProgress(common *searchResultsCommon) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addCommon(common)
+	progress := s.progress()
+	s.send("progress", &progress)
+}
+
+// addCommon adds the repositories of common to the progress. The caller must
+// hold s.mu.
+func (s *sendSearchStream) addCommon(common *searchResultsCommon) {
+	add := func(names map[string]struct{}, repos []*types.Repo) {
+		for _, repo := range repos {
+			names[string(repo.Name)] = struct{}{}
+		}
+	}
+	add(s.searched, common.searched)
+	add(s.indexed, common.indexed)
+	add(s.cloning, common.cloning)
+	add(s.missing, common.missing)
+	add(s.timedout, common.timedout)
+	s.limitHit = s.limitHit || common.limitHit
+}
+
+// progress returns the progress so far. The caller must hold s.mu.
+func (s *sendSearchStream) progress() SearchProgress {
+	sorted := func(names map[string]struct{}) []string {
+		list := make([]string, 0, len(names))
+		for name := range names {
+			list = append(list, name)
+		}
+		sort.Strings(list)
+		return list
+	}
+	return SearchProgress{
+		RepositoriesSearched:        len(s.searched),
+		IndexedRepositoriesSearched: len(s.indexed),
+		Cloning:                     sorted(s.cloning),
+		Missing:                     sorted(s.missing),
+		Timedout:                    sorted(s.timedout),
+		LimitHit:                    s.limitHit,
+	}
+}
+
+func toSearchMatch(ctx context.Context, result *searchResultResolver) SearchMatch {
+	switch {
+	case result.repo != nil:
+		return SearchMatch{
+			Type:       "repo",
+			Repository: string(result.repo.repo.Name),
+			URL:        result.repo.URL(),
+		}
+
+	case result.fileMatch != nil:
+		fm := result.fileMatch
+		m := SearchMatch{
+			Type:       "file",
+			Repository: string(fm.repo.Name),
+			URL:        fm.File().URL(ctx),
+			Commit:     string(fm.commitID),
+			Path:       fm.JPath,
+			LimitHit:   fm.JLimitHit,
+		}
+		if len(fm.symbols) > 0 {
+			m.Type = "symbol"
+		}
+		for _, lm := range fm.JLineMatches {
+			m.LineMatches = append(m.LineMatches, SearchLineMatch{
+				LineNumber:       lm.JLineNumber,
+				EndLineNumber:    lm.EndLineNumber(),
+				Preview:          lm.JPreview,
+				OffsetAndLengths: lm.JOffsetAndLengths,
+			})
+		}
+		for _, sym := range fm.symbols {
+			m.Symbols = append(m.Symbols, SearchSymbol{
+				Name:          sym.symbol.Name,
+				ContainerName: sym.symbol.ContainerName,
+				Kind:          sym.Kind(),
+				URL:           sym.URL(ctx),
+			})
+		}
+		return m
+
+	default:
+		commit := result.diff.commit
+		m := SearchMatch{
+			Type:       "commit",
+			Repository: string(commit.repo.repo.Name),
+			URL:        commit.URL(),
+			Commit:     string(commit.oid),
+			Message:    commit.message,
+			Date:       commit.author.Date(),
+		}
+		if commit.author.person != nil {
+			m.Author = commit.author.person.name
+		}
+		if result.diff.diffPreview != nil {
+			m.Type = "diff"
+			m.Preview = result.diff.diffPreview.value
+		} else if result.diff.messagePreview != nil {
+			m.Preview = result.diff.messagePreview.value
+		}
+		return m
+	}
+}
+
+// fileMatchResults returns the search results for the file matches.
+func fileMatchResults(fileMatches []*fileMatchResolver) []*searchResultResolver {
+	results := make([]*searchResultResolver, len(fileMatches))
+	for i, fm := range fileMatches {
+		results[i] = &searchResultResolver{fileMatch: fm}
+	}
+	return results
+}
@@ -0,0 +1,68 @@
+package graphqlbackend
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+func TestStreamSearch(t *testing.T) {
+	db.Mocks.Repos.List = func(_ context.Context, op db.ReposListOptions) ([]*types.Repo, error) {
+		return []*types.Repo{{ID: 1, Name: "repo"}, {ID: 2, Name: "cloning"}}, nil
+	}
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	repo := &types.Repo{ID: 1, Name: "repo"}
+	mockSearchFilesInRepos = func(args *search.Args) ([]*fileMatchResolver, *searchResultsCommon, error) {
+		if args.Pattern.Pattern == "bar" {
+			// The files that match the negated pattern.
+			return []*fileMatchResolver{{uri: "git://repo#b", JPath: "b", repo: repo}}, &searchResultsCommon{}, nil
+		}
+		return []*fileMatchResolver{
+			{uri: "git://repo#a", JPath: "a", repo: repo, JLineMatches: []*lineMatch{{JLineNumber: 1, JPreview: "foo", JOffsetAndLengths: [][2]int32{{0, 3}}}}},
+			{uri: "git://repo#b", JPath: "b", repo: repo},
+		}, &searchResultsCommon{
+			searched: []*types.Repo{repo},
+			cloning:  []*types.Repo{{ID: 2, Name: "cloning"}},
+		}, nil
+	}
+	defer func() { mockSearchFilesInRepos = nil }()
+
+	type event struct {
+		name string
+		data interface{}
+	}
+	var events []event
+	summary, err := StreamSearch(context.Background(), "foo -bar type:file", func(name string, data interface{}) {
+		events = append(events, event{name: name, data: data})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantEvents := []event{
+		{name: "matches", data: []SearchMatch{{
+			Type:        "file",
+			Repository:  "repo",
+			URL:         "/repo/-/blob/a",
+			Path:        "a",
+			LineMatches: []SearchLineMatch{{LineNumber: 1, EndLineNumber: 1, Preview: "foo", OffsetAndLengths: [][2]int32{{0, 3}}}},
+		}}},
+		{name: "progress", data: &SearchProgress{
+			RepositoriesSearched: 1,
+			Cloning:              []string{"cloning"},
+			Missing:              []string{},
+			Timedout:             []string{},
+		}},
+	}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("got events %+v, want %+v", events, wantEvents)
+	}
+	if summary.ResultCount != 1 || summary.RepositoriesSearched != 1 {
+		t.Errorf("got summary %+v, want 1 result in 1 repository", summary)
+	}
+}
@@ -40,6 +40,12 @@ func (r *searchResolver) Suggestions(ctx context.Context, args *searchSuggestion
 		return nil, nil
 	}
 
+	// Suggestions are not yet supported for queries with boolean operators
+	// or structural patterns.
+	if !r.query.IsFlat() || r.query.IsStructural() {
+		return nil, nil
+	}
+
 	// Only suggest for type:file.
 	typeValues, _ := r.query.StringValues(query.FieldType)
 	for _, resultType := range typeValues {
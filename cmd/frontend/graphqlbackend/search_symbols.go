@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/url"
+	"sort"
 	"strings"
 	"sync"
 
@@ -43,7 +44,11 @@ func searchSymbols(ctx context.Context, args *search.Args, limit int) (res []*fi
 		tr.Finish()
 	}()
 
-	if args.Pattern.Pattern == "" {
+	filters, err := symbolFiltersFromQuery(args.Query)
+	if err != nil {
+		return nil, nil, err
+	}
+	if args.Pattern.Pattern == "" && filters.empty() {
 		return nil, nil, nil
 	}
 
@@ -66,7 +71,7 @@ func searchSymbols(ctx context.Context, args *search.Args, limit int) (res []*fi
 		run.Acquire()
 		goroutine.Go(func() {
 			defer run.Release()
-			repoSymbols, repoErr := searchSymbolsInRepo(ctx, repoRevs, args.Pattern, args.Query, limit)
+			repoSymbols, repoErr := searchSymbolsInRepo(ctx, repoRevs, args.Pattern, filters, limit)
 			if repoErr != nil {
 				tr.LogFields(otlog.String("repo", string(repoRevs.Repo.Name)), otlog.String("repoErr", repoErr.Error()), otlog.Bool("timeout", errcode.IsTimeout(repoErr)), otlog.Bool("temporary", errcode.IsTemporary(repoErr)))
 			}
@@ -99,7 +104,55 @@ func searchSymbols(ctx context.Context, args *search.Args, limit int) (res []*fi
 	return res, common, err
 }
 
-func searchSymbolsInRepo(ctx context.Context, repoRevs *search.RepositoryRevisions, patternInfo *search.PatternInfo, query *query.Query, limit int) (res []*fileMatchResolver, err error) {
+// symbolFilters are the filters of a symbol search other than its pattern.
+type symbolFilters struct {
+	kinds  []string // ctags kinds
+	parent string   // regexp matching the name of the symbol's container
+}
+
+func (f *symbolFilters) empty() bool {
+	return len(f.kinds) == 0 && f.parent == ""
+}
+
+// symbolFiltersFromQuery returns the symbol filters of the query, given by
+// select:symbol.<kind>, symbolkind:<kind> and parent:<regexp>.
+func symbolFiltersFromQuery(q *query.Query) (*symbolFilters, error) {
+	filters := &symbolFilters{}
+	if q == nil {
+		return filters, nil
+	}
+	var err error
+	if filters.kinds, err = ctagsKindsForSymbolKinds(q.SymbolKinds()); err != nil {
+		return nil, &badRequestError{err}
+	}
+	if parents, _ := q.RegexpPatterns(query.FieldParent); len(parents) > 0 {
+		filters.parent = parents[0]
+	}
+	return filters, nil
+}
+
+// ctagsKindsForSymbolKinds returns the ctags kinds of symbols of the given
+// kinds, which are lowercase LSP symbol kinds (such as "function") or ctags
+// kinds (such as "func").
+func ctagsKindsForSymbolKinds(kinds []string) ([]string, error) {
+	var ctagsKindList []string
+	for _, kind := range kinds {
+		found := false
+		for ctagsKind, lspKind := range ctagsKinds {
+			if strings.ToLower(lspKind.String()) == kind || strings.ToLower(ctagsKind) == kind {
+				ctagsKindList = append(ctagsKindList, ctagsKind)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown symbol kind %q", kind)
+		}
+	}
+	sort.Strings(ctagsKindList)
+	return ctagsKindList, nil
+}
+
+func searchSymbolsInRepo(ctx context.Context, repoRevs *search.RepositoryRevisions, patternInfo *search.PatternInfo, filters *symbolFilters, limit int) (res []*fileMatchResolver, err error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "Search symbols in repo")
 	defer func() {
 		if err != nil {
@@ -134,6 +187,8 @@ func searchSymbolsInRepo(ctx context.Context, repoRevs *search.RepositoryRevisio
 		IsRegExp:        patternInfo.IsRegExp,
 		IncludePatterns: patternInfo.IncludePatterns,
 		ExcludePattern:  patternInfo.ExcludePattern,
+		Kinds:           filters.kinds,
+		Parent:          filters.parent,
 		First:           limit,
 	})
 	fileMatchesByURI := make(map[string]*fileMatchResolver)
@@ -208,61 +263,75 @@ func ctagsSymbolCharacter(s protocol.Symbol) int {
 	return 0
 }
 
+// ctagsKinds maps ctags kinds to LSP symbol kinds. Ctags kinds are determined by the parser and do not (in
+// general) match LSP symbol kinds.
+var ctagsKinds = map[string]lsp.SymbolKind{
+	"file":            lsp.SKFile,
+	"module":          lsp.SKModule,
+	"namespace":       lsp.SKNamespace,
+	"package":         lsp.SKPackage,
+	"subprogspec":     lsp.SKPackage,
+	"class":           lsp.SKClass,
+	"type":            lsp.SKClass,
+	"service":         lsp.SKClass,
+	"typedef":         lsp.SKClass,
+	"union":           lsp.SKClass,
+	"section":         lsp.SKClass,
+	"subtype":         lsp.SKClass,
+	"component":       lsp.SKClass,
+	"method":          lsp.SKMethod,
+	"property":        lsp.SKProperty,
+	"field":           lsp.SKField,
+	"member":          lsp.SKField,
+	"anonMember":      lsp.SKField,
+	"constructor":     lsp.SKConstructor,
+	"enum":            lsp.SKEnum,
+	"enumerator":      lsp.SKEnum,
+	"interface":       lsp.SKInterface,
+	"function":        lsp.SKFunction,
+	"func":            lsp.SKFunction,
+	"subroutine":      lsp.SKFunction,
+	"macro":           lsp.SKFunction,
+	"subprogram":      lsp.SKFunction,
+	"procedure":       lsp.SKFunction,
+	"command":         lsp.SKFunction,
+	"singletonMethod": lsp.SKFunction,
+	"variable":        lsp.SKVariable,
+	"var":             lsp.SKVariable,
+	"functionVar":     lsp.SKVariable,
+	"define":          lsp.SKVariable,
+	"alias":           lsp.SKVariable,
+	"constant":        lsp.SKConstant,
+	"const":           lsp.SKConstant,
+	"string":          lsp.SKString,
+	"message":         lsp.SKString,
+	"heredoc":         lsp.SKString,
+	"number":          lsp.SKNumber,
+	"bool":            lsp.SKBoolean,
+	"boolean":         lsp.SKBoolean,
+	"array":           lsp.SKArray,
+	"object":          lsp.SKObject,
+	"literal":         lsp.SKObject,
+	"map":             lsp.SKObject,
+	"key":             lsp.SKKey,
+	"label":           lsp.SKKey,
+	"target":          lsp.SKKey,
+	"selector":        lsp.SKKey,
+	"id":              lsp.SKKey,
+	"tag":             lsp.SKKey,
+	"null":            lsp.SKNull,
+	"enum member":     lsp.SKEnumMember,
+	"enumConstant":    lsp.SKEnumMember,
+	"struct":          lsp.SKStruct,
+	"event":           lsp.SKEvent,
+	"operator":        lsp.SKOperator,
+	"type parameter":  lsp.SKTypeParameter,
+	"annotation":      lsp.SKTypeParameter,
+}
+
 func ctagsKindToLSPSymbolKind(kind string) lsp.SymbolKind {
-	// Ctags kinds are determined by the parser and do not (in general) match LSP symbol kinds.
-	switch kind {
-	case "file":
-		return lsp.SKFile
-	case "module":
-		return lsp.SKModule
-	case "namespace":
-		return lsp.SKNamespace
-	case "package", "subprogspec":
-		return lsp.SKPackage
-	case "class", "type", "service", "typedef", "union", "section", "subtype", "component":
-		return lsp.SKClass
-	case "method":
-		return lsp.SKMethod
-	case "property":
-		return lsp.SKProperty
-	case "field", "member", "anonMember":
-		return lsp.SKField
-	case "constructor":
-		return lsp.SKConstructor
-	case "enum", "enumerator":
-		return lsp.SKEnum
-	case "interface":
-		return lsp.SKInterface
-	case "function", "func", "subroutine", "macro", "subprogram", "procedure", "command", "singletonMethod":
-		return lsp.SKFunction
-	case "variable", "var", "functionVar", "define", "alias":
-		return lsp.SKVariable
-	case "constant", "const":
-		return lsp.SKConstant
-	case "string", "message", "heredoc":
-		return lsp.SKString
-	case "number":
-		return lsp.SKNumber
-	case "bool", "boolean":
-		return lsp.SKBoolean
-	case "array":
-		return lsp.SKArray
-	case "object", "literal", "map":
-		return lsp.SKObject
-	case "key", "label", "target", "selector", "id", "tag":
-		return lsp.SKKey
-	case "null":
-		return lsp.SKNull
-	case "enum member", "enumConstant":
-		return lsp.SKEnumMember
-	case "struct":
-		return lsp.SKStruct
-	case "event":
-		return lsp.SKEvent
-	case "operator":
-		return lsp.SKOperator
-	case "type parameter", "annotation":
-		return lsp.SKTypeParameter
+	if lspKind, ok := ctagsKinds[kind]; ok {
+		return lspKind
 	}
 	log.Printf("Unknown ctags kind: %q", kind)
 	return 0
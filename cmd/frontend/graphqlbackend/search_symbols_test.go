@@ -0,0 +1,46 @@
+package graphqlbackend
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
+)
+
+func TestSymbolFiltersFromQuery(t *testing.T) {
+	tests := map[string]struct {
+		want    symbolFilters
+		wantErr bool
+	}{
+		"foo":                                {},
+		"select:symbol foo":                  {},
+		"select:symbol.struct Config$":       {want: symbolFilters{kinds: []string{"struct"}}},
+		"symbolkind:method parent:^Server$":  {want: symbolFilters{kinds: []string{"method"}, parent: "^Server$"}},
+		"symbolkind:constant":                {want: symbolFilters{kinds: []string{"const", "constant"}}},
+		"symbolkind:func":                    {want: symbolFilters{kinds: []string{"func"}}},
+		"symbolkind:enummember":              {want: symbolFilters{kinds: []string{"enum member", "enumConstant"}}},
+		"select:symbol.bogus":                {wantErr: true},
+		"symbolkind:method symbolkind:bogus": {wantErr: true},
+	}
+	for input, test := range tests {
+		t.Run(input, func(t *testing.T) {
+			q, err := query.ParseAndCheck(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filters, err := symbolFiltersFromQuery(q)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got nil error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*filters, test.want) {
+				t.Errorf("got %+v, want %+v", *filters, test.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package graphqlbackend
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -16,6 +17,7 @@ import (
 	"unicode/utf8"
 
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/go-lsp"
 
 	"github.com/opentracing-contrib/go-stdlib/nethttp"
 	opentracing "github.com/opentracing/opentracing-go"
@@ -104,10 +106,12 @@ func (fm *fileMatchResolver) LimitHit() bool {
 
 // LineMatch is the struct used by vscode to receive search results for a line
 type lineMatch struct {
-	JPreview          string     `json:"Preview"`
-	JOffsetAndLengths [][2]int32 `json:"OffsetAndLengths"`
-	JLineNumber       int32      `json:"LineNumber"`
-	JLimitHit         bool       `json:"LimitHit"`
+	JPreview          string      `json:"Preview"`
+	JOffsetAndLengths [][2]int32  `json:"OffsetAndLengths"`
+	JLineNumber       int32       `json:"LineNumber"`
+	JEndLineNumber    int32       `json:"EndLineNumber"`
+	JRanges           []lsp.Range `json:"Ranges"`
+	JLimitHit         bool        `json:"LimitHit"`
 }
 
 func (lm *lineMatch) Preview() string {
@@ -118,6 +122,54 @@ func (lm *lineMatch) LineNumber() int32 {
 	return lm.JLineNumber
 }
 
+func (lm *lineMatch) EndLineNumber() int32 {
+	if lm.JEndLineNumber < lm.JLineNumber {
+		// BACKCOMPAT: Old searchers do not send EndLineNumber.
+		return lm.JLineNumber
+	}
+	return lm.JEndLineNumber
+}
+
+func (lm *lineMatch) Ranges() []*rangeResolver {
+	ranges := lm.JRanges
+	if ranges == nil {
+		// Zoekt (and old searchers) only report the offsets in the preview.
+		ranges = previewRanges(lm.JPreview, lm.JLineNumber, lm.JOffsetAndLengths)
+	}
+	r := make([]*rangeResolver, len(ranges))
+	for i := range ranges {
+		r[i] = &rangeResolver{ranges[i]}
+	}
+	return r
+}
+
+// previewRanges returns the ranges in the file of the matches in preview,
+// which starts at line lineNumber. The offsets and lengths are measured in
+// characters and may span newlines in the preview.
+func previewRanges(preview string, lineNumber int32, offsetAndLengths [][2]int32) []lsp.Range {
+	runes := []rune(preview)
+	position := func(offset int32) lsp.Position {
+		if int(offset) > len(runes) {
+			offset = int32(len(runes))
+		}
+		pos := lsp.Position{Line: int(lineNumber)}
+		for _, r := range runes[:offset] {
+			if r == '\n' {
+				pos.Line++
+				pos.Character = 0
+			} else {
+				pos.Character++
+			}
+		}
+		return pos
+	}
+	ranges := make([]lsp.Range, len(offsetAndLengths))
+	for i, ol := range offsetAndLengths {
+		ranges[i] = lsp.Range{Start: position(ol[0]), End: position(ol[0] + ol[1])}
+	}
+	return ranges
+}
+
 func (lm *lineMatch) OffsetAndLengths() [][]int32 {
 	r := make([][]int32, len(lm.JOffsetAndLengths))
 	for i := range lm.JOffsetAndLengths {
@@ -139,55 +191,10 @@ func textSearch(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p
 		tr.Finish()
 	}()
 
-	// Combine IncludePattern and IncludePatterns.
-	//
-	// NOTE: This makes it easier to (in the future) remove support for
-	// IncludePattern from searcher and only have it consult IncludePatterns.
-	// We still need to send IncludePattern (because searcher isn't guaranteed
-	// to be upgraded yet).
-	var includePatterns []string
-	if p.IncludePattern != "" {
-		includePatterns = append(includePatterns, p.IncludePattern)
-	}
-	includePatterns = append(includePatterns, p.IncludePatterns...)
-
-	q := url.Values{
-		"Repo":            []string{string(repo.Name)},
-		"URL":             []string{repo.URL},
-		"Commit":          []string{string(commit)},
-		"Pattern":         []string{p.Pattern},
-		"ExcludePattern":  []string{p.ExcludePattern},
-		"IncludePatterns": includePatterns,
-		"IncludePattern":  []string{p.IncludePattern},
-		"FetchTimeout":    []string{fetchTimeout.String()},
-	}
-	if deadline, ok := ctx.Deadline(); ok {
-		t, err := deadline.MarshalText()
-		if err != nil {
-			return nil, false, err
-		}
-		q.Set("Deadline", string(t))
-	}
-	q.Set("FileMatchLimit", strconv.FormatInt(int64(p.FileMatchLimit), 10))
-	if p.IsRegExp {
-		q.Set("IsRegExp", "true")
-	}
-	if p.IsWordMatch {
-		q.Set("IsWordMatch", "true")
-	}
-	if p.IsCaseSensitive {
-		q.Set("IsCaseSensitive", "true")
-	}
-	if p.PathPatternsAreRegExps {
-		q.Set("PathPatternsAreRegExps", "true")
-	}
-	if p.PathPatternsAreCaseSensitive {
-		q.Set("PathPatternsAreCaseSensitive", "true")
+	q, err := searcherParams(ctx, repo, commit, p, fetchTimeout)
+	if err != nil {
+		return nil, false, err
 	}
-	// TEMP BACKCOMPAT: always set even if false so that searcher can distinguish new frontends that send
-	// these fields from old frontends that do not (and provide a default in the latter case).
-	q.Set("PatternMatchesContent", strconv.FormatBool(p.PatternMatchesContent))
-	q.Set("PatternMatchesPath", strconv.FormatBool(p.PatternMatchesPath))
 	rawQuery := q.Encode()
 
 	// Searcher caches the file contents for repo@commit since it is
@@ -245,6 +252,67 @@ func textSearch(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p
 	}
 }
 
+// searcherParams returns the searcher request parameters for searching
+// repo@commit with p.
+func searcherParams(ctx context.Context, repo gitserver.Repo, commit api.CommitID, p *search.PatternInfo, fetchTimeout time.Duration) (url.Values, error) {
+	// Combine IncludePattern and IncludePatterns.
+	//
+	// NOTE: This makes it easier to (in the future) remove support for
+	// IncludePattern from searcher and only have it consult IncludePatterns.
+	// We still need to send IncludePattern (because searcher isn't guaranteed
+	// to be upgraded yet).
+	var includePatterns []string
+	if p.IncludePattern != "" {
+		includePatterns = append(includePatterns, p.IncludePattern)
+	}
+	includePatterns = append(includePatterns, p.IncludePatterns...)
+
+	q := url.Values{
+		"Repo":            []string{string(repo.Name)},
+		"URL":             []string{repo.URL},
+		"Commit":          []string{string(commit)},
+		"Pattern":         []string{p.Pattern},
+		"ExcludePattern":  []string{p.ExcludePattern},
+		"IncludePatterns": includePatterns,
+		"IncludePattern":  []string{p.IncludePattern},
+		"FetchTimeout":    []string{fetchTimeout.String()},
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		t, err := deadline.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		q.Set("Deadline", string(t))
+	}
+	q.Set("FileMatchLimit", strconv.FormatInt(int64(p.FileMatchLimit), 10))
+	if p.IsRegExp {
+		q.Set("IsRegExp", "true")
+	}
+	if p.IsWordMatch {
+		q.Set("IsWordMatch", "true")
+	}
+	if p.IsCaseSensitive {
+		q.Set("IsCaseSensitive", "true")
+	}
+	if p.IsMultiline {
+		q.Set("IsMultiline", "true")
+	}
+	if p.IsStructural {
+		q.Set("IsStructural", "true")
+	}
+	if p.PathPatternsAreRegExps {
+		q.Set("PathPatternsAreRegExps", "true")
+	}
+	if p.PathPatternsAreCaseSensitive {
+		q.Set("PathPatternsAreCaseSensitive", "true")
+	}
+	// TEMP BACKCOMPAT: always set even if false so that searcher can distinguish new frontends that send
+	// these fields from old frontends that do not (and provide a default in the latter case).
+	q.Set("PatternMatchesContent", strconv.FormatBool(p.PatternMatchesContent))
+	q.Set("PatternMatchesPath", strconv.FormatBool(p.PatternMatchesPath))
+	return q, nil
+}
+
 func textSearchURL(ctx context.Context, url string) ([]*fileMatchResolver, bool, error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
@@ -518,9 +586,11 @@ func zoektSearchHEAD(ctx context.Context, query *search.PatternInfo, repos []*se
 					length := utf8.RuneCount(l.Line[m.LineOffset : m.LineOffset+m.MatchLength])
 					offsets[k] = [2]int32{int32(offset), int32(length)}
 				}
+				// Zoekt extends Line to include every line spanned by a match.
 				lines = append(lines, &lineMatch{
 					JPreview:          string(l.Line),
 					JLineNumber:       int32(l.LineNumber - 1),
+					JEndLineNumber:    int32(l.LineNumber - 1 + bytes.Count(l.Line, []byte{'\n'})),
 					JOffsetAndLengths: offsets,
 				})
 			}
@@ -732,6 +802,13 @@ func searchFilesInRepos(ctx context.Context, args *search.Args) (res []*fileMatc
 		}
 	}
 
+	if args.Pattern.IsStructural && len(zoektRepos) > 0 {
+		// Zoekt does not support structural search.
+		tr.LazyPrintf("structural search, bypassing zoekt (using searcher) for %d indexed repos", len(zoektRepos))
+		searcherRepos = append(searcherRepos, zoektRepos...)
+		zoektRepos = nil
+	}
+
 	var (
 		wg                sync.WaitGroup
 		mu                sync.Mutex
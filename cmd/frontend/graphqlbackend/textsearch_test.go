@@ -9,6 +9,7 @@ import (
 
 	zoektquery "github.com/google/zoekt/query"
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/go-lsp"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
@@ -205,6 +206,19 @@ func TestSearchFilesInRepos(t *testing.T) {
 	}
 }
 
+func TestPreviewRanges(t *testing.T) {
+	preview := "func föo(\n\tctx context.Context) {"
+	got := previewRanges(preview, 3, [][2]int32{{5, 3}, {0, 14}, {9, 1}})
+	want := []lsp.Range{
+		{Start: lsp.Position{Line: 3, Character: 5}, End: lsp.Position{Line: 3, Character: 8}},
+		{Start: lsp.Position{Line: 3, Character: 0}, End: lsp.Position{Line: 4, Character: 4}},
+		{Start: lsp.Position{Line: 3, Character: 9}, End: lsp.Position{Line: 4, Character: 0}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
 func makeRepositoryRevisions(repos ...string) []*search.RepositoryRevisions {
 	r := make([]*search.RepositoryRevisions, len(repos))
 	for i, repospec := range repos {
@@ -34,6 +34,7 @@ func (*schemaResolver) CreateUser(ctx context.Context, args *struct {
 	if err != nil {
 		return nil, err
 	}
+	db.UserPermissions.SyncInBackground(user.ID)
 	return &createUserResult{user: user}, nil
 }
 
@@ -120,6 +120,7 @@ func handleSignUp(w http.ResponseWriter, r *http.Request, failIfNewUserIsNotInit
 		http.Error(w, message, statusCode)
 		return
 	}
+	db.UserPermissions.SyncInBackground(usr.ID)
 	actor := &actor.Actor{UID: usr.ID}
 
 	if conf.EmailVerificationRequired() && !newUserData.EmailIsVerified {
@@ -2,6 +2,7 @@ package httpapi
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
@@ -35,8 +36,11 @@ func AccessTokenAuthMiddleware(next http.Handler) http.Handler {
 			token, sudoUser, err = authz.ParseAuthorizationHeader(headerValue)
 			if err != nil {
 				if authz.IsUnrecognizedScheme(err) {
-					// Ignore Authorization headers that we don't handle.
-					log15.Warn("Ignoring unrecognized Authorization header.", "err", err, "value", headerValue)
+					// Ignore Authorization headers that we don't handle. (SCIM clients send bearer
+					// tokens, which the SCIM handlers check themselves.)
+					if !strings.HasPrefix(r.URL.Path, scimPathPrefix) {
+						log15.Warn("Ignoring unrecognized Authorization header.", "err", err, "value", headerValue)
+					}
 					next.ServeHTTP(w, r)
 					return
 				}
@@ -39,6 +39,16 @@ func NewHandler(m *mux.Router) http.Handler {
 
 	m.Get(apirouter.Telemetry).Handler(trace.TraceRoute(telemetryHandler))
 
+	m.Get(apirouter.SearchStream).Handler(trace.TraceRoute(http.HandlerFunc(serveSearchStream)))
+
+	m.Get(apirouter.GitHubWebhooks).Handler(trace.TraceRoute(handler(serveGitHubWebhook)))
+	m.Get(apirouter.GitLabWebhooks).Handler(trace.TraceRoute(handler(serveGitLabWebhook)))
+
+	m.Get(apirouter.SCIMUsers).Handler(trace.TraceRoute(scimHandler(serveSCIMUsers)))
+	m.Get(apirouter.SCIMUser).Handler(trace.TraceRoute(scimHandler(serveSCIMUser)))
+	m.Get(apirouter.SCIMGroups).Handler(trace.TraceRoute(scimHandler(serveSCIMGroups)))
+	m.Get(apirouter.SCIMGroup).Handler(trace.TraceRoute(scimHandler(serveSCIMGroup)))
+
 	if envvar.SourcegraphDotComMode() {
 		m.Path("/updates").Methods("GET").Name("updatecheck").Handler(trace.TraceRoute(http.HandlerFunc(updatecheck.Handler)))
 	}
@@ -97,6 +107,9 @@ func NewInternalHandler(m *mux.Router) http.Handler {
 	m.Get(apirouter.Telemetry).Handler(trace.TraceRoute(telemetryHandler))
 	m.Get(apirouter.GraphQL).Handler(trace.TraceRoute(handler(serveGraphQL)))
 	m.Get(apirouter.Configuration).Handler(trace.TraceRoute(handler(serveConfiguration)))
+	m.Get(apirouter.UserPermissionsListStale).Handler(trace.TraceRoute(handler(serveUserPermissionsListStale)))
+	m.Get(apirouter.UserPermissionsSync).Handler(trace.TraceRoute(handler(serveUserPermissionsSync)))
+	m.Get(apirouter.UserPermissionsSyncNewRepos).Handler(trace.TraceRoute(handler(serveUserPermissionsSyncNewRepos)))
 	m.Path("/ping").Methods("GET").Name("ping").HandlerFunc(handlePing)
 
 	m.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
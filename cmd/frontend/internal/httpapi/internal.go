@@ -12,6 +12,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
@@ -542,3 +543,41 @@ func packetWrite(str string) []byte {
 func handlePing(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("pong"))
 }
+
+func serveUserPermissionsListStale(w http.ResponseWriter, r *http.Request) error {
+	var req api.UserPermissionsListStaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.Wrap(err, "Decode")
+	}
+
+	// Without authz providers, there are no permissions to sync.
+	resp := api.UserPermissionsListStaleResponse{Users: []api.StaleUserPermissions{}}
+	if _, providers := authz.GetProviders(); len(providers) > 0 {
+		var err error
+		resp.Users, resp.Total, err = db.UserPermissions.ListStale(r.Context(), req.SyncedBefore, req.Limit)
+		if err != nil {
+			return errors.Wrap(err, "UserPermissions.ListStale")
+		}
+	}
+	return json.NewEncoder(w).Encode(resp)
+}
+
+func serveUserPermissionsSync(w http.ResponseWriter, r *http.Request) error {
+	var req api.UserPermissionsSyncRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return errors.Wrap(err, "Decode")
+	}
+	if err := db.UserPermissions.Sync(r.Context(), req.UserID); err != nil {
+		return errors.Wrap(err, "UserPermissions.Sync")
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+func serveUserPermissionsSyncNewRepos(w http.ResponseWriter, r *http.Request) error {
+	if err := db.UserPermissions.SyncNewRepos(r.Context()); err != nil {
+		return errors.Wrap(err, "UserPermissions.SyncNewRepos")
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
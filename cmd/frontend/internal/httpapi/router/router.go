@@ -11,9 +11,18 @@ const (
 
 	Registry = "registry"
 
-	RepoShield  = "repo.shield"
-	RepoRefresh = "repo.refresh"
-	Telemetry   = "telemetry"
+	RepoShield   = "repo.shield"
+	RepoRefresh  = "repo.refresh"
+	SearchStream = "search.stream"
+	Telemetry    = "telemetry"
+
+	GitHubWebhooks = "webhooks.github"
+	GitLabWebhooks = "webhooks.gitlab"
+
+	SCIMUsers  = "scim.users"
+	SCIMUser   = "scim.user"
+	SCIMGroups = "scim.groups"
+	SCIMGroup  = "scim.group"
 
 	SavedQueriesListAll    = "internal.saved-queries.list-all"
 	SavedQueriesGetInfo    = "internal.saved-queries.get-info"
@@ -44,6 +53,10 @@ const (
 	Configuration          = "internal.configuration"
 	ExternalServiceConfigs = "internal.external-services.configs"
 	ExternalServicesList   = "internal.external-services.list"
+
+	UserPermissionsListStale    = "internal.user-permissions.list-stale"
+	UserPermissionsSync         = "internal.user-permissions.sync"
+	UserPermissionsSyncNewRepos = "internal.user-permissions.sync-new-repos"
 )
 
 // New creates a new API router with route URL pattern definitions but
@@ -59,6 +72,16 @@ func New(base *mux.Router) *mux.Router {
 	addGraphQLRoute(base)
 	addTelemetryRoute(base)
 
+	base.Path("/search/stream").Methods("GET").Name(SearchStream)
+
+	base.Path("/webhooks/github").Methods("POST").Name(GitHubWebhooks)
+	base.Path("/webhooks/gitlab").Methods("POST").Name(GitLabWebhooks)
+
+	base.Path("/scim/v2/Users").Methods("GET", "POST").Name(SCIMUsers)
+	base.Path("/scim/v2/Users/{ID}").Methods("GET", "PUT", "PATCH", "DELETE").Name(SCIMUser)
+	base.Path("/scim/v2/Groups").Methods("GET", "POST").Name(SCIMGroups)
+	base.Path("/scim/v2/Groups/{ID}").Methods("GET", "PUT", "PATCH", "DELETE").Name(SCIMGroup)
+
 	// repo contains routes that are NOT specific to a revision. In these routes, the URL may not contain a revspec after the repo (that is, no "github.com/foo/bar@myrevspec").
 	repoPath := `/repos/` + routevar.Repo
 
@@ -108,6 +131,9 @@ func NewInternal(base *mux.Router) *mux.Router {
 	base.Path("/repos/update-metadata").Methods("POST").Name(ReposUpdateMetadata)
 	base.Path("/repos/{RepoName:.*}").Methods("POST").Name(ReposGetByName)
 	base.Path("/configuration").Methods("POST").Name(Configuration)
+	base.Path("/user-permissions/list-stale").Methods("POST").Name(UserPermissionsListStale)
+	base.Path("/user-permissions/sync").Methods("POST").Name(UserPermissionsSync)
+	base.Path("/user-permissions/sync-new-repos").Methods("POST").Name(UserPermissionsSyncNewRepos)
 	addRegistryRoute(base)
 	addGraphQLRoute(base)
 	addTelemetryRoute(base)
@@ -0,0 +1,289 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/backend"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/globals"
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// This file implements the parts of the SCIM 2.0 protocol (RFC 7643 and RFC 7644) that identity
+// providers use to provision users and groups. SCIM users are Sourcegraph users, and SCIM groups
+// are Sourcegraph organizations.
+//
+// SCIM clients are trusted as much as site admins: they can change and deactivate any user, and
+// rename, change the members of and delete any organization (not only those created with SCIM).
+
+const (
+	scimContentType = "application/scim+json"
+
+	scimSchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimSchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	// scimPathPrefix is the path prefix of all SCIM endpoints.
+	scimPathPrefix = "/.api/scim/"
+
+	// scimMaxCount is the maximum number of resources returned in a single list response.
+	scimMaxCount = 1000
+
+	// maxSCIMPayloadSize is the maximum size of a SCIM request body.
+	maxSCIMPayloadSize = 1 << 20
+)
+
+// scimError is an error that is reported to the SCIM client as a SCIM error response (RFC 7644
+// section 3.12).
+type scimError struct {
+	Status   int
+	ScimType string // the SCIM error type (such as "uniqueness"), if any
+	Detail   string
+}
+
+func (e *scimError) Error() string { return e.Detail }
+
+func scimErrorf(status int, scimType, format string, args ...interface{}) error {
+	return &scimError{Status: status, ScimType: scimType, Detail: fmt.Sprintf(format, args...)}
+}
+
+// scimHandler is a wrapper func for SCIM handlers. It authenticates the request and reports errors
+// returned by h as SCIM error responses.
+//
+// 🚨 SECURITY: The SCIM endpoints are accessible to anonymous users (because identity providers
+// send bearer tokens, which AccessTokenAuthMiddleware does not handle). The serveSCIM* handlers
+// don't check permissions themselves, so they must only be registered wrapped in scimHandler, which
+// checks every request with authenticateSCIMRequest before it is handled.
+func scimHandler(h func(http.ResponseWriter, *http.Request) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, err := authenticateSCIMRequest(r)
+		if err == nil {
+			err = h(w, r)
+		}
+		if err != nil {
+			writeSCIMError(w, r, err)
+		}
+	})
+}
+
+// authenticateSCIMRequest checks that the request has an access token with the "site-admin:scim"
+// scope whose subject user is a site admin, and returns the request with that user as the actor.
+//
+// The access token is given in the Authorization header, with either the "Bearer" scheme (which
+// identity providers use) or the "token" scheme (which the rest of the API uses).
+func authenticateSCIMRequest(r *http.Request) (*http.Request, error) {
+	if conf.AccessTokensAllow() == conf.AccessTokensNone {
+		return nil, scimErrorf(http.StatusUnauthorized, "", "Access token authorization is disabled.")
+	}
+
+	var token string
+	if parts := strings.Fields(r.Header.Get("Authorization")); len(parts) == 2 && (strings.EqualFold(parts[0], "Bearer") || parts[0] == authz.SchemeToken) {
+		token = parts[1]
+	}
+	if token == "" {
+		return nil, scimErrorf(http.StatusUnauthorized, "", "An access token is required (in the Authorization header).")
+	}
+
+	// 🚨 SECURITY: Only tokens with the SCIM scope may be used to provision users and groups.
+	subjectUserID, err := db.AccessTokens.Lookup(r.Context(), token, authz.ScopeSiteAdminSCIM)
+	if err != nil {
+		log15.Error("Invalid SCIM access token.", "err", err)
+		return nil, scimErrorf(http.StatusUnauthorized, "", "Invalid access token.")
+	}
+
+	// 🚨 SECURITY: Confirm that the token's subject is still a site admin, to prevent users from
+	// retaining the ability to provision users after being demoted.
+	if err := backend.CheckUserIsSiteAdmin(r.Context(), subjectUserID); err != nil {
+		log15.Error("SCIM access token's subject is not a site admin.", "subjectUserID", subjectUserID, "err", err)
+		return nil, scimErrorf(http.StatusForbidden, "", "The subject user of a SCIM access token must be a site admin.")
+	}
+
+	return r.WithContext(actor.WithActor(r.Context(), &actor.Actor{UID: subjectUserID})), nil
+}
+
+func writeSCIMError(w http.ResponseWriter, r *http.Request, err error) {
+	e, ok := errors.Cause(err).(*scimError)
+	if !ok {
+		switch {
+		case db.IsUsernameExists(err):
+			e = &scimError{Status: http.StatusConflict, ScimType: "uniqueness", Detail: "The username is already taken."}
+		case db.IsEmailExists(err):
+			e = &scimError{Status: http.StatusConflict, ScimType: "uniqueness", Detail: "The email address is already in use."}
+		case errcode.IsNotFound(err):
+			e = &scimError{Status: http.StatusNotFound, Detail: "Resource not found."}
+		default:
+			log15.Error("SCIM HTTP handler error response", "method", r.Method, "request_uri", r.URL.RequestURI(), "error", err)
+			e = &scimError{Status: http.StatusInternalServerError, Detail: "Unexpected error."}
+		}
+	}
+
+	w.Header().Set("cache-control", "no-cache, max-age=0")
+	writeSCIMResponse(w, e.Status, struct {
+		Schemas  []string `json:"schemas"`
+		ScimType string   `json:"scimType,omitempty"`
+		Detail   string   `json:"detail"`
+		Status   string   `json:"status"`
+	}{
+		Schemas:  []string{scimSchemaError},
+		ScimType: e.ScimType,
+		Detail:   e.Detail,
+		Status:   strconv.Itoa(e.Status),
+	})
+}
+
+func writeSCIMResponse(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log15.Error("Error writing SCIM response.", "error", err)
+	}
+}
+
+// readSCIMRequest decodes the JSON request body into v.
+func readSCIMRequest(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxSCIMPayloadSize)).Decode(v); err != nil {
+		return scimErrorf(http.StatusBadRequest, "invalidSyntax", "Invalid request body: %s.", err)
+	}
+	return nil
+}
+
+// scimResourceID returns the numeric resource ID from the request URL.
+func scimResourceID(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["ID"], 10, 32)
+	if err != nil {
+		return 0, scimErrorf(http.StatusNotFound, "", "Resource not found.")
+	}
+	return int32(id), nil
+}
+
+type scimMeta struct {
+	ResourceType string    `json:"resourceType"`
+	Created      time.Time `json:"created"`
+	LastModified time.Time `json:"lastModified"`
+	Location     string    `json:"location"`
+}
+
+func newSCIMMeta(resourceType string, id int32, created, lastModified time.Time) *scimMeta {
+	return &scimMeta{
+		ResourceType: resourceType,
+		Created:      created,
+		LastModified: lastModified,
+		Location:     globals.ExternalURL.ResolveReference(&url.URL{Path: fmt.Sprintf("%sv2/%ss/%d", scimPathPrefix, resourceType, id)}).String(),
+	}
+}
+
+type scimListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// scimListParams are the pagination and filtering parameters of a SCIM list request (RFC 7644
+// section 3.4.2).
+type scimListParams struct {
+	startIndex int // 1-based index of the first result
+	count      int
+
+	// filterAttr and filterValue are set if the request has a filter.
+	filterAttr, filterValue string
+}
+
+func parseSCIMListParams(q url.Values) (*scimListParams, error) {
+	p := scimListParams{startIndex: 1, count: 100}
+	if v := q.Get("startIndex"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, scimErrorf(http.StatusBadRequest, "invalidValue", "Invalid startIndex %q.", v)
+		}
+		if n > 1 {
+			p.startIndex = n
+		}
+	}
+	if v := q.Get("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, scimErrorf(http.StatusBadRequest, "invalidValue", "Invalid count %q.", v)
+		}
+		if n < 0 {
+			n = 0
+		}
+		if n > scimMaxCount {
+			n = scimMaxCount
+		}
+		p.count = n
+	}
+	if v := q.Get("filter"); v != "" {
+		var err error
+		p.filterAttr, p.filterValue, err = parseSCIMFilter(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+var scimEqFilter = regexp.MustCompile(`^\s*([A-Za-z][\w.]*)\s+(?i:eq)\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// parseSCIMFilter parses a SCIM filter expression. Only filters of the form `attr eq "value"` are
+// supported, which are what identity providers use to look up resources before provisioning them.
+func parseSCIMFilter(filter string) (attr, value string, err error) {
+	m := scimEqFilter.FindStringSubmatch(filter)
+	if m == nil {
+		return "", "", scimErrorf(http.StatusBadRequest, "invalidFilter", `Unsupported filter %q (only filters of the form 'attribute eq "value"' are supported).`, filter)
+	}
+	if err := json.Unmarshal([]byte(`"`+m[2]+`"`), &value); err != nil {
+		return "", "", scimErrorf(http.StatusBadRequest, "invalidFilter", "Invalid filter value in %q.", filter)
+	}
+	return m[1], value, nil
+}
+
+// scimPatchRequest is a SCIM PATCH request (RFC 7644 section 3.5.2).
+type scimPatchRequest struct {
+	Schemas    []string `json:"schemas"`
+	Operations []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	} `json:"Operations"`
+}
+
+// scimBool is a boolean that may also be given as the string "true" or "false" (as some identity
+// providers do).
+type scimBool bool
+
+func (b *scimBool) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		*b = scimBool(v)
+	case string:
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			return err
+		}
+		*b = scimBool(parsed)
+	default:
+		return fmt.Errorf("invalid boolean value %s", data)
+	}
+	return nil
+}
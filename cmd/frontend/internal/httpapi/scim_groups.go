@@ -0,0 +1,416 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/auth"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+)
+
+// scimGroup is a SCIM group resource (RFC 7643 section 4.2). Groups are organizations, and the
+// group members are the organization members.
+type scimGroup struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []scimMember `json:"members"`
+	Meta        *scimMeta    `json:"meta,omitempty"`
+}
+
+type scimMember struct {
+	Value string `json:"value"` // the user ID
+}
+
+// memberIDs returns the user IDs of the members.
+func memberIDs(members []scimMember) ([]int32, error) {
+	ids := make([]int32, 0, len(members))
+	for _, m := range members {
+		id, err := strconv.ParseInt(m.Value, 10, 32)
+		if err != nil {
+			return nil, scimErrorf(http.StatusBadRequest, "invalidValue", "Invalid member %q.", m.Value)
+		}
+		ids = append(ids, int32(id))
+	}
+	return ids, nil
+}
+
+func toSCIMGroup(ctx context.Context, org *types.Org) (*scimGroup, error) {
+	members, err := db.OrgMembers.GetByOrgID(ctx, org.ID)
+	if err != nil {
+		return nil, err
+	}
+	g := &scimGroup{
+		Schemas:     []string{scimSchemaGroup},
+		ID:          strconv.Itoa(int(org.ID)),
+		DisplayName: org.Name,
+		Members:     make([]scimMember, 0, len(members)),
+		Meta:        newSCIMMeta("Group", org.ID, org.CreatedAt, org.UpdatedAt),
+	}
+	if org.DisplayName != nil && *org.DisplayName != "" {
+		g.DisplayName = *org.DisplayName
+	}
+	for _, m := range members {
+		g.Members = append(g.Members, scimMember{Value: strconv.Itoa(int(m.UserID))})
+	}
+	return g, nil
+}
+
+// normalizeSCIMGroupName converts a SCIM group displayName to a Sourcegraph organization name.
+// Organization names have the same format as usernames.
+func normalizeSCIMGroupName(displayName string) (string, error) {
+	name, err := auth.NormalizeUsername(strings.Replace(displayName, "@", "-", -1))
+	if err != nil {
+		return "", scimErrorf(http.StatusBadRequest, "invalidValue", "Invalid displayName: %s.", err)
+	}
+	return name, nil
+}
+
+// getSCIMGroupsByDisplayName returns the organizations whose display name or name is the given
+// SCIM group displayName (or whose name is its normalized form). Renaming a group only changes the
+// display name of its organization, so the name alone would not find renamed groups.
+func getSCIMGroupsByDisplayName(ctx context.Context, displayName string) ([]*types.Org, error) {
+	orgs, err := db.Orgs.List(ctx, &db.OrgsListOptions{NameOrDisplayName: displayName})
+	if err != nil || len(orgs) > 0 {
+		return orgs, err
+	}
+	if name, err := normalizeSCIMGroupName(displayName); err == nil && name != displayName {
+		return db.Orgs.List(ctx, &db.OrgsListOptions{NameOrDisplayName: name})
+	}
+	return nil, nil
+}
+
+// serveSCIMGroups handles the SCIM /Groups endpoint, which lists and creates groups.
+func serveSCIMGroups(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case "GET":
+		return serveSCIMGroupsList(w, r)
+	case "POST":
+		return serveSCIMGroupsCreate(w, r)
+	default:
+		return scimErrorf(http.StatusMethodNotAllowed, "", "Method not allowed.")
+	}
+}
+
+func serveSCIMGroupsList(w http.ResponseWriter, r *http.Request) error {
+	params, err := parseSCIMListParams(r.URL.Query())
+	if err != nil {
+		return err
+	}
+
+	var (
+		orgs  []*types.Org
+		total int
+	)
+	if params.filterAttr != "" {
+		if !strings.EqualFold(params.filterAttr, "displayName") {
+			return scimErrorf(http.StatusBadRequest, "invalidFilter", "Filtering groups by %q is not supported.", params.filterAttr)
+		}
+		if orgs, err = getSCIMGroupsByDisplayName(r.Context(), params.filterValue); err != nil {
+			return err
+		}
+		total = len(orgs)
+	} else {
+		opt := &db.OrgsListOptions{LimitOffset: &db.LimitOffset{Limit: params.count, Offset: params.startIndex - 1}}
+		if orgs, err = db.Orgs.List(r.Context(), opt); err != nil {
+			return err
+		}
+		if total, err = db.Orgs.Count(r.Context(), *opt); err != nil {
+			return err
+		}
+	}
+
+	resources := make([]*scimGroup, 0, len(orgs))
+	for _, org := range orgs {
+		g, err := toSCIMGroup(r.Context(), org)
+		if err != nil {
+			return err
+		}
+		resources = append(resources, g)
+	}
+	writeSCIMResponse(w, http.StatusOK, &scimListResponse{
+		Schemas:      []string{scimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   params.startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+	return nil
+}
+
+func serveSCIMGroupsCreate(w http.ResponseWriter, r *http.Request) error {
+	var g scimGroup
+	if err := readSCIMRequest(r, &g); err != nil {
+		return err
+	}
+	name, err := normalizeSCIMGroupName(g.DisplayName)
+	if err != nil {
+		return err
+	}
+	members, err := memberIDs(g.Members)
+	if err != nil {
+		return err
+	}
+
+	if existing, err := getSCIMGroupsByDisplayName(r.Context(), g.DisplayName); err != nil {
+		return err
+	} else if len(existing) > 0 {
+		return scimErrorf(http.StatusConflict, "uniqueness", "A group with the displayName %q already exists.", g.DisplayName)
+	}
+	org, err := db.Orgs.Create(r.Context(), name, &g.DisplayName)
+	if err != nil {
+		return err
+	}
+	if err := updateSCIMGroupMembers(r.Context(), org.ID, members, nil); err != nil {
+		return err
+	}
+
+	resource, err := toSCIMGroup(r.Context(), org)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", resource.Meta.Location)
+	writeSCIMResponse(w, http.StatusCreated, resource)
+	return nil
+}
+
+// serveSCIMGroup handles the SCIM /Groups/{id} endpoint, which gets, updates and deletes a group.
+func serveSCIMGroup(w http.ResponseWriter, r *http.Request) error {
+	id, err := scimResourceID(r)
+	if err != nil {
+		return err
+	}
+	org, err := db.Orgs.GetByID(r.Context(), id)
+	if err != nil {
+		if _, ok := err.(*db.OrgNotFoundError); ok {
+			return scimErrorf(http.StatusNotFound, "", "Resource not found.")
+		}
+		return err
+	}
+
+	switch r.Method {
+	case "GET":
+		// No update.
+
+	case "PUT":
+		var g scimGroup
+		if err := readSCIMRequest(r, &g); err != nil {
+			return err
+		}
+		add, err := memberIDs(g.Members)
+		if err != nil {
+			return err
+		}
+		current, err := db.OrgMembers.GetByOrgID(r.Context(), org.ID)
+		if err != nil {
+			return err
+		}
+		var remove []int32
+		for _, m := range current {
+			remove = append(remove, m.UserID)
+		}
+		if g.DisplayName != "" {
+			if org, err = db.Orgs.Update(r.Context(), org.ID, &g.DisplayName); err != nil {
+				return err
+			}
+		}
+		if err := updateSCIMGroupMembers(r.Context(), org.ID, add, remove); err != nil {
+			return err
+		}
+
+	case "PATCH":
+		var req scimPatchRequest
+		if err := readSCIMRequest(r, &req); err != nil {
+			return err
+		}
+		current, err := db.OrgMembers.GetByOrgID(r.Context(), org.ID)
+		if err != nil {
+			return err
+		}
+		members := make([]int32, 0, len(current))
+		for _, m := range current {
+			members = append(members, m.UserID)
+		}
+		displayName, add, remove, err := parseSCIMGroupPatch(&req, members)
+		if err != nil {
+			return err
+		}
+		if displayName != nil {
+			if org, err = db.Orgs.Update(r.Context(), org.ID, displayName); err != nil {
+				return err
+			}
+		}
+		if err := updateSCIMGroupMembers(r.Context(), org.ID, add, remove); err != nil {
+			return err
+		}
+
+	case "DELETE":
+		if err := db.Orgs.Delete(r.Context(), org.ID); err != nil {
+			return err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+
+	default:
+		return scimErrorf(http.StatusMethodNotAllowed, "", "Method not allowed.")
+	}
+
+	resource, err := toSCIMGroup(r.Context(), org)
+	if err != nil {
+		return err
+	}
+	writeSCIMResponse(w, http.StatusOK, resource)
+	return nil
+}
+
+var scimMemberPath = regexp.MustCompile(`^(?i:members)\[(?i:value)\s+(?i:eq)\s+"(\d+)"\]$`)
+
+// parseSCIMGroupPatch converts the operations of a PATCH request for a group with the given
+// members to a new display name (if changed) and the user IDs of the members to add and remove.
+//
+// Identity providers express membership changes in several ways: an "add" or "remove" operation
+// on the "members" path with a list of members; a "remove" operation on a path that selects a
+// single member; and a "replace" operation on the "members" path with the new list of members.
+func parseSCIMGroupPatch(req *scimPatchRequest, members []int32) (displayName *string, add, remove []int32, err error) {
+	// Track the desired membership of each user that is changed, so that later operations take
+	// precedence over earlier ones.
+	want := map[int32]bool{}
+	removeAll := func() {
+		for _, id := range members {
+			want[id] = false
+		}
+		for id := range want {
+			want[id] = false
+		}
+	}
+	setMembers := func(value json.RawMessage, member bool) error {
+		var ms []scimMember
+		if len(value) > 0 {
+			if err := json.Unmarshal(value, &ms); err != nil {
+				return scimErrorf(http.StatusBadRequest, "invalidValue", "Invalid members: %s.", err)
+			}
+		}
+		ids, err := memberIDs(ms)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			want[id] = member
+		}
+		return nil
+	}
+
+	for _, op := range req.Operations {
+		path := op.Path
+		value := op.Value
+		if path == "" {
+			// Without a path, the value is an object containing the attributes to change.
+			var g struct {
+				DisplayName *string         `json:"displayName"`
+				Members     json.RawMessage `json:"members"`
+			}
+			if err := json.Unmarshal(value, &g); err != nil {
+				return nil, nil, nil, scimErrorf(http.StatusBadRequest, "invalidValue", "Invalid value: %s.", err)
+			}
+			if g.DisplayName != nil && *g.DisplayName != "" {
+				displayName = g.DisplayName
+			}
+			if len(g.Members) == 0 {
+				continue
+			}
+			path, value = "members", g.Members
+		}
+
+		switch opName := strings.ToLower(op.Op); {
+		case strings.EqualFold(path, "displayName") && opName != "remove":
+			var s string
+			if err := json.Unmarshal(value, &s); err != nil || s == "" {
+				return nil, nil, nil, scimErrorf(http.StatusBadRequest, "invalidValue", "Invalid displayName.")
+			}
+			displayName = &s
+
+		case strings.EqualFold(path, "members"):
+			switch opName {
+			case "add":
+				err = setMembers(value, true)
+			case "remove":
+				if len(value) == 0 || string(value) == "null" {
+					removeAll()
+				} else {
+					err = setMembers(value, false)
+				}
+			case "replace":
+				removeAll()
+				err = setMembers(value, true)
+			default:
+				err = scimErrorf(http.StatusBadRequest, "invalidSyntax", "Unsupported operation %q.", op.Op)
+			}
+			if err != nil {
+				return nil, nil, nil, err
+			}
+
+		case scimMemberPath.MatchString(path) && opName == "remove":
+			id, _ := strconv.ParseInt(scimMemberPath.FindStringSubmatch(path)[1], 10, 32)
+			want[int32(id)] = false
+
+		default:
+			return nil, nil, nil, scimErrorf(http.StatusBadRequest, "invalidPath", "Unsupported operation %q on path %q.", op.Op, path)
+		}
+	}
+
+	isMember := map[int32]bool{}
+	for _, id := range members {
+		isMember[id] = true
+	}
+	for id, member := range want {
+		switch {
+		case member && !isMember[id]:
+			add = append(add, id)
+		case !member && isMember[id]:
+			remove = append(remove, id)
+		}
+	}
+	sortInt32s(add)
+	sortInt32s(remove)
+	return displayName, add, remove, nil
+}
+
+func sortInt32s(s []int32) {
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+}
+
+// updateSCIMGroupMembers adds and removes members of the organization. A user in both add and
+// remove remains (or becomes) a member.
+func updateSCIMGroupMembers(ctx context.Context, orgID int32, add, remove []int32) error {
+	isAdded := map[int32]bool{}
+	for _, userID := range add {
+		isAdded[userID] = true
+		if _, err := db.OrgMembers.GetByOrgIDAndUserID(ctx, orgID, userID); err == nil {
+			continue // already a member
+		} else if !errcode.IsNotFound(err) {
+			return err
+		}
+		if _, err := db.Users.GetByID(ctx, userID); err != nil {
+			return scimErrorf(http.StatusBadRequest, "invalidValue", "Member %d is not a user.", userID)
+		}
+		if _, err := db.OrgMembers.Create(ctx, orgID, userID); err != nil {
+			return err
+		}
+	}
+	for _, userID := range remove {
+		if isAdded[userID] {
+			continue
+		}
+		if err := db.OrgMembers.Remove(ctx, orgID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
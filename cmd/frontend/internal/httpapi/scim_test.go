@@ -0,0 +1,506 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/httpapi/router"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+)
+
+const (
+	scimTestToken   = "scimtoken"
+	scimTestAdminID = 1
+)
+
+// mockSCIMAuth mocks an access token with the SCIM scope (scimTestToken) whose subject is a site
+// admin, and the given users (which are deactivated if DeletedAt is set).
+func mockSCIMAuth(users ...*types.User) {
+	db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded, requiredScope string) (subjectUserID int32, err error) {
+		if tokenHexEncoded == scimTestToken && requiredScope == authz.ScopeSiteAdminSCIM {
+			return scimTestAdminID, nil
+		}
+		return 0, errors.New("invalid token")
+	}
+	db.Mocks.Users.GetByID = func(ctx context.Context, id int32) (*types.User, error) {
+		if id == scimTestAdminID {
+			return &types.User{ID: id, Username: "admin", SiteAdmin: true}, nil
+		}
+		for _, u := range users {
+			if u.ID == id && u.DeletedAt == nil {
+				return u, nil
+			}
+		}
+		return nil, db.NewUserNotFoundError(id)
+	}
+	db.Mocks.Users.List = func(ctx context.Context, opt *db.UsersListOptions) ([]*types.User, error) {
+		var list []*types.User
+		for _, u := range users {
+			if (u.DeletedAt == nil || opt.IncludeDeleted) && (opt.Username == "" || u.Username == opt.Username) && (opt.UserIDs == nil || (len(opt.UserIDs) == 1 && opt.UserIDs[0] == u.ID)) {
+				list = append(list, u)
+			}
+		}
+		return list, nil
+	}
+}
+
+func doSCIMRequest(t *testing.T, method, path, authorization, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	h := NewHandler(router.New(mux.NewRouter()))
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	if got := rr.Header().Get("Content-Type"); got != scimContentType && rr.Code != http.StatusNoContent {
+		t.Errorf("got Content-Type %q, want %q", got, scimContentType)
+	}
+	return rr
+}
+
+func TestSCIMAuth(t *testing.T) {
+	defer func() { db.Mocks = db.MockStores{} }()
+	mockSCIMAuth(&types.User{ID: 2, Username: "alice"})
+
+	tests := map[string]struct {
+		authorization string
+		wantStatus    int
+	}{
+		"no token":           {wantStatus: http.StatusUnauthorized},
+		"unrecognized":       {authorization: "Basic abcd", wantStatus: http.StatusUnauthorized},
+		"invalid token":      {authorization: "Bearer bad", wantStatus: http.StatusUnauthorized},
+		"bearer token":       {authorization: "Bearer " + scimTestToken, wantStatus: http.StatusOK},
+		"token scheme":       {authorization: "token " + scimTestToken, wantStatus: http.StatusOK},
+		"lowercase bearer":   {authorization: "bearer " + scimTestToken, wantStatus: http.StatusOK},
+		"subject not admin":  {authorization: "Bearer nonadmin", wantStatus: http.StatusForbidden},
+		"missing token part": {authorization: "Bearer", wantStatus: http.StatusUnauthorized},
+	}
+	db.Mocks.Users.List = func(ctx context.Context, opt *db.UsersListOptions) ([]*types.User, error) { return nil, nil }
+	db.Mocks.Users.Count = func(ctx context.Context, opt *db.UsersListOptions) (int, error) { return 0, nil }
+	lookup := db.Mocks.AccessTokens.Lookup
+	db.Mocks.AccessTokens.Lookup = func(tokenHexEncoded, requiredScope string) (int32, error) {
+		if tokenHexEncoded == "nonadmin" && requiredScope == authz.ScopeSiteAdminSCIM {
+			return 2, nil // not a site admin
+		}
+		return lookup(tokenHexEncoded, requiredScope)
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			rr := doSCIMRequest(t, "GET", "/scim/v2/Users", test.authorization, "")
+			if rr.Code != test.wantStatus {
+				t.Errorf("got status %d, want %d (body %q)", rr.Code, test.wantStatus, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestSCIMUsers(t *testing.T) {
+	defer func() { db.Mocks = db.MockStores{} }()
+	alice := &types.User{ID: 2, Username: "alice", DisplayName: "Alice", CreatedAt: time.Unix(1, 0).UTC(), UpdatedAt: time.Unix(2, 0).UTC()}
+	mockSCIMAuth(alice)
+	db.Mocks.UserEmails.ListByUser = func(id int32) ([]*db.UserEmail, error) {
+		if id == alice.ID {
+			return []*db.UserEmail{{UserID: id, Email: "alice@example.com"}}, nil
+		}
+		return nil, nil
+	}
+	auth := "Bearer " + scimTestToken
+
+	t.Run("list with filter", func(t *testing.T) {
+		rr := doSCIMRequest(t, "GET", `/scim/v2/Users?filter=userName+eq+%22alice%40example.com%22`, auth, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d (body %q)", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		var resp struct {
+			TotalResults int
+			Resources    []scimUser
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.TotalResults != 1 || len(resp.Resources) != 1 {
+			t.Fatalf("got %d results, want 1", resp.TotalResults)
+		}
+		u := resp.Resources[0]
+		if u.ID != "2" || u.UserName != "alice" || u.DisplayName != "Alice" || u.Active == nil || !*u.Active {
+			t.Errorf("got user %+v", u)
+		}
+		if want := []scimEmail{{Value: "alice@example.com", Primary: true}}; !reflect.DeepEqual(u.Emails, want) {
+			t.Errorf("got emails %+v, want %+v", u.Emails, want)
+		}
+		if want := "http://example.com/.api/scim/v2/Users/2"; u.Meta == nil || u.Meta.Location != want {
+			t.Errorf("got meta %+v, want location %q", u.Meta, want)
+		}
+	})
+
+	t.Run("list with unsupported filter", func(t *testing.T) {
+		rr := doSCIMRequest(t, "GET", `/scim/v2/Users?filter=emails+co+%22x%22`, auth, "")
+		if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), `"scimType":"invalidFilter"`) {
+			t.Errorf("got status %d and body %q, want invalidFilter error", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		var created db.NewUser
+		db.Mocks.Users.Create = func(ctx context.Context, info db.NewUser) (*types.User, error) {
+			created = info
+			return &types.User{ID: 3, Username: info.Username, DisplayName: info.DisplayName}, nil
+		}
+		defer func() { db.Mocks.Users.Create = nil }()
+
+		rr := doSCIMRequest(t, "POST", "/scim/v2/Users", auth, `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+			"userName": "bob@example.com",
+			"name": {"givenName": "Bob", "familyName": "Smith"},
+			"emails": [{"value": "bob@other.example.com"}, {"value": "bob@example.com", "primary": true}],
+			"active": true
+		}`)
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("got status %d, want %d (body %q)", rr.Code, http.StatusCreated, rr.Body.String())
+		}
+		want := db.NewUser{Username: "bob", DisplayName: "Bob Smith", Email: "bob@example.com", EmailIsVerified: true}
+		if !reflect.DeepEqual(created, want) {
+			t.Errorf("got new user %+v, want %+v", created, want)
+		}
+		if got, want := rr.Header().Get("Location"), "http://example.com/.api/scim/v2/Users/3"; got != want {
+			t.Errorf("got Location %q, want %q", got, want)
+		}
+	})
+
+	t.Run("get nonexistent", func(t *testing.T) {
+		rr := doSCIMRequest(t, "GET", "/scim/v2/Users/99", auth, "")
+		if rr.Code != http.StatusNotFound || !strings.Contains(rr.Body.String(), scimSchemaError) {
+			t.Errorf("got status %d and body %q, want SCIM not found error", rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		var updated db.UserUpdate
+		db.Mocks.Users.Update = func(userID int32, update db.UserUpdate) error {
+			if userID != alice.ID {
+				t.Errorf("got user ID %d, want %d", userID, alice.ID)
+			}
+			updated = update
+			return nil
+		}
+		db.Mocks.UserEmails.Get = func(userID int32, email string) (string, bool, error) {
+			return email, true, nil
+		}
+		defer func() {
+			db.Mocks.Users.Update = nil
+			db.Mocks.UserEmails.Get = nil
+		}()
+
+		rr := doSCIMRequest(t, "PUT", "/scim/v2/Users/2", auth, `{
+			"userName": "alice",
+			"displayName": "Alice Smith",
+			"emails": [{"value": "alice@example.com", "primary": true}]
+		}`)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("got status %d, want %d (body %q)", rr.Code, http.StatusOK, rr.Body.String())
+		}
+		if updated.Username != "" || updated.DisplayName == nil || *updated.DisplayName != "Alice Smith" {
+			t.Errorf("got update %+v, want only display name changed", updated)
+		}
+	})
+}
+
+func TestSCIMUsers_deactivated(t *testing.T) {
+	defer func() { db.Mocks = db.MockStores{} }()
+	deletedAt := time.Unix(3, 0).UTC()
+	carol := &types.User{ID: 4, Username: "carol", DeletedAt: &deletedAt}
+	mockSCIMAuth(carol)
+	db.Mocks.UserEmails.ListByUser = func(id int32) ([]*db.UserEmail, error) { return nil, nil }
+	auth := "Bearer " + scimTestToken
+
+	readUser := func(t *testing.T, rr *httptest.ResponseRecorder, wantStatus int) *scimUser {
+		t.Helper()
+		if rr.Code != wantStatus {
+			t.Fatalf("got status %d, want %d (body %q)", rr.Code, wantStatus, rr.Body.String())
+		}
+		var u scimUser
+		if err := json.Unmarshal(rr.Body.Bytes(), &u); err != nil {
+			t.Fatal(err)
+		}
+		return &u
+	}
+
+	t.Run("get", func(t *testing.T) {
+		u := readUser(t, doSCIMRequest(t, "GET", "/scim/v2/Users/4", auth, ""), http.StatusOK)
+		if u.ID != "4" || u.Active == nil || *u.Active {
+			t.Errorf("got user %+v, want inactive user 4", u)
+		}
+	})
+
+	t.Run("list with filter", func(t *testing.T) {
+		rr := doSCIMRequest(t, "GET", `/scim/v2/Users?filter=userName+eq+%22carol%22`, auth, "")
+		var resp struct {
+			TotalResults int
+			Resources    []scimUser
+		}
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Resources) != 1 || resp.Resources[0].Active == nil || *resp.Resources[0].Active {
+			t.Errorf("got users %+v, want inactive user 4", resp.Resources)
+		}
+	})
+
+	t.Run("update without activating", func(t *testing.T) {
+		db.Mocks.Users.Update = func(userID int32, update db.UserUpdate) error {
+			t.Error("deactivated user was updated")
+			return nil
+		}
+		defer func() { db.Mocks.Users.Update = nil }()
+
+		u := readUser(t, doSCIMRequest(t, "PUT", "/scim/v2/Users/4", auth, `{"userName": "carol", "displayName": "Carol"}`), http.StatusOK)
+		if u.Active == nil || *u.Active {
+			t.Errorf("got user %+v, want inactive", u)
+		}
+	})
+
+	for _, test := range []struct {
+		name, method, path, body string
+		wantStatus               int
+	}{
+		{"reactivate with PATCH", "PATCH", "/scim/v2/Users/4", `{"Operations": [{"op": "replace", "path": "active", "value": true}]}`, http.StatusOK},
+		{"reactivate with POST", "POST", "/scim/v2/Users", `{"userName": "carol", "emails": [{"value": "carol@example.com"}]}`, http.StatusCreated},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			carol.DeletedAt = &deletedAt
+			var undeleted int32
+			db.Mocks.Users.Undelete = func(id int32) error {
+				undeleted = id
+				return nil
+			}
+			db.Mocks.Users.Create = func(ctx context.Context, info db.NewUser) (*types.User, error) {
+				t.Error("user was created")
+				return nil, errors.New("x")
+			}
+			db.Mocks.UserEmails.Get = func(userID int32, email string) (string, bool, error) {
+				return email, true, nil
+			}
+			defer func() {
+				db.Mocks.Users.Undelete = nil
+				db.Mocks.Users.Create = nil
+				db.Mocks.UserEmails.Get = nil
+			}()
+
+			u := readUser(t, doSCIMRequest(t, test.method, test.path, auth, test.body), test.wantStatus)
+			if undeleted != carol.ID {
+				t.Errorf("got undeleted user %d, want %d", undeleted, carol.ID)
+			}
+			if u.ID != "4" || u.Active == nil || !*u.Active {
+				t.Errorf("got user %+v, want active user 4", u)
+			}
+		})
+	}
+}
+
+func TestSCIMGroups_listWithFilter(t *testing.T) {
+	defer func() { db.Mocks = db.MockStores{} }()
+	mockSCIMAuth()
+	displayName := "Engineering"
+	eng := &types.Org{ID: 5, Name: "Eng-Team", DisplayName: &displayName}
+	db.Mocks.Orgs.List = func(ctx context.Context, opt *db.OrgsListOptions) ([]*types.Org, error) {
+		if strings.EqualFold(opt.NameOrDisplayName, *eng.DisplayName) || strings.EqualFold(opt.NameOrDisplayName, eng.Name) {
+			return []*types.Org{eng}, nil
+		}
+		return nil, nil
+	}
+	db.Mocks.OrgMembers.GetByOrgID = func(ctx context.Context, orgID int32) ([]*types.OrgMembership, error) { return nil, nil }
+
+	for filter, wantResults := range map[string]int{
+		"engineering": 1, // renamed group
+		"Eng Team":    1, // original group name
+		"Marketing":   0,
+	} {
+		t.Run(filter, func(t *testing.T) {
+			rr := doSCIMRequest(t, "GET", "/scim/v2/Groups?filter="+url.QueryEscape(`displayName eq "`+filter+`"`), "Bearer "+scimTestToken, "")
+			if rr.Code != http.StatusOK {
+				t.Fatalf("got status %d, want %d (body %q)", rr.Code, http.StatusOK, rr.Body.String())
+			}
+			var resp struct {
+				TotalResults int
+				Resources    []scimGroup
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.TotalResults != wantResults || len(resp.Resources) != wantResults {
+				t.Fatalf("got %d results, want %d", len(resp.Resources), wantResults)
+			}
+			if wantResults > 0 && (resp.Resources[0].ID != "5" || resp.Resources[0].DisplayName != "Engineering") {
+				t.Errorf("got group %+v", resp.Resources[0])
+			}
+		})
+	}
+}
+
+func TestParseSCIMFilter(t *testing.T) {
+	tests := map[string][2]string{
+		`userName eq "alice"`:          {"userName", "alice"},
+		`  displayName EQ "a \"b\""  `: {"displayName", `a "b"`},
+		`userName eq ""`:               {"userName", ""},
+	}
+	for filter, want := range tests {
+		attr, value, err := parseSCIMFilter(filter)
+		if err != nil {
+			t.Errorf("%q: %s", filter, err)
+			continue
+		}
+		if attr != want[0] || value != want[1] {
+			t.Errorf("%q: got (%q, %q), want (%q, %q)", filter, attr, value, want[0], want[1])
+		}
+	}
+
+	for _, filter := range []string{
+		"",
+		`userName eq alice`,
+		`userName co "alice"`,
+		`userName eq "a" and active eq true`,
+	} {
+		if _, _, err := parseSCIMFilter(filter); err == nil {
+			t.Errorf("%q: got no error, want error", filter)
+		}
+	}
+}
+
+func TestParseSCIMUserPatch(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	boolPtr := func(b bool) *bool { return &b }
+	tests := map[string]struct {
+		patch string
+		want  scimUserUpdate
+	}{
+		"deactivate without path": {
+			patch: `[{"op": "replace", "value": {"active": false}}]`,
+			want:  scimUserUpdate{active: boolPtr(false)},
+		},
+		"deactivate with path and string value": {
+			patch: `[{"op": "Replace", "path": "active", "value": "False"}]`,
+			want:  scimUserUpdate{active: boolPtr(false)},
+		},
+		"attributes": {
+			patch: `[
+				{"op": "replace", "path": "userName", "value": "alice2"},
+				{"op": "add", "path": "name.formatted", "value": "Alice S"},
+				{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "a@example.com"},
+				{"op": "remove", "path": "title"}
+			]`,
+			want: scimUserUpdate{userName: strPtr("alice2"), displayName: strPtr("Alice S"), email: strPtr("a@example.com")},
+		},
+		"unknown attributes": {
+			patch: `[{"op": "replace", "path": "title", "value": "x"}]`,
+			want:  scimUserUpdate{},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var req scimPatchRequest
+			if err := json.Unmarshal([]byte(`{"Operations": `+test.patch+`}`), &req); err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseSCIMUserPatch(&req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSCIMGroupPatch(t *testing.T) {
+	tests := map[string]struct {
+		patch            string
+		wantDisplayName  string
+		wantAdd, wantRem []int32
+	}{
+		"add members": {
+			patch:   `[{"op": "add", "path": "members", "value": [{"value": "1"}, {"value": "4"}]}]`,
+			wantAdd: []int32{4},
+		},
+		"remove member by filter": {
+			patch:   `[{"op": "remove", "path": "members[value eq \"2\"]"}]`,
+			wantRem: []int32{2},
+		},
+		"remove members by value": {
+			patch:   `[{"op": "Remove", "path": "members", "value": [{"value": "1"}, {"value": "5"}]}]`,
+			wantRem: []int32{1},
+		},
+		"remove all members": {
+			patch:   `[{"op": "remove", "path": "members"}]`,
+			wantRem: []int32{1, 2},
+		},
+		"replace members": {
+			patch:   `[{"op": "replace", "path": "members", "value": [{"value": "2"}, {"value": "3"}]}]`,
+			wantAdd: []int32{3},
+			wantRem: []int32{1},
+		},
+		"later operations take precedence": {
+			patch: `[
+				{"op": "remove", "path": "members", "value": [{"value": "1"}]},
+				{"op": "add", "path": "members", "value": [{"value": "1"}, {"value": "3"}]},
+				{"op": "remove", "path": "members[value eq \"3\"]"}
+			]`,
+		},
+		"replace without path": {
+			patch:           `[{"op": "replace", "value": {"displayName": "Engineering", "members": [{"value": "1"}]}}]`,
+			wantDisplayName: "Engineering",
+			wantRem:         []int32{2},
+		},
+		"replace display name": {
+			patch:           `[{"op": "replace", "path": "displayName", "value": "Engineering"}]`,
+			wantDisplayName: "Engineering",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var req scimPatchRequest
+			if err := json.Unmarshal([]byte(`{"Operations": `+test.patch+`}`), &req); err != nil {
+				t.Fatal(err)
+			}
+			displayName, add, remove, err := parseSCIMGroupPatch(&req, []int32{1, 2})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (displayName == nil) != (test.wantDisplayName == "") || (displayName != nil && *displayName != test.wantDisplayName) {
+				t.Errorf("got display name %v, want %q", displayName, test.wantDisplayName)
+			}
+			if !reflect.DeepEqual(add, test.wantAdd) {
+				t.Errorf("got add %v, want %v", add, test.wantAdd)
+			}
+			if !reflect.DeepEqual(remove, test.wantRem) {
+				t.Errorf("got remove %v, want %v", remove, test.wantRem)
+			}
+		})
+	}
+
+	for _, patch := range []string{
+		`[{"op": "add", "path": "members", "value": [{"value": "x"}]}]`,
+		`[{"op": "move", "path": "members", "value": []}]`,
+		`[{"op": "add", "path": "owners", "value": []}]`,
+	} {
+		var req scimPatchRequest
+		if err := json.Unmarshal([]byte(`{"Operations": `+patch+`}`), &req); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, err := parseSCIMGroupPatch(&req, nil); err == nil {
+			t.Errorf("%s: got no error, want error", patch)
+		}
+	}
+}
@@ -0,0 +1,442 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/auth"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+)
+
+// scimUser is a SCIM user resource (RFC 7643 section 4.1).
+type scimUser struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	UserName    string      `json:"userName"`
+	Name        *scimName   `json:"name,omitempty"`
+	DisplayName string      `json:"displayName,omitempty"`
+	Emails      []scimEmail `json:"emails,omitempty"`
+	Active      *scimBool   `json:"active,omitempty"`
+	Meta        *scimMeta   `json:"meta,omitempty"`
+}
+
+type scimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type scimEmail struct {
+	Value   string `json:"value"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// displayName returns the user's display name, which identity providers give in either the
+// displayName or the name attribute.
+func (u *scimUser) displayName() string {
+	if u.DisplayName != "" || u.Name == nil {
+		return u.DisplayName
+	}
+	if u.Name.Formatted != "" {
+		return u.Name.Formatted
+	}
+	return strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+}
+
+// primaryEmail returns the user's primary email address (or the first one if none is marked as
+// primary).
+func (u *scimUser) primaryEmail() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+// scimUserUpdate describes changes to a user. Nil fields are unchanged.
+type scimUserUpdate struct {
+	userName, displayName, email *string
+	active                       *bool
+}
+
+func (u *scimUser) update() scimUserUpdate {
+	displayName := u.displayName()
+	upd := scimUserUpdate{userName: &u.UserName, displayName: &displayName}
+	if email := u.primaryEmail(); email != "" {
+		upd.email = &email
+	}
+	if u.Active != nil {
+		upd.active = (*bool)(u.Active)
+	}
+	return upd
+}
+
+// toSCIMUser converts a user to a SCIM user. Deactivated (soft-deleted) users are inactive.
+func toSCIMUser(ctx context.Context, user *types.User) (*scimUser, error) {
+	emails, err := db.UserEmails.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	active := user.DeletedAt == nil
+	u := &scimUser{
+		Schemas:     []string{scimSchemaUser},
+		ID:          strconv.Itoa(int(user.ID)),
+		UserName:    user.Username,
+		DisplayName: user.DisplayName,
+		Active:      (*scimBool)(&active),
+		Meta:        newSCIMMeta("User", user.ID, user.CreatedAt, user.UpdatedAt),
+	}
+	if user.DisplayName != "" {
+		u.Name = &scimName{Formatted: user.DisplayName}
+	}
+	for i, e := range emails {
+		u.Emails = append(u.Emails, scimEmail{Value: e.Email, Primary: i == 0})
+	}
+	return u, nil
+}
+
+// normalizeSCIMUserName converts a SCIM userName (which is often an email address) to a Sourcegraph
+// username.
+func normalizeSCIMUserName(userName string) (string, error) {
+	username, err := auth.NormalizeUsername(userName)
+	if err != nil {
+		return "", scimErrorf(http.StatusBadRequest, "invalidValue", "Invalid userName: %s.", err)
+	}
+	return username, nil
+}
+
+// getSCIMUser returns the user with the given ID, including deactivated (soft-deleted) users.
+func getSCIMUser(ctx context.Context, id int32) (*types.User, error) {
+	users, err := db.Users.List(ctx, &db.UsersListOptions{UserIDs: []int32{id}, IncludeDeleted: true})
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, scimErrorf(http.StatusNotFound, "", "Resource not found.")
+	}
+	return users[0], nil
+}
+
+// getSCIMUserByUsername returns the user with the given username, or nil if there is none. If
+// there is no active user with the username, it returns the most recently created deactivated
+// (soft-deleted) user with the username.
+func getSCIMUserByUsername(ctx context.Context, username string) (*types.User, error) {
+	users, err := db.Users.List(ctx, &db.UsersListOptions{Username: username, IncludeDeleted: true})
+	if err != nil {
+		return nil, err
+	}
+	var user *types.User
+	for _, u := range users { // ordered by ID
+		if u.DeletedAt == nil {
+			return u, nil
+		}
+		user = u
+	}
+	return user, nil
+}
+
+// serveSCIMUsers handles the SCIM /Users endpoint, which lists and creates users.
+func serveSCIMUsers(w http.ResponseWriter, r *http.Request) error {
+	switch r.Method {
+	case "GET":
+		return serveSCIMUsersList(w, r)
+	case "POST":
+		return serveSCIMUsersCreate(w, r)
+	default:
+		return scimErrorf(http.StatusMethodNotAllowed, "", "Method not allowed.")
+	}
+}
+
+func serveSCIMUsersList(w http.ResponseWriter, r *http.Request) error {
+	params, err := parseSCIMListParams(r.URL.Query())
+	if err != nil {
+		return err
+	}
+
+	var (
+		users []*types.User
+		total int
+	)
+	if params.filterAttr != "" {
+		if !strings.EqualFold(params.filterAttr, "userName") {
+			return scimErrorf(http.StatusBadRequest, "invalidFilter", "Filtering users by %q is not supported.", params.filterAttr)
+		}
+		user, err := getSCIMUserByUsername(r.Context(), params.filterValue)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			// The identity provider may use the original userName, not the normalized username.
+			if username, err := auth.NormalizeUsername(params.filterValue); err == nil && username != params.filterValue {
+				if user, err = getSCIMUserByUsername(r.Context(), username); err != nil {
+					return err
+				}
+			}
+		}
+		if user != nil {
+			users = []*types.User{user}
+		}
+		total = len(users)
+	} else {
+		opt := &db.UsersListOptions{IncludeDeleted: true, LimitOffset: &db.LimitOffset{Limit: params.count, Offset: params.startIndex - 1}}
+		if users, err = db.Users.List(r.Context(), opt); err != nil {
+			return err
+		}
+		if total, err = db.Users.Count(r.Context(), opt); err != nil {
+			return err
+		}
+	}
+
+	resources := make([]*scimUser, 0, len(users))
+	for _, user := range users {
+		u, err := toSCIMUser(r.Context(), user)
+		if err != nil {
+			return err
+		}
+		resources = append(resources, u)
+	}
+	writeSCIMResponse(w, http.StatusOK, &scimListResponse{
+		Schemas:      []string{scimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   params.startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+	return nil
+}
+
+func serveSCIMUsersCreate(w http.ResponseWriter, r *http.Request) error {
+	var u scimUser
+	if err := readSCIMRequest(r, &u); err != nil {
+		return err
+	}
+	if u.Active != nil && !*u.Active {
+		return scimErrorf(http.StatusBadRequest, "invalidValue", "Inactive users may not be created.")
+	}
+	username, err := normalizeSCIMUserName(u.UserName)
+	if err != nil {
+		return err
+	}
+
+	// Provisioning a deactivated user again reactivates it, instead of creating a new user with the
+	// same username.
+	if existing, err := getSCIMUserByUsername(r.Context(), username); err != nil {
+		return err
+	} else if existing != nil && existing.DeletedAt != nil {
+		upd, active := u.update(), true
+		upd.active = &active
+		if err := updateSCIMUser(r.Context(), existing, upd); err != nil {
+			return err
+		}
+		return writeSCIMUserCreated(w, r, existing)
+	}
+
+	// 🚨 SECURITY: The email address is considered verified, because it was given to us by the
+	// identity provider (and the request was authenticated as a site admin). This lets users sign
+	// in with an SSO auth provider and be matched to the account created here by email.
+	user, err := db.Users.Create(r.Context(), db.NewUser{
+		Username:        username,
+		DisplayName:     u.displayName(),
+		Email:           u.primaryEmail(),
+		EmailIsVerified: true,
+	})
+	if err != nil {
+		return err
+	}
+	db.UserPermissions.SyncInBackground(user.ID)
+	return writeSCIMUserCreated(w, r, user)
+}
+
+func writeSCIMUserCreated(w http.ResponseWriter, r *http.Request, user *types.User) error {
+	resource, err := toSCIMUser(r.Context(), user)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Location", resource.Meta.Location)
+	writeSCIMResponse(w, http.StatusCreated, resource)
+	return nil
+}
+
+// serveSCIMUser handles the SCIM /Users/{id} endpoint, which gets, updates and deactivates a user.
+func serveSCIMUser(w http.ResponseWriter, r *http.Request) error {
+	id, err := scimResourceID(r)
+	if err != nil {
+		return err
+	}
+	user, err := getSCIMUser(r.Context(), id)
+	if err != nil {
+		return err
+	}
+
+	var upd scimUserUpdate
+	switch r.Method {
+	case "GET":
+		// No update.
+
+	case "PUT":
+		var u scimUser
+		if err := readSCIMRequest(r, &u); err != nil {
+			return err
+		}
+		upd = u.update()
+
+	case "PATCH":
+		var req scimPatchRequest
+		if err := readSCIMRequest(r, &req); err != nil {
+			return err
+		}
+		if upd, err = parseSCIMUserPatch(&req); err != nil {
+			return err
+		}
+
+	case "DELETE":
+		// Users are deactivated (soft-deleted), so that their data is retained.
+		if user.DeletedAt == nil {
+			if err := db.Users.Delete(r.Context(), user.ID); err != nil {
+				return err
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+
+	default:
+		return scimErrorf(http.StatusMethodNotAllowed, "", "Method not allowed.")
+	}
+
+	if err := updateSCIMUser(r.Context(), user, upd); err != nil {
+		return err
+	}
+	resource, err := toSCIMUser(r.Context(), user)
+	if err != nil {
+		return err
+	}
+	writeSCIMResponse(w, http.StatusOK, resource)
+	return nil
+}
+
+// parseSCIMUserPatch converts the operations of a PATCH request to a user update. Only the
+// attributes that are stored for users (userName, displayName, emails and active) may be changed.
+func parseSCIMUserPatch(req *scimPatchRequest) (scimUserUpdate, error) {
+	var upd scimUserUpdate
+	for _, op := range req.Operations {
+		if !strings.EqualFold(op.Op, "add") && !strings.EqualFold(op.Op, "replace") {
+			// Removing the stored attributes is not supported, and other attributes are not
+			// stored, so there is nothing to remove.
+			continue
+		}
+
+		// Without a path, the value is an object containing the attributes to change.
+		var u scimUser
+		var err error
+		switch strings.ToLower(op.Path) {
+		case "":
+			err = json.Unmarshal(op.Value, &u)
+		case "username":
+			err = json.Unmarshal(op.Value, &u.UserName)
+		case "displayname":
+			err = json.Unmarshal(op.Value, &u.DisplayName)
+		case "name.formatted":
+			u.Name = &scimName{}
+			err = json.Unmarshal(op.Value, &u.Name.Formatted)
+		case "emails":
+			err = json.Unmarshal(op.Value, &u.Emails)
+		case `emails[type eq "work"].value`, `emails[primary eq true].value`:
+			u.Emails = []scimEmail{{}}
+			err = json.Unmarshal(op.Value, &u.Emails[0].Value)
+		case "active":
+			err = json.Unmarshal(op.Value, &u.Active)
+		default:
+			continue
+		}
+		if err != nil {
+			return upd, scimErrorf(http.StatusBadRequest, "invalidValue", "Invalid value for %q: %s.", op.Path, err)
+		}
+
+		if u.UserName != "" {
+			upd.userName = &u.UserName
+		}
+		if displayName := u.displayName(); displayName != "" {
+			upd.displayName = &displayName
+		}
+		if email := u.primaryEmail(); email != "" {
+			upd.email = &email
+		}
+		if u.Active != nil {
+			upd.active = (*bool)(u.Active)
+		}
+	}
+	return upd, nil
+}
+
+// updateSCIMUser applies the update to the user (and the user value).
+//
+// Deactivating a user soft-deletes it, and activating a deactivated user undeletes it. Its email
+// addresses are removed when it is deactivated, so they must be given again when it is activated.
+// Deactivated users can't be changed otherwise.
+func updateSCIMUser(ctx context.Context, user *types.User, upd scimUserUpdate) error {
+	if upd.active != nil && !*upd.active {
+		if user.DeletedAt == nil {
+			if err := db.Users.Delete(ctx, user.ID); err != nil {
+				return err
+			}
+			now := time.Now()
+			user.DeletedAt = &now
+		}
+		return nil
+	}
+	if user.DeletedAt != nil {
+		if upd.active == nil {
+			return nil
+		}
+		if err := db.Users.Undelete(ctx, user.ID); err != nil {
+			return err
+		}
+		user.DeletedAt = nil
+	}
+
+	var update db.UserUpdate
+	if upd.userName != nil {
+		username, err := normalizeSCIMUserName(*upd.userName)
+		if err != nil {
+			return err
+		}
+		if username != user.Username {
+			update.Username = username
+			user.Username = username
+		}
+	}
+	if upd.displayName != nil && *upd.displayName != user.DisplayName {
+		update.DisplayName = upd.displayName
+		user.DisplayName = *upd.displayName
+	}
+	if update != (db.UserUpdate{}) {
+		if err := db.Users.Update(ctx, user.ID, update); err != nil {
+			return err
+		}
+	}
+
+	if upd.email != nil && *upd.email != "" {
+		if _, _, err := db.UserEmails.Get(ctx, user.ID, *upd.email); errcode.IsNotFound(err) {
+			// 🚨 SECURITY: See serveSCIMUsersCreate for why the email address is verified.
+			if err := db.UserEmails.Add(ctx, user.ID, *upd.email, nil); err != nil {
+				return err
+			}
+			if err := db.UserEmails.SetVerified(ctx, user.ID, *upd.email, true); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	log15 "gopkg.in/inconshreveable/log15.v2"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/graphqlbackend"
+)
+
+// serveSearchStream runs the search query in the "q" URL query parameter and
+// streams its results as Server-Sent Events
+// (https://html.spec.whatwg.org/multipage/server-sent-events.html). The data
+// of each event is JSON:
+//
+//	event: matches    []graphqlbackend.SearchMatch, as soon as a backend finds them
+//	event: progress   graphqlbackend.SearchProgress, after each backend finishes
+//	event: done       graphqlbackend.SearchSummary, when the search is finished
+//	event: error      {"message": string}, if the search failed
+//
+// The search is canceled when the client closes the connection.
+func serveSearchStream(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		http.Error(w, "missing search query (q)", http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // don't buffer the events in nginx
+	w.WriteHeader(http.StatusOK)
+
+	ew := &eventWriter{w: w, flusher: flusher}
+	summary, err := graphqlbackend.StreamSearch(r.Context(), q, ew.write)
+	if err != nil {
+		if r.Context().Err() == nil {
+			log15.Debug("streaming search failed", "query", q, "error", err)
+		}
+		ew.write("error", &struct {
+			Message string `json:"message"`
+		}{Message: err.Error()})
+		return
+	}
+	ew.write("done", summary)
+}
+
+// eventWriter writes Server-Sent Events. After a write fails (usually because
+// the client closed the connection), all further events are dropped.
+type eventWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+	err     error
+}
+
+func (ew *eventWriter) write(event string, data interface{}) {
+	if ew.err != nil {
+		return
+	}
+	b, err := json.Marshal(data)
+	if err != nil {
+		ew.err = err
+		log15.Error("failed to marshal search event", "event", event, "error", err)
+		return
+	}
+	// The JSON encoding has no newlines, so it fits on a single data line.
+	if _, err := fmt.Fprintf(ew.w, "event: %s\ndata: %s\n\n", event, b); err != nil {
+		ew.err = err
+		return
+	}
+	ew.flusher.Flush()
+}
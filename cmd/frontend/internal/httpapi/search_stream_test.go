@@ -0,0 +1,50 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ew := &eventWriter{w: rec, flusher: rec}
+	ew.write("progress", &struct {
+		RepositoriesSearched int `json:"repositoriesSearched"`
+	}{RepositoriesSearched: 2})
+	ew.write("matches", []string{"a\nb"})
+
+	want := "event: progress\ndata: {\"repositoriesSearched\":2}\n\nevent: matches\ndata: [\"a\\nb\"]\n\n"
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if !rec.Flushed {
+		t.Error("events were not flushed")
+	}
+}
+
+type failingWriter struct{ writes int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("closed")
+}
+
+func TestEventWriter_dropsEventsAfterError(t *testing.T) {
+	w := &failingWriter{}
+	ew := &eventWriter{w: w, flusher: httptest.NewRecorder()}
+	ew.write("matches", nil)
+	ew.write("done", nil)
+	if w.writes != 1 {
+		t.Errorf("got %d writes, want 1", w.writes)
+	}
+}
+
+func TestServeSearchStream_noQuery(t *testing.T) {
+	rec := httptest.NewRecorder()
+	serveSearchStream(rec, httptest.NewRequest("GET", "/search/stream", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,188 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+// maxWebhookPayloadSize is the maximum size of a webhook payload we accept. It
+// is the maximum size of a payload that GitHub sends.
+const maxWebhookPayloadSize = 25 << 20
+
+var (
+	errWebhookUnauthorized = &errcode.HTTPErr{
+		Status: http.StatusUnauthorized,
+		Err:    errors.New("webhook secret does not match any configured connection"),
+	}
+	errWebhookRepoNotFound = &errcode.HTTPErr{
+		Status: http.StatusNotFound,
+		Err:    errors.New("webhook repository does not belong to any configured connection"),
+	}
+)
+
+// serveGitHubWebhook handles webhook events sent by GitHub. A push event
+// enqueues an immediate update of the pushed repository, so that new commits
+// are searchable without waiting for the next scheduled update.
+//
+// 🚨 SECURITY: This handler is accessible to anonymous users. Events are only
+// accepted if their signature (the X-Hub-Signature header) is valid for the
+// webhookSecret of a GitHub connection.
+func serveGitHubWebhook(w http.ResponseWriter, r *http.Request) error {
+	payload, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWebhookPayloadSize))
+	if err != nil {
+		return err
+	}
+
+	// The connection configs are only used to verify the signature and are
+	// never included in the response.
+	conns, err := db.ExternalServices.ListGitHubConnections(r.Context())
+	if err != nil {
+		return err
+	}
+	var verified []*schema.GitHubConnection
+	for _, c := range conns {
+		if c.WebhookSecret != "" && validGitHubSignature(payload, r.Header.Get("X-Hub-Signature"), c.WebhookSecret) {
+			verified = append(verified, c)
+		}
+	}
+	if len(verified) == 0 {
+		return errWebhookUnauthorized
+	}
+
+	if r.Header.Get("X-GitHub-Event") != "push" {
+		// Other events (such as the ping event sent when a webhook is created)
+		// are acknowledged but ignored.
+		return nil
+	}
+
+	var event struct {
+		Repository struct {
+			HTMLURL string `json:"html_url"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return &errcode.HTTPErr{Status: http.StatusBadRequest, Err: err}
+	}
+
+	var names []api.RepoName
+	for _, c := range verified {
+		name, err := reposource.GitHub{GitHubConnection: c}.CloneURLToRepoName(event.Repository.HTMLURL)
+		if err != nil {
+			return &errcode.HTTPErr{Status: http.StatusBadRequest, Err: err}
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return enqueueWebhookRepoUpdates(r.Context(), names)
+}
+
+// validGitHubSignature reports whether signature (of the form "sha1=<hex>") is
+// the HMAC-SHA1 of payload with secret.
+func validGitHubSignature(payload []byte, signature, secret string) bool {
+	if !strings.HasPrefix(signature, "sha1=") {
+		return false
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha1="))
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// serveGitLabWebhook handles webhook events sent by GitLab. A push event
+// enqueues an immediate update of the pushed project, so that new commits are
+// searchable without waiting for the next scheduled update.
+//
+// 🚨 SECURITY: This handler is accessible to anonymous users. Events are only
+// accepted if their secret token (the X-Gitlab-Token header) is the
+// webhookSecret of a GitLab connection.
+func serveGitLabWebhook(w http.ResponseWriter, r *http.Request) error {
+	payload, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWebhookPayloadSize))
+	if err != nil {
+		return err
+	}
+
+	// The connection configs are only used to verify the secret token and are
+	// never included in the response.
+	conns, err := db.ExternalServices.ListGitLabConnections(r.Context())
+	if err != nil {
+		return err
+	}
+	token := r.Header.Get("X-Gitlab-Token")
+	var verified []*schema.GitLabConnection
+	for _, c := range conns {
+		if c.WebhookSecret != "" && subtle.ConstantTimeCompare([]byte(token), []byte(c.WebhookSecret)) == 1 {
+			verified = append(verified, c)
+		}
+	}
+	if len(verified) == 0 {
+		return errWebhookUnauthorized
+	}
+
+	switch r.Header.Get("X-Gitlab-Event") {
+	case "Push Hook", "Tag Push Hook":
+	default:
+		// Other events are acknowledged but ignored.
+		return nil
+	}
+
+	var event struct {
+		Project struct {
+			WebURL string `json:"web_url"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return &errcode.HTTPErr{Status: http.StatusBadRequest, Err: err}
+	}
+
+	var names []api.RepoName
+	for _, c := range verified {
+		name, err := reposource.GitLab{GitLabConnection: c}.CloneURLToRepoName(event.Project.WebURL)
+		if err != nil {
+			return &errcode.HTTPErr{Status: http.StatusBadRequest, Err: err}
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return enqueueWebhookRepoUpdates(r.Context(), names)
+}
+
+// enqueueWebhookRepoUpdates requests an immediate update of each of the named
+// repositories.
+func enqueueWebhookRepoUpdates(ctx context.Context, names []api.RepoName) error {
+	if len(names) == 0 {
+		return errWebhookRepoNotFound
+	}
+	seen := make(map[api.RepoName]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if err := repoupdater.DefaultClient.EnqueueWebhookRepoUpdate(ctx, gitserver.Repo{Name: name}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
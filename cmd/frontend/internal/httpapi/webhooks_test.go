@@ -0,0 +1,172 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater"
+)
+
+func mockWebhookExternalServices(t *testing.T, kind string, configs ...string) {
+	db.Mocks.ExternalServices.List = func(opt db.ExternalServicesListOptions) ([]*types.ExternalService, error) {
+		if !reflect.DeepEqual(opt.Kinds, []string{kind}) {
+			t.Errorf("got kinds %v, want %v", opt.Kinds, []string{kind})
+		}
+		var services []*types.ExternalService
+		for i, config := range configs {
+			services = append(services, &types.ExternalService{ID: int64(i + 1), Kind: kind, Config: config})
+		}
+		return services, nil
+	}
+}
+
+func mockWebhookEnqueueRepoUpdate() *[]api.RepoName {
+	var enqueued []api.RepoName
+	repoupdater.MockEnqueueWebhookRepoUpdate = func(ctx context.Context, repo gitserver.Repo) error {
+		enqueued = append(enqueued, repo.Name)
+		return nil
+	}
+	return &enqueued
+}
+
+func TestGitHubWebhook(t *testing.T) {
+	c := newTest()
+	mockWebhookExternalServices(t, "GITHUB", `{
+		// comment
+		"url": "https://github.example.com",
+		"token": "t",
+		"repositoryPathPattern": "gh/{nameWithOwner}",
+		"webhookSecret": "s3cr3t"
+	}`, `{
+		"url": "https://github.example.org",
+		"token": "t"
+	}`)
+	enqueued := mockWebhookEnqueueRepoUpdate()
+	defer func() {
+		db.Mocks = db.MockStores{}
+		repoupdater.MockEnqueueWebhookRepoUpdate = nil
+	}()
+
+	sign := func(payload, secret string) string {
+		mac := hmac.New(sha1.New, []byte(secret))
+		mac.Write([]byte(payload))
+		return "sha1=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	push := func(htmlURL string) string {
+		return `{"ref": "refs/heads/master", "repository": {"full_name": "o/r", "html_url": "` + htmlURL + `"}}`
+	}
+
+	tests := map[string]struct {
+		event, payload, signature string
+		wantStatus                int
+		wantEnqueued              []api.RepoName
+	}{
+		"push": {
+			event:        "push",
+			payload:      push("https://github.example.com/o/r"),
+			signature:    sign(push("https://github.example.com/o/r"), "s3cr3t"),
+			wantStatus:   http.StatusOK,
+			wantEnqueued: []api.RepoName{"gh/o/r"},
+		},
+		"ping": {
+			event:      "ping",
+			payload:    `{"zen": "Keep it logically awesome."}`,
+			signature:  sign(`{"zen": "Keep it logically awesome."}`, "s3cr3t"),
+			wantStatus: http.StatusOK,
+		},
+		"wrong secret": {
+			event:      "push",
+			payload:    push("https://github.example.com/o/r"),
+			signature:  sign(push("https://github.example.com/o/r"), "wrong"),
+			wantStatus: http.StatusUnauthorized,
+		},
+		"no signature": {
+			event:      "push",
+			payload:    push("https://github.example.com/o/r"),
+			wantStatus: http.StatusUnauthorized,
+		},
+		"other host": {
+			event:      "push",
+			payload:    push("https://github.example.org/o/r"),
+			signature:  sign(push("https://github.example.org/o/r"), "s3cr3t"),
+			wantStatus: http.StatusNotFound,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			*enqueued = nil
+			req, _ := http.NewRequest("POST", "/webhooks/github", strings.NewReader(test.payload))
+			req.Header.Set("X-GitHub-Event", test.event)
+			if test.signature != "" {
+				req.Header.Set("X-Hub-Signature", test.signature)
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.wantStatus {
+				t.Errorf("got status %d, want %d", resp.StatusCode, test.wantStatus)
+			}
+			if !reflect.DeepEqual(*enqueued, test.wantEnqueued) {
+				t.Errorf("got enqueued %v, want %v", *enqueued, test.wantEnqueued)
+			}
+		})
+	}
+}
+
+func TestGitLabWebhook(t *testing.T) {
+	c := newTest()
+	mockWebhookExternalServices(t, "GITLAB", `{
+		"url": "https://gitlab.example.com",
+		"token": "t",
+		"webhookSecret": "s3cr3t"
+	}`)
+	enqueued := mockWebhookEnqueueRepoUpdate()
+	defer func() {
+		db.Mocks = db.MockStores{}
+		repoupdater.MockEnqueueWebhookRepoUpdate = nil
+	}()
+
+	push := `{"object_kind": "push", "project": {"path_with_namespace": "g/p", "web_url": "https://gitlab.example.com/g/p"}}`
+	tests := map[string]struct {
+		event, token string
+		wantStatus   int
+		wantEnqueued []api.RepoName
+	}{
+		"push":         {event: "Push Hook", token: "s3cr3t", wantStatus: http.StatusOK, wantEnqueued: []api.RepoName{"gitlab.example.com/g/p"}},
+		"tag push":     {event: "Tag Push Hook", token: "s3cr3t", wantStatus: http.StatusOK, wantEnqueued: []api.RepoName{"gitlab.example.com/g/p"}},
+		"other event":  {event: "Issue Hook", token: "s3cr3t", wantStatus: http.StatusOK},
+		"wrong secret": {event: "Push Hook", token: "wrong", wantStatus: http.StatusUnauthorized},
+		"no secret":    {event: "Push Hook", wantStatus: http.StatusUnauthorized},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			*enqueued = nil
+			req, _ := http.NewRequest("POST", "/webhooks/gitlab", strings.NewReader(push))
+			req.Header.Set("X-Gitlab-Event", test.event)
+			if test.token != "" {
+				req.Header.Set("X-Gitlab-Token", test.token)
+			}
+			resp, err := c.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != test.wantStatus {
+				t.Errorf("got status %d, want %d", resp.StatusCode, test.wantStatus)
+			}
+			if !reflect.DeepEqual(*enqueued, test.wantEnqueued) {
+				t.Errorf("got enqueued %v, want %v", *enqueued, test.wantEnqueued)
+			}
+		})
+	}
+}
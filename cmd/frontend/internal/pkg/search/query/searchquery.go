@@ -3,21 +3,33 @@
 package query
 
 import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query/syntax"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/internal/pkg/search/query/types"
 )
 
 // All field names.
 const (
-	FieldDefault   = ""
-	FieldCase      = "case"
-	FieldRepo      = "repo"
-	FieldRepoGroup = "repogroup"
-	FieldFile      = "file"
-	FieldFork      = "fork"
-	FieldArchived  = "archived"
-	FieldLang      = "lang"
-	FieldType      = "type"
+	FieldDefault     = ""
+	FieldCase        = "case"
+	FieldMultiline   = "multiline"
+	FieldPatternType = "patterntype"
+	FieldRepo        = "repo"
+	FieldRepoGroup   = "repogroup"
+	FieldFile        = "file"
+	FieldFork        = "fork"
+	FieldArchived    = "archived"
+	FieldLang        = "lang"
+	FieldType        = "type"
+	FieldSelect      = "select"
+
+	// For symbol search only:
+	FieldSymbolKind = "symbolkind"
+	FieldParent     = "parent"
 
 	// For diff and commit search only:
 	FieldBefore    = "before"
@@ -25,6 +37,7 @@ const (
 	FieldAuthor    = "author"
 	FieldCommitter = "committer"
 	FieldMessage   = "message"
+	FieldSigned    = "signed"
 
 	// Temporary experimental fields:
 	FieldIndex   = "index"
@@ -33,27 +46,44 @@ const (
 	FieldTimeout = "timeout"
 )
 
+// Values of the patterntype: field.
+const (
+	PatternTypeRegexp     = "regexp"
+	PatternTypeStructural = "structural"
+)
+
+// SelectSymbol is the value of the select: field that selects symbol results.
+// A symbol kind can be appended to it, as in "select:symbol.function".
+const SelectSymbol = "symbol"
+
 var (
 	regexpNegatableFieldType = types.FieldType{Literal: types.RegexpType, Quoted: types.RegexpType, Negatable: true}
 	stringFieldType          = types.FieldType{Literal: types.StringType, Quoted: types.StringType}
 
 	conf = types.Config{
 		FieldTypes: map[string]types.FieldType{
-			FieldDefault:   {Literal: types.RegexpType, Quoted: types.StringType},
-			FieldCase:      {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
-			FieldRepo:      regexpNegatableFieldType,
-			FieldRepoGroup: {Literal: types.StringType, Quoted: types.StringType, Singular: true},
-			FieldFile:      regexpNegatableFieldType,
-			FieldFork:      {Literal: types.StringType, Quoted: types.StringType, Singular: true},
-			FieldArchived:  {Literal: types.StringType, Quoted: types.StringType, Singular: true},
-			FieldLang:      {Literal: types.StringType, Quoted: types.StringType, Negatable: true},
-			FieldType:      stringFieldType,
+			FieldDefault:     {Literal: types.RegexpType, Quoted: types.StringType, Negatable: true},
+			FieldCase:        {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
+			FieldMultiline:   {Literal: types.BoolType, Quoted: types.BoolType, Singular: true},
+			FieldPatternType: {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldRepo:        regexpNegatableFieldType,
+			FieldRepoGroup:   {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldFile:        regexpNegatableFieldType,
+			FieldFork:        {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldArchived:    {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+			FieldLang:        {Literal: types.StringType, Quoted: types.StringType, Negatable: true},
+			FieldType:        stringFieldType,
+			FieldSelect:      {Literal: types.StringType, Quoted: types.StringType, Singular: true},
+
+			FieldSymbolKind: stringFieldType,
+			FieldParent:     {Literal: types.RegexpType, Quoted: types.RegexpType, Singular: true},
 
 			FieldBefore:    stringFieldType,
 			FieldAfter:     stringFieldType,
 			FieldAuthor:    regexpNegatableFieldType,
 			FieldCommitter: regexpNegatableFieldType,
 			FieldMessage:   regexpNegatableFieldType,
+			FieldSigned:    {Literal: types.StringType, Quoted: types.StringType, Singular: true},
 
 			// Experimental fields:
 			FieldIndex:   {Literal: types.StringType, Quoted: types.StringType, Singular: true},
@@ -80,8 +110,19 @@ type Query struct {
 	conf *types.Config // the typechecker config used to produce this query
 
 	*types.Query // the underlying query
+
+	// Disjuncts is set if the query contains boolean operators (and, or, not,
+	// or parenthesized groups). It holds the flat queries whose combined
+	// results are the results of this query. For a query with boolean
+	// operators, the fields of the underlying query contain the values from
+	// all expressions in the query, regardless of the operators.
+	Disjuncts []*Query
 }
 
+// maxDisjuncts is the maximum number of flat queries that a query with
+// boolean operators may expand to.
+const maxDisjuncts = 10
+
 // ParseAndCheck parses and typechecks a search query using the default
 // query type configuration.
 func ParseAndCheck(input string) (*Query, error) {
@@ -93,6 +134,109 @@ func parseAndCheck(conf *types.Config, input string) (*Query, error) {
 	if err != nil {
 		return nil, err
 	}
+	structural := isStructural(conf, syntaxQuery)
+	if structural {
+		conf = structuralConfig(conf)
+	}
+	checkedQuery, err := conf.Check(syntaxQuery)
+	if err != nil {
+		return nil, err
+	}
+	q := &Query{conf: conf, Query: checkedQuery}
+	if err := q.checkPatternType(); err != nil {
+		return nil, err
+	}
+	if err := q.checkSelect(); err != nil {
+		return nil, err
+	}
+	if syntaxQuery.IsFlat() {
+		return q, nil
+	}
+
+	conjunctions, err := syntax.DNF(syntaxQuery.Tree, maxDisjuncts)
+	if err != nil {
+		return nil, err
+	}
+	for _, exprs := range conjunctions {
+		disjunct, err := checkFlat(conf, exprs)
+		if err != nil {
+			return nil, err
+		}
+		q.Disjuncts = append(q.Disjuncts, disjunct)
+	}
+	if structural {
+		// Structural patterns can't be combined into a regexp.
+		return q, nil
+	}
+	q.Disjuncts, err = mergeDisjuncts(conf, q.Disjuncts)
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// isStructural reports whether the query specifies patterntype:structural.
+// It must be determined before typechecking the query with conf, because
+// structural patterns are not valid regexps.
+func isStructural(conf *types.Config, syntaxQuery *syntax.Query) bool {
+	conf = structuralConfig(conf)
+	checkedQuery, err := conf.Check(syntaxQuery)
+	if err != nil {
+		return false
+	}
+	q := &Query{conf: conf, Query: checkedQuery}
+	return q.IsStructural()
+}
+
+// structuralConfig returns a copy of conf in which search patterns are
+// strings (which are interpreted as structural patterns).
+func structuralConfig(conf *types.Config) *types.Config {
+	structuralConf := &types.Config{
+		FieldTypes:   make(map[string]types.FieldType, len(conf.FieldTypes)),
+		FieldAliases: conf.FieldAliases,
+	}
+	for field, fieldType := range conf.FieldTypes {
+		structuralConf.FieldTypes[field] = fieldType
+	}
+	structuralConf.FieldTypes[FieldDefault] = stringFieldType
+	return structuralConf
+}
+
+// checkPatternType returns an error if the query's patterntype: value is
+// not recognized.
+func (q *Query) checkPatternType() error {
+	for _, v := range q.Fields[FieldPatternType] {
+		switch *v.String {
+		case PatternTypeRegexp, PatternTypeStructural:
+		default:
+			return &types.TypeError{Pos: v.Syntax().Pos, Err: fmt.Errorf("invalid patterntype: value %q (valid values are %q and %q)", *v.String, PatternTypeRegexp, PatternTypeStructural)}
+		}
+	}
+	return nil
+}
+
+// checkSelect returns an error if the query's select: value is not
+// recognized.
+func (q *Query) checkSelect() error {
+	for _, v := range q.Fields[FieldSelect] {
+		if *v.String != SelectSymbol && (!strings.HasPrefix(*v.String, SelectSymbol+".") || *v.String == SelectSymbol+".") {
+			return &types.TypeError{Pos: v.Syntax().Pos, Err: fmt.Errorf("invalid select: value %q (valid values are %q and %q)", *v.String, SelectSymbol, SelectSymbol+".<kind>")}
+		}
+	}
+	return nil
+}
+
+// checkFlat typechecks the flat query consisting of exprs.
+func checkFlat(conf *types.Config, exprs []*syntax.Expr) (*Query, error) {
+	tree := make([]syntax.Node, len(exprs))
+	for i, expr := range exprs {
+		tree[i] = expr
+	}
+	syntaxQuery := &syntax.Query{
+		Input: syntax.ExprString(exprs),
+		Expr:  exprs,
+		Tree:  &syntax.Operator{Kind: syntax.OperatorAnd, Operands: tree},
+	}
 	checkedQuery, err := conf.Check(syntaxQuery)
 	if err != nil {
 		return nil, err
@@ -100,6 +244,103 @@ func parseAndCheck(conf *types.Config, input string) (*Query, error) {
 	return &Query{conf: conf, Query: checkedQuery}, nil
 }
 
+// mergeDisjuncts combines flat queries that differ only in their (single,
+// non-negated) search pattern into one query whose pattern is the
+// alternation of their patterns. For example, "(a or b) repo:r" is searched
+// as "/(?:a)|(?:b)/ repo:r" instead of as 2 separate queries.
+func mergeDisjuncts(conf *types.Config, disjuncts []*Query) ([]*Query, error) {
+	type group struct {
+		disjuncts []*Query
+		patterns  []string
+		others    []*syntax.Expr
+	}
+	var (
+		merged []*Query
+		keys   []string
+		groups = map[string]*group{}
+	)
+	for _, d := range disjuncts {
+		pattern, others, ok := d.splitPattern()
+		if !ok {
+			merged = append(merged, d)
+			continue
+		}
+		key := exprSetKey(others)
+		g, ok := groups[key]
+		if !ok {
+			keys = append(keys, key)
+			g = &group{others: others}
+			groups[key] = g
+		}
+		g.disjuncts = append(g.disjuncts, d)
+		g.patterns = append(g.patterns, pattern)
+	}
+
+	for _, key := range keys {
+		g := groups[key]
+		if len(g.disjuncts) == 1 {
+			merged = append(merged, g.disjuncts[0])
+			continue
+		}
+		pattern := &syntax.Expr{Value: "(?:" + strings.Join(g.patterns, ")|(?:") + ")", ValueType: syntax.TokenPattern}
+		q, err := checkFlat(conf, append(g.others, pattern))
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, q)
+	}
+	return merged, nil
+}
+
+// splitPattern returns the regexp source of q's only non-negated default
+// field value and q's other expressions. If q does not have exactly 1
+// non-negated default field value, ok is false.
+func (q *Query) splitPattern() (pattern string, others []*syntax.Expr, ok bool) {
+	var patternExpr *syntax.Expr
+	for _, v := range q.Fields[FieldDefault] {
+		if v.Not() {
+			continue
+		}
+		if patternExpr != nil {
+			return "", nil, false
+		}
+		patternExpr = v.Syntax()
+		switch {
+		case v.String != nil:
+			pattern = regexp.QuoteMeta(*v.String)
+		case v.Regexp != nil:
+			pattern = v.Regexp.String()
+		}
+	}
+	if patternExpr == nil || pattern == "" {
+		return "", nil, false
+	}
+	for _, expr := range q.Syntax.Expr {
+		if expr != patternExpr {
+			others = append(others, expr)
+		}
+	}
+	return pattern, others, true
+}
+
+// exprSetKey returns a key that is equal for 2 lists of expressions if they
+// contain the same expressions, in any order.
+func exprSetKey(exprs []*syntax.Expr) string {
+	s := make([]string, len(exprs))
+	for i, expr := range exprs {
+		s[i] = expr.String()
+	}
+	sort.Strings(s)
+	return strings.Join(s, "\x00")
+}
+
+// IsFlat reports whether the query has no boolean operators, so that its
+// fields can be used directly to perform a search. Otherwise, each of its
+// Disjuncts must be searched.
+func (q *Query) IsFlat() bool {
+	return len(q.Disjuncts) == 0
+}
+
 // BoolValue returns the last boolean value (yes/no) for the field. For example, if the query is
 // "foo:yes foo:no foo:yes", then the last boolean value for the "foo" field is true ("yes"). The
 // default boolean value is false.
@@ -118,6 +359,50 @@ func (q *Query) IsCaseSensitive() bool {
 	return q.BoolValue(FieldCase)
 }
 
+// IsMultiline reports whether the query's patterns may match text spanning
+// multiple lines.
+func (q *Query) IsMultiline() bool {
+	return q.BoolValue(FieldMultiline)
+}
+
+// IsStructural reports whether the query's search patterns are structural
+// search patterns (patterntype:structural).
+func (q *Query) IsStructural() bool {
+	if _, ok := q.conf.FieldTypes[FieldPatternType]; !ok {
+		return false
+	}
+	patternType, _ := q.StringValue(FieldPatternType)
+	return patternType == PatternTypeStructural
+}
+
+// SelectsSymbols reports whether the query's results are symbols, because it
+// has select:symbol (optionally with a symbol kind) or symbol filters
+// (symbolkind: or parent:).
+func (q *Query) SelectsSymbols() bool {
+	if _, ok := q.conf.FieldTypes[FieldSelect]; !ok {
+		return false
+	}
+	return len(q.Fields[FieldSelect]) > 0 || len(q.Fields[FieldSymbolKind]) > 0 || len(q.Fields[FieldParent]) > 0
+}
+
+// SymbolKinds returns the symbol kinds that the query's symbol results are
+// restricted to (in lowercase), given by select:symbol.<kind> and
+// symbolkind:<kind>. If it is empty, symbols of all kinds are included.
+func (q *Query) SymbolKinds() []string {
+	if _, ok := q.conf.FieldTypes[FieldSelect]; !ok {
+		return nil
+	}
+	var kinds []string
+	if selected, _ := q.StringValue(FieldSelect); strings.HasPrefix(selected, SelectSymbol+".") {
+		kinds = append(kinds, strings.ToLower(strings.TrimPrefix(selected, SelectSymbol+".")))
+	}
+	symbolKinds, _ := q.StringValues(FieldSymbolKind)
+	for _, kind := range symbolKinds {
+		kinds = append(kinds, strings.ToLower(kind))
+	}
+	return kinds
+}
+
 // Values returns the values for the given field.
 func (q *Query) Values(field string) []*types.Value {
 	if _, ok := q.conf.FieldTypes[field]; !ok {
@@ -142,3 +142,121 @@ func checkPanic(t *testing.T, msg string, f func()) {
 	}()
 	f()
 }
+
+func TestParseAndCheck_booleanOperators(t *testing.T) {
+	tests := map[string]struct {
+		wantDisjuncts []string
+	}{
+		"a b":                            {},
+		"a -b":                           {},
+		"a or b":                         {wantDisjuncts: []string{"/(?:a)|(?:b)/"}},
+		`(a or "b.c") repo:r -d`:         {wantDisjuncts: []string{`repo:r -d /(?:a)|(?:b\.c)/`}},
+		"a b or c":                       {wantDisjuncts: []string{"a b", "c"}},
+		"repo:a file:x or repo:b file:y": {wantDisjuncts: []string{"repo:a file:x", "repo:b file:y"}},
+		"(a or b) (repo:r or repo:s)":    {wantDisjuncts: []string{"repo:r /(?:a)|(?:b)/", "repo:s /(?:a)|(?:b)/"}},
+		"case:yes a or case:no b":        {wantDisjuncts: []string{"case:yes a", "case:no b"}},
+		"not (a or b)":                   {wantDisjuncts: []string{"-a -b"}},
+	}
+	for input, test := range tests {
+		t.Run(input, func(t *testing.T) {
+			query, err := ParseAndCheck(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query.IsFlat() != (len(test.wantDisjuncts) == 0) {
+				t.Errorf("got IsFlat() == %v", query.IsFlat())
+			}
+			var got []string
+			for _, d := range query.Disjuncts {
+				got = append(got, d.Syntax.Input)
+			}
+			if !reflect.DeepEqual(got, test.wantDisjuncts) {
+				t.Errorf("got disjuncts %q, want %q", got, test.wantDisjuncts)
+			}
+		})
+	}
+}
+
+func TestParseAndCheck_patternType(t *testing.T) {
+	tests := map[string]struct {
+		wantStructural bool
+		wantPatterns   []string
+		wantErr        bool
+	}{
+		"a(":                        {wantErr: true},
+		"patterntype:regexp a(":     {wantErr: true},
+		"patterntype:structural a(": {wantStructural: true, wantPatterns: []string{"a("}},
+		"patterntype:structural fmt.Errorf(:[args])":  {wantStructural: true, wantPatterns: []string{"fmt.Errorf(:[args])"}},
+		"patterntype:structural f(:[a], :[b])":        {wantStructural: true, wantPatterns: []string{"f(:[a],", ":[b])"}},
+		`patterntype:structural "if :[x] {" repo:r`:   {wantStructural: true, wantPatterns: []string{"if :[x] {"}},
+		"patterntype:regexp fmt.Errorf":               {wantPatterns: []string{"fmt.Errorf"}},
+		"patterntype:foo a":                           {wantErr: true},
+		"patterntype:structural patterntype:regexp a": {wantErr: true},
+	}
+	for input, test := range tests {
+		t.Run(input, func(t *testing.T) {
+			query, err := ParseAndCheck(input)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got nil error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if query.IsStructural() != test.wantStructural {
+				t.Errorf("got IsStructural() == %v", query.IsStructural())
+			}
+			var got []string
+			for _, v := range query.Fields[FieldDefault] {
+				if v.String != nil {
+					got = append(got, *v.String)
+				} else {
+					got = append(got, v.Regexp.String())
+				}
+			}
+			if !reflect.DeepEqual(got, test.wantPatterns) {
+				t.Errorf("got patterns %q, want %q", got, test.wantPatterns)
+			}
+		})
+	}
+}
+
+func TestParseAndCheck_selectSymbols(t *testing.T) {
+	tests := map[string]struct {
+		wantSelectsSymbols bool
+		wantKinds          []string
+		wantErr            bool
+	}{
+		"a":                                     {},
+		"select:symbol a":                       {wantSelectsSymbols: true},
+		"select:symbol.function a":              {wantSelectsSymbols: true, wantKinds: []string{"function"}},
+		"select:symbol.Struct symbolkind:class": {wantSelectsSymbols: true, wantKinds: []string{"struct", "class"}},
+		"symbolkind:method symbolkind:field":    {wantSelectsSymbols: true, wantKinds: []string{"method", "field"}},
+		"parent:Server":                         {wantSelectsSymbols: true},
+		"select:symbol.":                        {wantErr: true},
+		"select:file a":                         {wantErr: true},
+		"parent:(":                              {wantErr: true},
+	}
+	for input, test := range tests {
+		t.Run(input, func(t *testing.T) {
+			query, err := ParseAndCheck(input)
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("got nil error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := query.SelectsSymbols(); got != test.wantSelectsSymbols {
+				t.Errorf("got SelectsSymbols() == %v", got)
+			}
+			if got := query.SymbolKinds(); !reflect.DeepEqual(got, test.wantKinds) {
+				t.Errorf("got SymbolKinds() == %q, want %q", got, test.wantKinds)
+			}
+		})
+	}
+}
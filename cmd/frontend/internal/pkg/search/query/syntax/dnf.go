@@ -0,0 +1,91 @@
+package syntax
+
+import "fmt"
+
+// DNF returns the disjunctive normal form of the boolean expression tree n:
+// a list of conjunctions whose union is equivalent to n. Each conjunction is
+// a flat list of (possibly negated) expressions, just like the Expr list of a
+// flat query.
+//
+// Negated groups are rewritten using De Morgan's laws (e.g., "not (a or b)"
+// becomes "-a -b"). Because the number of conjunctions can grow exponentially
+// with the size of the query, an error is returned if there would be more
+// than max conjunctions.
+//
+// The expressions in the returned conjunctions are copies, so modifying them
+// does not affect n. They may be shared among conjunctions.
+func DNF(n Node, max int) ([][]*Expr, error) {
+	if n == nil {
+		return nil, nil
+	}
+	return dnf(n, false, max)
+}
+
+func dnf(n Node, not bool, max int) ([][]*Expr, error) {
+	switch n := n.(type) {
+	case *Expr:
+		e := *n
+		e.Not = e.Not != not
+		return [][]*Expr{{&e}}, nil
+
+	case *Operator:
+		kind := n.Kind
+		if kind == OperatorNot {
+			return dnf(n.Operands[0], !not, max)
+		}
+		if not {
+			// De Morgan's laws.
+			if kind == OperatorAnd {
+				kind = OperatorOr
+			} else {
+				kind = OperatorAnd
+			}
+		}
+
+		switch kind {
+		case OperatorOr:
+			var conjunctions [][]*Expr
+			for _, operand := range n.Operands {
+				c, err := dnf(operand, not, max)
+				if err != nil {
+					return nil, err
+				}
+				conjunctions = append(conjunctions, c...)
+				if len(conjunctions) > max {
+					return nil, tooComplexError(n.Pos, max)
+				}
+			}
+			return conjunctions, nil
+
+		case OperatorAnd:
+			// Distribute the AND over the conjunctions of each operand, as in
+			// "(a or b) c" => "a c or b c".
+			conjunctions := [][]*Expr{{}}
+			for _, operand := range n.Operands {
+				c, err := dnf(operand, not, max)
+				if err != nil {
+					return nil, err
+				}
+				if len(conjunctions)*len(c) > max {
+					return nil, tooComplexError(n.Pos, max)
+				}
+				product := make([][]*Expr, 0, len(conjunctions)*len(c))
+				for _, left := range conjunctions {
+					for _, right := range c {
+						conjunction := make([]*Expr, 0, len(left)+len(right))
+						conjunction = append(conjunction, left...)
+						conjunction = append(conjunction, right...)
+						product = append(product, conjunction)
+					}
+				}
+				conjunctions = product
+			}
+			return conjunctions, nil
+		}
+	}
+	panic(fmt.Sprintf("unexpected node %T", n))
+}
+
+func tooComplexError(pos, max int) error {
+	return &ParseError{Pos: pos, Msg: fmt.Sprintf("query is too complex (it expands to more than %d alternatives)", max)}
+}
@@ -0,0 +1,50 @@
+package syntax
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDNF(t *testing.T) {
+	tests := map[string]struct {
+		want    []string
+		wantErr *ParseError
+	}{
+		"":                               {},
+		"a":                              {want: []string{"a"}},
+		"a b":                            {want: []string{"a b"}},
+		"a or b":                         {want: []string{"a", "b"}},
+		"(a or b) c":                     {want: []string{"a c", "b c"}},
+		"(a or b) (c or d)":              {want: []string{"a c", "a d", "b c", "b d"}},
+		"(a or b) -c":                    {want: []string{"a -c", "b -c"}},
+		"not (a or b)":                   {want: []string{"-a -b"}},
+		"not (a b)":                      {want: []string{"-a", "-b"}},
+		"not (a -b)":                     {want: []string{"-a", "b"}},
+		"not (not (a or b) or c)":        {want: []string{"a -c", "b -c"}},
+		"repo:a file:x or repo:b file:y": {want: []string{"repo:a file:x", "repo:b file:y"}},
+		"(a or b) (c or d) (e or f)":     {wantErr: &ParseError{Pos: 0, Msg: "query is too complex (it expands to more than 5 alternatives)"}},
+	}
+	for input, test := range tests {
+		t.Run(input, func(t *testing.T) {
+			query, err := Parse(input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			conjunctions, err := DNF(query.Tree, 5)
+			if err != nil && test.wantErr == nil {
+				t.Fatal(err)
+			} else if err == nil && test.wantErr != nil {
+				t.Fatalf("got err == nil, want %q", test.wantErr)
+			} else if test.wantErr != nil && !reflect.DeepEqual(err, test.wantErr) {
+				t.Fatalf("got err == %q, want %q", err, test.wantErr)
+			}
+			var got []string
+			for _, c := range conjunctions {
+				got = append(got, ExprString(c))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package syntax
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // ParseError describes an error in query parsing.
 type ParseError struct {
@@ -27,20 +30,26 @@ type context struct {
 //
 // BNF-ish query syntax:
 //
-//   exprList  := {exprSign} | exprSign (sep exprSign)*
-//   exprSign  := {"-"} expr
+//   query     := {sep} [orExpr] {sep}
+//   orExpr    := andExpr (sep "or" sep andExpr)*
+//   andExpr   := notExpr (sep ["and" sep] notExpr)*
+//   notExpr   := "not" sep notExpr | ["-"] group | ["-"] expr
+//   group     := "(" {sep} orExpr {sep} ")"
 //   expr      := fieldExpr | lit | quoted | pattern
 //   fieldExpr := lit ":" value
 //   value     := lit | quoted
+//
+// The operator keywords "and", "or" and "not" may also be written in
+// uppercase. To search for one of them literally, quote it (e.g., "or").
 func Parse(input string) (*Query, error) {
 	tokens := Scan(input)
 	p := parser{tokens: tokens}
 	ctx := context{field: ""}
-	exprs, err := p.parseExprList(ctx)
+	tree, err := p.parseQuery(ctx)
 	if err != nil {
 		return nil, err
 	}
-	return &Query{Expr: exprs, Input: input}, nil
+	return &Query{Expr: leaves(tree, nil), Tree: tree, Input: input}, nil
 }
 
 // peek returns the next token without consuming it. Peeking beyond the end of
@@ -68,54 +77,167 @@ func (p *parser) next() Token {
 	return Token{Type: TokenEOF}
 }
 
-// exprList := {exprSign} | exprSign (sep exprSign)*
-func (p *parser) parseExprList(ctx context) (exprList []*Expr, err error) {
-	if p.peek().Type == TokenEOF {
+// query := {sep} [orExpr] {sep}
+func (p *parser) parseQuery(ctx context) (Node, error) {
+	p.skipSep()
+	if tok := p.peek(); tok.Type == TokenEOF {
 		return nil, nil
 	}
+	tree, err := p.parseOrExpr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSep()
+	switch tok := p.next(); tok.Type {
+	case TokenEOF:
+		return tree, nil
+	case TokenError:
+		return nil, &ParseError{Pos: tok.Pos, Msg: tok.Value}
+	default:
+		return nil, &ParseError{Pos: tok.Pos, Msg: fmt.Sprintf("got %s, want separator or EOF", tok.Type)}
+	}
+}
 
+// orExpr := andExpr (sep "or" sep andExpr)*
+func (p *parser) parseOrExpr(ctx context) (Node, error) {
+	pos := p.peek().Pos
+	var operands []Node
 	for {
-		tok := p.peek()
-		if tok.Type == TokenEOF {
+		operand, err := p.parseAndExpr(ctx)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+		if !p.acceptKeyword(keywordOr) {
 			break
 		}
-		if tok.Type == TokenSep {
-			p.next()
+	}
+	return newOperator(pos, OperatorOr, operands), nil
+}
+
+// andExpr := notExpr (sep ["and" sep] notExpr)*
+func (p *parser) parseAndExpr(ctx context) (Node, error) {
+	pos := p.peek().Pos
+	var operands []Node
+	for {
+		operand, err := p.parseNotExpr(ctx)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, operand)
+
+		start := p.pos
+		p.skipSep()
+		if p.acceptKeyword(keywordAnd) {
 			continue
 		}
+		if tok := p.peek(); tok.Type == TokenEOF || tok.Type == TokenRParen || p.atKeyword(keywordOr) || start == p.pos {
+			// Leave the separator (if any) for the caller.
+			p.pos = start
+			break
+		}
+	}
+	return newOperator(pos, OperatorAnd, operands), nil
+}
 
-		expr, err := p.parseExprSign(ctx)
+// notExpr := "not" sep notExpr | ["-"] group | ["-"] expr
+func (p *parser) parseNotExpr(ctx context) (Node, error) {
+	tok := p.peek()
+	if p.atKeyword(keywordNot) {
+		p.next()
+		p.skipSep()
+		operand, err := p.parseNotExpr(ctx)
 		if err != nil {
 			return nil, err
 		}
-		exprList = append(exprList, expr)
+		return negate(tok.Pos, operand), nil
 	}
 
-	return exprList, nil
-}
+	not := false
+	if tok.Type == TokenMinus {
+		p.next()
+		not = true
+	}
 
-// exprSign := {"-"} expr
-func (p *parser) parseExprSign(ctx context) (*Expr, error) {
-	tok := p.next()
-	switch tok.Type {
-	case TokenMinus:
-		// consume token
-	default:
-		tok = Token{Type: TokenEOF}
-		p.backup()
+	var (
+		operand Node
+		err     error
+	)
+	if p.peek().Type == TokenLParen {
+		operand, err = p.parseGroup(ctx)
+	} else {
+		operand, err = p.parseExpr(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if not {
+		return negate(tok.Pos, operand), nil
 	}
+	return operand, nil
+}
 
-	expr, err := p.parseExpr(ctx)
+// group := "(" {sep} orExpr {sep} ")"
+func (p *parser) parseGroup(ctx context) (Node, error) {
+	lparen := p.next()
+	p.skipSep()
+	if tok := p.peek(); tok.Type == TokenRParen {
+		return nil, &ParseError{Pos: tok.Pos, Msg: "empty group"}
+	}
+	operand, err := p.parseOrExpr(ctx)
 	if err != nil {
 		return nil, err
 	}
+	p.skipSep()
+	if tok := p.next(); tok.Type != TokenRParen {
+		if tok.Type == TokenEOF {
+			return nil, &ParseError{Pos: lparen.Pos, Msg: "unclosed group"}
+		}
+		return nil, &ParseError{Pos: tok.Pos, Msg: fmt.Sprintf("got %s, want %s", tok.Type, TokenRParen)}
+	}
+	return operand, nil
+}
 
-	switch tok.Type {
-	case TokenMinus:
-		expr.Not = true
+// Operator keywords.
+const (
+	keywordAnd = "and"
+	keywordOr  = "or"
+	keywordNot = "not"
+)
+
+// atKeyword reports whether the next token is the given operator keyword
+// (in lowercase or uppercase).
+func (p *parser) atKeyword(keyword string) bool {
+	tok := p.peek()
+	if tok.Type != TokenLiteral || (tok.Value != keyword && tok.Value != strings.ToUpper(keyword)) {
+		return false
+	}
+	// A keyword followed by ":" is a field name.
+	if p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].Type == TokenColon {
+		return false
 	}
+	return true
+}
 
-	return expr, nil
+// acceptKeyword consumes {sep} keyword {sep} if it is next in the token
+// stream and reports whether it did so.
+func (p *parser) acceptKeyword(keyword string) bool {
+	start := p.pos
+	p.skipSep()
+	if p.atKeyword(keyword) {
+		p.next()
+		p.skipSep()
+		return true
+	}
+	p.pos = start
+	return false
+}
+
+// skipSep consumes any separator tokens at the current position.
+func (p *parser) skipSep() {
+	for p.peek().Type == TokenSep {
+		p.next()
+	}
 }
 
 // expr := exprField | lit | quoted | pattern
@@ -129,29 +251,86 @@ func (p *parser) parseExpr(ctx context) (*Expr, error) {
 			valueTok := p.next()
 			switch valueTok.Type {
 			case TokenLiteral, TokenQuoted:
-				if tok3 := p.next(); tok3.Type != TokenSep && tok3.Type != TokenEOF {
-					return nil, &ParseError{Pos: tok3.Pos, Msg: fmt.Sprintf("got %s, want separator or EOF", tok3.Type)}
+				if err := p.expectExprEnd(); err != nil {
+					return nil, err
 				}
 				return &Expr{Pos: tok.Pos, Field: tok.Value, Value: valueTok.Value, ValueType: valueTok.Type}, nil
-			case TokenSep, TokenEOF:
+			case TokenSep, TokenEOF, TokenRParen:
+				p.backup()
 				return &Expr{Pos: tok.Pos, Field: tok.Value, Value: "", ValueType: TokenLiteral}, nil
 			default:
 				return nil, &ParseError{Pos: valueTok.Pos, Msg: fmt.Sprintf("got %s, want value", valueTok.Type)}
 			}
-		case TokenSep, TokenEOF:
+		case TokenSep, TokenEOF, TokenRParen:
+			p.backup()
 			return &Expr{Pos: tok.Pos, Value: tok.Value, ValueType: tok.Type}, nil
 		default:
 			panic("unreachable")
 		}
 	case TokenQuoted, TokenPattern:
-		tok2 := p.next()
-		switch tok2.Type {
-		case TokenSep, TokenEOF:
-			return &Expr{Pos: tok.Pos, Value: tok.Value, ValueType: tok.Type}, nil
-		default:
-			return nil, &ParseError{Pos: tok2.Pos, Msg: fmt.Sprintf("got %s, want separator or EOF", tok2.Type)}
+		if err := p.expectExprEnd(); err != nil {
+			return nil, err
 		}
+		return &Expr{Pos: tok.Pos, Value: tok.Value, ValueType: tok.Type}, nil
 	}
 
 	return nil, &ParseError{Pos: tok.Pos, Msg: fmt.Sprintf("got %s, want expr", tok.Type)}
 }
+
+// expectExprEnd returns an error unless the next token ends an expression
+// (a separator, the end of a group or EOF). It does not consume the token.
+func (p *parser) expectExprEnd() error {
+	switch tok := p.peek(); tok.Type {
+	case TokenSep, TokenEOF, TokenRParen:
+		return nil
+	default:
+		return &ParseError{Pos: tok.Pos, Msg: fmt.Sprintf("got %s, want separator or EOF", tok.Type)}
+	}
+}
+
+// newOperator returns an operator of the given kind with the given operands,
+// or the sole operand itself if there is only 1. Operands of the same kind
+// are flattened into the new operator (e.g., "a and (b and c)" is
+// equivalent to "a and b and c").
+func newOperator(pos int, kind OperatorKind, operands []Node) Node {
+	if len(operands) == 1 {
+		return operands[0]
+	}
+	flattened := make([]Node, 0, len(operands))
+	for _, operand := range operands {
+		if o, ok := operand.(*Operator); ok && o.Kind == kind {
+			flattened = append(flattened, o.Operands...)
+		} else {
+			flattened = append(flattened, operand)
+		}
+	}
+	return &Operator{Pos: pos, Kind: kind, Operands: flattened}
+}
+
+// negate returns the negation of n. Negated expressions are represented by
+// setting (*Expr).Not, so that "not a" is equivalent to "-a".
+func negate(pos int, n Node) Node {
+	switch n := n.(type) {
+	case *Expr:
+		n.Not = !n.Not
+		return n
+	case *Operator:
+		if n.Kind == OperatorNot {
+			return n.Operands[0]
+		}
+	}
+	return &Operator{Pos: pos, Kind: OperatorNot, Operands: []Node{n}}
+}
+
+// leaves appends the expressions in the tree rooted at n to exprs, in order.
+func leaves(n Node, exprs []*Expr) []*Expr {
+	switch n := n.(type) {
+	case *Expr:
+		exprs = append(exprs, n)
+	case *Operator:
+		for _, operand := range n.Operands {
+			exprs = leaves(operand, exprs)
+		}
+	}
+	return exprs
+}
@@ -111,3 +111,63 @@ func TestParser(t *testing.T) {
 		})
 	}
 }
+
+func TestParser_boolean(t *testing.T) {
+	tests := map[string]struct {
+		wantTree string
+		wantFlat bool
+		wantErr  *ParseError
+	}{
+		"a b":                            {wantTree: "a b", wantFlat: true},
+		"a and b":                        {wantTree: "a b", wantFlat: true},
+		"a AND b":                        {wantTree: "a b", wantFlat: true},
+		"not a":                          {wantTree: "-a", wantFlat: true},
+		"not not a":                      {wantTree: "a", wantFlat: true},
+		"a or b":                         {wantTree: "a or b"},
+		"a OR b":                         {wantTree: "a or b"},
+		"a b or c":                       {wantTree: "a b or c"},
+		"a (b or c)":                     {wantTree: "a (b or c)"},
+		"(a or b) -c":                    {wantTree: "(a or b) -c"},
+		"( a or b )":                     {wantTree: "a or b"},
+		"((a or b))":                     {wantTree: "a or b"},
+		"-(a b)":                         {wantTree: "not (a b)"},
+		"not (a or b)":                   {wantTree: "not (a or b)"},
+		"(a or (b or c))":                {wantTree: "a or b or c"},
+		"repo:a file:x or repo:b file:y": {wantTree: "repo:a file:x or repo:b file:y"},
+		"(repo:a or repo:b) c":           {wantTree: "(repo:a or repo:b) c"},
+		`("a" or /b/) c`:                 {wantTree: `("a" or /b/) c`},
+		"(a|b)c":                         {wantTree: "(a|b)c", wantFlat: true},
+		"f() or g()":                     {wantTree: "f() or g()"},
+		"(f() or g())":                   {wantTree: "f() or g()"},
+		"(a or (b|c))":                   {wantTree: "a or (b|c)"},
+		`"or"`:                           {wantTree: `"or"`, wantFlat: true},
+		"or:a":                           {wantTree: "or:a", wantFlat: true},
+		"a:or":                           {wantTree: "a:or", wantFlat: true},
+		"(a or b":                        {wantErr: &ParseError{Pos: 0, Msg: "unclosed group"}},
+		"( )":                            {wantErr: &ParseError{Pos: 2, Msg: "empty group"}},
+		"a or":                           {wantErr: &ParseError{Pos: 4, Msg: "got TokenEOF, want expr"}},
+		"(a)b":                           {wantTree: "(a)b", wantFlat: true},
+		"(a b)c":                         {wantErr: &ParseError{Pos: 0, Msg: "unclosed group"}},
+	}
+	for input, test := range tests {
+		t.Run(input, func(t *testing.T) {
+			query, err := Parse(input)
+			if err != nil && test.wantErr == nil {
+				t.Fatal(err)
+			} else if err == nil && test.wantErr != nil {
+				t.Fatalf("got err == nil, want %q", test.wantErr)
+			} else if test.wantErr != nil && !reflect.DeepEqual(err, test.wantErr) {
+				t.Fatalf("got err == %q, want %q", err, test.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if tree := query.Tree.String(); tree != test.wantTree {
+				t.Errorf("tree: got %q, want %q", tree, test.wantTree)
+			}
+			if flat := query.IsFlat(); flat != test.wantFlat {
+				t.Errorf("IsFlat: got %v, want %v", flat, test.wantFlat)
+			}
+		})
+	}
+}
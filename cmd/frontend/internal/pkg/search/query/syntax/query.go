@@ -8,7 +8,87 @@ import (
 // A Query contains the parse tree of a query.
 type Query struct {
 	Input string  // the original input query string
-	Expr  []*Expr // expressions in this query
+	Expr  []*Expr // expressions in this query (the leaves of Tree, in order)
+	Tree  Node    // boolean expression tree of the query (nil if the query is empty)
+}
+
+// IsFlat reports whether the query consists only of (possibly negated)
+// expressions that are implicitly ANDed together, with no boolean operators
+// or groups. All queries were flat before boolean operators were supported,
+// and most consumers of queries still only handle flat queries.
+func (q *Query) IsFlat() bool {
+	switch n := q.Tree.(type) {
+	case nil, *Expr:
+		return true
+	case *Operator:
+		if n.Kind != OperatorAnd {
+			return false
+		}
+		for _, o := range n.Operands {
+			if _, ok := o.(*Expr); !ok {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// A Node is a node in a query's boolean expression tree. It is either an
+// *Expr (a leaf) or an *Operator.
+type Node interface {
+	String() string
+}
+
+// OperatorKind is the set of boolean operators in the query syntax.
+type OperatorKind int
+
+// All OperatorKind values.
+const (
+	OperatorAnd OperatorKind = iota // "a and b", or implicitly "a b"
+	OperatorOr                      // "a or b"
+	OperatorNot                     // "not a" or "-(a)"
+)
+
+// An Operator is a boolean operator applied to one or more operands. An
+// OperatorNot always has exactly 1 operand.
+type Operator struct {
+	Pos      int // the starting character position of the operator's expression
+	Kind     OperatorKind
+	Operands []Node
+}
+
+func (o *Operator) String() string {
+	switch o.Kind {
+	case OperatorNot:
+		return "not " + groupString(o.Operands[0], true)
+	case OperatorOr:
+		s := make([]string, len(o.Operands))
+		for i, operand := range o.Operands {
+			s[i] = groupString(operand, false)
+		}
+		return strings.Join(s, " or ")
+	default:
+		s := make([]string, len(o.Operands))
+		for i, operand := range o.Operands {
+			s[i] = groupString(operand, isOperator(operand, OperatorOr))
+		}
+		return strings.Join(s, " ")
+	}
+}
+
+// groupString returns the string representation of n, wrapped in
+// parentheses if n is an operator and paren is true.
+func groupString(n Node, paren bool) string {
+	if _, ok := n.(*Operator); ok && paren {
+		return "(" + n.String() + ")"
+	}
+	return n.String()
+}
+
+func isOperator(n Node, kind OperatorKind) bool {
+	o, ok := n.(*Operator)
+	return ok && o.Kind == kind
 }
 
 // An Expr describes an expression in a query.
@@ -1,6 +1,7 @@
 package syntax
 
 import (
+	"regexp"
 	"strings"
 	"unicode"
 	"unicode/utf8"
@@ -21,7 +22,9 @@ const (
 	TokenPattern
 	TokenColon
 	TokenMinus
-	TokenSep // separator (like a semicolon)
+	TokenSep    // separator (like a semicolon)
+	TokenLParen // opening parenthesis of a group
+	TokenRParen // closing parenthesis of a group
 )
 
 var singleCharTokens = map[rune]TokenType{
@@ -54,6 +57,7 @@ type scanner struct {
 	pos     int
 	prevPos int
 	start   int
+	depth   int // number of currently open groups
 }
 
 func (s *scanner) next() rune {
@@ -106,6 +110,10 @@ func scanDefault(s *scanner) stateFn {
 	if !unicode.IsSpace(r) {
 		s.backup()
 		s.ignore()
+		if r == ':' && strings.HasPrefix(s.input[s.pos:], ":[") {
+			// A structural search hole (e.g., ":[x]"), not a field.
+			return scanLiteral
+		}
 		if typ, ok := singleCharTokens[r]; ok {
 			s.next()
 			s.emit(typ)
@@ -118,6 +126,18 @@ func scanDefault(s *scanner) stateFn {
 		if r == '/' {
 			return scanPattern
 		}
+		if r == '(' && isGroupStart(s.input[s.pos:]) {
+			s.next()
+			s.emit(TokenLParen)
+			s.depth++
+			return scanDefault
+		}
+		if r == ')' && s.depth > 0 {
+			s.next()
+			s.emit(TokenRParen)
+			s.depth--
+			return scanDefault
+		}
 
 		return scanText
 	}
@@ -180,10 +200,78 @@ func scanLiteral(s *scanner) stateFn {
 		}
 	}
 
+	// Closing parentheses at the end of a literal that are not balanced
+	// within the literal close open groups (e.g., the "b)" in "(a or b)").
+	if s.depth > 0 {
+		if n := unbalancedSuffixParens(s.input[s.start:s.pos]); n > 0 {
+			if n > s.depth {
+				n = s.depth
+			}
+			end := s.pos
+			s.pos -= n
+			if s.pos > s.start {
+				s.emit(TokenLiteral)
+			}
+			for ; s.pos < end; s.depth-- {
+				s.pos++
+				s.emit(TokenRParen)
+			}
+			return scanDefault
+		}
+	}
+
 	s.emit(TokenLiteral)
 	return scanDefault
 }
 
+// isGroupStart reports whether the '(' at the beginning of input opens a
+// group. A parenthesis is part of a literal (such as the regexp "(a|b)c")
+// if it is balanced within the whitespace-delimited word it begins, unless
+// it is followed by a field (as in "(repo:a)").
+func isGroupStart(input string) bool {
+	word := input
+	if i := strings.IndexFunc(input, unicode.IsSpace); i >= 0 {
+		word = input[:i]
+	}
+	if fieldPrefix.MatchString(word[1:]) {
+		return true
+	}
+	depth := 0
+	for _, r := range word {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+var fieldPrefix = regexp.MustCompile(`^-?[a-z0-9]+:`)
+
+// unbalancedSuffixParens returns the number of trailing ')' characters in
+// word that have no matching '(' earlier in word.
+func unbalancedSuffixParens(word string) int {
+	depth, unbalanced := 0, 0
+	for i, r := range word {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			} else if strings.Trim(word[i:], ")") == "" {
+				unbalanced++
+			}
+		}
+	}
+	return unbalanced
+}
+
 func scanQuoted(s *scanner) stateFn {
 	q := s.next()
 	escaped := false
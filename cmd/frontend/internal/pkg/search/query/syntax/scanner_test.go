@@ -15,6 +15,7 @@ func TestScanner(t *testing.T) {
 		"\n":       {wantTypes: []TokenType{}},
 		"a":        {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"a"}},
 		":":        {wantTypes: []TokenType{TokenColon}, wantValues: []string{":"}},
+		":[a]":     {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{":[a]"}},
 		"-":        {wantTypes: []TokenType{TokenMinus}, wantValues: []string{"-"}},
 		"a:b":      {wantTypes: []TokenType{TokenLiteral, TokenColon, TokenLiteral}, wantValues: []string{"a", ":", "b"}},
 		"a : b":    {wantTypes: []TokenType{TokenLiteral, TokenSep, TokenColon, TokenSep, TokenLiteral}, wantValues: []string{"a", " ", ":", " ", "b"}},
@@ -53,6 +54,16 @@ func TestScanner(t *testing.T) {
 		`a:"b`:     {wantTypes: []TokenType{TokenLiteral, TokenColon, TokenError}, wantValues: []string{"a", ":", `unclosed quoted string`}},
 		`a"b"c`:    {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{`a"b"c`}},
 		`a"b:c"d`:  {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{`a"b:c"d`}},
+		"(a|b)":    {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"(a|b)"}},
+		"f()":      {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"f()"}},
+		"a)":       {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"a)"}},
+		"(a b)":    {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenSep, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a", " ", "b", ")"}},
+		"((a))":    {wantTypes: []TokenType{TokenLiteral}, wantValues: []string{"((a))"}},
+		"((a b))":  {wantTypes: []TokenType{TokenLParen, TokenLParen, TokenLiteral, TokenSep, TokenLiteral, TokenRParen, TokenRParen}, wantValues: []string{"(", "(", "a", " ", "b", ")", ")"}},
+		"(a f())":  {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenSep, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a", " ", "f()", ")"}},
+		"(a:b)":    {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenColon, TokenLiteral, TokenRParen}, wantValues: []string{"(", "a", ":", "b", ")"}},
+		`(a "b")`:  {wantTypes: []TokenType{TokenLParen, TokenLiteral, TokenSep, TokenQuoted, TokenRParen}, wantValues: []string{"(", "a", " ", `"b"`, ")"}},
+		"-(a b)":   {wantTypes: []TokenType{TokenMinus, TokenLParen, TokenLiteral, TokenSep, TokenLiteral, TokenRParen}, wantValues: []string{"-", "(", "a", " ", "b", ")"}},
 		"/":        {wantTypes: []TokenType{TokenPattern}, wantValues: []string{""}},
 		"//":       {wantTypes: []TokenType{TokenPattern}, wantValues: []string{""}},
 		"///":      {wantTypes: []TokenType{TokenPattern, TokenPattern}, wantValues: []string{"", ""}},
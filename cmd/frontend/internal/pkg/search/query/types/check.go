@@ -49,7 +49,10 @@ func (c *Config) Check(query *syntax.Query) (*Query, error) {
 		if err != nil {
 			return nil, err
 		}
-		if fieldType.Singular && len(checkedQuery.Fields[field]) >= 1 {
+		// Singular fields may be used once per alternative in queries with
+		// boolean operators (e.g., "case:yes a or case:no b"). Each
+		// alternative is checked separately as a flat query.
+		if fieldType.Singular && query.IsFlat() && len(checkedQuery.Fields[field]) >= 1 {
 			return nil, &TypeError{Pos: expr.Pos, Err: fmt.Errorf("field %q may not be used more than once", field)}
 		}
 		checkedQuery.Fields[field] = append(checkedQuery.Fields[field], value)
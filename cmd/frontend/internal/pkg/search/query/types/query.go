@@ -36,6 +36,11 @@ func (v *Value) Not() bool {
 	return v.syntax.Not
 }
 
+// Syntax returns the query expression that the value was parsed from.
+func (v *Value) Syntax() *syntax.Expr {
+	return v.syntax
+}
+
 // Value returns the value as an interface{}.
 func (v *Value) Value() interface{} {
 	switch {
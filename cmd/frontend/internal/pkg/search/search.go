@@ -14,10 +14,10 @@ type PatternInfo struct {
 	IsRegExp        bool
 	IsWordMatch     bool
 	IsCaseSensitive bool
+	IsMultiline     bool
+	IsStructural    bool
 	FileMatchLimit  int32
 
-	// We do not support IsMultiline
-	//IsMultiline     bool
 	IncludePattern  string
 	IncludePatterns []string
 	ExcludePattern  string
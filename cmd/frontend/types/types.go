@@ -61,6 +61,7 @@ type User struct {
 	UpdatedAt   time.Time
 	SiteAdmin   bool
 	Tags        []string
+	DeletedAt   *time.Time // only set for soft-deleted users (see UsersListOptions.IncludeDeleted)
 }
 
 type Org struct {
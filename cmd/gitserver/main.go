@@ -26,6 +26,9 @@ const janitorInterval = 24 * time.Hour
 var (
 	reposDir          = env.Get("SRC_REPOS_DIR", "/data/repos", "Root dir containing repos.")
 	runRepoCleanup, _ = strconv.ParseBool(env.Get("SRC_RUN_REPO_CLEANUP", "", "Periodically remove inactive repositories."))
+	rebalance, _      = strconv.ParseBool(env.Get("SRC_GITSERVER_REBALANCE", "", "Transfer cloned repositories to other gitservers when the set of gitservers changes, instead of recloning them."))
+	selfAddr          = env.Get("SRC_GITSERVER_ADDR", "", "The address of this gitserver in SRC_GIT_SERVERS, used when rebalancing. If empty, it is found by the hostname and IP addresses of this host.")
+	wantPctFree       = env.Get("SRC_REPOS_DESIRED_PERCENT_FREE", "10", "Target percentage of free space on the disk of SRC_REPOS_DIR. The least recently accessed repositories are removed when there is less free space. 0 disables this.")
 )
 
 func main() {
@@ -40,9 +43,23 @@ func main() {
 		log.Fatalf("failed to create SRC_REPOS_DIR: %s", err)
 	}
 
+	desiredPercentFree, err := strconv.Atoi(wantPctFree)
+	if err != nil || desiredPercentFree < 0 || desiredPercentFree > 100 {
+		log.Fatalf("git-server: SRC_REPOS_DESIRED_PERCENT_FREE must be a percentage between 0 and 100, got %q", wantPctFree)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("failed to get hostname: %s", err)
+	}
+
 	gitserver := server.Server{
 		ReposDir:                reposDir,
 		DeleteStaleRepositories: runRepoCleanup,
+		Rebalance:               rebalance,
+		Addr:                    selfAddr,
+		Hostname:                hostname,
+		DesiredPercentFree:      desiredPercentFree,
 	}
 	gitserver.RegisterMetrics()
 
@@ -54,6 +71,8 @@ func main() {
 		os.Setenv("TMP_DIR", tmpDir)
 	}
 
+	gitserver.WatchTrustedSigningKeys()
+
 	// Create Handler now since it also initializes state
 	handler := nethttp.Middleware(opentracing.GlobalTracer(), gitserver.Handler())
 
@@ -66,6 +85,14 @@ func main() {
 		}
 	}()
 
+	if rebalance {
+		go gitserver.RunRebalancer()
+	}
+
+	if desiredPercentFree > 0 {
+		go gitserver.RunDiskPressureMonitor()
+	}
+
 	port := "3178"
 	host := ""
 	if env.InsecureDev {
@@ -3,7 +3,6 @@ package server
 import (
 	"context"
 	"io/ioutil"
-	"math/rand"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -30,7 +29,6 @@ func init() {
 // inactiveRepoTTL is the amount of time a repository will remain on a
 // gitserver without being updated before it is removed.
 const inactiveRepoTTL = time.Hour * 24 * 20
-const repoTTL = time.Hour * 24 * 45
 
 var reposRemoved = prometheus.NewCounter(prometheus.CounterOpts{
 	Namespace: "src",
@@ -42,7 +40,7 @@ var reposRecloned = prometheus.NewCounter(prometheus.CounterOpts{
 	Namespace: "src",
 	Subsystem: "gitserver",
 	Name:      "repos_recloned",
-	Help:      "number of repos removed and recloned due to age",
+	Help:      "number of repos removed and recloned due to corruption",
 })
 
 // cleanupRepos walks the repos directory and performs maintenance tasks:
@@ -50,11 +48,16 @@ var reposRecloned = prometheus.NewCounter(prometheus.CounterOpts{
 // 1. Remove corrupt repos.
 // 2. Remove stale lock files.
 // 3. Remove inactive repos on sourcegraph.com
-// 4. Reclone repos after a while. (simulate git gc)
+// 4. Run git maintenance (see maintainRepo), recloning corrupt repos.
+// 5. Record the disk usage of repos.
 func (s *Server) cleanupRepos() {
 	bCtx, bCancel := s.serverContext()
 	defer bCancel()
 
+	// sizes replaces the cached repo sizes once all repos are walked, so
+	// that repos which no longer exist are dropped.
+	sizes := make(map[api.RepoName]*protocol.RepoSize)
+
 	maybeRemoveCorrupt := func(gitDir string) (done bool, err error) {
 		// We treat repositories missing HEAD to be corrupt. Both our cloning
 		// and fetching ensure there is a HEAD file.
@@ -72,18 +75,18 @@ func (s *Server) cleanupRepos() {
 	}
 
 	maybeRemoveInactive := func(gitDir string) (done bool, err error) {
-		// We rewrite the HEAD file whenever we update a repo, and repos are
-		// updated in response to user traffic. Check to see the last time
-		// HEAD was rewritten to determine whether to consider this repo
-		// inactive. Note: This is only accurate for installations which set
+		// We record when a repo is accessed, and we rewrite the HEAD file
+		// whenever we update a repo. Check the more recent of the two to
+		// determine whether to consider this repo inactive. Note: updates
+		// are only triggered by user traffic for installations which set
 		// disableAutoGitUpdates=true. This is true for sourcegraph.com and
-		// maybeRemoveInactive should only be run for sourcegraph.com
-		head, err := os.Stat(filepath.Join(gitDir, "HEAD"))
+		// maybeRemoveInactive should only be run for sourcegraph.com. Other
+		// installations rely on RunDiskPressureMonitor instead.
+		lastAccessed, err := repoLastAccessed(gitDir)
 		if err != nil {
 			return false, err
 		}
-		lastUpdated := head.ModTime()
-		if time.Since(lastUpdated) <= inactiveRepoTTL {
+		if time.Since(lastAccessed) <= inactiveRepoTTL {
 			return false, nil
 		}
 
@@ -99,37 +102,49 @@ func (s *Server) cleanupRepos() {
 		return false, setGitAttributes(gitDir)
 	}
 
-	maybeReclone := func(gitDir string) (done bool, err error) {
-		recloneTime, err := getRecloneTime(gitDir)
-		if err != nil {
-			return false, err
-		}
-
-		// Add a jitter to spread out recloning of repos cloned at the same
-		// time.
-		if time.Since(recloneTime) <= repoTTL+randDuration(repoTTL/4) {
-			return false, nil
-		}
-
+	maintainOrReclone := func(gitDir string) (done bool, err error) {
 		ctx, cancel := context.WithTimeout(bCtx, longGitCommandTimeout)
 		defer cancel()
 
 		// name is the relative path to ReposDir, but without the .git suffix.
 		repo := protocol.NormalizeRepo(api.RepoName(strings.TrimPrefix(filepath.Dir(gitDir), s.ReposDir+"/")))
-		log15.Info("recloning expired repo", "repo", repo)
+
+		err = s.maintainRepoLocked(ctx, repo, gitDir)
+		if errors.Cause(err) != errRepoCorrupt {
+			return false, err
+		}
+		log15.Info("recloning corrupt repo", "repo", repo, "error", err)
 
 		remoteURL, err := repoRemoteURL(ctx, gitDir)
 		if err != nil {
 			return false, errors.Wrap(err, "failed to get remote URL")
 		}
 
-		if _, err := s.cloneRepo(ctx, repo, remoteURL, &cloneOptions{Block: true, Overwrite: true}); err != nil {
+		// Reclone shallow and partial clones the same way, and keep pushing
+		// to the same push mirror.
+		cloneOpts, err := getCloneOptions(gitDir)
+		if err != nil {
+			return false, err
+		}
+
+		if _, err := s.cloneRepo(ctx, repo, remoteURL, &cloneOptions{Block: true, Overwrite: true, Options: cloneOpts, PushMirrorURL: getPushMirrorURL(gitDir)}); err != nil {
 			return true, err
 		}
 		reposRecloned.Inc()
 		return true, nil
 	}
 
+	recordSize := func(gitDir string) (done bool, err error) {
+		size, err := computeRepoSize(gitDir)
+		if err != nil {
+			return false, err
+		}
+		// name is the relative path to ReposDir, but without the .git suffix.
+		repo := protocol.NormalizeRepo(api.RepoName(strings.TrimPrefix(filepath.Dir(gitDir), s.ReposDir+"/")))
+		sizes[repo] = size
+		return false, nil
+	}
+
 	removeStaleLocks := func(gitDir string) (done bool, err error) {
 		// if removing a lock fails, we still want to try the other locks.
 		var multi error
@@ -188,10 +203,12 @@ func (s *Server) cleanupRepos() {
 		cleanups = append(cleanups, cleanupFn{"maybe remove inactive", maybeRemoveInactive})
 	}
 	// Old git clones accumulate loose git objects that waste space and
-	// slow down git operations. Periodically do a fresh clone to avoid
-	// these problems. git gc is slow and resource intensive. It is
-	// cheaper and faster to just reclone the repository.
-	cleanups = append(cleanups, cleanupFn{"maybe reclone", maybeReclone})
+	// slow down git operations. Repack them when needed, rather than
+	// recloning them, which loads the code host and leaves the repository
+	// unavailable while it is cloned.
+	cleanups = append(cleanups, cleanupFn{"maintain or reclone", maintainOrReclone})
+	// Record the size after maintenance, which may shrink the repo.
+	cleanups = append(cleanups, cleanupFn{"record size", recordSize})
 
 	filepath.Walk(s.ReposDir, func(gitDir string, fi os.FileInfo, fileErr error) error {
 		if fileErr != nil {
@@ -221,6 +238,8 @@ func (s *Server) cleanupRepos() {
 		}
 		return filepath.SkipDir
 	})
+
+	s.setRepoSizes(sizes)
 }
 
 // removeRepoDirectory atomically removes a directory from s.ReposDir.
@@ -410,11 +429,6 @@ func getRecloneTime(gitDir string) (time.Time, error) {
 	return time.Unix(sec, 0), nil
 }
 
-// randDuration returns a psuedo-random duration between [0, d)
-func randDuration(d time.Duration) time.Duration {
-	return time.Duration(rand.Int63n(int64(d)))
-}
-
 // wrapCmdError will wrap errors for cmd to include the arguments. If the error
 // is an exec.ExitError and cmd was invoked with Output(), it will also include
 // the captured stderr.
@@ -60,27 +60,30 @@ func TestCleanupInactive(t *testing.T) {
 	}
 }
 
-func TestCleanupExpired(t *testing.T) {
+func TestCleanupCorrupt(t *testing.T) {
 	root, err := ioutil.TempDir("", "gitserver-test-")
 	if err != nil {
 		t.Fatal(err)
 	}
 	defer os.RemoveAll(root)
 
-	repoA := path.Join(root, testRepoA, ".git")
-	cmd := exec.Command("git", "--bare", "init", repoA)
-	if err := cmd.Run(); err != nil {
-		t.Fatal(err)
+	remote := path.Join(root, testRepoC)
+	for _, args := range [][]string{
+		{"init", remote},
+		{"-C", remote, "-c", "user.name=a", "-c", "user.email=a@a.com", "commit", "--allow-empty", "-m", "hello"},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %s: %s", strings.Join(args, " "), err, out)
+		}
 	}
+
+	// repoA and repoB are clones of remote with their objects in a pack.
+	repoA := path.Join(root, testRepoA, ".git")
 	repoB := path.Join(root, testRepoB, ".git")
-	cmd = exec.Command("git", "--bare", "init", repoB)
-	if err := cmd.Run(); err != nil {
-		t.Fatal(err)
-	}
-	remote := path.Join(root, testRepoC, ".git")
-	cmd = exec.Command("git", "--bare", "init", remote)
-	if err := cmd.Run(); err != nil {
-		t.Fatal(err)
+	for _, dir := range []string{repoA, repoB} {
+		if out, err := exec.Command("git", "clone", "--mirror", "--no-local", remote, dir).CombinedOutput(); err != nil {
+			t.Fatalf("git clone failed: %s: %s", err, out)
+		}
 	}
 
 	origRepoRemoteURL := repoRemoteURL
@@ -89,11 +92,24 @@ func TestCleanupExpired(t *testing.T) {
 	}
 	defer func() { repoRemoteURL = origRepoRemoteURL }()
 
+	// repoA was cloned long ago, but is not corrupt.
+	cmd := exec.Command("git", "config", "--add", "sourcegraph.recloneTimestamp", strconv.FormatInt(time.Now().Add(-365*24*time.Hour).Unix(), 10))
+	cmd.Dir = repoA
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
 	atime, err := os.Stat(filepath.Join(repoA, "HEAD"))
 	if err != nil {
 		t.Fatal(err)
 	}
-	cmd = exec.Command("git", "config", "--add", "sourcegraph.recloneTimestamp", strconv.FormatInt(time.Now().Add(-(2*repoTTL)).Unix(), 10))
+
+	// Corrupt the objects of repoB.
+	packs, err := filepath.Glob(filepath.Join(repoB, "objects", "pack", "*.pack"))
+	if err != nil || len(packs) != 1 {
+		t.Fatalf("expected a single pack in repoB, got %v (error: %v)", packs, err)
+	}
+	corruptPack(t, packs[0])
+	cmd = exec.Command("git", "config", "sourcegraph.corruptMarker", "true")
 	cmd.Dir = repoB
 	if err := cmd.Run(); err != nil {
 		t.Fatal(err)
@@ -112,15 +128,41 @@ func TestCleanupExpired(t *testing.T) {
 		// repoA should not have been recloned.
 		t.Error("expected repoA to not be modified")
 	}
-	fi, err = os.Stat(repoB)
+	if last, err := getLastMaintenance(repoA); err != nil {
+		t.Fatal(err)
+	} else if last.IsZero() {
+		t.Error("expected maintenance to run on repoA")
+	}
+
+	// Expect repoB to be recloned, which drops our marker from its config.
+	cmd = exec.Command("git", "config", "--get", "sourcegraph.corruptMarker")
+	cmd.Dir = repoB
+	if out, err := cmd.Output(); err == nil {
+		t.Errorf("expected repoB to be recloned during clean up, but config still has marker %q", out)
+	}
+	if _, err := os.Stat(filepath.Join(repoB, "HEAD")); err != nil {
+		t.Errorf("expected repoB to exist after being recloned: %s", err)
+	}
+}
+
+// corruptPack overwrites most of the contents of the pack at path with zeros.
+func corruptPack(t *testing.T, path string) {
+	t.Helper()
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
 	if err != nil {
-		// repoB should still exist after being recloned.
 		t.Fatal(err)
 	}
-	// Expect the repo to be recloned hand have a recent mod time.
-	ti := time.Now().Add(-repoTTL)
-	if fi.ModTime().Before(ti) {
-		t.Error("expected repoB to be recloned during clean up")
+	defer f.Close()
+	// Keep the header and the trailing checksum.
+	if _, err := f.WriteAt(make([]byte, fi.Size()-32), 12); err != nil {
+		t.Fatal(err)
 	}
 }
 
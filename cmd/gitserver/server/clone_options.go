@@ -0,0 +1,253 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+)
+
+// Shallow and partial clones
+//
+// By default gitserver clones all history and all file contents of a
+// repository. Very large repositories can be cloned with less (see
+// protocol.CloneOptions): a shallow clone omits older commits and a partial
+// clone omits file contents (blobs).
+//
+// Omitted blobs are fetched by git itself when a command needs them, since git
+// records the repository's remote as a "promisor" remote of a partial clone.
+// Omitted commits are fetched by ensureRevision when a command needs a commit
+// that the clone doesn't have.
+//
+// The options a clone was made with are stored in its git config, so that
+// updates and reclones of the repository respect them.
+
+// cloneArgs returns the arguments to git clone for a clone with the options
+// opts.
+func cloneArgs(opts *protocol.CloneOptions) []string {
+	if opts == nil {
+		return nil
+	}
+	var args []string
+	if opts.Depth > 0 {
+		// --depth implies --single-branch, but we want all branches.
+		args = append(args, "--depth", strconv.Itoa(opts.Depth), "--no-single-branch")
+	}
+	if filter := blobFilter(opts); filter != "" {
+		args = append(args, "--filter="+filter)
+	}
+	return args
+}
+
+// blobFilter returns the object filter for a partial clone with the options
+// opts. It returns "" if the clone has all blobs.
+func blobFilter(opts *protocol.CloneOptions) string {
+	switch {
+	case opts == nil:
+		return ""
+	case len(opts.SparsePaths) > 0:
+		// The blobs in the sparse paths are fetched by prefetchSparsePaths.
+		return "blob:none"
+	case opts.BlobSizeLimit != "":
+		return "blob:limit=" + opts.BlobSizeLimit
+	default:
+		return ""
+	}
+}
+
+// setCloneOptions stores the options that the clone in gitDir was made with.
+func setCloneOptions(gitDir string, opts *protocol.CloneOptions) error {
+	if opts == nil {
+		return nil
+	}
+	var args [][]string
+	if opts.Depth > 0 {
+		args = append(args, []string{"sourcegraph.cloneDepth", strconv.Itoa(opts.Depth)})
+	}
+	if opts.BlobSizeLimit != "" {
+		args = append(args, []string{"sourcegraph.blobSizeLimit", opts.BlobSizeLimit})
+	}
+	for _, p := range opts.SparsePaths {
+		args = append(args, []string{"sourcegraph.sparsePath", p})
+	}
+	for _, a := range args {
+		cmd := exec.Command("git", "config", "--add", a[0], a[1])
+		cmd.Dir = gitDir
+		if _, err := cmd.Output(); err != nil {
+			return errors.Wrap(wrapCmdError(cmd, err), "failed to store clone options")
+		}
+	}
+	return nil
+}
+
+// getCloneOptions returns the options that the clone in gitDir was made with.
+// It returns nil for a full clone.
+func getCloneOptions(gitDir string) (*protocol.CloneOptions, error) {
+	cmd := exec.Command("git", "config", "--get-regexp", `^sourcegraph\.(clonedepth|blobsizelimit|sparsepath)$`)
+	cmd.Dir = gitDir
+	out, err := cmd.Output()
+	if err != nil {
+		// Exit code 1 means no key is set.
+		if ee, ok := err.(*exec.ExitError); ok && ee.Sys().(syscall.WaitStatus).ExitStatus() == 1 {
+			return nil, nil
+		}
+		return nil, errors.Wrap(wrapCmdError(cmd, err), "failed to determine clone options")
+	}
+
+	var opts protocol.CloneOptions
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		// git config prints the keys in lowercase.
+		parts := strings.SplitN(scanner.Text(), " ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch parts[0] {
+		case "sourcegraph.clonedepth":
+			opts.Depth, _ = strconv.Atoi(parts[1])
+		case "sourcegraph.blobsizelimit":
+			opts.BlobSizeLimit = parts[1]
+		case "sourcegraph.sparsepath":
+			opts.SparsePaths = append(opts.SparsePaths, parts[1])
+		}
+	}
+	return &opts, scanner.Err()
+}
+
+// repoCloneMode returns how the repository in dir (or `${dir}/.git`) is
+// cloned.
+var repoCloneMode = func(dir string) protocol.CloneMode {
+	switch shallow, partial := repoIsShallow(dir), repoIsPartial(dir); {
+	case shallow && partial:
+		return protocol.CloneModeShallowPartial
+	case shallow:
+		return protocol.CloneModeShallow
+	case partial:
+		return protocol.CloneModePartial
+	default:
+		return protocol.CloneModeFull
+	}
+}
+
+// repoIsShallow reports whether the repository in dir (or `${dir}/.git`) is a
+// shallow clone.
+func repoIsShallow(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, "shallow")); err == nil {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(dir, ".git", "shallow"))
+	return err == nil
+}
+
+// repoIsPartial reports whether the repository in dir (or `${dir}/.git`) is a
+// partial clone. git marks the packs it fetched from a promisor remote with a
+// .promisor file.
+func repoIsPartial(dir string) bool {
+	for _, pattern := range []string{
+		filepath.Join(dir, "objects", "pack", "*.promisor"),
+		filepath.Join(dir, ".git", "objects", "pack", "*.promisor"),
+	} {
+		if matches, _ := filepath.Glob(pattern); len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// allowLazyFetch prepares cmd, a git command that runs in the repository in
+// dir, to fetch the blobs that a partial clone omitted without hanging on a
+// prompt for credentials.
+func allowLazyFetch(cmd *exec.Cmd, dir string) {
+	if !repoIsPartial(dir) {
+		return
+	}
+	cmd.Env = append(os.Environ(),
+		"GIT_ASKPASS=true",
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_SSH_COMMAND=ssh -o BatchMode=yes -o ConnectTimeout=30",
+	)
+	cmd.Args = append([]string{cmd.Args[0], "-c", "credential.helper="}, cmd.Args[1:]...)
+}
+
+// fetchCommit fetches a commit that a shallow clone in dir does not have
+// from url.
+func (s *Server) fetchCommit(ctx context.Context, dir, url string, commit string) error {
+	opts, err := getCloneOptions(dir)
+	if err != nil {
+		return err
+	}
+	depth := 1
+	if opts != nil && opts.Depth > 0 {
+		depth = opts.Depth
+	}
+	args := []string{"fetch", "--no-tags", "--depth", strconv.Itoa(depth)}
+	if filter := blobFilter(opts); filter != "" {
+		args = append(args, "--filter="+filter)
+	}
+	cmd := exec.CommandContext(ctx, "git", append(args, url, commit)...)
+	cmd.Dir = dir
+	if output, err := s.runWithRemoteOpts(ctx, cmd, nil); err != nil {
+		return errors.Wrapf(err, "failed to fetch commit %s. Output: %s", commit, newURLRedactor(url).redact(string(output)))
+	}
+	return nil
+}
+
+// prefetchSparsePaths fetches the blobs of the files in paths as of HEAD that
+// the partial clone in gitDir does not have from url.
+func (s *Server) prefetchSparsePaths(ctx context.Context, gitDir, url string, paths []string) error {
+	// Listing the objects does not fetch the missing ones.
+	cmd := exec.CommandContext(ctx, "git", "rev-list", "--objects", "--missing=print", "--no-walk", "HEAD")
+	cmd.Dir = gitDir
+	out, err := cmd.Output()
+	if err != nil {
+		return errors.Wrap(wrapCmdError(cmd, err), "failed to list missing objects")
+	}
+	missing := map[string]bool{}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(line, "?") {
+			missing[line[1:]] = true
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	cmd = exec.CommandContext(ctx, "git", append([]string{"ls-tree", "-r", "-z", "HEAD", "--"}, paths...)...)
+	cmd.Dir = gitDir
+	out, err = cmd.Output()
+	if err != nil {
+		return errors.Wrap(wrapCmdError(cmd, err), "failed to list sparse paths")
+	}
+	var oids []string
+	for _, entry := range strings.Split(string(out), "\x00") {
+		// Each entry is "<mode> SP <type> SP <object> TAB <file>".
+		fields := strings.Fields(strings.SplitN(entry, "\t", 2)[0])
+		if len(fields) == 3 && fields[1] == "blob" && missing[fields[2]] {
+			oids = append(oids, fields[2])
+		}
+	}
+
+	const batchSize = 1000
+	for len(oids) > 0 {
+		n := batchSize
+		if len(oids) < n {
+			n = len(oids)
+		}
+		args := append([]string{"-c", "fetch.negotiationAlgorithm=noop", "fetch", "--no-tags", "--filter=blob:none", url}, oids[:n]...)
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = gitDir
+		if output, err := s.runWithRemoteOpts(ctx, cmd, nil); err != nil {
+			return errors.Wrapf(err, "failed to fetch sparse paths. Output: %s", newURLRedactor(url).redact(string(output)))
+		}
+		oids = oids[n:]
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/mutablelimiter"
+)
+
+func TestCloneRepo_cloneOptions(t *testing.T) {
+	remote, cleanup1 := tmpDir(t)
+	defer cleanup1()
+
+	dir := remote
+	cmd := func(name string, arg ...string) string {
+		t.Helper()
+		c := exec.Command(name, arg...)
+		c.Dir = dir
+		c.Env = []string{
+			"GIT_COMMITTER_NAME=a",
+			"GIT_COMMITTER_EMAIL=a@a.com",
+			"GIT_AUTHOR_NAME=a",
+			"GIT_AUTHOR_EMAIL=a@a.com",
+		}
+		b, err := c.Output()
+		if err != nil {
+			t.Fatalf("%s %s failed: %s", name, strings.Join(arg, " "), err)
+		}
+		return string(b)
+	}
+
+	// Setup a repo with two commits and files inside and outside of the
+	// sparse path. Partial clones need a remote that supports filters.
+	cmd("git", "init", ".")
+	cmd("git", "config", "uploadpack.allowFilter", "true")
+	cmd("git", "config", "uploadpack.allowAnySHA1InWant", "true")
+	cmd("sh", "-c", "mkdir docs && echo hello > docs/hello.txt && echo world > world.txt")
+	cmd("git", "add", ".")
+	cmd("git", "commit", "-m", "first")
+	cmd("sh", "-c", "echo hello again > docs/hello.txt && echo world again > world.txt")
+	cmd("git", "commit", "-am", "second")
+	firstCommit := strings.TrimSpace(cmd("git", "rev-parse", "HEAD^"))
+	sparseBlob := strings.TrimSpace(cmd("git", "rev-parse", "HEAD:docs/hello.txt"))
+	otherBlob := strings.TrimSpace(cmd("git", "rev-parse", "HEAD:world.txt"))
+
+	reposDir, cleanup2 := tmpDir(t)
+	defer cleanup2()
+	s := &Server{
+		ReposDir:         reposDir,
+		ctx:              context.Background(),
+		locker:           &RepositoryLocker{},
+		cloneLimiter:     mutablelimiter.New(1),
+		cloneableLimiter: mutablelimiter.New(1),
+	}
+
+	const repo = api.RepoName("example.com/foo/bar")
+	want := &protocol.CloneOptions{Depth: 1, SparsePaths: []string{"docs"}}
+	if _, err := s.cloneRepo(context.Background(), repo, "file://"+remote, &cloneOptions{Block: true, Options: want}); err != nil {
+		t.Fatal(err)
+	}
+
+	dir = filepath.Join(reposDir, string(repo), ".git")
+	if got := repoCloneMode(dir); got != protocol.CloneModeShallowPartial {
+		t.Errorf("got clone mode %q, want %q", got, protocol.CloneModeShallowPartial)
+	}
+	if got, err := getCloneOptions(dir); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, want) {
+		t.Errorf("got clone options %+v, want %+v", got, want)
+	}
+
+	// The blobs in the sparse paths are fetched with the clone; the others
+	// are not.
+	missing := cmd("git", "rev-list", "--objects", "--missing=print", "--no-walk", "HEAD")
+	if strings.Contains(missing, "?"+sparseBlob) {
+		t.Errorf("blob %s in sparse path is missing", sparseBlob)
+	}
+	if !strings.Contains(missing, "?"+otherBlob) {
+		t.Errorf("blob %s outside of sparse path is not missing", otherBlob)
+	}
+
+	// Commits omitted by a shallow clone are fetched on demand. (A partial
+	// clone would fetch them lazily, so this clone is only shallow.)
+	const shallowRepo = api.RepoName("example.com/foo/shallow")
+	if _, err := s.cloneRepo(context.Background(), shallowRepo, "file://"+remote, &cloneOptions{Block: true, Options: &protocol.CloneOptions{Depth: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	dir = filepath.Join(reposDir, string(shallowRepo), ".git")
+	if got := repoCloneMode(dir); got != protocol.CloneModeShallow {
+		t.Errorf("got clone mode %q, want %q", got, protocol.CloneModeShallow)
+	}
+	if err := exec.Command("git", "-C", dir, "cat-file", "-e", firstCommit).Run(); err == nil {
+		t.Fatalf("commit %s is not omitted by the shallow clone", firstCommit)
+	}
+	if err := s.fetchCommit(context.Background(), dir, "file://"+remote, firstCommit); err != nil {
+		t.Fatal(err)
+	}
+	if err := exec.Command("git", "-C", dir, "cat-file", "-e", firstCommit).Run(); err != nil {
+		t.Errorf("commit %s is missing after fetchCommit: %s", firstCommit, err)
+	}
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Disk pressure
+//
+// A gitserver clones every repository it is asked about, so without limits it
+// eventually fills its disk and clones start to fail. When the free space on
+// the repos mount drops below DesiredPercentFree, the least recently accessed
+// repositories are evicted until enough space is free again. An evicted
+// repository is cloned again the next time it is accessed.
+//
+// A repository is accessed when a git command runs on it (see handleExec),
+// which is recorded in the sg_lastaccess file in its GIT_DIR. Repositories
+// which were never accessed are treated as last accessed when HEAD was last
+// written, which happens when a repository is cloned or updated.
+
+// diskPressureInterval is how often the free space of the repos mount is
+// checked.
+const diskPressureInterval = time.Minute
+
+// lastAccessResolution is how stale sg_lastaccess may get before an access
+// updates it. It avoids writing to disk on every git command.
+const lastAccessResolution = time.Hour
+
+var reposEvicted = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "repos_evicted",
+	Help:      "number of least recently accessed repos removed because the disk was almost full",
+})
+var evictedBytes = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "evicted_bytes",
+	Help:      "number of bytes of repos removed because the disk was almost full",
+})
+
+func init() {
+	prometheus.MustRegister(reposEvicted)
+	prometheus.MustRegister(evictedBytes)
+}
+
+// diskFree returns the number of bytes available to us and the total size of
+// the file system containing path. It is mocked by tests.
+var diskFree = func(path string) (free, total uint64, err error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), stat.Blocks * uint64(stat.Bsize), nil
+}
+
+// markRepoAccessed records that the repository in dir was accessed.
+func markRepoAccessed(dir string) error {
+	p := filepath.Join(dir, ".git", "sg_lastaccess")
+	fi, err := os.Stat(p)
+	if os.IsNotExist(err) {
+		return ioutil.WriteFile(p, nil, 0600)
+	}
+	if err != nil {
+		return err
+	}
+	if time.Since(fi.ModTime()) < lastAccessResolution {
+		return nil
+	}
+	now := time.Now()
+	return os.Chtimes(p, now, now)
+}
+
+// repoLastAccessed returns the last time the repository with GIT_DIR gitDir
+// was accessed. If it was never accessed, it returns when HEAD was last
+// written instead. HEAD is rewritten on every update, so it is only used as a
+// fallback; otherwise regularly updated repositories would never be evicted.
+func repoLastAccessed(gitDir string) (time.Time, error) {
+	fi, err := os.Stat(filepath.Join(gitDir, "sg_lastaccess"))
+	if os.IsNotExist(err) {
+		fi, err = os.Stat(filepath.Join(gitDir, "HEAD"))
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fi.ModTime(), nil
+}
+
+// RunDiskPressureMonitor periodically evicts the least recently accessed
+// repositories while the repos mount has less than DesiredPercentFree free
+// space. It returns when the server is stopped.
+func (s *Server) RunDiskPressureMonitor() {
+	ctx, cancel := s.serverContext()
+	defer cancel()
+
+	for {
+		if err := s.freeUpSpace(ctx); err != nil {
+			log15.Error("failed to free up disk space", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(diskPressureInterval):
+		}
+	}
+}
+
+// freeUpSpace evicts the least recently accessed repositories until the repos
+// mount has DesiredPercentFree free space.
+func (s *Server) freeUpSpace(ctx context.Context) error {
+	if s.DesiredPercentFree <= 0 {
+		return nil
+	}
+	free, total, err := diskFree(s.ReposDir)
+	if err != nil {
+		return err
+	}
+	desired := total / 100 * uint64(s.DesiredPercentFree)
+	if free >= desired {
+		return nil
+	}
+	want := int64(desired - free)
+
+	repos, err := s.clonedRepos(ctx)
+	if err != nil {
+		return err
+	}
+	type candidate struct {
+		repo         api.RepoName
+		lastAccessed time.Time
+	}
+	candidates := make([]candidate, 0, len(repos))
+	for _, repo := range repos {
+		lastAccessed, err := repoLastAccessed(filepath.Join(s.ReposDir, string(repo), ".git"))
+		if err != nil {
+			// The repo was removed or is corrupt. The janitor deals with it.
+			continue
+		}
+		candidates = append(candidates, candidate{repo: repo, lastAccessed: lastAccessed})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].lastAccessed.Before(candidates[j].lastAccessed)
+	})
+
+	log15.Warn("disk space is low, evicting least recently accessed repos", "free", free, "desired", desired)
+
+	var freed int64
+	for _, c := range candidates {
+		if freed >= want {
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		n, err := s.evictRepo(c.repo)
+		if err != nil {
+			log15.Error("failed to evict repo", "repo", c.repo, "error", err)
+			continue
+		}
+		if n < 0 {
+			// The repo is being cloned or transferred.
+			continue
+		}
+		log15.Info("evicted repo due to disk pressure", "repo", c.repo, "lastAccessed", c.lastAccessed, "bytes", n)
+		freed += n
+	}
+	if freed < want {
+		log15.Warn("failed to free up enough disk space", "freed", freed, "wanted", want)
+	}
+	return nil
+}
+
+// evictRepo removes the clone of repo. It returns the number of bytes the clone
+// used, or -1 if it is locked.
+func (s *Server) evictRepo(repo api.RepoName) (int64, error) {
+	dir := filepath.Join(s.ReposDir, string(protocol.NormalizeRepo(repo)))
+	lock, ok := s.locker.TryAcquire(dir, "evicting due to disk pressure")
+	if !ok {
+		return -1, nil
+	}
+	defer lock.Release()
+
+	gitDir := filepath.Join(dir, ".git")
+	size, err := s.repoSize(repo, gitDir)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.removeRepoDirectory(gitDir); err != nil {
+		return 0, err
+	}
+	s.forgetRepoSize(repo)
+
+	reposEvicted.Inc()
+	evictedBytes.Add(float64(size.TotalBytes()))
+	return size.TotalBytes(), nil
+}
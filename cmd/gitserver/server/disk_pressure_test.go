@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+)
+
+func TestFreeUpSpace(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+
+	// a was updated long ago, but accessed recently. e was updated just now,
+	// but last accessed long ago. The others were never accessed, so they are
+	// ordered by when they were last updated.
+	for name, age := range map[string]time.Duration{
+		"example.com/a": 72 * time.Hour,
+		"example.com/b": 48 * time.Hour,
+		"example.com/c": 24 * time.Hour,
+		"example.com/d": 12 * time.Hour,
+		"example.com/e": 0,
+	} {
+		gitDir := filepath.Join(root, name, ".git")
+		if out, err := exec.Command("git", "init", "--bare", gitDir).CombinedOutput(); err != nil {
+			t.Fatalf("git init failed: %s: %s", err, out)
+		}
+		mtime := time.Now().Add(-age)
+		if err := os.Chtimes(filepath.Join(gitDir, "HEAD"), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := markRepoAccessed(filepath.Join(root, "example.com/a")); err != nil {
+		t.Fatal(err)
+	}
+	accessed := time.Now().Add(-96 * time.Hour)
+	accessFile := filepath.Join(root, "example.com/e", ".git", "sg_lastaccess")
+	if err := ioutil.WriteFile(accessFile, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(accessFile, accessed, accessed); err != nil {
+		t.Fatal(err)
+	}
+
+	origDiskFree := diskFree
+	defer func() { diskFree = origDiskFree }()
+	origComputeRepoSize := computeRepoSize
+	defer func() { computeRepoSize = origComputeRepoSize }()
+	computeRepoSize = func(string) (*protocol.RepoSize, error) {
+		return &protocol.RepoSize{PackBytes: 10}, nil
+	}
+
+	s := &Server{ReposDir: root, DesiredPercentFree: 10}
+	s.Handler() // Handler as a side-effect sets up Server
+
+	// There is enough free space, so nothing is evicted.
+	diskFree = func(string) (uint64, uint64, error) { return 100, 1000, nil }
+	if err := s.freeUpSpace(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if repos, err := s.clonedRepos(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if len(repos) != 5 {
+		t.Fatalf("got %d repos after freeUpSpace with enough free space, want 5", len(repos))
+	}
+
+	// 15 bytes are missing, which needs evicting the two least recently
+	// accessed repos.
+	diskFree = func(string) (uint64, uint64, error) { return 85, 1000, nil }
+	if err := s.freeUpSpace(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{
+		"example.com/a": true,
+		"example.com/b": false,
+		"example.com/c": true,
+		"example.com/d": true,
+		"example.com/e": false,
+	} {
+		if got := repoCloned(filepath.Join(root, name)); got != want {
+			t.Errorf("%s: got cloned %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestMarkRepoAccessed(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+
+	dir := filepath.Join(root, "repo")
+	gitDir := filepath.Join(dir, ".git")
+	if out, err := exec.Command("git", "init", "--bare", gitDir).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %s: %s", err, out)
+	}
+	past := time.Now().Add(-2 * lastAccessResolution)
+	if err := os.Chtimes(filepath.Join(gitDir, "HEAD"), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without an access, the last access is the last update.
+	if got, err := repoLastAccessed(gitDir); err != nil {
+		t.Fatal(err)
+	} else if !got.Equal(past) {
+		t.Errorf("got last access %s, want %s", got, past)
+	}
+
+	if err := markRepoAccessed(dir); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := repoLastAccessed(gitDir); err != nil {
+		t.Fatal(err)
+	} else if time.Since(got) > time.Minute {
+		t.Errorf("got last access %s, want about now", got)
+	}
+
+	// A stale access time is updated, a recent one is left alone.
+	accessFile := filepath.Join(gitDir, "sg_lastaccess")
+	if err := os.Chtimes(accessFile, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if err := markRepoAccessed(dir); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(accessFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(fi.ModTime()) > time.Minute {
+		t.Errorf("expected a stale access time to be updated, got %s", fi.ModTime())
+	}
+	recent := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(accessFile, recent, recent); err != nil {
+		t.Fatal(err)
+	}
+	if err := markRepoAccessed(dir); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(accessFile); err != nil {
+		t.Fatal(err)
+	} else if !fi.ModTime().Equal(recent) {
+		t.Errorf("expected a recent access time to be left alone, got %s", fi.ModTime())
+	}
+}
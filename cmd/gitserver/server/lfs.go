@@ -0,0 +1,390 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/lfs"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Git LFS
+//
+// Git LFS stores an LFS pointer in the repository in place of the content of a
+// large file, and the content (the LFS object) on an LFS server, which usually
+// is the code host. When the gitLFS site configuration enables it, gitserver
+// fetches the LFS objects of the files on the default branch after it clones
+// or updates a repository (see fetchLFSObjects), and other LFS objects when
+// they are requested (see handleLFSObject). LFS objects are stored in the
+// lfs/objects directory of the repository like git-lfs does, so they are
+// removed along with the repository.
+//
+// LFS objects are fetched with the batch API of the LFS server at the clone URL
+// of the repository. Only HTTP(S) clone URLs are supported.
+
+// defaultLFSMaxObjectSize is the default for the maxObjectSize of the gitLFS
+// site configuration.
+const defaultLFSMaxObjectSize = 10 * 1024 * 1024
+
+// lfsBatchSize is the number of LFS objects requested from the LFS server at
+// once.
+const lfsBatchSize = 100
+
+// lfsHTTPClient is the HTTP client used to talk to LFS servers.
+var lfsHTTPClient = &http.Client{}
+
+// lfsSettings returns whether fetching LFS objects is enabled, and the maximum
+// size of the LFS objects to fetch.
+var lfsSettings = func() (enabled bool, maxObjectSize int64) {
+	c := conf.Get().GitLFS
+	if c == nil || !c.Enabled {
+		return false, 0
+	}
+	if c.MaxObjectSize == 0 {
+		return true, defaultLFSMaxObjectSize
+	}
+	return true, int64(c.MaxObjectSize)
+}
+
+// lfsObjectPath returns the path at which the LFS object with oid is stored in
+// the repository with GIT_DIR gitDir.
+func lfsObjectPath(gitDir, oid string) string {
+	return filepath.Join(gitDir, "lfs", "objects", oid[0:2], oid[2:4], oid)
+}
+
+// lfsEndpoint returns the URL of the LFS server for the repository with the
+// clone URL remoteURL.
+func lfsEndpoint(remoteURL string) (*url.URL, error) {
+	u, err := url.Parse(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("unsupported LFS server URL scheme %q", u.Scheme)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	if !strings.HasSuffix(u.Path, ".git") {
+		u.Path += ".git"
+	}
+	u.Path += "/info/lfs"
+	return u, nil
+}
+
+// fetchLFSObjects fetches the LFS objects of the files on the default branch
+// of the repository in gitDir that it does not have yet from remoteURL.
+func (s *Server) fetchLFSObjects(ctx context.Context, gitDir, remoteURL string) error {
+	enabled, maxObjectSize := lfsSettings()
+	if !enabled {
+		return nil
+	}
+
+	// Only look at the files that a partial clone has the contents of.
+	// Otherwise reading the pointers would fetch the missing contents one by
+	// one.
+	args := []string{"ls-tree", "-r", "-l", "-z", "HEAD"}
+	cloneOpts, err := getCloneOptions(gitDir)
+	if err != nil {
+		return err
+	}
+	if cloneOpts != nil && len(cloneOpts.SparsePaths) > 0 {
+		args = append(append(args, "--"), cloneOpts.SparsePaths...)
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = gitDir
+	out, err := cmd.Output()
+	if err != nil {
+		return errors.Wrap(wrapCmdError(cmd, err), "failed to list files")
+	}
+
+	// Each entry is "<mode> SP <type> SP <oid> SP+ <size> TAB <path>". Only
+	// small blobs can be pointers.
+	var candidates []string
+	for _, entry := range strings.Split(string(out), "\x00") {
+		tab := strings.IndexByte(entry, '\t')
+		if tab < 0 {
+			continue
+		}
+		fields := strings.Fields(entry[:tab])
+		if len(fields) != 4 || fields[1] != "blob" {
+			continue
+		}
+		if size, err := strconv.ParseInt(fields[3], 10, 64); err != nil || size > lfs.MaxPointerSize {
+			continue
+		}
+		candidates = append(candidates, fields[2])
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	pointers, err := readLFSPointers(ctx, gitDir, candidates)
+	if err != nil {
+		return err
+	}
+	var missing []lfs.Pointer
+	seen := map[string]bool{}
+	for _, p := range pointers {
+		if seen[p.OID] || p.Size > maxObjectSize {
+			continue
+		}
+		seen[p.OID] = true
+		if _, err := os.Stat(lfsObjectPath(gitDir, p.OID)); os.IsNotExist(err) {
+			missing = append(missing, p)
+		}
+	}
+
+	for len(missing) > 0 {
+		batch := missing
+		if len(batch) > lfsBatchSize {
+			batch = batch[:lfsBatchSize]
+		}
+		missing = missing[len(batch):]
+		if err := s.fetchLFSBatch(ctx, gitDir, remoteURL, batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readLFSPointers returns the LFS pointers among the blobs with oids in the
+// repository in gitDir.
+func readLFSPointers(ctx context.Context, gitDir string, oids []string) ([]lfs.Pointer, error) {
+	cmd := exec.CommandContext(ctx, "git", "cat-file", "--batch")
+	cmd.Dir = gitDir
+	cmd.Stdin = strings.NewReader(strings.Join(oids, "\n") + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrap(wrapCmdError(cmd, err), "failed to read blobs")
+	}
+
+	// Each blob is "<oid> SP <type> SP <size> LF <contents> LF".
+	var pointers []lfs.Pointer
+	r := bufio.NewReader(bytes.NewReader(out))
+	for {
+		header, err := r.ReadString('\n')
+		if err == io.EOF {
+			return pointers, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 3 {
+			// "<oid> missing"
+			continue
+		}
+		size, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, errors.Errorf("invalid cat-file output %q", header)
+		}
+		contents := make([]byte, size+1)
+		if _, err := io.ReadFull(r, contents); err != nil {
+			return nil, err
+		}
+		if p, ok := lfs.ParsePointer(contents[:size]); ok {
+			pointers = append(pointers, *p)
+		}
+	}
+}
+
+type lfsBatchRequest struct {
+	Operation string        `json:"operation"`
+	Transfers []string      `json:"transfers"`
+	Objects   []lfs.Pointer `json:"objects"`
+}
+
+type lfsBatchResponse struct {
+	Objects []struct {
+		lfs.Pointer
+		Actions struct {
+			Download *struct {
+				Href   string            `json:"href"`
+				Header map[string]string `json:"header"`
+			} `json:"download"`
+		} `json:"actions"`
+		Error *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"objects"`
+}
+
+// fetchLFSBatch fetches the LFS objects with pointers from the LFS server of
+// remoteURL into the repository in gitDir.
+func (s *Server) fetchLFSBatch(ctx context.Context, gitDir, remoteURL string, pointers []lfs.Pointer) error {
+	endpoint, err := lfsEndpoint(remoteURL)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(&lfsBatchRequest{Operation: "download", Transfers: []string{"basic"}, Objects: pointers})
+	if err != nil {
+		return err
+	}
+
+	// Send the credentials of the clone URL as basic auth.
+	user := endpoint.User
+	endpoint.User = nil
+	req, err := http.NewRequest("POST", endpoint.String()+"/objects/batch", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if user != nil {
+		password, _ := user.Password()
+		req.SetBasicAuth(user.Username(), password)
+	}
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+	resp, err := lfsHTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return errors.Wrap(err, "LFS batch request failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := readAtMost(resp.Body, 1024)
+		return errors.Errorf("LFS batch request failed with status %d: %s", resp.StatusCode, msg)
+	}
+	var batch lfsBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batch); err != nil {
+		return errors.Wrap(err, "invalid LFS batch response")
+	}
+
+	for _, o := range batch.Objects {
+		if o.Error != nil {
+			log15.Warn("LFS server failed to provide object", "oid", o.OID, "code", o.Error.Code, "message", o.Error.Message)
+			continue
+		}
+		if o.Actions.Download == nil {
+			continue
+		}
+		if err := downloadLFSObject(ctx, gitDir, o.Pointer, o.Actions.Download.Href, o.Actions.Download.Header); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log15.Warn("failed to download LFS object", "oid", o.OID, "error", err)
+		}
+	}
+	return nil
+}
+
+// downloadLFSObject downloads the LFS object with pointer p from href and
+// stores it in the repository in gitDir.
+func downloadLFSObject(ctx context.Context, gitDir string, p lfs.Pointer, href string, header map[string]string) error {
+	if len(p.OID) != sha256.Size*2 {
+		return errors.Errorf("invalid LFS object ID %q", p.OID)
+	}
+	req, err := http.NewRequest("GET", href, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := lfsHTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("LFS object %s download failed with status %d", p.OID, resp.StatusCode)
+	}
+
+	dst := lfsObjectPath(gitDir, p.OID)
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(filepath.Dir(dst), p.OID+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	// Store the object only if it matches the pointer.
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), io.LimitReader(resp.Body, p.Size+1))
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return err
+	}
+	if n != p.Size || hex.EncodeToString(h.Sum(nil)) != p.OID {
+		return errors.Errorf("LFS object %s does not match its pointer", p.OID)
+	}
+	return os.Rename(f.Name(), dst)
+}
+
+// fetchLFSObject fetches the LFS object with pointer p into the repository in
+// gitDir from the LFS server of the repository, unless fetching LFS objects is
+// disabled or the object is too large.
+func (s *Server) fetchLFSObject(ctx context.Context, gitDir string, p lfs.Pointer) error {
+	enabled, maxObjectSize := lfsSettings()
+	if !enabled || p.Size > maxObjectSize {
+		return nil
+	}
+	remoteURL, err := repoRemoteURL(ctx, gitDir)
+	if err != nil {
+		return err
+	}
+	return s.fetchLFSBatch(ctx, gitDir, remoteURL, []lfs.Pointer{p})
+}
+
+// handleLFSObject responds with the content of an LFS object (see
+// protocol.LFSObjectRequest), fetching it if this gitserver does not have it
+// yet.
+func (s *Server) handleLFSObject(w http.ResponseWriter, r *http.Request) {
+	var req protocol.LFSObjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Pointer.OID) != sha256.Size*2 {
+		http.Error(w, "invalid LFS object ID", http.StatusBadRequest)
+		return
+	}
+
+	dir := filepath.Join(s.ReposDir, string(protocol.NormalizeRepo(req.Repo)))
+	if !repoCloned(dir) {
+		http.Error(w, "repository not found", http.StatusNotFound)
+		return
+	}
+	gitDir := filepath.Join(dir, ".git")
+	p := lfsObjectPath(gitDir, req.Pointer.OID)
+
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		if err := s.fetchLFSObject(r.Context(), gitDir, req.Pointer); err != nil {
+			log15.Warn("failed to fetch LFS object", "repo", req.Repo, "oid", req.Pointer.OID, "error", err)
+		}
+		f, err = os.Open(p)
+	}
+	if os.IsNotExist(err) {
+		http.Error(w, "LFS object not available", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	if _, err := io.Copy(w, f); err != nil {
+		log15.Warn("failed to send LFS object", "repo", req.Repo, "oid", req.Pointer.OID, "error", err)
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/lfs"
+)
+
+func TestLFSObjects(t *testing.T) {
+	// objects are the LFS objects on the LFS server.
+	objects := map[string][]byte{}
+	pointer := func(content string) (lfs.Pointer, string) {
+		sum := sha256.Sum256([]byte(content))
+		p := lfs.Pointer{OID: hex.EncodeToString(sum[:]), Size: int64(len(content))}
+		objects[p.OID] = []byte(content)
+		return p, fmt.Sprintf("version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize %d\n", p.OID, p.Size)
+	}
+	small, smallPointer := pointer("hello LFS\n")
+	large, largePointer := pointer(strings.Repeat("large LFS\n", 100))
+	other, _ := pointer("not on the default branch\n")
+
+	var lfsServer *httptest.Server
+	lfsServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/foo/bar.git/info/lfs/objects/batch":
+			if user, password, _ := r.BasicAuth(); user != "u" || password != "p" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			var req lfsBatchRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			var objs []string
+			for _, p := range req.Objects {
+				objs = append(objs, fmt.Sprintf(`{"oid":%q,"size":%d,"actions":{"download":{"href":"%s/objects/%s","header":{"X-Token":"t"}}}}`, p.OID, p.Size, lfsServer.URL, p.OID))
+			}
+			fmt.Fprintf(w, `{"objects":[%s]}`, strings.Join(objs, ","))
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/objects/"):
+			content, ok := objects[strings.TrimPrefix(r.URL.Path, "/objects/")]
+			if !ok || r.Header.Get("X-Token") != "t" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(content)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer lfsServer.Close()
+	remoteURL := strings.Replace(lfsServer.URL, "http://", "http://u:p@", 1) + "/foo/bar"
+
+	origLFSSettings := lfsSettings
+	lfsSettings = func() (bool, int64) { return true, 100 }
+	defer func() { lfsSettings = origLFSSettings }()
+	origRepoRemoteURL := repoRemoteURL
+	repoRemoteURL = func(context.Context, string) (string, error) { return remoteURL, nil }
+	defer func() { repoRemoteURL = origRepoRemoteURL }()
+
+	// Setup a clone whose default branch has pointers to a small and a large
+	// LFS object, and a regular file.
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+	work := filepath.Join(root, "work")
+	if err := os.MkdirAll(work, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"small.txt": smallPointer, "large.bin": largePointer, "regular.txt": "hello\n"} {
+		if err := ioutil.WriteFile(filepath.Join(work, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reposDir := filepath.Join(root, "repos")
+	gitDir := filepath.Join(reposDir, "example.com/foo/bar", ".git")
+	for _, args := range [][]string{
+		{"init", work},
+		{"-C", work, "add", "."},
+		{"-C", work, "-c", "user.name=a", "-c", "user.email=a@a.com", "commit", "-m", "lfs"},
+		{"clone", "--mirror", work, gitDir},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %s: %s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	s := &Server{ReposDir: reposDir}
+	if err := s.fetchLFSObjects(context.Background(), gitDir, remoteURL); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ioutil.ReadFile(lfsObjectPath(gitDir, small.OID)); err != nil {
+		t.Fatalf("expected small LFS object to be fetched: %s", err)
+	} else if string(got) != string(objects[small.OID]) {
+		t.Errorf("got small LFS object %q, want %q", got, objects[small.OID])
+	}
+	if _, err := os.Stat(lfsObjectPath(gitDir, large.OID)); !os.IsNotExist(err) {
+		t.Errorf("expected large LFS object not to be fetched, got error %v", err)
+	}
+
+	// LFS objects that are not on the default branch are fetched when they
+	// are requested.
+	h := s.Handler()
+	for _, test := range []struct {
+		pointer    lfs.Pointer
+		wantStatus int
+	}{
+		{small, http.StatusOK},
+		{other, http.StatusOK},
+		{large, http.StatusNotFound},
+	} {
+		body, err := json.Marshal(&protocol.LFSObjectRequest{Repo: "example.com/foo/bar", Pointer: test.pointer})
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/lfs-object", bytes.NewReader(body)))
+		if rec.Code != test.wantStatus {
+			t.Errorf("%s: got status %d, want %d", test.pointer.OID, rec.Code, test.wantStatus)
+			continue
+		}
+		if test.wantStatus == http.StatusOK && rec.Body.String() != string(objects[test.pointer.OID]) {
+			t.Errorf("%s: got content %q, want %q", test.pointer.OID, rec.Body.String(), objects[test.pointer.OID])
+		}
+	}
+}
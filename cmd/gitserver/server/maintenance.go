@@ -0,0 +1,275 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Git maintenance
+//
+// Fetching into a repository accumulates loose objects and small packs, which
+// waste disk space and slow down git commands. The janitor job runs
+// maintainRepo on every repository, which repacks the repository when it has
+// too many loose objects or packs and keeps its commit-graph (which speeds up
+// walking history) up to date. Each step only runs when it is needed, so
+// maintaining a repository that hasn't changed is cheap.
+//
+// Maintenance holds the repository lock and waits for running updates, so it
+// never runs at the same time as a clone, fetch or eviction of the repository.
+// A repository which is locked is skipped until the next janitor run. A
+// repository is only recloned if maintenance reports that it is corrupt and
+// git fsck confirms it.
+
+const (
+	// looseObjectsThreshold is the number of loose objects above which the
+	// loose objects are packed into a new pack.
+	looseObjectsThreshold = 1024
+
+	// packsThreshold is the number of packs above which all packs are
+	// repacked into a single pack (with a reachability bitmap).
+	packsThreshold = 50
+)
+
+var maintenanceTasks = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "maintenance_tasks",
+	Help:      "number of git maintenance tasks run on repos during cleanup",
+}, []string{"task", "result"})
+
+func init() {
+	prometheus.MustRegister(maintenanceTasks)
+}
+
+// maintenanceLockStatus is the status of the repository lock while a
+// repository is maintained. The repository stays usable meanwhile, so it is
+// not reported as being cloned.
+const maintenanceLockStatus = "running git maintenance"
+
+// errRepoCorrupt is returned by maintainRepo if git reported that the
+// repository is corrupt.
+var errRepoCorrupt = errors.New("repository is corrupt")
+
+// corruptionMessages are the messages git prints when it reads a corrupt
+// object or pack. git does not always fail when it prints them, so they are
+// checked for in the output of successful commands too.
+var corruptionMessages = []string{
+	"is corrupt",
+	"corrupt loose object",
+	"unable to unpack",
+	"bad object",
+	"unknown object type",
+	"cannot be accessed",
+	"invalid sha1 pointer",
+	"packfile is truncated",
+	"inflate: data stream error",
+}
+
+// maintainRepoLocked runs maintainRepo on repo while holding its lock and
+// excluding its updates. It does nothing if the repository is locked, for
+// example because it is being cloned. If maintenance reports that the
+// repository is corrupt, that is confirmed with checkConnectivity before an
+// error wrapping errRepoCorrupt is returned.
+func (s *Server) maintainRepoLocked(ctx context.Context, repo api.RepoName, gitDir string) error {
+	_, _, mu := s.repoUpdateLock(repo)
+	mu.Lock()
+	defer mu.Unlock()
+
+	lock, ok := s.locker.TryAcquire(gitDir, maintenanceLockStatus)
+	if !ok {
+		maintenanceTasks.WithLabelValues("all", "locked").Inc()
+		return nil
+	}
+	defer lock.Release()
+
+	err := s.maintainRepo(ctx, gitDir)
+	if errors.Cause(err) != errRepoCorrupt {
+		return err
+	}
+	if cerr := checkConnectivity(ctx, gitDir); errors.Cause(cerr) != errRepoCorrupt {
+		// Output that looks like corruption can also be caused by something
+		// else modifying the repository at the same time.
+		log15.Warn("git fsck did not confirm corruption reported by maintenance", "repo", repo, "error", err, "fsckError", cerr)
+		return errors.Errorf("%s (not confirmed by git fsck)", err)
+	}
+	return err
+}
+
+// maintainRepo runs the git maintenance tasks that are due on the repository
+// in gitDir. It returns an error wrapping errRepoCorrupt if the repository is
+// corrupt.
+func (s *Server) maintainRepo(ctx context.Context, gitDir string) error {
+	lastMaintenance, err := getLastMaintenance(gitDir)
+	if err != nil {
+		return err
+	}
+
+	loose, packs, err := countObjects(ctx, gitDir)
+	if err != nil {
+		return err
+	}
+
+	repacked := false
+	switch {
+	case packs > packsThreshold:
+		// A full repack also packs the loose objects.
+		if err := runMaintenanceTask(ctx, gitDir, "full repack", "repack", "-A", "-d", "-l", "--write-bitmap-index"); err != nil {
+			return err
+		}
+		repacked = true
+	case loose > looseObjectsThreshold:
+		if err := runMaintenanceTask(ctx, gitDir, "incremental repack", "repack", "-d", "-l"); err != nil {
+			return err
+		}
+		if err := runMaintenanceTask(ctx, gitDir, "prune packed", "prune-packed"); err != nil {
+			return err
+		}
+		repacked = true
+	}
+
+	// gc --auto only does work if one of git's own thresholds (such as
+	// gc.auto) is exceeded, for example to expire reflogs and pack refs. We
+	// don't want it to detach, so that commands don't run concurrently with
+	// the next maintenance task.
+	if err := runMaintenanceTask(ctx, gitDir, "gc auto", "-c", "gc.autoDetach=false", "gc", "--auto", "--quiet"); err != nil {
+		return err
+	}
+
+	// The commit-graph only needs to be rewritten if the repository was
+	// fetched into (or repacked) since the last maintenance. git does not
+	// write a commit-graph for shallow clones.
+	lastFetched, err := repoLastFetched(gitDir)
+	if err != nil {
+		return err
+	}
+	if repacked || lastMaintenance.IsZero() || lastFetched.After(lastMaintenance) {
+		if err := runMaintenanceTask(ctx, gitDir, "commit graph", "commit-graph", "write", "--reachable"); err != nil {
+			return err
+		}
+	}
+
+	return setLastMaintenance(gitDir, time.Now())
+}
+
+// runMaintenanceTask runs git with args in gitDir. It returns an error
+// wrapping errRepoCorrupt if git reported that the repository is corrupt.
+func runMaintenanceTask(ctx context.Context, gitDir, task string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = gitDir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+
+	if isCorruptionOutput(stderr.String()) {
+		maintenanceTasks.WithLabelValues(task, "corrupt").Inc()
+		return errors.Wrapf(errRepoCorrupt, "%s: %s", task, strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		maintenanceTasks.WithLabelValues(task, "error").Inc()
+		return errors.Wrapf(err, "%s failed with stderr: %s", task, strings.TrimSpace(stderr.String()))
+	}
+	maintenanceTasks.WithLabelValues(task, "success").Inc()
+	return nil
+}
+
+// checkConnectivity runs git fsck on the repository in gitDir to check that
+// all objects reachable from its refs exist and can be read. It returns an
+// error wrapping errRepoCorrupt if they don't.
+func checkConnectivity(ctx context.Context, gitDir string) error {
+	cmd := exec.CommandContext(ctx, "git", "fsck", "--connectivity-only", "--no-dangling", "--no-progress")
+	cmd.Dir = gitDir
+	out, err := cmd.CombinedOutput()
+	if err == nil {
+		maintenanceTasks.WithLabelValues("fsck", "success").Inc()
+		return nil
+	}
+	if _, ok := err.(*exec.ExitError); !ok || ctx.Err() != nil {
+		maintenanceTasks.WithLabelValues("fsck", "error").Inc()
+		return errors.Wrap(wrapCmdError(cmd, err), "fsck failed")
+	}
+	maintenanceTasks.WithLabelValues("fsck", "corrupt").Inc()
+	return errors.Wrapf(errRepoCorrupt, "fsck: %s", strings.TrimSpace(string(out)))
+}
+
+// isCorruptionOutput reports whether the output of a git command says that
+// the repository is corrupt.
+func isCorruptionOutput(output string) bool {
+	output = strings.ToLower(output)
+	for _, msg := range corruptionMessages {
+		if strings.Contains(output, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// countObjects returns the number of loose objects and packs of the
+// repository in gitDir.
+func countObjects(ctx context.Context, gitDir string) (loose, packs int, err error) {
+	cmd := exec.CommandContext(ctx, "git", "count-objects", "-v")
+	cmd.Dir = gitDir
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, 0, errors.Wrap(wrapCmdError(cmd, err), "failed to count objects")
+	}
+
+	// Each line is "<key>: <value>".
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ": ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		switch parts[0] {
+		case "count":
+			loose, _ = strconv.Atoi(parts[1])
+		case "packs":
+			packs, _ = strconv.Atoi(parts[1])
+		}
+	}
+	return loose, packs, scanner.Err()
+}
+
+// getLastMaintenance returns the time maintainRepo last ran on the repository
+// in gitDir. It returns the zero time if maintainRepo never ran on it.
+func getLastMaintenance(gitDir string) (time.Time, error) {
+	cmd := exec.Command("git", "config", "--get", "sourcegraph.maintenanceTimestamp")
+	cmd.Dir = gitDir
+	out, err := cmd.Output()
+	if err != nil {
+		// Exit code 1 means the key is not set.
+		if ee, ok := err.(*exec.ExitError); ok && ee.Sys().(syscall.WaitStatus).ExitStatus() == 1 {
+			return time.Time{}, nil
+		}
+		return time.Time{}, errors.Wrap(wrapCmdError(cmd, err), "failed to determine maintenance timestamp")
+	}
+
+	sec, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 0)
+	if err != nil {
+		// Treat a bad value like a missing one.
+		return time.Time{}, nil
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// setLastMaintenance records that maintainRepo ran on the repository in
+// gitDir at t.
+func setLastMaintenance(gitDir string, t time.Time) error {
+	cmd := exec.Command("git", "config", "sourcegraph.maintenanceTimestamp", strconv.FormatInt(t.Unix(), 10))
+	cmd.Dir = gitDir
+	if _, err := cmd.Output(); err != nil {
+		return errors.Wrap(wrapCmdError(cmd, err), "failed to update maintenanceTimestamp")
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestMaintainRepo(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+
+	dir := filepath.Join(root, "repo")
+	cmd := func(name string, arg ...string) string {
+		t.Helper()
+		c := exec.Command(name, arg...)
+		c.Dir = dir
+		c.Env = []string{
+			"GIT_COMMITTER_NAME=a",
+			"GIT_COMMITTER_EMAIL=a@a.com",
+			"GIT_AUTHOR_NAME=a",
+			"GIT_AUTHOR_EMAIL=a@a.com",
+		}
+		b, err := c.Output()
+		if err != nil {
+			t.Fatalf("%s %s failed: %s", name, strings.Join(arg, " "), err)
+		}
+		return string(b)
+	}
+
+	// Setup a repo with more loose objects than looseObjectsThreshold.
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	cmd("git", "init", ".")
+	cmd("git", "config", "gc.auto", "0")
+	for i := 0; i <= looseObjectsThreshold; i++ {
+		if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("file%d.txt", i)), []byte(fmt.Sprintf("%d\n", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd("git", "add", ".")
+	cmd("git", "commit", "-m", "many files")
+
+	gitDir := filepath.Join(dir, ".git")
+	// Pretend the repo was last fetched a while ago.
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(gitDir, "HEAD"), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{ReposDir: root}
+	if err := s.maintainRepo(context.Background(), gitDir); err != nil {
+		t.Fatal(err)
+	}
+
+	loose, packs, err := countObjects(context.Background(), gitDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loose != 0 || packs != 1 {
+		t.Errorf("got %d loose objects and %d packs after maintenance, want 0 and 1", loose, packs)
+	}
+	graphPath := filepath.Join(gitDir, "objects", "info", "commit-graph")
+	if _, err := os.Stat(graphPath); err != nil {
+		t.Fatalf("expected a commit-graph after maintenance: %s", err)
+	}
+	if last, err := getLastMaintenance(gitDir); err != nil {
+		t.Fatal(err)
+	} else if time.Since(last) > time.Minute {
+		t.Errorf("got last maintenance time %s, want about now", last)
+	}
+
+	// Maintaining a repo that didn't change doesn't rewrite the commit-graph.
+	if err := os.Chtimes(graphPath, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.maintainRepo(context.Background(), gitDir); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(graphPath); err != nil {
+		t.Fatal(err)
+	} else if fi.ModTime().After(past.Add(time.Second)) {
+		t.Error("expected commit-graph not to be rewritten for an unchanged repo")
+	}
+}
+
+func TestIsCorruptionOutput(t *testing.T) {
+	for output, want := range map[string]bool{
+		"": false,
+		"fatal: packed object 8e7d70de (stored in ./objects/pack/pack-953d6d4d.pack) is corrupt": true,
+		"error: inflate: data stream error (incorrect header check)":                             true,
+		"error: unknown object type 0 at offset 12 in ./objects/pack/pack-fa92c8ae.pack":         true,
+		"fatal: bad object HEAD":                                true,
+		"warning: There are too many unreachable loose objects": false,
+	} {
+		if got := isCorruptionOutput(output); got != want {
+			t.Errorf("isCorruptionOutput(%q) = %v, want %v", output, got, want)
+		}
+	}
+}
+
+func TestMaintainRepoLocked(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+
+	gitDir := filepath.Join(root, "repo", ".git")
+	if out, err := exec.Command("git", "init", "--bare", gitDir).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %s: %s", err, out)
+	}
+
+	s := &Server{ReposDir: root}
+	s.Handler() // Handler as a side-effect sets up Server
+
+	// A repo that is locked, for example because it is being cloned, is
+	// skipped.
+	lock, ok := s.locker.TryAcquire(gitDir, "cloning")
+	if !ok {
+		t.Fatal("failed to lock repo")
+	}
+	if err := s.maintainRepoLocked(context.Background(), "repo", gitDir); err != nil {
+		t.Fatal(err)
+	}
+	if last, err := getLastMaintenance(gitDir); err != nil {
+		t.Fatal(err)
+	} else if !last.IsZero() {
+		t.Error("expected maintenance to skip a locked repo")
+	}
+	lock.Release()
+
+	if err := s.maintainRepoLocked(context.Background(), "repo", gitDir); err != nil {
+		t.Fatal(err)
+	}
+	if last, err := getLastMaintenance(gitDir); err != nil {
+		t.Fatal(err)
+	} else if last.IsZero() {
+		t.Error("expected maintenance to run on an unlocked repo")
+	}
+	if _, locked := s.locker.Status(gitDir); locked {
+		t.Error("expected the repo lock to be released after maintenance")
+	}
+}
+
+func TestCheckConnectivity(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+
+	remote := filepath.Join(root, "remote")
+	gitDir := filepath.Join(root, "repo", ".git")
+	for _, args := range [][]string{
+		{"init", remote},
+		{"-C", remote, "-c", "user.name=a", "-c", "user.email=a@a.com", "commit", "--allow-empty", "-m", "hello"},
+		{"clone", "--mirror", "--no-local", remote, gitDir},
+	} {
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s failed: %s: %s", strings.Join(args, " "), err, out)
+		}
+	}
+
+	if err := checkConnectivity(context.Background(), gitDir); err != nil {
+		t.Fatalf("expected no error for a healthy repo, got %s", err)
+	}
+
+	packs, err := filepath.Glob(filepath.Join(gitDir, "objects", "pack", "*.pack"))
+	if err != nil || len(packs) != 1 {
+		t.Fatalf("expected a single pack, got %v (error: %v)", packs, err)
+	}
+	corruptPack(t, packs[0])
+	if err := checkConnectivity(context.Background(), gitDir); errors.Cause(err) != errRepoCorrupt {
+		t.Errorf("expected errRepoCorrupt for a corrupt repo, got %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Push mirrors
+//
+// A repository can have a push mirror: a secondary remote that gitserver
+// pushes all branches and tags of the repository to after each successful
+// clone or update (see protocol.RepoUpdateRequest.PushMirrorURL). The URL of
+// the push mirror is stored in the repository's git config, so that updates
+// which are not requested by repo-updater push too. Pushes run in the
+// background and are retried with backoff. The status of the last push is
+// recorded in the sg_pushmirror file in the repository's GIT_DIR.
+
+// pushMirrorAttempts is how many times a push is attempted before giving up
+// until the next update.
+const pushMirrorAttempts = 3
+
+// pushMirrorRetryDelay is how long to wait before retrying a failed push. It
+// doubles after each attempt. It is a variable so tests can change it.
+var pushMirrorRetryDelay = 30 * time.Second
+
+var pushMirrorPushes = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "src",
+	Subsystem: "gitserver",
+	Name:      "push_mirror_pushes",
+	Help:      "number of pushes to push mirrors, by whether they succeeded",
+}, []string{"status"})
+
+func init() {
+	prometheus.MustRegister(pushMirrorPushes)
+}
+
+// setPushMirrorURL stores the URL of the push mirror of the repository in
+// gitDir. An empty URL removes the push mirror.
+func setPushMirrorURL(gitDir, pushMirrorURL string) error {
+	if pushMirrorURL == getPushMirrorURL(gitDir) {
+		return nil
+	}
+	args := []string{"config", "sourcegraph.pushMirrorURL", pushMirrorURL}
+	if pushMirrorURL == "" {
+		args = []string{"config", "--unset-all", "sourcegraph.pushMirrorURL"}
+	}
+	cmd := exec.Command("git", args...)
+	cmd.Dir = gitDir
+	if _, err := cmd.Output(); err != nil {
+		return errors.Wrap(wrapCmdError(cmd, err), "failed to store push mirror")
+	}
+	// The status of pushes to a previous push mirror no longer applies.
+	if err := os.Remove(filepath.Join(gitDir, "sg_pushmirror")); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// getPushMirrorURL returns the URL of the push mirror of the repository in
+// gitDir, or "" if it has none.
+func getPushMirrorURL(gitDir string) string {
+	cmd := exec.Command("git", "config", "--get", "sourcegraph.pushMirrorURL")
+	cmd.Dir = gitDir
+	out, err := cmd.Output()
+	if err != nil {
+		// Exit code 1 means the key is not set.
+		if ee, ok := err.(*exec.ExitError); !ok || ee.Sys().(syscall.WaitStatus).ExitStatus() != 1 {
+			log15.Warn("failed to determine push mirror", "dir", gitDir, "error", err)
+		}
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// schedulePushMirror pushes repo to its push mirror in the background, if it
+// has one. If a push of repo is already running, another push is started
+// when it finishes, so that the push mirror ends up with the latest refs.
+func (s *Server) schedulePushMirror(repo api.RepoName) {
+	gitDir := filepath.Join(s.ReposDir, string(protocol.NormalizeRepo(repo)), ".git")
+	if getPushMirrorURL(gitDir) == "" {
+		return
+	}
+
+	s.pushMirrorsMu.Lock()
+	if _, running := s.pushMirrors[repo]; running {
+		s.pushMirrors[repo] = true
+		s.pushMirrorsMu.Unlock()
+		return
+	}
+	if s.pushMirrors == nil {
+		s.pushMirrors = make(map[api.RepoName]bool)
+	}
+	s.pushMirrors[repo] = false
+	s.pushMirrorsMu.Unlock()
+
+	ctx, cancel := s.serverContext()
+	go func() {
+		defer cancel()
+		for {
+			s.pushMirrorWithRetries(ctx, repo, gitDir)
+
+			s.pushMirrorsMu.Lock()
+			if !s.pushMirrors[repo] || ctx.Err() != nil {
+				delete(s.pushMirrors, repo)
+				s.pushMirrorsMu.Unlock()
+				return
+			}
+			s.pushMirrors[repo] = false
+			s.pushMirrorsMu.Unlock()
+		}
+	}()
+}
+
+// pushMirrorWithRetries pushes the repository in gitDir to its push mirror,
+// retrying with backoff if the push fails, and records the status of the
+// push.
+func (s *Server) pushMirrorWithRetries(ctx context.Context, repo api.RepoName, gitDir string) {
+	pushMirrorURL := getPushMirrorURL(gitDir)
+	if pushMirrorURL == "" {
+		return
+	}
+
+	status, err := readPushMirrorStatus(gitDir)
+	if err != nil {
+		log15.Warn("failed to read push mirror status", "repo", repo, "error", err)
+	}
+	if status == nil {
+		status = &protocol.PushMirrorStatus{}
+	}
+
+	delay := pushMirrorRetryDelay
+	for attempt := 1; ; attempt++ {
+		now := time.Now()
+		status.URL = redactURLCredentials(pushMirrorURL)
+		status.LastAttempted = &now
+		err = s.pushMirror(ctx, gitDir, pushMirrorURL)
+		if err == nil || attempt == pushMirrorAttempts {
+			break
+		}
+		log15.Warn("failed to push to push mirror, retrying", "repo", repo, "attempt", attempt, "error", err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		delay *= 2
+	}
+
+	if err != nil {
+		pushMirrorPushes.WithLabelValues("failed").Inc()
+		log15.Error("failed to push to push mirror", "repo", repo, "error", err)
+		status.LastError = err.Error()
+	} else {
+		pushMirrorPushes.WithLabelValues("succeeded").Inc()
+		now := time.Now()
+		status.LastPushed = &now
+		status.LastError = ""
+	}
+	if err := writePushMirrorStatus(gitDir, status); err != nil {
+		log15.Warn("failed to record push mirror status", "repo", repo, "error", err)
+	}
+}
+
+// pushMirror pushes all branches and tags of the repository in gitDir to
+// pushMirrorURL, deleting the branches and tags that no longer exist.
+func (s *Server) pushMirror(ctx context.Context, gitDir, pushMirrorURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, longGitCommandTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "push", "--force", "--prune", pushMirrorURL, "refs/heads/*:refs/heads/*", "refs/tags/*:refs/tags/*")
+	cmd.Dir = gitDir
+	if output, err := s.runWithRemoteOpts(ctx, cmd, nil); err != nil {
+		redactor := newURLRedactor(pushMirrorURL)
+		return errors.Errorf("push failed: %s. Output: %s", redactor.redact(err.Error()), redactor.redact(string(output)))
+	}
+	return nil
+}
+
+// readPushMirrorStatus returns the recorded status of pushes of the
+// repository in gitDir to its push mirror, or nil if it has never been
+// pushed.
+func readPushMirrorStatus(gitDir string) (*protocol.PushMirrorStatus, error) {
+	b, err := ioutil.ReadFile(filepath.Join(gitDir, "sg_pushmirror"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var status protocol.PushMirrorStatus
+	if err := json.Unmarshal(b, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// writePushMirrorStatus records the status of pushes of the repository in
+// gitDir to its push mirror.
+func writePushMirrorStatus(gitDir string, status *protocol.PushMirrorStatus) error {
+	b, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(gitDir, "sg_pushmirror")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(gitDir, "sg_pushmirror"))
+}
+
+// redactURLCredentials returns rawurl without its username and password.
+func redactURLCredentials(rawurl string) string {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.User == nil {
+		return rawurl
+	}
+	u.User = nil
+	return u.String()
+}
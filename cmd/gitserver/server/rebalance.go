@@ -0,0 +1,566 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater"
+	repoupdaterprotocol "github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Rebalancing
+//
+// Repositories are sharded over the gitservers by hashing the repository name
+// over the gitserver addresses (the shard set). When the shard set changes,
+// many repositories are sharded to a different gitserver. Without rebalancing,
+// the new owner of a repository reclones it from the code host.
+//
+// When rebalancing is enabled, a gitserver watches the shard set. When it
+// changes, the gitserver asks the new owner of each repository that it has a
+// clone of, but no longer owns, to transfer the clone (see
+// handleAcceptTransfer). Once the new owner has the repository, the local clone
+// is removed. Additionally, when a gitserver has to clone a repository and the
+// shard set changed since it started, it first tries to transfer the
+// repository from its previous owner (see cloneRepo).
+//
+// Transfers are git bundles of all refs of the repository (see
+// handleTransfer), so they are consistent even if the repository is updated
+// concurrently. The gitserver that takes over a repository looks up its remote
+// URL itself, so that credentials in the URL are not sent between gitservers.
+//
+// A gitserver only rebalances if it finds its own address in the shard set (see
+// selfAddr), and it only accepts a transfer of a repository that it owns.
+
+// rebalanceInterval is how often the shard set is checked for changes.
+const rebalanceInterval = 10 * time.Second
+
+// rebalanceConcurrency is the number of repositories that a gitserver transfers
+// to other gitservers at the same time.
+const rebalanceConcurrency = 4
+
+// shardAddrs returns the current shard set. It is mocked by tests.
+var shardAddrs = func(ctx context.Context) []string {
+	return gitserver.DefaultClient.Addrs(ctx)
+}
+
+// transferHTTPClient is the HTTP client used to talk to other gitservers when
+// rebalancing.
+var transferHTTPClient = &http.Client{}
+
+// lookupRemoteURL returns the remote URL of a repository that is transferred
+// from another gitserver. It is mocked by tests.
+var lookupRemoteURL = func(ctx context.Context, repo api.RepoName) (string, error) {
+	result, err := repoupdater.DefaultClient.RepoLookup(ctx, repoupdaterprotocol.RepoLookupArgs{Repo: repo})
+	if err != nil {
+		return "", err
+	}
+	if result.Repo == nil || result.Repo.VCS.URL == "" {
+		return "", errors.Errorf("no remote URL for repository %s (%s)", repo, result)
+	}
+	return result.Repo.VCS.URL, nil
+}
+
+// lookupIPAddr and interfaceAddrs are used to find this gitserver in the shard
+// set. They are mocked by tests.
+var (
+	lookupIPAddr   = net.DefaultResolver.LookupIPAddr
+	interfaceAddrs = net.InterfaceAddrs
+)
+
+// RunRebalancer watches the shard set and transfers repositories to their new
+// owners when it changes. It returns when the server is stopped.
+func (s *Server) RunRebalancer() {
+	ctx, cancel := s.serverContext()
+	defer cancel()
+
+	cancelPass := func() {}
+	defer func() { cancelPass() }()
+	for {
+		if addrs := shardAddrs(ctx); len(addrs) > 0 && s.shardsChanged(addrs) {
+			self := s.selfAddr(ctx, addrs)
+			s.setShards(addrs, self)
+
+			// Abort the pass for the previous shard set, since it may transfer
+			// repositories to the wrong gitservers now.
+			cancelPass()
+			cancelPass = s.startRebalance(ctx, addrs, self)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(rebalanceInterval):
+		}
+	}
+}
+
+// startRebalance starts a rebalance pass for the shard set addrs, in which
+// this gitserver has the address self, in the background. The returned function
+// aborts the pass.
+func (s *Server) startRebalance(ctx context.Context, addrs []string, self string) context.CancelFunc {
+	ctx, cancel := context.WithCancel(ctx)
+	go s.rebalance(ctx, addrs, self)
+	return cancel
+}
+
+// shardsChanged reports whether addrs differs from the current shard set.
+func (s *Server) shardsChanged(addrs []string) bool {
+	s.shardsMu.Lock()
+	defer s.shardsMu.Unlock()
+	return !reflect.DeepEqual(addrs, s.shards)
+}
+
+// setShards records the current shard set and the address of this gitserver
+// in it ("" if it was not found).
+func (s *Server) setShards(addrs []string, self string) {
+	s.shardsMu.Lock()
+	defer s.shardsMu.Unlock()
+	if s.shards != nil {
+		log15.Info("gitserver shard set changed", "old", s.shards, "new", addrs)
+	}
+	s.prevShards, s.shards = s.shards, append([]string(nil), addrs...)
+	s.prevSelf, s.self = s.self, self
+}
+
+// previousOwner returns the address of the gitserver that owned the repository
+// before the last change of the shard set. It returns "" if the shard set did
+// not change since the server started or if this gitserver was the owner.
+func (s *Server) previousOwner(repo api.RepoName) string {
+	s.shardsMu.Lock()
+	defer s.shardsMu.Unlock()
+	if len(s.prevShards) == 0 {
+		return ""
+	}
+	addr := gitserver.AddrForRepo(repo, s.prevShards)
+	if addr == s.prevSelf || addr == s.self {
+		return ""
+	}
+	return addr
+}
+
+// owns reports whether this gitserver owns the repository in the shard set
+// addrs. The caller reads addrs with shardAddrs instead of using s.shards,
+// since another gitserver may see a change of the shard set first.
+func (s *Server) owns(ctx context.Context, repo api.RepoName, addrs []string) bool {
+	if len(addrs) == 0 {
+		return false
+	}
+	s.shardsMu.Lock()
+	self := s.self
+	s.shardsMu.Unlock()
+	if !containsString(addrs, self) {
+		self = s.selfAddr(ctx, addrs)
+	}
+	return self != "" && gitserver.AddrForRepo(repo, addrs) == self
+}
+
+// isShard reports whether addr is in the shard set addrs or in a shard set
+// that this gitserver has seen before.
+func (s *Server) isShard(addr string, addrs []string) bool {
+	s.shardsMu.Lock()
+	defer s.shardsMu.Unlock()
+	return containsString(addrs, addr) || containsString(s.shards, addr) || containsString(s.prevShards, addr)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// selfAddr returns the address of this gitserver in the shard set addrs, or ""
+// if it is not found. If s.Addr is set, it is the address. Otherwise, an address
+// matches if its host is Hostname (or a domain name starting with it, such as
+// "gitserver-1.gitserver" for "gitserver-1") or resolves to an IP address of this
+// host. If more than one address matches (such as when several gitservers run on
+// the same host), none is used.
+func (s *Server) selfAddr(ctx context.Context, addrs []string) string {
+	if s.Addr != "" {
+		if containsString(addrs, s.Addr) {
+			return s.Addr
+		}
+		return ""
+	}
+
+	local := map[string]bool{}
+	if ifaceAddrs, err := interfaceAddrs(); err != nil {
+		log15.Warn("rebalance: failed to list the IP addresses of this host", "error", err)
+	} else {
+		for _, a := range ifaceAddrs {
+			if ipNet, ok := a.(*net.IPNet); ok {
+				local[ipNet.IP.String()] = true
+			}
+		}
+	}
+
+	var matches []string
+	for _, addr := range addrs {
+		host := addr
+		if h, _, err := net.SplitHostPort(addr); err == nil {
+			host = h
+		}
+		if s.Hostname != "" && (host == s.Hostname || strings.HasPrefix(host, s.Hostname+".")) {
+			matches = append(matches, addr)
+			continue
+		}
+		ips, err := lookupIPAddr(ctx, host)
+		if err != nil {
+			continue
+		}
+		for _, ip := range ips {
+			if local[ip.IP.String()] {
+				matches = append(matches, addr)
+				break
+			}
+		}
+	}
+	if len(matches) > 1 {
+		log15.Warn("rebalance: found more than one address of this gitserver in the shard set (set SRC_GITSERVER_ADDR)", "addrs", matches)
+		return ""
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+	return matches[0]
+}
+
+// rebalance transfers the cloned repositories that are owned by another
+// gitserver in the shard set addrs to their owners. self is the address of
+// this gitserver in addrs. If it is empty, nothing is transferred, since the
+// owner of a repository can't be told apart from this gitserver.
+func (s *Server) rebalance(ctx context.Context, addrs []string, self string) {
+	if self == "" {
+		log15.Warn("rebalance: this gitserver was not found in the shard set, so no repositories are transferred (set SRC_GITSERVER_ADDR)", "addrs", addrs)
+		return
+	}
+
+	repos, err := s.clonedRepos(ctx)
+	if err != nil {
+		log15.Error("rebalance: failed to list cloned repositories", "error", err)
+		return
+	}
+
+	var moves []api.RepoName
+	for _, repo := range repos {
+		if gitserver.AddrForRepo(repo, addrs) != self {
+			moves = append(moves, repo)
+		}
+	}
+	if len(moves) == 0 {
+		return
+	}
+
+	log15.Info("rebalance: transferring repositories to other gitservers", "count", len(moves))
+	rebalancePending.Set(float64(len(moves)))
+	defer rebalancePending.Set(0)
+
+	from := self
+	todo := make(chan api.RepoName)
+	var wg sync.WaitGroup
+	for i := 0; i < rebalanceConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for repo := range todo {
+				to := gitserver.AddrForRepo(repo, addrs)
+				if err := s.transferRepo(ctx, repo, from, to); err != nil {
+					if ctx.Err() == nil {
+						log15.Warn("rebalance: failed to transfer repository", "repo", repo, "to", to, "error", err)
+						rebalanceTransfers.WithLabelValues("failed").Inc()
+					}
+				} else {
+					rebalanceTransfers.WithLabelValues("succeeded").Inc()
+				}
+				rebalancePending.Dec()
+			}
+		}()
+	}
+	for _, repo := range moves {
+		select {
+		case todo <- repo:
+		case <-ctx.Done():
+		}
+	}
+	close(todo)
+	wg.Wait()
+}
+
+// transferRepo asks the gitserver at the address to to take over the
+// repository from this gitserver (at the address from). When it has the
+// repository, the local clone is removed.
+func (s *Server) transferRepo(ctx context.Context, repo api.RepoName, from, to string) error {
+	if to == from {
+		return errors.Errorf("refusing to transfer %s to this gitserver (%s)", repo, to)
+	}
+	body, err := json.Marshal(&protocol.RepoTransferRequest{Repo: repo, From: from})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", "http://"+to+"/accept-transfer", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp, err := transferHTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := readAtMost(resp.Body, 1024)
+		return errors.Errorf("accept-transfer failed with status %d: %s", resp.StatusCode, msg)
+	}
+
+	dir := filepath.Join(s.ReposDir, string(protocol.NormalizeRepo(repo)))
+	lock, ok := s.locker.TryAcquire(dir, "transferred to "+to)
+	if !ok {
+		// The repository is being cloned or updated. It is removed on the
+		// next pass.
+		return nil
+	}
+	defer lock.Release()
+	return s.removeRepoDirectory(filepath.Join(dir, ".git"))
+}
+
+// handleAcceptTransfer takes over a repository from another gitserver (see
+// protocol.RepoTransferRequest). It responds once this gitserver has the
+// repository. Only repositories that this gitserver owns in the current shard
+// set are accepted, since the other gitserver removes its clone afterwards.
+func (s *Server) handleAcceptTransfer(w http.ResponseWriter, r *http.Request) {
+	var req protocol.RepoTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Repo == "" || req.From == "" {
+		http.Error(w, "repo and from are required", http.StatusBadRequest)
+		return
+	}
+
+	repo := protocol.NormalizeRepo(req.Repo)
+	addrs := shardAddrs(r.Context())
+	if !s.owns(r.Context(), repo, addrs) {
+		http.Error(w, "this gitserver does not own the repository in the current shard set", http.StatusConflict)
+		return
+	}
+	if !s.isShard(req.From, addrs) {
+		http.Error(w, "from is not a gitserver in the shard set", http.StatusBadRequest)
+		return
+	}
+
+	dir := filepath.Join(s.ReposDir, string(repo))
+	if repoCloned(dir) {
+		return
+	}
+
+	lock, ok := s.locker.TryAcquire(dir, "transferring from "+req.From)
+	if !ok {
+		http.Error(w, "repository is being cloned", http.StatusConflict)
+		return
+	}
+	defer lock.Release()
+
+	ctx, cancel, err := s.acquireCloneLimiter(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer cancel()
+
+	remoteURL, err := lookupRemoteURL(ctx, repo)
+	if err != nil {
+		log15.Warn("failed to look up remote URL of transferred repository", "repo", repo, "error", err)
+		http.Error(w, fmt.Sprintf("failed to look up remote URL: %s", err), http.StatusInternalServerError)
+		return
+	}
+
+	tmpPath, err := s.tempDir("transfer-")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(tmpPath)
+	tmpPath = filepath.Join(tmpPath, ".git")
+
+	if err := s.fetchTransfer(ctx, repo, req.From, remoteURL, tmpPath); err != nil {
+		log15.Warn("failed to transfer repository", "repo", repo, "from", req.From, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := prepareClone(tmpPath); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := os.Rename(tmpPath, filepath.Join(dir, ".git")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log15.Info("repo transferred", "repo", repo, "from", req.From)
+}
+
+// fetchTransfer clones the repository from the gitserver at the address from
+// into tmpPath (the GIT_DIR of the new clone). The origin remote of the clone is
+// set to remoteURL.
+func (s *Server) fetchTransfer(ctx context.Context, repo api.RepoName, from, remoteURL, tmpPath string) (err error) {
+	defer func() {
+		result := "succeeded"
+		if err != nil {
+			result = "failed"
+		}
+		transfersReceived.WithLabelValues(result).Inc()
+	}()
+
+	req, err := http.NewRequest("GET", "http://"+from+"/transfer?repo="+url.QueryEscape(string(repo)), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := transferHTTPClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := readAtMost(resp.Body, 1024)
+		return errors.Errorf("transfer failed with status %d: %s", resp.StatusCode, msg)
+	}
+	// git clone can't read a bundle from stdin, so we store it next to the
+	// clone.
+	bundle := filepath.Join(filepath.Dir(tmpPath), "transfer.bundle")
+	f, err := os.Create(bundle)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, resp.Body)
+	transferBytesReceived.Add(float64(n))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to receive bundle")
+	}
+	defer os.Remove(bundle)
+
+	cmd := exec.CommandContext(ctx, "git", "clone", "--mirror", bundle, tmpPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "failed to clone bundle. Output: %s", string(output))
+	}
+
+	// Future updates fetch from the code host, not from the bundle.
+	cmd = exec.CommandContext(ctx, "git", "remote", "set-url", "origin", remoteURL)
+	cmd.Dir = tmpPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "failed to set remote URL. Output: %s", newURLRedactor(remoteURL).redact(string(output)))
+	}
+	return nil
+}
+
+// handleTransfer responds with a git bundle of all refs of a cloned
+// repository, for another gitserver that takes over the repository (see
+// fetchTransfer).
+func (s *Server) handleTransfer(w http.ResponseWriter, r *http.Request) {
+	repo := protocol.NormalizeRepo(api.RepoName(r.URL.Query().Get("repo")))
+	if repo == "" {
+		http.Error(w, "repo missing", http.StatusBadRequest)
+		return
+	}
+	dir := filepath.Join(s.ReposDir, string(repo))
+	if !repoCloned(dir) {
+		http.Error(w, "repo not cloned", http.StatusNotFound)
+		return
+	}
+	if mode := repoCloneMode(dir); mode != protocol.CloneModeFull {
+		http.Error(w, fmt.Sprintf("can't transfer a %s clone", mode), http.StatusConflict)
+		return
+	}
+	gitDir := filepath.Join(dir, ".git")
+
+	// Write the bundle to a temporary file first, so that we can report an
+	// error (such as for an empty repository, which can't be bundled) with the
+	// response status.
+	tmp, err := s.tempDir("bundle-")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(tmp)
+	bundle := filepath.Join(tmp, "transfer.bundle")
+
+	cmd := exec.CommandContext(r.Context(), "git", "bundle", "create", bundle, "--all")
+	cmd.Dir = gitDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		http.Error(w, fmt.Sprintf("failed to create bundle: %s (output: %q)", err, output), http.StatusInternalServerError)
+		return
+	}
+	f, err := os.Open(bundle)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/x-git-bundle")
+	n, _ := io.Copy(w, f)
+	transferBytesSent.Add(float64(n))
+}
+
+// clonedRepos returns the names of the repositories that are cloned in
+// s.ReposDir. It only finds repositories with the new style directory layout
+// (see migrateGitDir).
+func (s *Server) clonedRepos(ctx context.Context) ([]api.RepoName, error) {
+	var repos []api.RepoName
+	err := filepath.Walk(s.ReposDir, func(path string, info os.FileInfo, err error) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if s.ignorePath(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if filepath.Base(path) == ".git" {
+			name, err := filepath.Rel(s.ReposDir, filepath.Dir(path))
+			if err != nil {
+				return err
+			}
+			repos = append(repos, protocol.NormalizeRepo(api.RepoName(name)))
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	return repos, err
+}
+
+// readAtMost reads at most n bytes from r.
+func readAtMost(r io.Reader, n int64) (string, error) {
+	var b bytes.Buffer
+	_, err := io.Copy(&b, io.LimitReader(r, n))
+	return b.String(), err
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+)
+
+func TestTransferRepo(t *testing.T) {
+	remote, cleanup1 := tmpDir(t)
+	defer cleanup1()
+
+	dir := remote
+	cmd := func(name string, arg ...string) string {
+		t.Helper()
+		c := exec.Command(name, arg...)
+		c.Dir = dir
+		c.Env = []string{
+			"GIT_COMMITTER_NAME=a",
+			"GIT_COMMITTER_EMAIL=a@a.com",
+			"GIT_AUTHOR_NAME=a",
+			"GIT_AUTHOR_EMAIL=a@a.com",
+		}
+		b, err := c.Output()
+		if err != nil {
+			t.Fatalf("%s %s failed: %s", name, strings.Join(arg, " "), err)
+		}
+		return string(b)
+	}
+
+	// Setup a repo with a commit so we can see if it is transferred.
+	cmd("git", "init", ".")
+	cmd("sh", "-c", "echo hello world > hello.txt")
+	cmd("git", "add", "hello.txt")
+	cmd("git", "commit", "-m", "hello")
+	wantCommit := cmd("git", "rev-parse", "HEAD")
+
+	srcDir, cleanup2 := tmpDir(t)
+	defer cleanup2()
+	dstDir, cleanup3 := tmpDir(t)
+	defer cleanup3()
+
+	src := &Server{ReposDir: srcDir}
+	srcServer := httptest.NewServer(src.Handler())
+	defer srcServer.Close()
+	defer src.Stop()
+
+	srcAddr := strings.TrimPrefix(srcServer.URL, "http://")
+
+	// The shard set changed from src to dst.
+	dst := &Server{ReposDir: dstDir, Addr: "dst"}
+	dst.setShards([]string{srcAddr}, "")
+	dstHandler := dst.Handler()
+	defer dst.Stop()
+	shardAddrs = func(context.Context) []string { return []string{"dst"} }
+	defer func() { shardAddrs = defaultShardAddrs }()
+
+	const repo = api.RepoName("example.com/foo/bar")
+	if _, err := src.cloneRepo(context.Background(), repo, remote, &cloneOptions{Block: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The receiving gitserver looks up the remote URL itself.
+	lookupRemoteURL = func(_ context.Context, name api.RepoName) (string, error) {
+		if name != repo {
+			t.Errorf("got remote URL lookup for %s, want %s", name, repo)
+		}
+		return remote, nil
+	}
+	defer func() { lookupRemoteURL = defaultLookupRemoteURL }()
+
+	acceptTransfer := func(req *protocol.RepoTransferRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		body, err := json.Marshal(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		dstHandler.ServeHTTP(rec, httptest.NewRequest("POST", "/accept-transfer", bytes.NewReader(body)))
+		return rec
+	}
+
+	// A transfer from a gitserver that is not in the shard set is rejected.
+	if rec := acceptTransfer(&protocol.RepoTransferRequest{Repo: repo, From: "evil.example.com:3178"}); rec.Code != http.StatusBadRequest {
+		t.Errorf("got status %d for a transfer from outside the shard set, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec := acceptTransfer(&protocol.RepoTransferRequest{Repo: repo, From: srcAddr})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("accept-transfer failed with status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	dir = filepath.Join(dstDir, string(repo), ".git")
+	if got := cmd("git", "rev-parse", "HEAD"); got != wantCommit {
+		t.Errorf("got commit %q, want %q", got, wantCommit)
+	}
+	if got := strings.TrimSpace(cmd("git", "config", "--get", "remote.origin.url")); got != remote {
+		t.Errorf("got remote URL %q, want %q", got, remote)
+	}
+
+	// A repository that is not cloned can't be transferred.
+	rec = httptest.NewRecorder()
+	dstHandler.ServeHTTP(rec, httptest.NewRequest("GET", "/transfer?repo=example.com/foo/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got status %d for a repo that is not cloned, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestAcceptTransfer_notOwner(t *testing.T) {
+	dir, cleanup := tmpDir(t)
+	defer cleanup()
+
+	s := &Server{ReposDir: dir, Addr: "gitserver-1:3178"}
+	h := s.Handler()
+	defer s.Stop()
+	shardAddrs = func(context.Context) []string { return []string{"gitserver-2:3178"} }
+	defer func() { shardAddrs = defaultShardAddrs }()
+
+	body, _ := json.Marshal(&protocol.RepoTransferRequest{Repo: "example.com/foo/bar", From: "gitserver-2:3178"})
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/accept-transfer", bytes.NewReader(body)))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("got status %d for a repository owned by another gitserver, want %d", rec.Code, http.StatusConflict)
+	}
+}
+
+func TestTransferRepo_self(t *testing.T) {
+	s := &Server{}
+	if err := s.transferRepo(context.Background(), "example.com/foo/bar", "gitserver-1:3178", "gitserver-1:3178"); err == nil {
+		t.Error("got nil error for a transfer to this gitserver")
+	}
+}
+
+func TestServer_selfAddr(t *testing.T) {
+	interfaceAddrs = func() ([]net.Addr, error) {
+		return []net.Addr{
+			&net.IPNet{IP: net.ParseIP("127.0.0.1"), Mask: net.CIDRMask(8, 32)},
+			&net.IPNet{IP: net.ParseIP("10.0.0.1"), Mask: net.CIDRMask(24, 32)},
+		}, nil
+	}
+	lookupIPAddr = func(_ context.Context, host string) ([]net.IPAddr, error) {
+		if ip := net.ParseIP(host); ip != nil {
+			return []net.IPAddr{{IP: ip}}, nil
+		}
+		switch host {
+		case "gitserver":
+			return []net.IPAddr{{IP: net.ParseIP("10.0.0.1")}}, nil
+		case "other":
+			return []net.IPAddr{{IP: net.ParseIP("10.0.0.2")}}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	defer func() {
+		interfaceAddrs = net.InterfaceAddrs
+		lookupIPAddr = net.DefaultResolver.LookupIPAddr
+	}()
+
+	for _, test := range []struct {
+		addr, hostname string
+		addrs          []string
+		want           string
+	}{
+		{hostname: "gitserver-1", addrs: []string{"gitserver-0:3178", "gitserver-1:3178"}, want: "gitserver-1:3178"},
+		{hostname: "gitserver-1", addrs: []string{"gitserver-10.gitserver:3178", "gitserver-1.gitserver:3178"}, want: "gitserver-1.gitserver:3178"},
+		{hostname: "gitserver-1", addrs: []string{"gitserver-2:3178"}, want: ""},
+		{hostname: "host", addrs: []string{"127.0.0.1:3178"}, want: "127.0.0.1:3178"},
+		{hostname: "host", addrs: []string{"other:3178", "gitserver:3178"}, want: "gitserver:3178"},
+		{hostname: "host", addrs: []string{"other:3178", "10.0.0.3:3178"}, want: ""},
+
+		// Several gitservers on this host can't be told apart.
+		{hostname: "host", addrs: []string{"127.0.0.1:3178", "127.0.0.1:3179"}, want: ""},
+		{addr: "127.0.0.1:3179", hostname: "host", addrs: []string{"127.0.0.1:3178", "127.0.0.1:3179"}, want: "127.0.0.1:3179"},
+		{addr: "gitserver-1:3178", hostname: "gitserver-1", addrs: []string{"gitserver-1.gitserver:3178"}, want: ""},
+	} {
+		s := &Server{Addr: test.addr, Hostname: test.hostname}
+		if got := s.selfAddr(context.Background(), test.addrs); got != test.want {
+			t.Errorf("%+v: got %q, want %q", test, got, test.want)
+		}
+	}
+}
+
+var (
+	defaultShardAddrs      = shardAddrs
+	defaultLookupRemoteURL = lookupRemoteURL
+)
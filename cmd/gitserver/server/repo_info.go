@@ -34,7 +34,7 @@ func (s *Server) handleRepoInfo(w http.ResponseWriter, r *http.Request) {
 		resp.URL = remoteURL
 	}
 	{
-		resp.CloneProgress, resp.CloneInProgress = s.locker.Status(dir)
+		resp.CloneProgress, resp.CloneInProgress = s.cloneStatus(dir)
 		if strings.ToLower(string(req.Repo)) == "github.com/sourcegraphtest/alwayscloningtest" {
 			resp.CloneInProgress = true
 			resp.CloneProgress = "This will never finish cloning"
@@ -58,6 +58,26 @@ func (s *Server) handleRepoInfo(w http.ResponseWriter, r *http.Request) {
 		} else {
 			resp.LastChanged = &lastChanged
 		}
+
+		resp.CloneMode = repoCloneMode(dir)
+
+		if size, err := s.repoSize(repo, filepath.Join(dir, ".git")); err != nil {
+			log15.Warn("error computing repo size", "repo", req.Repo, "err", err)
+		} else {
+			resp.Size = size
+		}
+
+		if pushMirrorURL := getPushMirrorURL(filepath.Join(dir, ".git")); pushMirrorURL != "" {
+			status, err := readPushMirrorStatus(filepath.Join(dir, ".git"))
+			if err != nil {
+				log15.Warn("error reading push mirror status", "repo", req.Repo, "err", err)
+			}
+			if status == nil {
+				// The repository has not been pushed yet.
+				status = &protocol.PushMirrorStatus{URL: redactURLCredentials(pushMirrorURL)}
+			}
+			resp.PushMirror = status
+		}
 	}
 
 	if err := json.NewEncoder(w).Encode(resp); err != nil {
@@ -98,5 +118,6 @@ func (s *Server) deleteRepo(repo api.RepoName) error {
 		}
 	}
 
+	s.forgetRepoSize(repo)
 	return s.removeRepoDirectory(dir)
 }
@@ -78,7 +78,16 @@ func TestServer_handleRepoInfo(t *testing.T) {
 		repoRemoteURL = func(context.Context, string) (string, error) { return "u", nil }
 		defer func() { repoRemoteURL = origRepoRemoteURL }()
 
-		if got, want := getRepoInfo(t, "x"), (protocol.RepoInfoResponse{Cloned: true, LastFetched: &lastFetched, LastChanged: &lastChanged, URL: "u"}); !reflect.DeepEqual(got, want) {
+		origRepoCloneMode := repoCloneMode
+		repoCloneMode = func(dir string) protocol.CloneMode { return protocol.CloneModeShallow }
+		defer func() { repoCloneMode = origRepoCloneMode }()
+
+		size := &protocol.RepoSize{PackBytes: 3, LooseBytes: 2, OtherBytes: 1, ComputedAt: lastFetched}
+		origComputeRepoSize := computeRepoSize
+		computeRepoSize = func(gitDir string) (*protocol.RepoSize, error) { return size, nil }
+		defer func() { computeRepoSize = origComputeRepoSize }()
+
+		if got, want := getRepoInfo(t, "x"), (protocol.RepoInfoResponse{Cloned: true, LastFetched: &lastFetched, LastChanged: &lastChanged, URL: "u", CloneMode: protocol.CloneModeShallow, Size: size}); !reflect.DeepEqual(got, want) {
 			t.Errorf("got %+v, want %+v", got, want)
 		}
 	})
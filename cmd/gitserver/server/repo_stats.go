@@ -0,0 +1,152 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+)
+
+// computeRepoSize returns the disk usage of the repository with GIT_DIR
+// gitDir. For repositories with the new style layout (gitDir is
+// `${dir}/.git`), files in `${dir}` outside of gitDir are counted as
+// worktree files.
+var computeRepoSize = func(gitDir string) (*protocol.RepoSize, error) {
+	size := &protocol.RepoSize{ComputedAt: time.Now()}
+	err := filepath.Walk(gitDir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// Files may be removed concurrently, for example by a repack.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(gitDir, path)
+		if err != nil {
+			return err
+		}
+		switch parts := strings.Split(filepath.ToSlash(rel), "/"); {
+		case len(parts) == 3 && parts[0] == "objects" && parts[1] == "pack":
+			size.PackBytes += fi.Size()
+		case len(parts) == 3 && parts[0] == "objects" && len(parts[1]) == 2:
+			size.LooseBytes += fi.Size()
+		case parts[0] == "worktrees":
+			size.WorktreeBytes += fi.Size()
+		default:
+			size.OtherBytes += fi.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.Base(gitDir) != ".git" {
+		return size, nil
+	}
+	err = filepath.Walk(filepath.Dir(gitDir), func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if path == gitDir {
+			return filepath.SkipDir
+		}
+		if !fi.IsDir() {
+			size.WorktreeBytes += fi.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return size, nil
+}
+
+// repoSize returns the disk usage of repo, whose GIT_DIR is gitDir. It
+// returns the size computed by the last janitor run if there is one.
+func (s *Server) repoSize(repo api.RepoName, gitDir string) (*protocol.RepoSize, error) {
+	s.repoSizesMu.Lock()
+	size, ok := s.repoSizes[repo]
+	s.repoSizesMu.Unlock()
+	if ok {
+		return size, nil
+	}
+
+	size, err := computeRepoSize(gitDir)
+	if err != nil {
+		return nil, err
+	}
+	s.repoSizesMu.Lock()
+	if s.repoSizes == nil {
+		s.repoSizes = make(map[api.RepoName]*protocol.RepoSize)
+	}
+	s.repoSizes[repo] = size
+	s.repoSizesMu.Unlock()
+	return size, nil
+}
+
+// setRepoSizes replaces the cached disk usage of all repos.
+func (s *Server) setRepoSizes(sizes map[api.RepoName]*protocol.RepoSize) {
+	s.repoSizesMu.Lock()
+	s.repoSizes = sizes
+	s.repoSizesMu.Unlock()
+}
+
+// forgetRepoSize removes the cached disk usage of repo, for example because it
+// was deleted.
+func (s *Server) forgetRepoSize(repo api.RepoName) {
+	s.repoSizesMu.Lock()
+	delete(s.repoSizes, repo)
+	s.repoSizesMu.Unlock()
+}
+
+// handleReposStats lists the disk usage of the repositories on this gitserver
+// as computed by the last janitor run, largest first. The optional limit
+// query parameter limits the number of repositories returned.
+func (s *Server) handleReposStats(w http.ResponseWriter, r *http.Request) {
+	limit := -1
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			http.Error(w, "invalid limit: "+v, http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.repoSizesMu.Lock()
+	resp := protocol.ReposStatsResponse{Repos: make([]*protocol.RepoStats, 0, len(s.repoSizes))}
+	for repo, size := range s.repoSizes {
+		resp.Repos = append(resp.Repos, &protocol.RepoStats{Repo: repo, Size: *size})
+	}
+	s.repoSizesMu.Unlock()
+
+	sort.Slice(resp.Repos, func(i, j int) bool {
+		if a, b := resp.Repos[i].Size.TotalBytes(), resp.Repos[j].Size.TotalBytes(); a != b {
+			return a > b
+		}
+		return resp.Repos[i].Repo < resp.Repos[j].Repo
+	})
+	if limit >= 0 && limit < len(resp.Repos) {
+		resp.Repos = resp.Repos[:limit]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
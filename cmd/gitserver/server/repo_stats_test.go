@@ -0,0 +1,102 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+)
+
+func TestComputeRepoSize(t *testing.T) {
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+
+	files := map[string]int{
+		"repo/.git/HEAD":                      1,
+		"repo/.git/config":                    2,
+		"repo/.git/objects/pack/pack-a.pack":  100,
+		"repo/.git/objects/pack/pack-a.idx":   10,
+		"repo/.git/objects/ab/cdef":           20,
+		"repo/.git/objects/ab/cdeg":           30,
+		"repo/.git/objects/info/commit-graph": 5,
+		"repo/.git/worktrees/wt/HEAD":         7,
+		"repo/README.md":                      40,
+		"repo/docs/index.md":                  50,
+	}
+	for name, size := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := computeRepoSize(filepath.Join(root, "repo", ".git"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := protocol.RepoSize{
+		PackBytes:     110,
+		LooseBytes:    50,
+		WorktreeBytes: 97,
+		OtherBytes:    8,
+		ComputedAt:    got.ComputedAt,
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("got %+v, want %+v", *got, want)
+	}
+	if got, want := got.TotalBytes(), int64(265); got != want {
+		t.Errorf("got total %d, want %d", got, want)
+	}
+}
+
+func TestServer_handleReposStats(t *testing.T) {
+	s := &Server{ReposDir: "/testroot"}
+	h := s.Handler()
+	s.setRepoSizes(map[api.RepoName]*protocol.RepoSize{
+		"a": {PackBytes: 10},
+		"b": {PackBytes: 30},
+		"c": {PackBytes: 20, LooseBytes: 5},
+	})
+
+	for _, tc := range []struct {
+		query string
+		want  []api.RepoName
+	}{
+		{"", []api.RepoName{"b", "c", "a"}},
+		{"?limit=2", []api.RepoName{"b", "c"}},
+		{"?limit=0", []api.RepoName{}},
+	} {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, httptest.NewRequest("GET", "/repos-stats"+tc.query, nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("%q: http non-200 status %d", tc.query, rr.Code)
+		}
+		var resp protocol.ReposStatsResponse
+		if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		got := []api.RepoName{}
+		for _, r := range resp.Repos {
+			got = append(got, r.Repo)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: got %v, want %v", tc.query, got, tc.want)
+		}
+	}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/repos-stats?limit=x", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("got status %d for an invalid limit, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
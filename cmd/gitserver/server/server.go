@@ -134,6 +134,41 @@ type Server struct {
 
 	repoUpdateLocksMu sync.Mutex // protects the map below and also updates to locks.once
 	repoUpdateLocks   map[api.RepoName]*locks
+
+	// Rebalance when true will transfer cloned repositories between
+	// gitservers when the shard set changes, instead of recloning them from
+	// the code host. See RunRebalancer.
+	Rebalance bool
+
+	// Addr is the address of this gitserver in the shard set (such as
+	// "gitserver-1:3178"). If it is empty, this gitserver is found in the
+	// shard set by Hostname and the IP addresses of this host when
+	// rebalancing (see selfAddr).
+	Addr string
+
+	// Hostname is the hostname of this gitserver.
+	Hostname string
+
+	shardsMu   sync.Mutex // protects the shard sets below
+	shards     []string   // the current shard set
+	self       string     // the address of this gitserver in shards, if it was found
+	prevShards []string   // the shard set before the last change
+	prevSelf   string     // the address of this gitserver in prevShards, if it was found
+
+	repoSizesMu sync.Mutex                          // protects the map below
+	repoSizes   map[api.RepoName]*protocol.RepoSize // cached disk usage of repos, see repoSize
+
+	// DesiredPercentFree is the percentage of the repos mount that should be
+	// free. When there is less free space, the least recently accessed
+	// repositories are evicted. 0 disables eviction. See
+	// RunDiskPressureMonitor.
+	DesiredPercentFree int
+
+	signingKeysMu  sync.RWMutex // protects signingKeysDir
+	signingKeysDir string       // trusted signing keys, see setTrustedSigningKeys
+
+	pushMirrorsMu sync.Mutex            // protects the map below
+	pushMirrors   map[api.RepoName]bool // repos being pushed to their push mirror, and whether to push again, see schedulePushMirror
 }
 
 type locks struct {
@@ -224,9 +259,13 @@ func (s *Server) Handler() http.Handler {
 	mux.HandleFunc("/is-repo-cloneable", s.handleIsRepoCloneable)
 	mux.HandleFunc("/is-repo-cloned", s.handleIsRepoCloned)
 	mux.HandleFunc("/repo", s.handleRepoInfo)
+	mux.HandleFunc("/repos-stats", s.handleReposStats)
 	mux.HandleFunc("/delete", s.handleRepoDelete)
 	mux.HandleFunc("/repo-update", s.handleRepoUpdate)
 	mux.HandleFunc("/upload-pack", s.handleUploadPack)
+	mux.HandleFunc("/lfs-object", s.handleLFSObject)
+	mux.HandleFunc("/transfer", s.handleTransfer)
+	mux.HandleFunc("/accept-transfer", s.handleAcceptTransfer)
 	mux.HandleFunc("/getGitolitePhabricatorMetadata", s.handleGetGitolitePhabricatorMetadata)
 	mux.HandleFunc("/create-commit-from-patch", s.handleCreateCommitFromPatch)
 	mux.HandleFunc("/ping", func(w http.ResponseWriter, _ *http.Request) {
@@ -401,7 +440,7 @@ func (s *Server) handleRepoUpdate(w http.ResponseWriter, r *http.Request) {
 		// optimistically, we assume that our cloning attempt might
 		// succeed.
 		resp.CloneInProgress = true
-		_, err := s.cloneRepo(ctx, req.Repo, req.URL, nil)
+		_, err := s.cloneRepo(ctx, req.Repo, req.URL, &cloneOptions{Options: req.CloneOptions, PushMirrorURL: req.PushMirrorURL})
 		if err != nil {
 			log15.Warn("error cloning repo", "repo", req.Repo, "err", err)
 			resp.Error = err.Error()
@@ -410,6 +449,10 @@ func (s *Server) handleRepoUpdate(w http.ResponseWriter, r *http.Request) {
 		resp.Cloned = true
 		var statusErr, updateErr error
 
+		if err := setPushMirrorURL(filepath.Join(dir, ".git"), req.PushMirrorURL); err != nil {
+			log15.Warn("failed to set push mirror", "repo", req.Repo, "error", err)
+		}
+
 		if debounce(req.Repo, req.Since) {
 			updateErr = s.doRepoUpdate(ctx, req.Repo, req.URL)
 		}
@@ -548,7 +591,7 @@ func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
 	}
 
 	dir := path.Join(s.ReposDir, string(req.Repo))
-	cloneProgress, cloneInProgress := s.locker.Status(dir)
+	cloneProgress, cloneInProgress := s.cloneStatus(dir)
 	if strings.ToLower(string(req.Repo)) == "github.com/sourcegraphtest/alwayscloningtest" {
 		cloneInProgress = true
 		cloneProgress = "This will never finish cloning"
@@ -586,6 +629,10 @@ func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := markRepoAccessed(dir); err != nil {
+		log15.Warn("failed to record repo access", "repo", req.Repo, "error", err)
+	}
+
 	didUpdate := s.ensureRevision(ctx, req.Repo, req.URL, req.EnsureRevision, dir)
 	if didUpdate {
 		ensureRevisionStatus = "fetched"
@@ -620,6 +667,8 @@ func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
 	cmd.Dir = dir
 	cmd.Stdout = stdoutW
 	cmd.Stderr = stderrW
+	allowLazyFetch(cmd, dir)
+	s.allowSignatureVerification(cmd)
 
 	var err error
 	exitStatus, err = runCommand(ctx, cmd)
@@ -664,6 +713,18 @@ func setGitAttributes(gitDir string) error {
 	return nil
 }
 
+// prepareClone finishes setting up a new clone at gitDir before it is moved
+// into place.
+func prepareClone(gitDir string) error {
+	// Update the last-changed stamp.
+	if err := setLastChanged(gitDir); err != nil {
+		return errors.Wrapf(err, "failed to update last changed time")
+	}
+
+	// Set gitattributes
+	return setGitAttributes(gitDir)
+}
+
 // cloneOptions specify optional behaviour for the cloneRepo function.
 type cloneOptions struct {
 	// Block will wait for the clone to finish before returning. If the clone
@@ -674,6 +735,25 @@ type cloneOptions struct {
 
 	// Overwrite will overwrite the existing clone.
 	Overwrite bool
+
+	// Options configures a shallow or partial clone. If nil, the repository
+	// is fully cloned.
+	Options *protocol.CloneOptions
+
+	// PushMirrorURL is the URL of the remote to push the repository to after
+	// it is cloned and updated, if any.
+	PushMirrorURL string
+}
+
+// cloneStatus returns the status of the clone of the repository in dir, and
+// whether it is in progress. A repository which is locked for maintenance
+// stays usable, so it is not reported as being cloned.
+func (s *Server) cloneStatus(dir string) (progress string, inProgress bool) {
+	progress, inProgress = s.locker.Status(dir)
+	if progress == maintenanceLockStatus {
+		return "", false
+	}
+	return progress, inProgress
 }
 
 // cloneRepo issues a git clone command for the given repo. It is
@@ -751,27 +831,68 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 		defer os.RemoveAll(tmpPath)
 		tmpPath = filepath.Join(tmpPath, ".git")
 
-		cmd := exec.CommandContext(ctx, "git", "clone", "--mirror", "--progress", url, tmpPath)
-		log15.Info("cloning repo", "repo", repo, "tmp", tmpPath, "dst", dstPath)
-
-		pr, pw := io.Pipe()
-		defer pw.Close()
-		go readCloneProgress(repo, url, lock, pr)
+		// If the repository was sharded to another gitserver before the
+		// shard set changed, that gitserver likely has a clone of it. Transfer
+		// it instead of recloning from the code host.
+		var cloneOpts *protocol.CloneOptions
+		if opts != nil {
+			cloneOpts = opts.Options
+		}
 
-		if output, err := s.runWithRemoteOpts(ctx, cmd, pw); err != nil {
-			return errors.Wrapf(err, "clone failed. Output: %s", string(output))
+		// Only full clones are transferred, since shallow and partial clones
+		// can't be bundled.
+		transferred := false
+		if peer := s.previousOwner(repo); s.Rebalance && peer != "" && cloneOpts == nil {
+			lock.SetStatus("transferring from " + peer)
+			if err := s.fetchTransfer(ctx, protocol.NormalizeRepo(repo), peer, url, tmpPath); err != nil {
+				log15.Warn("failed to transfer repo from previous gitserver, cloning instead", "repo", repo, "from", peer, "error", err)
+				if err := os.RemoveAll(tmpPath); err != nil {
+					return err
+				}
+			} else {
+				transferred = true
+			}
 		}
 
-		// Update the last-changed stamp.
-		if err := setLastChanged(tmpPath); err != nil {
-			return errors.Wrapf(err, "failed to update last changed time")
+		if !transferred {
+			args := append([]string{"clone", "--mirror", "--progress"}, cloneArgs(cloneOpts)...)
+			cmd := exec.CommandContext(ctx, "git", append(args, url, tmpPath)...)
+			log15.Info("cloning repo", "repo", repo, "tmp", tmpPath, "dst", dstPath)
+
+			pr, pw := io.Pipe()
+			defer pw.Close()
+			go readCloneProgress(repo, url, lock, pr)
+
+			if output, err := s.runWithRemoteOpts(ctx, cmd, pw); err != nil {
+				return errors.Wrapf(err, "clone failed. Output: %s", string(output))
+			}
+
+			if err := setCloneOptions(tmpPath, cloneOpts); err != nil {
+				return err
+			}
+			if cloneOpts != nil && len(cloneOpts.SparsePaths) > 0 {
+				if err := s.prefetchSparsePaths(ctx, tmpPath, url, cloneOpts.SparsePaths); err != nil {
+					// The blobs are fetched lazily instead.
+					log15.Warn("failed to fetch sparse paths", "repo", repo, "error", err)
+				}
+			}
 		}
 
-		// Set gitattributes
-		if err := setGitAttributes(tmpPath); err != nil {
+		if err := prepareClone(tmpPath); err != nil {
 			return err
 		}
 
+		if opts != nil {
+			if err := setPushMirrorURL(tmpPath, opts.PushMirrorURL); err != nil {
+				return err
+			}
+		}
+
+		if err := s.fetchLFSObjects(ctx, tmpPath, url); err != nil {
+			// The LFS objects are fetched when they are requested instead.
+			log15.Warn("failed to fetch LFS objects", "repo", repo, "error", err)
+		}
+
 		if overwrite {
 			// remove the current repo by putting it into our temporary directory
 			err := os.Rename(dstPath, filepath.Join(filepath.Dir(tmpPath), "old"))
@@ -790,6 +911,8 @@ func (s *Server) cloneRepo(ctx context.Context, repo api.RepoName, url string, o
 		log15.Info("repo cloned", "repo", repo)
 		repoClonedCounter.Inc()
 
+		s.schedulePushMirror(repo)
+
 		return nil
 	}
 
@@ -978,18 +1101,7 @@ func (s *Server) doRepoUpdate(ctx context.Context, repo api.RepoName, url string
 	span.SetTag("url", url)
 	defer span.Finish()
 
-	s.repoUpdateLocksMu.Lock()
-	l, ok := s.repoUpdateLocks[repo]
-	if !ok {
-		l = &locks{
-			once: new(sync.Once),
-			mu:   new(sync.Mutex),
-		}
-		s.repoUpdateLocks[repo] = l
-	}
-	once := l.once
-	mu := l.mu
-	s.repoUpdateLocksMu.Unlock()
+	l, once, mu := s.repoUpdateLock(repo)
 
 	// doRepoUpdate2 can block longer than our context deadline. done will
 	// close when its done. We can return when either done is closed or our
@@ -1019,6 +1131,22 @@ func (s *Server) doRepoUpdate(ctx context.Context, repo api.RepoName, url string
 	}
 }
 
+// repoUpdateLock returns the locks which serialize updates of repo, and the
+// sync.Once the next update waits on.
+func (s *Server) repoUpdateLock(repo api.RepoName) (l *locks, once *sync.Once, mu *sync.Mutex) {
+	s.repoUpdateLocksMu.Lock()
+	defer s.repoUpdateLocksMu.Unlock()
+	l, ok := s.repoUpdateLocks[repo]
+	if !ok {
+		l = &locks{
+			once: new(sync.Once),
+			mu:   new(sync.Mutex),
+		}
+		s.repoUpdateLocks[repo] = l
+	}
+	return l, l.once, l.mu
+}
+
 // setLastChanged discerns an approximate last-changed timestamp for a
 // repository. This can be approximate; it's used to determine how often we
 // should run `git fetch`, but is not relied on strongly. The basic plan
@@ -1197,7 +1325,18 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 		}
 	}
 
-	cmd := exec.CommandContext(ctx, "git", "fetch", "--prune", url, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*", "+refs/pull/*:refs/pull/*")
+	cloneOpts, err := getCloneOptions(dir)
+	if err != nil {
+		log15.Warn("Failed to determine clone options", "repo", repo, "error", err)
+	}
+
+	// Keep omitting the blobs that a partial clone omitted.
+	args := []string{"fetch", "--prune"}
+	if filter := blobFilter(cloneOpts); filter != "" {
+		args = append(args, "--filter="+filter)
+	}
+	args = append(args, url, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*", "+refs/pull/*:refs/pull/*")
+	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Dir = dir
 
 	// drop temporary pack files after a fetch. this function won't
@@ -1260,6 +1399,20 @@ func (s *Server) doRepoUpdate2(repo api.RepoName, url string) error {
 		log15.Error("Failed to set HEAD", "repo", repo, "error", err, "output", string(output))
 		return errors.Wrap(err, "Failed to set HEAD")
 	}
+
+	if cloneOpts != nil && len(cloneOpts.SparsePaths) > 0 {
+		if err := s.prefetchSparsePaths(ctx, dir, url, cloneOpts.SparsePaths); err != nil {
+			// The blobs are fetched lazily instead.
+			log15.Warn("Failed to fetch sparse paths", "repo", repo, "error", err)
+		}
+	}
+
+	if err := s.fetchLFSObjects(ctx, filepath.Join(dir, ".git"), url); err != nil {
+		// The LFS objects are fetched when they are requested instead.
+		log15.Warn("Failed to fetch LFS objects", "repo", repo, "error", err)
+	}
+
+	s.schedulePushMirror(repo)
 	return nil
 }
 
@@ -1279,6 +1432,20 @@ func (s *Server) ensureRevision(ctx context.Context, repo api.RepoName, url, rev
 	}
 	// Revision not found, update before returning.
 	s.doRepoUpdate(ctx, repo, url)
+
+	// An update doesn't fetch the commits that a shallow clone omitted.
+	if git.IsAbsoluteRevision(strings.TrimSuffix(rev, "^0")) && repoIsShallow(repoDir) {
+		cmd := exec.Command("git", "rev-parse", rev, "--")
+		cmd.Dir = repoDir
+		if err := cmd.Run(); err != nil {
+			if url == "" {
+				url, _ = repoRemoteURL(ctx, repoDir)
+			}
+			if err := s.fetchCommit(ctx, repoDir, url, strings.TrimSuffix(rev, "^0")); err != nil {
+				log15.Warn("failed to fetch commit omitted by shallow clone", "repo", repo, "commit", rev, "error", err)
+			}
+		}
+	}
 	return true
 }
 
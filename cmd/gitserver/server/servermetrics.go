@@ -9,6 +9,47 @@ import (
 	"gopkg.in/inconshreveable/log15.v2"
 )
 
+var (
+	rebalancePending = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "rebalance_pending",
+		Help:      "number of repos waiting to be transferred to another gitserver after the shard set changed.",
+	})
+	rebalanceTransfers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "rebalance_transfers",
+		Help:      "number of repos this gitserver transferred to another gitserver after the shard set changed.",
+	}, []string{"result"})
+	transfersReceived = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "transfers_received",
+		Help:      "number of repos this gitserver received from another gitserver instead of cloning them.",
+	}, []string{"result"})
+	transferBytesSent = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "transfer_bytes_sent",
+		Help:      "number of bytes of repos sent to other gitservers.",
+	})
+	transferBytesReceived = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "gitserver",
+		Name:      "transfer_bytes_received",
+		Help:      "number of bytes of repos received from other gitservers.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(rebalancePending)
+	prometheus.MustRegister(rebalanceTransfers)
+	prometheus.MustRegister(transfersReceived)
+	prometheus.MustRegister(transferBytesSent)
+	prometheus.MustRegister(transferBytesReceived)
+}
+
 func (s *Server) RegisterMetrics() {
 	// test the latency of exec, which may increase under certain memory
 	// conditions
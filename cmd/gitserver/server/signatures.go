@@ -0,0 +1,119 @@
+package server
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"golang.org/x/crypto/ssh"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Commit signatures
+//
+// git verifies commit signatures (e.g. for `git log --format=%G?`) with gpg
+// for GPG signatures and with ssh-keygen for SSH signatures. gitserver sets up
+// both to trust exactly the keys in the gitTrustedSigningKeys site
+// configuration: GPG keys are imported into a keyring of our own, which
+// trusts all of its keys, and SSH keys are written to an allowed signers file.
+// Both are rebuilt in a new directory whenever the trusted keys change, so
+// that commands never see a half-written keyring.
+
+// WatchTrustedSigningKeys keeps the keys that commit signatures are verified
+// against up to date with the site configuration. It must be called after
+// SetupAndClearTmp, since the keys are stored in the temporary directory.
+func (s *Server) WatchTrustedSigningKeys() {
+	var (
+		current []string
+		set     bool
+	)
+	conf.Watch(func() {
+		keys := conf.Get().GitTrustedSigningKeys
+		if set && reflect.DeepEqual(keys, current) {
+			return
+		}
+		if err := s.setTrustedSigningKeys(keys); err != nil {
+			log15.Error("failed to set up trusted signing keys", "error", err)
+			return
+		}
+		current, set = append([]string(nil), keys...), true
+	})
+}
+
+// setTrustedSigningKeys sets up the keys that commit signatures are verified
+// against. Keys which can't be parsed are logged and skipped.
+func (s *Server) setTrustedSigningKeys(keys []string) error {
+	dir, err := s.tempDir("signing-keys")
+	if err != nil {
+		return err
+	}
+	gnupgHome := filepath.Join(dir, "gnupg")
+	if err := os.Mkdir(gnupgHome, 0700); err != nil {
+		return err
+	}
+	// The keyring only contains trusted keys, so gpg does not need a web of
+	// trust to consider them valid.
+	if err := ioutil.WriteFile(filepath.Join(gnupgHome, "gpg.conf"), []byte("trust-model always\n"), 0600); err != nil {
+		return err
+	}
+
+	var allowedSigners bytes.Buffer
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if strings.HasPrefix(key, "-----BEGIN PGP PUBLIC KEY BLOCK-----") {
+			cmd := exec.Command("gpg", "--batch", "--no-autostart", "--import")
+			cmd.Env = append(os.Environ(), "GNUPGHOME="+gnupgHome)
+			cmd.Stdin = strings.NewReader(key)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				log15.Error("failed to import trusted GPG signing key", "error", err, "output", string(out))
+			}
+			continue
+		}
+
+		pub, _, _, rest, err := ssh.ParseAuthorizedKey([]byte(key))
+		if err != nil || len(bytes.TrimSpace(rest)) > 0 {
+			log15.Error("ignoring trusted signing key which is neither an ASCII-armored GPG public key nor an SSH public key", "key", key)
+			continue
+		}
+		// SSH keys are trusted no matter which identity they sign for.
+		allowedSigners.WriteString("* ")
+		allowedSigners.Write(ssh.MarshalAuthorizedKey(pub))
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "allowed_signers"), allowedSigners.Bytes(), 0600); err != nil {
+		return err
+	}
+
+	s.signingKeysMu.Lock()
+	old := s.signingKeysDir
+	s.signingKeysDir = dir
+	s.signingKeysMu.Unlock()
+
+	if old != "" {
+		if err := os.RemoveAll(old); err != nil {
+			log15.Warn("failed to remove old trusted signing keys", "dir", old, "error", err)
+		}
+	}
+	return nil
+}
+
+// allowSignatureVerification prepares cmd, a git command, to verify commit
+// signatures against the trusted signing keys.
+func (s *Server) allowSignatureVerification(cmd *exec.Cmd) {
+	s.signingKeysMu.RLock()
+	dir := s.signingKeysDir
+	s.signingKeysMu.RUnlock()
+	if dir == "" {
+		return
+	}
+
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, "GNUPGHOME="+filepath.Join(dir, "gnupg"))
+	cmd.Args = append([]string{cmd.Args[0], "-c", "gpg.ssh.allowedSignersFile=" + filepath.Join(dir, "allowed_signers")}, cmd.Args[1:]...)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTrustedSigningKeys(t *testing.T) {
+	for _, name := range []string{"gpg", "ssh-keygen"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s is not installed", name)
+		}
+	}
+
+	root, cleanup := tmpDir(t)
+	defer cleanup()
+
+	run := func(env []string, name string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(name, args...)
+		cmd.Env = append(os.Environ(), env...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s %s failed: %s: %s", name, strings.Join(args, " "), err, out)
+		}
+		return string(out)
+	}
+
+	// Create the signing keys of the committers. Alice's keys are trusted,
+	// Bob's are not.
+	signerEnv := []string{"GNUPGHOME=" + filepath.Join(root, "signer")}
+	if err := os.Mkdir(filepath.Join(root, "signer"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		cmd := exec.Command("gpgconf", "--kill", "gpg-agent")
+		cmd.Env = append(os.Environ(), signerEnv...)
+		cmd.Run()
+	}()
+	for _, uid := range []string{"alice@example.com", "bob@example.com"} {
+		run(signerEnv, "gpg", "--batch", "--passphrase", "", "--quick-gen-key", uid, "ed25519", "sign", "never")
+		run(nil, "ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", filepath.Join(root, uid))
+	}
+	trustedGPGKey := run(signerEnv, "gpg", "--armor", "--export", "alice@example.com")
+	trustedSSHKey, err := ioutil.ReadFile(filepath.Join(root, "alice@example.com.pub"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := filepath.Join(root, "repo")
+	run(nil, "git", "init", repo)
+	commit := func(msg string, config ...string) {
+		args := append([]string{"-C", repo, "-c", "user.name=a", "-c", "user.email=a@a.com"}, config...)
+		run(signerEnv, "git", append(args, "commit", "--allow-empty", "-m", msg)...)
+	}
+	commit("unsigned")
+	for name, uid := range map[string]string{"trusted": "alice@example.com", "untrusted": "bob@example.com"} {
+		commit("gpg-"+name, "-c", "user.signingkey="+uid, "-c", "commit.gpgsign=true")
+		commit("ssh-"+name, "-c", "gpg.format=ssh", "-c", "user.signingkey="+filepath.Join(root, uid+".pub"), "-c", "commit.gpgsign=true")
+	}
+
+	s := &Server{ReposDir: filepath.Join(root, "repos")}
+	if err := s.setTrustedSigningKeys([]string{trustedGPGKey, string(trustedSSHKey), "not a key"}); err != nil {
+		t.Fatal(err)
+	}
+	oldDir := s.signingKeysDir
+
+	signatures := func() map[string]string {
+		t.Helper()
+		cmd := exec.Command("git", "log", "--format=%s %G?")
+		cmd.Dir = repo
+		s.allowSignatureVerification(cmd)
+		out, err := cmd.Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]string{}
+		for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+			fields := strings.Fields(line)
+			got[fields[0]] = fields[1]
+		}
+		return got
+	}
+	want := map[string]string{
+		"unsigned":      "N",
+		"gpg-trusted":   "G",
+		"gpg-untrusted": "E",
+		"ssh-trusted":   "G",
+		"ssh-untrusted": "U",
+	}
+	if got := signatures(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got signatures %v, want %v", got, want)
+	}
+
+	// Changing the trusted keys replaces the old ones.
+	if err := s.setTrustedSigningKeys(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(oldDir); !os.IsNotExist(err) {
+		t.Errorf("expected old trusted signing keys to be removed, got error %v", err)
+	}
+	want["gpg-trusted"] = "E"
+	want["ssh-trusted"] = "U"
+	if got := signatures(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got signatures %v without trusted keys, want %v", got, want)
+	}
+}
@@ -85,6 +85,10 @@ func main() {
 	// Repos purging thread
 	go repos.RunRepositoryPurgeWorker(ctx)
 
+	// User permissions syncing thread
+	go repos.RunUserPermissionsSyncWorker(ctx)
+	go repos.RunNewRepoPermissionsSyncWorker(ctx)
+
 	// GitHub connections and repos syncing threads
 	go repos.SyncGitHubConnections(ctx)
 	go repos.RunGitHubRepositorySyncWorker(ctx)
@@ -107,6 +111,18 @@ func main() {
 	go repos.SyncBitbucketServerConnections(ctx)
 	go repos.RunBitbucketServerRepositorySyncWorker(ctx)
 
+	// Bitbucket Cloud connections and repos syncing threads
+	go repos.SyncBitbucketCloudConnections(ctx)
+	go repos.RunBitbucketCloudRepositorySyncWorker(ctx)
+
+	// Gitea connections and repos syncing threads
+	go repos.SyncGiteaConnections(ctx)
+	go repos.RunGiteaRepositorySyncWorker(ctx)
+
+	// Gerrit connections and projects syncing threads
+	go repos.SyncGerritConnections(ctx)
+	go repos.RunGerritRepositorySyncWorker(ctx)
+
 	// Start other repos syncer syncing thread
 	go func() { log.Fatal(syncer.Run(ctx, repos.GetUpdateInterval())) }()
 
@@ -19,7 +19,6 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/atomicvalue"
-	"github.com/sourcegraph/sourcegraph/pkg/conf"
 	"github.com/sourcegraph/sourcegraph/pkg/conf/reposource"
 	"github.com/sourcegraph/sourcegraph/pkg/extsvc/awscodecommit"
 	"github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
@@ -39,26 +38,31 @@ var awsCodeCommitConnections = func() *atomicvalue.Value {
 // the Frontend API.
 func SyncAWSCodeCommitConnections(ctx context.Context) {
 	t := time.NewTicker(configWatchInterval)
-	var lastConfig []*schema.AWSCodeCommitConnection
+	var (
+		lastConfig []*schema.AWSCodeCommitConnection
+		lastIDs    []int64
+	)
 	for range t.C {
-		config, err := conf.AWSCodeCommitConfigs(ctx)
+		var config []*schema.AWSCodeCommitConnection
+		ids, err := externalServiceConfigs(ctx, "AWSCODECOMMIT", &config)
 		if err != nil {
 			log15.Error("unable to fetch AWS CodeCommit configs", "err", err)
 			continue
 		}
 
-		if reflect.DeepEqual(config, lastConfig) {
+		if reflect.DeepEqual(config, lastConfig) && reflect.DeepEqual(ids, lastIDs) {
 			continue
 		}
-		lastConfig = config
+		lastConfig, lastIDs = config, ids
 
 		var conns []*awsCodeCommitConnection
-		for _, c := range config {
+		for i, c := range config {
 			conn, err := newAWSCodeCommitConnection(c)
 			if err != nil {
 				log15.Error("Error processing configured AWS CodeCommit connection. Skipping it.", "region", c.Region, "error", err)
 				continue
 			}
+			conn.externalServiceID = ids[i]
 			conns = append(conns, conn)
 		}
 
@@ -179,7 +183,7 @@ func updateAWSCodeCommitRepositories(ctx context.Context, conn *awsCodeCommitCon
 
 	repoChan := make(chan repoCreateOrUpdateRequest)
 	defer close(repoChan)
-	go createEnableUpdateRepos(ctx, fmt.Sprintf("aws:%s", conn.config.AccessKeyID), repoChan)
+	go createEnableUpdateRepos(ctx, fmt.Sprintf("aws:%s", conn.config.AccessKeyID), conn.externalServiceID, conn.config.UpdateLimits, conn.config.CloneOptions, conn.config.PushMirror, repoChan)
 	for repo := range repos {
 		// log15.Debug("awscodecommit sync: create/enable/update repo", "repo", repo.Name)
 		remoteURL, err := conn.authenticatedRemoteURL(repo)
@@ -228,11 +232,12 @@ func newAWSCodeCommitConnection(config *schema.AWSCodeCommitConnection) (*awsCod
 }
 
 type awsCodeCommitConnection struct {
-	config       *schema.AWSCodeCommitConnection
-	awsConfig    aws.Config
-	awsPartition endpoints.Partition // "aws", "aws-cn", "aws-us-gov"
-	awsRegion    endpoints.Region
-	client       *awscodecommit.Client
+	config            *schema.AWSCodeCommitConnection
+	externalServiceID int64 // the ID of the external service whose config this is
+	awsConfig         aws.Config
+	awsPartition      endpoints.Partition // "aws", "aws-cn", "aws-us-gov"
+	awsRegion         endpoints.Region
+	client            *awscodecommit.Client
 
 	mu           sync.Mutex
 	awsAccountID string
@@ -0,0 +1,326 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/atomicvalue"
+	"github.com/sourcegraph/sourcegraph/pkg/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/bitbucketcloud"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
+	"github.com/sourcegraph/sourcegraph/schema"
+	"golang.org/x/time/rate"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+var bitbucketCloudConnections = func() *atomicvalue.Value {
+	c := atomicvalue.New()
+	c.Set(func() interface{} {
+		return []*bitbucketCloudConnection{}
+	})
+	return c
+}()
+
+// SyncBitbucketCloudConnections periodically syncs connections from
+// the Frontend API.
+func SyncBitbucketCloudConnections(ctx context.Context) {
+	t := time.NewTicker(configWatchInterval)
+	var (
+		lastConfig []*schema.BitbucketCloudConnection
+		lastIDs    []int64
+	)
+	for range t.C {
+		var config []*schema.BitbucketCloudConnection
+		ids, err := externalServiceConfigs(ctx, "BITBUCKETCLOUD", &config)
+		if err != nil {
+			log15.Error("unable to fetch Bitbucket Cloud configs", "err", err)
+			continue
+		}
+
+		if reflect.DeepEqual(config, lastConfig) && reflect.DeepEqual(ids, lastIDs) {
+			continue
+		}
+		lastConfig, lastIDs = config, ids
+
+		var conns []*bitbucketCloudConnection
+		for i, c := range config {
+			conn, err := newBitbucketCloudConnection(c)
+			if err != nil {
+				log15.Error("Error processing configured Bitbucket Cloud connection. Skipping it.", "url", c.Url, "error", err)
+				continue
+			}
+			conn.externalServiceID = ids[i]
+			conns = append(conns, conn)
+		}
+
+		bitbucketCloudConnections.Set(func() interface{} {
+			return conns
+		})
+
+		bitbucketCloudWorker.restart()
+	}
+}
+
+// getBitbucketCloudConnection returns the Bitbucket Cloud connection (config + API client) that is
+// responsible for the repository specified by the args.
+func getBitbucketCloudConnection(args protocol.RepoLookupArgs) (*bitbucketCloudConnection, error) {
+	conns := bitbucketCloudConnections.Get().([]*bitbucketCloudConnection)
+
+	if args.ExternalRepo != nil && args.ExternalRepo.ServiceType == bitbucketcloud.ServiceType {
+		// Look up by external repository spec.
+		for _, conn := range conns {
+			if args.ExternalRepo.ServiceID == conn.baseURL.String() {
+				return conn, nil
+			}
+		}
+		return nil, errors.Errorf("no configured Bitbucket Cloud connection with URL: %q", args.ExternalRepo.ServiceID)
+	}
+
+	if args.Repo != "" {
+		// Look up by repository name.
+		for _, conn := range conns {
+			if _, ok := conn.nameWithOwner(args.Repo); ok {
+				return conn, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func bitbucketCloudRepoInfo(config *schema.BitbucketCloudConnection, repo *bitbucketcloud.Repo) *protocol.RepoInfo {
+	host, err := bitbucketCloudBaseURL(config)
+	if err != nil {
+		// This should never happen
+		log15.Error("malformed Bitbucket Cloud config, invalid URL", "url", config.Url, "error", err)
+		return nil
+	}
+
+	// Clone URL
+	var cloneURL string
+	for _, l := range repo.Links.Clone {
+		if l.Name == "ssh" && config.GitURLType == "ssh" {
+			cloneURL = l.Href
+			break
+		}
+		if l.Name == "https" {
+			cloneURL = setUserinfoBestEffort(l.Href, config.Username, config.AppPassword)
+			// No break, so that we fallback to https in case of ssh missing
+			// with GitURLType == "ssh"
+		}
+	}
+
+	// Repo Links
+	var links *protocol.RepoLinks
+	if root := repo.Links.HTML.Href; root != "" {
+		links = &protocol.RepoLinks{
+			Root:   root,
+			Tree:   root + "/src/{rev}/{path}",
+			Blob:   root + "/src/{rev}/{path}",
+			Commit: root + "/commits/{commit}",
+		}
+	}
+
+	return &protocol.RepoInfo{
+		Name: reposource.BitbucketCloudRepoName(config.RepositoryPathPattern, host.Hostname(), repo.FullName),
+		ExternalRepo: &api.ExternalRepoSpec{
+			ID:          repo.UUID,
+			ServiceType: bitbucketcloud.ServiceType,
+			ServiceID:   host.String(),
+		},
+		Description: repo.Description,
+		Fork:        repo.Parent != nil,
+		VCS: protocol.VCSInfo{
+			URL: cloneURL,
+		},
+		Links: links,
+	}
+}
+
+// GetBitbucketCloudRepository queries a configured Bitbucket Cloud connection for information about
+// the specified repository.
+//
+// If args.Repo refers to a repository that is not known to be on a configured Bitbucket Cloud
+// connection's host, it returns authoritative == false.
+func GetBitbucketCloudRepository(ctx context.Context, args protocol.RepoLookupArgs) (repo *protocol.RepoInfo, authoritative bool, err error) {
+	conn, err := getBitbucketCloudConnection(args)
+	if err != nil {
+		return nil, true, err // refers to a Bitbucket Cloud repo but the host is not configured
+	}
+	if conn == nil {
+		return nil, false, nil // refers to a non-Bitbucket Cloud repo
+	}
+
+	var fullName string
+	if args.ExternalRepo != nil && args.ExternalRepo.ServiceType == bitbucketcloud.ServiceType {
+		// Look up by external repository spec. The "{}" placeholder lets the API look up a
+		// repository by its UUID alone, without the owner.
+		fullName = "{}/" + args.ExternalRepo.ID
+	} else if args.Repo != "" {
+		// Look up by repository name.
+		fullName, _ = conn.nameWithOwner(args.Repo)
+	} else {
+		return nil, true, fmt.Errorf("unable to look up Bitbucket Cloud repository (%+v)", args)
+	}
+
+	r, err := conn.client.Repo(ctx, fullName)
+	if err != nil {
+		return nil, true, err
+	}
+	if r.SCM != "git" {
+		return nil, true, errors.Errorf("Bitbucket Cloud repository %q is not a Git repository", r.FullName)
+	}
+	return bitbucketCloudRepoInfo(conn.config, r), true, nil
+}
+
+var bitbucketCloudWorker = &worker{
+	work: func(ctx context.Context, shutdown chan struct{}) {
+		for _, c := range bitbucketCloudConnections.Get().([]*bitbucketCloudConnection) {
+			go func(c *bitbucketCloudConnection) {
+				for {
+					updateBitbucketCloudRepos(ctx, c)
+					bitbucketCloudUpdateTime.WithLabelValues(c.baseURL.String()).Set(float64(time.Now().Unix()))
+					select {
+					case <-shutdown:
+						return
+					case <-time.After(GetUpdateInterval()):
+					}
+				}
+			}(c)
+		}
+	},
+}
+
+// RunBitbucketCloudRepositorySyncWorker runs the worker that syncs repositories from configured
+// Bitbucket Cloud connections to Sourcegraph.
+func RunBitbucketCloudRepositorySyncWorker(ctx context.Context) {
+	bitbucketCloudWorker.start(ctx)
+}
+
+// updateBitbucketCloudRepos ensures that all provided repositories exist in the repository table.
+func updateBitbucketCloudRepos(ctx context.Context, conn *bitbucketCloudConnection) {
+	repoChan := make(chan repoCreateOrUpdateRequest)
+	defer close(repoChan)
+	go createEnableUpdateRepos(ctx, fmt.Sprintf("bitbucketcloud:%s", conn.config.Username), conn.externalServiceID, conn.config.UpdateLimits, conn.config.CloneOptions, conn.config.PushMirror, repoChan)
+	for r := range conn.listAllRepos(ctx) {
+		if r.SCM != "git" {
+			continue // Mercurial repositories are not supported
+		}
+
+		ri := bitbucketCloudRepoInfo(conn.config, r)
+		if ri == nil || ri.VCS.URL == "" {
+			continue
+		}
+
+		repoChan <- repoCreateOrUpdateRequest{
+			RepoCreateOrUpdateRequest: api.RepoCreateOrUpdateRequest{
+				RepoName:     ri.Name,
+				ExternalRepo: ri.ExternalRepo,
+				Description:  ri.Description,
+				Fork:         ri.Fork,
+				Enabled:      conn.config.InitialRepositoryEnablement,
+			},
+			URL: ri.VCS.URL,
+		}
+	}
+}
+
+// bitbucketCloudBaseURL returns the normalized web URL of the Bitbucket Cloud connection, which is
+// the ServiceID of its repositories.
+func bitbucketCloudBaseURL(config *schema.BitbucketCloudConnection) (*url.URL, error) {
+	rawurl := config.Url
+	if rawurl == "" {
+		rawurl = "https://bitbucket.org"
+	}
+	baseURL, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return NormalizeBaseURL(baseURL), nil
+}
+
+func newBitbucketCloudConnection(config *schema.BitbucketCloudConnection) (*bitbucketCloudConnection, error) {
+	baseURL, err := bitbucketCloudBaseURL(config)
+	if err != nil {
+		return nil, err
+	}
+
+	rawAPIURL := config.ApiURL
+	if rawAPIURL == "" {
+		rawAPIURL = "https://api.bitbucket.org"
+	}
+	apiURL, err := url.Parse(rawAPIURL)
+	if err != nil {
+		return nil, err
+	}
+	apiURL = NormalizeBaseURL(apiURL)
+
+	transport, err := cachedTransportWithCertTrusted("")
+	if err != nil {
+		return nil, err
+	}
+
+	return &bitbucketCloudConnection{
+		config:  config,
+		baseURL: baseURL,
+		client: &bitbucketcloud.Client{
+			URL:         apiURL,
+			Username:    config.Username,
+			AppPassword: config.AppPassword,
+			HTTPClient: &http.Client{
+				Transport: bitbucketcloud.WithRequestCounter(transport),
+			},
+			RateLimit: rate.NewLimiter(rateLimitRequestsPerSecond, rateLimitMaxBurstRequests),
+		},
+	}, nil
+}
+
+type bitbucketCloudConnection struct {
+	config            *schema.BitbucketCloudConnection
+	externalServiceID int64 // the ID of the external service whose config this is
+	baseURL           *url.URL
+	client            *bitbucketcloud.Client
+}
+
+// nameWithOwner returns the "owner/slug" of the Bitbucket Cloud repository with the given
+// Sourcegraph repository name, if the name belongs to this connection.
+func (c *bitbucketCloudConnection) nameWithOwner(repo api.RepoName) (string, bool) {
+	pattern := c.config.RepositoryPathPattern
+	if pattern == "" {
+		pattern = "{host}/{nameWithOwner}"
+	}
+	return trimRepositoryPathPattern(repo, pattern, "{nameWithOwner}", c.baseURL.Hostname())
+}
+
+func (c *bitbucketCloudConnection) listAllRepos(ctx context.Context) <-chan *bitbucketcloud.Repo {
+	perPage := 100
+	ch := make(chan *bitbucketcloud.Repo, perPage)
+	go func() {
+		defer close(ch)
+
+		page := &bitbucketcloud.PageToken{Pagelen: perPage}
+		for {
+			var repos []*bitbucketcloud.Repo
+			var err error
+			repos, page, err = c.client.Repos(ctx, page)
+			if err != nil {
+				log15.Error("failed when listing Bitbucket Cloud repos", "url", c.client.URL, "error", err)
+				return
+			}
+			for _, r := range repos {
+				ch <- r
+			}
+			if !page.HasMore() {
+				return
+			}
+		}
+	}()
+
+	return ch
+}
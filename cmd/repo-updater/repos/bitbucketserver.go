@@ -13,7 +13,6 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/atomicvalue"
-	"github.com/sourcegraph/sourcegraph/pkg/conf"
 	"github.com/sourcegraph/sourcegraph/pkg/conf/reposource"
 	"github.com/sourcegraph/sourcegraph/pkg/extsvc/bitbucketserver"
 	"github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
@@ -35,26 +34,31 @@ var bitbucketServerConnections = func() *atomicvalue.Value {
 // the Frontend API.
 func SyncBitbucketServerConnections(ctx context.Context) {
 	t := time.NewTicker(configWatchInterval)
-	var lastConfig []*schema.BitbucketServerConnection
+	var (
+		lastConfig []*schema.BitbucketServerConnection
+		lastIDs    []int64
+	)
 	for range t.C {
-		config, err := conf.BitbucketServerConfigs(ctx)
+		var config []*schema.BitbucketServerConnection
+		ids, err := externalServiceConfigs(ctx, "BITBUCKETSERVER", &config)
 		if err != nil {
 			log15.Error("unable to fetch Bitbucket Server configs", "err", err)
 			continue
 		}
 
-		if reflect.DeepEqual(config, lastConfig) {
+		if reflect.DeepEqual(config, lastConfig) && reflect.DeepEqual(ids, lastIDs) {
 			continue
 		}
-		lastConfig = config
+		lastConfig, lastIDs = config, ids
 
 		var conns []*bitbucketServerConnection
-		for _, c := range config {
+		for i, c := range config {
 			conn, err := newBitbucketServerConnection(c)
 			if err != nil {
 				log15.Error("Error processing configured Bitbucket Server connection. Skipping it.", "url", c.Url, "error", err)
 				continue
 			}
+			conn.externalServiceID = ids[i]
 			conns = append(conns, conn)
 		}
 
@@ -262,7 +266,7 @@ func updateBitbucketServerRepos(ctx context.Context, conn *bitbucketServerConnec
 	if sourceID == "" {
 		sourceID = conn.config.Username
 	}
-	go createEnableUpdateRepos(ctx, fmt.Sprintf("bitbucket:%s", sourceID), repoChan)
+	go createEnableUpdateRepos(ctx, fmt.Sprintf("bitbucket:%s", sourceID), conn.externalServiceID, conn.config.UpdateLimits, conn.config.CloneOptions, conn.config.PushMirror, repoChan)
 	for r := range conn.listAllRepos(ctx) {
 		if r.State != "AVAILABLE" {
 			continue
@@ -340,8 +344,9 @@ func newBitbucketServerConnection(config *schema.BitbucketServerConnection) (*bi
 }
 
 type bitbucketServerConnection struct {
-	config *schema.BitbucketServerConnection
-	client *bitbucketserver.Client
+	config            *schema.BitbucketServerConnection
+	externalServiceID int64 // the ID of the external service whose config this is
+	client            *bitbucketserver.Client
 }
 
 func (c *bitbucketServerConnection) listAllRepos(ctx context.Context) <-chan *bitbucketserver.Repo {
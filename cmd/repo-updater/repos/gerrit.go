@@ -0,0 +1,315 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/atomicvalue"
+	"github.com/sourcegraph/sourcegraph/pkg/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/gerrit"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs"
+	"github.com/sourcegraph/sourcegraph/schema"
+	"golang.org/x/time/rate"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+var gerritConnections = func() *atomicvalue.Value {
+	c := atomicvalue.New()
+	c.Set(func() interface{} {
+		return []*gerritConnection{}
+	})
+	return c
+}()
+
+// SyncGerritConnections periodically syncs connections from the Frontend API.
+func SyncGerritConnections(ctx context.Context) {
+	t := time.NewTicker(configWatchInterval)
+	var (
+		lastConfig []*schema.GerritConnection
+		lastIDs    []int64
+	)
+	for range t.C {
+		var config []*schema.GerritConnection
+		ids, err := externalServiceConfigs(ctx, "GERRIT", &config)
+		if err != nil {
+			log15.Error("unable to fetch Gerrit configs", "err", err)
+			continue
+		}
+
+		if reflect.DeepEqual(config, lastConfig) && reflect.DeepEqual(ids, lastIDs) {
+			continue
+		}
+		lastConfig, lastIDs = config, ids
+
+		var conns []*gerritConnection
+		for i, c := range config {
+			conn, err := newGerritConnection(c)
+			if err != nil {
+				log15.Error("Error processing configured Gerrit connection. Skipping it.", "url", c.Url, "error", err)
+				continue
+			}
+			conn.externalServiceID = ids[i]
+			conns = append(conns, conn)
+		}
+
+		gerritConnections.Set(func() interface{} {
+			return conns
+		})
+
+		gerritWorker.restart()
+	}
+}
+
+// getGerritConnection returns the Gerrit connection (config + API client) that is responsible for
+// the repository specified by the args.
+func getGerritConnection(args protocol.RepoLookupArgs) (*gerritConnection, error) {
+	conns := gerritConnections.Get().([]*gerritConnection)
+
+	if args.ExternalRepo != nil && args.ExternalRepo.ServiceType == gerrit.ServiceType {
+		// Look up by external repository spec.
+		for _, conn := range conns {
+			if args.ExternalRepo.ServiceID == conn.client.URL.String() {
+				return conn, nil
+			}
+		}
+		return nil, errors.Errorf("no configured Gerrit connection with URL: %q", args.ExternalRepo.ServiceID)
+	}
+
+	if args.Repo != "" {
+		// Look up by repository name.
+		for _, conn := range conns {
+			if _, ok := conn.projectName(args.Repo); ok {
+				return conn, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func gerritProjectInfo(config *schema.GerritConnection, project *gerrit.Project) *protocol.RepoInfo {
+	host, err := url.Parse(config.Url)
+	if err != nil {
+		// This should never happen
+		log15.Error("malformed Gerrit config, invalid URL", "url", config.Url, "error", err)
+		return nil
+	}
+	host = NormalizeBaseURL(host)
+
+	// Clone URL. Authenticated clones use the same "a/" prefix as the REST API.
+	cloneURL := *host
+	if config.Username != "" {
+		cloneURL.Path += "a/"
+		cloneURL.User = url.UserPassword(config.Username, config.Password)
+	}
+	cloneURL.Path += project.Name
+
+	// Repo Links. Gerrit has no repository browser of its own, but plugins (such as
+	// gitiles) add links to theirs.
+	var links *protocol.RepoLinks
+	for _, l := range project.WebLinks {
+		root, err := host.Parse(l.URL)
+		if err != nil {
+			continue
+		}
+		links = &protocol.RepoLinks{Root: root.String()}
+		if strings.Contains(root.Path, "gitiles") {
+			links.Tree = links.Root + "/+/{rev}/{path}"
+			links.Blob = links.Root + "/+/{rev}/{path}"
+			links.Commit = links.Root + "/+/{commit}"
+		}
+		break
+	}
+
+	return &protocol.RepoInfo{
+		Name: reposource.GerritRepoName(config.RepositoryPathPattern, host.Hostname(), project.Name),
+		ExternalRepo: &api.ExternalRepoSpec{
+			ID:          project.Name,
+			ServiceType: gerrit.ServiceType,
+			ServiceID:   host.String(),
+		},
+		Description: project.Description,
+		Archived:    project.State == "READ_ONLY",
+		VCS: protocol.VCSInfo{
+			URL: cloneURL.String(),
+		},
+		Links: links,
+	}
+}
+
+// gerritProjectIsSynced reports whether the project contains code that should be synced.
+// Hidden projects and the projects that only hold Gerrit's own configuration are not.
+func gerritProjectIsSynced(project *gerrit.Project) bool {
+	switch {
+	case project.State == "HIDDEN":
+		return false
+	case project.Name == "All-Projects", project.Name == "All-Users":
+		return false
+	default:
+		return true
+	}
+}
+
+// GetGerritRepository queries a configured Gerrit connection endpoint for information about the
+// specified repository (a.k.a. project in Gerrit's naming scheme).
+//
+// If args.Repo refers to a repository that is not known to be on a configured Gerrit connection's
+// host, it returns authoritative == false.
+func GetGerritRepository(ctx context.Context, args protocol.RepoLookupArgs) (repo *protocol.RepoInfo, authoritative bool, err error) {
+	conn, err := getGerritConnection(args)
+	if err != nil {
+		return nil, true, err // refers to a Gerrit project but the host is not configured
+	}
+	if conn == nil {
+		return nil, false, nil // refers to a non-Gerrit project
+	}
+
+	var name string
+	if args.ExternalRepo != nil && args.ExternalRepo.ServiceType == gerrit.ServiceType {
+		// Look up by external repository spec. The ID is the project name.
+		name = args.ExternalRepo.ID
+	} else if args.Repo != "" {
+		// Look up by repository name.
+		name, _ = conn.projectName(args.Repo)
+	} else {
+		return nil, true, fmt.Errorf("unable to look up Gerrit project (%+v)", args)
+	}
+
+	project, err := conn.client.Project(ctx, name)
+	if err != nil {
+		return nil, true, err
+	}
+	if !gerritProjectIsSynced(project) {
+		return nil, true, &vcs.RepoNotExistError{Repo: args.Repo}
+	}
+	return gerritProjectInfo(conn.config, project), true, nil
+}
+
+var gerritWorker = &worker{
+	work: func(ctx context.Context, shutdown chan struct{}) {
+		for _, c := range gerritConnections.Get().([]*gerritConnection) {
+			go func(c *gerritConnection) {
+				for {
+					updateGerritProjects(ctx, c)
+					gerritUpdateTime.WithLabelValues(c.config.Url).Set(float64(time.Now().Unix()))
+					select {
+					case <-shutdown:
+						return
+					case <-time.After(GetUpdateInterval()):
+					}
+				}
+			}(c)
+		}
+	},
+}
+
+// RunGerritRepositorySyncWorker runs the worker that syncs projects from configured Gerrit
+// instances to Sourcegraph.
+func RunGerritRepositorySyncWorker(ctx context.Context) {
+	gerritWorker.start(ctx)
+}
+
+// updateGerritProjects ensures that all provided projects exist in the repository table.
+func updateGerritProjects(ctx context.Context, conn *gerritConnection) {
+	repoChan := make(chan repoCreateOrUpdateRequest)
+	defer close(repoChan)
+	go createEnableUpdateRepos(ctx, fmt.Sprintf("gerrit:%s@%s", conn.config.Username, conn.client.URL), conn.externalServiceID, conn.config.UpdateLimits, conn.config.CloneOptions, conn.config.PushMirror, repoChan)
+	for p := range conn.listAllProjects(ctx) {
+		if !gerritProjectIsSynced(p) {
+			continue
+		}
+
+		ri := gerritProjectInfo(conn.config, p)
+		if ri == nil {
+			continue
+		}
+
+		repoChan <- repoCreateOrUpdateRequest{
+			RepoCreateOrUpdateRequest: api.RepoCreateOrUpdateRequest{
+				RepoName:     ri.Name,
+				ExternalRepo: ri.ExternalRepo,
+				Description:  ri.Description,
+				Archived:     ri.Archived,
+				Enabled:      conn.config.InitialRepositoryEnablement,
+			},
+			URL: ri.VCS.URL,
+		}
+	}
+}
+
+func newGerritConnection(config *schema.GerritConnection) (*gerritConnection, error) {
+	baseURL, err := url.Parse(config.Url)
+	if err != nil {
+		return nil, err
+	}
+	baseURL = NormalizeBaseURL(baseURL)
+
+	transport, err := cachedTransportWithCertTrusted(config.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gerritConnection{
+		config: config,
+		client: &gerrit.Client{
+			URL:      baseURL,
+			Username: config.Username,
+			Password: config.Password,
+			HTTPClient: &http.Client{
+				Transport: gerrit.WithRequestCounter(transport),
+			},
+			RateLimit: rate.NewLimiter(rateLimitRequestsPerSecond, rateLimitMaxBurstRequests),
+		},
+	}, nil
+}
+
+type gerritConnection struct {
+	config            *schema.GerritConnection
+	externalServiceID int64 // the ID of the external service whose config this is
+	client            *gerrit.Client
+}
+
+// projectName returns the name of the Gerrit project with the given Sourcegraph repository
+// name, if the name belongs to this connection.
+func (c *gerritConnection) projectName(repo api.RepoName) (string, bool) {
+	pattern := c.config.RepositoryPathPattern
+	if pattern == "" {
+		pattern = "{host}/{name}"
+	}
+	return trimRepositoryPathPattern(repo, pattern, "{name}", c.client.URL.Hostname())
+}
+
+func (c *gerritConnection) listAllProjects(ctx context.Context) <-chan *gerrit.Project {
+	perPage := 100
+	ch := make(chan *gerrit.Project, perPage)
+	go func() {
+		defer close(ch)
+
+		for skip, hasMore := 0, true; hasMore; {
+			var projects []*gerrit.Project
+			var err error
+			projects, hasMore, err = c.client.Projects(ctx, skip, perPage)
+			if err != nil {
+				log15.Error("failed when listing Gerrit projects", "url", c.client.URL, "error", err)
+				return
+			}
+			if len(projects) == 0 {
+				return
+			}
+			for _, p := range projects {
+				ch <- p
+			}
+			skip += len(projects)
+		}
+	}()
+
+	return ch
+}
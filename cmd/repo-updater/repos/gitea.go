@@ -0,0 +1,308 @@
+package repos
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/atomicvalue"
+	"github.com/sourcegraph/sourcegraph/pkg/conf/reposource"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/gitea"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
+	"github.com/sourcegraph/sourcegraph/schema"
+	"golang.org/x/time/rate"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+var giteaConnections = func() *atomicvalue.Value {
+	c := atomicvalue.New()
+	c.Set(func() interface{} {
+		return []*giteaConnection{}
+	})
+	return c
+}()
+
+// SyncGiteaConnections periodically syncs connections from the Frontend API.
+func SyncGiteaConnections(ctx context.Context) {
+	t := time.NewTicker(configWatchInterval)
+	var (
+		lastConfig []*schema.GiteaConnection
+		lastIDs    []int64
+	)
+	for range t.C {
+		var config []*schema.GiteaConnection
+		ids, err := externalServiceConfigs(ctx, "GITEA", &config)
+		if err != nil {
+			log15.Error("unable to fetch Gitea configs", "err", err)
+			continue
+		}
+
+		if reflect.DeepEqual(config, lastConfig) && reflect.DeepEqual(ids, lastIDs) {
+			continue
+		}
+		lastConfig, lastIDs = config, ids
+
+		var conns []*giteaConnection
+		for i, c := range config {
+			conn, err := newGiteaConnection(c)
+			if err != nil {
+				log15.Error("Error processing configured Gitea connection. Skipping it.", "url", c.Url, "error", err)
+				continue
+			}
+			conn.externalServiceID = ids[i]
+			conns = append(conns, conn)
+		}
+
+		giteaConnections.Set(func() interface{} {
+			return conns
+		})
+
+		giteaWorker.restart()
+	}
+}
+
+// getGiteaConnection returns the Gitea connection (config + API client) that is responsible for the
+// repository specified by the args.
+func getGiteaConnection(args protocol.RepoLookupArgs) (*giteaConnection, error) {
+	conns := giteaConnections.Get().([]*giteaConnection)
+
+	if args.ExternalRepo != nil && args.ExternalRepo.ServiceType == gitea.ServiceType {
+		// Look up by external repository spec.
+		for _, conn := range conns {
+			if args.ExternalRepo.ServiceID == conn.client.URL.String() {
+				return conn, nil
+			}
+		}
+		return nil, errors.Errorf("no configured Gitea connection with URL: %q", args.ExternalRepo.ServiceID)
+	}
+
+	if args.Repo != "" {
+		// Look up by repository name.
+		for _, conn := range conns {
+			if _, ok := conn.nameWithOwner(args.Repo); ok {
+				return conn, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func giteaRepoInfo(config *schema.GiteaConnection, repo *gitea.Repo) *protocol.RepoInfo {
+	host, err := url.Parse(config.Url)
+	if err != nil {
+		// This should never happen
+		log15.Error("malformed Gitea config, invalid URL", "url", config.Url, "error", err)
+		return nil
+	}
+	host = NormalizeBaseURL(host)
+
+	cloneURL := repo.CloneURL
+	if config.GitURLType == "ssh" && repo.SSHURL != "" {
+		cloneURL = repo.SSHURL
+	} else {
+		// Gitea accepts an access token as the username.
+		cloneURL = setUserinfoBestEffort(cloneURL, config.Token, "")
+	}
+
+	var links *protocol.RepoLinks
+	if repo.HTMLURL != "" {
+		links = &protocol.RepoLinks{
+			Root:   repo.HTMLURL,
+			Tree:   repo.HTMLURL + "/src/{rev}/{path}",
+			Blob:   repo.HTMLURL + "/src/{rev}/{path}",
+			Commit: repo.HTMLURL + "/commit/{commit}",
+		}
+	}
+
+	return &protocol.RepoInfo{
+		Name: reposource.GiteaRepoName(config.RepositoryPathPattern, host.Hostname(), repo.FullName),
+		ExternalRepo: &api.ExternalRepoSpec{
+			ID:          strconv.FormatInt(repo.ID, 10),
+			ServiceType: gitea.ServiceType,
+			ServiceID:   host.String(),
+		},
+		Description: repo.Description,
+		Fork:        repo.Fork,
+		Archived:    repo.Archived,
+		VCS: protocol.VCSInfo{
+			URL: cloneURL,
+		},
+		Links: links,
+	}
+}
+
+// GetGiteaRepository queries a configured Gitea connection endpoint for information about the
+// specified repository.
+//
+// If args.Repo refers to a repository that is not known to be on a configured Gitea connection's
+// host, it returns authoritative == false.
+func GetGiteaRepository(ctx context.Context, args protocol.RepoLookupArgs) (repo *protocol.RepoInfo, authoritative bool, err error) {
+	conn, err := getGiteaConnection(args)
+	if err != nil {
+		return nil, true, err // refers to a Gitea repo but the host is not configured
+	}
+	if conn == nil {
+		return nil, false, nil // refers to a non-Gitea repo
+	}
+
+	if args.ExternalRepo != nil && args.ExternalRepo.ServiceType == gitea.ServiceType {
+		// Look up by external repository spec.
+		id, err := strconv.ParseInt(args.ExternalRepo.ID, 10, 64)
+		if err != nil {
+			return nil, true, errors.Errorf("malformed Gitea repository ID: %q", args.ExternalRepo.ID)
+		}
+		repo, err := conn.client.RepoByID(ctx, id)
+		if err != nil {
+			return nil, true, err
+		}
+		return giteaRepoInfo(conn.config, repo), true, nil
+	}
+
+	if args.Repo != "" {
+		// Look up by repository name.
+		nameWithOwner, _ := conn.nameWithOwner(args.Repo)
+		i := strings.Index(nameWithOwner, "/")
+		if i < 0 || i == len(nameWithOwner)-1 {
+			return nil, true, errors.Errorf("malformed Gitea repository name: %q", args.Repo)
+		}
+		repo, err := conn.client.Repo(ctx, nameWithOwner[:i], nameWithOwner[i+1:])
+		if err != nil {
+			return nil, true, err
+		}
+		return giteaRepoInfo(conn.config, repo), true, nil
+	}
+
+	return nil, true, fmt.Errorf("unable to look up Gitea repository (%+v)", args)
+}
+
+var giteaWorker = &worker{
+	work: func(ctx context.Context, shutdown chan struct{}) {
+		for _, c := range giteaConnections.Get().([]*giteaConnection) {
+			go func(c *giteaConnection) {
+				for {
+					updateGiteaRepos(ctx, c)
+					giteaUpdateTime.WithLabelValues(c.config.Url).Set(float64(time.Now().Unix()))
+					select {
+					case <-shutdown:
+						return
+					case <-time.After(GetUpdateInterval()):
+					}
+				}
+			}(c)
+		}
+	},
+}
+
+// RunGiteaRepositorySyncWorker runs the worker that syncs repositories from configured Gitea
+// instances to Sourcegraph.
+func RunGiteaRepositorySyncWorker(ctx context.Context) {
+	giteaWorker.start(ctx)
+}
+
+// updateGiteaRepos ensures that all provided repositories exist in the repository table.
+func updateGiteaRepos(ctx context.Context, conn *giteaConnection) {
+	repoChan := make(chan repoCreateOrUpdateRequest)
+	defer close(repoChan)
+	go createEnableUpdateRepos(ctx, fmt.Sprintf("gitea:%s", conn.config.Token), conn.externalServiceID, conn.config.UpdateLimits, conn.config.CloneOptions, conn.config.PushMirror, repoChan)
+	for r := range conn.listAllRepos(ctx) {
+		if r.Empty {
+			continue
+		}
+
+		ri := giteaRepoInfo(conn.config, r)
+		if ri == nil || ri.VCS.URL == "" {
+			continue
+		}
+
+		repoChan <- repoCreateOrUpdateRequest{
+			RepoCreateOrUpdateRequest: api.RepoCreateOrUpdateRequest{
+				RepoName:     ri.Name,
+				ExternalRepo: ri.ExternalRepo,
+				Description:  ri.Description,
+				Fork:         ri.Fork,
+				Archived:     ri.Archived,
+				Enabled:      conn.config.InitialRepositoryEnablement,
+			},
+			URL: ri.VCS.URL,
+		}
+	}
+}
+
+func newGiteaConnection(config *schema.GiteaConnection) (*giteaConnection, error) {
+	baseURL, err := url.Parse(config.Url)
+	if err != nil {
+		return nil, err
+	}
+	baseURL = NormalizeBaseURL(baseURL)
+
+	transport, err := cachedTransportWithCertTrusted(config.Certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &giteaConnection{
+		config: config,
+		client: &gitea.Client{
+			URL:   baseURL,
+			Token: config.Token,
+			HTTPClient: &http.Client{
+				Transport: gitea.WithRequestCounter(transport),
+			},
+			RateLimit: rate.NewLimiter(rateLimitRequestsPerSecond, rateLimitMaxBurstRequests),
+		},
+	}, nil
+}
+
+type giteaConnection struct {
+	config            *schema.GiteaConnection
+	externalServiceID int64 // the ID of the external service whose config this is
+	client            *gitea.Client
+}
+
+// nameWithOwner returns the "owner/name" of the Gitea repository with the given
+// Sourcegraph repository name, if the name belongs to this connection.
+func (c *giteaConnection) nameWithOwner(repo api.RepoName) (string, bool) {
+	pattern := c.config.RepositoryPathPattern
+	if pattern == "" {
+		pattern = "{host}/{nameWithOwner}"
+	}
+	return trimRepositoryPathPattern(repo, pattern, "{nameWithOwner}", c.client.URL.Hostname())
+}
+
+func (c *giteaConnection) listAllRepos(ctx context.Context) <-chan *gitea.Repo {
+	const perPage = 50 // the maximum page size of the Gitea API
+	ch := make(chan *gitea.Repo, perPage)
+	go func() {
+		defer close(ch)
+
+		seen := map[int64]bool{}
+		for page, hasMore := 1, true; hasMore; page++ {
+			var repos []*gitea.Repo
+			var err error
+			repos, hasMore, err = c.client.Repos(ctx, page, perPage)
+			if err != nil {
+				log15.Error("failed when listing Gitea repos", "url", c.client.URL, "error", err)
+				return
+			}
+			for _, r := range repos {
+				// Repositories created while listing can shift the pages, so the
+				// same repository may be returned twice.
+				if seen[r.ID] {
+					continue
+				}
+				seen[r.ID] = true
+				ch <- r
+			}
+		}
+	}()
+
+	return ch
+}
@@ -14,7 +14,6 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/atomicvalue"
-	"github.com/sourcegraph/sourcegraph/pkg/conf"
 	"github.com/sourcegraph/sourcegraph/pkg/conf/reposource"
 	"github.com/sourcegraph/sourcegraph/pkg/extsvc/github"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
@@ -35,9 +34,13 @@ var githubConnections = func() *atomicvalue.Value {
 // the Frontend API.
 func SyncGitHubConnections(ctx context.Context) {
 	t := time.NewTicker(configWatchInterval)
-	var lastGitHubConf []*schema.GitHubConnection
+	var (
+		lastGitHubConf []*schema.GitHubConnection
+		lastGitHubIDs  []int64
+	)
 	for range t.C {
-		githubConf, err := conf.GitHubConfigs(ctx)
+		var githubConf []*schema.GitHubConnection
+		ids, err := externalServiceConfigs(ctx, "GITHUB", &githubConf)
 		if err != nil {
 			log15.Error("unable to fetch GitHub configs", "err", err)
 			continue
@@ -59,20 +62,22 @@ func SyncGitHubConnections(ctx context.Context) {
 				Url:                         "https://github.com",
 				InitialRepositoryEnablement: true,
 			})
+			ids = append(ids, 0)
 		}
 
-		if reflect.DeepEqual(githubConf, lastGitHubConf) {
+		if reflect.DeepEqual(githubConf, lastGitHubConf) && reflect.DeepEqual(ids, lastGitHubIDs) {
 			continue
 		}
-		lastGitHubConf = githubConf
+		lastGitHubConf, lastGitHubIDs = githubConf, ids
 
 		var conns []*githubConnection
-		for _, c := range githubConf {
+		for i, c := range githubConf {
 			conn, err := newGitHubConnection(c)
 			if err != nil {
 				log15.Error("Error processing configured GitHub connection. Skipping it.", "url", c.Url, "error", err)
 				continue
 			}
+			conn.externalServiceID = ids[i]
 			conns = append(conns, conn)
 		}
 
@@ -305,7 +310,7 @@ func updateGitHubRepositories(ctx context.Context, conn *githubConnection) {
 
 	repoChan := make(chan repoCreateOrUpdateRequest)
 	defer close(repoChan)
-	go createEnableUpdateRepos(ctx, fmt.Sprintf("github:%s", conn.config.Token), repoChan)
+	go createEnableUpdateRepos(ctx, fmt.Sprintf("github:%s", conn.config.Token), conn.externalServiceID, conn.config.UpdateLimits, conn.config.CloneOptions, conn.config.PushMirror, repoChan)
 	for repo := range repos {
 		// log15.Debug("github sync: create/enable/update repo", "repo", repo.NameWithOwner)
 		repoChan <- repoCreateOrUpdateRequest{
@@ -348,10 +353,11 @@ func newGitHubConnection(config *schema.GitHubConnection) (*githubConnection, er
 }
 
 type githubConnection struct {
-	config       *schema.GitHubConnection
-	githubDotCom bool
-	baseURL      *url.URL
-	client       *github.Client
+	config            *schema.GitHubConnection
+	externalServiceID int64 // the ID of the external service whose config this is, or 0 for the default connection
+	githubDotCom      bool
+	baseURL           *url.URL
+	client            *github.Client
 	// searchClient is for using the GitHub search API, which has an independent
 	// rate limit much lower than non-search API requests.
 	searchClient *github.Client
@@ -12,7 +12,6 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/atomicvalue"
-	"github.com/sourcegraph/sourcegraph/pkg/conf"
 	"github.com/sourcegraph/sourcegraph/pkg/conf/reposource"
 	"github.com/sourcegraph/sourcegraph/pkg/extsvc/gitlab"
 	"github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
@@ -32,9 +31,13 @@ var gitlabConnections = func() *atomicvalue.Value {
 // the Frontend API.
 func SyncGitLabConnections(ctx context.Context) {
 	t := time.NewTicker(configWatchInterval)
-	var lastConfig []*schema.GitLabConnection
+	var (
+		lastConfig []*schema.GitLabConnection
+		lastIDs    []int64
+	)
 	for range t.C {
-		gitlabConf, err := conf.GitLabConfigs(ctx)
+		var gitlabConf []*schema.GitLabConnection
+		ids, err := externalServiceConfigs(ctx, "GITLAB", &gitlabConf)
 		if err != nil {
 			log15.Error("unable to fetch Gitlab configs", "err", err)
 			continue
@@ -56,20 +59,22 @@ func SyncGitLabConnections(ctx context.Context) {
 				Url:                         "https://gitlab.com",
 				InitialRepositoryEnablement: true,
 			})
+			ids = append(ids, 0)
 		}
 
-		if reflect.DeepEqual(gitlabConf, lastConfig) {
+		if reflect.DeepEqual(gitlabConf, lastConfig) && reflect.DeepEqual(ids, lastIDs) {
 			continue
 		}
-		lastConfig = gitlabConf
+		lastConfig, lastIDs = gitlabConf, ids
 
 		var conns []*gitlabConnection
-		for _, c := range gitlabConf {
+		for i, c := range gitlabConf {
 			conn, err := newGitLabConnection(c)
 			if err != nil {
 				log15.Error("Error processing configured GitLab connection. Skipping it.", "url", c.Url, "error", err)
 				continue
 			}
+			conn.externalServiceID = ids[i]
 			conns = append(conns, conn)
 		}
 
@@ -217,7 +222,7 @@ func updateGitLabProjects(ctx context.Context, conn *gitlabConnection) {
 
 	repoChan := make(chan repoCreateOrUpdateRequest)
 	defer close(repoChan)
-	go createEnableUpdateRepos(ctx, fmt.Sprintf("gitlab:%s", conn.config.Token), repoChan)
+	go createEnableUpdateRepos(ctx, fmt.Sprintf("gitlab:%s", conn.config.Token), conn.externalServiceID, conn.config.UpdateLimits, conn.config.CloneOptions, conn.config.PushMirror, repoChan)
 	for proj := range projs {
 		repoChan <- repoCreateOrUpdateRequest{
 			RepoCreateOrUpdateRequest: api.RepoCreateOrUpdateRequest{
@@ -253,9 +258,10 @@ func newGitLabConnection(config *schema.GitLabConnection) (*gitlabConnection, er
 }
 
 type gitlabConnection struct {
-	config  *schema.GitLabConnection
-	baseURL *url.URL // URL with path /api/v4 (no trailing slash)
-	client  *gitlab.Client
+	config            *schema.GitLabConnection
+	externalServiceID int64    // the ID of the external service whose config this is, or 0 for the default connection
+	baseURL           *url.URL // URL with path /api/v4 (no trailing slash)
+	client            *gitlab.Client
 }
 
 // authenticatedRemoteURL returns the GitLab projects's Git remote URL with the configured GitLab personal access
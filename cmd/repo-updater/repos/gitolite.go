@@ -27,15 +27,16 @@ func RunGitoliteRepositorySyncWorker(ctx context.Context) {
 	phabricatorMetadataCounter := 0
 	for {
 		log15.Debug("RunGitoliteRepositorySyncWorker:GitoliteUpdateRepos")
-		config, err := conf.GitoliteConfigs(context.Background())
+		var config []*schema.GitoliteConnection
+		ids, err := externalServiceConfigs(context.Background(), "GITOLITE", &config)
 		if err != nil {
 			log15.Error("unable to fetch Gitolite configs", "err", err)
 			time.Sleep(GetUpdateInterval())
 			continue
 		}
 
-		for _, gconf := range config {
-			if err := gitoliteUpdateRepos(ctx, gconf, (phabricatorMetadataCounter%10) == 0); err != nil {
+		for i, gconf := range config {
+			if err := gitoliteUpdateRepos(ctx, ids[i], gconf, (phabricatorMetadataCounter%10) == 0); err != nil {
 				log15.Error("error updating Gitolite repositories", "err", err, "prefix", gconf.Prefix)
 			} else {
 				log15.Debug("updated Gitolite repositories", "prefix", gconf.Prefix)
@@ -104,8 +105,8 @@ func tryUpdateGitolitePhabricatorMetadata(ctx context.Context, gconf *schema.Git
 }
 
 // gitoliteUpdateRepos updates the repos associated with a specific
-// Gitolite connection.
-func gitoliteUpdateRepos(ctx context.Context, gconf *schema.GitoliteConnection, doPhabricator bool) error {
+// Gitolite connection, whose external service has the given ID.
+func gitoliteUpdateRepos(ctx context.Context, externalServiceID int64, gconf *schema.GitoliteConnection, doPhabricator bool) error {
 	// Get list of Gitolite repositories for this connection.
 	rlist, err := gitserver.DefaultClient.ListGitolite(ctx, gconf.Host)
 	if err != nil {
@@ -114,7 +115,7 @@ func gitoliteUpdateRepos(ctx context.Context, gconf *schema.GitoliteConnection,
 
 	repoChan := make(chan repoCreateOrUpdateRequest)
 	defer close(repoChan)
-	go createEnableUpdateRepos(ctx, fmt.Sprintf("gitolite:%s", gconf.Prefix), repoChan)
+	go createEnableUpdateRepos(ctx, fmt.Sprintf("gitolite:%s", gconf.Prefix), externalServiceID, gconf.UpdateLimits, gconf.CloneOptions, gconf.PushMirror, repoChan)
 	if doPhabricator && gconf.Phabricator != nil {
 		go tryUpdateGitolitePhabricatorMetadata(ctx, gconf, rlist)
 	}
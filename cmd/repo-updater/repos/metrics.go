@@ -36,6 +36,24 @@ var (
 		Name:      "time_last_bitbucketserver_sync",
 		Help:      "The last time a comprehensive Bitbucket Server sync finished",
 	}, []string{"id"})
+	bitbucketCloudUpdateTime = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "time_last_bitbucketcloud_sync",
+		Help:      "The last time a comprehensive Bitbucket Cloud sync finished",
+	}, []string{"id"})
+	giteaUpdateTime = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "time_last_gitea_sync",
+		Help:      "The last time a comprehensive Gitea sync finished",
+	}, []string{"id"})
+	gerritUpdateTime = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "time_last_gerrit_sync",
+		Help:      "The last time a comprehensive Gerrit sync finished",
+	}, []string{"id"})
 
 	// gitoliteUpdateTime is an ugly duckling as repo-updater just
 	// hits a HTTP endpoint on the frontend that returns after
@@ -89,6 +107,43 @@ var (
 		Help:      "Incremented each time we skip a repository clone to remove.",
 	})
 
+	userPermissionsSyncs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "user_permissions_syncs_total",
+		Help:      "Incremented each time we sync the repository permissions of a user.",
+	}, []string{"success"})
+	userPermissionsSyncDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "user_permissions_sync_duration",
+		Help:      "Time spent syncing the repository permissions of a single user.",
+	})
+	userPermissionsStaleUsers = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "user_permissions_stale_users",
+		Help:      "The number of users whose repository permissions are due to be synced.",
+	})
+	userPermissionsOldestSync = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "time_oldest_user_permissions_sync",
+		Help:      "The last sync time of the least recently synced user permissions that are due to be synced.",
+	})
+	userPermissionsNewRepoSyncs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "user_permissions_new_repo_syncs_total",
+		Help:      "Incremented each time we sync the repository permissions of users on new repositories.",
+	}, []string{"success"})
+	userPermissionsNewRepoSyncDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "user_permissions_new_repo_sync_duration",
+		Help:      "Time spent syncing the repository permissions of users on new repositories.",
+	})
+
 	schedError = promauto.NewCounter(prometheus.CounterOpts{
 		Namespace: "src",
 		Subsystem: "repoupdater",
@@ -113,6 +168,18 @@ var (
 		Name:      "sched_manual_fetch",
 		Help:      "Incremented each time the scheduler updates a repository due to user traffic.",
 	})
+	schedWebhookFetch = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "sched_webhook_fetch",
+		Help:      "Incremented each time the scheduler updates a repository due to a code host webhook.",
+	})
+	schedExternalServiceWait = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "src",
+		Subsystem: "repoupdater",
+		Name:      "sched_external_service_wait",
+		Help:      "Incremented each time the scheduler holds back an update because its external service's update budget is exhausted.",
+	}, []string{"reason"})
 	schedKnownRepos = promauto.NewGauge(prometheus.GaugeOpts{
 		Namespace: "src",
 		Subsystem: "repoupdater",
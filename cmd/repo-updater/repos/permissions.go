@@ -0,0 +1,145 @@
+package repos
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/env"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+const defaultUserPermissionsSyncInterval = time.Hour
+
+var userPermissionsSyncInterval, _ = time.ParseDuration(env.Get("SRC_USER_PERMISSIONS_SYNC_INTERVAL", defaultUserPermissionsSyncInterval.String(), "How often the repository permissions of each user are synced from the authz providers."))
+
+// userPermissionsSyncBatchSize is the maximum number of users whose permissions are synced
+// between two lookups of stale users.
+const userPermissionsSyncBatchSize = 100
+
+// UserPermissionsAPI captures the internal API methods needed for syncing user permissions.
+type UserPermissionsAPI interface {
+	UserPermissionsListStale(context.Context, api.UserPermissionsListStaleRequest) (*api.UserPermissionsListStaleResponse, error)
+	UserPermissionsSync(ctx context.Context, userID int32) error
+	UserPermissionsSyncNewRepos(context.Context) error
+}
+
+// RunUserPermissionsSyncWorker is a worker which keeps the repository permissions of users that
+// are stored in the database up to date. The permissions of every user are synced at least once
+// per SRC_USER_PERMISSIONS_SYNC_INTERVAL.
+func RunUserPermissionsSyncWorker(ctx context.Context) {
+	log := log15.Root().New("worker", "user-permissions-sync")
+	syncInterval := userPermissionsSyncInterval
+	if syncInterval <= 0 {
+		// Users can only access repositories from code hosts with authz providers after their
+		// permissions have been synced, so syncing can't be disabled.
+		log.Warn("ignoring invalid env SRC_USER_PERMISSIONS_SYNC_INTERVAL, using the default", "default", defaultUserPermissionsSyncInterval)
+		syncInterval = defaultUserPermissionsSyncInterval
+	}
+
+	for {
+		synced, err := syncStaleUserPermissions(ctx, log, api.InternalClient, time.Now().Add(-syncInterval))
+		if err != nil {
+			log.Error("failed to sync user permissions", "error", err)
+		}
+		// Keep going without pause while there is a backlog of stale users that can be synced.
+		if err != nil || synced < userPermissionsSyncBatchSize {
+			randSleep(time.Minute, 10*time.Second)
+		}
+	}
+}
+
+// syncStaleUserPermissions syncs the permissions of the users whose permissions were last synced
+// before syncedBefore, up to userPermissionsSyncBatchSize users. It returns the number of users
+// whose permissions were synced successfully.
+func syncStaleUserPermissions(ctx context.Context, log log15.Logger, a UserPermissionsAPI, syncedBefore time.Time) (int, error) {
+	resp, err := a.UserPermissionsListStale(ctx, api.UserPermissionsListStaleRequest{
+		SyncedBefore: syncedBefore,
+		Limit:        userPermissionsSyncBatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	userPermissionsStaleUsers.Set(float64(resp.Total))
+	if len(resp.Users) > 0 && resp.Users[0].SyncedAt != nil {
+		userPermissionsOldestSync.Set(float64(resp.Users[0].SyncedAt.Unix()))
+	}
+
+	synced := 0
+	for _, u := range resp.Users {
+		start := time.Now()
+		err := a.UserPermissionsSync(ctx, u.UserID)
+		userPermissionsSyncDuration.Observe(time.Since(start).Seconds())
+		userPermissionsSyncs.WithLabelValues(strconv.FormatBool(err == nil)).Inc()
+		if err != nil {
+			// Do not fail at this point, just log so we can sync other users.
+			log.Error("failed to sync user permissions", "user", u.UserID, "error", err)
+			continue
+		}
+		synced++
+	}
+	return synced, nil
+}
+
+// newRepoPermissionsSyncInterval is how often the permissions on new repositories are synced if
+// repo-updater didn't add any repositories in the meantime. Repositories can also be added by the
+// frontend (for example when a user visits a repository that wasn't synced yet).
+const newRepoPermissionsSyncInterval = time.Minute
+
+// newRepos tracks the repositories that repo-updater created or enabled, to notice when it adds
+// repositories.
+var newRepos = struct {
+	sync.Mutex
+	maxID api.RepoID    // the largest repository ID seen
+	added chan struct{} // receives a value when a repository with a larger ID is seen
+}{added: make(chan struct{}, 1)}
+
+// repoSeen records that the repository with the given ID exists. A repository with an ID larger
+// than all repositories seen before was added since, so the permissions of users on it are synced
+// soon (see RunNewRepoPermissionsSyncWorker).
+func repoSeen(id api.RepoID) {
+	newRepos.Lock()
+	defer newRepos.Unlock()
+	if id <= newRepos.maxID {
+		return
+	}
+	// After a restart, the first repository seen counts as added, which causes one unneeded (but
+	// cheap) sync.
+	newRepos.maxID = id
+	select {
+	case newRepos.added <- struct{}{}:
+	default:
+	}
+}
+
+// RunNewRepoPermissionsSyncWorker is a worker which syncs the repository permissions of users on
+// repositories that were added since their permissions were last synced, so that users can access
+// them without waiting for the next sync by RunUserPermissionsSyncWorker. It runs when
+// repo-updater adds repositories and at least every newRepoPermissionsSyncInterval.
+func RunNewRepoPermissionsSyncWorker(ctx context.Context) {
+	log := log15.Root().New("worker", "new-repo-permissions-sync")
+	for {
+		select {
+		case <-newRepos.added:
+		case <-time.After(newRepoPermissionsSyncInterval):
+		case <-ctx.Done():
+			return
+		}
+		if err := syncNewRepoPermissions(ctx, api.InternalClient); err != nil {
+			log.Error("failed to sync user permissions on new repositories", "error", err)
+		}
+	}
+}
+
+// syncNewRepoPermissions syncs the permissions of users on repositories that were added since
+// their permissions were last synced.
+func syncNewRepoPermissions(ctx context.Context, a UserPermissionsAPI) error {
+	start := time.Now()
+	err := a.UserPermissionsSyncNewRepos(ctx)
+	userPermissionsNewRepoSyncDuration.Observe(time.Since(start).Seconds())
+	userPermissionsNewRepoSyncs.WithLabelValues(strconv.FormatBool(err == nil)).Inc()
+	return err
+}
@@ -0,0 +1,101 @@
+package repos
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+type fakeUserPermissionsAPI struct {
+	stale        []api.StaleUserPermissions
+	failing      map[int32]bool
+	synced       []int32
+	newRepoSyncs int
+}
+
+func (a *fakeUserPermissionsAPI) UserPermissionsListStale(_ context.Context, req api.UserPermissionsListStaleRequest) (*api.UserPermissionsListStaleResponse, error) {
+	users := a.stale
+	if len(users) > req.Limit {
+		users = users[:req.Limit]
+	}
+	return &api.UserPermissionsListStaleResponse{Users: users, Total: len(a.stale)}, nil
+}
+
+func (a *fakeUserPermissionsAPI) UserPermissionsSync(_ context.Context, userID int32) error {
+	if a.failing[userID] {
+		return errors.New("sync failed")
+	}
+	a.synced = append(a.synced, userID)
+	return nil
+}
+
+func (a *fakeUserPermissionsAPI) UserPermissionsSyncNewRepos(context.Context) error {
+	a.newRepoSyncs++
+	return nil
+}
+
+func TestSyncStaleUserPermissions(t *testing.T) {
+	syncedAt := time.Now().Add(-2 * time.Hour)
+	a := &fakeUserPermissionsAPI{
+		stale: []api.StaleUserPermissions{
+			{UserID: 1},
+			{UserID: 2, SyncedAt: &syncedAt},
+			{UserID: 3, SyncedAt: &syncedAt},
+		},
+		failing: map[int32]bool{2: true},
+	}
+
+	synced, err := syncStaleUserPermissions(context.Background(), log15.Root(), a, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if synced != 2 {
+		t.Errorf("got %d synced users, want 2", synced)
+	}
+	if want := []int32{1, 3}; !reflect.DeepEqual(a.synced, want) {
+		t.Errorf("got synced users %v, want %v", a.synced, want)
+	}
+}
+
+func TestRepoSeen(t *testing.T) {
+	added := func() bool {
+		select {
+		case <-newRepos.added:
+			return true
+		default:
+			return false
+		}
+	}
+	added() // drain notifications of other tests
+
+	newRepos.maxID = 0
+	for _, tc := range []struct {
+		ids   []api.RepoID
+		added bool
+	}{
+		{ids: []api.RepoID{5, 3}, added: true},
+		{ids: []api.RepoID{3, 5}, added: false},
+		{ids: []api.RepoID{6, 7, 4}, added: true},
+		{ids: nil, added: false},
+	} {
+		for _, id := range tc.ids {
+			repoSeen(id)
+		}
+		if got := added(); got != tc.added {
+			t.Errorf("after seeing repos %v: got added %v, want %v", tc.ids, got, tc.added)
+		}
+	}
+
+	a := &fakeUserPermissionsAPI{}
+	if err := syncNewRepoPermissions(context.Background(), a); err != nil {
+		t.Fatal(err)
+	}
+	if a.newRepoSyncs != 1 {
+		t.Errorf("got %d syncs of new repos, want 1", a.newRepoSyncs)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"math/rand"
+	"reflect"
 	"sort"
 	"sync"
 	"time"
@@ -57,6 +58,14 @@ type repoData struct {
 	// URL is the git origin URL.
 	URL string
 
+	// CloneOptions are the options to clone the repo with. nil means a full
+	// clone.
+	CloneOptions *gitserverprotocol.CloneOptions
+
+	// PushMirrorURL is the URL of the remote to push the repo to after each
+	// update, if any.
+	PushMirrorURL string
+
 	// Due is the next time this repo should be updated.
 	Due time.Time
 
@@ -186,8 +195,10 @@ func (r *repoList) setAutoUpdatesDisabled(b bool) {
 // a configuration source, such as information retrieved from GitHub for a
 // given GitHubConnection.
 type configuredRepo struct {
-	url     string
-	enabled bool
+	url           string
+	enabled       bool
+	cloneOptions  *gitserverprotocol.CloneOptions
+	pushMirrorURL string
 }
 
 // a sourceRepoList represents the set of repositories associated with a
@@ -429,6 +440,8 @@ func (r *repoList) doUpdate(ctx context.Context, repo *repoData) {
 	r.mu.Lock()
 	repoName := api.RepoName(repo.Name)
 	url := repo.URL
+	cloneOptions := repo.CloneOptions
+	pushMirrorURL := repo.PushMirrorURL
 	interval := repo.UpdateInterval
 	manual := repo.UpdateSoon
 	autoUpdatesDisabled := r.autoUpdatesDisabled
@@ -456,7 +469,7 @@ func (r *repoList) doUpdate(ctx context.Context, repo *repoData) {
 		if manual {
 			interval = 5 * time.Second
 		}
-		resp, err = gitserver.DefaultClient.RequestRepoUpdate(ctx, gitserver.Repo{Name: repoName, URL: url}, interval)
+		resp, err = gitserver.DefaultClient.RequestRepoUpdate(ctx, gitserver.Repo{Name: repoName, URL: url, CloneOptions: cloneOptions, PushMirrorURL: pushMirrorURL}, interval)
 		if err != nil {
 			log15.Warn("error requesting repo update", "repo", repoName, "err", err)
 			return
@@ -700,11 +713,11 @@ func (r *repoList) updateSource(source string, newList sourceRepoList) (enqueued
 		switch {
 		case !ok:
 			log15.Info("adding repo", "source", source, "name", name, "url", value.url)
-		case value.url != old.url, value.enabled != old.enabled:
+		case value.url != old.url, value.enabled != old.enabled, !reflect.DeepEqual(value.cloneOptions, old.cloneOptions), value.pushMirrorURL != old.pushMirrorURL:
 			log15.Debug("updating repo", "source", source, "name", name, "url", value.url)
 		default:
-			// No change in whether or not it's enabled, no change in URL, we
-			// can ignore this one.
+			// No change in whether or not it's enabled, no change in URL,
+			// clone options or push mirror, we can ignore this one.
 			continue
 		}
 		oldList[name] = value
@@ -715,6 +728,10 @@ func (r *repoList) updateSource(source string, newList sourceRepoList) (enqueued
 			dequeued++
 			r.dequeue(name, value.url)
 		}
+		if repo, ok := r.repos[name]; ok {
+			repo.CloneOptions = value.cloneOptions
+			repo.PushMirrorURL = value.pushMirrorURL
+		}
 	}
 	return enqueued, dequeued
 }
@@ -13,6 +13,7 @@ import (
 	gitserverprotocol "github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/pkg/mutablelimiter"
 	"github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
+	"github.com/sourcegraph/sourcegraph/schema"
 	log15 "gopkg.in/inconshreveable/log15.v2"
 )
 
@@ -35,9 +36,13 @@ const (
 // This heuristic is simple to compute and has nice backoff properties.
 //
 // When it is time for a repo to update, the scheduler inserts the repo into a queue.
+// Updates requested by users are queued before updates requested by code host webhooks,
+// which are queued before scheduled updates.
 //
 // A worker continuously dequeues repos and sends updates to gitserver, but its concurrency
-// is limited by the gitMaxConcurrentClones site configuration.
+// is limited by the gitMaxConcurrentClones site configuration. The updates of repos of an
+// external service can be further limited by the updateLimits of its configuration, in
+// which case repos of other external services are dequeued while its budget is exhausted.
 type updateScheduler struct {
 	mu sync.Mutex
 
@@ -56,6 +61,18 @@ type configuredRepo2 struct {
 	URL     string
 	Name    api.RepoName
 	Enabled bool
+
+	// ExternalServiceID is the ID of the external service that the repo was
+	// synced from, or 0 if it is unknown.
+	ExternalServiceID int64
+
+	// CloneOptions are the options to clone the repo with. nil means a full
+	// clone.
+	CloneOptions *gitserverprotocol.CloneOptions
+
+	// PushMirrorURL is the URL of the remote to push the repo to after each
+	// update, if any.
+	PushMirrorURL string
 }
 
 // sourceRepoMap is the set of repositories associated with a specific configuration source.
@@ -71,8 +88,9 @@ func newUpdateScheduler() *updateScheduler {
 	return &updateScheduler{
 		sourceRepos: make(map[string]sourceRepoMap),
 		updateQueue: &updateQueue{
-			index:         make(map[api.RepoName]*repoUpdate),
-			notifyEnqueue: make(chan struct{}, notifyChanBuffer),
+			index:            make(map[api.RepoName]*repoUpdate),
+			externalServices: make(map[int64]*externalServiceBudget),
+			notifyEnqueue:    make(chan struct{}, notifyChanBuffer),
 		},
 		schedule: &schedule{
 			index:  make(map[api.RepoName]*scheduledRepoUpdate),
@@ -161,7 +179,7 @@ func (s *updateScheduler) runUpdateLoop(ctx context.Context) {
 
 // requestRepoUpdate sends a request to gitserver to request an update.
 var requestRepoUpdate = func(ctx context.Context, repo *configuredRepo2, since time.Duration) (*gitserverprotocol.RepoUpdateResponse, error) {
-	return gitserver.DefaultClient.RequestRepoUpdate(ctx, gitserver.Repo{Name: repo.Name, URL: repo.URL}, since)
+	return gitserver.DefaultClient.RequestRepoUpdate(ctx, gitserver.Repo{Name: repo.Name, URL: repo.URL, CloneOptions: repo.CloneOptions, PushMirrorURL: repo.PushMirrorURL}, since)
 }
 
 // configuredLimiter returns a mutable limiter that is
@@ -223,12 +241,34 @@ func (s *updateScheduler) updateSource(source string, newList sourceRepoMap) {
 // UpdateOnce causes a single update of the given repository.
 // It neither adds nor removes the repo from the schedule.
 func (s *updateScheduler) UpdateOnce(name api.RepoName, url string) {
-	repo := &configuredRepo2{
-		Name: name,
-		URL:  url,
-	}
 	schedManualFetch.Inc()
-	s.updateQueue.enqueue(repo, priorityHigh)
+	s.updateQueue.enqueue(s.repoToUpdate(name, url), priorityHigh)
+}
+
+// UpdateFromWebhook is like UpdateOnce, but for updates that are requested by code host
+// webhooks. These are queued behind the updates that are requested by users.
+func (s *updateScheduler) UpdateFromWebhook(name api.RepoName, url string) {
+	schedWebhookFetch.Inc()
+	s.updateQueue.enqueue(s.repoToUpdate(name, url), priorityMedium)
+}
+
+// repoToUpdate returns the repo to enqueue for a single update. If the URL is empty, it uses
+// the URL of the scheduled repo (if any). The external service, clone options and push mirror
+// are always those of the scheduled repo (if any), so that the update counts against the
+// budget of the repo's external service.
+func (s *updateScheduler) repoToUpdate(name api.RepoName, url string) *configuredRepo2 {
+	repo := &configuredRepo2{Name: name, URL: url}
+	s.schedule.mu.Lock()
+	if update := s.schedule.index[name]; update != nil {
+		if repo.URL == "" {
+			repo.URL = update.Repo.URL
+		}
+		repo.ExternalServiceID = update.Repo.ExternalServiceID
+		repo.CloneOptions = update.Repo.CloneOptions
+		repo.PushMirrorURL = update.Repo.PushMirrorURL
+	}
+	s.schedule.mu.Unlock()
+	return repo
 }
 
 // DebugDump returns the state of the update scheduler for debugging.
@@ -312,9 +352,18 @@ func (s *updateScheduler) ScheduleInfo(name api.RepoName) *protocol.RepoUpdateSc
 	s.updateQueue.mu.Lock()
 	if update := s.updateQueue.index[name]; update != nil {
 		result.Queue = &protocol.RepoQueueState{
-			Index:    update.Index,
-			Total:    len(s.updateQueue.index),
-			Updating: update.Updating,
+			Index:             update.Index,
+			Total:             len(s.updateQueue.index),
+			Updating:          update.Updating,
+			Priority:          update.Priority.String(),
+			ExternalServiceID: update.Repo.ExternalServiceID,
+		}
+		if b := s.updateQueue.externalServices[update.Repo.ExternalServiceID]; b != nil && update.Repo.ExternalServiceID != 0 {
+			result.Queue.ExternalServiceUpdating = b.Updating
+			result.Queue.ExternalServiceMaxConcurrentUpdates = b.MaxConcurrent
+			if !update.Updating {
+				result.Queue.WaitingFor = b.waitingFor(timeNow())
+			}
 		}
 	}
 	s.updateQueue.mu.Unlock()
@@ -332,25 +381,79 @@ type updateQueue struct {
 
 	seq uint64
 
+	// externalServices are the update budgets of external services, keyed by external
+	// service ID. It contains the external services that have configured limits or
+	// updating repos. Repos whose external service is unknown (ID 0) have no budget.
+	externalServices map[int64]*externalServiceBudget
+
 	// The queue performs a non-blocking send on this channel
 	// when a new value is enqueued so that the update loop
-	// can wake up if it is idle.
+	// can wake up if it is idle. It also does so when the
+	// budget of an external service with held back updates frees up.
 	notifyEnqueue chan struct{}
+
+	// timer notifies the update loop when the rate limit
+	// of an external service with held back updates expires.
+	timer *time.Timer
 }
 
 type priority int
 
 const (
-	priorityLow priority = iota
-	priorityHigh
+	priorityLow    priority = iota // scheduled updates
+	priorityMedium                 // updates requested by code host webhooks
+	priorityHigh                   // updates requested by users
 )
 
+func (p priority) String() string {
+	switch p {
+	case priorityLow:
+		return "low"
+	case priorityMedium:
+		return "medium"
+	case priorityHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// externalServiceBudget is the update budget of an external service and how much of it is
+// in use.
+type externalServiceBudget struct {
+	MaxConcurrent int           // the maximum number of concurrent updates, or 0 for no limit
+	Interval      time.Duration // the minimum time between the starts of updates, or 0 for no limit
+
+	Updating  int       // the number of repos of the external service that are updating
+	NextStart time.Time // the earliest time at which the next update may start
+	Blocked   bool      // whether updates are held back until an update finishes
+}
+
+// limited reports whether the external service has a configured limit.
+func (b *externalServiceBudget) limited() bool {
+	return b.MaxConcurrent > 0 || b.Interval > 0
+}
+
+// waitingFor returns the reason why an update of a repo of the external service must wait,
+// or "" if it can start now.
+func (b *externalServiceBudget) waitingFor(now time.Time) string {
+	switch {
+	case b.MaxConcurrent > 0 && b.Updating >= b.MaxConcurrent:
+		return protocol.RepoQueueWaitingForExternalServiceConcurrency
+	case now.Before(b.NextStart):
+		return protocol.RepoQueueWaitingForExternalServiceRateLimit
+	default:
+		return ""
+	}
+}
+
 // repoUpdate is a repository that has been queued for an update.
 type repoUpdate struct {
 	Repo     *configuredRepo2
 	Priority priority
 	Seq      uint64 // the sequence number of the update
 	Updating bool   // whether the repo has been acquired for update
+	Waiting  string // why the update is held back by its external service's budget, if it is
 	Index    int    `json:"-"` // the index in the heap
 }
 
@@ -362,6 +465,79 @@ func (q *updateQueue) reset() {
 	q.index = map[api.RepoName]*repoUpdate{}
 	q.seq = 0
 	q.notifyEnqueue = make(chan struct{}, notifyChanBuffer)
+	for id, b := range q.externalServices {
+		b.Updating = 0
+		b.Blocked = false
+		if !b.limited() {
+			delete(q.externalServices, id)
+		}
+	}
+	if q.timer != nil {
+		q.timer.Stop()
+		q.timer = nil
+	}
+}
+
+// setExternalServiceLimits sets the update budget of the external service with the given
+// ID from the updateLimits of its configuration. A nil limits removes its limits. It does
+// nothing for ID 0.
+func (q *updateQueue) setExternalServiceLimits(id int64, limits *schema.UpdateLimits) {
+	if id == 0 {
+		return
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var maxConcurrent int
+	var interval time.Duration
+	if limits != nil {
+		maxConcurrent = limits.MaxConcurrentUpdates
+		if limits.MaxUpdatesPerMinute > 0 {
+			interval = time.Minute / time.Duration(limits.MaxUpdatesPerMinute)
+		}
+	}
+
+	b := q.externalServices[id]
+	if b == nil && maxConcurrent == 0 && interval == 0 {
+		return
+	}
+	b = q.externalService(id)
+	if b.MaxConcurrent == maxConcurrent && b.Interval == interval {
+		return
+	}
+	b.MaxConcurrent = maxConcurrent
+	b.Interval = interval
+	if !b.limited() && b.Updating == 0 {
+		delete(q.externalServices, id)
+	}
+
+	if len(q.heap) > 0 {
+		// Held back updates may be within the new limits.
+		notify(q.notifyEnqueue)
+	}
+}
+
+// externalService returns the update budget of the external service with the given ID,
+// creating it if necessary. The caller must hold the lock on q.mu.
+func (q *updateQueue) externalService(id int64) *externalServiceBudget {
+	b := q.externalServices[id]
+	if b == nil {
+		b = &externalServiceBudget{}
+		q.externalServices[id] = b
+	}
+	return b
+}
+
+// limited reports whether any external service has a configured limit.
+// The caller must hold the lock on q.mu.
+func (q *updateQueue) limited() bool {
+	for _, b := range q.externalServices {
+		if b.limited() {
+			return true
+		}
+	}
+	return false
 }
 
 // enqueue add the repo to the queue with the given priority.
@@ -413,10 +589,34 @@ func (q *updateQueue) remove(repo *configuredRepo2, updating bool) {
 	q.mu.Lock()
 	if update := q.index[repo.Name]; update != nil && update.Updating == updating {
 		heap.Remove(q, update.Index)
+		if updating {
+			q.finishUpdate(update.Repo)
+		}
 	}
 	q.mu.Unlock()
 }
 
+// finishUpdate releases the budget of its external service that the update of the repo used.
+// The caller must hold the lock on q.mu.
+func (q *updateQueue) finishUpdate(repo *configuredRepo2) {
+	id := repo.ExternalServiceID
+	b := q.externalServices[id]
+	if b == nil {
+		return
+	}
+	if b.Updating > 0 {
+		b.Updating--
+	}
+	if b.Blocked {
+		// Updates of the external service's repos were held back until an update finishes.
+		b.Blocked = false
+		notify(q.notifyEnqueue)
+	}
+	if !b.limited() && b.Updating == 0 {
+		delete(q.externalServices, id)
+	}
+}
+
 // acquireNext acquires the next repo for update.
 // The acquired repo must be removed from the queue
 // when the update finishes (independent of success or failure).
@@ -431,18 +631,85 @@ func (q *updateQueue) acquireNext() *configuredRepo2 {
 		// Everything in the queue is already updating.
 		return nil
 	}
+	if q.limited() {
+		// The update at the front of the queue may be held back by the budget of its
+		// external service, in which case an update of another external service's repo
+		// is next.
+		if update = q.nextWithinBudget(); update == nil {
+			return nil
+		}
+	}
 	update.Updating = true
+	update.Waiting = ""
 	heap.Fix(q, update.Index)
+
+	if id := update.Repo.ExternalServiceID; id != 0 {
+		b := q.externalService(id)
+		b.Updating++
+		if b.Interval > 0 {
+			b.NextStart = timeNow().Add(b.Interval)
+		}
+	}
 	return update.Repo
 }
 
+// nextWithinBudget returns the queued update with the highest priority whose external
+// service has budget left, or nil if there is none. If updates are held back by the rate
+// limit of their external service, it arranges for the update loop to be notified when it expires.
+// The caller must hold the lock on q.mu.
+func (q *updateQueue) nextWithinBudget() *repoUpdate {
+	now := timeNow()
+	var (
+		next   *repoUpdate
+		wakeup time.Time
+	)
+	for _, update := range q.heap {
+		if update.Updating || (next != nil && !q.less(update, next)) {
+			continue
+		}
+
+		if b := q.externalServices[update.Repo.ExternalServiceID]; b != nil && update.Repo.ExternalServiceID != 0 {
+			if reason := b.waitingFor(now); reason != "" {
+				if update.Waiting != reason {
+					// Count each update once (per reason), not on every scan of the queue.
+					update.Waiting = reason
+					schedExternalServiceWait.WithLabelValues(reason).Inc()
+				}
+				switch reason {
+				case protocol.RepoQueueWaitingForExternalServiceConcurrency:
+					b.Blocked = true
+				case protocol.RepoQueueWaitingForExternalServiceRateLimit:
+					if wakeup.IsZero() || b.NextStart.Before(wakeup) {
+						wakeup = b.NextStart
+					}
+				}
+				continue
+			}
+		}
+
+		next = update
+	}
+
+	if next == nil && !wakeup.IsZero() {
+		if q.timer != nil {
+			q.timer.Stop()
+		}
+		ch := q.notifyEnqueue
+		q.timer = timeAfterFunc(wakeup.Sub(now), func() {
+			notify(ch)
+		})
+	}
+	return next
+}
+
 // The following methods implement heap.Interface based on the priority queue example:
 // https://golang.org/pkg/container/heap/#example__priorityQueue
 
 func (q *updateQueue) Len() int { return len(q.heap) }
 func (q *updateQueue) Less(i, j int) bool {
-	qi := q.heap[i]
-	qj := q.heap[j]
+	return q.less(q.heap[i], q.heap[j])
+}
+func (q *updateQueue) less(qi, qj *repoUpdate) bool {
 	if qi.Updating != qj.Updating {
 		// Repos that are already updating are sorted last.
 		return qj.Updating
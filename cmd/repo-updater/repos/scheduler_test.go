@@ -11,6 +11,8 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	gitserverprotocol "github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/pkg/mutablelimiter"
+	"github.com/sourcegraph/sourcegraph/pkg/repoupdater/protocol"
+	"github.com/sourcegraph/sourcegraph/schema"
 )
 
 var defaultTime = time.Date(2000, 1, 1, 1, 1, 1, 1, time.UTC)
@@ -490,6 +492,87 @@ func TestUpdateQueue_acquireNext(t *testing.T) {
 	}
 }
 
+func TestUpdateQueue_acquireNextWithinBudget(t *testing.T) {
+	a1 := &configuredRepo2{Name: "a1", URL: "https://a.com/a1", ExternalServiceID: 1}
+	a2 := &configuredRepo2{Name: "a2", URL: "https://a.com/a2", ExternalServiceID: 1}
+	b := &configuredRepo2{Name: "b", URL: "https://a.com/b", ExternalServiceID: 2}
+
+	initialQueue := func() []*repoUpdate {
+		return []*repoUpdate{
+			{Repo: a1, Priority: priorityHigh, Seq: 1},
+			{Repo: a2, Priority: priorityMedium, Seq: 2},
+			{Repo: b, Priority: priorityLow, Seq: 3},
+		}
+	}
+
+	t.Run("concurrency", func(t *testing.T) {
+		r, stop := startRecording()
+		defer stop()
+
+		s := newUpdateScheduler()
+		s.updateQueue.setExternalServiceLimits(1, &schema.UpdateLimits{MaxConcurrentUpdates: 1})
+		setupInitialQueue(s, initialQueue())
+
+		for i, expected := range []*configuredRepo2{a1, b, nil} {
+			if actual := s.updateQueue.acquireNext(); !reflect.DeepEqual(expected, actual) {
+				t.Fatalf("\nacquireNext expected %d\n%s\ngot\n%s", i, spew.Sdump(expected), spew.Sdump(actual))
+			}
+		}
+		if info := s.ScheduleInfo(a2.Name).Queue; info.WaitingFor != protocol.RepoQueueWaitingForExternalServiceConcurrency || info.ExternalServiceID != 1 || info.ExternalServiceUpdating != 1 || info.Priority != "medium" {
+			t.Fatalf("unexpected queue state %+v", info)
+		}
+		update := s.updateQueue.index[a2.Name]
+		if update.Waiting != protocol.RepoQueueWaitingForExternalServiceConcurrency {
+			t.Fatalf("expected a2 to be waiting for concurrency, got %q", update.Waiting)
+		}
+
+		// Finishing the update of a1 frees up the budget of external service 1.
+		s.updateQueue.remove(a1, true)
+		if actual := s.updateQueue.acquireNext(); actual != a2 {
+			t.Fatalf("acquireNext expected a2, got %s", spew.Sdump(actual))
+		}
+		if update.Waiting != "" {
+			t.Fatalf("expected a2 to no longer be waiting, got %q", update.Waiting)
+		}
+
+		expected := []chan struct{}{s.updateQueue.notifyEnqueue}
+		if !reflect.DeepEqual(expected, r.notifications) || len(r.timeAfterFuncDelays) != 0 {
+			t.Fatalf("\nexpected notifications\n%s\ngot\n%s", spew.Sdump(expected), spew.Sdump(r))
+		}
+	})
+
+	t.Run("rate limit", func(t *testing.T) {
+		r, stop := startRecording()
+		defer stop()
+
+		s := newUpdateScheduler()
+		s.updateQueue.setExternalServiceLimits(1, &schema.UpdateLimits{MaxUpdatesPerMinute: 2})
+		setupInitialQueue(s, initialQueue())
+
+		for i, expected := range []*configuredRepo2{a1, b, nil} {
+			if actual := s.updateQueue.acquireNext(); !reflect.DeepEqual(expected, actual) {
+				t.Fatalf("\nacquireNext expected %d\n%s\ngot\n%s", i, spew.Sdump(expected), spew.Sdump(actual))
+			}
+		}
+		if info := s.ScheduleInfo(a2.Name).Queue; info.WaitingFor != protocol.RepoQueueWaitingForExternalServiceRateLimit {
+			t.Fatalf("unexpected queue state %+v", info)
+		}
+
+		mockTime(defaultTime.Add(30 * time.Second))
+		if actual := s.updateQueue.acquireNext(); actual != a2 {
+			t.Fatalf("acquireNext expected a2, got %s", spew.Sdump(actual))
+		}
+
+		expected := &recording{
+			notifications:       []chan struct{}{s.updateQueue.notifyEnqueue},
+			timeAfterFuncDelays: []time.Duration{30 * time.Second},
+		}
+		if !reflect.DeepEqual(expected, r) {
+			t.Fatalf("\nexpected\n%s\ngot\n%s", spew.Sdump(expected), spew.Sdump(r))
+		}
+	})
+}
+
 func setupInitialQueue(s *updateScheduler, initialQueue []*repoUpdate) {
 	for _, update := range initialQueue {
 		heap.Push(s.updateQueue, update)
@@ -1,11 +1,14 @@
 package repos
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strings"
 	"time"
 
@@ -15,7 +18,10 @@ import (
 	"github.com/opentracing-contrib/go-stdlib/nethttp"
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
+	gitserverprotocol "github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
 	"github.com/sourcegraph/sourcegraph/pkg/httputil"
+	"github.com/sourcegraph/sourcegraph/pkg/jsonc"
+	"github.com/sourcegraph/sourcegraph/schema"
 	log15 "gopkg.in/inconshreveable/log15.v2"
 )
 
@@ -57,6 +63,39 @@ func cachedTransportWithCertTrusted(cert string) (http.RoundTripper, error) {
 	}, nil
 }
 
+// externalServiceConfigs fetches the configs of the external services of the
+// given kind into result, which should be a pointer to a slice of the config
+// type of the kind. It returns the IDs of the external services in the same
+// order as their configs. External services with invalid configs are skipped.
+func externalServiceConfigs(ctx context.Context, kind string, result interface{}) ([]int64, error) {
+	services, err := api.InternalClient.ExternalServicesList(ctx, api.ExternalServicesListRequest{Kind: kind})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(services))
+	configs := make([]json.RawMessage, 0, len(services))
+	for _, service := range services {
+		// Raw configs may have comments in them.
+		config, err := jsonc.Parse(service.Config)
+		if err == nil && len(bytes.TrimSpace(config)) == 0 {
+			err = errors.New("empty config")
+		}
+		if err != nil {
+			log15.Error("ignoring external service config that has invalid json", "id", service.ID, "displayName", service.DisplayName, "err", err)
+			continue
+		}
+		ids = append(ids, service.ID)
+		configs = append(configs, config)
+	}
+
+	data, err := json.Marshal(configs)
+	if err != nil {
+		return nil, err
+	}
+	return ids, json.Unmarshal(data, result)
+}
+
 // A repoCreateOrUpdateRequest is a RepoCreateOrUpdateRequest, from the API,
 // plus a specific URL we'd like to use for it.
 type repoCreateOrUpdateRequest struct {
@@ -67,8 +106,12 @@ type repoCreateOrUpdateRequest struct {
 // createEnableUpdateRepos receives requests on the provided channel. The
 // source argument should be a distinctive string identifying the configuration
 // being updated, so repo-updater can detect when repositories are dropped from
-// a given source.
-func createEnableUpdateRepos(ctx context.Context, source string, repoChan <-chan repoCreateOrUpdateRequest) {
+// a given source. externalServiceID is the ID of the external service whose
+// configuration the source is (0 if none), and updateLimits are the limits on
+// its repository updates. cloneOptions and pushMirror are the clone options and
+// push mirror of the source's configuration (see repoCloneOptions and
+// repoPushMirrorURL).
+func createEnableUpdateRepos(ctx context.Context, source string, externalServiceID int64, updateLimits *schema.UpdateLimits, cloneOptions []*schema.GitCloneOptions, pushMirror *schema.PushMirror, repoChan <-chan repoCreateOrUpdateRequest) {
 	c := conf.Get()
 	newList := make(sourceRepoList)
 	newScheduler := newSchedulerEnabled(c)
@@ -84,6 +127,7 @@ func createEnableUpdateRepos(ctx context.Context, source string, repoChan <-chan
 			log15.Warn("Error creating or updating repository", "repo", op.RepoName, "error", err)
 			return
 		}
+		repoSeen(createdRepo.ID)
 
 		err = api.InternalClient.ReposUpdateMetadata(ctx, op.RepoName, op.Description, op.Fork, op.Archived)
 		if err != nil {
@@ -91,13 +135,18 @@ func createEnableUpdateRepos(ctx context.Context, source string, repoChan <-chan
 			return
 		}
 
+		opts := repoCloneOptions(cloneOptions, createdRepo.Name)
+		pushMirrorURL := repoPushMirrorURL(pushMirror, createdRepo.Name)
 		if !newScheduler {
-			newList[string(createdRepo.Name)] = configuredRepo{url: op.URL, enabled: createdRepo.Enabled}
+			newList[string(createdRepo.Name)] = configuredRepo{url: op.URL, enabled: createdRepo.Enabled, cloneOptions: opts, pushMirrorURL: pushMirrorURL}
 		} else if !c.DisableAutoGitUpdates {
 			newMap[createdRepo.Name] = &configuredRepo2{
-				Name:    createdRepo.Name,
-				URL:     op.URL,
-				Enabled: createdRepo.Enabled,
+				Name:              createdRepo.Name,
+				URL:               op.URL,
+				Enabled:           createdRepo.Enabled,
+				ExternalServiceID: externalServiceID,
+				CloneOptions:      opts,
+				PushMirrorURL:     pushMirrorURL,
 			}
 		}
 	}
@@ -109,10 +158,41 @@ func createEnableUpdateRepos(ctx context.Context, source string, repoChan <-chan
 	if !newScheduler {
 		repos.updateSource(source, newList)
 	} else if !c.DisableAutoGitUpdates {
+		Scheduler.updateQueue.setExternalServiceLimits(externalServiceID, updateLimits)
 		Scheduler.updateSource(source, newMap)
 	}
 }
 
+// repoCloneOptions returns the options to clone the repo with the given name
+// with, from the first entry of opts whose pattern matches the name. It
+// returns nil (a full clone) if no entry matches.
+func repoCloneOptions(opts []*schema.GitCloneOptions, name api.RepoName) *gitserverprotocol.CloneOptions {
+	for _, o := range opts {
+		pattern, err := regexp.Compile(o.Pattern)
+		if err != nil {
+			log15.Warn("ignoring clone options with invalid pattern", "pattern", o.Pattern, "error", err)
+			continue
+		}
+		if pattern.MatchString(string(name)) {
+			return &gitserverprotocol.CloneOptions{
+				Depth:         o.Depth,
+				BlobSizeLimit: o.BlobSizeLimit,
+				SparsePaths:   o.SparsePaths,
+			}
+		}
+	}
+	return nil
+}
+
+// repoPushMirrorURL returns the URL of the push mirror of the repo with the
+// given name, or "" if there is no push mirror.
+func repoPushMirrorURL(m *schema.PushMirror, name api.RepoName) string {
+	if m == nil {
+		return ""
+	}
+	return strings.Replace(m.Url, "{name}", string(name), -1)
+}
+
 // setUserinfoBestEffort adds the username and password to rawurl. If user is
 // not set in rawurl, username is used. If password is not set and there is a
 // user, password is used. If anything fails, the original rawurl is returned.
@@ -148,6 +228,21 @@ func setUserinfoBestEffort(rawurl, username, password string) string {
 	return u.String()
 }
 
+// trimRepositoryPathPattern returns the part of the repo name that was substituted
+// for placeholder when the name was generated with repositoryPathPattern (after
+// replacing "{host}" with host). It only supports patterns that end with placeholder.
+// If name could not have been generated with the pattern, ok is false.
+func trimRepositoryPathPattern(name api.RepoName, repositoryPathPattern, placeholder, host string) (value string, ok bool) {
+	if !strings.HasSuffix(repositoryPathPattern, placeholder) {
+		return "", false
+	}
+	prefix := strings.Replace(strings.TrimSuffix(repositoryPathPattern, placeholder), "{host}", host, -1)
+	if len(name) <= len(prefix) || !strings.EqualFold(string(name[:len(prefix)]), prefix) {
+		return "", false
+	}
+	return string(name[len(prefix):]), true
+}
+
 // worker represents a worker that does work under some context and can be restarted.
 type worker struct {
 	// work is invoked to perform work under the given context. It should
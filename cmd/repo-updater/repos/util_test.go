@@ -1,6 +1,13 @@
 package repos
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	gitserverprotocol "github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
 
 func TestSetUserinfoBestEffort(t *testing.T) {
 	cases := []struct {
@@ -41,3 +48,59 @@ func TestSetUserinfoBestEffort(t *testing.T) {
 		}
 	}
 }
+
+func TestTrimRepositoryPathPattern(t *testing.T) {
+	cases := []struct {
+		name    api.RepoName
+		pattern string
+		want    string
+		wantOK  bool
+	}{
+		{"gitea.example.com/owner/repo", "{host}/{nameWithOwner}", "owner/repo", true},
+		{"Gitea.Example.com/owner/repo", "{host}/{nameWithOwner}", "owner/repo", true},
+		{"gitea/owner/repo", "gitea/{nameWithOwner}", "owner/repo", true},
+		{"owner/repo", "{nameWithOwner}", "owner/repo", true},
+
+		{"github.com/owner/repo", "{host}/{nameWithOwner}", "", false},
+		{"gitea.example.com/", "{host}/{nameWithOwner}", "", false},
+		{"gitea.example.com/owner/repo", "{nameWithOwner}.git", "", false},
+	}
+	for _, c := range cases {
+		got, ok := trimRepositoryPathPattern(c.name, c.pattern, "{nameWithOwner}", "gitea.example.com")
+		if got != c.want || ok != c.wantOK {
+			t.Errorf("trimRepositoryPathPattern(%q, %q): got %q, %v want %q, %v", c.name, c.pattern, got, ok, c.want, c.wantOK)
+		}
+	}
+}
+
+func TestRepoCloneOptions(t *testing.T) {
+	opts := []*schema.GitCloneOptions{
+		{Pattern: "(", Depth: 1}, // invalid, ignored
+		{Pattern: "^github.com/big/", Depth: 50, BlobSizeLimit: "1m"},
+		{Pattern: "monorepo$", SparsePaths: []string{"docs"}},
+	}
+	cases := []struct {
+		name api.RepoName
+		want *gitserverprotocol.CloneOptions
+	}{
+		{"github.com/big/repo", &gitserverprotocol.CloneOptions{Depth: 50, BlobSizeLimit: "1m"}},
+		{"github.com/big/monorepo", &gitserverprotocol.CloneOptions{Depth: 50, BlobSizeLimit: "1m"}},
+		{"github.com/small/monorepo", &gitserverprotocol.CloneOptions{SparsePaths: []string{"docs"}}},
+		{"github.com/small/repo", nil},
+	}
+	for _, c := range cases {
+		if got := repoCloneOptions(opts, c.name); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("repoCloneOptions(%q): got %+v want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRepoPushMirrorURL(t *testing.T) {
+	if got := repoPushMirrorURL(nil, "github.com/foo/bar"); got != "" {
+		t.Errorf("got %q without push mirror, want empty", got)
+	}
+	m := &schema.PushMirror{Url: "https://u:p@mirror.example.com/{name}.git"}
+	if got, want := repoPushMirrorURL(m, "github.com/foo/bar"), "https://u:p@mirror.example.com/github.com/foo/bar.git"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
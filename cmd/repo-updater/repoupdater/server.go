@@ -86,7 +86,11 @@ func (s *Server) handleEnqueueRepoUpdate(w http.ResponseWriter, r *http.Request)
 	}
 
 	if conf.UpdateScheduler2Enabled() {
-		repos.Scheduler.UpdateOnce(req.Repo, req.URL)
+		if req.Trigger == protocol.RepoUpdateTriggerWebhook {
+			repos.Scheduler.UpdateFromWebhook(req.Repo, req.URL)
+		} else {
+			repos.Scheduler.UpdateOnce(req.Repo, req.URL)
+		}
 		return
 	}
 
@@ -148,6 +152,9 @@ func (s *Server) repoLookup(ctx context.Context, args protocol.RepoLookupArgs) (
 		repos.GetGitHubRepository,
 		repos.GetGitLabRepository,
 		repos.GetBitbucketServerRepository,
+		repos.GetBitbucketCloudRepository,
+		repos.GetGiteaRepository,
+		repos.GetGerritRepository,
 		repos.GetAWSCodeCommitRepository,
 		repos.GetGitoliteRepository,
 	} {
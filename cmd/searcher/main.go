@@ -23,6 +23,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/debugserver"
 	"github.com/sourcegraph/sourcegraph/pkg/env"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/lfs"
 	"github.com/sourcegraph/sourcegraph/pkg/search/rpc"
 	"github.com/sourcegraph/sourcegraph/pkg/tracer"
 	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
@@ -53,6 +54,9 @@ func main() {
 			FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
 				return git.Archive(ctx, repo, git.ArchiveOptions{Treeish: string(commit), Format: "tar"})
 			},
+			FetchLFSObject: func(ctx context.Context, repo gitserver.Repo, p lfs.Pointer) ([]byte, error) {
+				return gitserver.DefaultClient.LFSObject(ctx, repo.Name, p)
+			},
 			Path:              filepath.Join(cacheDir, "searcher-archives"),
 			MaxCacheSizeBytes: cacheSizeBytes,
 		},
@@ -61,6 +65,7 @@ func main() {
 	service.Store.SetMaxConcurrentFetchTar(10)
 	service.Store.Start()
 	handler := nethttp.Middleware(opentracing.GlobalTracer(), service)
+	replaceHandler := nethttp.Middleware(opentracing.GlobalTracer(), http.HandlerFunc(service.ServeReplace))
 	rpcHandler, err := rpc.Server(&search.StoreSearcher{Store: service.Store})
 	if err != nil {
 		log.Fatal(err)
@@ -84,6 +89,10 @@ func main() {
 				rpcHandler.ServeHTTP(w, r)
 				return
 			}
+			if r.URL.Path == "/replace" {
+				replaceHandler.ServeHTTP(w, r)
+				return
+			}
 
 			handler.ServeHTTP(w, r)
 		}),
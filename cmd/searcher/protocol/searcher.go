@@ -61,6 +61,17 @@ type PatternInfo struct {
 	// when finding matches.
 	IsCaseSensitive bool
 
+	// IsMultiline if true will allow matches to span multiple lines. For
+	// example "func main\(\) \{\n" will only match in multiline mode.
+	IsMultiline bool
+
+	// IsStructural if true will treat the Pattern as a structural search
+	// pattern, such as "fmt.Errorf(:[args])". Holes (":[name]") match text
+	// with balanced brackets, skipping strings and comments. IsRegExp,
+	// IsWordMatch and IsMultiline are ignored, and the pattern only
+	// matches file contents.
+	IsStructural bool
+
 	// ExcludePattern is a pattern that may not match the returned files' paths.
 	// eg '**/node_modules'
 	ExcludePattern string
@@ -114,6 +125,40 @@ func (p PatternInfo) AllIncludePatterns() []string {
 	return all
 }
 
+// ReplaceRequest represents a request to searcher to preview replacing the
+// matches of a pattern.
+type ReplaceRequest struct {
+	Request
+
+	// Replacement is what each match is replaced with. It may refer to the
+	// pattern's capture groups as $1 or ${name} (see regexp.Regexp.Expand).
+	// eg "fmt.Errorf($1)"
+	Replacement string
+}
+
+// ReplaceResponse represents the response from a Replace request.
+type ReplaceResponse struct {
+	FileDiffs []FileDiff
+
+	// LimitHit is true if FileDiffs may not include all changed files
+	// because a match limit was hit.
+	LimitHit bool
+
+	// DeadlineHit is true if FileDiffs may not include all changed files
+	// because a deadline was hit.
+	DeadlineHit bool
+}
+
+// FileDiff is the change that replacing matches makes to a file.
+type FileDiff struct {
+	Path string
+
+	// Diff is the unified diff of the file's contents at the searched
+	// commit and after replacing matches. Path is used as both the old and
+	// the new file name (with no "a/" or "b/" prefix).
+	Diff string
+}
+
 // Response represents the response from a Search request.
 type Response struct {
 	Matches []FileMatch
@@ -135,6 +180,10 @@ type FileMatch struct {
 }
 
 // LineMatch is the struct used by vscode to receive search results for a line.
+//
+// In multiline mode a match can span multiple lines. Preview then contains
+// all of the lines spanned by the matches, and Ranges describes where each
+// match starts and ends.
 type LineMatch struct {
 	// Preview is the matched line.
 	Preview string
@@ -143,11 +192,34 @@ type LineMatch struct {
 	// 1-based line numbers, but internally vscode uses 0-based.
 	LineNumber int
 
+	// EndLineNumber is the 0-based line number of the last line in
+	// Preview. It is only different to LineNumber in multiline mode.
+	EndLineNumber int
+
 	// OffsetAndLengths is a slice of 2-tuples (Offset, Length)
 	// representing each match on a line.
 	// Offsets and lengths are measured in characters, not bytes.
+	// Offsets are relative to the start of Preview.
 	OffsetAndLengths [][2]int
 
+	// Ranges is the range in the file of each match in OffsetAndLengths.
+	Ranges []Range
+
 	// LimitHit is true if OffsetAndLengths may not include all OffsetAndLengths.
 	LimitHit bool
 }
+
+// Range is a range in a file. Start is inclusive and End is exclusive.
+type Range struct {
+	Start, End Position
+}
+
+// Position is a position in a file.
+type Position struct {
+	// Line is the 0-based line number.
+	Line int
+
+	// Character is the 0-based offset in the line, measured in characters
+	// (not bytes).
+	Character int
+}
@@ -462,10 +462,14 @@ func (c *contentProvider) fillMatches(candidates []*candidateMatch) []api.LineMa
 			})
 		}
 
+		// A match may span multiple lines, in which case Line contains all
+		// of them.
+		lineNumber := bytes.Count(data[:lineStart], []byte{'\n'}) + 1
 		result = append(result, api.LineMatch{
 			// Intentionally create a copy since we can't hold onto data
 			Line:          append([]byte{}, data[lineStart:lineEnd]...),
-			LineNumber:    bytes.Count(data[:lineStart], []byte{'\n'}) + 1,
+			LineNumber:    lineNumber,
+			EndLineNumber: lineNumber + bytes.Count(data[lineStart:lineEnd], []byte{'\n'}),
 			LineFragments: fragments,
 		})
 	}
@@ -43,6 +43,10 @@ const (
 	// maxOffsets is the limit on number of matches to return on a line.
 	maxOffsets = 10
 
+	// maxPreviewSize is the maximum size in bytes of the lines spanned by a
+	// multiline match. Matches spanning more than this are not returned.
+	maxPreviewSize = 10 * maxLineSize
+
 	// numWorkers is how many concurrent readerGreps run per
 	// concurrentFind
 	numWorkers = 8
@@ -71,6 +75,13 @@ type readerGrep struct {
 	// ignoreCase if true means we need to do case insensitive matching.
 	ignoreCase bool
 
+	// isMultiline if true means matches may span multiple lines.
+	isMultiline bool
+
+	// structural is the structural pattern to match instead of re, or nil
+	// if the pattern is not structural.
+	structural *structuralPattern
+
 	// transformBuf is reused between file searches to avoid
 	// re-allocating. It is only used if we need to transform the input
 	// before matching. For example we lower case the input in the case of
@@ -92,9 +103,17 @@ type readerGrep struct {
 func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 	var (
 		re               *regexp.Regexp
+		structural       *structuralPattern
 		literalSubstring []byte
 	)
-	if p.Pattern != "" {
+	if p.IsStructural && p.Pattern != "" {
+		var err error
+		structural, err = compileStructural(p.Pattern, !p.IsCaseSensitive)
+		if err != nil {
+			return nil, err
+		}
+		literalSubstring = structural.longestLiteral()
+	} else if p.Pattern != "" {
 		expr := p.Pattern
 		if !p.IsRegExp {
 			expr = regexp.QuoteMeta(expr)
@@ -149,6 +168,8 @@ func compile(p *protocol.PatternInfo) (*readerGrep, error) {
 	return &readerGrep{
 		re:               re,
 		ignoreCase:       !p.IsCaseSensitive,
+		isMultiline:      p.IsMultiline,
+		structural:       structural,
 		matchPath:        matchPath,
 		literalSubstring: literalSubstring,
 	}, nil
@@ -164,6 +185,8 @@ func (rg *readerGrep) Copy() *readerGrep {
 	return &readerGrep{
 		re:               reCopy,
 		ignoreCase:       rg.ignoreCase,
+		isMultiline:      rg.isMultiline,
+		structural:       rg.structural,
 		matchPath:        rg.matchPath.Copy(),
 		literalSubstring: rg.literalSubstring,
 	}
@@ -181,7 +204,9 @@ func (rg *readerGrep) matchString(s string) bool {
 	return rg.re.MatchString(s)
 }
 
-// Find returns a LineMatch for each line that matches rg in reader.
+// Find returns a LineMatch for each line that matches rg in reader. If rg is
+// multiline or structural, a LineMatch may span multiple lines (see
+// findMultiline).
 // LimitHit is true if some matches may not have been included in the result.
 // NOTE: This is not safe to use concurrently.
 func (rg *readerGrep) Find(zf *zipFile, f *srcFile) (matches []protocol.LineMatch, limitHit bool, err error) {
@@ -214,11 +239,20 @@ func (rg *readerGrep) Find(zf *zipFile, f *srcFile) (matches []protocol.LineMatc
 	if !bytes.Contains(fileMatchBuf, rg.literalSubstring) {
 		return nil, false, nil
 	}
+	if rg.structural != nil {
+		matches, limitHit = rg.structural.find(fileBuf, fileMatchBuf, structuralSyntaxFor(f.Name))
+		return matches, limitHit, nil
+	}
 	first := rg.re.FindIndex(fileMatchBuf)
 	if first == nil {
 		return nil, false, nil
 	}
 
+	if rg.isMultiline {
+		matches, limitHit = findMultiline(rg.re, fileBuf, fileMatchBuf)
+		return matches, limitHit, nil
+	}
+
 	idx := 0
 	for i := 0; len(matches) < maxLineMatches; i++ {
 		advance, lineBuf, err := bufio.ScanLines(fileBuf, true)
@@ -254,11 +288,16 @@ func (rg *readerGrep) Find(zf *zipFile, f *srcFile) (matches []protocol.LineMatc
 		if len(locs) > 0 {
 			lineLimitHit := len(locs) == maxOffsets
 			offsetAndLengths := make([][2]int, len(locs))
-			for i, match := range locs {
+			ranges := make([]protocol.Range, len(locs))
+			for j, match := range locs {
 				start, end := match[0], match[1]
 				offset := utf8.RuneCount(lineBuf[:start])
 				length := utf8.RuneCount(lineBuf[start:end])
-				offsetAndLengths[i] = [2]int{offset, length}
+				offsetAndLengths[j] = [2]int{offset, length}
+				ranges[j] = protocol.Range{
+					Start: protocol.Position{Line: i, Character: offset},
+					End:   protocol.Position{Line: i, Character: offset + length},
+				}
 			}
 			matches = append(matches, protocol.LineMatch{
 				// making a copy of lineBuf is intentional.
@@ -269,7 +308,9 @@ func (rg *readerGrep) Find(zf *zipFile, f *srcFile) (matches []protocol.LineMatc
 				// Special care must be taken to call Close on all possible paths, including error paths.
 				Preview:          string(lineBuf),
 				LineNumber:       i,
+				EndLineNumber:    i,
 				OffsetAndLengths: offsetAndLengths,
+				Ranges:           ranges,
 				LimitHit:         lineLimitHit,
 			})
 		}
@@ -278,6 +319,109 @@ func (rg *readerGrep) Find(zf *zipFile, f *srcFile) (matches []protocol.LineMatc
 	return matches, limitHit, nil
 }
 
+// findMultiline returns the LineMatches for the matches of re in
+// fileMatchBuf, which may span multiple lines. fileBuf is the original data
+// (for Preview), fileMatchBuf is the (possibly transformed) data to match
+// on. They must have the same length.
+func findMultiline(re *regexp.Regexp, fileBuf, fileMatchBuf []byte) (matches []protocol.LineMatch, limitHit bool) {
+	locs := re.FindAllIndex(fileMatchBuf, maxLineMatches*maxOffsets)
+	matches, limitHit = multilineMatches(fileBuf, locs)
+	return matches, limitHit || len(locs) == maxLineMatches*maxOffsets
+}
+
+// multilineMatches returns the LineMatches for the matches in fileBuf at
+// the byte ranges locs, which must be in order and non-overlapping. The
+// matches may span multiple lines.
+//
+// A match starting on a line which is part of the Preview of the previous
+// LineMatch is added to that LineMatch. So the LineMatches never overlap.
+func multilineMatches(fileBuf []byte, locs [][]int) (matches []protocol.LineMatch, limitHit bool) {
+	var (
+		// line is the 0-based line number of the line starting at byte
+		// offset lineStart. It only moves forward, since the matches are
+		// in order.
+		line, lineStart int
+
+		// cur is the LineMatch we are adding matches to. Its Preview is
+		// fileBuf[curStart:curEnd].
+		cur              *protocol.LineMatch
+		curStart, curEnd int
+	)
+	// positionOf returns the position of the byte offset i, which must not
+	// be before lineStart.
+	positionOf := func(i int) protocol.Position {
+		for {
+			j := bytes.IndexByte(fileBuf[lineStart:i], '\n')
+			if j < 0 {
+				break
+			}
+			lineStart += j + 1
+			line++
+		}
+		return protocol.Position{Line: line, Character: utf8.RuneCount(fileBuf[lineStart:i])}
+	}
+	flush := func() {
+		if cur != nil {
+			// Making a copy of the preview is intentional, see Find.
+			cur.Preview = string(fileBuf[curStart:curEnd])
+			matches = append(matches, *cur)
+			cur = nil
+		}
+	}
+
+	for _, loc := range locs {
+		start, end := loc[0], loc[1]
+		startPos := positionOf(start)
+		startLineStart := lineStart
+		endPos := positionOf(end)
+
+		// The preview ends at the end of the line containing the last
+		// character of the match. So a match ending in a newline does not
+		// include the following line.
+		last, endLine := end, endPos.Line
+		if end > start && fileBuf[end-1] == '\n' {
+			last, endLine = end-1, endLine-1
+		}
+		previewEnd := len(fileBuf)
+		if j := bytes.IndexByte(fileBuf[last:], '\n'); j >= 0 {
+			previewEnd = last + j
+		}
+
+		if cur != nil && start <= curEnd {
+			// The match starts on a line in the preview of cur.
+			if len(cur.OffsetAndLengths) == maxOffsets || previewEnd-curStart > maxPreviewSize {
+				cur.LimitHit = true
+				continue
+			}
+			if previewEnd > curEnd {
+				curEnd = previewEnd
+				cur.EndLineNumber = endLine
+			}
+		} else {
+			flush()
+			if len(matches) == maxLineMatches {
+				break
+			}
+			// Skip matches that span too much text.
+			if previewEnd-startLineStart > maxPreviewSize {
+				continue
+			}
+			cur = &protocol.LineMatch{
+				LineNumber:    startPos.Line,
+				EndLineNumber: endLine,
+			}
+			curStart, curEnd = startLineStart, previewEnd
+		}
+
+		offset := utf8.RuneCount(fileBuf[curStart:start])
+		length := utf8.RuneCount(fileBuf[start:end])
+		cur.OffsetAndLengths = append(cur.OffsetAndLengths, [2]int{offset, length})
+		cur.Ranges = append(cur.Ranges, protocol.Range{Start: startPos, End: endPos})
+	}
+	flush()
+	return matches, len(matches) == maxLineMatches
+}
+
 // FindZip is a convenience function to run Find on f.
 func (rg *readerGrep) FindZip(zf *zipFile, f *srcFile) (protocol.FileMatch, error) {
 	lm, limitHit, err := rg.Find(zf, f)
@@ -331,7 +475,7 @@ func concurrentFind(ctx context.Context, rg *readerGrep, zf *zipFile, fileMatchL
 		matches   = []protocol.FileMatch{}
 	)
 
-	if patternMatchesPaths && (!patternMatchesContent || rg.re == nil) {
+	if patternMatchesPaths && (!patternMatchesContent || (rg.re == nil && rg.structural == nil)) {
 		// Fast path for only matching file paths (or with a nil pattern, which matches all files,
 		// so is effectively matching only on file paths).
 		for _, f := range files {
@@ -465,6 +465,105 @@ func TestLineLimit(t *testing.T) {
 	}
 }
 
+func TestFindMultiline(t *testing.T) {
+	data := []byte("func foo(\n\tctx context.Context) {\n}\nfunc bar(\n\tctx int)\n")
+
+	rng := func(startLine, startCharacter, endLine, endCharacter int) protocol.Range {
+		return protocol.Range{
+			Start: protocol.Position{Line: startLine, Character: startCharacter},
+			End:   protocol.Position{Line: endLine, Character: endCharacter},
+		}
+	}
+
+	tests := []struct {
+		pattern     protocol.PatternInfo
+		wantMatches []protocol.LineMatch
+	}{
+		{
+			pattern: protocol.PatternInfo{Pattern: `FUNC\s+\w+\(\n\s+ctx`, IsRegExp: true, IsMultiline: true},
+			wantMatches: []protocol.LineMatch{
+				{
+					Preview:          "func foo(\n\tctx context.Context) {",
+					LineNumber:       0,
+					EndLineNumber:    1,
+					OffsetAndLengths: [][2]int{{0, 14}},
+					Ranges:           []protocol.Range{rng(0, 0, 1, 4)},
+				},
+				{
+					Preview:          "func bar(\n\tctx int)",
+					LineNumber:       3,
+					EndLineNumber:    4,
+					OffsetAndLengths: [][2]int{{0, 14}},
+					Ranges:           []protocol.Range{rng(3, 0, 4, 4)},
+				},
+			},
+		},
+		{
+			// Without multiline, matches can't span lines.
+			pattern: protocol.PatternInfo{Pattern: `func\s+\w+\(\n\s+ctx`, IsRegExp: true},
+		},
+		{
+			// A match ending in a newline ends at the start of the next
+			// line, but the preview does not include it.
+			pattern: protocol.PatternInfo{Pattern: `\{\n`, IsRegExp: true, IsMultiline: true},
+			wantMatches: []protocol.LineMatch{
+				{
+					Preview:          "\tctx context.Context) {",
+					LineNumber:       1,
+					EndLineNumber:    1,
+					OffsetAndLengths: [][2]int{{22, 2}},
+					Ranges:           []protocol.Range{rng(1, 22, 2, 0)},
+				},
+			},
+		},
+		{
+			// A match starting on the last line of the previous match is
+			// part of the same LineMatch.
+			pattern: protocol.PatternInfo{Pattern: `\(\n\s*ctx|\)`, IsRegExp: true, IsMultiline: true},
+			wantMatches: []protocol.LineMatch{
+				{
+					Preview:          "func foo(\n\tctx context.Context) {",
+					LineNumber:       0,
+					EndLineNumber:    1,
+					OffsetAndLengths: [][2]int{{8, 6}, {30, 1}},
+					Ranges:           []protocol.Range{rng(0, 8, 1, 4), rng(1, 20, 1, 21)},
+				},
+				{
+					Preview:          "func bar(\n\tctx int)",
+					LineNumber:       3,
+					EndLineNumber:    4,
+					OffsetAndLengths: [][2]int{{8, 6}, {18, 1}},
+					Ranges:           []protocol.Range{rng(3, 8, 4, 4), rng(4, 8, 4, 9)},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern.Pattern, func(t *testing.T) {
+			rg, err := compile(&test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fakeZipFile := zipFile{
+				MaxLen: len(data),
+				Data:   data,
+			}
+			fakeSrcFile := srcFile{Len: int32(len(data))}
+			matches, limitHit, err := rg.Find(&fakeZipFile, &fakeSrcFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if limitHit {
+				t.Fatalf("expected limit to not hit")
+			}
+			if !reflect.DeepEqual(matches, test.wantMatches) {
+				t.Errorf("got matches %+v, want %+v", matches, test.wantMatches)
+			}
+		})
+	}
+}
+
 func TestMaxMatches(t *testing.T) {
 	pattern := "foo"
 
@@ -0,0 +1,377 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/net/trace"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+)
+
+// diffContextLines is the number of unchanged lines shown around each change
+// in a unified diff (like the default of diff -u).
+const diffContextLines = 3
+
+// ServeReplace handles HTTP based requests to preview replacing the matches
+// of a pattern. It responds with the unified diffs of the changed files.
+func (s *Service) ServeReplace(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	running.Inc()
+	defer running.Dec()
+
+	err := r.ParseForm()
+	if err != nil {
+		http.Error(w, "failed to parse form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var p protocol.ReplaceRequest
+	err = decoder.Decode(&p, r.Form)
+	if err != nil {
+		http.Error(w, "failed to decode form: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if p.Deadline != "" {
+		var deadline time.Time
+		if err := deadline.UnmarshalText([]byte(p.Deadline)); err != nil {
+			http.Error(w, "invalid deadline: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		dctx, cancel := context.WithDeadline(ctx, deadline)
+		defer cancel()
+		ctx = dctx
+	}
+	// Only file contents are replaced.
+	p.PatternMatchesContent = true
+	p.PatternMatchesPath = false
+	if err = validateParams(&p.Request); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fileDiffs, limitHit, deadlineHit, err := s.replace(ctx, &p)
+	if err != nil {
+		code := http.StatusInternalServerError
+		if isBadRequest(err) || ctx.Err() == context.Canceled {
+			code = http.StatusBadRequest
+		} else if isTemporary(err) {
+			code = http.StatusServiceUnavailable
+		}
+		http.Error(w, err.Error(), code)
+		return
+	}
+	if fileDiffs == nil {
+		// Return an empty list
+		fileDiffs = make([]protocol.FileDiff, 0)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(&protocol.ReplaceResponse{
+		FileDiffs:   fileDiffs,
+		LimitHit:    limitHit,
+		DeadlineHit: deadlineHit,
+	})
+}
+
+func (s *Service) replace(ctx context.Context, p *protocol.ReplaceRequest) (fileDiffs []protocol.FileDiff, limitHit, deadlineHit bool, err error) {
+	tr := trace.New("replace", fmt.Sprintf("%s@%s", p.Repo, p.Commit))
+	tr.LazyPrintf("%s -> %s", p.Pattern, p.Replacement)
+	defer func() {
+		if err != nil {
+			tr.LazyPrintf("error: %v", err)
+			tr.SetError()
+		}
+		tr.LazyPrintf("fileDiffs=%d limitHit=%v deadlineHit=%v", len(fileDiffs), limitHit, deadlineHit)
+		tr.Finish()
+	}()
+
+	if p.IsStructural {
+		return nil, false, false, badRequestError{"replacing structural search matches is not supported"}
+	}
+	if p.Pattern == "" {
+		return nil, false, false, badRequestError{"Pattern must be non-empty"}
+	}
+	rg, err := compile(&p.PatternInfo)
+	if err != nil {
+		return nil, false, false, badRequestError{err.Error()}
+	}
+
+	if p.FetchTimeout == "" {
+		p.FetchTimeout = "500ms"
+	}
+	fetchTimeout, err := time.ParseDuration(p.FetchTimeout)
+	if err != nil {
+		return nil, false, false, err
+	}
+	prepareCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+	path, err := s.Store.prepareZip(prepareCtx, p.GitserverRepo(), p.Commit)
+	if err != nil {
+		return nil, false, false, err
+	}
+	zf, err := s.Store.zipCache.get(path)
+	if err != nil {
+		return nil, false, false, err
+	}
+	defer zf.Close()
+
+	matches, limitHit, err := concurrentFind(ctx, rg, zf, p.FileMatchLimit, true, false)
+	if err != nil {
+		return nil, false, false, err
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		deadlineHit = true
+	}
+
+	files := make(map[string]*srcFile, len(matches))
+	for _, fm := range matches {
+		files[fm.Path] = nil
+	}
+	for i := range zf.Files {
+		if _, ok := files[zf.Files[i].Name]; ok {
+			files[zf.Files[i].Name] = &zf.Files[i]
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Path < matches[j].Path })
+	for _, fm := range matches {
+		f := files[fm.Path]
+		if f == nil {
+			continue
+		}
+		if diff := unifiedDiff(f.Name, zf.DataFor(f), rg.replaceEdits(zf, f, p.Replacement)); diff != "" {
+			fileDiffs = append(fileDiffs, protocol.FileDiff{Path: f.Name, Diff: diff})
+		}
+	}
+	return fileDiffs, limitHit, deadlineHit, nil
+}
+
+// edit replaces the bytes [start, end) of a file with text.
+type edit struct {
+	start, end int
+	text       []byte
+}
+
+// replaceEdits returns the edits that replace each match of rg in f with
+// template, in which capture groups are expanded as by regexp.Expand. The
+// matches are the same as those of Find (except that there is no limit on
+// the number of matches).
+// NOTE: This is not safe to use concurrently.
+func (rg *readerGrep) replaceEdits(zf *zipFile, f *srcFile, template string) []edit {
+	if rg.ignoreCase && rg.transformBuf == nil {
+		rg.transformBuf = make([]byte, zf.MaxLen)
+	}
+
+	fileBuf := zf.DataFor(f)
+	fileMatchBuf := fileBuf
+	if rg.ignoreCase {
+		fileMatchBuf = rg.transformBuf[:len(fileBuf)]
+		bytesToLowerASCII(fileMatchBuf, fileBuf)
+	}
+
+	var locs [][]int
+	if rg.isMultiline {
+		locs = rg.re.FindAllSubmatchIndex(fileMatchBuf, -1)
+	} else {
+		for start := 0; start < len(fileMatchBuf); {
+			end := len(fileMatchBuf)
+			if i := bytes.IndexByte(fileMatchBuf[start:], '\n'); i >= 0 {
+				end = start + i
+			}
+			// Match each line without its line ending, like Find.
+			line := bytes.TrimSuffix(fileMatchBuf[start:end], []byte{'\r'})
+			if len(line) <= maxLineSize {
+				for _, loc := range rg.re.FindAllSubmatchIndex(line, -1) {
+					for i := range loc {
+						if loc[i] >= 0 {
+							loc[i] += start
+						}
+					}
+					locs = append(locs, loc)
+				}
+			}
+			start = end + 1
+		}
+	}
+
+	edits := make([]edit, len(locs))
+	for i, loc := range locs {
+		// The capture groups are expanded with the original (not
+		// lowercased) data, which has the same offsets.
+		edits[i] = edit{
+			start: loc[0],
+			end:   loc[1],
+			text:  rg.re.Expand(nil, []byte(template), fileBuf, loc),
+		}
+	}
+	return edits
+}
+
+// unifiedDiff returns the unified diff of data and the result of applying
+// edits to data, or "" if the edits don't change data. The edits must be in
+// order and non-overlapping.
+func unifiedDiff(name string, data []byte, edits []edit) string {
+	// Find the changed regions of whole lines, merging edits on the same
+	// or adjacent lines.
+	type region struct {
+		start, end int    // byte offsets in data, at line boundaries
+		edits      []edit // edits within [start, end)
+	}
+	var regions []*region
+	for _, e := range edits {
+		if bytes.Equal(data[e.start:e.end], e.text) {
+			continue
+		}
+		start := bytes.LastIndexByte(data[:e.start], '\n') + 1
+		end := len(data)
+		if e.end > e.start && data[e.end-1] == '\n' {
+			end = e.end
+		} else if i := bytes.IndexByte(data[e.end:], '\n'); i >= 0 {
+			end = e.end + i + 1
+		}
+		if n := len(regions); n > 0 && start <= regions[n-1].end {
+			last := regions[n-1]
+			if end > last.end {
+				last.end = end
+			}
+			last.edits = append(last.edits, e)
+			continue
+		}
+		regions = append(regions, &region{start: start, end: end, edits: []edit{e}})
+	}
+	if len(regions) == 0 {
+		return ""
+	}
+
+	// lineStarts[i] is the byte offset of the start of the line i.
+	lineStarts := []int{0}
+	for i, c := range data {
+		if c == '\n' && i+1 < len(data) {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+	numLines := len(lineStarts)
+	if len(data) == 0 {
+		numLines = 0
+	}
+	// lineAt returns the line that starts at or contains offset (or
+	// numLines at the end of data).
+	lineAt := func(offset int) int {
+		if offset >= len(data) {
+			return numLines
+		}
+		return sort.Search(numLines, func(i int) bool { return lineStarts[i] > offset }) - 1
+	}
+	line := func(i int) []byte {
+		if i+1 < numLines {
+			return data[lineStarts[i]:lineStarts[i+1]]
+		}
+		return data[lineStarts[i]:]
+	}
+
+	var (
+		b     strings.Builder
+		hunk  bytes.Buffer
+		delta int // the number of lines added (or removed) before the hunk
+	)
+	writeLine := func(prefix byte, line []byte) {
+		hunk.WriteByte(prefix)
+		hunk.Write(line)
+		if !bytes.HasSuffix(line, []byte{'\n'}) {
+			hunk.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", name, name)
+	for i := 0; i < len(regions); {
+		// Each hunk contains the regions whose context lines overlap.
+		hunk.Reset()
+		oldStart := lineAt(regions[i].start) - diffContextLines
+		if oldStart < 0 {
+			oldStart = 0
+		}
+		oldEnd := oldStart
+		oldLines, newLines := 0, 0
+		for first := true; i < len(regions); i, first = i+1, false {
+			r := regions[i]
+			start, end := lineAt(r.start), lineAt(r.end)
+			if !first && start-oldEnd > 2*diffContextLines {
+				break
+			}
+			var newText []byte
+			offset := r.start
+			for _, e := range r.edits {
+				newText = append(newText, data[offset:e.start]...)
+				newText = append(newText, e.text...)
+				offset = e.end
+			}
+			newText = append(newText, data[offset:r.end]...)
+			var added [][]byte
+			for len(newText) > 0 {
+				n := bytes.IndexByte(newText, '\n') + 1
+				if n == 0 {
+					n = len(newText)
+				}
+				added = append(added, newText[:n])
+				newText = newText[n:]
+			}
+
+			// Lines at the start or end of the region that the edits
+			// leave unchanged are context, not changes.
+			for start < end && len(added) > 0 && bytes.Equal(line(start), added[0]) {
+				start++
+				added = added[1:]
+			}
+			unchangedEnd := end
+			for unchangedEnd > start && len(added) > 0 && bytes.Equal(line(unchangedEnd-1), added[len(added)-1]) {
+				unchangedEnd--
+				added = added[:len(added)-1]
+			}
+
+			for ; oldEnd < start; oldEnd++ {
+				writeLine(' ', line(oldEnd))
+				oldLines++
+				newLines++
+			}
+			for ; oldEnd < unchangedEnd; oldEnd++ {
+				writeLine('-', line(oldEnd))
+				oldLines++
+			}
+			for _, l := range added {
+				writeLine('+', l)
+				newLines++
+			}
+			for ; oldEnd < end; oldEnd++ {
+				writeLine(' ', line(oldEnd))
+				oldLines++
+				newLines++
+			}
+		}
+		for n := 0; n < diffContextLines && oldEnd < numLines; n++ {
+			writeLine(' ', line(oldEnd))
+			oldEnd++
+			oldLines++
+			newLines++
+		}
+
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(oldStart, oldLines), hunkRange(oldStart+delta, newLines))
+		b.Write(hunk.Bytes())
+		delta += newLines - oldLines
+	}
+	return b.String()
+}
+
+// hunkRange formats the range of a unified diff hunk that starts at the
+// 0-based line start.
+func hunkRange(start, lines int) string {
+	if lines == 0 {
+		// An empty range refers to the line before it.
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, lines)
+}
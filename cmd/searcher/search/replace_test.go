@@ -0,0 +1,174 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+)
+
+func TestReplaceEdits(t *testing.T) {
+	data := []byte("foo(a)\nFOO(b)\r\nbar(foo(c))\n")
+
+	tests := []struct {
+		pattern     protocol.PatternInfo
+		replacement string
+		want        string
+	}{
+		{
+			pattern:     protocol.PatternInfo{Pattern: `foo\((\w)\)`, IsRegExp: true},
+			replacement: "baz($1, $1)",
+			want:        "baz(a, a)\nbaz(b, b)\r\nbar(baz(c, c))\n",
+		},
+		{
+			pattern:     protocol.PatternInfo{Pattern: `foo\((?P<arg>\w)\)`, IsRegExp: true, IsCaseSensitive: true},
+			replacement: "${arg}",
+			want:        "a\nFOO(b)\r\nbar(c)\n",
+		},
+		{
+			pattern:     protocol.PatternInfo{Pattern: "foo(a)"},
+			replacement: "$1",
+			want:        "\nFOO(b)\r\nbar(foo(c))\n",
+		},
+		{
+			// Without multiline, matches can't span lines.
+			pattern:     protocol.PatternInfo{Pattern: `\)\s+`, IsRegExp: true},
+			replacement: ")",
+			want:        string(data),
+		},
+		{
+			pattern:     protocol.PatternInfo{Pattern: `\)\s+`, IsRegExp: true, IsMultiline: true},
+			replacement: ") ",
+			want:        "foo(a) FOO(b) bar(foo(c)) ",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.pattern.Pattern, func(t *testing.T) {
+			rg, err := compile(&test.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fakeZipFile := zipFile{
+				MaxLen: len(data),
+				Data:   data,
+			}
+			fakeSrcFile := srcFile{Len: int32(len(data))}
+			edits := rg.replaceEdits(&fakeZipFile, &fakeSrcFile, test.replacement)
+
+			var got []byte
+			offset := 0
+			for _, e := range edits {
+				got = append(got, data[offset:e.start]...)
+				got = append(got, e.text...)
+				offset = e.end
+			}
+			got = append(got, data[offset:]...)
+			if string(got) != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	data := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n16\n17\n18\n19\n20")
+
+	// replace returns the edit that replaces the first occurrence of old.
+	replace := func(old, new string) edit {
+		for i := 0; i+len(old) <= len(data); i++ {
+			if string(data[i:i+len(old)]) == old {
+				return edit{start: i, end: i + len(old), text: []byte(new)}
+			}
+		}
+		panic("not found: " + old)
+	}
+
+	tests := map[string]struct {
+		edits []edit
+		want  string
+	}{
+		"no edits": {},
+		"unchanged": {
+			edits: []edit{replace("2\n", "2\n")},
+		},
+		"single line": {
+			edits: []edit{replace("5", "five")},
+			want: `--- f
++++ f
+@@ -2,7 +2,7 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+`,
+		},
+		"merged hunks and lines": {
+			edits: []edit{replace("1\n", "one\n"), replace("2\n3", "two three"), replace("9\n", "")},
+			want: `--- f
++++ f
+@@ -1,12 +1,10 @@
+-1
+-2
+-3
++one
++two three
+ 4
+ 5
+ 6
+ 7
+ 8
+-9
+ 10
+ 11
+ 12
+`,
+		},
+		"separate hunks and end of file": {
+			edits: []edit{replace("2\n", "2\n2.5\n"), replace("20", "twenty\n")},
+			want: `--- f
++++ f
+@@ -1,5 +1,6 @@
+ 1
+ 2
++2.5
+ 3
+ 4
+ 5
+@@ -17,4 +18,4 @@
+ 17
+ 18
+ 19
+-20
+\ No newline at end of file
++twenty
+`,
+		},
+		"insertion at end of file": {
+			edits: []edit{{start: len(data), end: len(data), text: []byte("\n21")}},
+			want: `--- f
++++ f
+@@ -17,4 +17,5 @@
+ 17
+ 18
+ 19
+-20
+\ No newline at end of file
++20
++21
+\ No newline at end of file
+`,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := unifiedDiff("f", data, test.edits)
+			if got != test.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, test.want)
+			}
+		})
+	}
+}
@@ -83,6 +83,11 @@ main.go:5:func main() {
 main.go:5:func main() {
 `},
 
+		{protocol.PatternInfo{Pattern: `main\(\) \{\n\s+fmt`, IsRegExp: true, IsMultiline: true}, `
+main.go:5:func main() {
+	fmt.Println("Hello world")
+`},
+
 		{protocol.PatternInfo{Pattern: "mai", IsWordMatch: true}, ""},
 
 		{protocol.PatternInfo{Pattern: "main", IsWordMatch: true}, `
@@ -351,6 +356,9 @@ func doSearch(u string, p *protocol.Request) ([]protocol.FileMatch, error) {
 	if p.IsCaseSensitive {
 		form.Set("IsCaseSensitive", "true")
 	}
+	if p.IsMultiline {
+		form.Set("IsMultiline", "true")
+	}
 	if p.PathPatternsAreRegExps {
 		form.Set("PathPatternsAreRegExps", "true")
 	}
@@ -9,12 +9,14 @@ import (
 	"encoding/hex"
 	"io"
 	"log"
+	"os"
 	"sync"
 	"time"
 
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/diskcache"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/lfs"
 	"github.com/sourcegraph/sourcegraph/pkg/mutablelimiter"
 
 	opentracing "github.com/opentracing/opentracing-go"
@@ -45,6 +47,12 @@ type Store struct {
 	// determine if the error is a bad request (eg invalid repo).
 	FetchTar func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error)
 
+	// FetchLFSObject returns the content of the file stored with Git LFS with
+	// the LFS pointer p in repo. It returns an error satisfying os.IsNotExist
+	// if the content is not available. If it is nil, the LFS pointers of files
+	// stored with Git LFS are searched instead of their content.
+	FetchLFSObject func(ctx context.Context, repo gitserver.Repo, p lfs.Pointer) ([]byte, error)
+
 	// Path is the directory to store the cache
 	Path string
 
@@ -225,7 +233,13 @@ func (s *Store) fetch(ctx context.Context, repo gitserver.Repo, commit api.Commi
 		defer r.Close()
 		tr := tar.NewReader(r)
 		zw := zip.NewWriter(pw)
-		err := copySearchable(tr, zw)
+		var fetchLFSObject func(lfs.Pointer) ([]byte, error)
+		if s.FetchLFSObject != nil {
+			fetchLFSObject = func(p lfs.Pointer) ([]byte, error) {
+				return s.FetchLFSObject(ctx, repo, p)
+			}
+		}
+		err := copySearchable(tr, zw, fetchLFSObject)
 		if err1 := zw.Close(); err == nil {
 			err = err1
 		}
@@ -238,8 +252,9 @@ func (s *Store) fetch(ctx context.Context, repo gitserver.Repo, commit api.Commi
 
 // copySearchable copies searchable files from tr to zw. A searchable file is
 // any file that is a candidate for being searched (under size limit and
-// non-binary).
-func copySearchable(tr *tar.Reader, zw *zip.Writer) error {
+// non-binary). The content of files stored with Git LFS is copied instead of
+// their LFS pointer if fetchLFSObject is non-nil and the content is available.
+func copySearchable(tr *tar.Reader, zw *zip.Writer, fetchLFSObject func(lfs.Pointer) ([]byte, error)) error {
 	// 32*1024 is the same size used by io.Copy
 	buf := make([]byte, 32*1024)
 	for {
@@ -281,6 +296,31 @@ func copySearchable(tr *tar.Reader, zw *zip.Writer) error {
 			continue
 		}
 
+		// Search the content of a file stored with Git LFS instead of its LFS
+		// pointer.
+		if fetchLFSObject != nil && int64(n) == hdr.Size {
+			if p, ok := lfs.ParsePointer(buf[:n]); ok && p.Size <= maxFileSize {
+				content, err := fetchLFSObject(*p)
+				if err != nil && !os.IsNotExist(err) {
+					return err
+				}
+				if err == nil {
+					// We only search names of binary files.
+					head := content
+					if len(head) > len(buf) {
+						head = head[:len(buf)]
+					}
+					if bytes.IndexByte(head, 0x00) >= 0 {
+						continue
+					}
+					if _, err := w.Write(content); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+		}
+
 		// Heuristic: Assume file is binary if first 256 bytes contain a
 		// 0x00. Best effort, so ignore err. We only search names of binary files.
 		if n > 0 && bytes.IndexByte(buf[:n], 0x00) >= 0 {
@@ -2,11 +2,13 @@ package search
 
 import (
 	"archive/tar"
+	"archive/zip"
 	"bytes"
 	"context"
 	"io"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -14,6 +16,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/lfs"
 )
 
 func TestPrepareZip(t *testing.T) {
@@ -95,6 +98,82 @@ func TestPrepareZip_fetchTarFail(t *testing.T) {
 	}
 }
 
+func TestCopySearchable_lfs(t *testing.T) {
+	const (
+		textOID    = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+		binaryOID  = "b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c"
+		missingOID = "7d865e959b2466918c9863afca942d0fb89d7c9ac0c99bafc3749504ded97730"
+	)
+	pointer := func(oid string) string {
+		return "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 10\n"
+	}
+	files := map[string]string{
+		"text.txt":    pointer(textOID),
+		"binary.bin":  pointer(binaryOID),
+		"missing.txt": pointer(missingOID),
+		"regular.txt": "regular\n",
+	}
+
+	buf := new(bytes.Buffer)
+	tw := tar.NewWriter(buf)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fetchLFSObject := func(p lfs.Pointer) ([]byte, error) {
+		switch p.OID {
+		case textOID:
+			return []byte("LFS text\n"), nil
+		case binaryOID:
+			return []byte("LFS\x00bin\n"), nil
+		}
+		return nil, &os.PathError{Op: "LFSObject", Path: p.OID, Err: os.ErrNotExist}
+	}
+	zipBuf := new(bytes.Buffer)
+	zw := zip.NewWriter(zipBuf)
+	if err := copySearchable(tar.NewReader(buf), zw, fetchLFSObject); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(zipBuf.Bytes()), int64(zipBuf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[f.Name] = string(b)
+	}
+	want := map[string]string{
+		"text.txt":    "LFS text\n",
+		"binary.bin":  "", // only the name of binary files is searched
+		"missing.txt": pointer(missingOID),
+		"regular.txt": "regular\n",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
 func tmpStore(t *testing.T) (*Store, func()) {
 	d, err := ioutil.TempDir("", "search_test")
 	if err != nil {
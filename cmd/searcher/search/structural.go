@@ -0,0 +1,483 @@
+package search
+
+import (
+	"bytes"
+	"errors"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+)
+
+// This file implements structural search. A structural pattern such as
+//
+//   fmt.Errorf(:[args])
+//
+// consists of literal text and holes. A hole (":[name]") matches any text in
+// which brackets are balanced, so ":[args]" above matches all the arguments
+// of the call, even if they contain nested calls or span multiple lines.
+// Holes skip over strings and comments, so brackets inside them are ignored.
+// Holes that are not inside brackets in the pattern do not match across
+// lines (except inside brackets in the matched text).
+//
+// Other rules:
+//
+// - ":[[name]]" matches a single word (like \w+).
+// - Holes with the same name must match the same text (except ":[_]").
+// - A hole at the start of the pattern starts at the beginning of the line
+//   (after any indentation), and a hole at the end of the pattern extends to
+//   the end of the line.
+// - Whitespace in the pattern matches any amount of whitespace. It may match
+//   no whitespace if it is not between 2 words.
+
+// maxStructuralSteps bounds the work done to match a structural pattern in a
+// single file, since matching holes requires backtracking.
+const maxStructuralSteps = 1 << 20
+
+type structuralTokenKind int
+
+const (
+	structuralLiteral  structuralTokenKind = iota // literal text
+	structuralSpace                               // whitespace
+	structuralHole                                // :[name]
+	structuralWordHole                            // :[[name]]
+)
+
+type structuralToken struct {
+	kind structuralTokenKind
+	text []byte // the literal text, or the name of a hole
+
+	// inBrackets is whether a hole is inside brackets in the pattern, in
+	// which case it may match newlines.
+	inBrackets bool
+}
+
+// structuralPattern is a compiled structural search pattern.
+type structuralPattern struct {
+	tokens []structuralToken
+}
+
+// compileStructural compiles a structural search pattern. If ignoreCase is
+// true, the pattern is lowercased, so it must be matched against lowercased
+// data.
+func compileStructural(pattern string, ignoreCase bool) (*structuralPattern, error) {
+	var (
+		p       structuralPattern
+		literal []byte
+		depth   int // the bracket depth of the pattern so far
+	)
+	flushLiteral := func() {
+		if len(literal) > 0 {
+			p.tokens = append(p.tokens, structuralToken{kind: structuralLiteral, text: literal})
+			literal = nil
+		}
+	}
+
+	pattern = strings.TrimSpace(pattern)
+	for i := 0; i < len(pattern); {
+		if isSpace(pattern[i]) {
+			flushLiteral()
+			for i < len(pattern) && isSpace(pattern[i]) {
+				i++
+			}
+			p.tokens = append(p.tokens, structuralToken{kind: structuralSpace})
+			continue
+		}
+		if kind, name, n := parseHole(pattern[i:]); n > 0 {
+			flushLiteral()
+			p.tokens = append(p.tokens, structuralToken{kind: kind, text: []byte(name), inBrackets: depth > 0})
+			i += n
+			continue
+		}
+		if isOpeningBracket(pattern[i]) {
+			depth++
+		} else if isClosingBracket(pattern[i]) && depth > 0 {
+			depth--
+		}
+		literal = append(literal, pattern[i])
+		i++
+	}
+	flushLiteral()
+
+	if p.longestLiteral() == nil {
+		return nil, errors.New("structural pattern must contain text other than holes")
+	}
+	if ignoreCase {
+		for _, t := range p.tokens {
+			if t.kind == structuralLiteral {
+				bytesToLowerASCII(t.text, t.text)
+			}
+		}
+	}
+	return &p, nil
+}
+
+// parseHole parses the hole at the start of s. It returns the number of
+// bytes of s used by the hole, or 0 if s does not start with a hole.
+func parseHole(s string) (kind structuralTokenKind, name string, n int) {
+	if !strings.HasPrefix(s, ":[") {
+		return 0, "", 0
+	}
+	kind, open, close := structuralHole, ":[", "]"
+	if strings.HasPrefix(s, ":[[") {
+		kind, open, close = structuralWordHole, ":[[", "]]"
+	}
+	end := strings.Index(s, close)
+	if end < len(open) {
+		return 0, "", 0
+	}
+	name = s[len(open):end]
+	for i := 0; i < len(name); i++ {
+		if !isWordChar(name[i]) {
+			return 0, "", 0
+		}
+	}
+	return kind, name, end + len(close)
+}
+
+// longestLiteral returns the longest literal text in p, which appears in
+// every match of p.
+func (p *structuralPattern) longestLiteral() []byte {
+	var longest []byte
+	for _, t := range p.tokens {
+		if t.kind == structuralLiteral && len(t.text) > len(longest) {
+			longest = t.text
+		}
+	}
+	return longest
+}
+
+// find returns the LineMatches for the matches of p. fileBuf is the
+// original data (for Preview), fileMatchBuf is the (possibly transformed)
+// data to match on. They must have the same length.
+func (p *structuralPattern) find(fileBuf, fileMatchBuf []byte, syntax *structuralSyntax) (matches []protocol.LineMatch, limitHit bool) {
+	m := structuralMatcher{pattern: p, data: fileMatchBuf, syntax: syntax}
+	locs := m.findAll(maxLineMatches * maxOffsets)
+	matches, limitHit = multilineMatches(fileBuf, locs)
+	return matches, limitHit || len(locs) == maxLineMatches*maxOffsets || m.steps > maxStructuralSteps
+}
+
+// structuralMatcher matches a structural pattern against data.
+type structuralMatcher struct {
+	pattern *structuralPattern
+	data    []byte
+	syntax  *structuralSyntax
+
+	steps int               // number of calls to match so far
+	holes map[string][]byte // the text matched by each named hole
+}
+
+// findAll returns the byte ranges of up to n non-overlapping matches, in
+// order.
+func (m *structuralMatcher) findAll(n int) (locs [][]int) {
+	tokens := m.pattern.tokens
+	for pos := 0; pos < len(m.data) && len(locs) < n && m.steps <= maxStructuralSteps; {
+		start := m.nextStart(pos)
+		if start < 0 {
+			break
+		}
+		m.holes = nil
+		if end, ok := m.match(0, start); ok && end > start {
+			locs = append(locs, []int{start, end})
+			pos = end
+			continue
+		}
+		if tokens[0].kind == structuralHole {
+			// Try the next line.
+			pos = start + bytes.IndexByte(m.data[start:], '\n') + 1
+			if pos == start {
+				break
+			}
+		} else {
+			pos = start + 1
+		}
+	}
+	return locs
+}
+
+// nextStart returns the first offset at or after pos where a match may
+// start, or -1 if there is none.
+func (m *structuralMatcher) nextStart(pos int) int {
+	first := m.pattern.tokens[0]
+	switch first.kind {
+	case structuralLiteral:
+		i := bytes.Index(m.data[pos:], first.text)
+		if i < 0 {
+			return -1
+		}
+		return pos + i
+	case structuralHole:
+		// Leading holes start at the beginning of a line.
+		if pos > 0 && m.data[pos-1] != '\n' {
+			i := bytes.IndexByte(m.data[pos:], '\n')
+			if i < 0 {
+				return -1
+			}
+			pos += i + 1
+		}
+		for pos < len(m.data) && isSpace(m.data[pos]) && m.data[pos] != '\n' {
+			pos++
+		}
+		return pos
+	default:
+		return pos
+	}
+}
+
+// match returns the end offset of a match of the pattern's tokens[i:] at
+// the offset pos in data.
+func (m *structuralMatcher) match(i, pos int) (end int, ok bool) {
+	m.steps++
+	if m.steps > maxStructuralSteps {
+		return 0, false
+	}
+	tokens := m.pattern.tokens
+	if i == len(tokens) {
+		return pos, true
+	}
+
+	t := tokens[i]
+	switch t.kind {
+	case structuralLiteral:
+		if !bytes.HasPrefix(m.data[pos:], t.text) {
+			return 0, false
+		}
+		return m.match(i+1, pos+len(t.text))
+
+	case structuralSpace:
+		next := pos
+		for next < len(m.data) && isSpace(m.data[next]) {
+			next++
+		}
+		if next == pos && pos > 0 && pos < len(m.data) && isWordChar(m.data[pos-1]) && isWordChar(m.data[pos]) {
+			// Whitespace is required between 2 words.
+			return 0, false
+		}
+		return m.match(i+1, next)
+
+	case structuralWordHole:
+		next := pos
+		for next < len(m.data) && isWordChar(m.data[next]) {
+			next++
+		}
+		if next == pos {
+			return 0, false
+		}
+		bound, ok := m.bind(t.text, m.data[pos:next])
+		if !ok {
+			return 0, false
+		}
+		end, ok := m.match(i+1, next)
+		if !ok && bound {
+			m.unbind(t.text)
+		}
+		return end, ok
+
+	case structuralHole:
+		last := i == len(tokens)-1
+		next := pos
+		for {
+			// Holes are lazy, so try to match the rest of the pattern
+			// before extending the hole. A hole at the end of the pattern
+			// extends as far as possible.
+			if !last {
+				if bound, ok := m.bind(t.text, m.data[pos:next]); ok {
+					if end, ok := m.match(i+1, next); ok {
+						return end, true
+					}
+					if bound {
+						m.unbind(t.text)
+					}
+				}
+			}
+			n := m.holeExtent(next, t.inBrackets)
+			if n < 0 {
+				break
+			}
+			next = n
+		}
+		if last && next > pos {
+			if _, ok := m.bind(t.text, m.data[pos:next]); ok {
+				return next, true
+			}
+		}
+		return 0, false
+	}
+	return 0, false
+}
+
+// holeExtent returns the offset after the text at pos that a hole can
+// extend over: a bracketed group, a string or comment, or any other
+// character. It returns -1 if a hole can't extend past pos, which is the
+// case at the end of data, at an unbalanced closing bracket and, unless
+// newlines is true, at a newline outside of brackets.
+func (m *structuralMatcher) holeExtent(pos int, newlines bool) int {
+	if pos >= len(m.data) {
+		return -1
+	}
+	c := m.data[pos]
+	switch {
+	case (c == '\n' && !newlines) || isClosingBracket(c):
+		return -1
+	case isOpeningBracket(c):
+		return m.skipBrackets(pos)
+	}
+	if end := m.syntax.skip(m.data, pos); end > pos {
+		return end
+	}
+	_, size := utf8.DecodeRune(m.data[pos:])
+	return pos + size
+}
+
+// skipBrackets returns the offset after the closing bracket matching the
+// opening bracket at pos, or -1 if the brackets are not balanced.
+func (m *structuralMatcher) skipBrackets(pos int) int {
+	var stack []byte
+	for pos < len(m.data) {
+		c := m.data[pos]
+		switch {
+		case isOpeningBracket(c):
+			stack = append(stack, closingBracket(c))
+		case isClosingBracket(c):
+			if stack[len(stack)-1] != c {
+				return -1
+			}
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return pos + 1
+			}
+		default:
+			if end := m.syntax.skip(m.data, pos); end > pos {
+				pos = end
+				continue
+			}
+		}
+		pos++
+	}
+	return -1
+}
+
+// bind records that the hole named name matched value. It returns ok ==
+// false if the hole already matched different text, and bound == true if
+// the hole was not matched before (so the caller must unbind it when
+// backtracking).
+func (m *structuralMatcher) bind(name, value []byte) (bound, ok bool) {
+	if len(name) == 0 || string(name) == "_" {
+		return false, true
+	}
+	if prev, ok := m.holes[string(name)]; ok {
+		return false, bytes.Equal(prev, value)
+	}
+	if m.holes == nil {
+		m.holes = make(map[string][]byte)
+	}
+	m.holes[string(name)] = value
+	return true, true
+}
+
+// unbind undoes a call to bind that returned bound == true.
+func (m *structuralMatcher) unbind(name []byte) {
+	delete(m.holes, string(name))
+}
+
+// structuralSyntax describes the strings and comments of a language, which
+// holes skip over when matching brackets.
+type structuralSyntax struct {
+	lineComment  string    // e.g. "//"
+	blockComment [2]string // e.g. {"/*", "*/"}
+	quotes       string    // quotes of strings with backslash escapes that end at a newline
+	rawQuotes    string    // quotes of strings without escapes that may span lines
+}
+
+var (
+	cStyleSyntax = &structuralSyntax{
+		lineComment:  "//",
+		blockComment: [2]string{"/*", "*/"},
+		quotes:       `"'`,
+		rawQuotes:    "`",
+	}
+	hashCommentSyntax = &structuralSyntax{
+		lineComment: "#",
+		quotes:      `"'`,
+	}
+	genericSyntax = &structuralSyntax{
+		quotes: `"`,
+	}
+)
+
+// structuralSyntaxFor returns the syntax of the file named name, based on
+// its extension.
+func structuralSyntaxFor(name string) *structuralSyntax {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".c", ".h", ".cc", ".cpp", ".hpp", ".cs", ".go", ".java", ".js", ".jsx", ".kt", ".m", ".php", ".rs", ".scala", ".swift", ".ts", ".tsx":
+		return cStyleSyntax
+	case ".bash", ".pl", ".py", ".r", ".rb", ".sh", ".toml", ".yaml", ".yml":
+		return hashCommentSyntax
+	}
+	return genericSyntax
+}
+
+// skip returns the offset after the string or comment starting at pos in
+// data. If there is none, it returns pos.
+func (s *structuralSyntax) skip(data []byte, pos int) int {
+	rest := data[pos:]
+	c := rest[0]
+	switch {
+	case s.lineComment != "" && bytes.HasPrefix(rest, []byte(s.lineComment)):
+		// The newline is not part of the comment, so that a hole outside
+		// of brackets still ends at it.
+		if i := bytes.IndexByte(rest, '\n'); i >= 0 {
+			return pos + i
+		}
+		return len(data)
+	case s.blockComment[0] != "" && bytes.HasPrefix(rest, []byte(s.blockComment[0])):
+		start := len(s.blockComment[0])
+		if i := bytes.Index(rest[start:], []byte(s.blockComment[1])); i >= 0 {
+			return pos + start + i + len(s.blockComment[1])
+		}
+	case strings.IndexByte(s.rawQuotes, c) >= 0:
+		if i := bytes.IndexByte(rest[1:], c); i >= 0 {
+			return pos + 1 + i + 1
+		}
+	case strings.IndexByte(s.quotes, c) >= 0:
+		for i := 1; i < len(rest); i++ {
+			switch rest[i] {
+			case '\\':
+				i++
+			case '\n':
+				// Unterminated, so treat the quote as a normal character.
+				return pos
+			case c:
+				return pos + i + 1
+			}
+		}
+	}
+	return pos
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isOpeningBracket(c byte) bool {
+	return c == '(' || c == '[' || c == '{'
+}
+
+func isClosingBracket(c byte) bool {
+	return c == ')' || c == ']' || c == '}'
+}
+
+func closingBracket(c byte) byte {
+	switch c {
+	case '(':
+		return ')'
+	case '[':
+		return ']'
+	}
+	return '}'
+}
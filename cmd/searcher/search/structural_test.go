@@ -0,0 +1,152 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/searcher/protocol"
+)
+
+func TestStructuralPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		file    string
+		data    string
+		want    []string
+	}{
+		{
+			name:    "balanced",
+			pattern: "fmt.Errorf(:[args])",
+			data:    `return fmt.Errorf("x: %s", f(a, g(b))), fmt.Errorf("y")`,
+			want:    []string{`fmt.Errorf("x: %s", f(a, g(b)))`, `fmt.Errorf("y")`},
+		},
+		{
+			name:    "brackets in strings and comments",
+			pattern: "f(:[x])",
+			file:    "a.go",
+			data:    "f(\")\", ')', `)`, /* ) */ a)",
+			want:    []string{"f(\")\", ')', `)`, /* ) */ a)"},
+		},
+		{
+			name:    "line comment",
+			pattern: "f(:[x])",
+			file:    "a.go",
+			data:    "f(a, // )\n b)",
+			want:    []string{"f(a, // )\n b)"},
+		},
+		{
+			name:    "no line comments in generic syntax",
+			pattern: "f(:[x])",
+			data:    "f(a, // )\n b)",
+			want:    []string{"f(a, // )"},
+		},
+		{
+			name:    "newlines outside of brackets",
+			pattern: "x = :[v]",
+			data:    "x = f(\n) + 1\ny",
+			want:    []string{"x = f(\n) + 1"},
+		},
+		{
+			name:    "mismatched brackets",
+			pattern: "f(:[x])",
+			data:    "f(a[)] f(b)",
+			want:    []string{"f(b)"},
+		},
+		{
+			name:    "holes don't span lines outside of brackets",
+			pattern: "if :[cond] {",
+			data:    "if a &&\n b {\nif c(\n) {",
+			want:    []string{"if c(\n) {"},
+		},
+		{
+			name:    "whitespace",
+			pattern: "if  :[cond]  {",
+			data:    "if x{\nif\n\ty  {\nifz {",
+			want:    []string{"if x{", "if\n\ty  {"},
+		},
+		{
+			name:    "word hole",
+			pattern: ":[[a]].Close()",
+			data:    "f().Close() x.Close()",
+			want:    []string{"x.Close()"},
+		},
+		{
+			name:    "same name",
+			pattern: ":[[a]] == :[[a]]",
+			data:    "x == y\nz == z",
+			want:    []string{"z == z"},
+		},
+		{
+			name:    "leading and trailing holes",
+			pattern: ":[lhs] = append(:[args]):[rest]",
+			data:    "\tx.y = append(x.y, z) // c\n",
+			want:    []string{"x.y = append(x.y, z) // c"},
+		},
+		{
+			name:    "not a hole",
+			pattern: "a:[b-c]",
+			data:    "a:[b-c] a:[b]",
+			want:    []string{"a:[b-c]"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p, err := compileStructural(test.pattern, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := structuralMatcher{pattern: p, data: []byte(test.data), syntax: structuralSyntaxFor(test.file)}
+			var got []string
+			for _, loc := range m.findAll(maxLineMatches) {
+				got = append(got, test.data[loc[0]:loc[1]])
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCompileStructural_error(t *testing.T) {
+	for _, pattern := range []string{":[a]", " :[a] :[[b]] "} {
+		if _, err := compileStructural(pattern, false); err == nil {
+			t.Errorf("%q: got nil error", pattern)
+		}
+	}
+}
+
+func TestFindStructural(t *testing.T) {
+	data := []byte("func f() error {\n\treturn FMT.Errorf(\"%s\",\n\t\tx)\n}\n")
+	rg, err := compile(&protocol.PatternInfo{Pattern: "fmt.Errorf(:[args])", IsStructural: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeZipFile := zipFile{
+		MaxLen: len(data),
+		Data:   data,
+	}
+	fakeSrcFile := srcFile{Name: "f.go", Len: int32(len(data))}
+	matches, limitHit, err := rg.Find(&fakeZipFile, &fakeSrcFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limitHit {
+		t.Fatalf("expected limit to not hit")
+	}
+	want := []protocol.LineMatch{
+		{
+			Preview:          "\treturn FMT.Errorf(\"%s\",\n\t\tx)",
+			LineNumber:       1,
+			EndLineNumber:    2,
+			OffsetAndLengths: [][2]int{{8, 21}},
+			Ranges: []protocol.Range{{
+				Start: protocol.Position{Line: 1, Character: 8},
+				End:   protocol.Position{Line: 2, Character: 4},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("got matches %+v, want %+v", matches, want)
+	}
+}
@@ -2,22 +2,25 @@ package symbols
 
 import (
 	"bytes"
-	"compress/gzip"
 	"context"
-	"encoding/gob"
 	"io"
 	"io/ioutil"
+	"os"
 
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
 	otlog "github.com/opentracing/opentracing-go/log"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
 	"github.com/sourcegraph/sourcegraph/pkg/symbols/protocol"
 	"golang.org/x/net/trace"
+	log15 "gopkg.in/inconshreveable/log15.v2"
 )
 
-func (s *Service) indexedSymbols(ctx context.Context, repo api.RepoName, commitID api.CommitID) (symbols []protocol.Symbol, err error) {
-	span, ctx := opentracing.StartSpanFromContext(ctx, "indexedSymbols")
+// openIndex opens the symbol index of the repository at the commit, creating
+// it if it is not cached. The caller must close the index.
+func (s *Service) openIndex(ctx context.Context, repo api.RepoName, commitID api.CommitID) (index *symbolIndex, err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "openIndex")
 	defer func() {
 		if err != nil {
 			ext.Error.Set(span, true)
@@ -26,14 +29,16 @@ func (s *Service) indexedSymbols(ctx context.Context, repo api.RepoName, commitI
 		span.Finish()
 	}()
 
-	key := string(repo) + ":" + string(commitID) + ":v1" // suffix is index format version (vN)
+	key := indexKey(repo, commitID)
 
-	tr := trace.New("indexedSymbols", string(repo))
+	tr := trace.New("openIndex", string(repo))
 	tr.LazyPrintf("commitID: %s", commitID)
 
 	var fetched bool
 	defer func() {
-		tr.LazyPrintf("fetched=%v symbols=%d", fetched, len(symbols))
+		if index != nil {
+			tr.LazyPrintf("fetched=%v symbols=%d", fetched, index.len())
+		}
 		if err != nil {
 			tr.LazyPrintf("error: %s", err)
 			tr.SetError()
@@ -44,66 +49,121 @@ func (s *Service) indexedSymbols(ctx context.Context, repo api.RepoName, commitI
 	f, err := s.cache.Open(ctx, key, func(ctx context.Context) (io.ReadCloser, error) {
 		fetched = true
 
-		var err error
-		symbols, err = s.parseUncached(ctx, repo, commitID)
+		symbols, err := s.parseIncremental(ctx, repo, commitID, tr)
 		if err != nil {
 			return nil, err
 		}
-		return encodeSymbols(symbols)
+		var buf bytes.Buffer
+		if err := writeSymbolIndex(&buf, symbols); err != nil {
+			return nil, err
+		}
+		return ioutil.NopCloser(&buf), nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-
-	// Skip deserializing symbols if we just serialized them in the s.cache.Open call above and still have the
-	// slice.
-	if symbols == nil {
-		var size int64
-		if fi, err := f.Stat(); err == nil {
-			size = fi.Size()
-		}
-		tr.LazyPrintf("decode bytes=%d", size)
-		symbols, err = decodeSymbols(ctx, f)
-		tr.LazyPrintf("decode (done) symbols=%d", len(symbols))
-		if err != nil {
-			return nil, err
-		}
+
+	index, err = readSymbolIndex(f)
+	if err != nil {
+		f.Close()
+		return nil, err
 	}
+	index.closer = f
+	span.LogFields(otlog.String("event", "result"), otlog.Int("count", index.len()))
+	return index, nil
+}
 
-	span.LogFields(otlog.String("event", "result"), otlog.Int("count", len(symbols)))
-	return symbols, nil
+// indexKey returns the cache key of the symbols of the repository at the commit.
+func indexKey(repo api.RepoName, commitID api.CommitID) string {
+	return string(repo) + ":" + string(commitID) + ":v2" // suffix is index format version (vN)
 }
 
-func encodeSymbols(symbols []protocol.Symbol) (io.ReadCloser, error) {
-	var buf bytes.Buffer
-	zw := gzip.NewWriter(&buf)
-	enc := gob.NewEncoder(zw)
-	if err := enc.Encode(symbols); err != nil {
-		return nil, err
+const (
+	// maxIndexedAncestors is the number of ancestors of a commit that are
+	// checked for a cached index by parseIncremental.
+	maxIndexedAncestors = 100
+
+	// maxIncrementalPaths is the maximum number of changed paths for which
+	// parseIncremental re-parses the files instead of the whole repository.
+	maxIncrementalPaths = 1000
+)
+
+// parseIncremental returns the symbols of the repository at the commit. If an
+// index of one of the commit's nearest ancestors is cached, it re-parses only
+// the files that changed since the ancestor and reuses the symbols of the
+// others. Otherwise it parses all files.
+func (s *Service) parseIncremental(ctx context.Context, repo api.RepoName, commitID api.CommitID, tr trace.Trace) ([]protocol.Symbol, error) {
+	if s.Ancestors == nil || s.ChangedFiles == nil || s.FetchTarPaths == nil {
+		return s.parseUncached(ctx, repo, commitID, nil)
 	}
-	if err := zw.Close(); err != nil {
-		return nil, err
+
+	base, baseSymbols, err := s.nearestIndexedAncestor(ctx, repo, commitID)
+	if err != nil {
+		log15.Warn("Failed to find indexed ancestor, parsing all files.", "repo", repo, "commitID", commitID, "error", err)
 	}
-	return ioutil.NopCloser(&buf), nil
-}
+	if baseSymbols == nil {
+		return s.parseUncached(ctx, repo, commitID, nil)
+	}
+	tr.LazyPrintf("ancestor: %s", base)
 
-func decodeSymbols(ctx context.Context, r io.Reader) (symbols []protocol.Symbol, err error) {
-	span, _ := opentracing.StartSpanFromContext(ctx, "decodeSymbols")
-	defer func() {
+	changed, deleted, err := s.ChangedFiles(ctx, gitserver.Repo{Name: repo}, base, commitID)
+	if err != nil {
+		log15.Warn("Failed to get changed files, parsing all files.", "repo", repo, "base", base, "commitID", commitID, "error", err)
+		return s.parseUncached(ctx, repo, commitID, nil)
+	}
+	tr.LazyPrintf("changed=%d deleted=%d", len(changed), len(deleted))
+	if len(changed)+len(deleted) > maxIncrementalPaths {
+		return s.parseUncached(ctx, repo, commitID, nil)
+	}
+
+	stale := make(map[string]struct{}, len(changed)+len(deleted))
+	for _, path := range changed {
+		stale[path] = struct{}{}
+	}
+	for _, path := range deleted {
+		stale[path] = struct{}{}
+	}
+	symbols := baseSymbols[:0]
+	for _, symbol := range baseSymbols {
+		if _, ok := stale[symbol.Path]; !ok {
+			symbols = append(symbols, symbol)
+		}
+	}
+
+	if len(changed) > 0 {
+		changedSymbols, err := s.parseUncached(ctx, repo, commitID, changed)
 		if err != nil {
-			ext.Error.Set(span, true)
-			span.LogFields(otlog.Error(err))
+			return nil, err
 		}
-		span.Finish()
-	}()
+		symbols = append(symbols, changedSymbols...)
+	}
+	return symbols, nil
+}
 
-	zr, err := gzip.NewReader(r)
+// nearestIndexedAncestor returns the nearest ancestor of the commit whose index
+// is cached and its symbols. If there is none, the symbols are nil.
+func (s *Service) nearestIndexedAncestor(ctx context.Context, repo api.RepoName, commitID api.CommitID) (api.CommitID, []protocol.Symbol, error) {
+	ancestors, err := s.Ancestors(ctx, gitserver.Repo{Name: repo}, commitID, maxIndexedAncestors)
 	if err != nil {
-		return nil, err
+		return "", nil, err
+	}
+	for _, ancestor := range ancestors {
+		f, err := s.cache.OpenCached(indexKey(repo, ancestor))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return "", nil, err
+		}
+		var symbols []protocol.Symbol
+		index, err := readSymbolIndex(f)
+		if err == nil {
+			symbols, err = index.all(ctx) // non-nil, even if there are no symbols
+		}
+		f.Close()
+		if err != nil {
+			return "", nil, err
+		}
+		return ancestor, symbols, nil
 	}
-	defer zr.Close()
-	dec := gob.NewDecoder(zr)
-	err = dec.Decode(&symbols)
-	return symbols, err
+	return "", nil, nil
 }
@@ -5,11 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"regexp"
 	"time"
 
-	"github.com/sourcegraph/sourcegraph/pkg/pathmatch"
-
 	opentracing "github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
 	otlog "github.com/opentracing/opentracing-go/log"
@@ -54,6 +51,7 @@ func (s *Service) search(ctx context.Context, args protocol.SearchArgs) (result
 	span.SetTag("repo", args.Repo)
 	span.SetTag("commitID", args.CommitID)
 	span.SetTag("query", args.Query)
+	span.SetTag("match", args.Match)
 	span.SetTag("first", args.First)
 	defer func() {
 		if err != nil {
@@ -72,74 +70,22 @@ func (s *Service) search(ctx context.Context, args protocol.SearchArgs) (result
 		tr.Finish()
 	}()
 
-	symbols, err := s.indexedSymbols(ctx, args.Repo, args.CommitID)
+	index, err := s.openIndex(ctx, args.Repo, args.CommitID)
 	if err != nil {
 		return nil, err
 	}
+	defer index.Close()
 
 	const maxFirst = 500
 	if args.First < 0 || args.First > maxFirst {
 		args.First = maxFirst
 	}
 
-	result = &protocol.SearchResult{}
-	if args.Query == "" && len(args.IncludePatterns) == 0 && args.ExcludePattern == "" {
-		// No filters were provided, save iterating the symbols and return a slice
-		if args.First != 0 && len(symbols) > args.First {
-			symbols = symbols[:args.First]
-		}
-		result.Symbols = symbols
-	} else {
-		res, err := filterSymbols(ctx, symbols, args)
-		if err != nil {
-			return nil, err
-		}
-		result.Symbols = res
-	}
-	return result, nil
-}
-
-func filterSymbols(ctx context.Context, symbols []protocol.Symbol, args protocol.SearchArgs) (res []protocol.Symbol, err error) {
-	span, _ := opentracing.StartSpanFromContext(ctx, "filterSymbols")
-	defer func() {
-		if err != nil {
-			ext.Error.Set(span, true)
-			span.LogFields(otlog.Error(err))
-		}
-		span.Finish()
-	}()
-	span.SetTag("before", len(symbols))
-
-	query := args.Query
-	if !args.IsRegExp {
-		query = regexp.QuoteMeta(query)
-	}
-	if !args.IsCaseSensitive {
-		query = "(?i:" + query + ")"
-	}
-	queryRegex, err := regexp.Compile(query)
-	if err != nil {
-		return nil, err
-	}
-
-	fileFilter, err := pathmatch.CompilePathPatterns(args.IncludePatterns, args.ExcludePattern, pathmatch.CompileOptions{
-		CaseSensitive: args.IsCaseSensitive,
-		RegExp:        args.IsRegExp,
-	})
+	span.SetTag("before", index.len())
+	symbols, err := index.search(ctx, args)
 	if err != nil {
 		return nil, err
 	}
-
-	for _, symbol := range symbols {
-		if !fileFilter.MatchPath(symbol.Path) || !queryRegex.MatchString(symbol.Name) {
-			continue
-		}
-		res = append(res, symbol)
-		if args.First > 0 && len(res) == args.First {
-			break
-		}
-	}
-
-	span.SetTag("after", len(res))
-	return res, nil
+	span.SetTag("after", len(symbols))
+	return &protocol.SearchResult{Symbols: symbols}, nil
 }
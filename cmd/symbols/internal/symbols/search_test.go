@@ -26,13 +26,32 @@ func BenchmarkSearch(b *testing.B) {
 	ctx := context.Background()
 	b.ResetTimer()
 
-	tests := []protocol.SearchArgs{
+	repos := []protocol.SearchArgs{
 		{Repo: "github.com/sourcegraph/go-langserver", CommitID: "391a062a7d9977510e7e883e412769b07fed8b5e"},
 		{Repo: "github.com/moby/moby", CommitID: "6e5c2d639f67ae70f54d9f2285f3261440b074aa"},
 	}
+	queries := []protocol.SearchArgs{
+		{},
+		{Query: "handler"},
+		{Query: "Handler", IsCaseSensitive: true},
+		{Query: "^New.*Handler$", IsRegExp: true},
+		{Query: "newhandler", Match: protocol.MatchExact},
+		{Query: "new", Match: protocol.MatchPrefix},
+		{Query: "hndler", Match: protocol.MatchFuzzy},
+		{Query: "config", Kinds: []string{"struct"}, Languages: []string{"go"}},
+		{Query: "handler", IncludePatterns: []string{`_test\.go$`}, IsRegExp: true},
+	}
+
+	var tests []protocol.SearchArgs
+	for _, repo := range repos {
+		for _, query := range queries {
+			query.Repo, query.CommitID = repo.Repo, repo.CommitID
+			tests = append(tests, query)
+		}
+	}
 
 	for _, test := range tests {
-		b.Run(fmt.Sprintf("%s@%s", path.Base(string(test.Repo)), test.CommitID[:3]), func(b *testing.B) {
+		b.Run(fmt.Sprintf("%s@%s/%s%s", path.Base(string(test.Repo)), test.CommitID[:3], test.Match, test.Query), func(b *testing.B) {
 			for n := 0; n < b.N; n++ {
 				_, err := service.search(ctx, test)
 				if err != nil {
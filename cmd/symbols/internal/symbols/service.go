@@ -22,6 +22,17 @@ type Service struct {
 	// determine if the error is a bad request (eg invalid repo).
 	FetchTar func(context.Context, gitserver.Repo, api.CommitID) (io.ReadCloser, error)
 
+	// FetchTarPaths is like FetchTar, but the archive only includes the files
+	// at the given paths.
+	FetchTarPaths func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, paths []string) (io.ReadCloser, error)
+
+	// Ancestors returns up to n ancestors of a commit, nearest first.
+	Ancestors func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, n int) ([]api.CommitID, error)
+
+	// ChangedFiles returns the paths of the files that were added or modified
+	// (changed) and removed (deleted) between the base and head commits.
+	ChangedFiles func(ctx context.Context, repo gitserver.Repo, base, head api.CommitID) (changed, deleted []string, err error)
+
 	// MaxConcurrentFetchTar is the maximum number of concurrent calls allowed
 	// to FetchTar. It defaults to 15.
 	MaxConcurrentFetchTar int
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"reflect"
+	"sort"
 	"testing"
 
 	"github.com/sourcegraph/sourcegraph/cmd/symbols/internal/pkg/ctags"
@@ -73,6 +74,85 @@ func TestService(t *testing.T) {
 	}
 }
 
+func TestOpenIndex_incremental(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { os.RemoveAll(tmpDir) }()
+
+	files := map[api.CommitID]map[string]string{
+		"base": {"a.js": "a", "b.js": "b", "c.js": "c"},
+		"head": {"a.js": "a", "b.js": "b2", "d.js": "d"},
+	}
+	var fetchedPaths []string
+	service := Service{
+		FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
+			return createTar(files[commit])
+		},
+		FetchTarPaths: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+			fetchedPaths = append(fetchedPaths, paths...)
+			subset := map[string]string{}
+			for _, path := range paths {
+				subset[path] = files[commit][path]
+			}
+			return createTar(subset)
+		},
+		Ancestors: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, n int) ([]api.CommitID, error) {
+			if commit == "head" {
+				return []api.CommitID{"unindexed", "base"}, nil
+			}
+			return nil, nil
+		},
+		ChangedFiles: func(ctx context.Context, repo gitserver.Repo, base, head api.CommitID) (changed, deleted []string, err error) {
+			if base != "base" || head != "head" {
+				t.Errorf("got changed files between %s and %s, want base and head", base, head)
+			}
+			return []string{"b.js", "d.js"}, []string{"c.js"}, nil
+		},
+		NewParser: func() (ctags.Parser, error) {
+			return contentParser{}, nil
+		},
+		Path: tmpDir,
+	}
+	if err := service.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	symbolNames := func(commit api.CommitID) []string {
+		index, err := service.openIndex(context.Background(), "repo", commit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer index.Close()
+		symbols, err := index.all(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, symbol := range symbols {
+			names = append(names, symbol.Name)
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	if got, want := symbolNames("base"), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("base: got symbols %v, want %v", got, want)
+	}
+	if fetchedPaths != nil {
+		t.Errorf("base: got fetched paths %v, want a full archive", fetchedPaths)
+	}
+
+	if got, want := symbolNames("head"), []string{"a", "b2", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("head: got symbols %v, want %v", got, want)
+	}
+	sort.Strings(fetchedPaths)
+	if want := []string{"b.js", "d.js"}; !reflect.DeepEqual(fetchedPaths, want) {
+		t.Errorf("head: got fetched paths %v, want %v", fetchedPaths, want)
+	}
+}
+
 func createTar(files map[string]string) (io.ReadCloser, error) {
 	buf := new(bytes.Buffer)
 	w := tar.NewWriter(buf)
@@ -108,3 +188,12 @@ func (m mockParser) Parse(name string, content []byte) ([]ctags.Entry, error) {
 }
 
 func (mockParser) Close() {}
+
+// contentParser returns a symbol named after the content of each file.
+type contentParser struct{}
+
+func (contentParser) Parse(name string, content []byte) ([]ctags.Entry, error) {
+	return []ctags.Entry{{Name: string(content), Path: name}}, nil
+}
+
+func (contentParser) Close() {}
@@ -0,0 +1,759 @@
+package symbols
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/pathmatch"
+	"github.com/sourcegraph/sourcegraph/pkg/symbols/protocol"
+)
+
+// A symbol index is the on-disk format of the symbols of a repository at a
+// commit. It is searched in place (through an io.ReaderAt) without decoding
+// all of the symbols. It consists of a header followed by these sections:
+//
+//	names     the name of each symbol, sorted by lowercase name (uvarint length + bytes)
+//	strings   the other distinct strings of the symbols, such as paths and kinds (uvarint length + bytes)
+//	records   a fixed-size record for each symbol, in the order of names, referring to its strings
+//	trigrams  a sorted table of the trigrams of the lowercase names with the offset of their postings
+//	postings  for each trigram, the ascending indexes of the symbols whose name contains it (uvarint deltas)
+//
+// All integers are little-endian. Because strings are deduplicated, two
+// symbols have the same kind (or path, etc.) iff their records refer to the
+// same string offset.
+
+const symbolIndexMagic = "SGSYMIX2"
+
+type symbolIndexHeader struct {
+	NumSymbols uint32
+	Names      indexSection
+	Strings    indexSection
+	Records    indexSection
+	Trigrams   indexSection
+	Postings   indexSection
+}
+
+type indexSection struct {
+	Offset, Length uint32
+}
+
+// indexRecord is the record of a symbol. The name is an offset into the names
+// section and the other strings are offsets into the strings section.
+type indexRecord struct {
+	name, path, kind, language, parent, parentKind, signature, pattern uint32
+	line                                                               uint32
+	flags                                                              uint32
+}
+
+const (
+	indexRecordSize   = 10 * 4
+	indexTrigramSize  = 3 * 4 // trigram, postings offset, postings count
+	recordFileLimited = 1 << 0
+)
+
+func (r *indexRecord) encode(b []byte) {
+	for i, v := range [...]uint32{r.name, r.path, r.kind, r.language, r.parent, r.parentKind, r.signature, r.pattern, r.line, r.flags} {
+		binary.LittleEndian.PutUint32(b[i*4:], v)
+	}
+}
+
+func decodeIndexRecord(b []byte) indexRecord {
+	u := func(i int) uint32 { return binary.LittleEndian.Uint32(b[i*4:]) }
+	return indexRecord{
+		name: u(0), path: u(1), kind: u(2), language: u(3), parent: u(4),
+		parentKind: u(5), signature: u(6), pattern: u(7), line: u(8), flags: u(9),
+	}
+}
+
+// writeSymbolIndex writes the symbol index of symbols to w.
+func writeSymbolIndex(w io.Writer, symbols []protocol.Symbol) error {
+	lowerNames := make([]string, len(symbols))
+	order := make([]int, len(symbols))
+	for i, symbol := range symbols {
+		lowerNames[i] = strings.ToLower(symbol.Name)
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		if lowerNames[a] != lowerNames[b] {
+			return lowerNames[a] < lowerNames[b]
+		}
+		if symbols[a].Name != symbols[b].Name {
+			return symbols[a].Name < symbols[b].Name
+		}
+		if symbols[a].Path != symbols[b].Path {
+			return symbols[a].Path < symbols[b].Path
+		}
+		return symbols[a].Line < symbols[b].Line
+	})
+
+	var (
+		names, strs, records bytes.Buffer
+		stringOffsets        = map[string]uint32{}
+		postings             = map[uint32][]uint32{}
+		recordBuf            [indexRecordSize]byte
+	)
+	addString := func(s string) uint32 {
+		if off, ok := stringOffsets[s]; ok {
+			return off
+		}
+		off := uint32(strs.Len())
+		writeIndexString(&strs, s)
+		stringOffsets[s] = off
+		return off
+	}
+	for i, j := range order {
+		symbol := symbols[j]
+		record := indexRecord{
+			name:       uint32(names.Len()),
+			path:       addString(symbol.Path),
+			kind:       addString(symbol.Kind),
+			language:   addString(symbol.Language),
+			parent:     addString(symbol.Parent),
+			parentKind: addString(symbol.ParentKind),
+			signature:  addString(symbol.Signature),
+			pattern:    addString(symbol.Pattern),
+			line:       uint32(symbol.Line),
+		}
+		if symbol.FileLimited {
+			record.flags |= recordFileLimited
+		}
+		writeIndexString(&names, symbol.Name)
+		record.encode(recordBuf[:])
+		records.Write(recordBuf[:])
+
+		for _, trigram := range trigrams(lowerNames[j]) {
+			if p := postings[trigram]; len(p) == 0 || p[len(p)-1] != uint32(i) {
+				postings[trigram] = append(p, uint32(i))
+			}
+		}
+	}
+
+	sortedTrigrams := make([]uint32, 0, len(postings))
+	for trigram := range postings {
+		sortedTrigrams = append(sortedTrigrams, trigram)
+	}
+	sort.Slice(sortedTrigrams, func(i, j int) bool { return sortedTrigrams[i] < sortedTrigrams[j] })
+	var trigramTable, postingLists bytes.Buffer
+	var buf [binary.MaxVarintLen64]byte
+	for _, trigram := range sortedTrigrams {
+		var entry [indexTrigramSize]byte
+		binary.LittleEndian.PutUint32(entry[0:], trigram)
+		binary.LittleEndian.PutUint32(entry[4:], uint32(postingLists.Len()))
+		binary.LittleEndian.PutUint32(entry[8:], uint32(len(postings[trigram])))
+		trigramTable.Write(entry[:])
+
+		prev := uint32(0)
+		for _, i := range postings[trigram] {
+			postingLists.Write(buf[:binary.PutUvarint(buf[:], uint64(i-prev))])
+			prev = i
+		}
+	}
+
+	header := symbolIndexHeader{NumSymbols: uint32(len(symbols))}
+	offset := uint32(len(symbolIndexMagic) + binary.Size(header))
+	for _, s := range []struct {
+		section *indexSection
+		data    *bytes.Buffer
+	}{
+		{&header.Names, &names},
+		{&header.Strings, &strs},
+		{&header.Records, &records},
+		{&header.Trigrams, &trigramTable},
+		{&header.Postings, &postingLists},
+	} {
+		*s.section = indexSection{Offset: offset, Length: uint32(s.data.Len())}
+		offset += s.section.Length
+	}
+
+	if _, err := io.WriteString(w, symbolIndexMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	for _, data := range []*bytes.Buffer{&names, &strs, &records, &trigramTable, &postingLists} {
+		if _, err := data.WriteTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeIndexString(buf *bytes.Buffer, s string) {
+	var n [binary.MaxVarintLen64]byte
+	buf.Write(n[:binary.PutUvarint(n[:], uint64(len(s)))])
+	buf.WriteString(s)
+}
+
+// trigrams returns the distinct trigrams of s.
+func trigrams(s string) []uint32 {
+	if len(s) < 3 {
+		return nil
+	}
+	list := make([]uint32, 0, len(s)-2)
+	seen := make(map[uint32]struct{}, len(s)-2)
+	for i := 0; i+3 <= len(s); i++ {
+		trigram := uint32(s[i])<<16 | uint32(s[i+1])<<8 | uint32(s[i+2])
+		if _, ok := seen[trigram]; !ok {
+			seen[trigram] = struct{}{}
+			list = append(list, trigram)
+		}
+	}
+	return list
+}
+
+// symbolIndex reads a symbol index written by writeSymbolIndex.
+type symbolIndex struct {
+	r      io.ReaderAt
+	header symbolIndexHeader
+
+	// closer, if set, is closed by Close.
+	closer io.Closer
+}
+
+var errInvalidSymbolIndex = errors.New("invalid symbol index")
+
+func readSymbolIndex(r io.ReaderAt) (*symbolIndex, error) {
+	var header symbolIndexHeader
+	buf := make([]byte, len(symbolIndexMagic)+binary.Size(header))
+	if err := readFullAt(r, buf, 0); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errInvalidSymbolIndex
+		}
+		return nil, err
+	}
+	if string(buf[:len(symbolIndexMagic)]) != symbolIndexMagic {
+		return nil, errInvalidSymbolIndex
+	}
+	if err := binary.Read(bytes.NewReader(buf[len(symbolIndexMagic):]), binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if header.Records.Length != header.NumSymbols*indexRecordSize || header.Trigrams.Length%indexTrigramSize != 0 {
+		return nil, errInvalidSymbolIndex
+	}
+	return &symbolIndex{r: r, header: header}, nil
+}
+
+// readFullAt reads len(buf) bytes at off. It returns io.ErrUnexpectedEOF if
+// fewer bytes could be read.
+func readFullAt(r io.ReaderAt, buf []byte, off int64) error {
+	n, err := r.ReadAt(buf, off)
+	if n == len(buf) {
+		return nil
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (x *symbolIndex) Close() error {
+	if x.closer != nil {
+		return x.closer.Close()
+	}
+	return nil
+}
+
+func (x *symbolIndex) len() int {
+	return int(x.header.NumSymbols)
+}
+
+// readString reads the string at the offset of a section.
+func (x *symbolIndex) readString(section indexSection, off uint32) (string, error) {
+	if off >= section.Length {
+		return "", errInvalidSymbolIndex
+	}
+	pos := int64(section.Offset) + int64(off)
+	buf := make([]byte, 64)
+	n, err := x.r.ReadAt(buf, pos)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	length, k := binary.Uvarint(buf[:n])
+	if k <= 0 || int64(off)+int64(k)+int64(length) > int64(section.Length) {
+		return "", errInvalidSymbolIndex
+	}
+	if k+int(length) <= n {
+		return string(buf[k : k+int(length)]), nil
+	}
+	s := make([]byte, length)
+	if err := readFullAt(x.r, s, pos+int64(k)); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+func (x *symbolIndex) name(record indexRecord) (string, error) {
+	return x.readString(x.header.Names, record.name)
+}
+
+// readRecords reads the records of the symbols with indexes [lo, hi).
+func (x *symbolIndex) readRecords(lo, hi int) ([]indexRecord, error) {
+	buf := make([]byte, (hi-lo)*indexRecordSize)
+	if err := readFullAt(x.r, buf, int64(x.header.Records.Offset)+int64(lo)*indexRecordSize); err != nil {
+		return nil, err
+	}
+	records := make([]indexRecord, hi-lo)
+	for i := range records {
+		records[i] = decodeIndexRecord(buf[i*indexRecordSize:])
+	}
+	return records, nil
+}
+
+func (x *symbolIndex) record(i int) (indexRecord, error) {
+	records, err := x.readRecords(i, i+1)
+	if err != nil {
+		return indexRecord{}, err
+	}
+	return records[0], nil
+}
+
+// forEachRecord calls fn with the records of the symbols with indexes [lo,
+// hi) in order until fn returns true or an error.
+func (x *symbolIndex) forEachRecord(ctx context.Context, lo, hi int, fn func(record indexRecord) (bool, error)) error {
+	const chunkSize = 1024
+	for ; lo < hi; lo += chunkSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		end := lo + chunkSize
+		if end > hi {
+			end = hi
+		}
+		records, err := x.readRecords(lo, end)
+		if err != nil {
+			return err
+		}
+		for _, record := range records {
+			if done, err := fn(record); done || err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// forEachName calls fn with the name of each symbol in order until fn
+// returns true or an error. It reads the names section sequentially.
+func (x *symbolIndex) forEachName(ctx context.Context, fn func(i int, name string) (bool, error)) error {
+	br := bufio.NewReader(io.NewSectionReader(x.r, int64(x.header.Names.Offset), int64(x.header.Names.Length)))
+	var buf []byte
+	for i := 0; i < x.len(); i++ {
+		if i%1024 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		length, err := binary.ReadUvarint(br)
+		if err != nil {
+			return err
+		}
+		if uint64(cap(buf)) < length {
+			buf = make([]byte, length)
+		}
+		buf = buf[:length]
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return err
+		}
+		if done, err := fn(i, string(buf)); done || err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nameRange returns the range [lo, hi) of the indexes of the symbols whose
+// lowercase name is equal to (or if prefix is true, begins with) lowerQuery.
+func (x *symbolIndex) nameRange(lowerQuery string, prefix bool) (lo, hi int, err error) {
+	lowerName := func(i int) string {
+		if err != nil {
+			return ""
+		}
+		var record indexRecord
+		if record, err = x.record(i); err != nil {
+			return ""
+		}
+		var name string
+		name, err = x.name(record)
+		return strings.ToLower(name)
+	}
+	lo = sort.Search(x.len(), func(i int) bool { return lowerName(i) >= lowerQuery })
+	hi = lo + sort.Search(x.len()-lo, func(i int) bool {
+		name := lowerName(lo + i)
+		if prefix {
+			return !strings.HasPrefix(name, lowerQuery)
+		}
+		return name != lowerQuery
+	})
+	return lo, hi, err
+}
+
+// postings returns the ascending indexes of the symbols whose lowercase name
+// contains the trigram.
+func (x *symbolIndex) postings(trigram uint32) ([]uint32, error) {
+	n := int(x.header.Trigrams.Length / indexTrigramSize)
+	var entry [indexTrigramSize]byte
+	var err error
+	readEntry := func(i int) uint32 {
+		if err != nil {
+			return 0
+		}
+		err = readFullAt(x.r, entry[:], int64(x.header.Trigrams.Offset)+int64(i)*indexTrigramSize)
+		return binary.LittleEndian.Uint32(entry[0:])
+	}
+	i := sort.Search(n, func(i int) bool { return readEntry(i) >= trigram })
+	if err != nil {
+		return nil, err
+	}
+	if i == n || readEntry(i) != trigram {
+		return nil, err
+	}
+	off, count := binary.LittleEndian.Uint32(entry[4:]), binary.LittleEndian.Uint32(entry[8:])
+	if off >= x.header.Postings.Length {
+		return nil, errInvalidSymbolIndex
+	}
+
+	br := bufio.NewReader(io.NewSectionReader(x.r, int64(x.header.Postings.Offset)+int64(off), int64(x.header.Postings.Length-off)))
+	list := make([]uint32, count)
+	prev := uint64(0)
+	for j := range list {
+		delta, err := binary.ReadUvarint(br)
+		if err != nil {
+			return nil, err
+		}
+		prev += delta
+		list[j] = uint32(prev)
+	}
+	return list, nil
+}
+
+// substringCandidates returns the ascending indexes of the symbols whose
+// lowercase name contains all of the trigrams of lowerQuery.
+func (x *symbolIndex) substringCandidates(lowerQuery string) ([]uint32, error) {
+	var candidates []uint32
+	for i, trigram := range trigrams(lowerQuery) {
+		list, err := x.postings(trigram)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			candidates = list
+			continue
+		}
+		// Intersect the sorted lists.
+		n, j := 0, 0
+		for _, c := range candidates {
+			for j < len(list) && list[j] < c {
+				j++
+			}
+			if j < len(list) && list[j] == c {
+				candidates[n] = c
+				n++
+			}
+		}
+		candidates = candidates[:n]
+		if len(candidates) == 0 {
+			break
+		}
+	}
+	return candidates, nil
+}
+
+// fuzzyCandidates returns the indexes of the symbols whose lowercase name
+// contains at least half of the trigrams of lowerQuery, with the symbols
+// sharing the most trigrams first.
+func (x *symbolIndex) fuzzyCandidates(lowerQuery string) ([]uint32, error) {
+	queryTrigrams := trigrams(lowerQuery)
+	counts := map[uint32]int{}
+	for _, trigram := range queryTrigrams {
+		list, err := x.postings(trigram)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range list {
+			counts[i]++
+		}
+	}
+	minCount := (len(queryTrigrams) + 1) / 2
+	candidates := make([]uint32, 0, len(counts))
+	for i, count := range counts {
+		if count >= minCount {
+			candidates = append(candidates, i)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if counts[a] != counts[b] {
+			return counts[a] > counts[b]
+		}
+		return a < b
+	})
+	return candidates, nil
+}
+
+func (x *symbolIndex) symbol(record indexRecord) (protocol.Symbol, error) {
+	symbol := protocol.Symbol{Line: int(record.line), FileLimited: record.flags&recordFileLimited != 0}
+	var err error
+	if symbol.Name, err = x.name(record); err != nil {
+		return protocol.Symbol{}, err
+	}
+	for _, f := range []struct {
+		field *string
+		off   uint32
+	}{
+		{&symbol.Path, record.path},
+		{&symbol.Kind, record.kind},
+		{&symbol.Language, record.language},
+		{&symbol.Parent, record.parent},
+		{&symbol.ParentKind, record.parentKind},
+		{&symbol.Signature, record.signature},
+		{&symbol.Pattern, record.pattern},
+	} {
+		if *f.field, err = x.readString(x.header.Strings, f.off); err != nil {
+			return protocol.Symbol{}, err
+		}
+	}
+	return symbol, nil
+}
+
+// all returns all of the symbols.
+func (x *symbolIndex) all(ctx context.Context) ([]protocol.Symbol, error) {
+	symbols := make([]protocol.Symbol, 0, x.len())
+	err := x.forEachRecord(ctx, 0, x.len(), func(record indexRecord) (bool, error) {
+		symbol, err := x.symbol(record)
+		symbols = append(symbols, symbol)
+		return false, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return symbols, nil
+}
+
+// recordFilter matches the records of symbols against the kind, language,
+// parent and path filters of a search. Since strings are deduplicated, it caches the
+// result for each string offset and reads each distinct string at most once.
+type recordFilter struct {
+	x                                    *symbolIndex
+	kinds, languages                     []string
+	parent                               *regexp.Regexp
+	pathMatcher                          pathmatch.PathMatcher
+	kindOK, languageOK, parentOK, pathOK map[uint32]bool
+}
+
+func newRecordFilter(x *symbolIndex, args protocol.SearchArgs) (*recordFilter, error) {
+	f := &recordFilter{
+		x:          x,
+		kinds:      args.Kinds,
+		languages:  args.Languages,
+		kindOK:     map[uint32]bool{},
+		languageOK: map[uint32]bool{},
+		parentOK:   map[uint32]bool{},
+		pathOK:     map[uint32]bool{},
+	}
+	if args.Parent != "" {
+		parent := args.Parent
+		if !args.IsCaseSensitive {
+			parent = "(?i:" + parent + ")"
+		}
+		var err error
+		f.parent, err = regexp.Compile(parent)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(args.IncludePatterns) > 0 || args.ExcludePattern != "" {
+		var err error
+		f.pathMatcher, err = pathmatch.CompilePathPatterns(args.IncludePatterns, args.ExcludePattern, pathmatch.CompileOptions{
+			CaseSensitive: args.IsCaseSensitive,
+			RegExp:        args.IsRegExp,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (f *recordFilter) match(record indexRecord) (bool, error) {
+	oneOf := func(values []string) func(string) bool {
+		return func(s string) bool {
+			for _, v := range values {
+				if strings.EqualFold(s, v) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	for _, c := range []struct {
+		enabled bool
+		off     uint32
+		cache   map[uint32]bool
+		match   func(string) bool
+	}{
+		{len(f.kinds) > 0, record.kind, f.kindOK, oneOf(f.kinds)},
+		{len(f.languages) > 0, record.language, f.languageOK, oneOf(f.languages)},
+		{f.parent != nil, record.parent, f.parentOK, func(parent string) bool { return f.parent.MatchString(parent) }},
+		{f.pathMatcher != nil, record.path, f.pathOK, func(path string) bool { return f.pathMatcher.MatchPath(path) }},
+	} {
+		if !c.enabled {
+			continue
+		}
+		ok, cached := c.cache[c.off]
+		if !cached {
+			s, err := f.x.readString(f.x.header.Strings, c.off)
+			if err != nil {
+				return false, err
+			}
+			ok = c.match(s)
+			c.cache[c.off] = ok
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// search returns the symbols matching the search args, in the order of their
+// names (or for fuzzy queries, best match first).
+func (x *symbolIndex) search(ctx context.Context, args protocol.SearchArgs) ([]protocol.Symbol, error) {
+	filter, err := newRecordFilter(x, args)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []protocol.Symbol
+	add := func(record indexRecord) (bool, error) {
+		if ok, err := filter.match(record); err != nil || !ok {
+			return false, err
+		}
+		symbol, err := x.symbol(record)
+		if err != nil {
+			return false, err
+		}
+		res = append(res, symbol)
+		return args.First > 0 && len(res) >= args.First, nil
+	}
+	addIndexes := func(indexes []uint32, matchName func(string) bool) error {
+		for i, index := range indexes {
+			if i%1024 == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			record, err := x.record(int(index))
+			if err != nil {
+				return err
+			}
+			if matchName != nil {
+				name, err := x.name(record)
+				if err != nil {
+					return err
+				}
+				if !matchName(name) {
+					continue
+				}
+			}
+			if done, err := add(record); done || err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	scanNames := func(matchName func(string) bool) error {
+		return x.forEachName(ctx, func(i int, name string) (bool, error) {
+			if !matchName(name) {
+				return false, nil
+			}
+			record, err := x.record(i)
+			if err != nil {
+				return false, err
+			}
+			return add(record)
+		})
+	}
+
+	query, lowerQuery := args.Query, strings.ToLower(args.Query)
+	matchMode := args.Match
+	if matchMode == protocol.MatchFuzzy && len(trigrams(lowerQuery)) == 0 {
+		// Queries shorter than a trigram match names beginning with them.
+		matchMode = protocol.MatchPrefix
+	}
+	switch {
+	case query == "":
+		err = x.forEachRecord(ctx, 0, x.len(), add)
+
+	case args.IsRegExp:
+		if !args.IsCaseSensitive {
+			query = "(?i:" + query + ")"
+		}
+		var queryRegex *regexp.Regexp
+		queryRegex, err = regexp.Compile(query)
+		if err != nil {
+			return nil, err
+		}
+		err = scanNames(queryRegex.MatchString)
+
+	case matchMode == protocol.MatchExact || matchMode == protocol.MatchPrefix:
+		prefix := matchMode == protocol.MatchPrefix
+		var lo, hi int
+		lo, hi, err = x.nameRange(lowerQuery, prefix)
+		if err != nil {
+			return nil, err
+		}
+		err = x.forEachRecord(ctx, lo, hi, func(record indexRecord) (bool, error) {
+			if args.IsCaseSensitive {
+				name, err := x.name(record)
+				if err != nil {
+					return false, err
+				}
+				if (prefix && !strings.HasPrefix(name, query)) || (!prefix && name != query) {
+					return false, nil
+				}
+			}
+			return add(record)
+		})
+
+	case matchMode == protocol.MatchFuzzy:
+		var candidates []uint32
+		candidates, err = x.fuzzyCandidates(lowerQuery)
+		if err != nil {
+			return nil, err
+		}
+		err = addIndexes(candidates, nil)
+
+	case matchMode == protocol.MatchSubstring:
+		matchName := func(name string) bool { return strings.Contains(strings.ToLower(name), lowerQuery) }
+		if args.IsCaseSensitive {
+			matchName = func(name string) bool { return strings.Contains(name, query) }
+		}
+		if len(trigrams(lowerQuery)) == 0 {
+			err = scanNames(matchName)
+			break
+		}
+		var candidates []uint32
+		candidates, err = x.substringCandidates(lowerQuery)
+		if err != nil {
+			return nil, err
+		}
+		err = addIndexes(candidates, matchName)
+
+	default:
+		return nil, fmt.Errorf("invalid symbol match mode %q", args.Match)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,103 @@
+package symbols
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/symbols/protocol"
+)
+
+func TestSymbolIndex(t *testing.T) {
+	symbols := []protocol.Symbol{
+		{Name: "Server", Path: "server.go", Line: 10, Kind: "struct", Language: "Go"},
+		{Name: "NewServer", Path: "server.go", Line: 20, Kind: "func", Language: "Go", Signature: "()"},
+		{Name: "Serve", Path: "server.go", Line: 30, Kind: "method", Language: "Go", Parent: "Server", ParentKind: "struct"},
+		{Name: "serveHTTP", Path: "http/handler.go", Line: 5, Kind: "func", Language: "Go", FileLimited: true},
+		{Name: "ServerConfig", Path: "config.ts", Line: 1, Kind: "interface", Language: "TypeScript"},
+		{Name: "x", Path: "a.js", Kind: "variable", Language: "JavaScript", Pattern: "/^var x = 1$/"},
+	}
+	var buf bytes.Buffer
+	if err := writeSymbolIndex(&buf, symbols); err != nil {
+		t.Fatal(err)
+	}
+	index, err := readSymbolIndex(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := index.all(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []protocol.Symbol{symbols[1], symbols[2], symbols[3], symbols[0], symbols[4], symbols[5]}
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("got all symbols %+v, want %+v", all, want)
+	}
+
+	tests := map[string]struct {
+		args protocol.SearchArgs
+		want []string
+	}{
+		"all":                    {args: protocol.SearchArgs{}, want: []string{"NewServer", "Serve", "serveHTTP", "Server", "ServerConfig", "x"}},
+		"first":                  {args: protocol.SearchArgs{First: 2}, want: []string{"NewServer", "Serve"}},
+		"substring":              {args: protocol.SearchArgs{Query: "server"}, want: []string{"NewServer", "Server", "ServerConfig"}},
+		"substring short":        {args: protocol.SearchArgs{Query: "ve"}, want: []string{"NewServer", "Serve", "serveHTTP", "Server", "ServerConfig"}},
+		"substring case":         {args: protocol.SearchArgs{Query: "serve", IsCaseSensitive: true}, want: []string{"serveHTTP"}},
+		"regexp":                 {args: protocol.SearchArgs{Query: "^serve", IsRegExp: true}, want: []string{"Serve", "serveHTTP", "Server", "ServerConfig"}},
+		"exact":                  {args: protocol.SearchArgs{Query: "server", Match: protocol.MatchExact}, want: []string{"Server"}},
+		"exact case":             {args: protocol.SearchArgs{Query: "server", Match: protocol.MatchExact, IsCaseSensitive: true}, want: nil},
+		"prefix":                 {args: protocol.SearchArgs{Query: "serve", Match: protocol.MatchPrefix}, want: []string{"Serve", "serveHTTP", "Server", "ServerConfig"}},
+		"prefix case":            {args: protocol.SearchArgs{Query: "Server", Match: protocol.MatchPrefix, IsCaseSensitive: true}, want: []string{"Server", "ServerConfig"}},
+		"fuzzy":                  {args: protocol.SearchArgs{Query: "servrconfig", Match: protocol.MatchFuzzy}, want: []string{"ServerConfig"}},
+		"fuzzy short":            {args: protocol.SearchArgs{Query: "ne", Match: protocol.MatchFuzzy}, want: []string{"NewServer"}},
+		"kind":                   {args: protocol.SearchArgs{Query: "serve", Kinds: []string{"FUNC", "method"}}, want: []string{"NewServer", "Serve", "serveHTTP"}},
+		"language":               {args: protocol.SearchArgs{Query: "server", Languages: []string{"typescript"}}, want: []string{"ServerConfig"}},
+		"parent":                 {args: protocol.SearchArgs{Parent: "^server$"}, want: []string{"Serve"}},
+		"parent case":            {args: protocol.SearchArgs{Parent: "^server$", IsCaseSensitive: true}, want: nil},
+		"include":                {args: protocol.SearchArgs{Query: "serve", IncludePatterns: []string{"^http/"}, IsRegExp: true}, want: []string{"serveHTTP"}},
+		"exclude":                {args: protocol.SearchArgs{Query: "serve", ExcludePattern: "*.go"}, want: []string{"ServerConfig"}},
+		"no matches":             {args: protocol.SearchArgs{Query: "foo"}, want: nil},
+		"no matches with filter": {args: protocol.SearchArgs{Query: "x", Kinds: []string{"func"}}, want: nil},
+	}
+	for label, test := range tests {
+		t.Run(label, func(t *testing.T) {
+			res, err := index.search(context.Background(), test.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, symbol := range res {
+				names = append(names, symbol.Name)
+			}
+			if !reflect.DeepEqual(names, test.want) {
+				t.Errorf("got %v, want %v", names, test.want)
+			}
+		})
+	}
+
+	if _, err := index.search(context.Background(), protocol.SearchArgs{Query: "x", Match: "bogus"}); err == nil {
+		t.Error("got nil error for invalid match mode")
+	}
+}
+
+func TestSymbolIndex_empty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeSymbolIndex(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	index, err := readSymbolIndex(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range []protocol.SearchArgs{{}, {Query: "foo"}, {Query: "foo", Match: protocol.MatchPrefix}, {Query: "foo", Match: protocol.MatchFuzzy}} {
+		if res, err := index.search(context.Background(), args); err != nil || res != nil {
+			t.Errorf("%+v: got %v (error %v), want no symbols", args, res, err)
+		}
+	}
+
+	if _, err := readSymbolIndex(bytes.NewReader([]byte("not an index"))); err != errInvalidSymbolIndex {
+		t.Errorf("got error %v, want %v", err, errInvalidSymbolIndex)
+	}
+}
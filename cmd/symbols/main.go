@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 	"os/signal"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/opentracing-contrib/go-stdlib/nethttp"
@@ -51,6 +53,21 @@ func main() {
 		FetchTar: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID) (io.ReadCloser, error) {
 			return git.Archive(ctx, repo, git.ArchiveOptions{Treeish: string(commit), Format: "tar"})
 		},
+		FetchTarPaths: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, paths []string) (io.ReadCloser, error) {
+			return git.Archive(ctx, repo, git.ArchiveOptions{Treeish: string(commit), Format: "tar", Paths: paths})
+		},
+		Ancestors: func(ctx context.Context, repo gitserver.Repo, commit api.CommitID, n int) ([]api.CommitID, error) {
+			commits, err := git.Commits(ctx, repo, git.CommitsOptions{Range: string(commit), N: uint(n), Skip: 1})
+			if err != nil {
+				return nil, err
+			}
+			ancestors := make([]api.CommitID, len(commits))
+			for i, c := range commits {
+				ancestors[i] = c.ID
+			}
+			return ancestors, nil
+		},
+		ChangedFiles: changedFiles,
 		NewParser: func() (ctags.Parser, error) {
 			parser, err := ctags.NewParser(ctagsCommand)
 			if err != nil {
@@ -90,6 +107,48 @@ func main() {
 	}
 }
 
+// changedFiles returns the paths of the files that were added or modified
+// (changed) and removed (deleted) between the base and head commits.
+func changedFiles(ctx context.Context, repo gitserver.Repo, base, head api.CommitID) (changed, deleted []string, err error) {
+	rc, err := git.ExecReader(ctx, repo, []string{"diff", "--name-status", "-z", string(base), string(head), "--"})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rc.Close()
+	out, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The output is a NUL-separated list of statuses, each followed by its
+	// path (or by the old and new paths for renames and copies).
+	fields := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	for i := 0; i < len(fields) && fields[i] != ""; i++ {
+		status := fields[i]
+		if i+1 >= len(fields) {
+			return nil, nil, fmt.Errorf("unexpected git diff output %q", out)
+		}
+		switch status[0] {
+		case 'D':
+			i++
+			deleted = append(deleted, fields[i])
+		case 'R', 'C':
+			if i+2 >= len(fields) {
+				return nil, nil, fmt.Errorf("unexpected git diff output %q", out)
+			}
+			if status[0] == 'R' {
+				deleted = append(deleted, fields[i+1])
+			}
+			changed = append(changed, fields[i+2])
+			i += 2
+		default:
+			i++
+			changed = append(changed, fields[i])
+		}
+	}
+	return changed, deleted, nil
+}
+
 func shutdownOnSIGINT(s *http.Server) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
@@ -0,0 +1,122 @@
+package ldap
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/auth"
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"github.com/sourcegraph/sourcegraph/schema"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+	ldap "gopkg.in/ldap.v3"
+)
+
+var mockGetProviderValue *provider
+
+// getProvider looks up the registered LDAP auth provider with the given ID.
+func getProvider(id string) *provider {
+	if mockGetProviderValue != nil {
+		return mockGetProviderValue
+	}
+	p, _ := auth.GetProviderByConfigID(auth.ProviderConfigID{Type: providerType, ID: id}).(*provider)
+	return p
+}
+
+func handleGetProvider(ctx context.Context, w http.ResponseWriter, id string) (p *provider, handled bool) {
+	p = getProvider(id)
+	if p == nil {
+		log15.Error("No LDAP auth provider found with ID.", "id", id)
+		http.Error(w, "Misconfigured LDAP auth provider.", http.StatusInternalServerError)
+		return nil, true
+	}
+	return p, false
+}
+
+func init() {
+	conf.ContributeValidator(validateConfig)
+}
+
+func validateConfig(c conf.Unified) (problems []string) {
+	seen := map[string]int{}
+	for i, p := range c.Critical.AuthProviders {
+		if p.Ldap == nil {
+			continue
+		}
+		pc := withConfigDefaults(p.Ldap)
+
+		id := providerConfigID(pc)
+		if j, ok := seen[id]; ok {
+			problems = append(problems, fmt.Sprintf("LDAP auth provider at index %d is duplicate of index %d, ignoring", i, j))
+			continue
+		}
+		seen[id] = i
+
+		if u, err := url.Parse(pc.Url); err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("LDAP auth provider at index %d has an invalid url %q (it must be of the form ldap://host[:port] or ldaps://host[:port])", i, pc.Url))
+		} else if u.Scheme == "ldap" && !pc.StartTLS {
+			// 🚨 SECURITY: Without TLS, the bindPassword and users' passwords would be sent in
+			// plaintext.
+			problems = append(problems, fmt.Sprintf("LDAP auth provider at index %d would send credentials in plaintext (use an ldaps:// url, or set startTLS to true)", i))
+		} else if u.Scheme == "ldaps" && pc.StartTLS {
+			problems = append(problems, fmt.Sprintf("LDAP auth provider at index %d has startTLS set with an ldaps:// url (startTLS is only for ldap:// urls)", i))
+		}
+		if _, err := (&provider{config: *pc}).tlsConfig(); err != nil {
+			problems = append(problems, fmt.Sprintf("LDAP auth provider at index %d has an invalid certificate", i))
+		}
+		for _, f := range []struct{ name, filter string }{
+			{"userSearchFilter", pc.UserSearchFilter},
+			{"groupSearchFilter", pc.GroupSearchFilter},
+		} {
+			filter := strings.NewReplacer("{username}", "x", "{dn}", "x").Replace(f.filter)
+			if _, err := ldap.CompileFilter(filter); err != nil {
+				problems = append(problems, fmt.Sprintf("LDAP auth provider at index %d has an invalid %s: %s", i, f.name, err))
+			}
+		}
+		if !strings.Contains(pc.UserSearchFilter, "{username}") {
+			problems = append(problems, fmt.Sprintf("LDAP auth provider at index %d has a userSearchFilter without the {username} placeholder", i))
+		}
+	}
+	return problems
+}
+
+// withConfigDefaults returns a copy of pc with the default values set for unset properties.
+func withConfigDefaults(pc *schema.LDAPAuthProvider) *schema.LDAPAuthProvider {
+	tmp := *pc
+	if tmp.UserSearchFilter == "" {
+		tmp.UserSearchFilter = "(uid={username})"
+	}
+	if tmp.UsernameAttribute == "" {
+		tmp.UsernameAttribute = "uid"
+	}
+	if tmp.EmailAttribute == "" {
+		tmp.EmailAttribute = "mail"
+	}
+	if tmp.DisplayNameAttribute == "" {
+		tmp.DisplayNameAttribute = "cn"
+	}
+	if tmp.GroupSearchBase == "" {
+		tmp.GroupSearchBase = tmp.UserSearchBase
+	}
+	if tmp.GroupSearchFilter == "" {
+		tmp.GroupSearchFilter = "(member={dn})"
+	}
+	return &tmp
+}
+
+// providerConfigID produces a semi-stable identifier for an LDAP auth provider config object. It is
+// used to distinguish between multiple auth providers of the same type in the login form. Its
+// value is never persisted, and it must be deterministic.
+func providerConfigID(pc *schema.LDAPAuthProvider) string {
+	data, err := json.Marshal(pc)
+	if err != nil {
+		panic(err)
+	}
+	b := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(b[:16])
+}
@@ -0,0 +1,82 @@
+package ldap
+
+import (
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/conf"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestValidateCustom(t *testing.T) {
+	tests := map[string]struct {
+		input        conf.Unified
+		wantProblems []string
+	}{
+		"valid": {
+			input: conf.Unified{Critical: schema.CriticalConfiguration{
+				AuthProviders: []schema.AuthProviders{
+					{Ldap: &schema.LDAPAuthProvider{Type: "ldap", Url: "ldaps://ldap.example.com", UserSearchBase: "dc=example,dc=com"}},
+				},
+			}},
+		},
+		"duplicates": {
+			input: conf.Unified{Critical: schema.CriticalConfiguration{
+				AuthProviders: []schema.AuthProviders{
+					{Ldap: &schema.LDAPAuthProvider{Type: "ldap", Url: "ldap://x", StartTLS: true, UserSearchBase: "dc=x"}},
+					{Ldap: &schema.LDAPAuthProvider{Type: "ldap", Url: "ldap://x", StartTLS: true, UserSearchBase: "dc=x"}},
+				},
+			}},
+			wantProblems: []string{"LDAP auth provider at index 1 is duplicate of index 0"},
+		},
+		"invalid url": {
+			input: conf.Unified{Critical: schema.CriticalConfiguration{
+				AuthProviders: []schema.AuthProviders{
+					{Ldap: &schema.LDAPAuthProvider{Type: "ldap", Url: "https://x", UserSearchBase: "dc=x"}},
+				},
+			}},
+			wantProblems: []string{"LDAP auth provider at index 0 has an invalid url"},
+		},
+		"plaintext credentials": {
+			input: conf.Unified{Critical: schema.CriticalConfiguration{
+				AuthProviders: []schema.AuthProviders{
+					{Ldap: &schema.LDAPAuthProvider{Type: "ldap", Url: "ldap://x", UserSearchBase: "dc=x"}},
+				},
+			}},
+			wantProblems: []string{"LDAP auth provider at index 0 would send credentials in plaintext"},
+		},
+		"startTLS with ldaps": {
+			input: conf.Unified{Critical: schema.CriticalConfiguration{
+				AuthProviders: []schema.AuthProviders{
+					{Ldap: &schema.LDAPAuthProvider{Type: "ldap", Url: "ldaps://x", StartTLS: true, UserSearchBase: "dc=x"}},
+				},
+			}},
+			wantProblems: []string{"LDAP auth provider at index 0 has startTLS set with an ldaps:// url"},
+		},
+		"invalid filters": {
+			input: conf.Unified{Critical: schema.CriticalConfiguration{
+				AuthProviders: []schema.AuthProviders{
+					{Ldap: &schema.LDAPAuthProvider{Type: "ldap", Url: "ldap://x", StartTLS: true, UserSearchBase: "dc=x", UserSearchFilter: "(uid=alice", GroupSearchFilter: "member={dn}"}},
+				},
+			}},
+			wantProblems: []string{
+				"LDAP auth provider at index 0 has an invalid userSearchFilter",
+				"LDAP auth provider at index 0 has an invalid groupSearchFilter",
+				"LDAP auth provider at index 0 has a userSearchFilter without the {username} placeholder",
+			},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			conf.TestValidator(t, test.input, validateConfig, test.wantProblems)
+		})
+	}
+}
+
+func TestProviderConfigID(t *testing.T) {
+	p := schema.LDAPAuthProvider{Url: "ldap://x"}
+	id1 := providerConfigID(&p)
+	id2 := providerConfigID(&p)
+	if id1 != id2 {
+		t.Errorf("id1 (%q) != id2 (%q)", id1, id2)
+	}
+}
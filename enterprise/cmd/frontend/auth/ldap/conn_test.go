@@ -0,0 +1,145 @@
+package ldap
+
+import (
+	"errors"
+	"strings"
+
+	ber "gopkg.in/asn1-ber.v1"
+	ldap "gopkg.in/ldap.v3"
+)
+
+// testDirectory is an in-memory LDAP directory for tests. Its dial method can be used as mockDial.
+type testDirectory struct {
+	entries []*testEntry
+
+	// requireBind makes searches fail unless the connection is bound with a password.
+	requireBind bool
+}
+
+type testEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+func (d *testDirectory) dial(*provider) (conn, error) { return &testConn{d: d}, nil }
+
+// testConn is a connection to a testDirectory. Like *ldap.Conn, its Search method returns no
+// entries if the size limit is exceeded.
+type testConn struct {
+	d     *testDirectory
+	bound bool
+}
+
+func (c *testConn) Bind(dn, password string) error {
+	c.bound = false
+	if password == "" {
+		return errors.New("empty password not allowed by the client")
+	}
+	for _, e := range c.d.entries {
+		if strings.EqualFold(e.dn, dn) && e.password == password {
+			c.bound = true
+			return nil
+		}
+	}
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+}
+
+func (c *testConn) UnauthenticatedBind(string) error {
+	c.bound = false
+	return nil
+}
+
+func (c *testConn) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if c.d.requireBind && !c.bound {
+		return nil, ldap.NewError(ldap.LDAPResultInsufficientAccessRights, errors.New("bind required"))
+	}
+	filter, err := ldap.CompileFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var res ldap.SearchResult
+	for _, e := range c.d.entries {
+		if !strings.HasSuffix(strings.ToLower(e.dn), strings.ToLower(req.BaseDN)) || !matchFilter(filter, e) {
+			continue
+		}
+		if req.SizeLimit > 0 && len(res.Entries) == req.SizeLimit {
+			return nil, ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("size limit exceeded"))
+		}
+		attrs := map[string][]string{}
+		for name, vs := range e.attrs {
+			for _, a := range req.Attributes {
+				if strings.EqualFold(name, a) {
+					attrs[name] = vs
+				}
+			}
+		}
+		res.Entries = append(res.Entries, ldap.NewEntry(e.dn, attrs))
+	}
+	return &res, nil
+}
+
+func (c *testConn) Close() {}
+
+// matchFilter reports whether the entry matches the compiled filter f. Values are compared
+// case-insensitively.
+func matchFilter(f *ber.Packet, e *testEntry) bool {
+	values := func(attr string) []string {
+		for name, vs := range e.attrs {
+			if strings.EqualFold(name, attr) {
+				return vs
+			}
+		}
+		return nil
+	}
+
+	switch f.Tag {
+	case ldap.FilterAnd:
+		for _, c := range f.Children {
+			if !matchFilter(c, e) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, c := range f.Children {
+			if matchFilter(c, e) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return !matchFilter(f.Children[0], e)
+	case ldap.FilterEqualityMatch:
+		for _, v := range values(f.Children[0].Data.String()) {
+			if strings.EqualFold(v, f.Children[1].Data.String()) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterPresent:
+		return len(values(f.Data.String())) > 0
+	case ldap.FilterSubstrings:
+		for _, v := range values(f.Children[0].Data.String()) {
+			v = strings.ToLower(v)
+			ok := true
+			for _, s := range f.Children[1].Children {
+				sub := strings.ToLower(s.Data.String())
+				switch s.Tag {
+				case ldap.FilterSubstringsInitial:
+					ok = ok && strings.HasPrefix(v, sub)
+				case ldap.FilterSubstringsAny:
+					ok = ok && strings.Contains(v, sub)
+				case ldap.FilterSubstringsFinal:
+					ok = ok && strings.HasSuffix(v, sub)
+				}
+			}
+			if ok {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+// Package ldap implements auth via LDAP (including Active Directory).
+package ldap
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/auth"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/external/session"
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// All LDAP endpoints are under this path prefix.
+const authPrefix = auth.AuthURLPrefix + "/ldap"
+
+// loginCookieName is the name of the cookie that holds the login form token, which protects the
+// login form against cross-site request forgery.
+const loginCookieName = "sg-ldap-login"
+
+const loginCookieTimeout = time.Minute * 15
+
+// Middleware is middleware for LDAP authentication, adding endpoints under the auth path prefix
+// ("/.auth") to enable the login flow.
+//
+// Unlike the SSO auth providers, the LDAP auth provider collects the user's username and password
+// itself (in a login form served at "/.auth/ldap/login"), and checks them by binding to the LDAP
+// server as the user. Upon success, it creates a new session and session cookie.
+//
+// 🚨 SECURITY
+var Middleware = &auth.Middleware{
+	API: func(next http.Handler) http.Handler {
+		return next
+	},
+	App: func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handleLDAPAuth(w, r, next)
+		})
+	},
+}
+
+func handleLDAPAuth(w http.ResponseWriter, r *http.Request, next http.Handler) {
+	// Delegate to the LDAP auth handler.
+	if strings.HasPrefix(r.URL.Path, authPrefix+"/") {
+		authHandler(w, r)
+		return
+	}
+
+	// If there is only one auth provider configured, the single auth provider is LDAP, and the
+	// actor is not authenticated, redirect to the login form immediately. The user wouldn't be able
+	// to do anything else anyway; there's no point in showing them a signin screen with just a
+	// single signin option.
+	if ps := auth.Providers(); len(ps) == 1 && ps[0].Config().Ldap != nil && !actor.FromContext(r.Context()).IsAuthenticated() {
+		http.Redirect(w, r, loginURL(ps[0].ConfigID().ID, auth.SafeRedirectURL(r.URL.String())), http.StatusFound)
+		return
+	}
+
+	next.ServeHTTP(w, r)
+}
+
+func loginURL(providerID, redirect string) string {
+	return (&url.URL{
+		Path:     authPrefix + "/login",
+		RawQuery: (url.Values{"pc": []string{providerID}, "redirect": []string{redirect}}).Encode(),
+	}).String()
+}
+
+// authHandler handles the LDAP login form.
+//
+// 🚨 SECURITY
+func authHandler(w http.ResponseWriter, r *http.Request) {
+	if strings.TrimPrefix(r.URL.Path, authPrefix) != "/login" {
+		http.Error(w, "", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		p, handled := handleGetProvider(r.Context(), w, r.URL.Query().Get("pc"))
+		if handled {
+			return
+		}
+		renderLoginForm(w, r, p, http.StatusOK, loginForm{Redirect: r.URL.Query().Get("redirect")})
+
+	case "POST":
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Authentication failed. The login form data was malformed.", http.StatusBadRequest)
+			return
+		}
+		p, handled := handleGetProvider(r.Context(), w, r.PostForm.Get("pc"))
+		if handled {
+			return
+		}
+		form := loginForm{
+			Redirect: r.PostForm.Get("redirect"),
+			Username: r.PostForm.Get("username"),
+		}
+
+		// 🚨 SECURITY: Check that the form was submitted from the login form that we served (and
+		// not from another site, which could otherwise sign the user in as someone else).
+		cookie, err := r.Cookie(loginCookieName)
+		if err != nil || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.PostForm.Get("token"))) != 1 {
+			log15.Error("LDAP auth failed: login form token mismatch (possible request forgery).")
+			form.Error = "Your sign-in attempt expired. Please try again."
+			renderLoginForm(w, r, p, http.StatusBadRequest, form)
+			return
+		}
+
+		user, groups, err := p.authenticate(r.Context(), form.Username, r.PostForm.Get("password"))
+		if err == errInvalidCredentials {
+			form.Error = "Invalid username or password."
+			renderLoginForm(w, r, p, http.StatusUnauthorized, form)
+			return
+		}
+		if err != nil {
+			log15.Error("LDAP auth failed: error authenticating user.", "username", form.Username, "error", err)
+			form.Error = "Unable to check your username and password with the LDAP server. Please try again or contact your site admin."
+			renderLoginForm(w, r, p, http.StatusInternalServerError, form)
+			return
+		}
+
+		actr, safeErrMsg, err := getOrCreateUser(r.Context(), p, user)
+		if err != nil {
+			log15.Error("LDAP auth failed: error looking up LDAP-authenticated user.", "error", err, "userErr", safeErrMsg)
+			http.Error(w, safeErrMsg, http.StatusInternalServerError)
+			return
+		}
+
+		if len(p.config.GroupOrgs) > 0 {
+			if err := syncOrgMembers(r.Context(), actr.UID, orgMemberships(p.config.GroupOrgs, groups)); err != nil {
+				log15.Error("LDAP auth failed: could not update organization memberships.", "error", err)
+				http.Error(w, "Authentication failed. The error was: could not update your organization memberships.", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := session.SetActor(w, r, actr, 0); err != nil {
+			log15.Error("LDAP auth failed: could not initiate session.", "error", err)
+			http.Error(w, "Authentication failed. Try signing in again (and clearing cookies for the current site). The error was: could not initiate session.", http.StatusInternalServerError)
+			return
+		}
+
+		// 🚨 SECURITY: Call auth.SafeRedirectURL to avoid an open-redirect vuln.
+		http.Redirect(w, r, auth.SafeRedirectURL(form.Redirect), http.StatusFound)
+
+	default:
+		http.Error(w, "", http.StatusMethodNotAllowed)
+	}
+}
+
+type loginForm struct {
+	DisplayName string
+	ProviderID  string
+	Token       string
+	Redirect    string
+	Username    string
+	Error       string
+}
+
+// renderLoginForm renders the login form with a new login form token.
+func renderLoginForm(w http.ResponseWriter, r *http.Request, p *provider, status int, form loginForm) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log15.Error("LDAP auth failed: could not generate login form token.", "error", err)
+		http.Error(w, "Unexpected error generating the login form.", http.StatusInternalServerError)
+		return
+	}
+	form.Token = base64.RawURLEncoding.EncodeToString(b)
+	form.DisplayName = p.CachedInfo().DisplayName
+	form.ProviderID = p.ConfigID().ID
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     loginCookieName,
+		Value:    form.Token,
+		Path:     authPrefix + "/",
+		Expires:  time.Now().Add(loginCookieTimeout),
+		HttpOnly: true,
+	})
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := loginFormTemplate.Execute(w, form); err != nil {
+		log15.Error("Error rendering LDAP login form.", "error", err)
+	}
+}
+
+var loginFormTemplate = template.Must(template.New("").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign in - Sourcegraph</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Roboto, sans-serif; background: #f2f4f8; }
+form { max-width: 20rem; margin: 5rem auto; padding: 1.5rem; background: #fff; border-radius: 4px; }
+label, input, button { display: block; width: 100%; box-sizing: border-box; }
+input { margin: 0.25rem 0 1rem; padding: 0.375rem; }
+button { padding: 0.5rem; }
+.error { color: #c00; }
+</style>
+</head>
+<body>
+<form method="post" action="login">
+<h1>Sign in with {{.DisplayName}}</h1>
+{{with .Error}}<p class="error">{{.}}</p>{{end}}
+<input type="hidden" name="pc" value="{{.ProviderID}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<input type="hidden" name="redirect" value="{{.Redirect}}">
+<label>Username <input name="username" value="{{.Username}}" autocomplete="username" required autofocus></label>
+<label>Password <input type="password" name="password" autocomplete="current-password" required></label>
+<button type="submit">Sign in</button>
+</form>
+</body>
+</html>
+`))
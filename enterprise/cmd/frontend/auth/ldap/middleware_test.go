@@ -0,0 +1,174 @@
+package ldap
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/auth"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/external/session"
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
+)
+
+func TestMiddleware(t *testing.T) {
+	cleanup := session.ResetMockSessionStore(t)
+	defer cleanup()
+
+	mockDial = (&testDirectory{entries: testEntries}).dial
+	defer func() { mockDial = nil }()
+
+	mockGetProviderValue = newTestProvider()
+	defer func() { mockGetProviderValue = nil }()
+	auth.MockProviders = []auth.Provider{mockGetProviderValue}
+	defer func() { auth.MockProviders = nil }()
+
+	const mockUserID = 123
+	auth.MockGetAndSaveUser = func(ctx context.Context, op auth.GetAndSaveUserOp) (userID int32, safeErrMsg string, err error) {
+		if op.ExternalAccount.ServiceType == "ldap" && op.ExternalAccount.ServiceID == mockGetProviderValue.config.Url && op.ExternalAccount.AccountID == "alice" &&
+			op.UserProps.Username == "alice" && op.UserProps.Email == "alice@example.com" && op.UserProps.DisplayName == "Alice Smith" {
+			return mockUserID, "", nil
+		}
+		return 0, "safeErr", fmt.Errorf("account %v not found in mock", op.ExternalAccount)
+	}
+	defer func() { auth.MockGetAndSaveUser = nil }()
+
+	var syncedOrgs map[string]bool
+	mockSyncOrgMembers = func(userID int32, member map[string]bool) error {
+		if userID != mockUserID {
+			t.Errorf("got user ID %d, want %d", userID, mockUserID)
+		}
+		syncedOrgs = member
+		return nil
+	}
+	defer func() { mockSyncOrgMembers = nil }()
+
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	authedHandler := http.NewServeMux()
+	authedHandler.Handle("/.api/", Middleware.API(h))
+	authedHandler.Handle("/", Middleware.App(h))
+
+	doRequest := func(method, urlStr string, form url.Values, cookies []*http.Cookie, authed bool) *http.Response {
+		req := httptest.NewRequest(method, urlStr, strings.NewReader(form.Encode()))
+		if form != nil {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		if authed {
+			req = req.WithContext(actor.WithActor(context.Background(), &actor.Actor{UID: mockUserID}))
+		}
+		respRecorder := httptest.NewRecorder()
+		authedHandler.ServeHTTP(respRecorder, req)
+		return respRecorder.Result()
+	}
+
+	providerID := mockGetProviderValue.ConfigID().ID
+
+	// getLoginForm requests the login form and returns the login form cookie and token.
+	getLoginForm := func(t *testing.T) (*http.Cookie, string) {
+		resp := doRequest("GET", "http://example.com/.auth/ldap/login?pc="+providerID+"&redirect=/redirect", nil, nil, false)
+		if want := http.StatusOK; resp.StatusCode != want {
+			t.Fatalf("got response code %v, want %v", resp.StatusCode, want)
+		}
+		var cookie *http.Cookie
+		for _, c := range resp.Cookies() {
+			if c.Name == loginCookieName {
+				cookie = c
+			}
+		}
+		if cookie == nil {
+			t.Fatal("no login form cookie set")
+		}
+		body := readAll(t, resp)
+		m := regexp.MustCompile(`name="token" value="([^"]+)"`).FindStringSubmatch(body)
+		if m == nil || m[1] != cookie.Value {
+			t.Fatalf("login form token does not match cookie %q", cookie.Value)
+		}
+		if !strings.Contains(body, `name="redirect" value="/redirect"`) {
+			t.Errorf("login form does not preserve redirect")
+		}
+		return cookie, m[1]
+	}
+
+	login := func(token, username, password string) url.Values {
+		return url.Values{"pc": {providerID}, "token": {token}, "redirect": {"/redirect"}, "username": {username}, "password": {password}}
+	}
+
+	t.Run("unauthenticated homepage visit -> login form", func(t *testing.T) {
+		resp := doRequest("GET", "http://example.com/page", nil, nil, false)
+		if want := http.StatusFound; resp.StatusCode != want {
+			t.Errorf("got response code %v, want %v", resp.StatusCode, want)
+		}
+		if got, want := resp.Header.Get("Location"), "/.auth/ldap/login?pc="+providerID+"&redirect=%2Fpage"; got != want {
+			t.Errorf("got redirect URL %v, want %v", got, want)
+		}
+	})
+	t.Run("unauthenticated API request -> pass through", func(t *testing.T) {
+		resp := doRequest("GET", "http://example.com/.api/foo", nil, nil, false)
+		if want := http.StatusOK; resp.StatusCode != want {
+			t.Errorf("got response code %v, want %v", resp.StatusCode, want)
+		}
+	})
+	t.Run("authenticated app request", func(t *testing.T) {
+		resp := doRequest("GET", "http://example.com/", nil, nil, true)
+		if want := http.StatusOK; resp.StatusCode != want {
+			t.Errorf("got response code %v, want %v", resp.StatusCode, want)
+		}
+	})
+	t.Run("login without form token -> error", func(t *testing.T) {
+		cookie, _ := getLoginForm(t)
+		resp := doRequest("POST", "http://example.com/.auth/ldap/login", login("bad", "alice", "alice-secret"), []*http.Cookie{cookie}, false)
+		if want := http.StatusBadRequest; resp.StatusCode != want {
+			t.Errorf("got response code %v, want %v", resp.StatusCode, want)
+		}
+		if len(resp.Header["Set-Cookie"]) != 1 {
+			t.Errorf("got cookies %q, want only a new login form cookie", resp.Header["Set-Cookie"])
+		}
+	})
+	t.Run("login with wrong password -> error", func(t *testing.T) {
+		cookie, token := getLoginForm(t)
+		resp := doRequest("POST", "http://example.com/.auth/ldap/login", login(token, "alice", "wrong"), []*http.Cookie{cookie}, false)
+		if want := http.StatusUnauthorized; resp.StatusCode != want {
+			t.Errorf("got response code %v, want %v", resp.StatusCode, want)
+		}
+		if body := readAll(t, resp); !strings.Contains(body, "Invalid username or password.") {
+			t.Errorf("got body %q, want error message", body)
+		}
+	})
+	t.Run("login with empty password -> error", func(t *testing.T) {
+		cookie, token := getLoginForm(t)
+		resp := doRequest("POST", "http://example.com/.auth/ldap/login", login(token, "alice", ""), []*http.Cookie{cookie}, false)
+		if want := http.StatusUnauthorized; resp.StatusCode != want {
+			t.Errorf("got response code %v, want %v", resp.StatusCode, want)
+		}
+	})
+	t.Run("login with valid credentials -> set auth cookies", func(t *testing.T) {
+		cookie, token := getLoginForm(t)
+		resp := doRequest("POST", "http://example.com/.auth/ldap/login", login(token, "alice", "alice-secret"), []*http.Cookie{cookie}, false)
+		if want := http.StatusFound; resp.StatusCode != want {
+			t.Errorf("got response code %v, want %v", resp.StatusCode, want)
+		}
+		if got, want := resp.Header.Get("Location"), "/redirect"; got != want {
+			t.Errorf("got redirect URL %v, want %v", got, want)
+		}
+		if want := map[string]bool{"eng": true, "admins": true}; !reflect.DeepEqual(syncedOrgs, want) {
+			t.Errorf("got org memberships %v, want %v", syncedOrgs, want)
+		}
+	})
+}
+
+func readAll(t *testing.T, resp *http.Response) string {
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
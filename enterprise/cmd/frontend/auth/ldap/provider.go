@@ -0,0 +1,239 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/auth"
+	"github.com/sourcegraph/sourcegraph/schema"
+	ldap "gopkg.in/ldap.v3"
+)
+
+const providerType = "ldap"
+
+// errInvalidCredentials is returned by authenticate when the username or password is wrong.
+var errInvalidCredentials = errors.New("invalid username or password")
+
+type provider struct {
+	config schema.LDAPAuthProvider
+}
+
+// ConfigID implements auth.Provider.
+func (p *provider) ConfigID() auth.ProviderConfigID {
+	return auth.ProviderConfigID{
+		Type: providerType,
+		ID:   providerConfigID(&p.config),
+	}
+}
+
+// Config implements auth.Provider.
+func (p *provider) Config() schema.AuthProviders {
+	return schema.AuthProviders{Ldap: &p.config}
+}
+
+// Refresh implements auth.Provider.
+func (p *provider) Refresh(context.Context) error { return nil }
+
+// CachedInfo implements auth.Provider.
+func (p *provider) CachedInfo() *auth.ProviderInfo {
+	info := auth.ProviderInfo{
+		ServiceID:   p.config.Url,
+		DisplayName: p.config.DisplayName,
+		AuthenticationURL: (&url.URL{
+			Path:     path.Join(authPrefix, "login"),
+			RawQuery: (url.Values{"pc": []string{providerConfigID(&p.config)}}).Encode(),
+		}).String(),
+	}
+	if info.DisplayName == "" {
+		info.DisplayName = "LDAP"
+	}
+	return &info
+}
+
+func (p *provider) tlsConfig() (*tls.Config, error) {
+	if p.config.Certificate == "" {
+		return nil, nil
+	}
+	roots := x509.NewCertPool()
+	if ok := roots.AppendCertsFromPEM([]byte(p.config.Certificate)); !ok {
+		return nil, errors.New("invalid certificate for LDAP server")
+	}
+	return &tls.Config{RootCAs: roots}, nil
+}
+
+// defaultTimeout is the timeout for a connection to an LDAP server if the context has no deadline.
+const defaultTimeout = 30 * time.Second
+
+// conn is the subset of the methods of *ldap.Conn that authenticate uses.
+type conn interface {
+	Bind(username, password string) error
+	UnauthenticatedBind(username string) error
+	Search(*ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close()
+}
+
+// mockDial mocks (*provider).dial. It should only be set in tests.
+var mockDial func(p *provider) (conn, error)
+
+// dial connects to the LDAP server. If the URL scheme is ldap://, the connection is upgraded to TLS
+// with StartTLS.
+//
+// 🚨 SECURITY: dial never returns a connection that isn't secured by TLS, because the bindDN's and
+// users' passwords are sent over it.
+func (p *provider) dial(ctx context.Context) (conn, error) {
+	if mockDial != nil {
+		return mockDial(p)
+	}
+
+	u, err := url.Parse(p.config.Url)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "ldap" && !p.config.StartTLS {
+		return nil, errors.New("refusing to send credentials in plaintext (use an ldaps:// url, or set startTLS to true)")
+	}
+	host := u.Host
+	if u.Port() == "" {
+		switch u.Scheme {
+		case "ldap":
+			host = net.JoinHostPort(u.Hostname(), "389")
+		case "ldaps":
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+	}
+
+	tlsConfig, err := p.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig.ServerName = u.Hostname()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	dialer := net.Dialer{Deadline: deadline}
+
+	var nc net.Conn
+	switch u.Scheme {
+	case "ldap":
+		nc, err = dialer.DialContext(ctx, "tcp", host)
+	case "ldaps":
+		nc, err = tls.DialWithDialer(&dialer, "tcp", host, tlsConfig)
+	default:
+		return nil, fmt.Errorf("unsupported LDAP URL scheme %q (must be ldap or ldaps)", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := nc.SetDeadline(deadline); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	c := ldap.NewConn(nc, u.Scheme == "ldaps")
+	c.Start()
+	c.SetTimeout(time.Until(deadline))
+
+	if u.Scheme == "ldap" {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, errors.Wrap(err, "StartTLS")
+		}
+	}
+	return c, nil
+}
+
+// bindService binds the connection as bindDN, or anonymously if bindDN is not set.
+func (p *provider) bindService(c conn) error {
+	if p.config.BindDN == "" {
+		return c.UnauthenticatedBind("")
+	}
+	return c.Bind(p.config.BindDN, p.config.BindPassword)
+}
+
+// authenticate checks the user's password against the LDAP server and returns the user's entry
+// and the DNs of the groups the user is a member of. The groups are only looked up if the provider
+// maps groups to organizations.
+//
+// 🚨 SECURITY: The user is authenticated if and only if the returned error is nil.
+func (p *provider) authenticate(ctx context.Context, username, password string) (user *ldap.Entry, groups []string, err error) {
+	// 🚨 SECURITY: LDAP servers treat a bind with an empty password as an anonymous bind, which
+	// would succeed for any user.
+	if username == "" || password == "" {
+		return nil, nil, errInvalidCredentials
+	}
+
+	c, err := p.dial(ctx)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "connecting to LDAP server")
+	}
+	defer c.Close()
+
+	if p.config.BindDN != "" {
+		if err := p.bindService(c); err != nil {
+			return nil, nil, errors.Wrap(err, "binding as bindDN")
+		}
+	}
+
+	// Request a size limit of 1 so that the server reports an error if the filter matches more than
+	// 1 entry.
+	res, err := c.Search(ldap.NewSearchRequest(
+		p.config.UserSearchBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 1, 0, false,
+		strings.Replace(p.config.UserSearchFilter, "{username}", ldap.EscapeFilter(username), -1),
+		[]string{p.config.UsernameAttribute, p.config.EmailAttribute, p.config.DisplayNameAttribute},
+		nil,
+	))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, nil, fmt.Errorf("userSearchFilter matched more than 1 entry for username %q", username)
+	}
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "searching for user")
+	}
+	if len(res.Entries) != 1 {
+		return nil, nil, errInvalidCredentials
+	}
+	user = res.Entries[0]
+
+	if err := c.Bind(user.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, nil, errInvalidCredentials
+		}
+		return nil, nil, errors.Wrap(err, "binding as user")
+	}
+
+	if len(p.config.GroupOrgs) == 0 {
+		return user, nil, nil
+	}
+
+	// Search for groups as bindDN (or anonymously), not as the user, who might not be allowed to.
+	if err := p.bindService(c); err != nil {
+		return nil, nil, errors.Wrap(err, "binding as bindDN")
+	}
+	res, err = c.Search(ldap.NewSearchRequest(
+		p.config.GroupSearchBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		strings.NewReplacer(
+			"{dn}", ldap.EscapeFilter(user.DN),
+			"{username}", ldap.EscapeFilter(username),
+		).Replace(p.config.GroupSearchFilter),
+		[]string{"1.1"}, // no attributes (see RFC 4511 section 4.5.1.8)
+		nil,
+	))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "searching for user's groups")
+	}
+	for _, g := range res.Entries {
+		groups = append(groups, g.DN)
+	}
+	return user, groups, nil
+}
@@ -0,0 +1,132 @@
+package ldap
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+var testEntries = []*testEntry{
+	{
+		dn:       "cn=sourcegraph,ou=services,dc=example,dc=com",
+		password: "service-secret",
+	},
+	{
+		dn:       "uid=alice,ou=people,dc=example,dc=com",
+		password: "alice-secret",
+		attrs: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {"alice"},
+			"mail":        {"alice@example.com"},
+			"cn":          {"Alice Smith"},
+		},
+	},
+	{
+		dn:       "uid=bob,ou=people,dc=example,dc=com",
+		password: "bob-secret",
+		attrs: map[string][]string{
+			"objectClass": {"person"},
+			"uid":         {"bob"},
+			"cn":          {"Bob"},
+		},
+	},
+	{
+		dn: "cn=engineering,ou=groups,dc=example,dc=com",
+		attrs: map[string][]string{
+			"objectClass": {"groupOfNames"},
+			"member":      {"uid=alice,ou=people,dc=example,dc=com", "uid=bob,ou=people,dc=example,dc=com"},
+		},
+	},
+	{
+		dn: "cn=admins,ou=groups,dc=example,dc=com",
+		attrs: map[string][]string{
+			"objectClass": {"groupOfNames"},
+			"member":      {"uid=alice,ou=people,dc=example,dc=com"},
+		},
+	},
+}
+
+func newTestProvider() *provider {
+	return &provider{config: *withConfigDefaults(&schema.LDAPAuthProvider{
+		Type:           providerType,
+		Url:            "ldaps://ldap.example.com",
+		BindDN:         "cn=sourcegraph,ou=services,dc=example,dc=com",
+		BindPassword:   "service-secret",
+		UserSearchBase: "ou=people,dc=example,dc=com",
+		GroupOrgs: []*schema.LDAPGroupOrg{
+			{Group: "cn=engineering,ou=groups,dc=example,dc=com", Org: "eng"},
+			{Group: "cn=admins,ou=groups,dc=example,dc=com", Org: "admins"},
+		},
+		GroupSearchBase: "ou=groups,dc=example,dc=com",
+	})}
+}
+
+func TestProvider_authenticate(t *testing.T) {
+	mockDial = (&testDirectory{entries: testEntries, requireBind: true}).dial
+	defer func() { mockDial = nil }()
+	p := newTestProvider()
+	ctx := context.Background()
+
+	t.Run("valid credentials", func(t *testing.T) {
+		user, groups, err := p.authenticate(ctx, "alice", "alice-secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "uid=alice,ou=people,dc=example,dc=com"; user.DN != want {
+			t.Errorf("got DN %q, want %q", user.DN, want)
+		}
+		if got, want := entryAttr(user, "mail"), "alice@example.com"; got != want {
+			t.Errorf("got mail %q, want %q", got, want)
+		}
+		if got, want := entryAttr(user, "cn"), "Alice Smith"; got != want {
+			t.Errorf("got cn %q, want %q", got, want)
+		}
+		if want := []string{"cn=engineering,ou=groups,dc=example,dc=com", "cn=admins,ou=groups,dc=example,dc=com"}; !reflect.DeepEqual(groups, want) {
+			t.Errorf("got groups %q, want %q", groups, want)
+		}
+	})
+
+	for _, test := range []struct {
+		name, username, password string
+	}{
+		{"wrong password", "alice", "bob-secret"},
+		{"empty password", "alice", ""},
+		{"unknown user", "carol", "alice-secret"},
+		{"wildcard username", "*", "alice-secret"},
+		{"filter injection", "alice)(uid=*", "alice-secret"},
+		{"service account", "sourcegraph", "service-secret"},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := p.authenticate(ctx, test.username, test.password); err != errInvalidCredentials {
+				t.Errorf("got error %v, want %v", err, errInvalidCredentials)
+			}
+		})
+	}
+
+	t.Run("ambiguous user search filter", func(t *testing.T) {
+		p := newTestProvider()
+		p.config.UserSearchFilter = "(|(uid={username})(objectClass=person))"
+		if _, _, err := p.authenticate(ctx, "alice", "alice-secret"); err == nil || err == errInvalidCredentials {
+			t.Errorf("got error %v, want ambiguous filter error", err)
+		}
+	})
+
+	t.Run("wrong bindPassword", func(t *testing.T) {
+		p := newTestProvider()
+		p.config.BindPassword = "wrong"
+		if _, _, err := p.authenticate(ctx, "alice", "alice-secret"); err == nil || err == errInvalidCredentials {
+			t.Errorf("got error %v, want bind error", err)
+		}
+	})
+}
+
+func TestProvider_dial_plaintext(t *testing.T) {
+	p := newTestProvider()
+	p.config.Url = "ldap://127.0.0.1:1"
+	if _, err := p.dial(context.Background()); err == nil || !strings.Contains(err.Error(), "plaintext") {
+		t.Errorf("got error %v, want plaintext credentials error", err)
+	}
+}
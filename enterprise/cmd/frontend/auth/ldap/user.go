@@ -0,0 +1,146 @@
+package ldap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/auth"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/pkg/actor"
+	"github.com/sourcegraph/sourcegraph/pkg/errcode"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc"
+	"github.com/sourcegraph/sourcegraph/schema"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+	ldap "gopkg.in/ldap.v3"
+)
+
+// getOrCreateUser gets or creates a user account based on the user's LDAP entry. It returns the
+// authenticated actor if successful; otherwise it returns an friendly error message (safeErrMsg)
+// that is safe to display to users, and a non-nil err with lower-level error details.
+func getOrCreateUser(ctx context.Context, p *provider, user *ldap.Entry) (_ *actor.Actor, safeErrMsg string, err error) {
+	// The unnormalized username is the external account ID, so that two LDAP users whose usernames
+	// normalize to the same Sourcegraph username are not conflated.
+	rawUsername := entryAttr(user, p.config.UsernameAttribute)
+	if rawUsername == "" {
+		return nil, fmt.Sprintf("Your LDAP entry has no %q attribute, which is required to sign in to Sourcegraph.", p.config.UsernameAttribute), fmt.Errorf("LDAP entry %q has no %q attribute", user.DN, p.config.UsernameAttribute)
+	}
+	login, err := auth.NormalizeUsername(rawUsername)
+	if err != nil {
+		return nil, fmt.Sprintf("Error normalizing the username %q. See https://docs.sourcegraph.com/admin/auth/#username-normalization.", rawUsername), err
+	}
+	email := entryAttr(user, p.config.EmailAttribute)
+	displayName := entryAttr(user, p.config.DisplayNameAttribute)
+	if displayName == "" {
+		displayName = login
+	}
+
+	var data extsvc.ExternalAccountData
+	data.SetAccountData(struct {
+		DN         string              `json:"dn"`
+		Attributes map[string][]string `json:"attributes"`
+	}{DN: user.DN, Attributes: entryAttrs(user)})
+
+	userID, safeErrMsg, err := auth.GetAndSaveUser(ctx, auth.GetAndSaveUserOp{
+		UserProps: db.NewUser{
+			Username:        login,
+			Email:           email,
+			EmailIsVerified: email != "", // LDAP emails are assumed to be verified
+			DisplayName:     displayName,
+		},
+		ExternalAccount: extsvc.ExternalAccountSpec{
+			ServiceType: providerType,
+			ServiceID:   p.config.Url,
+			AccountID:   rawUsername,
+		},
+		ExternalAccountData: data,
+		CreateIfNotExist:    true,
+	})
+	if err != nil {
+		return nil, safeErrMsg, err
+	}
+	return actor.FromUser(userID), "", nil
+}
+
+// entryAttr returns the first value of the entry's attribute, or "" if the entry has no such
+// attribute. Attribute names are compared case-insensitively (as LDAP servers do).
+func entryAttr(e *ldap.Entry, name string) string {
+	for _, a := range e.Attributes {
+		if strings.EqualFold(a.Name, name) && len(a.Values) > 0 {
+			return a.Values[0]
+		}
+	}
+	return ""
+}
+
+// entryAttrs returns the entry's attributes, keyed by lowercased attribute name.
+func entryAttrs(e *ldap.Entry) map[string][]string {
+	attrs := make(map[string][]string, len(e.Attributes))
+	for _, a := range e.Attributes {
+		name := strings.ToLower(a.Name)
+		attrs[name] = append(attrs[name], a.Values...)
+	}
+	return attrs
+}
+
+// orgMemberships returns, for each organization that groupOrgs maps a group to, whether a member
+// of the given groups should be a member of the organization.
+func orgMemberships(groupOrgs []*schema.LDAPGroupOrg, groups []string) map[string]bool {
+	member := make(map[string]bool, len(groupOrgs))
+	for _, m := range groupOrgs {
+		isMember := false
+		for _, g := range groups {
+			// DNs are case-insensitive in practice (the attribute types are, and most servers
+			// compare values case-insensitively).
+			if strings.EqualFold(strings.TrimSpace(m.Group), g) {
+				isMember = true
+				break
+			}
+		}
+		member[m.Org] = member[m.Org] || isMember
+	}
+	return member
+}
+
+// mockSyncOrgMembers mocks syncOrgMembers. It should only be set in tests.
+var mockSyncOrgMembers func(userID int32, member map[string]bool) error
+
+// syncOrgMembers adds the user to or removes the user from each of the organizations in member.
+func syncOrgMembers(ctx context.Context, userID int32, member map[string]bool) error {
+	if mockSyncOrgMembers != nil {
+		return mockSyncOrgMembers(userID, member)
+	}
+
+	names := make([]string, 0, len(member))
+	for name := range member {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		org, err := db.Orgs.GetByName(ctx, name)
+		if _, ok := err.(*db.OrgNotFoundError); ok {
+			log15.Warn("LDAP group is mapped to an organization that does not exist.", "org", name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = db.OrgMembers.GetByOrgIDAndUserID(ctx, org.ID, userID)
+		switch {
+		case err == nil && !member[name]:
+			err = db.OrgMembers.Remove(ctx, org.ID, userID)
+		case errcode.IsNotFound(err) && member[name]:
+			_, err = db.OrgMembers.Create(ctx, org.ID, userID)
+		case errcode.IsNotFound(err):
+			err = nil
+		}
+		if err != nil {
+			return errors.Wrapf(err, "updating membership of organization %q", name)
+		}
+	}
+	return nil
+}
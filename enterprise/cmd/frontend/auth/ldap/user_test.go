@@ -0,0 +1,39 @@
+package ldap
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+func TestOrgMemberships(t *testing.T) {
+	groupOrgs := []*schema.LDAPGroupOrg{
+		{Group: "cn=eng,ou=groups,dc=example,dc=com", Org: "eng"},
+		{Group: "cn=eng-contractors,ou=groups,dc=example,dc=com", Org: "eng"},
+		{Group: "cn=admins,ou=groups,dc=example,dc=com", Org: "admins"},
+	}
+	tests := map[string]struct {
+		groups []string
+		want   map[string]bool
+	}{
+		"no groups": {
+			want: map[string]bool{"eng": false, "admins": false},
+		},
+		"one of the groups mapped to an org": {
+			groups: []string{"cn=eng-contractors,ou=groups,dc=example,dc=com", "cn=other,ou=groups,dc=example,dc=com"},
+			want:   map[string]bool{"eng": true, "admins": false},
+		},
+		"case-insensitive": {
+			groups: []string{"CN=Admins,OU=Groups,DC=example,DC=com"},
+			want:   map[string]bool{"eng": false, "admins": true},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			if got := orgMemberships(groupOrgs, test.groups); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package authz
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	permbbs "github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/schema"
+	"golang.org/x/time/rate"
+)
+
+func bitbucketServerProviders(ctx context.Context) (
+	authzProviders []authz.Provider,
+	seriousProblems []string,
+	warnings []string,
+) {
+	bbss, err := db.ExternalServices.ListBitbucketServerConnections(ctx)
+	if err != nil {
+		seriousProblems = append(seriousProblems, fmt.Sprintf("Could not load Bitbucket Server external service configs: %s", err))
+		return
+	}
+
+	for _, b := range bbss {
+		p, err := bitbucketServerProvider(b)
+		if err != nil {
+			seriousProblems = append(seriousProblems, err.Error())
+			continue
+		}
+		if p != nil {
+			authzProviders = append(authzProviders, p)
+			seriousProblems = append(seriousProblems, p.Validate()...)
+		}
+	}
+	return authzProviders, seriousProblems, warnings
+}
+
+func bitbucketServerProvider(b *schema.BitbucketServerConnection) (authz.Provider, error) {
+	if b.Authorization == nil {
+		return nil, nil
+	}
+
+	bbsURL, err := url.Parse(b.Url)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse URL for Bitbucket Server instance %q: %s", b.Url, err)
+	}
+
+	ttl, err := parseTTL(b.Authorization.Ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	signingKey, err := bitbucketserver.ParseSigningKey(b.Authorization.Oauth.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid OAuth signing key for Bitbucket Server instance %q: %s", b.Url, err)
+	}
+
+	// Permissions are not cached by the HTTP transport: they are cached by the provider for the
+	// configured TTL instead.
+	var transport http.RoundTripper = http.DefaultTransport
+	if b.Certificate != "" {
+		certPool := x509.NewCertPool()
+		if ok := certPool.AppendCertsFromPEM([]byte(b.Certificate)); !ok {
+			return nil, fmt.Errorf("Invalid certificate for Bitbucket Server instance %q", b.Url)
+		}
+		transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: certPool}}
+	}
+
+	return permbbs.NewProvider(&bitbucketserver.Client{
+		URL:      extsvc.NormalizeBaseURL(bbsURL),
+		Token:    b.Token,
+		Username: b.Username,
+		Password: b.Password,
+		HTTPClient: &http.Client{
+			Transport: bitbucketserver.WithRequestCounter(&nethttp.Transport{RoundTripper: transport}),
+		},
+		RateLimit: rate.NewLimiter(2, 500),
+		Oauth: &bitbucketserver.OAuthConsumer{
+			ConsumerKey: b.Authorization.Oauth.ConsumerKey,
+			SigningKey:  signingKey,
+		},
+	}, ttl, nil), nil
+}
@@ -0,0 +1,28 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/explicit"
+)
+
+// explicitProviders returns the explicit permissions authz provider if any repository has explicit
+// permissions.
+func explicitProviders(ctx context.Context) (
+	authzProviders []authz.Provider,
+	seriousProblems []string,
+	warnings []string,
+) {
+	exists, err := db.RepoPermissions.Exists(ctx)
+	if err != nil {
+		seriousProblems = append(seriousProblems, fmt.Sprintf("Could not check for explicit repository permissions: %s", err))
+		return
+	}
+	if exists {
+		authzProviders = append(authzProviders, explicit.NewProvider())
+	}
+	return authzProviders, seriousProblems, warnings
+}
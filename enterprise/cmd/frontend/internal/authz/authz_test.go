@@ -11,6 +11,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
 	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/explicit"
 	"github.com/sourcegraph/sourcegraph/enterprise/cmd/frontend/internal/authz/gitlab"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/conf"
@@ -46,6 +47,7 @@ func Test_providersFromConfig(t *testing.T) {
 	}
 
 	db.Mocks = db.MockStores{}
+	db.Mocks.RepoPermissions.Exists = func() (bool, error) { return false, nil }
 	defer func() { db.Mocks = db.MockStores{} }()
 
 	tests := []struct {
@@ -283,6 +285,23 @@ func Test_providersFromConfig(t *testing.T) {
 	}
 }
 
+func Test_providersFromConfig_explicit(t *testing.T) {
+	db.Mocks = db.MockStores{}
+	db.Mocks.ExternalServices.List = func(db.ExternalServicesListOptions) ([]*types.ExternalService, error) { return nil, nil }
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	for _, exists := range []bool{false, true} {
+		db.Mocks.RepoPermissions.Exists = func() (bool, error) { return exists, nil }
+		_, authzProviders, seriousProblems, _ := providersFromConfig(context.Background(), &conf.Unified{})
+		if len(seriousProblems) != 0 {
+			t.Errorf("unexpected problems %v", seriousProblems)
+		}
+		if got := len(authzProviders) == 1 && authzProviders[0].ServiceType() == explicit.ServiceType; got != exists {
+			t.Errorf("with explicit permissions %v: got providers %+v", exists, authzProviders)
+		}
+	}
+}
+
 func mustURLParse(t *testing.T, u string) *url.URL {
 	parsed, err := url.Parse(u)
 	if err != nil {
@@ -0,0 +1,217 @@
+// Package bitbucketserver contains an authorization provider for Bitbucket Server that impersonates
+// users through an OAuth application link to list the repositories they can read.
+package bitbucketserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/bitbucketserver"
+	"github.com/sourcegraph/sourcegraph/pkg/rcache"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+// Provider implements authz.Provider for Bitbucket Server repository permissions.
+type Provider struct {
+	client   *bitbucketserver.Client
+	codeHost *bitbucketserver.CodeHost
+	cache    cache
+	cacheTTL time.Duration
+}
+
+var _ authz.Provider = ((*Provider)(nil))
+
+// NewProvider returns a new Bitbucket Server authorization provider that uses the given client to
+// talk to Bitbucket Server. The client must have an OAuth consumer, which is used to impersonate
+// users. If mockCache is non-nil, it replaces the default Redis-based cache; it should only be used
+// in tests.
+func NewProvider(client *bitbucketserver.Client, cacheTTL time.Duration, mockCache cache) *Provider {
+	p := &Provider{
+		client:   client,
+		codeHost: bitbucketserver.NewCodeHost(client.URL),
+		cache:    mockCache,
+		cacheTTL: cacheTTL,
+	}
+	if p.cache == nil {
+		p.cache = rcache.NewWithTTL(fmt.Sprintf("bitbucketServerAuthz:%s", client.URL.String()), int(math.Ceil(cacheTTL.Seconds())))
+	}
+	return p
+}
+
+func (p *Provider) Validate() (problems []string) {
+	if p.client.Oauth == nil {
+		problems = append(problems, "Bitbucket Server authorization requires an OAuth consumer key and signing key.")
+	}
+	return problems
+}
+
+func (p *Provider) ServiceID() string {
+	return p.codeHost.ServiceID()
+}
+
+func (p *Provider) ServiceType() string {
+	return p.codeHost.ServiceType()
+}
+
+// Repos implements the authz.Provider interface.
+func (p *Provider) Repos(ctx context.Context, repos map[authz.Repo]struct{}) (mine map[authz.Repo]struct{}, others map[authz.Repo]struct{}) {
+	return authz.GetCodeHostRepos(p.codeHost, repos)
+}
+
+// RepoPerms implements the authz.Provider interface.
+//
+// A user can read a repository if it is public or if Bitbucket Server lists it among the
+// repositories the user has the REPO_READ permission on. Both lists are cached for the cache TTL.
+// Users without an account on Bitbucket Server can only read public repositories.
+func (p *Provider) RepoPerms(ctx context.Context, account *extsvc.ExternalAccount, repos map[authz.Repo]struct{}) (map[api.RepoName]map[authz.Perm]bool, error) {
+	myRepos, _ := p.Repos(ctx, repos)
+	if len(myRepos) == 0 {
+		return nil, nil
+	}
+
+	publicRepos, err := p.readableRepos(ctx, publicReposCacheKey, p.client, url.Values{"visibility": {"public"}})
+	if err != nil {
+		return nil, err
+	}
+
+	var userRepos map[string]struct{}
+	if account != nil && account.ServiceID == p.codeHost.ServiceID() && account.ServiceType == p.codeHost.ServiceType() {
+		user, err := bitbucketserver.GetExternalAccountData(&account.ExternalAccountData)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil {
+			return nil, fmt.Errorf("no Bitbucket Server user in external account %d", account.ID)
+		}
+		client, err := p.client.Sudo(user.Name)
+		if err != nil {
+			return nil, err
+		}
+		userRepos, err = p.readableRepos(ctx, account.AccountID, client, url.Values{"permission": {"REPO_READ"}})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	perms := make(map[api.RepoName]map[authz.Perm]bool, len(myRepos))
+	for repo := range myRepos {
+		perms[repo.RepoName] = map[authz.Perm]bool{}
+		_, isPublic := publicRepos[repo.ExternalRepoSpec.ID]
+		_, isReadable := userRepos[repo.ExternalRepoSpec.ID]
+		if isPublic || isReadable {
+			perms[repo.RepoName][authz.Read] = true
+		}
+	}
+	return perms, nil
+}
+
+// FetchAccount implements the authz.Provider interface. It returns the Bitbucket Server user
+// whose username is the Sourcegraph user's username.
+func (p *Provider) FetchAccount(ctx context.Context, user *types.User, current []*extsvc.ExternalAccount) (mine *extsvc.ExternalAccount, err error) {
+	if user == nil {
+		return nil, nil
+	}
+
+	var page *bitbucketserver.PageToken
+	for page.HasMore() {
+		var users []*bitbucketserver.User
+		users, page, err = p.client.Users(ctx, user.Username, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			if !strings.EqualFold(u.Name, user.Username) {
+				continue
+			}
+			var data extsvc.ExternalAccountData
+			bitbucketserver.SetExternalAccountData(&data, u)
+			return &extsvc.ExternalAccount{
+				UserID: user.ID,
+				ExternalAccountSpec: extsvc.ExternalAccountSpec{
+					ServiceType: p.codeHost.ServiceType(),
+					ServiceID:   p.codeHost.ServiceID(),
+					AccountID:   strconv.Itoa(u.ID),
+				},
+				ExternalAccountData: data,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+// readableRepos returns the IDs of the repositories that match the filters when listed with
+// client. The result is cached under key.
+func (p *Provider) readableRepos(ctx context.Context, key string, client *bitbucketserver.Client, filters url.Values) (map[string]struct{}, error) {
+	if repoIDs, exists := p.getCachedRepos(key); exists {
+		return repoIDs, nil
+	}
+
+	repoIDs, err := p.fetchRepos(ctx, client, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(cacheVal{
+		RepoIDs: repoIDs,
+		TTL:     p.cacheTTL,
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.cache.Set(key, b)
+	return repoIDs, nil
+}
+
+// getCachedRepos returns the set of repository IDs cached under key and whether the cache entry
+// exists.
+func (p *Provider) getCachedRepos(key string) (map[string]struct{}, bool) {
+	b, exists := p.cache.Get(key)
+	if !exists {
+		return nil, false
+	}
+	var v cacheVal
+	if err := json.Unmarshal(b, &v); err != nil || v.TTL == 0 || v.TTL > p.cacheTTL {
+		if err != nil {
+			log15.Warn("Failed to unmarshal Bitbucket Server repo perm cache entry", "err", err.Error())
+		}
+		p.cache.Delete(key)
+		return nil, false
+	}
+	return v.RepoIDs, true
+}
+
+// fetchRepos fetches the IDs of all repositories that match the filters from the Bitbucket Server
+// API.
+func (p *Provider) fetchRepos(ctx context.Context, client *bitbucketserver.Client, filters url.Values) (map[string]struct{}, error) {
+	repoIDs := make(map[string]struct{})
+	page := &bitbucketserver.PageToken{Limit: 1000}
+	for iters := 0; page.HasMore(); iters++ {
+		if iters >= 100 && iters%100 == 0 {
+			log15.Warn("Excessively many Bitbucket Server API requests to fetch complete authz list", "iters", iters, "filters", filters.Encode(), "host", p.client.URL.String())
+		}
+
+		var repos []*bitbucketserver.Repo
+		var err error
+		repos, page, err = client.ReposWithFilter(ctx, filters, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range repos {
+			if r.Project == nil {
+				continue
+			}
+			repoIDs[r.Project.Key+"/"+r.Slug] = struct{}{}
+		}
+	}
+	return repoIDs, nil
+}
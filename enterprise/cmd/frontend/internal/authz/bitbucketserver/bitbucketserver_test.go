@@ -0,0 +1,261 @@
+package bitbucketserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc/bitbucketserver"
+	"golang.org/x/time/rate"
+)
+
+// fakeBitbucketServer serves the parts of the Bitbucket Server REST API that Provider uses.
+type fakeBitbucketServer struct {
+	users       []*bitbucketserver.User
+	publicRepos []string            // repository IDs
+	acls        map[string][]string // username -> repository IDs
+	requests    int
+}
+
+func (f *fakeBitbucketServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests++
+
+	q := r.URL.Query()
+	sudo := q.Get("xoauth_requestor_id")
+	if sudo != "" && !strings.HasPrefix(r.Header.Get("Authorization"), "OAuth ") {
+		http.Error(w, "impersonation requires OAuth", http.StatusUnauthorized)
+		return
+	}
+
+	// Serve one item per page to exercise pagination.
+	start, _ := strconv.Atoi(q.Get("start"))
+	var values []interface{}
+	switch {
+	case r.URL.Path == "/rest/api/1.0/users":
+		for _, u := range f.users {
+			if strings.Contains(u.Name, q.Get("filter")) {
+				values = append(values, u)
+			}
+		}
+	case r.URL.Path == "/rest/api/1.0/repos" && q.Get("visibility") == "public":
+		values = repos(f.publicRepos)
+	case r.URL.Path == "/rest/api/1.0/repos" && q.Get("permission") == "REPO_READ" && sudo != "":
+		values = repos(f.acls[sudo])
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	resp := map[string]interface{}{"start": start, "isLastPage": start+1 >= len(values)}
+	if start < len(values) {
+		resp["values"] = values[start : start+1]
+		resp["nextPageStart"] = start + 1
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func repos(ids []string) (values []interface{}) {
+	for _, id := range ids {
+		parts := strings.SplitN(id, "/", 2)
+		values = append(values, &bitbucketserver.Repo{Slug: parts[1], Project: &bitbucketserver.Project{Key: parts[0]}})
+	}
+	return values
+}
+
+func newTestProvider(t *testing.T, f *fakeBitbucketServer, cacheTTL time.Duration, cache cache) (*Provider, func()) {
+	srv := httptest.NewServer(f)
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &bitbucketserver.Client{
+		URL:        u,
+		Token:      "admin-token",
+		HTTPClient: http.DefaultClient,
+		RateLimit:  rate.NewLimiter(rate.Inf, 0),
+		Oauth:      &bitbucketserver.OAuthConsumer{ConsumerKey: "sourcegraph", SigningKey: key},
+	}
+	return NewProvider(client, cacheTTL, cache), srv.Close
+}
+
+func Test_BitbucketServer_FetchAccount(t *testing.T) {
+	f := &fakeBitbucketServer{
+		users: []*bitbucketserver.User{
+			{ID: 1, Name: "alice2"},
+			{ID: 2, Name: "alice"},
+		},
+	}
+	p, done := newTestProvider(t, f, time.Hour, make(mockCache))
+	defer done()
+
+	acct, err := p.FetchAccount(context.Background(), &types.User{ID: 42, Username: "alice"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acct == nil {
+		t.Fatal("expected account for alice")
+	}
+	if acct.UserID != 42 || acct.ServiceType != bitbucketserver.ServiceType || acct.ServiceID != p.ServiceID() || acct.AccountID != "2" {
+		t.Errorf("unexpected account %+v", acct.ExternalAccountSpec)
+	}
+	if u, err := bitbucketserver.GetExternalAccountData(&acct.ExternalAccountData); err != nil || u == nil || u.Name != "alice" {
+		t.Errorf("got account data %+v, %v, want alice", u, err)
+	}
+
+	acct, err = p.FetchAccount(context.Background(), &types.User{ID: 43, Username: "bob"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acct != nil {
+		t.Errorf("expected no account for bob, got %+v", acct)
+	}
+}
+
+func Test_BitbucketServer_RepoPerms(t *testing.T) {
+	f := &fakeBitbucketServer{
+		users:       []*bitbucketserver.User{{ID: 1, Name: "alice"}, {ID: 2, Name: "bob"}},
+		publicRepos: []string{"PUB/public"},
+		acls: map[string][]string{
+			"alice": {"PUB/public", "PRIV/alice"},
+			"bob":   {"PRIV/bob"},
+		},
+	}
+	cache := make(mockCache)
+	p, done := newTestProvider(t, f, time.Hour, cache)
+	defer done()
+
+	repos := map[authz.Repo]struct{}{
+		repo("public", p.ServiceID(), "PUB/public"):               {},
+		repo("alice", p.ServiceID(), "PRIV/alice"):                {},
+		repo("bob", p.ServiceID(), "PRIV/bob"):                    {},
+		repo("other", "https://other.example.com/", "PRIV/alice"): {},
+	}
+	account := func(username string) *extsvc.ExternalAccount {
+		acct, err := p.FetchAccount(context.Background(), &types.User{Username: username}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return acct
+	}
+
+	for _, test := range []struct {
+		description string
+		account     *extsvc.ExternalAccount
+		want        map[api.RepoName]map[authz.Perm]bool
+	}{
+		{
+			description: "anonymous",
+			want: map[api.RepoName]map[authz.Perm]bool{
+				"public": {authz.Read: true},
+				"alice":  {},
+				"bob":    {},
+			},
+		},
+		{
+			description: "alice",
+			account:     account("alice"),
+			want: map[api.RepoName]map[authz.Perm]bool{
+				"public": {authz.Read: true},
+				"alice":  {authz.Read: true},
+				"bob":    {},
+			},
+		},
+		{
+			description: "bob",
+			account:     account("bob"),
+			want: map[api.RepoName]map[authz.Perm]bool{
+				"public": {authz.Read: true},
+				"alice":  {},
+				"bob":    {authz.Read: true},
+			},
+		},
+	} {
+		perms, err := p.RepoPerms(context.Background(), test.account, repos)
+		if err != nil {
+			t.Fatalf("%s: %s", test.description, err)
+		}
+		if !reflect.DeepEqual(perms, test.want) {
+			t.Errorf("%s: got perms %v, want %v", test.description, perms, test.want)
+		}
+	}
+
+	if want := mockCache(map[string]string{
+		"public": `{"repos":{"PUB/public":{}},"ttl":3600000000000}`,
+		"1":      `{"repos":{"PRIV/alice":{},"PUB/public":{}},"ttl":3600000000000}`,
+		"2":      `{"repos":{"PRIV/bob":{}},"ttl":3600000000000}`,
+	}); !reflect.DeepEqual(cache, want) {
+		t.Errorf("got cache %v, want %v", cache, want)
+	}
+
+	// Cached permissions are served without requests to Bitbucket Server.
+	f.requests = 0
+	if _, err := p.RepoPerms(context.Background(), account("alice"), repos); err != nil {
+		t.Fatal(err)
+	}
+	if f.requests != 1 { // only the request of FetchAccount
+		t.Errorf("got %d requests with cached permissions, want 1", f.requests)
+	}
+
+	// Cache entries with a larger TTL than the provider's are refetched.
+	p.cacheTTL = time.Minute
+	f.publicRepos = nil
+	perms, err := p.RepoPerms(context.Background(), nil, repos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perms["public"][authz.Read] {
+		t.Error("expected stale cache entry to be refetched")
+	}
+}
+
+func Test_BitbucketServer_Validate(t *testing.T) {
+	p, done := newTestProvider(t, &fakeBitbucketServer{}, time.Hour, make(mockCache))
+	defer done()
+	if problems := p.Validate(); len(problems) != 0 {
+		t.Errorf("unexpected problems %v", problems)
+	}
+	p.client.Oauth = nil
+	if problems := p.Validate(); len(problems) != 1 {
+		t.Errorf("got problems %v, want one", problems)
+	}
+}
+
+type mockCache map[string]string
+
+func (m mockCache) Get(key string) ([]byte, bool) {
+	v, ok := m[key]
+	return []byte(v), ok
+}
+func (m mockCache) Set(key string, b []byte) {
+	m[key] = string(b)
+}
+func (m mockCache) Delete(key string) {
+	delete(m, key)
+}
+
+func repo(name, serviceID, id string) authz.Repo {
+	return authz.Repo{
+		RepoName: api.RepoName(name),
+		ExternalRepoSpec: api.ExternalRepoSpec{
+			ID:          id,
+			ServiceType: bitbucketserver.ServiceType,
+			ServiceID:   serviceID,
+		},
+	}
+}
@@ -0,0 +1,23 @@
+package bitbucketserver
+
+import "time"
+
+type cache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, b []byte)
+	Delete(key string)
+}
+
+// publicReposCacheKey is the cache key of the set of public repositories. It can't collide with
+// the cache keys of users, which are their numeric Bitbucket Server user IDs.
+const publicReposCacheKey = "public"
+
+type cacheVal struct {
+	// RepoIDs is the set of repository IDs ("PROJECT_KEY/repo-slug") to which a Bitbucket Server
+	// user has read access.
+	RepoIDs map[string]struct{} `json:"repos"`
+
+	// TTL is the ttl of the cache entry. This must be checked for equality in case the TTL has
+	// changed (and the cache entry should therefore be invalidated).
+	TTL time.Duration `json:"ttl"`
+}
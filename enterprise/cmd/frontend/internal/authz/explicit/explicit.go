@@ -0,0 +1,126 @@
+// Package explicit contains an authorization provider for the repository permissions that site
+// admins set explicitly, for repositories whose code host is not a source of permissions (such as
+// Gitolite repositories).
+package explicit
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/extsvc"
+	log15 "gopkg.in/inconshreveable/log15.v2"
+)
+
+const (
+	// ServiceType is the service type of the provider and of the external accounts it returns.
+	ServiceType = "sourcegraph"
+
+	// ServiceID is the service ID of the provider and of the external accounts it returns.
+	ServiceID = "explicit"
+)
+
+// Provider implements authz.Provider for explicit repository permissions, which are stored in the
+// database (see db.RepoPermissions).
+//
+// It is the source of permissions for all repositories that have explicit permissions, so it must
+// come after the code host authz providers, which take precedence.
+type Provider struct{}
+
+var _ authz.TransientAccountProvider = ((*Provider)(nil))
+
+// NewProvider returns a new explicit permissions authorization provider.
+func NewProvider() *Provider {
+	return &Provider{}
+}
+
+func (p *Provider) Validate() (problems []string) {
+	return nil
+}
+
+func (p *Provider) ServiceID() string {
+	return ServiceID
+}
+
+func (p *Provider) ServiceType() string {
+	return ServiceType
+}
+
+// Repos implements the authz.Provider interface. It claims the repositories that have explicit
+// permissions.
+func (p *Provider) Repos(ctx context.Context, repos map[authz.Repo]struct{}) (mine map[authz.Repo]struct{}, others map[authz.Repo]struct{}) {
+	names := make([]api.RepoName, 0, len(repos))
+	for repo := range repos {
+		names = append(names, repo.RepoName)
+	}
+	explicit, err := db.RepoPermissions.ListRepoNames(ctx, names)
+	if err != nil {
+		// 🚨 SECURITY: Claim all repositories, so that RepoPerms decides (and likely fails) instead
+		// of granting access to repositories that may have explicit permissions.
+		log15.Error("Failed to list repositories with explicit permissions", "error", err)
+		return repos, map[authz.Repo]struct{}{}
+	}
+
+	mine, others = make(map[authz.Repo]struct{}), make(map[authz.Repo]struct{})
+	for repo := range repos {
+		if _, ok := explicit[repo.RepoName]; ok {
+			mine[repo] = struct{}{}
+		} else {
+			others[repo] = struct{}{}
+		}
+	}
+	return mine, others
+}
+
+// RepoPerms implements the authz.Provider interface. A user can read a repository if they were
+// granted the permission, directly or through an organization they are a member of. Users without
+// an account (anonymous users) can't read any repository with explicit permissions.
+func (p *Provider) RepoPerms(ctx context.Context, account *extsvc.ExternalAccount, repos map[authz.Repo]struct{}) (map[api.RepoName]map[authz.Perm]bool, error) {
+	if len(repos) == 0 {
+		return nil, nil
+	}
+
+	perms := make(map[api.RepoName]map[authz.Perm]bool, len(repos))
+	names := make([]api.RepoName, 0, len(repos))
+	for repo := range repos {
+		perms[repo.RepoName] = map[authz.Perm]bool{}
+		names = append(names, repo.RepoName)
+	}
+	if account == nil || account.ServiceType != ServiceType || account.ServiceID != ServiceID {
+		return perms, nil
+	}
+
+	readable, err := db.RepoPermissions.ListUserRepoNames(ctx, account.UserID, authz.Read, names)
+	if err != nil {
+		return nil, err
+	}
+	for name := range readable {
+		if _, ok := perms[name]; ok {
+			perms[name][authz.Read] = true
+		}
+	}
+	return perms, nil
+}
+
+// TransientAccounts implements the authz.TransientAccountProvider interface. The accounts returned
+// by FetchAccount are not stored, because they only identify the Sourcegraph user.
+func (p *Provider) TransientAccounts() {}
+
+// FetchAccount implements the authz.Provider interface. Explicit permissions are granted to
+// Sourcegraph users, so the returned account identifies the user by their Sourcegraph user ID.
+func (p *Provider) FetchAccount(ctx context.Context, user *types.User, current []*extsvc.ExternalAccount) (mine *extsvc.ExternalAccount, err error) {
+	if user == nil {
+		return nil, nil
+	}
+	return &extsvc.ExternalAccount{
+		UserID: user.ID,
+		ExternalAccountSpec: extsvc.ExternalAccountSpec{
+			ServiceType: ServiceType,
+			ServiceID:   ServiceID,
+			AccountID:   strconv.Itoa(int(user.ID)),
+		},
+	}, nil
+}
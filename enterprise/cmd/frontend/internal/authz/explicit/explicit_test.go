@@ -0,0 +1,80 @@
+package explicit
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/authz"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/db"
+	"github.com/sourcegraph/sourcegraph/cmd/frontend/types"
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+)
+
+func Test_Explicit(t *testing.T) {
+	// r1 can be read by user 1 and r2 by nobody; r3 has no explicit permissions.
+	db.Mocks.RepoPermissions.ListRepoNames = func(names []api.RepoName) (map[api.RepoName]struct{}, error) {
+		return map[api.RepoName]struct{}{"r1": {}, "r2": {}}, nil
+	}
+	db.Mocks.RepoPermissions.ListUserRepoNames = func(userID int32, p authz.Perm, names []api.RepoName) (map[api.RepoName]struct{}, error) {
+		if userID == 1 && p == authz.Read {
+			return map[api.RepoName]struct{}{"r1": {}}, nil
+		}
+		return map[api.RepoName]struct{}{}, nil
+	}
+	defer func() { db.Mocks = db.MockStores{} }()
+
+	ctx := context.Background()
+	p := NewProvider()
+	repos := map[authz.Repo]struct{}{{RepoName: "r1"}: {}, {RepoName: "r2"}: {}, {RepoName: "r3"}: {}}
+
+	mine, others := p.Repos(ctx, repos)
+	if want := (map[authz.Repo]struct{}{{RepoName: "r1"}: {}, {RepoName: "r2"}: {}}); !reflect.DeepEqual(mine, want) {
+		t.Errorf("got mine %v, want %v", mine, want)
+	}
+	if want := (map[authz.Repo]struct{}{{RepoName: "r3"}: {}}); !reflect.DeepEqual(others, want) {
+		t.Errorf("got others %v, want %v", others, want)
+	}
+
+	for _, test := range []struct {
+		description string
+		user        *types.User
+		want        map[api.RepoName]map[authz.Perm]bool
+	}{
+		{
+			description: "anonymous",
+			want:        map[api.RepoName]map[authz.Perm]bool{"r1": {}, "r2": {}},
+		},
+		{
+			description: "allowed user",
+			user:        &types.User{ID: 1},
+			want:        map[api.RepoName]map[authz.Perm]bool{"r1": {authz.Read: true}, "r2": {}},
+		},
+		{
+			description: "other user",
+			user:        &types.User{ID: 2},
+			want:        map[api.RepoName]map[authz.Perm]bool{"r1": {}, "r2": {}},
+		},
+	} {
+		acct, err := p.FetchAccount(ctx, test.user, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		perms, err := p.RepoPerms(ctx, acct, mine)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(perms, test.want) {
+			t.Errorf("%s: got perms %v, want %v", test.description, perms, test.want)
+		}
+	}
+
+	// If the repositories with explicit permissions can't be listed, all repositories are claimed.
+	db.Mocks.RepoPermissions.ListRepoNames = func([]api.RepoName) (map[api.RepoName]struct{}, error) {
+		return nil, errors.New("x")
+	}
+	if mine, _ := p.Repos(ctx, repos); !reflect.DeepEqual(mine, repos) {
+		t.Errorf("got mine %v on error, want all repos", mine)
+	}
+}
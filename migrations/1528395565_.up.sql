@@ -0,0 +1,11 @@
+BEGIN;
+CREATE TABLE user_permissions (
+  user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  permission text NOT NULL,
+  service_type text NOT NULL,
+  service_id text NOT NULL,
+  object_ids integer[] NOT NULL,
+  updated_at timestamp with time zone NOT NULL,
+  PRIMARY KEY (user_id, permission, service_type, service_id)
+);
+END;
@@ -0,0 +1,10 @@
+BEGIN;
+CREATE TABLE repo_permissions (
+  repo_id integer NOT NULL REFERENCES repo(id) ON DELETE CASCADE,
+  permission text NOT NULL,
+  user_ids integer[] NOT NULL,
+  org_ids integer[] NOT NULL,
+  updated_at timestamp with time zone NOT NULL,
+  PRIMARY KEY (repo_id, permission)
+);
+END;
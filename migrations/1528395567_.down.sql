@@ -0,0 +1,12 @@
+BEGIN;
+UPDATE user_permissions SET object_ids = COALESCE((
+  SELECT array_agg(user_repo_permissions.repo_id)
+  FROM user_repo_permissions JOIN repo ON repo.id = user_repo_permissions.repo_id
+  WHERE user_repo_permissions.user_id = user_permissions.user_id
+    AND user_repo_permissions.permission = user_permissions.permission
+    AND repo.external_service_type = user_permissions.service_type
+    AND repo.external_service_id = user_permissions.service_id
+), '{}');
+ALTER TABLE user_permissions ALTER COLUMN object_ids DROP DEFAULT;
+DROP TABLE IF EXISTS user_repo_permissions;
+END;
@@ -0,0 +1,14 @@
+BEGIN;
+CREATE TABLE user_repo_permissions (
+  user_id integer NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  permission text NOT NULL,
+  repo_id integer NOT NULL REFERENCES repo(id) ON DELETE CASCADE,
+  PRIMARY KEY (user_id, permission, repo_id)
+);
+INSERT INTO user_repo_permissions (user_id, permission, repo_id)
+  SELECT DISTINCT user_permissions.user_id, user_permissions.permission, repo.id
+  FROM user_permissions JOIN repo ON repo.id = ANY(user_permissions.object_ids);
+-- object_ids is superseded by user_repo_permissions. It is kept for frontends that haven't been
+-- updated yet.
+ALTER TABLE user_permissions ALTER COLUMN object_ids SET DEFAULT '{}';
+END;
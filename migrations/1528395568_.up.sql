@@ -0,0 +1,5 @@
+BEGIN;
+-- Remove the accounts that were stored for the explicit repository permissions authz provider. They
+-- only identified the user itself and are no longer stored.
+DELETE FROM user_external_accounts WHERE service_type='sourcegraph' AND service_id='explicit';
+END;
@@ -0,0 +1,3 @@
+BEGIN;
+ALTER TABLE user_permissions DROP COLUMN max_repo_id;
+END;
@@ -0,0 +1,6 @@
+BEGIN;
+-- The largest repository ID at the time the permissions were synced. Permissions on repositories
+-- with larger IDs (which were added later) are synced incrementally. It is NULL for permissions
+-- synced before this column existed until they are next synced.
+ALTER TABLE user_permissions ADD COLUMN max_repo_id integer;
+END;
@@ -192,6 +192,16 @@
 // 1528395563_.up.sql (181B)
 // 1528395564_.down.sql (0)
 // 1528395564_.up.sql (0)
+// 1528395565_.down.sql (51B)
+// 1528395565_.up.sql (343B)
+// 1528395566_.down.sql (51B)
+// 1528395566_.up.sql (286B)
+// 1528395567_.down.sql (583B)
+// 1528395567_.up.sql (669B)
+// 1528395568_.down.sql (0)
+// 1528395568_.up.sql (269B)
+// 1528395569_.down.sql (66B)
+// 1528395569_.up.sql (333B)
 
 package migrations
 
@@ -4100,6 +4110,206 @@ func _1528395564_UpSql() (*asset, error) {
 	return a, nil
 }
 
+var __1528395565_DownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x33\x00\xcc\xff\x42\x45\x47\x49\x4e\x3b\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x49\x46\x20\x45\x58\x49\x53\x54\x53\x20\x75\x73\x65\x72\x5f\x70\x65\x72\x6d\x69\x73\x73\x69\x6f\x6e\x73\x3b\x0a\x45\x4e\x44\x3b\x0a\x01\x00\x00\xff\xff\xa4\xa1\xa2\xa1\x33\x00\x00\x00")
+
+func _1528395565_DownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395565_DownSql,
+		"1528395565_.down.sql",
+	)
+}
+
+func _1528395565_DownSql() (*asset, error) {
+	bytes, err := _1528395565_DownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395565_.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x8, 0xa3, 0xf0, 0x97, 0xe2, 0x72, 0x95, 0xcd, 0x5, 0x40, 0x9f, 0x85, 0x6e, 0xaa, 0x7a, 0x48, 0x40, 0x8c, 0x4b, 0x56, 0xfb, 0x1c, 0x91, 0x3e, 0x5e, 0xc2, 0x69, 0x52, 0xf7, 0xee, 0x67, 0x56}}
+	return a, nil
+}
+
+var __1528395565_UpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x74\x8f\xcb\x4a\xc4\x40\x10\x45\xf7\xfd\x15\x77\x99\x40\xfe\x20\xab\x9e\xa4\x94\xc1\xd8\x23\x3d\x71\x31\x88\x84\x38\x5d\x68\x09\x79\xd0\x5d\xe3\xeb\xeb\xc5\xf1\x31\x06\x71\x79\xeb\x1e\x2e\xa7\x56\x74\xbe\x76\xa5\xa9\x3c\xd9\x96\xd0\xda\x55\x43\x38\x24\x8e\xdd\xcc\x71\x90\x94\x64\x1a\x13\x32\x83\xcf\xa3\x04\xc8\xa8\x7c\xcf\x11\x6e\xd3\xc2\x5d\x37\x0d\x3c\x9d\x91\x27\x57\xd1\xf6\xc8\xa4\x4c\x42\x8e\x8d\x43\x4d\x0d\xb5\x84\xca\x6e\x2b\x5b\x53\x61\x80\xd3\x24\x94\x5f\xf4\x67\xe2\xa3\x4b\x1c\x9f\x64\xcf\x9d\xbe\xce\xfc\x7f\x2b\xe1\x6f\x37\xdd\x3d\xf2\x5e\x3b\x09\xe9\xdb\xed\xe6\x76\x01\x1c\xe6\xd0\x2b\x87\xae\x57\xa8\x0c\x9c\xb4\x1f\x66\x3c\x8b\x3e\x1c\x23\xde\xa6\x91\x17\xfc\x95\x5f\x5f\x5a\xbf\xc3\x05\xed\x90\x7d\xbd\x5d\xfc\x92\x2f\x16\xb2\xa7\x24\x21\x37\x79\x69\xc8\xd5\xa5\x79\x0f\x00\x00\xff\xff\x06\xb9\xcd\x1c\x57\x01\x00\x00")
+
+func _1528395565_UpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395565_UpSql,
+		"1528395565_.up.sql",
+	)
+}
+
+func _1528395565_UpSql() (*asset, error) {
+	bytes, err := _1528395565_UpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395565_.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x1f, 0x42, 0xaf, 0x30, 0x17, 0xb, 0x55, 0xd8, 0x76, 0x22, 0xbe, 0x95, 0x34, 0xb4, 0x98, 0x9b, 0x35, 0x16, 0xef, 0xfd, 0x89, 0xd8, 0x39, 0x33, 0x79, 0x88, 0x90, 0xaf, 0x4d, 0xef, 0x11, 0xd2}}
+	return a, nil
+}
+
+var __1528395566_DownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x33\x00\xcc\xff\x42\x45\x47\x49\x4e\x3b\x0a\x44\x52\x4f\x50\x20\x54\x41\x42\x4c\x45\x20\x49\x46\x20\x45\x58\x49\x53\x54\x53\x20\x72\x65\x70\x6f\x5f\x70\x65\x72\x6d\x69\x73\x73\x69\x6f\x6e\x73\x3b\x0a\x45\x4e\x44\x3b\x0a\x01\x00\x00\xff\xff\x8a\x11\x4d\x9c\x33\x00\x00\x00")
+
+func _1528395566_DownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395566_DownSql,
+		"1528395566_.down.sql",
+	)
+}
+
+func _1528395566_DownSql() (*asset, error) {
+	bytes, err := _1528395566_DownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395566_.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x91, 0xfa, 0xa2, 0x5e, 0x70, 0x11, 0x1b, 0x4d, 0xf2, 0x4a, 0x7a, 0x81, 0xa8, 0x18, 0x45, 0xf0, 0xe, 0x44, 0x63, 0x45, 0x8a, 0xbf, 0x74, 0x81, 0xf5, 0x96, 0x1c, 0x93, 0x96, 0x5d, 0x70, 0x88}}
+	return a, nil
+}
+
+var __1528395566_UpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x8f\xcf\x6a\xc3\x30\x0c\x87\xef\x7e\x8a\xdf\x31\x81\xbe\x41\x4e\x6e\xa2\x8d\xb2\xcc\x1d\x6e\x76\x28\x63\x84\x80\x45\xa7\x43\x62\x63\xab\x6c\xec\xe9\xc7\xba\xbf\xb9\xf4\x28\x7d\x1f\x1f\xd2\x96\x6e\x77\xae\x31\xad\x27\x3b\x10\x06\xbb\xed\x09\x99\x53\x1c\x13\xe7\x59\x4a\x91\xb8\x14\x54\x06\x5f\x4b\x09\x90\x45\xf9\xc4\x19\x6e\x3f\xc0\x3d\xf6\x3d\x3c\xdd\x90\x27\xd7\xd2\xe1\xe2\x54\x12\x6a\xec\x1d\x3a\xea\x69\x20\xb4\xf6\xd0\xda\x8e\x36\x06\xf8\x2b\x42\xf9\x4d\x7f\x0b\x9f\xec\x5c\x38\x8f\x12\xca\x4f\xfe\xe9\x79\x85\x63\x3e\x5d\xa1\xe7\x14\x26\xe5\x30\x4e\x0a\x95\x99\x8b\x4e\x73\xc2\xab\xe8\xcb\x65\xc4\x7b\x5c\x78\xe5\x3f\xf8\xdd\xbd\xf5\x47\xdc\xd1\x11\xd5\xf7\x5f\x9b\x7f\xe7\xd5\xa6\x6e\x0c\xb9\xae\x31\x1f\x01\x00\x00\xff\xff\x6d\xf8\x8f\x20\x1e\x01\x00\x00")
+
+func _1528395566_UpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395566_UpSql,
+		"1528395566_.up.sql",
+	)
+}
+
+func _1528395566_UpSql() (*asset, error) {
+	bytes, err := _1528395566_UpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395566_.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x8f, 0x6d, 0x16, 0xf5, 0x9, 0xf6, 0x6f, 0x4a, 0x51, 0xbe, 0x5f, 0xd3, 0x5c, 0x81, 0x20, 0xb5, 0xc, 0xef, 0xd8, 0x58, 0x88, 0x94, 0xec, 0xd7, 0x54, 0xac, 0x71, 0xa6, 0x96, 0xb3, 0x7c, 0x24}}
+	return a, nil
+}
+
+var __1528395567_DownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x84\xd1\x41\x4f\x83\x30\x14\x07\xf0\x7b\x3f\xc5\xff\xb6\x91\x18\xbe\x00\xd9\xa1\x83\x87\x62\x3a\xba\x40\x89\xde\x08\x8e\x66\xa9\xd1\x41\x5a\x34\x2e\xc6\xef\x6e\x06\x89\x70\xe8\xe6\xa9\xaf\xef\xff\xfa\x3b\xf4\x6d\xe9\x3e\xcb\x23\x56\xed\x13\xae\x08\x1f\x4e\xdb\xba\xd7\xf6\xdd\x38\x67\xba\x93\x43\x49\x0a\xdd\xcb\xab\x3e\x0c\xb5\x69\x1d\x36\x88\x25\x17\x54\xc6\xb4\x5e\x33\xa0\x24\x41\xb1\x42\x63\x6d\x73\xae\x9b\xe3\x71\x3d\xbe\xb7\xba\xef\x96\x48\x38\x36\x4c\x1b\x30\x20\x2d\xe4\x0e\xde\x29\x3c\xca\x2c\xc7\xa5\x0b\x39\x9d\xa1\x69\xb1\xc1\x4d\x92\x01\x4f\x0f\x54\xd0\x95\xa9\xb1\x3b\x2b\x9e\x88\x01\x00\xcf\x93\x2b\xc0\x5c\xfb\x8c\xb9\xfe\x63\x2e\x42\xa8\xbf\x06\x6d\x4f\xcd\x5b\xed\xb4\xfd\x34\x07\x5d\x0f\xe7\x5e\xfb\x80\x65\xfe\x0f\x61\xda\x5b\x80\x69\x59\x70\x87\xd5\xf7\xcf\x2a\x88\x18\x17\x8a\x0a\x28\xbe\x15\x9e\x85\x4e\x61\x2c\x45\xb5\xcb\x97\x9b\x4d\x0a\xb9\x47\x42\x29\xaf\x84\x8a\xd8\x78\x9b\x84\x2c\x05\x3d\x67\xa5\x2a\xfd\x5f\x14\x31\xca\x93\x88\xfd\x06\x00\x00\xff\xff\x60\xfb\x85\x58\x47\x02\x00\x00")
+
+func _1528395567_DownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395567_DownSql,
+		"1528395567_.down.sql",
+	)
+}
+
+func _1528395567_DownSql() (*asset, error) {
+	bytes, err := _1528395567_DownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395567_.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xcf, 0xe5, 0x81, 0x67, 0x1f, 0x9c, 0xf6, 0xdd, 0xae, 0x50, 0x30, 0x6f, 0x99, 0x55, 0x80, 0xce, 0x83, 0xbd, 0x7f, 0xac, 0xd6, 0x45, 0x4d, 0x76, 0x70, 0x1a, 0x5b, 0xd6, 0x79, 0x4e, 0xeb, 0xc7}}
+	return a, nil
+}
+
+var __1528395567_UpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x84\x92\xc1\x8e\xd3\x30\x18\x84\xef\x7e\x8a\xb9\x35\x91\xba\x79\x81\x88\x83\x37\xf9\x8b\x0c\xae\x83\x1c\xf7\xd0\x53\xd5\xe2\x7f\x59\x83\x48\xa2\xd8\x45\xac\x10\xef\x8e\xd2\x2c\xdb\x48\x65\xd9\x9b\xad\x19\x7d\x33\x23\xfd\xf7\xf4\x5e\x99\x52\x54\x96\xa4\x23\x38\x79\xaf\x09\xe7\xc8\xe3\x61\xe4\xa1\x3f\x0c\x3c\x7e\x0f\x31\x86\xbe\x8b\xc8\x04\x66\x25\x78\x84\x2e\xf1\x17\x1e\x61\x1a\x07\xb3\xd3\x1a\x96\x36\x64\xc9\x54\xd4\x5e\x3c\x31\x0b\x3e\x47\x63\x50\x93\x26\x47\xa8\x64\x5b\xc9\x9a\xd6\x02\xb8\x22\x91\xf8\x67\x7a\x41\x4c\xda\x25\xf3\x0d\xfc\xe4\x79\x9d\xfe\xc9\xaa\xad\xb4\x7b\x7c\xa4\x3d\xb2\xe7\xb6\xeb\x45\xe6\xfa\x6f\x46\x2e\xf2\x52\x28\xd3\x92\x75\x50\xc6\x35\xaf\x8d\xfe\x3f\x03\x68\x49\x53\xe5\x50\xab\xd6\x29\x53\xb9\x19\xb3\x20\x14\x2f\x80\x1b\xe5\xfa\x9e\x5b\x15\xc1\x0b\x60\x63\x9b\xed\x0d\x05\x1f\x1a\x65\x2e\xa6\x69\xf6\xb3\x19\xef\x20\xcd\x3e\xbb\xe1\xf6\xa7\xaf\xfc\x39\x1d\x82\x8f\x79\x29\xee\xee\x70\xfd\x23\x44\xc4\xf3\xc0\x63\x64\xcf\x1e\xa7\xa7\x7f\xaf\x2e\xa0\xd2\x64\xfd\xc6\x43\xc2\x43\x3f\xe2\x61\xec\xbb\xc4\x9d\x8f\x48\x8f\xc7\x84\xc7\xe3\x0f\xee\x56\x09\x27\xe6\x6e\x0a\x38\x0f\xfe\x98\xd8\xe3\x89\x53\x21\xa4\x76\x64\x97\x87\xb4\x00\x63\x16\xab\x46\xef\xb6\x66\xd9\xab\x25\x87\x9a\x36\x72\xa7\x1d\x56\xbf\x7e\xaf\x4a\x41\xa6\x2e\xc5\x9f\x00\x00\x00\xff\xff\x8b\xad\xbe\x6c\x9d\x02\x00\x00")
+
+func _1528395567_UpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395567_UpSql,
+		"1528395567_.up.sql",
+	)
+}
+
+func _1528395567_UpSql() (*asset, error) {
+	bytes, err := _1528395567_UpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395567_.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x62, 0xdb, 0x13, 0xad, 0xe2, 0x7f, 0x9d, 0xb9, 0x18, 0xee, 0xbe, 0x6f, 0x42, 0x5c, 0xe, 0xb3, 0xa5, 0x52, 0xd3, 0x43, 0xb9, 0x31, 0x36, 0x64, 0xae, 0x71, 0xef, 0xac, 0x24, 0xf5, 0x6c, 0x8e}}
+	return a, nil
+}
+
+var __1528395568_DownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x01\x00\x00\xff\xff\x00\x00\x00\x00\x00\x00\x00\x00")
+
+func _1528395568_DownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395568_DownSql,
+		"1528395568_.down.sql",
+	)
+}
+
+func _1528395568_DownSql() (*asset, error) {
+	bytes, err := _1528395568_DownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395568_.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xe3, 0xb0, 0xc4, 0x42, 0x98, 0xfc, 0x1c, 0x14, 0x9a, 0xfb, 0xf4, 0xc8, 0x99, 0x6f, 0xb9, 0x24, 0x27, 0xae, 0x41, 0xe4, 0x64, 0x9b, 0x93, 0x4c, 0xa4, 0x95, 0x99, 0x1b, 0x78, 0x52, 0xb8, 0x55}}
+	return a, nil
+}
+
+var __1528395568_UpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x3c\x8f\xcd\x6a\xc3\x30\x10\x84\xef\x7e\x8a\xbd\xf9\x94\xbc\x80\xc9\xa1\xc5\xea\x0f\xb4\x2e\x98\x40\x8f\x46\x58\xe3\x78\x41\xd1\x8a\xd5\xda\x8d\xfb\xf4\xa5\xa6\xcd\x79\x7e\xbe\x99\x47\xf7\xfc\xda\x35\xd5\xe1\x40\x3d\xae\xb2\x82\x6c\x06\xf9\x71\x94\x25\x59\x21\x9b\xbd\xd1\x17\x14\x54\x4c\x14\x81\x26\xd1\xdd\x81\x5b\x8e\x3c\xb2\x91\x22\x4b\x61\x13\xdd\x28\x43\xaf\x5c\x0a\x4b\x2a\xe4\x17\x9b\xbf\x29\xab\xac\x1c\xa0\x47\x3a\xcf\xd8\x7e\x21\x92\xe2\x46\x1c\x90\x8c\x27\x46\xd8\xbb\x96\x02\x25\xb6\x82\x38\x91\x4f\x81\xbc\x82\x92\x50\x94\x74\x81\xfe\x81\x8f\x55\xeb\xde\xdc\xd9\xd1\x53\xff\xf1\xbe\x27\x06\xdc\x0c\x9a\x7c\x1c\xee\x6b\x3f\x5f\x5c\xef\xa8\x40\x57\x1e\x31\xd8\x96\x71\xaa\x8b\x2c\x3a\xe2\xa2\x3e\xcf\x35\x3d\x74\xed\x5d\xe6\x70\xaa\xff\x5f\xd4\x4d\xe5\xba\xb6\xa9\x7e\x02\x00\x00\xff\xff\x89\x67\xc4\x5d\x0d\x01\x00\x00")
+
+func _1528395568_UpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395568_UpSql,
+		"1528395568_.up.sql",
+	)
+}
+
+func _1528395568_UpSql() (*asset, error) {
+	bytes, err := _1528395568_UpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395568_.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x87, 0x27, 0x78, 0x2e, 0xc0, 0x8d, 0xea, 0xd9, 0x97, 0xdb, 0xb5, 0x67, 0x67, 0xe5, 0xa7, 0x38, 0xe2, 0xb9, 0x48, 0x7f, 0x70, 0xaf, 0x64, 0xc2, 0x47, 0x66, 0xfa, 0xcc, 0xf, 0xf9, 0x4, 0xfc}}
+	return a, nil
+}
+
+var __1528395569_DownSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x00\x42\x00\xbd\xff\x42\x45\x47\x49\x4e\x3b\x0a\x41\x4c\x54\x45\x52\x20\x54\x41\x42\x4c\x45\x20\x75\x73\x65\x72\x5f\x70\x65\x72\x6d\x69\x73\x73\x69\x6f\x6e\x73\x20\x44\x52\x4f\x50\x20\x43\x4f\x4c\x55\x4d\x4e\x20\x6d\x61\x78\x5f\x72\x65\x70\x6f\x5f\x69\x64\x3b\x0a\x45\x4e\x44\x3b\x0a\x01\x00\x00\xff\xff\x0c\xf3\x1d\x58\x42\x00\x00\x00")
+
+func _1528395569_DownSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395569_DownSql,
+		"1528395569_.down.sql",
+	)
+}
+
+func _1528395569_DownSql() (*asset, error) {
+	bytes, err := _1528395569_DownSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395569_.down.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0x40, 0x28, 0x86, 0x30, 0xc3, 0xb, 0x86, 0xa6, 0x6b, 0xfb, 0x77, 0x8d, 0xe8, 0x5b, 0x70, 0xeb, 0x2, 0xee, 0x2a, 0xea, 0x13, 0xf2, 0x5b, 0xca, 0x94, 0x63, 0xa6, 0x69, 0x61, 0x42, 0xa8, 0x4a}}
+	return a, nil
+}
+
+var __1528395569_UpSql = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x4c\x8f\x41\x6b\x83\x40\x10\x85\xef\xfe\x8a\x77\x6c\x0f\xc9\x1f\xc8\x29\xa9\x52\x04\x6b\x4b\x31\x67\xd9\xba\x93\x38\xb0\xee\x86\x99\x11\xf5\xdf\x17\x93\xd0\x7a\x1b\x18\xde\xfb\xde\x77\x2a\xde\xcb\xfa\x90\xed\x76\x68\x7a\x42\x70\x72\x25\x35\x08\xdd\x92\xb2\x25\x59\x50\xe6\x70\x06\xeb\x09\xc6\x03\xdd\x8f\x1b\xc9\xc0\xaa\x9c\xa2\x62\x22\x21\xe8\x12\x3b\xf2\x7b\x7c\x6d\x1e\x29\xfe\xb7\x30\xe9\x4a\x98\xd8\xfa\x07\x42\x50\xe6\x8a\x97\xa9\xe7\xae\x7f\x54\x38\xef\xc9\x23\x38\x23\x79\x85\xfb\xeb\x04\xc7\x4e\x68\xa0\x68\x2e\x84\x65\x8f\xd2\xc0\x8a\xfa\x5c\x55\xb8\x24\xd9\x2e\x59\x01\xcf\xcc\x0f\x5d\x92\xac\x53\x59\xd1\xa5\x30\x0e\x11\x34\xb3\x1a\x79\x8c\xd1\x38\xac\x12\xcb\x1d\x12\x69\xb6\x67\x6a\x9f\x1d\xab\xa6\xf8\x46\x73\x3c\x55\x05\x46\x25\x69\xb7\x9e\xc7\x3c\xc7\xdb\x67\x75\xfe\xa8\x31\xb8\xb9\x5d\xd5\x5a\xf6\xe0\x68\x74\x25\x39\x64\x45\x9d\x1f\xb2\xdf\x00\x00\x00\xff\xff\x2d\x9b\x08\xa1\x4d\x01\x00\x00")
+
+func _1528395569_UpSqlBytes() ([]byte, error) {
+	return bindataRead(
+		__1528395569_UpSql,
+		"1528395569_.up.sql",
+	)
+}
+
+func _1528395569_UpSql() (*asset, error) {
+	bytes, err := _1528395569_UpSqlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "1528395569_.up.sql", size: 0, mode: os.FileMode(0), modTime: time.Unix(0, 0)}
+	a := &asset{bytes: bytes, info: info, digest: [32]uint8{0xf5, 0x5a, 0xfe, 0xff, 0x6, 0x25, 0xd2, 0xb1, 0x23, 0xdb, 0xe8, 0x8a, 0xdc, 0x2d, 0x41, 0x83, 0x5b, 0xa5, 0xb2, 0x48, 0x8a, 0x1a, 0x62, 0xb4, 0xca, 0x39, 0xe2, 0x9f, 0x1b, 0xf2, 0x9f, 0xe2}}
+	return a, nil
+}
+
 // Asset loads and returns the asset for the given name.
 // It returns an error if the asset could not be found or
 // could not be loaded.
@@ -4574,6 +4784,26 @@ var _bindata = map[string]func() (*asset, error){
 	"1528395564_.down.sql": _1528395564_DownSql,
 
 	"1528395564_.up.sql": _1528395564_UpSql,
+
+	"1528395565_.down.sql": _1528395565_DownSql,
+
+	"1528395565_.up.sql": _1528395565_UpSql,
+
+	"1528395566_.down.sql": _1528395566_DownSql,
+
+	"1528395566_.up.sql": _1528395566_UpSql,
+
+	"1528395567_.down.sql": _1528395567_DownSql,
+
+	"1528395567_.up.sql": _1528395567_UpSql,
+
+	"1528395568_.down.sql": _1528395568_DownSql,
+
+	"1528395568_.up.sql": _1528395568_UpSql,
+
+	"1528395569_.down.sql": _1528395569_DownSql,
+
+	"1528395569_.up.sql": _1528395569_UpSql,
 }
 
 // AssetDir returns the file names below a certain
@@ -4809,6 +5039,16 @@ var _bintree = &bintree{nil, map[string]*bintree{
 	"1528395563_.up.sql":                                          {_1528395563_UpSql, map[string]*bintree{}},
 	"1528395564_.down.sql":                                        {_1528395564_DownSql, map[string]*bintree{}},
 	"1528395564_.up.sql":                                          {_1528395564_UpSql, map[string]*bintree{}},
+	"1528395565_.down.sql":                                        {_1528395565_DownSql, map[string]*bintree{}},
+	"1528395565_.up.sql":                                          {_1528395565_UpSql, map[string]*bintree{}},
+	"1528395566_.down.sql":                                        {_1528395566_DownSql, map[string]*bintree{}},
+	"1528395566_.up.sql":                                          {_1528395566_UpSql, map[string]*bintree{}},
+	"1528395567_.down.sql":                                        {_1528395567_DownSql, map[string]*bintree{}},
+	"1528395567_.up.sql":                                          {_1528395567_UpSql, map[string]*bintree{}},
+	"1528395568_.down.sql":                                        {_1528395568_DownSql, map[string]*bintree{}},
+	"1528395568_.up.sql":                                          {_1528395568_UpSql, map[string]*bintree{}},
+	"1528395569_.down.sql":                                        {_1528395569_DownSql, map[string]*bintree{}},
+	"1528395569_.up.sql":                                          {_1528395569_UpSql, map[string]*bintree{}},
 }}
 
 // RestoreAsset restores an asset under the given directory.
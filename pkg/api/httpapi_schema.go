@@ -1,5 +1,7 @@
 package api
 
+import "time"
+
 // RepoCreateOrUpdateRequest is a request to create or update a repository.
 //
 // The request handler determines if the request refers to an existing repository (and should therefore update
@@ -66,3 +68,25 @@ type ExternalServiceConfigsRequest struct {
 type ExternalServicesListRequest struct {
 	Kind string `json:"kind"`
 }
+
+type UserPermissionsListStaleRequest struct {
+	// SyncedBefore is the time before which the permissions of a user must have been synced for
+	// them to be stale.
+	SyncedBefore time.Time `json:"syncedBefore"`
+	Limit        int       `json:"limit"`
+}
+
+type UserPermissionsListStaleResponse struct {
+	Users []StaleUserPermissions `json:"users"` // least recently synced first
+	Total int                    `json:"total"` // the total number of users with stale permissions
+}
+
+// StaleUserPermissions describes a user whose repository permissions are stale.
+type StaleUserPermissions struct {
+	UserID   int32      `json:"userID"`
+	SyncedAt *time.Time `json:"syncedAt"` // nil if the user's permissions were never synced
+}
+
+type UserPermissionsSyncRequest struct {
+	UserID int32 `json:"userID"`
+}
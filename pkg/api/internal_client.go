@@ -342,6 +342,27 @@ func (c *internalClient) ExternalServicesList(ctx context.Context, opts External
 	return extsvcs, c.postInternal(ctx, "external-services/list", &opts, &extsvcs)
 }
 
+// UserPermissionsListStale lists the users whose repository permissions were last synced before
+// req.SyncedBefore or were never synced.
+func (c *internalClient) UserPermissionsListStale(ctx context.Context, req UserPermissionsListStaleRequest) (*UserPermissionsListStaleResponse, error) {
+	var resp UserPermissionsListStaleResponse
+	if err := c.postInternal(ctx, "user-permissions/list-stale", &req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// UserPermissionsSync syncs the repository permissions of the user from the code hosts.
+func (c *internalClient) UserPermissionsSync(ctx context.Context, userID int32) error {
+	return c.postInternal(ctx, "user-permissions/sync", &UserPermissionsSyncRequest{UserID: userID}, nil)
+}
+
+// UserPermissionsSyncNewRepos syncs the repository permissions of all users on the repositories that
+// were added since their permissions were last synced.
+func (c *internalClient) UserPermissionsSyncNewRepos(ctx context.Context) error {
+	return c.postInternal(ctx, "user-permissions/sync-new-repos", nil, nil)
+}
+
 func (c *internalClient) LogTelemetry(ctx context.Context, env string, reqBody interface{}) error {
 	return c.postInternal(ctx, "telemetry/log/v1/"+env, reqBody, nil)
 }
@@ -114,6 +114,30 @@ func BitbucketServerConfigs(ctx context.Context) ([]*schema.BitbucketServerConne
 	return config, nil
 }
 
+func BitbucketCloudConfigs(ctx context.Context) ([]*schema.BitbucketCloudConnection, error) {
+	var config []*schema.BitbucketCloudConnection
+	if err := api.InternalClient.ExternalServiceConfigs(ctx, "BITBUCKETCLOUD", &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func GerritConfigs(ctx context.Context) ([]*schema.GerritConnection, error) {
+	var config []*schema.GerritConnection
+	if err := api.InternalClient.ExternalServiceConfigs(ctx, "GERRIT", &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func GiteaConfigs(ctx context.Context) ([]*schema.GiteaConnection, error) {
+	var config []*schema.GiteaConnection
+	if err := api.InternalClient.ExternalServiceConfigs(ctx, "GITEA", &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
 func GitHubConfigs(ctx context.Context) ([]*schema.GitHubConnection, error) {
 	var config []*schema.GitHubConnection
 	if err := api.InternalClient.ExternalServiceConfigs(ctx, "GITHUB", &config); err != nil {
@@ -0,0 +1,44 @@
+package reposource
+
+import (
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+type BitbucketCloud struct {
+	*schema.BitbucketCloudConnection
+}
+
+var _ RepoSource = BitbucketCloud{}
+
+func (c BitbucketCloud) CloneURLToRepoName(cloneURL string) (repoName api.RepoName, err error) {
+	baseURL := c.Url
+	if baseURL == "" {
+		baseURL = "https://bitbucket.org"
+	}
+	parsedCloneURL, parsedBaseURL, match, err := parseURLs(cloneURL, baseURL)
+	if err != nil {
+		return "", err
+	}
+	if !match {
+		return "", nil
+	}
+
+	nameWithOwner := strings.TrimPrefix(strings.TrimSuffix(parsedCloneURL.Path, ".git"), "/")
+	if strings.Count(nameWithOwner, "/") != 1 || strings.HasPrefix(nameWithOwner, "/") || strings.HasSuffix(nameWithOwner, "/") {
+		return "", nil // Not a Bitbucket Cloud clone URL
+	}
+	return BitbucketCloudRepoName(c.RepositoryPathPattern, parsedBaseURL.Hostname(), nameWithOwner), nil
+}
+
+func BitbucketCloudRepoName(repositoryPathPattern, host, nameWithOwner string) api.RepoName {
+	if repositoryPathPattern == "" {
+		repositoryPathPattern = "{host}/{nameWithOwner}"
+	}
+	return api.RepoName(strings.NewReplacer(
+		"{host}", host,
+		"{nameWithOwner}", nameWithOwner,
+	).Replace(repositoryPathPattern))
+}
@@ -0,0 +1,47 @@
+package reposource
+
+import (
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+type Gerrit struct {
+	*schema.GerritConnection
+}
+
+var _ RepoSource = Gerrit{}
+
+func (c Gerrit) CloneURLToRepoName(cloneURL string) (repoName api.RepoName, err error) {
+	parsedCloneURL, baseURL, match, err := parseURLs(cloneURL, c.Url)
+	if err != nil {
+		return "", err
+	}
+	if !match {
+		return "", nil
+	}
+
+	path := parsedCloneURL.Path
+	if strings.HasPrefix(parsedCloneURL.Scheme, "http") {
+		// Gerrit may be served from a subpath (such as https://example.com/r/), and
+		// authenticated clone URLs have an extra "a/" path component.
+		path = strings.TrimPrefix(path, strings.TrimSuffix(baseURL.Path, "/"))
+		path = strings.TrimPrefix(path, "/a/")
+	}
+	name := strings.Trim(strings.TrimSuffix(path, ".git"), "/")
+	if name == "" {
+		return "", nil
+	}
+	return GerritRepoName(c.RepositoryPathPattern, baseURL.Hostname(), name), nil
+}
+
+func GerritRepoName(repositoryPathPattern, host, name string) api.RepoName {
+	if repositoryPathPattern == "" {
+		repositoryPathPattern = "{host}/{name}"
+	}
+	return api.RepoName(strings.NewReplacer(
+		"{host}", host,
+		"{name}", name,
+	).Replace(repositoryPathPattern))
+}
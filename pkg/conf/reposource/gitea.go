@@ -0,0 +1,45 @@
+package reposource
+
+import (
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/schema"
+)
+
+type Gitea struct {
+	*schema.GiteaConnection
+}
+
+var _ RepoSource = Gitea{}
+
+func (c Gitea) CloneURLToRepoName(cloneURL string) (repoName api.RepoName, err error) {
+	parsedCloneURL, baseURL, match, err := parseURLs(cloneURL, c.Url)
+	if err != nil {
+		return "", err
+	}
+	if !match {
+		return "", nil
+	}
+
+	path := parsedCloneURL.Path
+	if strings.HasPrefix(parsedCloneURL.Scheme, "http") {
+		// Gitea may be served from a subpath (such as https://example.com/gitea/).
+		path = strings.TrimPrefix(path, strings.TrimSuffix(baseURL.Path, "/"))
+	}
+	nameWithOwner := strings.TrimPrefix(strings.TrimSuffix(path, ".git"), "/")
+	if strings.Count(nameWithOwner, "/") != 1 || strings.HasPrefix(nameWithOwner, "/") || strings.HasSuffix(nameWithOwner, "/") {
+		return "", nil // Not a Gitea clone URL
+	}
+	return GiteaRepoName(c.RepositoryPathPattern, baseURL.Hostname(), nameWithOwner), nil
+}
+
+func GiteaRepoName(repositoryPathPattern, host, nameWithOwner string) api.RepoName {
+	if repositoryPathPattern == "" {
+		repositoryPathPattern = "{host}/{nameWithOwner}"
+	}
+	return api.RepoName(strings.NewReplacer(
+		"{host}", host,
+		"{nameWithOwner}", nameWithOwner,
+	).Replace(repositoryPathPattern))
+}
@@ -0,0 +1,187 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/metrics"
+	"golang.org/x/net/context/ctxhttp"
+	"golang.org/x/time/rate"
+)
+
+var requestCounter = metrics.NewRequestCounter("bitbucketcloud", "Total number of requests sent to the Bitbucket Cloud API.")
+
+// WithRequestCounter wraps the given transport with a request counter metric.
+func WithRequestCounter(transport http.RoundTripper) http.RoundTripper {
+	return requestCounter.Transport(transport, func(u *url.URL) string {
+		// API to URL mapping looks like this:
+		//
+		// 	Repo -> 2.0/repositories/%s/%s
+		// 	Repos -> 2.0/repositories
+		switch {
+		case strings.HasSuffix(u.Path, "/2.0/repositories"):
+			return "Repos"
+		case strings.Contains(u.Path, "/2.0/repositories/"):
+			return "Repo"
+		default:
+			// don't return the path directly as that could introduce too much dimensionality
+			return "unknown"
+		}
+	})
+}
+
+// Client access Bitbucket Cloud via the REST API 2.0.
+type Client struct {
+	// URL is the base URL of the Bitbucket Cloud API (such as https://api.bitbucket.org/).
+	URL *url.URL
+
+	// The username and app password credentials for accessing the API. App passwords
+	// are created at https://bitbucket.org/account/settings/app-passwords/.
+	Username, AppPassword string
+
+	// HTTPClient is the client used to access Bitbucket Cloud. To enabled tracing,
+	// ensure the transport includes nethttp.Transport.
+	//
+	// To enable metrics, always wrap the Transport using WithRequestCounter.
+	HTTPClient *http.Client
+
+	// RateLimit is the self-imposed rate limiter (since Bitbucket Cloud does not report
+	// its rate limits in HTTP response headers).
+	RateLimit *rate.Limiter
+}
+
+// Repo returns the repository with the given full name (such as "myteam/myrepo").
+func (c *Client) Repo(ctx context.Context, fullName string) (*Repo, error) {
+	req, err := http.NewRequest("GET", "2.0/repositories/"+fullName, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp Repo
+	err = c.do(ctx, req, &resp)
+	return &resp, err
+}
+
+// Repos returns a page of the repositories the authenticated user is a member of. The
+// first page is requested with a token that only has Pagelen set; the following pages
+// with the token returned for the previous page.
+func (c *Client) Repos(ctx context.Context, pageToken *PageToken) ([]*Repo, *PageToken, error) {
+	u := pageToken.Next
+	if pageToken.Next == "" {
+		v := url.Values{}
+		v.Set("role", "member")
+		v.Set("pagelen", strconv.Itoa(pageToken.Pagelen))
+		u = "2.0/repositories?" + v.Encode()
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var resp struct {
+		PageToken
+		Values []*Repo `json:"values"`
+	}
+	if err := c.do(ctx, req, &resp); err != nil {
+		return nil, nil, err
+	}
+	if resp.Next != "" {
+		// The next page is requested with our credentials, so it must be on the API host.
+		next, err := url.Parse(resp.Next)
+		if err != nil {
+			return nil, nil, err
+		}
+		if next.IsAbs() && next.Host != c.URL.Host {
+			return nil, nil, errors.Errorf("unexpected Bitbucket Cloud API next page URL: %q", resp.Next)
+		}
+	}
+	return resp.Values, &resp.PageToken, nil
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request, result interface{}) error {
+	req.URL = c.URL.ResolveReference(req.URL)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if c.Username != "" || c.AppPassword != "" {
+		req.SetBasicAuth(c.Username, c.AppPassword)
+	}
+
+	req, ht := nethttp.TraceRequest(opentracing.GlobalTracer(), req,
+		nethttp.OperationName("Bitbucket Cloud"),
+		nethttp.ClientTrace(false))
+	defer ht.Finish()
+
+	if err := c.RateLimit.Wait(ctx); err != nil {
+		return err
+	}
+	resp, err := ctxhttp.Do(ctx, c.HTTPClient, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.WithStack(&httpError{URL: req.URL, StatusCode: resp.StatusCode})
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// PageToken describes a page of results. Next is the absolute URL of the next page, if
+// any.
+type PageToken struct {
+	Pagelen int    `json:"pagelen"`
+	Page    int    `json:"page"`
+	Next    string `json:"next"`
+}
+
+// HasMore reports whether there is a page after the one described by t.
+func (t *PageToken) HasMore() bool {
+	return t.Next != ""
+}
+
+type Repo struct {
+	UUID        string `json:"uuid"`
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	FullName    string `json:"full_name"`
+	Description string `json:"description"`
+	IsPrivate   bool   `json:"is_private"`
+
+	// SCM is "git" or "hg". Only Git repositories are supported.
+	SCM string `json:"scm"`
+
+	// Parent is the repository this repository was forked from, if any.
+	Parent *Repo `json:"parent"`
+	Links  struct {
+		Clone []struct {
+			Href string `json:"href"`
+			Name string `json:"name"`
+		} `json:"clone"`
+		HTML struct {
+			Href string `json:"href"`
+		} `json:"html"`
+	} `json:"links"`
+}
+
+type httpError struct {
+	StatusCode int
+	URL        *url.URL
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("unexpected %d response from Bitbucket Cloud API at %s", e.StatusCode, e.URL)
+}
+
+func (e *httpError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+func (e *httpError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,109 @@
+package bitbucketcloud
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/httptestutil"
+	"golang.org/x/time/rate"
+)
+
+// newTestClient returns a client for the repositories recorded in testdata. The
+// recorded next page links point to the test server.
+func newTestClient(t *testing.T) (*Client, func()) {
+	srv := httptestutil.NewFixtureServer(t, "https://api.bitbucket.org", func(r *http.Request) string {
+		if username, password, _ := r.BasicAuth(); username != "alice" || password != "secret" {
+			t.Errorf("got credentials %q:%q, want alice:secret", username, password)
+		}
+		switch {
+		case r.URL.Path == "/2.0/repositories" && r.URL.Query().Get("page") == "":
+			if got := r.URL.Query().Get("role"); got != "member" {
+				t.Errorf("got role %q, want member", got)
+			}
+			return "repositories-1.json"
+		case r.URL.Path == "/2.0/repositories" && r.URL.Query().Get("page") == "2":
+			return "repositories-2.json"
+		case r.URL.Path == "/2.0/repositories/sourcegraph/go-langserver":
+			return "repository.json"
+		}
+		return ""
+	})
+	u, _ := url.Parse(srv.URL + "/")
+	return &Client{
+		URL:         u,
+		Username:    "alice",
+		AppPassword: "secret",
+		HTTPClient:  srv.Client(),
+		RateLimit:   rate.NewLimiter(rate.Inf, 1),
+	}, srv.Close
+}
+
+func TestClient_Repos(t *testing.T) {
+	c, done := newTestClient(t)
+	defer done()
+
+	var got []string
+	page := &PageToken{Pagelen: 2}
+	for {
+		var repos []*Repo
+		var err error
+		repos, page, err = c.Repos(context.Background(), page)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range repos {
+			got = append(got, r.FullName+" "+r.SCM)
+		}
+		if !page.HasMore() {
+			break
+		}
+	}
+	want := []string{
+		"sourcegraph/go-langserver git",
+		"sourcegraph/hg-tools hg",
+		"alice/go-langserver git",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got repos %v, want %v", got, want)
+	}
+}
+
+func TestClient_Repos_foreignNextPage(t *testing.T) {
+	c, done := newTestClient(t)
+	defer done()
+
+	// Serve the recorded next page link, which points to the real API rather than
+	// the test server.
+	srv := httptestutil.NewFixtureServer(t, "", func(*http.Request) string { return "repositories-1.json" })
+	defer srv.Close()
+	c.URL, _ = url.Parse(srv.URL + "/")
+
+	if _, _, err := c.Repos(context.Background(), &PageToken{Pagelen: 2}); err == nil {
+		t.Error("got nil error for a next page on another host")
+	}
+}
+
+func TestClient_Repo(t *testing.T) {
+	c, done := newTestClient(t)
+	defer done()
+
+	repo, err := c.Repo(context.Background(), "sourcegraph/go-langserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.UUID != "{6d3e5a2b-1f8c-4c7e-9a41-2b5c7d8e9f01}" || repo.Description != "Go language server" || repo.Parent != nil {
+		t.Errorf("got repo %+v", repo)
+	}
+	if repo.Links.HTML.Href != "https://bitbucket.org/sourcegraph/go-langserver" || len(repo.Links.Clone) != 2 {
+		t.Errorf("got links %+v", repo.Links)
+	}
+
+	_, err = c.Repo(context.Background(), "sourcegraph/missing")
+	if e, ok := errors.Cause(err).(interface{ NotFound() bool }); !ok || !e.NotFound() {
+		t.Errorf("got error %v, want a not found error", err)
+	}
+}
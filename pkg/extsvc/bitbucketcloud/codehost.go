@@ -0,0 +1,5 @@
+package bitbucketcloud
+
+// ServiceType is the (api.ExternalRepoSpec).ServiceType value for Bitbucket Cloud repositories. The
+// ServiceID value is the base URL to Bitbucket Cloud (such as https://bitbucket.org/).
+const ServiceType = "bitbucketCloud"
@@ -70,6 +70,25 @@ type Client struct {
 	// RateLimit is the self-imposed rate limiter (since Bitbucket does not have a concept
 	// of rate limiting in HTTP response headers).
 	RateLimit *rate.Limiter
+
+	// Oauth is the consumer of an application link with user impersonation allowed. It is
+	// required to make requests on behalf of other users (see Sudo).
+	Oauth *OAuthConsumer
+
+	// sudo is the username of the user that requests are made on behalf of, if any.
+	sudo string
+}
+
+// Sudo returns a copy of the client that makes requests on behalf of the user with the given
+// username, who sees exactly what they would see on Bitbucket Server. It requires the client
+// to have an OAuth consumer.
+func (c *Client) Sudo(username string) (*Client, error) {
+	if c.Oauth == nil {
+		return nil, errors.New("impersonating users on Bitbucket Server requires an OAuth consumer")
+	}
+	sudo := *c
+	sudo.sudo = username
+	return &sudo, nil
 }
 
 func (c *Client) Repo(ctx context.Context, projectKey, repoSlug string) (*Repo, error) {
@@ -100,6 +119,49 @@ func (c *Client) Repos(ctx context.Context, pageToken *PageToken) ([]*Repo, *Pag
 	return resp.Values, resp.PageToken, nil
 }
 
+// ReposWithFilter returns the repositories matching the given filters, such as
+// permission=REPO_READ or visibility=public.
+//
+// See https://docs.atlassian.com/bitbucket-server/rest/5.16.0/bitbucket-rest.html#idm8297065392
+func (c *Client) ReposWithFilter(ctx context.Context, filters url.Values, pageToken *PageToken) ([]*Repo, *PageToken, error) {
+	q := pageToken.values()
+	for k, vs := range filters {
+		q[k] = vs
+	}
+	req, err := http.NewRequest("GET", "rest/api/1.0/repos?"+q.Encode(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var resp struct {
+		*PageToken
+		Values []*Repo
+	}
+	err = c.do(ctx, req, &resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Values, resp.PageToken, nil
+}
+
+// Users returns the users whose username, display name or email address contains filter.
+func (c *Client) Users(ctx context.Context, filter string, pageToken *PageToken) ([]*User, *PageToken, error) {
+	q := pageToken.values()
+	q.Set("filter", filter)
+	req, err := http.NewRequest("GET", "rest/api/1.0/users?"+q.Encode(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var resp struct {
+		*PageToken
+		Values []*User
+	}
+	err = c.do(ctx, req, &resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Values, resp.PageToken, nil
+}
+
 func (c *Client) RecentRepos(ctx context.Context, pageToken *PageToken) ([]*Repo, *PageToken, error) {
 	u := fmt.Sprintf("rest/api/1.0/profile/recent/repos%s", pageToken.Query())
 	req, err := http.NewRequest("GET", u, nil)
@@ -122,7 +184,11 @@ func (c *Client) do(ctx context.Context, req *http.Request, result interface{})
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 
 	// Authenticate request, preferring token.
-	if c.Token != "" {
+	if c.sudo != "" {
+		if err := c.Oauth.sign(req, c.sudo); err != nil {
+			return err
+		}
+	} else if c.Token != "" {
 		req.Header.Set("Authorization", "Bearer "+c.Token)
 	} else if c.Username != "" || c.Password != "" {
 		req.SetBasicAuth(c.Username, c.Password)
@@ -165,20 +231,25 @@ func (t *PageToken) HasMore() bool {
 }
 
 func (t *PageToken) Query() string {
-	if t == nil {
+	v := t.values()
+	if len(v) == 0 {
 		return ""
 	}
+	return "?" + v.Encode()
+}
+
+func (t *PageToken) values() url.Values {
 	v := url.Values{}
+	if t == nil {
+		return v
+	}
 	if t.NextPageStart != 0 {
 		v.Set("start", strconv.Itoa(t.NextPageStart))
 	}
 	if t.Limit != 0 {
 		v.Set("limit", strconv.Itoa(t.Limit))
 	}
-	if len(v) == 0 {
-		return ""
-	}
-	return "?" + v.Encode()
+	return v
 }
 
 type Repo struct {
@@ -208,6 +279,16 @@ func (r *Repo) IsPersonalRepository() bool {
 	return r.Project.Type == "PERSONAL"
 }
 
+type User struct {
+	Name         string `json:"name"`
+	EmailAddress string `json:"emailAddress"`
+	ID           int    `json:"id"`
+	DisplayName  string `json:"displayName"`
+	Active       bool   `json:"active"`
+	Slug         string `json:"slug"`
+	Type         string `json:"type"`
+}
+
 type Project struct {
 	Key    string `json:"key"`
 	ID     int    `json:"id"`
@@ -0,0 +1,130 @@
+package bitbucketserver
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// OAuthConsumer is the consumer of an incoming application link on Bitbucket Server with user
+// impersonation allowed. It signs requests with 2-legged OAuth 1.0a (RSA-SHA1), which lets the
+// consumer act as any user (see Client.Sudo).
+type OAuthConsumer struct {
+	ConsumerKey string
+	SigningKey  *rsa.PrivateKey
+}
+
+// ParseSigningKey parses the base64-encoded PEM RSA private key of an OAuthConsumer. Both PKCS #1
+// and PKCS #8 keys are supported.
+func ParseSigningKey(base64PEM string) (*rsa.PrivateKey, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(base64PEM))
+	if err != nil {
+		return nil, errors.Wrap(err, "signing key is not base64-encoded")
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("signing key is not PEM-encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse signing key")
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("signing key is not an RSA key")
+	}
+	return rsaKey, nil
+}
+
+// sign adds the OAuth Authorization header to req, which acts as the user with the given
+// username. req.URL must be absolute.
+func (c *OAuthConsumer) sign(req *http.Request, username string) error {
+	q := req.URL.Query()
+	q.Set("xoauth_requestor_id", username)
+	req.URL.RawQuery = q.Encode()
+
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	params := map[string]string{
+		"oauth_consumer_key":     c.ConsumerKey,
+		"oauth_nonce":            hex.EncodeToString(nonce[:]),
+		"oauth_signature_method": "RSA-SHA1",
+		"oauth_timestamp":        strconv.FormatInt(time.Now().Unix(), 10),
+		"oauth_version":          "1.0",
+	}
+
+	hashed := sha1.Sum([]byte(signatureBaseString(req.Method, req.URL, params)))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, c.SigningKey, crypto.SHA1, hashed[:])
+	if err != nil {
+		return err
+	}
+	params["oauth_signature"] = base64.StdEncoding.EncodeToString(sig)
+
+	header := make([]string, 0, len(params))
+	for k, v := range params {
+		header = append(header, percentEncode(k)+`="`+percentEncode(v)+`"`)
+	}
+	sort.Strings(header)
+	req.Header.Set("Authorization", "OAuth "+strings.Join(header, ", "))
+	return nil
+}
+
+// signatureBaseString returns the signature base string of a request with the given OAuth
+// parameters, as specified in https://tools.ietf.org/html/rfc5849#section-3.4.1.
+func signatureBaseString(method string, u *url.URL, oauthParams map[string]string) string {
+	var params []string
+	for k, vs := range u.Query() {
+		for _, v := range vs {
+			params = append(params, percentEncode(k)+"="+percentEncode(v))
+		}
+	}
+	for k, v := range oauthParams {
+		params = append(params, percentEncode(k)+"="+percentEncode(v))
+	}
+	// Sorting the encoded "key=value" pairs sorts by key and then by value, because "=" sorts
+	// before all characters that may appear in an encoded key.
+	sort.Strings(params)
+
+	scheme, host := strings.ToLower(u.Scheme), strings.ToLower(u.Host)
+	if (scheme == "http" && strings.HasSuffix(host, ":80")) || (scheme == "https" && strings.HasSuffix(host, ":443")) {
+		host = host[:strings.LastIndex(host, ":")]
+	}
+	baseURL := scheme + "://" + host + u.EscapedPath()
+
+	return strings.ToUpper(method) + "&" + percentEncode(baseURL) + "&" + percentEncode(strings.Join(params, "&"))
+}
+
+// percentEncode encodes s as specified in https://tools.ietf.org/html/rfc5849#section-3.6.
+func percentEncode(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '.', c == '_', c == '~':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&15])
+		}
+	}
+	return b.String()
+}
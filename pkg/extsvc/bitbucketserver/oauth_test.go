@@ -0,0 +1,98 @@
+package bitbucketserver
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSignatureBaseString(t *testing.T) {
+	// Example from https://tools.ietf.org/html/rfc5849#section-1.2, with the parameters of the
+	// POST body moved to the query.
+	u, err := url.Parse("http://photos.example.net:80/photos?file=vacation.jpg&size=original")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := signatureBaseString("get", u, map[string]string{
+		"oauth_consumer_key":     "dpf43f3p2l4k3l03",
+		"oauth_token":            "nnch734d00sl2jdk",
+		"oauth_signature_method": "HMAC-SHA1",
+		"oauth_timestamp":        "137131202",
+		"oauth_nonce":            "chapoH",
+	})
+	want := "GET&http%3A%2F%2Fphotos.example.net%2Fphotos&file%3Dvacation.jpg%26oauth_consumer_key%3Ddpf43f3p2l4k3l03%26oauth_nonce%3DchapoH%26oauth_signature_method%3DHMAC-SHA1%26oauth_timestamp%3D137131202%26oauth_token%3Dnnch734d00sl2jdk%26size%3Doriginal"
+	if got != want {
+		t.Errorf("got base string\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestOAuthConsumer_sign(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	signingKey, err := ParseSigningKey(base64.StdEncoding.EncodeToString(pemKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &OAuthConsumer{ConsumerKey: "sourcegraph", SigningKey: signingKey}
+
+	req, err := http.NewRequest("GET", "https://bitbucket.example.com/rest/api/1.0/repos?permission=REPO_READ", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.sign(req, "alice smith"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := req.URL.Query().Get("xoauth_requestor_id"); got != "alice smith" {
+		t.Errorf("got xoauth_requestor_id %q, want %q", got, "alice smith")
+	}
+
+	header := req.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "OAuth ") {
+		t.Fatalf("got Authorization header %q, want OAuth", header)
+	}
+	params := map[string]string{}
+	for _, param := range strings.Split(strings.TrimPrefix(header, "OAuth "), ", ") {
+		kv := strings.SplitN(param, "=", 2)
+		v, err := url.PathUnescape(strings.Trim(kv[1], `"`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		params[kv[0]] = v
+	}
+	if params["oauth_consumer_key"] != "sourcegraph" || params["oauth_signature_method"] != "RSA-SHA1" {
+		t.Errorf("unexpected OAuth parameters %v", params)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(params["oauth_signature"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	delete(params, "oauth_signature")
+	hashed := sha1.Sum([]byte(signatureBaseString(req.Method, req.URL, params)))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA1, hashed[:], sig); err != nil {
+		t.Errorf("invalid signature: %s", err)
+	}
+}
+
+func TestParseSigningKey(t *testing.T) {
+	for name, key := range map[string]string{
+		"not base64": "not a key!",
+		"not PEM":    base64.StdEncoding.EncodeToString([]byte("not a key")),
+	} {
+		if _, err := ParseSigningKey(key); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
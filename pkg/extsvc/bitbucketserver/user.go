@@ -0,0 +1,21 @@
+package bitbucketserver
+
+import "github.com/sourcegraph/sourcegraph/pkg/extsvc"
+
+// GetExternalAccountData returns the deserialized user from the external account data JSON blob in
+// a typesafe way.
+func GetExternalAccountData(data *extsvc.ExternalAccountData) (usr *User, err error) {
+	if data.AccountData == nil {
+		return nil, nil
+	}
+	var u User
+	if err := data.GetAccountData(&u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// SetExternalAccountData sets the user into the external account data blob.
+func SetExternalAccountData(data *extsvc.ExternalAccountData, user *User) {
+	data.SetAccountData(user)
+}
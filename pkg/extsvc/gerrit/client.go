@@ -0,0 +1,191 @@
+package gerrit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/metrics"
+	"golang.org/x/net/context/ctxhttp"
+	"golang.org/x/time/rate"
+)
+
+var requestCounter = metrics.NewRequestCounter("gerrit", "Total number of requests sent to the Gerrit API.")
+
+// WithRequestCounter wraps the given transport with a request counter metric.
+func WithRequestCounter(transport http.RoundTripper) http.RoundTripper {
+	return requestCounter.Transport(transport, func(u *url.URL) string {
+		// API to URL mapping looks like this:
+		//
+		// 	Project -> [a/]projects/%s
+		// 	Projects -> [a/]projects/
+		switch {
+		case strings.HasSuffix(u.Path, "/projects/"):
+			return "Projects"
+		case strings.Contains(u.Path, "/projects/"):
+			return "Project"
+		default:
+			// don't return the path directly as that could introduce too much dimensionality
+			return "unknown"
+		}
+	})
+}
+
+// Client access a Gerrit instance via the REST API.
+type Client struct {
+	// URL is the base URL of the Gerrit instance.
+	URL *url.URL
+
+	// The username and HTTP password credentials for accessing the instance. If they
+	// are empty, the client accesses the instance anonymously and only sees projects
+	// that are visible to anonymous users.
+	Username, Password string
+
+	// HTTPClient is the client used to access Gerrit. To enabled tracing, ensure
+	// the transport includes nethttp.Transport.
+	//
+	// To enable metrics, always wrap the Transport using WithRequestCounter.
+	HTTPClient *http.Client
+
+	// RateLimit is the self-imposed rate limiter (since Gerrit does not have a concept
+	// of rate limiting in HTTP response headers).
+	RateLimit *rate.Limiter
+}
+
+// Project returns the project with the given name.
+func (c *Client) Project(ctx context.Context, name string) (*Project, error) {
+	req, err := http.NewRequest("GET", c.endpoint("projects/"+url.PathEscape(name)), nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp Project
+	if err := c.do(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Name == "" {
+		// Older Gerrit versions only return the URL-encoded name as the ID.
+		resp.Name = name
+	}
+	return &resp, nil
+}
+
+// Projects returns up to limit projects visible to the user, skipping the first skip
+// projects. The projects are sorted by name. hasMore is false if there are no more
+// projects after the returned ones.
+func (c *Client) Projects(ctx context.Context, skip, limit int) (projects []*Project, hasMore bool, err error) {
+	v := url.Values{}
+	v.Set("n", strconv.Itoa(limit))
+	v.Set("S", strconv.Itoa(skip))
+	// The "d" option (include descriptions) has no value.
+	req, err := http.NewRequest("GET", c.endpoint("projects/")+"?d&"+v.Encode(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	var resp map[string]*Project
+	if err := c.do(ctx, req, &resp); err != nil {
+		return nil, false, err
+	}
+
+	projects = make([]*Project, 0, len(resp))
+	for name, p := range resp {
+		p.Name = name
+		hasMore = hasMore || p.MoreProjects
+		projects = append(projects, p)
+	}
+	sort.Slice(projects, func(i, j int) bool { return projects[i].Name < projects[j].Name })
+	return projects, hasMore, nil
+}
+
+// endpoint returns the path of the REST endpoint. Authenticated requests must be sent
+// to endpoints under the "a/" prefix.
+func (c *Client) endpoint(path string) string {
+	if c.Username != "" || c.Password != "" {
+		return "a/" + path
+	}
+	return path
+}
+
+// xssiPrefix is the prefix Gerrit adds to all JSON responses to prevent cross-site
+// script inclusion.
+var xssiPrefix = []byte(")]}'")
+
+func (c *Client) do(ctx context.Context, req *http.Request, result interface{}) error {
+	req.URL = c.URL.ResolveReference(req.URL)
+	req.Header.Set("Accept", "application/json")
+	if c.Username != "" || c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	req, ht := nethttp.TraceRequest(opentracing.GlobalTracer(), req,
+		nethttp.OperationName("Gerrit"),
+		nethttp.ClientTrace(false))
+	defer ht.Finish()
+
+	if err := c.RateLimit.Wait(ctx); err != nil {
+		return err
+	}
+	resp, err := ctxhttp.Do(ctx, c.HTTPClient, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.WithStack(&httpError{URL: req.URL, StatusCode: resp.StatusCode})
+	}
+
+	body := bufio.NewReader(resp.Body)
+	if prefix, err := body.Peek(len(xssiPrefix)); err == nil && bytes.Equal(prefix, xssiPrefix) {
+		if _, err := body.ReadString('\n'); err != nil {
+			return err
+		}
+	}
+	return json.NewDecoder(body).Decode(result)
+}
+
+// Project is a Gerrit project (a Git repository). See
+// https://gerrit-review.googlesource.com/Documentation/rest-api-projects.html#project-info.
+type Project struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Parent      string `json:"parent"`
+	Description string `json:"description"`
+
+	// State is "ACTIVE", "READ_ONLY" or "HIDDEN".
+	State    string `json:"state"`
+	WebLinks []struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"web_links"`
+
+	// MoreProjects is set on the last project of a list of projects if the list was
+	// truncated by the limit.
+	MoreProjects bool `json:"_more_projects"`
+}
+
+type httpError struct {
+	StatusCode int
+	URL        *url.URL
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("unexpected %d response from Gerrit REST API at %s", e.StatusCode, e.URL)
+}
+
+func (e *httpError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+func (e *httpError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
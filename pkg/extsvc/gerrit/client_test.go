@@ -0,0 +1,91 @@
+package gerrit
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/httptestutil"
+	"golang.org/x/time/rate"
+)
+
+// newTestClient returns a client for the projects recorded in testdata.
+func newTestClient(t *testing.T) (*Client, func()) {
+	srv := httptestutil.NewFixtureServer(t, "", func(r *http.Request) string {
+		if username, password, _ := r.BasicAuth(); username != "alice" || password != "secret" {
+			t.Errorf("got credentials %q:%q, want alice:secret", username, password)
+		}
+		if _, ok := r.URL.Query()["d"]; r.URL.Path == "/a/projects/" && !ok {
+			t.Error("projects are listed without descriptions")
+		}
+		switch {
+		case r.URL.Path == "/a/projects/" && r.URL.Query().Get("S") == "0":
+			return "projects-1.json"
+		case r.URL.Path == "/a/projects/" && r.URL.Query().Get("S") == "2":
+			return "projects-2.json"
+		case r.URL.EscapedPath() == "/a/projects/platform%2Fbuild":
+			return "project.json"
+		}
+		return ""
+	})
+	u, _ := url.Parse(srv.URL + "/")
+	return &Client{
+		URL:        u,
+		Username:   "alice",
+		Password:   "secret",
+		HTTPClient: srv.Client(),
+		RateLimit:  rate.NewLimiter(rate.Inf, 1),
+	}, srv.Close
+}
+
+func TestClient_Projects(t *testing.T) {
+	c, done := newTestClient(t)
+	defer done()
+
+	var got []string
+	for skip, hasMore := 0, true; hasMore; {
+		var projects []*Project
+		var err error
+		projects, hasMore, err = c.Projects(context.Background(), skip, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range projects {
+			got = append(got, p.Name+" "+p.State)
+		}
+		skip += len(projects)
+	}
+	want := []string{
+		"All-Projects ACTIVE",
+		"platform/build ACTIVE",
+		"platform/legacy READ_ONLY",
+		"tools/repo ACTIVE",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got projects %v, want %v", got, want)
+	}
+}
+
+func TestClient_Project(t *testing.T) {
+	c, done := newTestClient(t)
+	defer done()
+
+	project, err := c.Project(context.Background(), "platform/build")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if project.Name != "platform/build" || project.Description != "Build system" || project.Parent != "All-Projects" {
+		t.Errorf("got project %+v", project)
+	}
+	if len(project.WebLinks) != 1 || project.WebLinks[0].URL != "https://gerrit.example.com/plugins/gitiles/platform/build" {
+		t.Errorf("got web links %+v", project.WebLinks)
+	}
+
+	_, err = c.Project(context.Background(), "missing")
+	if e, ok := errors.Cause(err).(interface{ NotFound() bool }); !ok || !e.NotFound() {
+		t.Errorf("got error %v, want a not found error", err)
+	}
+}
@@ -0,0 +1,171 @@
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/opentracing-contrib/go-stdlib/nethttp"
+	opentracing "github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/metrics"
+	"golang.org/x/net/context/ctxhttp"
+	"golang.org/x/time/rate"
+)
+
+var requestCounter = metrics.NewRequestCounter("gitea", "Total number of requests sent to the Gitea API.")
+
+// WithRequestCounter wraps the given transport with a request counter metric.
+func WithRequestCounter(transport http.RoundTripper) http.RoundTripper {
+	return requestCounter.Transport(transport, func(u *url.URL) string {
+		// API to URL mapping looks like this:
+		//
+		// 	Repo -> api/v1/repos/%s/%s
+		// 	RepoByID -> api/v1/repositories/%d
+		// 	Repos -> api/v1/repos/search
+		switch {
+		case strings.HasSuffix(u.Path, "/api/v1/repos/search"):
+			return "Repos"
+		case strings.Contains(u.Path, "/api/v1/repos/"):
+			return "Repo"
+		case strings.Contains(u.Path, "/api/v1/repositories/"):
+			return "RepoByID"
+		default:
+			// don't return the path directly as that could introduce too much dimensionality
+			return "unknown"
+		}
+	})
+}
+
+// Client access a Gitea instance via the REST API.
+type Client struct {
+	// URL is the base URL of the Gitea instance.
+	URL *url.URL
+
+	// Token is the access token for accessing the instance.
+	// https://gitea.example.com/user/settings/applications
+	Token string
+
+	// HTTPClient is the client used to access Gitea. To enabled tracing, ensure
+	// the transport includes nethttp.Transport.
+	//
+	// To enable metrics, always wrap the Transport using WithRequestCounter.
+	HTTPClient *http.Client
+
+	// RateLimit is the self-imposed rate limiter (since Gitea does not have a concept
+	// of rate limiting in HTTP response headers).
+	RateLimit *rate.Limiter
+}
+
+// Repo returns the repository with the given owner and name.
+func (c *Client) Repo(ctx context.Context, owner, name string) (*Repo, error) {
+	u := fmt.Sprintf("api/v1/repos/%s/%s", url.PathEscape(owner), url.PathEscape(name))
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp Repo
+	err = c.do(ctx, req, &resp)
+	return &resp, err
+}
+
+// RepoByID returns the repository with the given ID.
+func (c *Client) RepoByID(ctx context.Context, id int64) (*Repo, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("api/v1/repositories/%d", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp Repo
+	err = c.do(ctx, req, &resp)
+	return &resp, err
+}
+
+// Repos returns a page of the repositories visible to the authenticated user. Pages
+// are numbered starting at 1. hasMore is false if this is the last page.
+func (c *Client) Repos(ctx context.Context, page, perPage int) (repos []*Repo, hasMore bool, err error) {
+	v := url.Values{}
+	v.Set("page", strconv.Itoa(page))
+	v.Set("limit", strconv.Itoa(perPage))
+	req, err := http.NewRequest("GET", "api/v1/repos/search?"+v.Encode(), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	var resp struct {
+		OK   bool    `json:"ok"`
+		Data []*Repo `json:"data"`
+	}
+	if err := c.do(ctx, req, &resp); err != nil {
+		return nil, false, err
+	}
+	return resp.Data, len(resp.Data) >= perPage, nil
+}
+
+func (c *Client) do(ctx context.Context, req *http.Request, result interface{}) error {
+	req.URL = c.URL.ResolveReference(req.URL)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "token "+c.Token)
+	}
+
+	req, ht := nethttp.TraceRequest(opentracing.GlobalTracer(), req,
+		nethttp.OperationName("Gitea"),
+		nethttp.ClientTrace(false))
+	defer ht.Finish()
+
+	if err := c.RateLimit.Wait(ctx); err != nil {
+		return err
+	}
+	resp, err := ctxhttp.Do(ctx, c.HTTPClient, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.WithStack(&httpError{URL: req.URL, StatusCode: resp.StatusCode})
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+type Repo struct {
+	ID          int64  `json:"id"`
+	Owner       *User  `json:"owner"`
+	Name        string `json:"name"`
+	FullName    string `json:"full_name"`
+	Description string `json:"description"`
+	Empty       bool   `json:"empty"`
+	Private     bool   `json:"private"`
+	Fork        bool   `json:"fork"`
+	Mirror      bool   `json:"mirror"`
+	Archived    bool   `json:"archived"`
+	HTMLURL     string `json:"html_url"`
+	SSHURL      string `json:"ssh_url"`
+	CloneURL    string `json:"clone_url"`
+}
+
+type User struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+}
+
+type httpError struct {
+	StatusCode int
+	URL        *url.URL
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("unexpected %d response from Gitea API at %s", e.StatusCode, e.URL)
+}
+
+func (e *httpError) Unauthorized() bool {
+	return e.StatusCode == http.StatusUnauthorized
+}
+
+func (e *httpError) NotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
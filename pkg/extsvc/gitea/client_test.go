@@ -0,0 +1,96 @@
+package gitea
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sourcegraph/sourcegraph/pkg/httptestutil"
+	"golang.org/x/time/rate"
+)
+
+// newTestClient returns a client for the repositories recorded in testdata, which
+// authenticates with the token "secret".
+func newTestClient(t *testing.T) (*Client, func()) {
+	srv := httptestutil.NewFixtureServer(t, "", func(r *http.Request) string {
+		if got, want := r.Header.Get("Authorization"), "token secret"; got != want {
+			t.Errorf("got Authorization header %q, want %q", got, want)
+		}
+		switch {
+		case r.URL.Path == "/api/v1/repos/search" && r.URL.Query().Get("page") == "1":
+			return "repos-search-1.json"
+		case r.URL.Path == "/api/v1/repos/search" && r.URL.Query().Get("page") == "2":
+			return "repos-search-2.json"
+		case r.URL.Path == "/api/v1/repos/sourcegraph/go-langserver", r.URL.Path == "/api/v1/repositories/1":
+			return "repo.json"
+		}
+		return ""
+	})
+	u, _ := url.Parse(srv.URL + "/")
+	return &Client{
+		URL:        u,
+		Token:      "secret",
+		HTTPClient: srv.Client(),
+		RateLimit:  rate.NewLimiter(rate.Inf, 1),
+	}, srv.Close
+}
+
+func TestClient_Repos(t *testing.T) {
+	c, done := newTestClient(t)
+	defer done()
+
+	var names []string
+	for page, hasMore := 1, true; hasMore; page++ {
+		var repos []*Repo
+		var err error
+		repos, hasMore, err = c.Repos(context.Background(), page, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range repos {
+			names = append(names, r.FullName)
+		}
+	}
+	if want := []string{"sourcegraph/go-langserver", "alice/go-langserver", "sourcegraph/legacy"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got repos %v, want %v", names, want)
+	}
+}
+
+func TestClient_Repo(t *testing.T) {
+	c, done := newTestClient(t)
+	defer done()
+
+	repo, err := c.Repo(context.Background(), "sourcegraph", "go-langserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Repo{
+		ID:          1,
+		Owner:       &User{ID: 1, Login: "sourcegraph"},
+		Name:        "go-langserver",
+		FullName:    "sourcegraph/go-langserver",
+		Description: "Go language server",
+		HTMLURL:     "https://gitea.example.com/sourcegraph/go-langserver",
+		SSHURL:      "git@gitea.example.com:sourcegraph/go-langserver.git",
+		CloneURL:    "https://gitea.example.com/sourcegraph/go-langserver.git",
+	}
+	if !reflect.DeepEqual(repo, want) {
+		t.Errorf("got repo %+v, want %+v", repo, want)
+	}
+
+	repo, err = c.RepoByID(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(repo, want) {
+		t.Errorf("got repo by ID %+v, want %+v", repo, want)
+	}
+
+	_, err = c.Repo(context.Background(), "sourcegraph", "missing")
+	if e, ok := errors.Cause(err).(interface{ NotFound() bool }); !ok || !e.NotFound() {
+		t.Errorf("got error %v, want a not found error", err)
+	}
+}
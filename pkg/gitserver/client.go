@@ -15,6 +15,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -31,6 +32,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/pkg/conf"
 	"github.com/sourcegraph/sourcegraph/pkg/env"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver/protocol"
+	"github.com/sourcegraph/sourcegraph/pkg/lfs"
 	"github.com/sourcegraph/sourcegraph/pkg/vcs"
 	"golang.org/x/net/context/ctxhttp"
 	log15 "gopkg.in/inconshreveable/log15.v2"
@@ -138,6 +140,16 @@ func (c *Client) addrForKey(ctx context.Context, key string) string {
 	if len(addrs) == 0 {
 		panic("unexpected state: no gitserver addresses")
 	}
+	return addrForKey(key, addrs)
+}
+
+// AddrForRepo returns the address of the gitserver in addrs that the given repo
+// is sharded to. addrs must not be empty.
+func AddrForRepo(repo api.RepoName, addrs []string) string {
+	return addrForKey(string(protocol.NormalizeRepo(repo)), addrs)
+}
+
+func addrForKey(key string, addrs []string) string {
 	sum := md5.Sum([]byte(key))
 	serverIndex := binary.BigEndian.Uint64(sum[:]) % uint64(len(addrs))
 	return addrs[serverIndex]
@@ -225,6 +237,14 @@ type Repo struct {
 	// this field is optional (it will use the last-used Git remote URL). If the repository is not
 	// cloned on the gitserver, the request will fail.
 	URL string
+
+	// CloneOptions configures how the repository is cloned if it is not cloned yet. It is only
+	// used by RequestRepoUpdate. If nil, the repository is fully cloned.
+	CloneOptions *protocol.CloneOptions
+
+	// PushMirrorURL is the URL of the secondary remote that the repository is pushed to after
+	// each update. It is only used by RequestRepoUpdate. If empty, the repository is not pushed.
+	PushMirrorURL string
 }
 
 // Command creates a new Cmd. Command name must be 'git',
@@ -401,6 +421,70 @@ func (c *Client) ListCloned(ctx context.Context) ([]string, error) {
 	return repos, err
 }
 
+// MockReposStats mocks (*Client).ReposStats for tests.
+var MockReposStats func(limit int) ([]*protocol.RepoStats, error)
+
+// ReposStats returns the disk usage of the limit largest repositories across
+// all gitservers, largest first. The sizes are computed by the gitservers'
+// janitor jobs, so they may be out of date by up to a day.
+func (c *Client) ReposStats(ctx context.Context, limit int) ([]*protocol.RepoStats, error) {
+	if MockReposStats != nil {
+		return MockReposStats(limit)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		err   error
+		repos []*protocol.RepoStats
+	)
+	for _, addr := range c.Addrs(ctx) {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			// The limit largest repos are among the limit largest repos of
+			// each gitserver.
+			r, e := doReposStatsOne(ctx, addr, limit)
+			mu.Lock()
+			if e != nil {
+				err = e
+			}
+			repos = append(repos, r...)
+			mu.Unlock()
+		}(addr)
+	}
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(repos, func(i, j int) bool {
+		if a, b := repos[i].Size.TotalBytes(), repos[j].Size.TotalBytes(); a != b {
+			return a > b
+		}
+		return repos[i].Repo < repos[j].Repo
+	})
+	if limit < len(repos) {
+		repos = repos[:limit]
+	}
+	return repos, nil
+}
+
+func doReposStatsOne(ctx context.Context, addr string, limit int) ([]*protocol.RepoStats, error) {
+	resp, err := ctxhttp.Get(ctx, nil, "http://"+addr+"/repos-stats?limit="+strconv.Itoa(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &url.Error{URL: resp.Request.URL.String(), Op: "ReposStats", Err: fmt.Errorf("ReposStats: http status %d", resp.StatusCode)}
+	}
+
+	var stats protocol.ReposStatsResponse
+	err = json.NewDecoder(resp.Body).Decode(&stats)
+	return stats.Repos, err
+}
+
 // GetGitolitePhabricatorMetadata returns Phabricator metadata for a
 // Gitolite repository fetched via a user-provided command.
 func (c *Client) GetGitolitePhabricatorMetadata(ctx context.Context, gitoliteHost string, repo string) (*protocol.GitolitePhabricatorMetadataResponse, error) {
@@ -436,9 +520,11 @@ func doListOne(ctx context.Context, urlSuffix string, addr string) ([]string, er
 // update won't happen.
 func (c *Client) RequestRepoUpdate(ctx context.Context, repo Repo, since time.Duration) (*protocol.RepoUpdateResponse, error) {
 	req := &protocol.RepoUpdateRequest{
-		Repo:  repo.Name,
-		URL:   repo.URL,
-		Since: since,
+		Repo:          repo.Name,
+		URL:           repo.URL,
+		Since:         since,
+		CloneOptions:  repo.CloneOptions,
+		PushMirrorURL: repo.PushMirrorURL,
 	}
 	resp, err := c.httpPost(ctx, repo.Name, "repo-update", req)
 	if err != nil {
@@ -579,6 +665,38 @@ func (c *Client) Remove(ctx context.Context, repo api.RepoName) error {
 	return nil
 }
 
+// MockLFSObject mocks (*Client).LFSObject for tests.
+var MockLFSObject func(repo api.RepoName, p lfs.Pointer) ([]byte, error)
+
+// LFSObject returns the content of the file stored with Git LFS with the LFS
+// pointer p in repo. It returns an error satisfying os.IsNotExist if gitserver
+// can't fetch the content, for example because fetching LFS objects is
+// disabled.
+func (c *Client) LFSObject(ctx context.Context, repo api.RepoName, p lfs.Pointer) ([]byte, error) {
+	if MockLFSObject != nil {
+		return MockLFSObject(repo, p)
+	}
+
+	req := &protocol.LFSObjectRequest{
+		Repo:    repo,
+		Pointer: p,
+	}
+	resp, err := c.httpPost(ctx, repo, "lfs-object", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &os.PathError{Op: "LFSObject", Path: p.OID, Err: os.ErrNotExist}
+	}
+	if resp.StatusCode != http.StatusOK {
+		// best-effort inclusion of body in error message
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 200))
+		return nil, &url.Error{URL: resp.Request.URL.String(), Op: "LFSObject", Err: fmt.Errorf("LFSObject: http status %d: %s", resp.StatusCode, string(body))}
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
 func (c *Client) httpPost(ctx context.Context, repo api.RepoName, method string, payload interface{}) (resp *http.Response, err error) {
 	span, ctx := opentracing.StartSpanFromContext(ctx, "Client.httpPost")
 	defer func() {
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/lfs"
 )
 
 // ExecRequest is a request to execute a command inside a git repository.
@@ -44,8 +45,44 @@ type RepoUpdateRequest struct {
 	Repo  api.RepoName  `json:"repo"`  // identifying URL for repo
 	URL   string        `json:"url"`   // repo's remote URL
 	Since time.Duration `json:"since"` // debounce interval for queries, used only with request-repo-update
+
+	// CloneOptions configures how the repo is cloned if it doesn't exist. If nil, the repo is
+	// fully cloned. It has no effect on repos that are already cloned.
+	CloneOptions *CloneOptions `json:"cloneOptions,omitempty"`
+
+	// PushMirrorURL, if set, is the URL of a secondary remote that the repo is pushed to
+	// after each successful update. If empty, the repo is not pushed anywhere.
+	PushMirrorURL string `json:"pushMirrorURL,omitempty"`
+}
+
+// CloneOptions configures how gitserver clones a repository. They are intended for very large
+// repositories that would use too much disk space when fully cloned. The objects that are
+// omitted from the clone are fetched from the repository's remote when they are needed.
+type CloneOptions struct {
+	// Depth, if positive, limits the history of each branch and tag in the clone to this
+	// many commits (a shallow clone).
+	Depth int `json:"depth,omitempty"`
+
+	// BlobSizeLimit, if set, omits the contents of files larger than this size (such as
+	// "1m") from the clone (a partial clone). It is ignored if SparsePaths is set.
+	BlobSizeLimit string `json:"blobSizeLimit,omitempty"`
+
+	// SparsePaths, if set, omits the contents of files outside of these paths from the
+	// clone (a partial clone). The contents of files in these paths as of HEAD are fetched
+	// when the repository is cloned and updated.
+	SparsePaths []string `json:"sparsePaths,omitempty"`
 }
 
+// CloneMode describes how a repository is cloned on gitserver.
+type CloneMode string
+
+const (
+	CloneModeFull           CloneMode = "full"            // all history and all file contents
+	CloneModeShallow        CloneMode = "shallow"         // truncated history (CloneOptions.Depth)
+	CloneModePartial        CloneMode = "partial"         // some file contents are omitted
+	CloneModeShallowPartial CloneMode = "shallow-partial" // truncated history and some file contents are omitted
+)
+
 // RepoUpdateResponse returns meta information of the repo enqueued for
 // update.
 //
@@ -104,6 +141,18 @@ type RepoDeleteRequest struct {
 	Repo api.RepoName
 }
 
+// LFSObjectRequest is a request for the content of a file stored with Git LFS.
+// The response body is the content. gitserver responds with status 404 if it
+// can't fetch the content, for example because it is larger than the
+// maxObjectSize of the gitLFS site configuration.
+type LFSObjectRequest struct {
+	// Repo is the repository that contains the LFS pointer.
+	Repo api.RepoName
+
+	// Pointer is the LFS pointer of the file.
+	Pointer lfs.Pointer
+}
+
 // RepoInfoResponse is the response to a repository information request (RepoInfoRequest).
 type RepoInfoResponse struct {
 	URL             string     // this repository's Git remote URL
@@ -117,6 +166,53 @@ type RepoInfoResponse struct {
 	// recloned automatically, so this time is likely to move forward
 	// periodically.
 	CloneTime *time.Time
+
+	// CloneMode is how the repository is cloned, if it has been cloned.
+	CloneMode CloneMode `json:",omitempty"`
+
+	// Size is the disk usage of the repository, if it has been cloned.
+	Size *RepoSize `json:",omitempty"`
+
+	// PushMirror is the status of pushing the repository to its push mirror, if it has one.
+	PushMirror *PushMirrorStatus `json:",omitempty"`
+}
+
+// PushMirrorStatus is the status of pushing a repository to its push mirror.
+type PushMirrorStatus struct {
+	URL           string     // the push mirror's URL, with credentials redacted
+	LastAttempted *time.Time // when the last push was attempted
+	LastPushed    *time.Time // when the last successful push finished
+	LastError     string     // the error of the last push, if it failed
+}
+
+// RepoSize is the disk usage of a repository on gitserver, in bytes.
+type RepoSize struct {
+	PackBytes     int64 // packfiles and their indexes and bitmaps
+	LooseBytes    int64 // loose objects
+	WorktreeBytes int64 // worktrees and checked out files
+	OtherBytes    int64 // everything else, such as refs, config and commit-graph
+
+	// ComputedAt is when the size was computed. Sizes are recomputed by
+	// gitserver's janitor job, so they may be out of date by up to a day.
+	ComputedAt time.Time
+}
+
+// TotalBytes returns the total disk usage of the repository.
+func (s *RepoSize) TotalBytes() int64 {
+	return s.PackBytes + s.LooseBytes + s.WorktreeBytes + s.OtherBytes
+}
+
+// RepoStats is the disk usage of a single repository, as returned by the
+// /repos-stats endpoint.
+type RepoStats struct {
+	Repo api.RepoName
+	Size RepoSize
+}
+
+// ReposStatsResponse is the response of the /repos-stats endpoint. It lists
+// the repositories on a gitserver with the largest disk usage first.
+type ReposStatsResponse struct {
+	Repos []*RepoStats
 }
 
 // CreateCommitFromPatchRequest is the request information needed for creating
@@ -148,3 +244,11 @@ type CreatePatchFromPatchResponse struct {
 	// Rev is the tag that the staging object can be found at
 	Rev string
 }
+
+// RepoTransferRequest is a request to a gitserver to take over a repository from another
+// gitserver (the peer) by transferring the peer's clone, instead of recloning the repository
+// from its code host.
+type RepoTransferRequest struct {
+	Repo api.RepoName `json:"repo"`
+	From string       `json:"from"` // the address of the peer gitserver
+}
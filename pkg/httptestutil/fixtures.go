@@ -0,0 +1,37 @@
+package httptestutil
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// NewFixtureServer starts a server that responds to each request with the contents of the file in
+// the testdata directory named by fixture, or with 404 Not Found if fixture returns "".
+//
+// If recordedURL is not empty, its occurrences in the files are replaced with the server's URL, so
+// that links in the recorded responses point to the server. The caller must close the server.
+func NewFixtureServer(t testing.TB, recordedURL string, fixture func(r *http.Request) string) *httptest.Server {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := fixture(r)
+		if name == "" {
+			http.NotFound(w, r)
+			return
+		}
+		b, err := ioutil.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Error(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if recordedURL != "" {
+			b = bytes.Replace(b, []byte(recordedURL), []byte(srv.URL), -1)
+		}
+		w.Write(b)
+	}))
+	return srv
+}
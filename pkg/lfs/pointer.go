@@ -0,0 +1,79 @@
+// Package lfs parses Git LFS pointer files. Git LFS stores a pointer file in
+// the repository in place of the content of a large file, and the content in
+// a separate object store. See
+// https://github.com/git-lfs/git-lfs/blob/master/docs/spec.md.
+package lfs
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+)
+
+// MaxPointerSize is the maximum size of a pointer file. Larger files are
+// never pointers.
+const MaxPointerSize = 1024
+
+// Pointer is a Git LFS pointer file.
+type Pointer struct {
+	// OID is the SHA-256 hash (in hex) of the content of the file.
+	OID string `json:"oid"`
+
+	// Size is the size of the content of the file in bytes.
+	Size int64 `json:"size"`
+}
+
+var (
+	versionLines = [][]byte{
+		[]byte("version https://git-lfs.github.com/spec/v1"),
+		[]byte("version https://hawser.github.com/spec/v1"), // pre-release version of the spec
+	}
+	oidPattern = regexp.MustCompile(`^sha256:([0-9a-f]{64})$`)
+)
+
+// ParsePointer parses the content of a file as a pointer. It reports whether
+// the file is a pointer.
+func ParsePointer(b []byte) (*Pointer, bool) {
+	if len(b) > MaxPointerSize {
+		return nil, false
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(b, []byte("\n")), []byte("\n"))
+	isVersion := false
+	for _, v := range versionLines {
+		if bytes.Equal(lines[0], v) {
+			isVersion = true
+		}
+	}
+	if !isVersion {
+		return nil, false
+	}
+
+	var p Pointer
+	hasSize := false
+	for _, line := range lines[1:] {
+		parts := bytes.SplitN(line, []byte(" "), 2)
+		if len(parts) != 2 {
+			return nil, false
+		}
+		switch string(parts[0]) {
+		case "oid":
+			m := oidPattern.FindSubmatch(parts[1])
+			if m == nil {
+				return nil, false
+			}
+			p.OID = string(m[1])
+		case "size":
+			size, err := strconv.ParseInt(string(parts[1]), 10, 64)
+			if err != nil || size < 0 {
+				return nil, false
+			}
+			p.Size = size
+			hasSize = true
+		}
+	}
+	if p.OID == "" || !hasSize {
+		return nil, false
+	}
+	return &p, true
+}
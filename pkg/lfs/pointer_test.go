@@ -0,0 +1,34 @@
+package lfs
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePointer(t *testing.T) {
+	const oid = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+	tests := map[string]*Pointer{
+		"version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 12345\n":                           {OID: oid, Size: 12345},
+		"version https://git-lfs.github.com/spec/v1\next-0-foo sha256:" + oid + "\noid sha256:" + oid + "\nsize 0\n": {OID: oid, Size: 0},
+		"version https://hawser.github.com/spec/v1\noid sha256:" + oid + "\nsize 1\n":                                {OID: oid, Size: 1},
+
+		"":              nil,
+		"hello world\n": nil,
+		"version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\n":                                               nil,
+		"version https://git-lfs.github.com/spec/v1\nsize 12345\n":                                                           nil,
+		"version https://git-lfs.github.com/spec/v1\noid sha1:" + oid[:40] + "\nsize 12345\n":                                nil,
+		"version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize -1\n":                                      nil,
+		"version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 1\n" + strings.Repeat("x", MaxPointerSize): nil,
+	}
+	for input, want := range tests {
+		got, ok := ParsePointer([]byte(input))
+		if ok != (want != nil) {
+			t.Errorf("ParsePointer(%q): got ok %v, want %v", input, ok, want != nil)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParsePointer(%q): got %+v, want %+v", input, got, want)
+		}
+	}
+}
@@ -147,10 +147,30 @@ func (c *Client) EnqueueRepoUpdate(ctx context.Context, repo gitserver.Repo) err
 		return MockEnqueueRepoUpdate(ctx, repo)
 	}
 
-	req := &protocol.RepoUpdateRequest{
+	return c.enqueueRepoUpdate(ctx, &protocol.RepoUpdateRequest{
 		Repo: repo.Name,
 		URL:  repo.URL,
+	})
+}
+
+// MockEnqueueWebhookRepoUpdate mocks (*Client).EnqueueWebhookRepoUpdate for tests.
+var MockEnqueueWebhookRepoUpdate func(ctx context.Context, repo gitserver.Repo) error
+
+// EnqueueWebhookRepoUpdate is like EnqueueRepoUpdate, but for updates that are requested by
+// code host webhooks. These are queued behind the updates that are requested by users.
+func (c *Client) EnqueueWebhookRepoUpdate(ctx context.Context, repo gitserver.Repo) error {
+	if MockEnqueueWebhookRepoUpdate != nil {
+		return MockEnqueueWebhookRepoUpdate(ctx, repo)
 	}
+
+	return c.enqueueRepoUpdate(ctx, &protocol.RepoUpdateRequest{
+		Repo:    repo.Name,
+		URL:     repo.URL,
+		Trigger: protocol.RepoUpdateTriggerWebhook,
+	})
+}
+
+func (c *Client) enqueueRepoUpdate(ctx context.Context, req *protocol.RepoUpdateRequest) error {
 	resp, err := c.httpPost(ctx, "enqueue-repo-update", req)
 	if err != nil {
 		return err
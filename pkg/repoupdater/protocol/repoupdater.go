@@ -29,8 +29,39 @@ type RepoQueueState struct {
 	Index    int
 	Total    int
 	Updating bool
+
+	// Priority is the priority of the update: "high" for updates requested by users,
+	// "medium" for updates requested by code host webhooks and "low" for scheduled updates.
+	Priority string
+
+	// ExternalServiceID is the ID of the external service of the repository, or 0 if it
+	// is unknown. The repository's update budget is that of its external service.
+	ExternalServiceID int64 `json:",omitempty"`
+
+	// ExternalServiceUpdating is the number of repositories of the external service that
+	// are updating.
+	ExternalServiceUpdating int
+
+	// ExternalServiceMaxConcurrentUpdates is the maximum number of repositories of the
+	// external service that are updated concurrently, or 0 if there is no limit.
+	ExternalServiceMaxConcurrentUpdates int `json:",omitempty"`
+
+	// WaitingFor is the reason why the update is held back, if it is held back by the
+	// update budget of the external service (one of the RepoQueueWaitingFor* constants).
+	WaitingFor string `json:",omitempty"`
 }
 
+// The reasons why a queued update is held back (RepoQueueState.WaitingFor).
+const (
+	// RepoQueueWaitingForExternalServiceConcurrency means that the external service
+	// already has the maximum number of concurrent updates in progress.
+	RepoQueueWaitingForExternalServiceConcurrency = "externalServiceConcurrency"
+
+	// RepoQueueWaitingForExternalServiceRateLimit means that the maximum number of
+	// updates per minute has been reached for the external service.
+	RepoQueueWaitingForExternalServiceRateLimit = "externalServiceRateLimit"
+)
+
 // RepoLookupArgs is a request for information about a repository on repoupdater.
 //
 // Exactly one of Repo and ExternalRepo should be set.
@@ -119,8 +150,24 @@ type RepoUpdateRequest struct {
 
 	// URL is the repository's Git remote URL (from which to clone or update).
 	URL string `json:"url"`
+
+	// Trigger is what caused the update request. It determines the priority of the update.
+	// The zero value means that the update was requested by a user.
+	Trigger RepoUpdateTrigger `json:"trigger,omitempty"`
 }
 
+// RepoUpdateTrigger describes what caused a repository update request.
+type RepoUpdateTrigger string
+
+const (
+	// RepoUpdateTriggerUser is an update requested by a user (or on behalf of a user, such
+	// as when a user visits a repository).
+	RepoUpdateTriggerUser RepoUpdateTrigger = ""
+
+	// RepoUpdateTriggerWebhook is an update requested by a code host webhook.
+	RepoUpdateTriggerWebhook RepoUpdateTrigger = "webhook"
+)
+
 // ExternalServiceSyncRequest is a request to sync a specific external service eagerly.
 //
 // The FrontendAPI is one of the issuers of this request. It does so when creating or
@@ -1,10 +1,12 @@
 package search
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/search/query"
@@ -26,15 +28,42 @@ func (f *FileMatch) IsPathMatch() bool {
 	return len(f.LineMatches) == 0
 }
 
-// LineMatch holds the matches within a single line in a file.
+// LineMatch holds the matches within a single line in a file. If a match
+// spans multiple lines, Line contains all the lines it spans.
 type LineMatch struct {
 	// The line in which a match was found.
 	Line       []byte
 	LineNumber int
 
+	// EndLineNumber is the line number of the last line in Line. It is only
+	// different to LineNumber if a match spans multiple lines.
+	EndLineNumber int
+
 	LineFragments []LineFragmentMatch
 }
 
+// Range returns the range in the file of the fragment f of l.
+func (l *LineMatch) Range(f LineFragmentMatch) Range {
+	return Range{
+		Start: l.position(f.LineOffset),
+		End:   l.position(f.LineOffset + f.MatchLength),
+	}
+}
+
+// position returns the position in the file of the byte offset within
+// l.Line.
+func (l *LineMatch) position(offset int) Position {
+	if offset > len(l.Line) {
+		offset = len(l.Line)
+	}
+	before := l.Line[:offset]
+	lineStart := bytes.LastIndexByte(before, '\n') + 1
+	return Position{
+		Line:      l.LineNumber + bytes.Count(before, []byte{'\n'}),
+		Character: utf8.RuneCount(before[lineStart:]),
+	}
+}
+
 // LineFragmentMatch a segment of matching text within a line.
 type LineFragmentMatch struct {
 	// Offset within the line, in bytes.
@@ -44,6 +73,22 @@ type LineFragmentMatch struct {
 	MatchLength int
 }
 
+// Range is a range in a file. Start is inclusive and End is exclusive.
+type Range struct {
+	Start, End Position
+}
+
+// Position is a position in a file.
+type Position struct {
+	// Line is the line number, using the same numbering as
+	// LineMatch.LineNumber.
+	Line int
+
+	// Character is the offset within the line, measured in characters
+	// (not bytes) like protocol.Position.Character.
+	Character int
+}
+
 // Repository is a repository at a commit.
 type Repository struct {
 	Name       api.RepoName
@@ -0,0 +1,33 @@
+package search
+
+import "testing"
+
+func TestLineMatch_Range(t *testing.T) {
+	l := LineMatch{
+		Line:          []byte("func foo(\n\tctx contéxt.Context) {"),
+		LineNumber:    3,
+		EndLineNumber: 4,
+	}
+	cases := []struct {
+		Fragment LineFragmentMatch
+		Want     Range
+	}{{
+		Fragment: LineFragmentMatch{LineOffset: 5, MatchLength: 3},
+		Want:     Range{Start: Position{Line: 3, Character: 5}, End: Position{Line: 3, Character: 8}},
+	}, {
+		Fragment: LineFragmentMatch{LineOffset: 0, MatchLength: 14},
+		Want:     Range{Start: Position{Line: 3, Character: 0}, End: Position{Line: 4, Character: 4}},
+	}, {
+		Fragment: LineFragmentMatch{LineOffset: 9, MatchLength: 1},
+		Want:     Range{Start: Position{Line: 3, Character: 9}, End: Position{Line: 4, Character: 0}},
+	}, {
+		// Columns are measured in characters, not bytes.
+		Fragment: LineFragmentMatch{LineOffset: 23, MatchLength: 1},
+		Want:     Range{Start: Position{Line: 4, Character: 12}, End: Position{Line: 4, Character: 13}},
+	}}
+	for _, c := range cases {
+		if got := l.Range(c.Fragment); got != c.Want {
+			t.Errorf("Range(%+v) = %+v, want %+v", c.Fragment, got, c.Want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package backend
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"math/rand"
@@ -622,9 +623,11 @@ func mapZoektFileMatch(zf []zoekt.FileMatch) []search.FileMatch {
 				}
 			}
 
+			// Zoekt extends Line to include every line spanned by a match.
 			lines = append(lines, search.LineMatch{
 				Line:          lm.Line,
 				LineNumber:    lm.LineNumber,
+				EndLineNumber: lm.LineNumber + bytes.Count(lm.Line, []byte{'\n'}),
 				LineFragments: frags,
 			})
 		}
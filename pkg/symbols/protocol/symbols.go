@@ -18,6 +18,10 @@ type SearchArgs struct {
 	// IsRegExp if true will treat the Pattern as a regular expression.
 	IsRegExp bool
 
+	// Match is how Query is matched against symbol names when IsRegExp is
+	// false. The default is to match the names that contain Query.
+	Match MatchMode
+
 	// IsCaseSensitive if false will ignore the case of query and file pattern
 	// when finding matches.
 	IsCaseSensitive bool
@@ -35,10 +39,32 @@ type SearchArgs struct {
 	// need to match to get included in the result
 	ExcludePattern string
 
+	// Kinds, if non-empty, restricts the result to symbols of one of these
+	// kinds (such as "function" or "struct"), ignoring case.
+	Kinds []string
+
+	// Languages, if non-empty, restricts the result to symbols in one of
+	// these languages (such as "Go"), ignoring case.
+	Languages []string
+
+	// Parent, if non-empty, is a regular expression that the name of a
+	// symbol's container (such as its class or type) must match.
+	Parent string
+
 	// First indicates that only the first n symbols should be returned.
 	First int
 }
 
+// MatchMode is how the query of a search is matched against symbol names.
+type MatchMode string
+
+const (
+	MatchSubstring MatchMode = ""       // names that contain the query
+	MatchExact     MatchMode = "exact"  // names equal to the query
+	MatchPrefix    MatchMode = "prefix" // names that begin with the query
+	MatchFuzzy     MatchMode = "fuzzy"  // names similar to the query (sharing most of its trigrams), best match first
+)
+
 // SearchResult is the result of a search on the symbols service.
 type SearchResult struct {
 	Symbols []Symbol // code symbols
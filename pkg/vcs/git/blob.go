@@ -10,6 +10,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sourcegraph/sourcegraph/pkg/api"
 	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/lfs"
 	"github.com/sourcegraph/sourcegraph/pkg/vcs/util"
 )
 
@@ -28,6 +29,18 @@ func ReadFile(ctx context.Context, repo gitserver.Repo, commit api.CommitID, nam
 	if err != nil {
 		return nil, err
 	}
+
+	// Return the content of a file stored with Git LFS instead of its LFS
+	// pointer if gitserver can fetch it.
+	if p, ok := lfs.ParsePointer(b); ok {
+		content, err := gitserver.DefaultClient.LFSObject(ctx, repo.Name, *p)
+		if err == nil {
+			return content, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
 	return b, nil
 }
 
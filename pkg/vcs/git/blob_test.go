@@ -0,0 +1,54 @@
+package git_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+	"github.com/sourcegraph/sourcegraph/pkg/gitserver"
+	"github.com/sourcegraph/sourcegraph/pkg/lfs"
+	"github.com/sourcegraph/sourcegraph/pkg/vcs/git"
+)
+
+func TestReadFile_lfs(t *testing.T) {
+	const (
+		fetchedOID = "4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+		missingOID = "b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c"
+	)
+	pointer := func(oid string) string {
+		return "version https://git-lfs.github.com/spec/v1\noid sha256:" + oid + "\nsize 6\n"
+	}
+	repo := makeGitRepository(t,
+		"printf '"+pointer(fetchedOID)+"' > fetched.txt",
+		"printf '"+pointer(missingOID)+"' > missing.txt",
+		"git add fetched.txt missing.txt",
+		"GIT_COMMITTER_NAME=a GIT_COMMITTER_EMAIL=a@a.com GIT_COMMITTER_DATE=2006-01-02T15:04:05Z git commit -m lfs --author='a <a@a.com>' --date 2006-01-02T15:04:05Z",
+	)
+	commitID, err := git.ResolveRevision(ctx, repo, nil, "master", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gitserver.MockLFSObject = func(_ api.RepoName, p lfs.Pointer) ([]byte, error) {
+		if p.OID == fetchedOID {
+			return []byte("hello\n"), nil
+		}
+		return nil, &os.PathError{Op: "LFSObject", Path: p.OID, Err: os.ErrNotExist}
+	}
+	defer func() { gitserver.MockLFSObject = nil }()
+
+	// The content of an LFS object that gitserver can fetch is returned
+	// instead of its pointer. Otherwise the pointer is returned.
+	for name, want := range map[string]string{
+		"fetched.txt": "hello\n",
+		"missing.txt": pointer(missingOID),
+	} {
+		got, err := git.ReadFile(ctx, repo, commitID, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
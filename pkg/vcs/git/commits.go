@@ -22,6 +22,8 @@ type Commit struct {
 	Message   string       `json:"Message,omitempty"`
 	// Parents are the commit IDs of this commit's parent commits.
 	Parents []api.CommitID `json:"Parents,omitempty"`
+	// Signature is the GPG or SSH signature of the commit, or nil if the commit is not signed.
+	Signature *CommitSignature `json:"Signature,omitempty"`
 }
 
 type Signature struct {
@@ -30,6 +32,47 @@ type Signature struct {
 	Date  time.Time `json:"Date"`
 }
 
+// CommitSignature describes the GPG or SSH signature of a commit, as verified by gitserver against
+// the trusted signing keys in the site configuration.
+type CommitSignature struct {
+	Status SignatureStatus `json:"Status"`
+	// KeyID is the ID of the GPG key, or the fingerprint of the SSH key, that made the signature.
+	KeyID string `json:"KeyID,omitempty"`
+}
+
+// SignatureStatus is the result of verifying a commit signature.
+type SignatureStatus string
+
+const (
+	// SignatureVerified is a good signature made by a trusted key.
+	SignatureVerified SignatureStatus = "VERIFIED"
+	// SignatureUntrustedKey is a good signature made by an SSH key that is not trusted.
+	SignatureUntrustedKey SignatureStatus = "UNTRUSTED_KEY"
+	// SignatureUnknownKey is a signature that can't be checked, usually because it was made by a
+	// GPG key that is not trusted.
+	SignatureUnknownKey SignatureStatus = "UNKNOWN_KEY"
+	// SignatureBad is a signature that does not match the commit.
+	SignatureBad SignatureStatus = "BAD"
+	// SignatureExpired is a good signature that has expired.
+	SignatureExpired SignatureStatus = "EXPIRED"
+	// SignatureExpiredKey is a good signature made by a key that has expired.
+	SignatureExpiredKey SignatureStatus = "EXPIRED_KEY"
+	// SignatureRevokedKey is a good signature made by a key that has been revoked.
+	SignatureRevokedKey SignatureStatus = "REVOKED_KEY"
+)
+
+// signatureStatuses maps the signature verification status letters of `git log --format=%G?` to
+// signature statuses. The letter N (no signature) is not included.
+var signatureStatuses = map[string]SignatureStatus{
+	"G": SignatureVerified,
+	"U": SignatureUntrustedKey,
+	"E": SignatureUnknownKey,
+	"B": SignatureBad,
+	"X": SignatureExpired,
+	"Y": SignatureExpiredKey,
+	"R": SignatureRevokedKey,
+}
+
 // CommitsOptions specifies options for (Repository).Commits (Repository).CommitCount.
 type CommitsOptions struct {
 	Range string // commit range (revspec, "A..B", "A...B", etc.)
@@ -43,6 +86,10 @@ type CommitsOptions struct {
 	After  string // include only commits after this date
 
 	Path string // only commits modifying the given path are selected (optional)
+
+	// Signatures includes the commit signatures (Commit.Signature). This is slow, because git
+	// verifies the signature of each commit.
+	Signatures bool
 }
 
 // logEntryPattern is the regexp pattern that matches entries in the output of the `git shortlog
@@ -106,7 +153,11 @@ func isInvalidRevisionRangeError(output, obj string) bool {
 //
 // The caller is responsible for doing checkSpecArgSafety on opt.Head and opt.Base.
 func commitLog(ctx context.Context, repo gitserver.Repo, opt CommitsOptions) ([]*Commit, error) {
-	args, err := commitLogArgs([]string{"log", logFormatWithoutRefs}, opt)
+	format := logFormatWithoutRefs
+	if opt.Signatures {
+		format = logFormatWithoutRefsWithSignatures
+	}
+	args, err := commitLogArgs([]string{"log", format}, opt)
 	if err != nil {
 		return nil, err
 	}
@@ -128,7 +179,7 @@ func commitLog(ctx context.Context, repo gitserver.Repo, opt CommitsOptions) ([]
 	for len(data) > 0 {
 		var commit *Commit
 		var err error
-		commit, _, data, err = parseCommitFromLog(data)
+		commit, _, data, err = parseCommitFromLog(data, opt.Signatures)
 		if err != nil {
 			return nil, err
 		}
@@ -200,21 +251,32 @@ func CommitCount(ctx context.Context, repo gitserver.Repo, opt CommitsOptions) (
 }
 
 const (
-	partsPerCommit = 10 // number of \x00-separated fields per commit
+	partsPerCommit              = 10 // number of \x00-separated fields per commit
+	partsPerCommitWithSignature = 12 // number of \x00-separated fields per commit with signatures
 
 	// include refs (slow on repos with many refs)
 	logFormatWithRefs = "--format=format:%H%x00%D%x00%aN%x00%aE%x00%at%x00%cN%x00%cE%x00%ct%x00%B%x00%P%x00"
 
 	// don't include refs (faster, should be used if refs are not needed)
 	logFormatWithoutRefs = "--format=format:%H%x00%x00%aN%x00%aE%x00%at%x00%cN%x00%cE%x00%ct%x00%B%x00%P%x00"
+
+	// The formats with signatures additionally include the signature verification status and key.
+	// They are slow, because git verifies the signature of each commit.
+	logFormatWithRefsWithSignatures    = logFormatWithRefs + "%G?%x00%GK%x00"
+	logFormatWithoutRefsWithSignatures = logFormatWithoutRefs + "%G?%x00%GK%x00"
 )
 
 // parseCommitFromLog parses the next commit from data and returns the commit and the remaining
 // data. The data arg is a byte array that contains NUL-separated log fields as formatted by
-// logFormatFlag.
-func parseCommitFromLog(data []byte) (commit *Commit, refs []string, rest []byte, err error) {
-	parts := bytes.SplitN(data, []byte{'\x00'}, partsPerCommit+1)
-	if len(parts) < partsPerCommit {
+// logFormatFlag. If withSignature is true, the log was formatted by one of the formats with
+// signatures.
+func parseCommitFromLog(data []byte, withSignature bool) (commit *Commit, refs []string, rest []byte, err error) {
+	n := partsPerCommit
+	if withSignature {
+		n = partsPerCommitWithSignature
+	}
+	parts := bytes.SplitN(data, []byte{'\x00'}, n+1)
+	if len(parts) < n {
 		return nil, nil, nil, fmt.Errorf("invalid commit log entry: %q", parts)
 	}
 
@@ -245,16 +307,24 @@ func parseCommitFromLog(data []byte) (commit *Commit, refs []string, rest []byte
 		refs = strings.Split(string(parts[1]), ", ")
 	}
 
+	var signature *CommitSignature
+	if withSignature {
+		if status, ok := signatureStatuses[string(parts[10])]; ok {
+			signature = &CommitSignature{Status: status, KeyID: string(parts[11])}
+		}
+	}
+
 	commit = &Commit{
 		ID:        commitID,
 		Author:    Signature{Name: string(parts[2]), Email: string(parts[3]), Date: time.Unix(authorTime, 0).UTC()},
 		Committer: &Signature{Name: string(parts[5]), Email: string(parts[6]), Date: time.Unix(committerTime, 0).UTC()},
 		Message:   string(bytes.TrimSuffix(parts[8], []byte{'\n'})),
 		Parents:   parents,
+		Signature: signature,
 	}
 
-	if len(parts) == partsPerCommit+1 {
-		rest = parts[10]
+	if len(parts) == n+1 {
+		rest = parts[n]
 	}
 
 	return commit, refs, rest, nil
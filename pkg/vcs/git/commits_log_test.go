@@ -0,0 +1,53 @@
+package git
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/pkg/api"
+)
+
+func TestParseCommitFromLog_signature(t *testing.T) {
+	entry := func(id, status, keyID string) string {
+		return strings.Join([]string{id, "", "a", "a@a.com", "1136214245", "c", "c@c.com", "1136214246", "msg\n", "", status, keyID, ""}, "\x00")
+	}
+	data := []byte(entry("a1", "G", "235AFD7C2D5576D2") + "\n" + entry("b2", "N", "") + "\n" + entry("c3", "U", "SHA256:rls5YrfxF6XaqJdvBW3M8XIeOx9N+VVACgtWEFFGPI4"))
+
+	want := map[api.CommitID]*CommitSignature{
+		"a1": {Status: SignatureVerified, KeyID: "235AFD7C2D5576D2"},
+		"b2": nil,
+		"c3": {Status: SignatureUntrustedKey, KeyID: "SHA256:rls5YrfxF6XaqJdvBW3M8XIeOx9N+VVACgtWEFFGPI4"},
+	}
+	var n int
+	for len(data) > 0 {
+		var commit *Commit
+		var err error
+		commit, _, data, err = parseCommitFromLog(data, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n++
+		if !reflect.DeepEqual(commit.Signature, want[commit.ID]) {
+			t.Errorf("%s: got signature %+v, want %+v", commit.ID, commit.Signature, want[commit.ID])
+		}
+		if want := time.Unix(1136214246, 0).UTC(); !commit.Committer.Date.Equal(want) {
+			t.Errorf("%s: got committer date %s, want %s", commit.ID, commit.Committer.Date, want)
+		}
+	}
+	if n != len(want) {
+		t.Errorf("got %d commits, want %d", n, len(want))
+	}
+}
+
+func TestParseCommitFromLog_withoutSignature(t *testing.T) {
+	data := []byte(strings.Join([]string{"a1", "", "a", "a@a.com", "1136214245", "c", "c@c.com", "1136214246", "msg\n", "p1", ""}, "\x00"))
+	commit, _, rest, err := parseCommitFromLog(data, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.ID != "a1" || commit.Signature != nil || !reflect.DeepEqual(commit.Parents, []api.CommitID{"p1"}) || len(rest) != 0 {
+		t.Errorf("got commit %+v (rest %q)", commit, rest)
+	}
+}
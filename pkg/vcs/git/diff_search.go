@@ -59,6 +59,10 @@ type RawLogDiffSearchOptions struct {
 	// Paths specifies the paths to include/exclude.
 	Paths PathOptions
 
+	// Signatures includes the commit signatures (Commit.Signature) in the results. This is slow,
+	// because git verifies the signature of each commit. It is ignored if FormatArgs is set.
+	Signatures bool
+
 	// FormatArgs is a list of format args that are passed to the `git log` command.
 	// Because the output is parsed, it is expected to be in a known format. If the
 	// FormatArgs does not match one of the server's expected values, the operation
@@ -111,6 +115,8 @@ var (
 	validRawLogDiffSearchFormatArgs = [][]string{
 		{"--no-merges", "-z", "--decorate=full", "--patch", logFormatWithRefs},
 		{"--no-merges", "-z", "--decorate=full", logFormatWithRefs},
+		{"--no-merges", "-z", "--decorate=full", "--patch", logFormatWithRefsWithSignatures},
+		{"--no-merges", "-z", "--decorate=full", logFormatWithRefsWithSignatures},
 	}
 )
 
@@ -150,15 +156,19 @@ func RawLogDiffSearch(ctx context.Context, repo gitserver.Repo, opt RawLogDiffSe
 	}()
 
 	if opt.FormatArgs == nil {
+		i := 1 // without --patch
 		if opt.Diff {
-			opt.FormatArgs = validRawLogDiffSearchFormatArgs[0] // with --patch
-		} else {
-			opt.FormatArgs = validRawLogDiffSearchFormatArgs[1] // without --patch
+			i = 0 // with --patch
+		}
+		if opt.Signatures {
+			i += 2
 		}
+		opt.FormatArgs = validRawLogDiffSearchFormatArgs[i]
 	}
 	if opt.FormatArgs != nil && !isValidRawLogDiffSearchFormatArgs(opt.FormatArgs) {
 		return nil, false, fmt.Errorf("invalid FormatArgs: %q", opt.FormatArgs)
 	}
+	withSignatures := opt.FormatArgs[len(opt.FormatArgs)-1] == logFormatWithRefsWithSignatures
 	for _, arg := range opt.Args {
 		if arg == "--" {
 			return nil, false, fmt.Errorf("invalid Args (must not contain \"--\" element): %q", opt.Args)
@@ -356,7 +366,7 @@ func RawLogDiffSearch(ctx context.Context, repo gitserver.Repo, opt RawLogDiffSe
 		var commit *Commit
 		var refs []string
 		var err error
-		commit, refs, data, err = parseCommitFromLog(data)
+		commit, refs, data, err = parseCommitFromLog(data, withSignatures)
 		if err != nil {
 			if !complete {
 				// Partial data can yield parse errors, but we still want to return what we have.
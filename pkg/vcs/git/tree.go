@@ -270,8 +270,15 @@ func lsTreeUncached(ctx context.Context, repo gitserver.Repo, commit api.CommitI
 					return nil, fmt.Errorf("error parsing .gitmodules: %s", err)
 				}
 
-				submodule.Path = cfg.Section("submodule").Subsection(name).Option("path")
-				submodule.URL = cfg.Section("submodule").Subsection(name).Option("url")
+				// The name of a submodule is usually, but not necessarily, its
+				// path.
+				for _, ss := range cfg.Section("submodule").Subsections {
+					if ss.Option("path") == name {
+						submodule.Path = ss.Option("path")
+						submodule.URL = ss.Option("url")
+						break
+					}
+				}
 			}
 			submodule.CommitID = api.CommitID(oid)
 			sys = submodule
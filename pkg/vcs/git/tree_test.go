@@ -394,6 +394,12 @@ func TestRepository_FileSystem_gitSubmodules(t *testing.T) {
 		"git cmd": {
 			repo: makeGitRepository(t, gitCommands...),
 		},
+		"submodule name differs from path": {
+			repo: makeGitRepository(t,
+				"git submodule add --name othername "+filepath.ToSlash(submodDir)+" submod",
+				gitCommands[1],
+			),
+		},
 	}
 
 	for label, test := range tests {
@@ -90,7 +90,7 @@ const CriticalSchemaJSON = `{
         "properties": {
           "type": {
             "type": "string",
-            "enum": ["builtin", "saml", "openidconnect", "http-header", "github", "gitlab"]
+            "enum": ["builtin", "saml", "openidconnect", "http-header", "github", "gitlab", "ldap"]
           }
         },
         "oneOf": [
@@ -99,7 +99,8 @@ const CriticalSchemaJSON = `{
           { "$ref": "#/definitions/OpenIDConnectAuthProvider" },
           { "$ref": "#/definitions/HTTPHeaderAuthProvider" },
           { "$ref": "#/definitions/GitHubAuthProvider" },
-          { "$ref": "#/definitions/GitLabAuthProvider" }
+          { "$ref": "#/definitions/GitLabAuthProvider" },
+          { "$ref": "#/definitions/LDAPAuthProvider" }
         ],
         "!go": {
           "taggedUnionType": true
@@ -294,6 +295,113 @@ const CriticalSchemaJSON = `{
         }
       }
     },
+    "LDAPAuthProvider": {
+      "description":
+        "Configures the LDAP authentication provider (which authenticates users with their username and password against an LDAP directory server, such as OpenLDAP or Active Directory).",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["type", "url", "userSearchBase"],
+      "properties": {
+        "type": {
+          "type": "string",
+          "const": "ldap"
+        },
+        "url": {
+          "description": "URL of the LDAP server. The scheme must be ldaps:// (for LDAP over TLS) or ldap:// (with startTLS set).",
+          "type": "string",
+          "pattern": "^ldaps?://",
+          "examples": ["ldaps://ldap.example.com", "ldap://ldap.example.com:389"]
+        },
+        "startTLS": {
+          "description":
+            "Upgrade the connection to TLS with the StartTLS operation before sending any credentials. Required if the url scheme is ldap:// (otherwise passwords would be sent in plaintext). Not allowed with ldaps://.",
+          "type": "boolean",
+          "default": false
+        },
+        "certificate": {
+          "description":
+            "TLS certificate of the LDAP server (or of the CA that issued it), in PEM format. Only needed if the server's certificate is not signed by a trusted CA.",
+          "type": "string",
+          "pattern": "^-----BEGIN CERTIFICATE-----\n"
+        },
+        "bindDN": {
+          "description":
+            "The distinguished name of the service account used to search for users and groups. If empty, searches are performed anonymously.",
+          "type": "string",
+          "examples": ["cn=sourcegraph,ou=services,dc=example,dc=com"]
+        },
+        "bindPassword": {
+          "description": "The password of the service account specified in ` + "`" + `bindDN` + "`" + `.",
+          "type": "string"
+        },
+        "userSearchBase": {
+          "description": "The distinguished name of the entry under which users are searched for.",
+          "type": "string",
+          "examples": ["ou=people,dc=example,dc=com"]
+        },
+        "userSearchFilter": {
+          "description":
+            "The LDAP filter used to find the entry of the user who is signing in. The placeholder {username} is replaced with the username they entered (escaped for use in a filter). The filter must match exactly 1 entry.",
+          "type": "string",
+          "default": "(uid={username})",
+          "examples": ["(&(objectClass=user)(sAMAccountName={username}))"]
+        },
+        "usernameAttribute": {
+          "description": "The attribute of the user's entry whose value is used as their Sourcegraph username.",
+          "type": "string",
+          "default": "uid",
+          "examples": ["sAMAccountName"]
+        },
+        "emailAttribute": {
+          "description": "The attribute of the user's entry whose value is used as their email address.",
+          "type": "string",
+          "default": "mail"
+        },
+        "displayNameAttribute": {
+          "description": "The attribute of the user's entry whose value is used as their display name.",
+          "type": "string",
+          "default": "cn",
+          "examples": ["displayName"]
+        },
+        "groupSearchBase": {
+          "description":
+            "The distinguished name of the entry under which the groups of the user who is signing in are searched for. Defaults to ` + "`" + `userSearchBase` + "`" + `. Groups are only searched for if ` + "`" + `groupOrgs` + "`" + ` is set.",
+          "type": "string",
+          "examples": ["ou=groups,dc=example,dc=com"]
+        },
+        "groupSearchFilter": {
+          "description":
+            "The LDAP filter used to find the groups of the user who is signing in. The placeholders {dn} and {username} are replaced with the distinguished name of the user's entry and the username they entered (escaped for use in a filter).",
+          "type": "string",
+          "default": "(member={dn})",
+          "examples": ["(memberUid={username})"]
+        },
+        "groupOrgs": {
+          "description":
+            "Maps LDAP groups to Sourcegraph organizations. Each time a user signs in, they are added to the organizations mapped to the groups they are a member of, and removed from the other organizations listed here. Organizations that are not listed here are not changed.",
+          "type": "array",
+          "items": { "$ref": "#/definitions/LDAPGroupOrg" }
+        },
+        "displayName": { "$ref": "#/definitions/AuthProviderCommon/properties/displayName" }
+      }
+    },
+    "LDAPGroupOrg": {
+      "description": "Maps an LDAP group to a Sourcegraph organization.",
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["group", "org"],
+      "properties": {
+        "group": {
+          "description": "The distinguished name of the LDAP group.",
+          "type": "string",
+          "examples": ["cn=engineering,ou=groups,dc=example,dc=com"]
+        },
+        "org": {
+          "description": "The name of the Sourcegraph organization. The organization must exist.",
+          "type": "string"
+        }
+      }
+    },
     "GitHubAuthProvider": {
       "description":
         "Configures the GitHub (or GitHub Enterprise) OAuth authentication provider for SSO. In addition to specifying this configuration object, you must also create a OAuth App on your GitHub instance: https://developer.github.com/apps/building-oauth-apps/creating-an-oauth-app/. When a user signs into Sourcegraph or links their GitHub account to their existing Sourcegraph account, GitHub will prompt the user for the repo scope.",
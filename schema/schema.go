@@ -9,11 +9,14 @@ import (
 )
 
 type AWSCodeCommitConnection struct {
-	AccessKeyID                 string `json:"accessKeyID"`
-	InitialRepositoryEnablement bool   `json:"initialRepositoryEnablement,omitempty"`
-	Region                      string `json:"region"`
-	RepositoryPathPattern       string `json:"repositoryPathPattern,omitempty"`
-	SecretAccessKey             string `json:"secretAccessKey"`
+	AccessKeyID                 string             `json:"accessKeyID"`
+	CloneOptions                []*GitCloneOptions `json:"cloneOptions,omitempty"`
+	InitialRepositoryEnablement bool               `json:"initialRepositoryEnablement,omitempty"`
+	PushMirror                  *PushMirror        `json:"pushMirror,omitempty"`
+	Region                      string             `json:"region"`
+	RepositoryPathPattern       string             `json:"repositoryPathPattern,omitempty"`
+	SecretAccessKey             string             `json:"secretAccessKey"`
+	UpdateLimits                *UpdateLimits      `json:"updateLimits,omitempty"`
 }
 
 // AuthAccessTokens description: Settings for access tokens, which enable external tools to access the Sourcegraph API with the privileges of the user.
@@ -32,6 +35,7 @@ type AuthProviders struct {
 	HttpHeader    *HTTPHeaderAuthProvider
 	Github        *GitHubAuthProvider
 	Gitlab        *GitLabAuthProvider
+	Ldap          *LDAPAuthProvider
 }
 
 func (v AuthProviders) MarshalJSON() ([]byte, error) {
@@ -53,6 +57,9 @@ func (v AuthProviders) MarshalJSON() ([]byte, error) {
 	if v.Gitlab != nil {
 		return json.Marshal(v.Gitlab)
 	}
+	if v.Ldap != nil {
+		return json.Marshal(v.Ldap)
+	}
 	return nil, errors.New("tagged union type must have exactly 1 non-nil field value")
 }
 func (v *AuthProviders) UnmarshalJSON(data []byte) error {
@@ -71,24 +78,48 @@ func (v *AuthProviders) UnmarshalJSON(data []byte) error {
 		return json.Unmarshal(data, &v.Gitlab)
 	case "http-header":
 		return json.Unmarshal(data, &v.HttpHeader)
+	case "ldap":
+		return json.Unmarshal(data, &v.Ldap)
 	case "openidconnect":
 		return json.Unmarshal(data, &v.Openidconnect)
 	case "saml":
 		return json.Unmarshal(data, &v.Saml)
 	}
-	return fmt.Errorf("tagged union type must have a %q property whose value is one of %s", "type", []string{"builtin", "saml", "openidconnect", "http-header", "github", "gitlab"})
+	return fmt.Errorf("tagged union type must have a %q property whose value is one of %s", "type", []string{"builtin", "saml", "openidconnect", "http-header", "github", "gitlab", "ldap"})
+}
+
+type BitbucketCloudConnection struct {
+	ApiURL                      string             `json:"apiURL,omitempty"`
+	AppPassword                 string             `json:"appPassword"`
+	CloneOptions                []*GitCloneOptions `json:"cloneOptions,omitempty"`
+	GitURLType                  string             `json:"gitURLType,omitempty"`
+	InitialRepositoryEnablement bool               `json:"initialRepositoryEnablement,omitempty"`
+	PushMirror                  *PushMirror        `json:"pushMirror,omitempty"`
+	RepositoryPathPattern       string             `json:"repositoryPathPattern,omitempty"`
+	UpdateLimits                *UpdateLimits      `json:"updateLimits,omitempty"`
+	Url                         string             `json:"url,omitempty"`
+	Username                    string             `json:"username"`
 }
 
+// BitbucketServerAuthorization description: If non-null, enforces Bitbucket Server repository permissions. Sourcegraph users are matched to the Bitbucket Server users with the same username, so usernames must be managed by a trusted authentication provider (such as SAML or OpenID Connect) and not chosen by users at sign-up.
+type BitbucketServerAuthorization struct {
+	Oauth Oauth  `json:"oauth"`
+	Ttl   string `json:"ttl,omitempty"`
+}
 type BitbucketServerConnection struct {
-	Certificate                 string `json:"certificate,omitempty"`
-	ExcludePersonalRepositories bool   `json:"excludePersonalRepositories,omitempty"`
-	GitURLType                  string `json:"gitURLType,omitempty"`
-	InitialRepositoryEnablement bool   `json:"initialRepositoryEnablement,omitempty"`
-	Password                    string `json:"password,omitempty"`
-	RepositoryPathPattern       string `json:"repositoryPathPattern,omitempty"`
-	Token                       string `json:"token,omitempty"`
-	Url                         string `json:"url"`
-	Username                    string `json:"username,omitempty"`
+	Authorization               *BitbucketServerAuthorization `json:"authorization,omitempty"`
+	Certificate                 string                        `json:"certificate,omitempty"`
+	CloneOptions                []*GitCloneOptions            `json:"cloneOptions,omitempty"`
+	ExcludePersonalRepositories bool                          `json:"excludePersonalRepositories,omitempty"`
+	GitURLType                  string                        `json:"gitURLType,omitempty"`
+	InitialRepositoryEnablement bool                          `json:"initialRepositoryEnablement,omitempty"`
+	Password                    string                        `json:"password,omitempty"`
+	PushMirror                  *PushMirror                   `json:"pushMirror,omitempty"`
+	RepositoryPathPattern       string                        `json:"repositoryPathPattern,omitempty"`
+	Token                       string                        `json:"token,omitempty"`
+	UpdateLimits                *UpdateLimits                 `json:"updateLimits,omitempty"`
+	Url                         string                        `json:"url"`
+	Username                    string                        `json:"username,omitempty"`
 }
 
 // BuiltinAuthProvider description: Configures the builtin username-password authentication provider.
@@ -141,6 +172,26 @@ type Extensions struct {
 	RemoteRegistry        interface{} `json:"remoteRegistry,omitempty"`
 }
 
+type GerritConnection struct {
+	Certificate                 string             `json:"certificate,omitempty"`
+	CloneOptions                []*GitCloneOptions `json:"cloneOptions,omitempty"`
+	InitialRepositoryEnablement bool               `json:"initialRepositoryEnablement,omitempty"`
+	Password                    string             `json:"password,omitempty"`
+	PushMirror                  *PushMirror        `json:"pushMirror,omitempty"`
+	RepositoryPathPattern       string             `json:"repositoryPathPattern,omitempty"`
+	UpdateLimits                *UpdateLimits      `json:"updateLimits,omitempty"`
+	Url                         string             `json:"url"`
+	Username                    string             `json:"username,omitempty"`
+}
+
+// GitCloneOptions description: Options for cloning the repositories whose names match a pattern.
+type GitCloneOptions struct {
+	BlobSizeLimit string   `json:"blobSizeLimit,omitempty"`
+	Depth         int      `json:"depth,omitempty"`
+	Pattern       string   `json:"pattern"`
+	SparsePaths   []string `json:"sparsePaths,omitempty"`
+}
+
 // GitHubAuthProvider description: Configures the GitHub (or GitHub Enterprise) OAuth authentication provider for SSO. In addition to specifying this configuration object, you must also create a OAuth App on your GitHub instance: https://developer.github.com/apps/building-oauth-apps/creating-an-oauth-app/. When a user signs into Sourcegraph or links their GitHub account to their existing Sourcegraph account, GitHub will prompt the user for the repo scope.
 type GitHubAuthProvider struct {
 	AllowSignup  bool   `json:"allowSignup,omitempty"`
@@ -158,13 +209,23 @@ type GitHubAuthorization struct {
 type GitHubConnection struct {
 	Authorization               *GitHubAuthorization `json:"authorization,omitempty"`
 	Certificate                 string               `json:"certificate,omitempty"`
+	CloneOptions                []*GitCloneOptions   `json:"cloneOptions,omitempty"`
 	GitURLType                  string               `json:"gitURLType,omitempty"`
 	InitialRepositoryEnablement bool                 `json:"initialRepositoryEnablement,omitempty"`
+	PushMirror                  *PushMirror          `json:"pushMirror,omitempty"`
 	Repos                       []string             `json:"repos,omitempty"`
 	RepositoryPathPattern       string               `json:"repositoryPathPattern,omitempty"`
 	RepositoryQuery             []string             `json:"repositoryQuery,omitempty"`
 	Token                       string               `json:"token"`
+	UpdateLimits                *UpdateLimits        `json:"updateLimits,omitempty"`
 	Url                         string               `json:"url"`
+	WebhookSecret               string               `json:"webhookSecret,omitempty"`
+}
+
+// GitLFS description: Fetching of Git LFS objects.
+type GitLFS struct {
+	Enabled       bool `json:"enabled,omitempty"`
+	MaxObjectSize int  `json:"maxObjectSize,omitempty"`
 }
 
 // GitLabAuthProvider description: Configures the GitLab OAuth authentication provider for SSO. In addition to specifying this configuration object, you must also create a OAuth App on your GitLab instance: https://docs.gitlab.com/ee/integration/oauth_provider.html. The application should have `api` and `read_user` scopes and the callback URL set to the concatenation of your Sourcegraph instance URL and "/.auth/gitlab/callback".
@@ -183,19 +244,37 @@ type GitLabAuthorization struct {
 type GitLabConnection struct {
 	Authorization               *GitLabAuthorization `json:"authorization,omitempty"`
 	Certificate                 string               `json:"certificate,omitempty"`
+	CloneOptions                []*GitCloneOptions   `json:"cloneOptions,omitempty"`
 	GitURLType                  string               `json:"gitURLType,omitempty"`
 	InitialRepositoryEnablement bool                 `json:"initialRepositoryEnablement,omitempty"`
 	ProjectQuery                []string             `json:"projectQuery,omitempty"`
+	PushMirror                  *PushMirror          `json:"pushMirror,omitempty"`
 	RepositoryPathPattern       string               `json:"repositoryPathPattern,omitempty"`
 	Token                       string               `json:"token"`
+	UpdateLimits                *UpdateLimits        `json:"updateLimits,omitempty"`
 	Url                         string               `json:"url"`
+	WebhookSecret               string               `json:"webhookSecret,omitempty"`
+}
+type GiteaConnection struct {
+	Certificate                 string             `json:"certificate,omitempty"`
+	CloneOptions                []*GitCloneOptions `json:"cloneOptions,omitempty"`
+	GitURLType                  string             `json:"gitURLType,omitempty"`
+	InitialRepositoryEnablement bool               `json:"initialRepositoryEnablement,omitempty"`
+	PushMirror                  *PushMirror        `json:"pushMirror,omitempty"`
+	RepositoryPathPattern       string             `json:"repositoryPathPattern,omitempty"`
+	Token                       string             `json:"token"`
+	UpdateLimits                *UpdateLimits      `json:"updateLimits,omitempty"`
+	Url                         string             `json:"url"`
 }
 type GitoliteConnection struct {
-	Blacklist                  string       `json:"blacklist,omitempty"`
-	Host                       string       `json:"host"`
-	Phabricator                *Phabricator `json:"phabricator,omitempty"`
-	PhabricatorMetadataCommand string       `json:"phabricatorMetadataCommand,omitempty"`
-	Prefix                     string       `json:"prefix"`
+	Blacklist                  string             `json:"blacklist,omitempty"`
+	CloneOptions               []*GitCloneOptions `json:"cloneOptions,omitempty"`
+	Host                       string             `json:"host"`
+	Phabricator                *Phabricator       `json:"phabricator,omitempty"`
+	PhabricatorMetadataCommand string             `json:"phabricatorMetadataCommand,omitempty"`
+	Prefix                     string             `json:"prefix"`
+	PushMirror                 *PushMirror        `json:"pushMirror,omitempty"`
+	UpdateLimits               *UpdateLimits      `json:"updateLimits,omitempty"`
 }
 
 // HTTPHeaderAuthProvider description: Configures the HTTP header authentication provider (which authenticates users by consulting an HTTP request header set by an authentication proxy such as https://github.com/bitly/oauth2_proxy).
@@ -213,11 +292,42 @@ type IMAPServerConfig struct {
 	Username string `json:"username,omitempty"`
 }
 
+// LDAPAuthProvider description: Configures the LDAP authentication provider (which authenticates users with their username and password against an LDAP directory server, such as OpenLDAP or Active Directory).
+type LDAPAuthProvider struct {
+	BindDN               string          `json:"bindDN,omitempty"`
+	BindPassword         string          `json:"bindPassword,omitempty"`
+	Certificate          string          `json:"certificate,omitempty"`
+	DisplayName          string          `json:"displayName,omitempty"`
+	DisplayNameAttribute string          `json:"displayNameAttribute,omitempty"`
+	EmailAttribute       string          `json:"emailAttribute,omitempty"`
+	GroupOrgs            []*LDAPGroupOrg `json:"groupOrgs,omitempty"`
+	GroupSearchBase      string          `json:"groupSearchBase,omitempty"`
+	GroupSearchFilter    string          `json:"groupSearchFilter,omitempty"`
+	StartTLS             bool            `json:"startTLS,omitempty"`
+	Type                 string          `json:"type"`
+	Url                  string          `json:"url"`
+	UserSearchBase       string          `json:"userSearchBase"`
+	UserSearchFilter     string          `json:"userSearchFilter,omitempty"`
+	UsernameAttribute    string          `json:"usernameAttribute,omitempty"`
+}
+
+// LDAPGroupOrg description: Maps an LDAP group to a Sourcegraph organization.
+type LDAPGroupOrg struct {
+	Group string `json:"group"`
+	Org   string `json:"org"`
+}
+
 // Log description: Configuration for logging and alerting, including to external services.
 type Log struct {
 	Sentry *Sentry `json:"sentry,omitempty"`
 }
 
+// Oauth description: The OAuth consumer of an incoming application link on Bitbucket Server with user impersonation allowed. Sourcegraph impersonates each user to list the repositories they can access. The connection's token (or username and password) is used to look up users.
+type Oauth struct {
+	ConsumerKey string `json:"consumerKey"`
+	SigningKey  string `json:"signingKey"`
+}
+
 // OpenIDConnectAuthProvider description: Configures the OpenID Connect authentication provider for SSO.
 type OpenIDConnectAuthProvider struct {
 	ClientID           string `json:"clientID"`
@@ -250,6 +360,11 @@ type PhabricatorConnection struct {
 	Token string   `json:"token,omitempty"`
 	Url   string   `json:"url,omitempty"`
 }
+
+// PushMirror description: A secondary remote to which repositories are pushed after each update.
+type PushMirror struct {
+	Url string `json:"url"`
+}
 type Repos struct {
 	Callsign string `json:"callsign"`
 	Path     string `json:"path"`
@@ -331,7 +446,9 @@ type SiteConfiguration struct {
 	ExperimentalFeatures              *ExperimentalFeatures       `json:"experimentalFeatures,omitempty"`
 	Extensions                        *Extensions                 `json:"extensions,omitempty"`
 	GitCloneURLToRepositoryName       []*CloneURLToRepositoryName `json:"git.cloneURLToRepositoryName,omitempty"`
+	GitLFS                            *GitLFS                     `json:"gitLFS,omitempty"`
 	GitMaxConcurrentClones            int                         `json:"gitMaxConcurrentClones,omitempty"`
+	GitTrustedSigningKeys             []string                    `json:"gitTrustedSigningKeys,omitempty"`
 	GithubClientID                    string                      `json:"githubClientID,omitempty"`
 	GithubClientSecret                string                      `json:"githubClientSecret,omitempty"`
 	MaxReposToSearch                  int                         `json:"maxReposToSearch,omitempty"`
@@ -345,3 +462,9 @@ type SiteConfiguration struct {
 type SlackNotificationsConfig struct {
 	WebhookURL string `json:"webhookURL"`
 }
+
+// UpdateLimits description: Limits on the repository updates of an external service.
+type UpdateLimits struct {
+	MaxConcurrentUpdates int `json:"maxConcurrentUpdates,omitempty"`
+	MaxUpdatesPerMinute  int `json:"maxUpdatesPerMinute,omitempty"`
+}
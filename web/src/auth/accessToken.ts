@@ -4,4 +4,5 @@
 export enum AccessTokenScopes {
     UserAll = 'user:all',
     SiteAdminSudo = 'site-admin:sudo',
+    SiteAdminSCIM = 'site-admin:scim',
 }
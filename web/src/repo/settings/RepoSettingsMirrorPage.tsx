@@ -64,6 +64,11 @@ class UpdateMirrorRepositoryActionContainer extends React.PureComponent<UpdateMi
             info = <DirectImportRepoAlert className="action-container__alert" />
         } else if (this.props.repo.mirrorInfo.cloned) {
             const updateSchedule = this.props.repo.mirrorInfo.updateSchedule
+            const updateQueue = this.props.repo.mirrorInfo.updateQueue
+            const externalServiceName =
+                updateQueue && updateQueue.externalService
+                    ? updateQueue.externalService.displayName
+                    : 'its external service'
             title = (
                 <>
                     <div>
@@ -84,6 +89,10 @@ class UpdateMirrorRepositoryActionContainer extends React.PureComponent<UpdateMi
                         <div>
                             Queued for update (position {this.props.repo.mirrorInfo.updateQueue.index + 1} out of{' '}
                             {this.props.repo.mirrorInfo.updateQueue.total} in the queue)
+                            {this.props.repo.mirrorInfo.updateQueue.waitingFor === 'EXTERNAL_SERVICE_CONCURRENCY' &&
+                                `, waiting for other updates of ${externalServiceName} to finish`}
+                            {this.props.repo.mirrorInfo.updateQueue.waitingFor === 'EXTERNAL_SERVICE_RATE_LIMIT' &&
+                                `, waiting for the update rate limit of ${externalServiceName}`}
                         </div>
                     )}
                 </>
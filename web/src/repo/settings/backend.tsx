@@ -32,6 +32,10 @@ export function fetchRepository(name: string): Observable<GQL.IRepository> {
                             updating
                             index
                             total
+                            externalService {
+                                displayName
+                            }
+                            waitingFor
                         }
                     }
                 }
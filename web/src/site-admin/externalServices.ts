@@ -42,6 +42,21 @@ export const ALL_EXTERNAL_SERVICES: ExternalServiceMetadata[] = [
   "region": "",
   "accessKeyID": "",
   "secretAccessKey": ""
+}`,
+    },
+    {
+        kind: GQL.ExternalServiceKind.BITBUCKETCLOUD,
+        displayName: 'Bitbucket Cloud',
+        defaultConfig: `{
+  // Use Ctrl+Space for completion, and hover over JSON properties for documentation.
+  // Configuration options are documented here:
+  // https://docs.sourcegraph.com/admin/site_config/all#bitbucketcloudconnection-object
+
+  "username": "",
+
+  // Create an app password with the Repositories: Read permission at
+  // https://bitbucket.org/account/settings/app-passwords/
+  "appPassword": ""
 }`,
     },
     {
@@ -57,6 +72,37 @@ export const ALL_EXTERNAL_SERVICES: ExternalServiceMetadata[] = [
   // Create a personal access token with read scope at
   // https://[your-bitbucket-hostname]/plugins/servlet/access-tokens/add
   "token": ""
+}`,
+    },
+    {
+        kind: GQL.ExternalServiceKind.GERRIT,
+        displayName: 'Gerrit',
+        defaultConfig: `{
+  // Use Ctrl+Space for completion, and hover over JSON properties for documentation.
+  // Configuration options are documented here:
+  // https://docs.sourcegraph.com/admin/site_config/all#gerritconnection-object
+
+  "url": "https://gerrit.example.com",
+
+  // Generate an HTTP password at
+  // https://[your-gerrit-hostname]/settings/#HTTPCredentials
+  "username": "",
+  "password": ""
+}`,
+    },
+    {
+        kind: GQL.ExternalServiceKind.GITEA,
+        displayName: 'Gitea',
+        defaultConfig: `{
+  // Use Ctrl+Space for completion, and hover over JSON properties for documentation.
+  // Configuration options are documented here:
+  // https://docs.sourcegraph.com/admin/site_config/all#giteaconnection-object
+
+  "url": "https://gitea.example.com",
+
+  // Create an access token at
+  // https://[your-gitea-hostname]/user/settings/applications
+  "token": ""
 }`,
     },
     GITHUB_EXTERNAL_SERVICE,
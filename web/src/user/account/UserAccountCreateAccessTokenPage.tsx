@@ -175,6 +175,25 @@ export class UserAccountCreateAccessTokenPage extends React.PureComponent<Props,
                                 </label>
                             </div>
                         )}
+                        {this.props.user.siteAdmin && (
+                            <div className="form-check">
+                                <input
+                                    className="form-check-input"
+                                    type="checkbox"
+                                    id="user-settings-create-access-token-page__scope-site-admin:scim"
+                                    checked={this.state.scopes.includes(AccessTokenScopes.SiteAdminSCIM)}
+                                    value={AccessTokenScopes.SiteAdminSCIM}
+                                    onChange={this.onScopesChange}
+                                />
+                                <label
+                                    className="form-check-label"
+                                    htmlFor="user-settings-create-access-token-page__scope-site-admin:scim"
+                                >
+                                    <strong>{AccessTokenScopes.SiteAdminSCIM}</strong> — Ability to provision users
+                                    and organizations with the SCIM API
+                                </label>
+                            </div>
+                        )}
                     </div>
                     <button
                         type="submit"